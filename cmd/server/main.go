@@ -49,11 +49,11 @@ func main() {
 		log.Fatal("应用初始化失败", "error", err)
 	}
 
-	// 在后台监听信号
+	// 在后台监听信号：取消 context 后，RunWithContext 内部会完成完整的优雅关闭序列
+	// （停止接受新请求 -> 排空正在执行的工具调用 -> 关闭数据库等资源），此处无需重复调用 Shutdown。
 	go func() {
 		sig := <-sigCh
 		log.Info("收到系统信号，开始优雅关闭: " + sig.String())
-		application.Shutdown()
 		cancel()
 	}()
 
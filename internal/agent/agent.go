@@ -2,11 +2,17 @@ package agent
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	mrand "math/rand"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,6 +26,7 @@ import (
 	"cyberstrike-ai/internal/openai"
 	"cyberstrike-ai/internal/security"
 	"cyberstrike-ai/internal/storage"
+	"cyberstrike-ai/internal/tracing"
 
 	"go.uber.org/zap"
 )
@@ -41,6 +48,57 @@ type Agent struct {
 	currentConversationID string            // 当前对话ID（用于自动传递给工具）
 	promptBaseDir         string            // 解析 system_prompt_path 时相对路径的基准目录（通常为 config.yaml 所在目录）
 	toolDescriptionMode   string            // 工具描述模式: "short" | "full"，默认 short
+	checkpointSaver       CheckpointSaver   // 运行中任务的 messages/迭代计数持久化，用于重启后 /api/agent-loop/resume 续跑
+
+	retryMaxAttempts int           // LLM 调用最大尝试次数（含首次），来自 config.OpenAIConfig.Retry
+	retryBaseBackoff time.Duration // 指数退避基准值
+	retryMaxBackoff  time.Duration // 退避等待时间上限
+	retryStatusCodes map[int]bool  // 触发重试的 HTTP 状态码集合
+
+	retryStatsMu       sync.Mutex    // 保护下面两个重试统计字段
+	retryStatsTotal    int64         // 累计重试次数（不含首次尝试）
+	retryStatsByStatus map[int]int64 // 按 HTTP 状态码维度累计的重试次数（网络类错误不计入，键为 0 表示其它可重试错误）
+}
+
+// defaultRetryStatusCodes 是 RetryConfig.RetryOnStatusCodes 未配置时使用的默认重试状态码集合。
+var defaultRetryStatusCodes = []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// resolveRetryPolicy 将 config.RetryConfig 中的用户配置与内置默认值合并，返回可直接使用的重试参数。
+func resolveRetryPolicy(cfg *config.OpenAIConfig) (maxAttempts int, baseBackoff, maxBackoff time.Duration, statusCodes map[int]bool) {
+	maxAttempts = 3
+	baseBackoff = 1 * time.Second
+	maxBackoff = 30 * time.Second
+	codes := defaultRetryStatusCodes
+
+	if cfg != nil {
+		if cfg.Retry.MaxAttempts > 0 {
+			maxAttempts = cfg.Retry.MaxAttempts
+		}
+		if cfg.Retry.BaseBackoffMs > 0 {
+			baseBackoff = time.Duration(cfg.Retry.BaseBackoffMs) * time.Millisecond
+		}
+		if cfg.Retry.MaxBackoffMs > 0 {
+			maxBackoff = time.Duration(cfg.Retry.MaxBackoffMs) * time.Millisecond
+		}
+		if len(cfg.Retry.RetryOnStatusCodes) > 0 {
+			codes = cfg.Retry.RetryOnStatusCodes
+		}
+	}
+
+	statusCodes = make(map[int]bool, len(codes))
+	for _, code := range codes {
+		statusCodes[code] = true
+	}
+	return maxAttempts, baseBackoff, maxBackoff, statusCodes
+}
+
+// CheckpointSaver 持久化 Agent 运行中每轮迭代的消息快照与迭代计数，供服务重启后续跑。
+// 任务正常结束（成功、出错、达到最大迭代）时会清除对应会话的检查点；仅在上下文被取消/超时中途退出时保留，
+// 以便进程重启后可以从最后一次迭代继续。
+type CheckpointSaver interface {
+	SaveCheckpoint(conversationID, messagesJSON string, iteration int, roleToolsJSON string) error
+	GetCheckpoint(conversationID string) (messagesJSON string, iteration int, roleToolsJSON string, found bool, err error)
+	ClearCheckpoint(conversationID string) error
 }
 
 // ResultStorage 结果存储接口（直接使用 storage 包的类型）
@@ -64,7 +122,8 @@ func withAgentConversationID(ctx context.Context, id string) context.Context {
 	if id == "" || ctx == nil {
 		return ctx
 	}
-	return context.WithValue(ctx, agentConversationIDKey{}, id)
+	ctx = context.WithValue(ctx, agentConversationIDKey{}, id)
+	return openai.WithConversationID(ctx, id)
 }
 
 func agentConversationIDFromContext(ctx context.Context) string {
@@ -75,6 +134,67 @@ func agentConversationIDFromContext(ctx context.Context) string {
 	return v
 }
 
+type agentLanguageKey struct{}
+
+// WithLanguage 在 context 中注入本次对话的输出语言偏好（如 zh、en），覆盖 AgentConfig.Language 的全局默认值。
+func WithLanguage(ctx context.Context, language string) context.Context {
+	language = strings.TrimSpace(language)
+	if language == "" || ctx == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, agentLanguageKey{}, language)
+}
+
+func languageFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	v, _ := ctx.Value(agentLanguageKey{}).(string)
+	return v
+}
+
+type agentDefaultToolProfilesKey struct{}
+
+// WithDefaultToolProfiles 在 context 中注入当前对话所用角色的默认扫描档位（工具名 -> profile 名，
+// 见 config.RoleConfig.DefaultToolProfiles），模型调用工具时若未显式传入 profile 参数会自动套用。
+func WithDefaultToolProfiles(ctx context.Context, profiles map[string]string) context.Context {
+	if len(profiles) == 0 || ctx == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, agentDefaultToolProfilesKey{}, profiles)
+}
+
+func defaultToolProfileFromContext(ctx context.Context, toolName string) string {
+	if ctx == nil {
+		return ""
+	}
+	profiles, _ := ctx.Value(agentDefaultToolProfilesKey{}).(map[string]string)
+	return profiles[toolName]
+}
+
+// languageInstruction 返回追加到 system prompt / 总结提示末尾的语言要求；language 为空或为中文时不追加（内置提示本身即为中文）。
+func languageInstruction(language string) string {
+	switch strings.ToLower(strings.TrimSpace(language)) {
+	case "", "zh", "zh-cn", "zh_cn", "chinese", "中文":
+		return ""
+	case "en", "en-us", "english":
+		return "\n\nIMPORTANT: Respond to the user in English, regardless of the language of the system prompt above."
+	default:
+		return fmt.Sprintf("\n\nIMPORTANT: Respond to the user in the following language: %s.", language)
+	}
+}
+
+// resolveLanguage 优先取 context 中按会话覆盖的语言，否则回退到 AgentConfig 中的全局默认值。
+func (a *Agent) resolveLanguage(ctx context.Context) string {
+	if lang := languageFromContext(ctx); lang != "" {
+		return lang
+	}
+	if a.agentConfig != nil {
+		return strings.TrimSpace(a.agentConfig.Language)
+	}
+	return ""
+}
+
 // ConversationIDFromContext 返回当前 Agent 请求上下文中注入的对话 ID（如 C2 MCP 入队与人机协同门控使用）。
 func ConversationIDFromContext(ctx context.Context) string {
 	return agentConversationIDFromContext(ctx)
@@ -91,6 +211,51 @@ func WithToolCallInterceptor(ctx context.Context, fn ToolCallInterceptor) contex
 	return context.WithValue(ctx, toolCallInterceptorCtxKey{}, fn)
 }
 
+// applyOpenAITransportOptions 按 config.OpenAIConfig 中的 ProxyURL/InsecureSkipVerify/CACertPath
+// 配置 transport 的代理与 TLS 选项，使 Agent 能够在企业代理网关或自签名证书的自建网关后正常工作。
+// 三项均为空/false 时不修改 transport 的默认行为（不使用代理，使用系统证书池）。
+func applyOpenAITransportOptions(transport *http.Transport, cfg *config.OpenAIConfig, logger *zap.Logger) error {
+	if cfg == nil {
+		return nil
+	}
+
+	if proxyURL := strings.TrimSpace(cfg.ProxyURL); proxyURL != "" {
+		parsed, err := url.Parse(proxyURL)
+		if err != nil {
+			return fmt.Errorf("解析 proxy_url 失败: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(parsed)
+		logger.Info("OpenAI HTTP 客户端已启用代理", zap.String("proxyURL", proxyURL))
+	}
+
+	if !cfg.InsecureSkipVerify && strings.TrimSpace(cfg.CACertPath) == "" {
+		return nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.InsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
+		logger.Warn("OpenAI HTTP 客户端已关闭 TLS 证书校验（insecure_skip_verify），仅应在受信任的自建网关调试环境使用")
+	}
+	if caCertPath := strings.TrimSpace(cfg.CACertPath); caCertPath != "" {
+		caCert, err := os.ReadFile(caCertPath)
+		if err != nil {
+			return fmt.Errorf("读取 ca_cert_path 失败: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caCert) {
+			return fmt.Errorf("ca_cert_path 未包含有效的 PEM 证书: %s", caCertPath)
+		}
+		tlsConfig.RootCAs = pool
+		logger.Info("OpenAI HTTP 客户端已加载自定义 CA 证书", zap.String("caCertPath", caCertPath))
+	}
+	transport.TLSClientConfig = tlsConfig
+	return nil
+}
+
 // NewAgent 创建新的Agent
 func NewAgent(cfg *config.OpenAIConfig, agentCfg *config.AgentConfig, mcpServer *mcp.Server, externalMCPMgr *mcp.ExternalMCPManager, logger *zap.Logger, maxIterations int) *Agent {
 	// 如果 maxIterations 为 0 或负数，使用默认值 30
@@ -131,6 +296,9 @@ func NewAgent(cfg *config.OpenAIConfig, agentCfg *config.AgentConfig, mcpServer
 		ResponseHeaderTimeout: 60 * time.Minute, // 响应头超时：增加到15分钟，应对大响应
 		DisableKeepAlives:     false,            // 启用连接复用
 	}
+	if err := applyOpenAITransportOptions(transport, cfg, logger); err != nil {
+		logger.Warn("配置 OpenAI HTTP Transport 的代理/证书选项失败，将使用默认直连配置", zap.Error(err))
+	}
 
 	// 增加超时时间到30分钟，以支持长时间运行的AI推理
 	// 特别是当使用流式响应或处理复杂任务时
@@ -157,6 +325,8 @@ func NewAgent(cfg *config.OpenAIConfig, agentCfg *config.AgentConfig, mcpServer
 		logger.Warn("OpenAI配置为空，无法初始化MemoryCompressor")
 	}
 
+	retryMaxAttempts, retryBaseBackoff, retryMaxBackoff, retryStatusCodes := resolveRetryPolicy(cfg)
+
 	return &Agent{
 		openAIClient:         llmClient,
 		config:               cfg,
@@ -170,6 +340,11 @@ func NewAgent(cfg *config.OpenAIConfig, agentCfg *config.AgentConfig, mcpServer
 		largeResultThreshold: largeResultThreshold,
 		toolNameMapping:      make(map[string]string), // 初始化工具名称映射
 		toolDescriptionMode:  "short",
+		retryMaxAttempts:     retryMaxAttempts,
+		retryBaseBackoff:     retryBaseBackoff,
+		retryMaxBackoff:      retryMaxBackoff,
+		retryStatusCodes:     retryStatusCodes,
+		retryStatsByStatus:   make(map[int]int64),
 	}
 }
 
@@ -180,6 +355,39 @@ func (a *Agent) SetResultStorage(storage ResultStorage) {
 	a.resultStorage = storage
 }
 
+// SetCheckpointSaver 设置检查点持久化实现（用于避免循环依赖，一般由 database.DB 实现）。
+func (a *Agent) SetCheckpointSaver(saver CheckpointSaver) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.checkpointSaver = saver
+}
+
+// saveCheckpoint 尽力保存本轮迭代的消息快照；保存失败仅记录日志，不影响主流程。
+func (a *Agent) saveCheckpoint(conversationID, messagesJSON string, iteration int, roleToolsJSON string) {
+	a.mu.RLock()
+	saver := a.checkpointSaver
+	a.mu.RUnlock()
+	if saver == nil {
+		return
+	}
+	if err := saver.SaveCheckpoint(conversationID, messagesJSON, iteration, roleToolsJSON); err != nil {
+		a.logger.Warn("保存Agent检查点失败", zap.String("conversationId", conversationID), zap.Error(err))
+	}
+}
+
+// clearCheckpoint 任务自然结束（成功、出错或达到最大迭代）后清除检查点，避免误续跑已结束的任务。
+func (a *Agent) clearCheckpoint(conversationID string) {
+	a.mu.RLock()
+	saver := a.checkpointSaver
+	a.mu.RUnlock()
+	if saver == nil {
+		return
+	}
+	if err := saver.ClearCheckpoint(conversationID); err != nil {
+		a.logger.Warn("清除Agent检查点失败", zap.String("conversationId", conversationID), zap.Error(err))
+	}
+}
+
 // SetPromptBaseDir 设置单代理 system_prompt_path 相对路径的基准目录（一般为 config.yaml 所在目录）。
 func (a *Agent) SetPromptBaseDir(dir string) {
 	a.mu.Lock()
@@ -247,6 +455,28 @@ type OpenAIRequest struct {
 	Messages []ChatMessage `json:"messages"`
 	Tools    []Tool        `json:"tools,omitempty"`
 	Stream   bool          `json:"stream,omitempty"`
+	// ParallelToolCalls 对应 config.ProviderQuirksConfig 的兼容开关：
+	// 留空(nil)不下发该字段（大多数后端的默认行为）；由 applyToolCallQuirks 按配置填充。
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
+	// MaxTokens 仅供 CreateSamplingCompletion（MCP sampling/request 转发）等直接指定单次补全长度上限的
+	// 调用方使用；Agent 主循环的常规请求不设置该字段，交由后端使用其默认值。
+	MaxTokens int `json:"max_tokens,omitempty"`
+}
+
+// applyToolCallQuirks 按 config.ProviderQuirksConfig 调整请求体中与工具调用相关的字段，
+// 兼容部分 OpenAI 兼容后端（较旧版本的 vLLM、某些代理网关）对 parallel_tool_calls 的差异处理。
+func (a *Agent) applyToolCallQuirks(reqBody *OpenAIRequest) {
+	if len(reqBody.Tools) == 0 || a.config == nil {
+		return
+	}
+	quirks := a.config.Quirks
+	switch {
+	case quirks.DisableParallelToolCalls:
+		reqBody.ParallelToolCalls = nil
+	case quirks.ForceSequentialToolCalls:
+		sequential := false
+		reqBody.ParallelToolCalls = &sequential
+	}
 }
 
 // OpenAIResponse OpenAI API响应
@@ -269,6 +499,33 @@ type MessageWithTools struct {
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 }
 
+// UnmarshalJSON 自定义JSON解析，兼容部分较旧的 OpenAI 兼容后端仍返回单个顶层 function_call
+// 字段（而非 tool_calls 数组）的情况：仅当 tool_calls 为空时才回退解析 function_call，避免覆盖标准字段。
+func (m *MessageWithTools) UnmarshalJSON(data []byte) error {
+	type Alias MessageWithTools
+	aux := &struct {
+		FunctionCall *FunctionCall `json:"function_call,omitempty"`
+		*Alias
+	}{
+		Alias: (*Alias)(m),
+	}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+
+	if len(m.ToolCalls) == 0 && aux.FunctionCall != nil && strings.TrimSpace(aux.FunctionCall.Name) != "" {
+		m.ToolCalls = []ToolCall{
+			{
+				ID:       "legacy_call_0",
+				Type:     "function",
+				Function: *aux.FunctionCall,
+			},
+		}
+	}
+
+	return nil
+}
+
 // Tool OpenAI工具定义
 type Tool struct {
 	Type     string             `json:"type"`
@@ -280,6 +537,8 @@ type FunctionDefinition struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description"`
 	Parameters  map[string]interface{} `json:"parameters"`
+	// Strict 为 true 时启用 OpenAI strict function calling（要求 Parameters 全程 additionalProperties:false）。
+	Strict bool `json:"strict,omitempty"`
 }
 
 // Error OpenAI错误
@@ -392,12 +651,6 @@ func (a *Agent) AgentLoopWithProgress(ctx context.Context, userInput string, his
 	a.mu.Lock()
 	a.currentConversationID = conversationID
 	a.mu.Unlock()
-	// 发送进度更新
-	sendProgress := func(eventType, message string, data interface{}) {
-		if callback != nil {
-			callback(eventType, message, data)
-		}
-	}
 
 	systemPrompt := DefaultSingleAgentSystemPrompt()
 	if a.agentConfig != nil {
@@ -416,6 +669,8 @@ func (a *Agent) AgentLoopWithProgress(ctx context.Context, userInput string, his
 			}
 		}
 	}
+	language := a.resolveLanguage(ctx)
+	systemPrompt += languageInstruction(language)
 
 	messages := []ChatMessage{
 		{
@@ -474,6 +729,106 @@ func (a *Agent) AgentLoopWithProgress(ctx context.Context, userInput string, his
 		Content: userInput,
 	})
 
+	return a.runAgentLoop(ctx, messages, 0, conversationID, callback, roleTools)
+}
+
+// AgentLoopResumeFromCheckpoint 从上次保存的检查点（messages 快照 + 迭代计数）继续执行 Agent Loop，
+// 用于服务重启后恢复被中断的任务。检查点不存在时返回 error。
+func (a *Agent) AgentLoopResumeFromCheckpoint(ctx context.Context, conversationID string, callback ProgressCallback) (*AgentLoopResult, error) {
+	a.mu.RLock()
+	saver := a.checkpointSaver
+	a.mu.RUnlock()
+	if saver == nil {
+		return nil, fmt.Errorf("检查点存储未配置")
+	}
+
+	messagesJSON, iteration, roleToolsJSON, found, err := saver.GetCheckpoint(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("读取检查点失败: %w", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("会话 %s 没有可续跑的检查点", conversationID)
+	}
+
+	var messages []ChatMessage
+	if err := json.Unmarshal([]byte(messagesJSON), &messages); err != nil {
+		return nil, fmt.Errorf("解析检查点消息失败: %w", err)
+	}
+
+	var roleTools []string
+	if strings.TrimSpace(roleToolsJSON) != "" {
+		if err := json.Unmarshal([]byte(roleToolsJSON), &roleTools); err != nil {
+			a.logger.Warn("解析检查点角色工具列表失败，使用全部工具", zap.Error(err))
+			roleTools = nil
+		}
+	}
+
+	ctx = withAgentConversationID(ctx, conversationID)
+	a.mu.Lock()
+	a.currentConversationID = conversationID
+	a.mu.Unlock()
+
+	a.logger.Info("从检查点续跑Agent Loop",
+		zap.String("conversationId", conversationID),
+		zap.Int("iteration", iteration),
+		zap.Int("messagesCount", len(messages)),
+	)
+
+	return a.runAgentLoop(ctx, messages, iteration, conversationID, callback, roleTools)
+}
+
+// maxConsecutiveDuplicateToolCalls 允许连续出现的“相同工具名+相同参数”调用次数上限，
+// 超过后不再实际执行，直接复用上一次的结果并提示模型更换策略。
+const maxConsecutiveDuplicateToolCalls = 3
+
+// toolCallDedupState 跟踪一次 Agent Loop 运行中连续重复的工具调用，用于短路重复执行。
+// 仅作为 runAgentLoop 的局部状态使用，不随 Agent 实例保留，避免不同会话之间互相影响。
+type toolCallDedupState struct {
+	lastKey     string
+	repeatCount int
+	lastResult  *ToolExecutionResult
+}
+
+// hashToolCall 对 (toolName, args) 计算稳定哈希，用于判断两次工具调用是否完全相同。
+// encoding/json 对 map 序列化时按 key 排序，因此相同参数总能得到相同的哈希值。
+func hashToolCall(toolName string, args map[string]interface{}) string {
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		argsJSON = []byte(fmt.Sprintf("%v", args))
+	}
+	sum := sha256.Sum256([]byte(toolName + ":" + string(argsJSON)))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// formatDuplicateToolCallHint 构造重复调用被短路时返回给模型的提示，附带上一次的真实结果，
+// 使模型既能看到结果，也能意识到需要调整策略而不是继续重复调用。
+func (a *Agent) formatDuplicateToolCallHint(toolName string, repeatCount int, cachedResult string) string {
+	return fmt.Sprintf(
+		"[系统提示] 检测到工具 %s 已使用完全相同的参数连续调用 %d 次，本次已跳过实际执行，直接复用上一次的结果，避免浪费资源。请勿再重复调用相同工具和参数，尝试更换参数或使用其他工具推进任务。\n\n以下为上一次的执行结果：\n%s",
+		toolName, repeatCount, cachedResult,
+	)
+}
+
+// runAgentLoop 执行 ReAct 主循环，从 startIteration 开始迭代直至得出最终回复或达到最大迭代次数。
+// 由 AgentLoopWithProgress（首次运行，startIteration=0）与 AgentLoopResumeFromCheckpoint（续跑）共用。
+func (a *Agent) runAgentLoop(ctx context.Context, messages []ChatMessage, startIteration int, conversationID string, callback ProgressCallback, roleTools []string) (*AgentLoopResult, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "agent.AgentLoop")
+	defer span.End()
+	span.SetAttributes(
+		tracing.StringAttr("conversation_id", conversationID),
+		tracing.StringAttr("max_iterations", fmt.Sprintf("%d", a.maxIterations)),
+	)
+
+	sendProgress := func(eventType, message string, data interface{}) {
+		if callback != nil {
+			callback(eventType, message, data)
+		}
+	}
+	ctx = withRetryWaitCallback(ctx, func(ev RetryWaitEvent) {
+		sendProgress("rate_limited", fmt.Sprintf("触发限流(HTTP %d)，%.0f 秒后进行第 %d/%d 次重试", ev.StatusCode, ev.WaitSeconds, ev.Attempt, ev.MaxAttempts), ev)
+	})
+	language := a.resolveLanguage(ctx)
+
 	result := &AgentLoopResult{
 		MCPExecutionIDs: make([]string, 0),
 	}
@@ -483,7 +838,23 @@ func (a *Agent) AgentLoopWithProgress(ctx context.Context, userInput string, his
 
 	maxIterations := a.maxIterations
 	thinkingStreamSeq := 0
-	for i := 0; i < maxIterations; i++ {
+	dedupState := toolCallDedupState{}
+	reflectionRounds := 0
+	runStart := time.Now()
+
+	roleToolsJSON := "[]"
+	if b, err := json.Marshal(roleTools); err == nil {
+		roleToolsJSON = string(b)
+	}
+
+	// 任务自然结束时清除检查点；因上下文取消/超时中途退出时保留，以便重启后续跑。
+	defer func() {
+		if ctx.Err() == nil {
+			a.clearCheckpoint(conversationID)
+		}
+	}()
+
+	for i := startIteration; i < maxIterations; i++ {
 		// 先获取本轮可用工具并统计 tools token，再压缩，以便压缩时预留 tools 占用的空间
 		tools := a.getAvailableTools(roleTools)
 		toolsTokens := a.countToolsTokens(tools)
@@ -501,6 +872,8 @@ func (a *Agent) AgentLoopWithProgress(ctx context.Context, userInput string, his
 			currentAgentTraceInput = string(messagesJSON)
 			// 更新result中的值，确保始终保存最新的ReAct输入（压缩后的）
 			result.LastAgentTraceInput = currentAgentTraceInput
+			// 保存检查点，供进程重启后通过 /api/agent-loop/resume 从本轮迭代续跑
+			a.saveCheckpoint(conversationID, currentAgentTraceInput, i, roleToolsJSON)
 		}
 
 		// 检查上下文是否已取消
@@ -520,9 +893,10 @@ func (a *Agent) AgentLoopWithProgress(ctx context.Context, userInput string, his
 		}
 
 		// 记录当前上下文的 Token 用量（messages + tools），展示压缩器运行状态
+		currentTotalTokens := 0
 		if a.memoryCompressor != nil {
 			messagesTokens, systemCount, regularCount := a.memoryCompressor.totalTokensFor(messages)
-			totalTokens := messagesTokens + toolsTokens
+			currentTotalTokens = messagesTokens + toolsTokens
 			a.logger.Info("memory compressor context stats",
 				zap.Int("iteration", i+1),
 				zap.Int("messagesCount", len(messages)),
@@ -530,11 +904,26 @@ func (a *Agent) AgentLoopWithProgress(ctx context.Context, userInput string, his
 				zap.Int("regularMessages", regularCount),
 				zap.Int("messagesTokens", messagesTokens),
 				zap.Int("toolsTokens", toolsTokens),
-				zap.Int("totalTokens", totalTokens),
+				zap.Int("totalTokens", currentTotalTokens),
 				zap.Int("maxTotalTokens", a.memoryCompressor.maxTotalTokens),
 			)
 		}
 
+		// 时间盒自主模式：按墙钟时间/token 预算（而非固定轮数）判断是否该收尾，
+		// 命中预算时提前将本轮标记为最后一次迭代，复用下方固有的"最后一轮强制总结"逻辑，
+		// 从而始终为最终总结预留出 FinalSummaryReserveSeconds 的余量。
+		if budget := a.evaluateAutonomyBudget(runStart, currentTotalTokens); budget.enabled {
+			sendProgress("budget", budget.describe(), map[string]interface{}{
+				"iteration":            i + 1,
+				"timeRemainingSeconds": budget.timeRemainingSecs,
+				"tokenRemaining":       budget.tokenRemaining,
+				"exhausted":            budget.exhausted,
+			})
+			if budget.exhausted {
+				isLastIteration = true
+			}
+		}
+
 		// 发送迭代开始事件
 		if i == 0 {
 			sendProgress("iteration", "开始分析请求并制定测试策略", map[string]interface{}{
@@ -681,12 +1070,13 @@ func (a *Agent) AgentLoopWithProgress(ctx context.Context, userInput string, his
 
 			// 执行所有工具调用
 			for idx, toolCall := range choice.Message.ToolCalls {
-				// 发送工具调用开始事件
-				toolArgsJSON, _ := json.Marshal(toolCall.Function.Arguments)
+				// 发送工具调用开始事件（敏感参数已按工具配置掩码，避免 Cookie/Token 等明文出现在 SSE 事件中）
+				maskedArgs := a.mcpServer.MaskToolArguments(toolCall.Function.Name, toolCall.Function.Arguments)
+				toolArgsJSON, _ := json.Marshal(maskedArgs)
 				sendProgress("tool_call", fmt.Sprintf("正在调用工具: %s", toolCall.Function.Name), map[string]interface{}{
 					"toolName":     toolCall.Function.Name,
 					"arguments":    string(toolArgsJSON),
-					"argumentsObj": toolCall.Function.Arguments,
+					"argumentsObj": maskedArgs,
 					"toolCallId":   toolCall.ID,
 					"index":        idx + 1,
 					"total":        len(choice.Message.ToolCalls),
@@ -735,7 +1125,32 @@ func (a *Agent) AgentLoopWithProgress(ctx context.Context, userInput string, his
 					})
 				}))
 
-				execResult, err := a.executeToolViaMCP(toolCtx, toolCall.Function.Name, execArgs)
+				// 检测连续重复的工具调用（相同工具名 + 相同参数），超过阈值后短路复用上次结果
+				callKey := hashToolCall(toolCall.Function.Name, execArgs)
+				if callKey == dedupState.lastKey {
+					dedupState.repeatCount++
+				} else {
+					dedupState = toolCallDedupState{lastKey: callKey, repeatCount: 1}
+				}
+
+				var execResult *ToolExecutionResult
+				var err error
+				if dedupState.repeatCount > maxConsecutiveDuplicateToolCalls && dedupState.lastResult != nil {
+					a.logger.Warn("检测到重复工具调用，已短路复用上次结果",
+						zap.String("tool", toolCall.Function.Name),
+						zap.Int("repeatCount", dedupState.repeatCount),
+					)
+					execResult = &ToolExecutionResult{
+						Result:      a.formatDuplicateToolCallHint(toolCall.Function.Name, dedupState.repeatCount, dedupState.lastResult.Result),
+						ExecutionID: dedupState.lastResult.ExecutionID,
+						IsError:     dedupState.lastResult.IsError,
+					}
+				} else {
+					execResult, err = a.executeToolViaMCP(toolCtx, toolCall.Function.Name, execArgs)
+					if err == nil {
+						dedupState.lastResult = execResult
+					}
+				}
 				if err != nil {
 					// 构建详细的错误信息，帮助AI理解问题并做出决策
 					errorMsg := a.formatToolError(toolCall.Function.Name, toolCall.Function.Arguments, err)
@@ -807,7 +1222,7 @@ func (a *Agent) AgentLoopWithProgress(ctx context.Context, userInput string, his
 				// 添加用户消息，要求AI进行总结
 				messages = append(messages, ChatMessage{
 					Role:    "user",
-					Content: "这是最后一次迭代。请总结到目前为止的所有测试结果、发现的问题和已完成的工作。如果需要继续测试，请提供详细的下一步执行计划。请直接回复，不要调用工具。",
+					Content: "这是最后一次迭代。请总结到目前为止的所有测试结果、发现的问题和已完成的工作。如果需要继续测试，请提供详细的下一步执行计划。请直接回复，不要调用工具。" + languageInstruction(language),
 				})
 				messages = a.applyMemoryCompression(ctx, messages, 0) // 总结时不带 tools，不预留
 				// 流式调用OpenAI获取总结（不提供工具，强制AI直接回复）
@@ -854,7 +1269,7 @@ func (a *Agent) AgentLoopWithProgress(ctx context.Context, userInput string, his
 			// 添加用户消息，要求AI进行总结
 			messages = append(messages, ChatMessage{
 				Role:    "user",
-				Content: "这是最后一次迭代。请总结到目前为止的所有测试结果、发现的问题和已完成的工作。如果需要继续测试，请提供详细的下一步执行计划。请直接回复，不要调用工具。",
+				Content: "这是最后一次迭代。请总结到目前为止的所有测试结果、发现的问题和已完成的工作。如果需要继续测试，请提供详细的下一步执行计划。请直接回复，不要调用工具。" + languageInstruction(language),
 			})
 			messages = a.applyMemoryCompression(ctx, messages, 0) // 总结时不带 tools，不预留
 			// 流式调用OpenAI获取总结（不提供工具，强制AI直接回复）
@@ -885,8 +1300,27 @@ func (a *Agent) AgentLoopWithProgress(ctx context.Context, userInput string, his
 			break
 		}
 
-		// 如果完成，返回结果
+		// 如果完成，返回结果；启用反思时先做一轮不带工具的自我审查，判定覆盖不足则继续迭代而非直接返回。
 		if choice.FinishReason == "stop" {
+			if a.agentConfig != nil && a.agentConfig.ReflectionMaxRounds > 0 &&
+				reflectionRounds < a.agentConfig.ReflectionMaxRounds && !isLastIteration {
+				verdict := a.reflectOnAnswer(ctx, messages, language)
+				if verdict.ContinueWork {
+					reflectionRounds++
+					sendProgress("reflection", fmt.Sprintf("自我反思认为覆盖尚不完整（第 %d/%d 轮）：%s", reflectionRounds, a.agentConfig.ReflectionMaxRounds, verdict.Note), map[string]interface{}{
+						"round":     reflectionRounds,
+						"maxRounds": a.agentConfig.ReflectionMaxRounds,
+					})
+					followUp := "自我反思认为当前回复还不够完整"
+					if verdict.Note != "" {
+						followUp += "：" + verdict.Note
+					}
+					followUp += "。请继续处理，必要时调用工具补充验证。"
+					messages = append(messages, ChatMessage{Role: "user", Content: followUp + languageInstruction(language)})
+					continue
+				}
+			}
+
 			sendProgress("progress", "正在生成最终回复...", nil)
 			result.Response = choice.Message.Content
 			result.LastAgentTraceOutput = result.Response
@@ -899,7 +1333,7 @@ func (a *Agent) AgentLoopWithProgress(ctx context.Context, userInput string, his
 	sendProgress("progress", "达到最大迭代次数，正在生成总结...", nil)
 	finalSummaryPrompt := ChatMessage{
 		Role:    "user",
-		Content: fmt.Sprintf("已达到最大迭代次数（%d轮）。请总结到目前为止的所有测试结果、发现的问题和已完成的工作。如果需要继续测试，请提供详细的下一步执行计划。请直接回复，不要调用工具。", a.maxIterations),
+		Content: fmt.Sprintf("已达到最大迭代次数（%d轮）。请总结到目前为止的所有测试结果、发现的问题和已完成的工作。如果需要继续测试，请提供详细的下一步执行计划。请直接回复，不要调用工具。", a.maxIterations) + languageInstruction(language),
 	}
 	messages = append(messages, finalSummaryPrompt)
 	messages = a.applyMemoryCompression(ctx, messages, 0) // 总结时不带 tools，不预留
@@ -929,6 +1363,40 @@ func (a *Agent) AgentLoopWithProgress(ctx context.Context, userInput string, his
 	return result, nil
 }
 
+// reflectionVerdict 自我反思调用的解析结果。
+type reflectionVerdict struct {
+	ContinueWork bool
+	Note         string
+}
+
+// reflectOnAnswer 在 finish_reason=stop 后追加一次不带工具的自我审查调用，让模型判断当前回复是否已完整覆盖用户诉求。
+// 调用失败或返回内容无法识别时一律视为通过（不阻塞正常回复），避免反思本身成为新的故障点。
+func (a *Agent) reflectOnAnswer(ctx context.Context, messages []ChatMessage, language string) reflectionVerdict {
+	reflectionMessages := make([]ChatMessage, len(messages), len(messages)+1)
+	copy(reflectionMessages, messages)
+	reflectionMessages = append(reflectionMessages, ChatMessage{
+		Role: "user",
+		Content: "请自我审查你刚才的回复是否已完整覆盖用户诉求（是否遗漏必要的测试项、未验证的假设或明显的后续步骤）。" +
+			"如果已经足够完整，只回复 REFLECTION_OK；如果还需要继续，回复 REFLECTION_CONTINUE: 后跟一句话说明还需要做什么。" +
+			"不要调用工具，只回复这一行。" + languageInstruction(language),
+	})
+	reflectionMessages = a.applyMemoryCompression(ctx, reflectionMessages, 0)
+
+	response, err := a.callOpenAI(ctx, reflectionMessages, []Tool{})
+	if err != nil || response == nil || len(response.Choices) == 0 {
+		return reflectionVerdict{}
+	}
+
+	content := strings.TrimSpace(response.Choices[0].Message.Content)
+	if !strings.HasPrefix(content, "REFLECTION_CONTINUE") {
+		return reflectionVerdict{}
+	}
+
+	note := strings.TrimSpace(strings.TrimPrefix(content, "REFLECTION_CONTINUE"))
+	note = strings.TrimSpace(strings.TrimPrefix(note, ":"))
+	return reflectionVerdict{ContinueWork: true, Note: note}
+}
+
 // getAvailableTools 获取可用工具
 // 从MCP服务器动态获取工具列表，描述模式由 tool_description_mode 控制
 // roleTools: 角色配置的工具列表（toolKey格式），如果为空或nil，则使用所有工具（默认角色）
@@ -958,6 +1426,10 @@ func (a *Agent) getAvailableTools(roleTools []string) []Tool {
 
 		// 转换schema中的类型为OpenAI标准类型
 		convertedSchema := a.convertSchemaTypes(mcpTool.InputSchema)
+		strict := a.strictToolSchemasEnabled()
+		if strict {
+			convertedSchema = makeStrictSchema(convertedSchema)
+		}
 
 		tools = append(tools, Tool{
 			Type: "function",
@@ -965,6 +1437,7 @@ func (a *Agent) getAvailableTools(roleTools []string) []Tool {
 				Name:        mcpTool.Name,
 				Description: description, // 使用简短描述减少token消耗
 				Parameters:  convertedSchema,
+				Strict:      strict,
 			},
 		})
 	}
@@ -1032,6 +1505,10 @@ func (a *Agent) getAvailableTools(roleTools []string) []Tool {
 
 				// 转换schema中的类型为OpenAI标准类型
 				convertedSchema := a.convertSchemaTypes(externalTool.InputSchema)
+				strict := a.strictToolSchemasEnabled()
+				if strict {
+					convertedSchema = makeStrictSchema(convertedSchema)
+				}
 
 				// 将工具名称中的 "::" 替换为 "__" 以符合OpenAI命名规范
 				// OpenAI要求工具名称只能包含 [a-zA-Z0-9_-]
@@ -1046,6 +1523,7 @@ func (a *Agent) getAvailableTools(roleTools []string) []Tool {
 						Name:        openAIName, // 使用符合OpenAI规范的名称
 						Description: description,
 						Parameters:  convertedSchema,
+						Strict:      strict,
 					},
 				})
 			}
@@ -1063,6 +1541,11 @@ func (a *Agent) getAvailableTools(roleTools []string) []Tool {
 	return tools
 }
 
+// strictToolSchemasEnabled 是否为工具启用 OpenAI strict function schema（additionalProperties:false + function.strict）。
+func (a *Agent) strictToolSchemasEnabled() bool {
+	return a.agentConfig != nil && a.agentConfig.StrictToolSchemas
+}
+
 func (a *Agent) pickToolDescription(shortDesc, fullDesc string) string {
 	a.mu.RLock()
 	mode := strings.TrimSpace(strings.ToLower(a.toolDescriptionMode))
@@ -1134,13 +1617,24 @@ func (a *Agent) convertToOpenAIType(configType string) string {
 	}
 }
 
-// isRetryableError 判断错误是否可重试
-func (a *Agent) isRetryableError(err error) bool {
+// isRetryableError 判断错误是否可重试：网络类错误按消息子串匹配；HTTP 错误按 config.RetryConfig.RetryOnStatusCodes 匹配状态码。
+// 返回值中的 statusCode 在命中 *openai.APIError 时为该次响应的状态码，否则为 0（供重试统计按维度归类使用）；
+// retryAfter 为服务端通过 Retry-After 响应头声明的建议等待时长，未提供时为 0。
+func (a *Agent) isRetryableError(err error) (retryable bool, statusCode int, retryAfter time.Duration) {
 	if err == nil {
-		return false
+		return false, 0, 0
 	}
-	errStr := err.Error()
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		if a.retryStatusCodes[apiErr.StatusCode] {
+			return true, apiErr.StatusCode, apiErr.RetryAfter
+		}
+		return false, apiErr.StatusCode, 0
+	}
+
 	// 网络相关错误，可以重试
+	errStr := err.Error()
 	retryableErrors := []string{
 		"connection reset",
 		"connection reset by peer",
@@ -1158,15 +1652,81 @@ func (a *Agent) isRetryableError(err error) bool {
 	}
 	for _, retryable := range retryableErrors {
 		if strings.Contains(strings.ToLower(errStr), retryable) {
-			return true
+			return true, 0, 0
+		}
+	}
+	return false, 0, 0
+}
+
+// retryWaitEventKey 用于在 context 中传递重试等待事件回调（见 withRetryWaitCallback）。
+type retryWaitEventKey struct{}
+
+// RetryWaitEvent 描述一次因 HTTP 429/5xx 触发的重试等待，通过 AgentLoopWithProgress 的
+// "rate_limited" 进度事件下发给前端，用于展示「为什么在等待、还要等多久」。
+type RetryWaitEvent struct {
+	StatusCode    int     `json:"statusCode"`
+	Attempt       int     `json:"attempt"`     // 即将进行的重试次数（从 1 开始）
+	MaxAttempts   int     `json:"maxAttempts"` // 最大尝试次数（含首次调用）
+	WaitSeconds   float64 `json:"waitSeconds"`
+	ViaRetryAfter bool    `json:"viaRetryAfter"` // 等待时长是否来自服务端 Retry-After 响应头（否则为本地指数退避）
+}
+
+// withRetryWaitCallback 在 context 中注入回调，供 callOpenAI* 系列重试逻辑在等待重试前上报 RetryWaitEvent。
+func withRetryWaitCallback(ctx context.Context, fn func(RetryWaitEvent)) context.Context {
+	if fn == nil || ctx == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, retryWaitEventKey{}, fn)
+}
+
+func retryWaitCallbackFromContext(ctx context.Context) func(RetryWaitEvent) {
+	if ctx == nil {
+		return nil
+	}
+	fn, _ := ctx.Value(retryWaitEventKey{}).(func(RetryWaitEvent))
+	return fn
+}
+
+// retryWaitDuration 计算本次重试前的等待时长：服务端返回 Retry-After 时优先使用（但限制在 retryMaxBackoff 的 4 倍以内，
+// 避免个别异常大的值导致长时间挂起）；否则使用带 ±20% 抖动的指数退避，抖动用于避免多个并发请求同时退避后又同时重试（惊群）。
+func (a *Agent) retryWaitDuration(attempt int, retryAfter time.Duration) (wait time.Duration, viaRetryAfter bool) {
+	if retryAfter > 0 {
+		if capDuration := a.retryMaxBackoff * 4; capDuration > 0 && retryAfter > capDuration {
+			retryAfter = capDuration
 		}
+		return retryAfter, true
+	}
+	return a.retryBackoffFor(attempt), false
+}
+
+// recordRetry 累计一次 LLM 调用重试的统计信息，供 /api/agent-loop/llm-retry-stats 上报。
+func (a *Agent) recordRetry(statusCode int) {
+	a.retryStatsMu.Lock()
+	defer a.retryStatsMu.Unlock()
+	a.retryStatsTotal++
+	if a.retryStatsByStatus == nil {
+		a.retryStatsByStatus = make(map[int]int64)
+	}
+	a.retryStatsByStatus[statusCode]++
+}
+
+// GetLLMRetryStats 返回自进程启动以来 LLM 调用的累计重试次数，以及按触发状态码（0 表示网络类错误）拆分的明细。
+func (a *Agent) GetLLMRetryStats() (total int64, byStatus map[int]int64) {
+	a.retryStatsMu.Lock()
+	defer a.retryStatsMu.Unlock()
+	byStatus = make(map[int]int64, len(a.retryStatsByStatus))
+	for code, count := range a.retryStatsByStatus {
+		byStatus[code] = count
 	}
-	return false
+	return a.retryStatsTotal, byStatus
 }
 
 // callOpenAI 调用OpenAI API（带重试机制）
 func (a *Agent) callOpenAI(ctx context.Context, messages []ChatMessage, tools []Tool) (*OpenAIResponse, error) {
-	maxRetries := 3
+	ctx, span := tracing.Tracer().Start(ctx, "agent.callOpenAI")
+	defer span.End()
+
+	maxRetries := a.retryMaxAttempts
 	var lastErr error
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
@@ -1184,30 +1744,15 @@ func (a *Agent) callOpenAI(ctx context.Context, messages []ChatMessage, tools []
 		lastErr = err
 
 		// 如果不是可重试的错误，直接返回
-		if !a.isRetryableError(err) {
+		retryable, statusCode, retryAfter := a.isRetryableError(err)
+		if !retryable {
 			return nil, err
 		}
 
 		// 如果不是最后一次重试，等待后重试
 		if attempt < maxRetries-1 {
-			// 指数退避：2s, 4s, 8s...
-			backoff := time.Duration(1<<uint(attempt+1)) * time.Second
-			if backoff > 30*time.Second {
-				backoff = 30 * time.Second // 最大30秒
-			}
-			a.logger.Warn("OpenAI API调用失败，准备重试",
-				zap.Error(err),
-				zap.Int("attempt", attempt+1),
-				zap.Int("maxRetries", maxRetries),
-				zap.Duration("backoff", backoff),
-			)
-
-			// 检查上下文是否已取消
-			select {
-			case <-ctx.Done():
-				return nil, fmt.Errorf("上下文已取消: %w", ctx.Err())
-			case <-time.After(backoff):
-				// 继续重试
+			if waitErr := a.waitBeforeRetry(ctx, "OpenAI API调用失败，准备重试", err, attempt, maxRetries, statusCode, retryAfter); waitErr != nil {
+				return nil, waitErr
 			}
 		}
 	}
@@ -1215,6 +1760,57 @@ func (a *Agent) callOpenAI(ctx context.Context, messages []ChatMessage, tools []
 	return nil, fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
 }
 
+// waitBeforeRetry 统一处理三个 callOpenAI* 重试入口在准备重试前的公共步骤：计算等待时长（优先 Retry-After）、
+// 累计重试统计、429 时上报 rate_limited 进度事件、记录日志，并阻塞直到等待结束或 ctx 被取消。
+func (a *Agent) waitBeforeRetry(ctx context.Context, logMsg string, err error, attempt, maxRetries, statusCode int, retryAfter time.Duration) error {
+	wait, viaRetryAfter := a.retryWaitDuration(attempt, retryAfter)
+	a.recordRetry(statusCode)
+
+	if statusCode == http.StatusTooManyRequests {
+		if cb := retryWaitCallbackFromContext(ctx); cb != nil {
+			cb(RetryWaitEvent{
+				StatusCode:    statusCode,
+				Attempt:       attempt + 1,
+				MaxAttempts:   maxRetries,
+				WaitSeconds:   wait.Seconds(),
+				ViaRetryAfter: viaRetryAfter,
+			})
+		}
+	}
+
+	a.logger.Warn(logMsg,
+		zap.Error(err),
+		zap.Int("attempt", attempt+1),
+		zap.Int("maxRetries", maxRetries),
+		zap.Int("statusCode", statusCode),
+		zap.Duration("wait", wait),
+		zap.Bool("viaRetryAfter", viaRetryAfter),
+	)
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("上下文已取消: %w", ctx.Err())
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// retryBackoffFor 按配置的基准值/上限计算第 attempt 次重试（从 0 开始）前的指数退避等待时间，并叠加 ±20% 抖动。
+func (a *Agent) retryBackoffFor(attempt int) time.Duration {
+	backoff := a.retryBaseBackoff * time.Duration(1<<uint(attempt+1))
+	if backoff > a.retryMaxBackoff {
+		backoff = a.retryMaxBackoff
+	}
+	jitterRange := int64(backoff) / 5 // ±20%
+	if jitterRange > 0 {
+		backoff += time.Duration(mrand.Int63n(jitterRange*2+1) - jitterRange)
+	}
+	if backoff < 0 {
+		backoff = 0
+	}
+	return backoff
+}
+
 // callOpenAISingle 单次调用OpenAI API（不包含重试逻辑）
 func (a *Agent) callOpenAISingle(ctx context.Context, messages []ChatMessage, tools []Tool) (*OpenAIResponse, error) {
 	reqBody := OpenAIRequest{
@@ -1225,6 +1821,7 @@ func (a *Agent) callOpenAISingle(ctx context.Context, messages []ChatMessage, to
 	if len(tools) > 0 {
 		reqBody.Tools = tools
 	}
+	a.applyToolCallQuirks(&reqBody)
 
 	a.logger.Debug("准备发送OpenAI请求",
 		zap.Int("messagesCount", len(messages)),
@@ -1242,6 +1839,48 @@ func (a *Agent) callOpenAISingle(ctx context.Context, messages []ChatMessage, to
 	return &response, nil
 }
 
+// CreateSamplingCompletion 实现 mcp.SamplingHandler，将外部 MCP 客户端的 sampling/request 转发为一次
+// 不带工具调用的纯文本补全：req.Model 已由 mcp.Server 按 mcp.sampling.model_aliases 映射、req.MaxTokens
+// 已按 mcp.sampling.max_tokens 截断，此处直接透传给 OpenAI 兼容后端；req.Model 为空时使用 Agent 自身配置的模型。
+func (a *Agent) CreateSamplingCompletion(ctx context.Context, req mcp.SamplingRequest) (mcp.SamplingResponse, error) {
+	if a.openAIClient == nil {
+		return mcp.SamplingResponse{}, fmt.Errorf("OpenAI客户端未初始化")
+	}
+
+	messages := make([]ChatMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		messages = append(messages, ChatMessage{Role: m.Role, Content: m.Content})
+	}
+
+	model := req.Model
+	if model == "" {
+		model = a.config.Model
+	}
+
+	reqBody := OpenAIRequest{
+		Model:     model,
+		Messages:  messages,
+		MaxTokens: req.MaxTokens,
+	}
+
+	var response OpenAIResponse
+	if err := a.openAIClient.ChatCompletion(ctx, reqBody, &response); err != nil {
+		return mcp.SamplingResponse{}, err
+	}
+	if len(response.Choices) == 0 {
+		return mcp.SamplingResponse{}, fmt.Errorf("LLM未返回任何选择")
+	}
+
+	choice := response.Choices[0]
+	return mcp.SamplingResponse{
+		Content: []mcp.SamplingContent{
+			{Type: "text", Text: choice.Message.Content},
+		},
+		Model:      model,
+		StopReason: choice.FinishReason,
+	}, nil
+}
+
 // callOpenAISingleStreamText 单次调用OpenAI的流式模式，只用于“不会调用工具”的纯文本输出（tools 为空时最佳）。
 // onDelta 每收到一段 content delta，就回调一次；如果 callback 返回错误，会终止读取并返回错误。
 func (a *Agent) callOpenAISingleStreamText(ctx context.Context, messages []ChatMessage, tools []Tool, onDelta func(delta string) error) (string, error) {
@@ -1253,6 +1892,7 @@ func (a *Agent) callOpenAISingleStreamText(ctx context.Context, messages []ChatM
 	if len(tools) > 0 {
 		reqBody.Tools = tools
 	}
+	a.applyToolCallQuirks(&reqBody)
 
 	if a.openAIClient == nil {
 		return "", fmt.Errorf("OpenAI客户端未初始化")
@@ -1263,7 +1903,7 @@ func (a *Agent) callOpenAISingleStreamText(ctx context.Context, messages []ChatM
 
 // callOpenAIStreamText 调用OpenAI流式模式（带重试），仅在“未输出任何 delta”时才允许重试，避免重复发送已下发的内容。
 func (a *Agent) callOpenAIStreamText(ctx context.Context, messages []ChatMessage, tools []Tool, onDelta func(delta string) error) (string, error) {
-	maxRetries := 3
+	maxRetries := a.retryMaxAttempts
 	var lastErr error
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
@@ -1288,26 +1928,14 @@ func (a *Agent) callOpenAIStreamText(ctx context.Context, messages []ChatMessage
 			return "", err
 		}
 
-		if !a.isRetryableError(err) {
+		retryable, statusCode, retryAfter := a.isRetryableError(err)
+		if !retryable {
 			return "", err
 		}
 
 		if attempt < maxRetries-1 {
-			backoff := time.Duration(1<<uint(attempt+1)) * time.Second
-			if backoff > 30*time.Second {
-				backoff = 30 * time.Second
-			}
-			a.logger.Warn("OpenAI stream 调用失败，准备重试",
-				zap.Error(err),
-				zap.Int("attempt", attempt+1),
-				zap.Int("maxRetries", maxRetries),
-				zap.Duration("backoff", backoff),
-			)
-
-			select {
-			case <-ctx.Done():
-				return "", fmt.Errorf("上下文已取消: %w", ctx.Err())
-			case <-time.After(backoff):
+			if waitErr := a.waitBeforeRetry(ctx, "OpenAI stream 调用失败，准备重试", err, attempt, maxRetries, statusCode, retryAfter); waitErr != nil {
+				return "", waitErr
 			}
 		}
 	}
@@ -1330,6 +1958,7 @@ func (a *Agent) callOpenAISingleStreamWithToolCalls(
 	if len(tools) > 0 {
 		reqBody.Tools = tools
 	}
+	a.applyToolCallQuirks(&reqBody)
 	if a.openAIClient == nil {
 		return nil, fmt.Errorf("OpenAI客户端未初始化")
 	}
@@ -1388,7 +2017,7 @@ func (a *Agent) callOpenAIStreamWithToolCalls(
 	tools []Tool,
 	onContentDelta func(delta string) error,
 ) (*OpenAIResponse, error) {
-	maxRetries := 3
+	maxRetries := a.retryMaxAttempts
 	var lastErr error
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
@@ -1416,25 +2045,13 @@ func (a *Agent) callOpenAIStreamWithToolCalls(
 			return nil, err
 		}
 
-		if !a.isRetryableError(err) {
+		retryable, statusCode, retryAfter := a.isRetryableError(err)
+		if !retryable {
 			return nil, err
 		}
 		if attempt < maxRetries-1 {
-			backoff := time.Duration(1<<uint(attempt+1)) * time.Second
-			if backoff > 30*time.Second {
-				backoff = 30 * time.Second
-			}
-			a.logger.Warn("OpenAI stream 调用失败，准备重试",
-				zap.Error(err),
-				zap.Int("attempt", attempt+1),
-				zap.Int("maxRetries", maxRetries),
-				zap.Duration("backoff", backoff),
-			)
-
-			select {
-			case <-ctx.Done():
-				return nil, fmt.Errorf("上下文已取消: %w", ctx.Err())
-			case <-time.After(backoff):
+			if waitErr := a.waitBeforeRetry(ctx, "OpenAI stream 调用失败，准备重试", err, attempt, maxRetries, statusCode, retryAfter); waitErr != nil {
+				return nil, waitErr
 			}
 		}
 	}
@@ -1457,15 +2074,15 @@ func (a *Agent) executeToolViaMCP(ctx context.Context, toolName string, args map
 		zap.Any("args", args),
 	)
 
+	conversationID := agentConversationIDFromContext(ctx)
+	if conversationID == "" {
+		a.mu.RLock()
+		conversationID = a.currentConversationID
+		a.mu.RUnlock()
+	}
+
 	// 如果是record_vulnerability工具，自动添加conversation_id
 	if toolName == builtin.ToolRecordVulnerability {
-		conversationID := agentConversationIDFromContext(ctx)
-		if conversationID == "" {
-			a.mu.RLock()
-			conversationID = a.currentConversationID
-			a.mu.RUnlock()
-		}
-
 		if conversationID != "" {
 			args["conversation_id"] = conversationID
 			a.logger.Debug("自动添加conversation_id到record_vulnerability工具",
@@ -1476,6 +2093,17 @@ func (a *Agent) executeToolViaMCP(ctx context.Context, toolName string, args map
 		}
 	}
 
+	// 模型未显式传入 profile 参数时，套用当前角色为该工具配置的默认扫描档位（见 WithDefaultToolProfiles）
+	if _, hasProfile := args["profile"]; !hasProfile {
+		if defaultProfile := defaultToolProfileFromContext(ctx, toolName); defaultProfile != "" {
+			args["profile"] = defaultProfile
+			a.logger.Debug("套用角色默认扫描档位",
+				zap.String("tool", toolName),
+				zap.String("profile", defaultProfile),
+			)
+		}
+	}
+
 	var result *mcp.ToolResult
 	var executionID string
 	var err error
@@ -1493,12 +2121,33 @@ func (a *Agent) executeToolViaMCP(ctx context.Context, toolName string, args map
 	}
 	// C2 危险任务 HITL 异步等待：须绑定整条 Agent 运行期 ctx，而非单次工具子 ctx（return 时会被 cancel）
 	toolCtx = c2.WithHITLRunContext(toolCtx, ctx)
+	if conversationID != "" {
+		// 供 security.Executor 做目标范围校验（见 internal/scope），不依赖工具自身参数
+		toolCtx = mcp.WithConversationID(toolCtx, conversationID)
+	}
 
 	// 检查是否是外部MCP工具（通过工具名称映射）
 	a.mu.RLock()
 	originalToolName, isExternalTool := a.toolNameMapping[toolName]
 	a.mu.RUnlock()
 
+	// 执行前按 InputSchema 校验参数（仅内置工具，外部 MCP schema 需异步拉取，不在此处校验）。
+	// 校验失败时不执行工具，直接把机读错误回传给模型，让其自我纠正后重试。
+	if !isExternalTool {
+		if toolDef, ok := a.mcpServer.GetToolDef(toolName); ok {
+			if valErrs := validateToolArguments(toolDef.InputSchema, args); len(valErrs) > 0 {
+				a.logger.Warn("工具参数校验失败，跳过执行",
+					zap.String("tool", toolName),
+					zap.Any("errors", valErrs),
+				)
+				return &ToolExecutionResult{
+					Result:  formatValidationErrorForModel(toolName, valErrs),
+					IsError: true,
+				}, nil
+			}
+		}
+	}
+
 	if isExternalTool && a.externalMCPMgr != nil {
 		// 使用原始工具名称调用外部MCP工具
 		a.logger.Debug("调用外部MCP工具",
@@ -1590,6 +2239,28 @@ func (a *Agent) executeToolViaMCP(ctx context.Context, toolName string, args map
 		}
 		notification := a.formatMinimalNotification(executionID, toolName, resultSize, len(lines), filePath)
 
+		// 如果配置了摘要模型，用更便宜的次级模型对超大结果生成摘要，与 execution ID 指针一起返回给主模型，
+		// 避免主模型只拿到"结果已保存"这一句空话而无法立即推进任务。
+		a.mu.RLock()
+		summarizerModel := ""
+		if a.config != nil {
+			summarizerModel = a.config.SummarizerModel
+		}
+		a.mu.RUnlock()
+
+		if summarizerModel != "" {
+			summary, err := a.summarizeLargeResult(ctx, summarizerModel, toolName, resultStr)
+			if err != nil {
+				a.logger.Warn("摘要大结果失败，回退为最小化通知",
+					zap.String("executionID", executionID),
+					zap.String("toolName", toolName),
+					zap.Error(err),
+				)
+			} else {
+				notification = a.formatSummarizedNotification(executionID, toolName, resultSize, len(lines), filePath, summary)
+			}
+		}
+
 		return &ToolExecutionResult{
 			Result:      notification,
 			ExecutionID: executionID,
@@ -1604,6 +2275,64 @@ func (a *Agent) executeToolViaMCP(ctx context.Context, toolName string, args map
 	}, nil
 }
 
+// maxSummarizerInputChars 送入摘要模型的原始结果最大字符数，避免超大结果本身撑爆次级模型的上下文
+const maxSummarizerInputChars = 60000
+
+// summarizeLargeResult 用配置的次级（更便宜）模型对超大工具结果生成摘要，供主模型在不加载完整结果的情况下继续推理。
+// 仅用于摘要，不影响 resultStorage 中保存的原始结果，原文始终可通过 execution ID 完整查询。
+func (a *Agent) summarizeLargeResult(ctx context.Context, summarizerModel, toolName, resultStr string) (string, error) {
+	if a.openAIClient == nil {
+		return "", fmt.Errorf("OpenAI客户端未初始化")
+	}
+
+	truncated := resultStr
+	if len(truncated) > maxSummarizerInputChars {
+		truncated = truncated[:maxSummarizerInputChars] + "\n...(已截断)"
+	}
+
+	prompt := fmt.Sprintf(
+		"以下是工具 %s 的执行结果（可能已截断），请用简洁的要点总结其中的关键信息（如发现的漏洞、开放端口、异常状态等），忽略无关的冗余输出：\n\n%s",
+		toolName, truncated,
+	)
+
+	reqBody := OpenAIRequest{
+		Model: summarizerModel,
+		Messages: []ChatMessage{
+			{Role: "user", Content: prompt},
+		},
+	}
+
+	var response OpenAIResponse
+	if err := a.openAIClient.ChatCompletion(ctx, reqBody, &response); err != nil {
+		return "", err
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("摘要模型未返回结果")
+	}
+
+	summary := strings.TrimSpace(response.Choices[0].Message.Content)
+	if summary == "" {
+		return "", fmt.Errorf("摘要模型返回空内容")
+	}
+	return summary, nil
+}
+
+// formatSummarizedNotification 在最小化通知的基础上附加次级模型生成的摘要，
+// 使主模型无需查询 execution ID 即可获得关键信息，仍保留完整结果的查询方式。
+func (a *Agent) formatSummarizedNotification(executionID, toolName string, size, lineCount int, filePath, summary string) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("工具执行完成，结果已保存（ID: %s），以下是自动生成的摘要：\n\n", executionID))
+	sb.WriteString(summary)
+	sb.WriteString("\n\n")
+	sb.WriteString(fmt.Sprintf("原始结果：%d 字节 (%.2f KB)，%d 行。如需查看完整内容或摘要未覆盖的细节，可使用 query_execution_result 工具查询（execution_id=\"%s\"）。\n", size, float64(size)/1024, lineCount, executionID))
+	if filePath != "" {
+		sb.WriteString(fmt.Sprintf("原始结果文件路径：%s\n", filePath))
+	}
+
+	return sb.String()
+}
+
 // formatMinimalNotification 格式化最小化通知
 func (a *Agent) formatMinimalNotification(executionID string, toolName string, size int, lineCount int, filePath string) string {
 	var sb strings.Builder
@@ -1622,6 +2351,7 @@ func (a *Agent) formatMinimalNotification(executionID string, toolName string, s
 	sb.WriteString(fmt.Sprintf("  - 搜索关键词: query_execution_result(execution_id=\"%s\", search=\"关键词\")\n", executionID))
 	sb.WriteString(fmt.Sprintf("  - 过滤条件: query_execution_result(execution_id=\"%s\", filter=\"error\")\n", executionID))
 	sb.WriteString(fmt.Sprintf("  - 正则匹配: query_execution_result(execution_id=\"%s\", search=\"\\\\d+\\\\.\\\\d+\\\\.\\\\d+\\\\.\\\\d+\", use_regex=true)\n", executionID))
+	sb.WriteString(fmt.Sprintf("  - 批量提取: query_execution_result(execution_id=\"%s\", extract=\"(\\\\d+\\\\.\\\\d+\\\\.\\\\d+\\\\.\\\\d+)\", max_matches=200)\n", executionID))
 	sb.WriteString("\n")
 	if filePath != "" {
 		sb.WriteString("如果 query_execution_result 工具不满足需求，也可以使用其他工具处理文件：\n")
@@ -1696,6 +2426,8 @@ func (a *Agent) UpdateToolDescriptionMode(mode string) {
 
 // formatToolError 格式化工具错误信息，提供更友好的错误描述
 func (a *Agent) formatToolError(toolName string, args map[string]interface{}, err error) string {
+	// 敏感参数（如 Cookie、Token）已按工具配置掩码，避免明文写入对话记录/日志
+	maskedArgs := a.mcpServer.MaskToolArguments(toolName, args)
 	errorMsg := fmt.Sprintf(`工具执行失败
 
 工具名称: %s
@@ -1706,7 +2438,7 @@ func (a *Agent) formatToolError(toolName string, args map[string]interface{}, er
 1. 如果参数错误，请修正参数后重试
 2. 如果工具不可用，请尝试使用替代工具
 3. 如果这是系统问题，请向用户说明情况并提供建议
-4. 如果错误信息中包含有用信息，可以基于这些信息继续分析`, toolName, args, err)
+4. 如果错误信息中包含有用信息，可以基于这些信息继续分析`, toolName, maskedArgs, err)
 
 	return errorMsg
 }
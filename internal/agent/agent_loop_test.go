@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"cyberstrike-ai/internal/config"
+	"cyberstrike-ai/internal/mcp"
+
+	"go.uber.org/zap"
+)
+
+// fakeTokenCounter 是一个不发起任何网络请求的 TokenCounter 桩实现，用估算字节长度代替真实的
+// tiktoken 编码，避免 TikTokenCounter 在 model 名未命中时回退到 tiktoken.GetEncoding 发起的真实
+// HTTP 请求（对不存在的 "test-model" 总会未命中）。
+type fakeTokenCounter struct{}
+
+func (fakeTokenCounter) Count(model, text string) (int, error) {
+	return len(text) / 4, nil
+}
+
+// setupAgentLoopTestAgent 创建一个指向假 LLM 服务器的 Agent，用于端到端驱动 AgentLoopWithProgress，
+// 全程不发起真实网络请求。
+func setupAgentLoopTestAgent(t *testing.T, turns []fakeLLMTurn, maxIterations int) (*Agent, *mcp.Server, *fakeLLMServer) {
+	t.Helper()
+	logger := zap.NewNop()
+	mcpServer := mcp.NewServer(logger)
+	server, fakeLLM := newFakeLLMServer(t, turns)
+
+	openAICfg := &config.OpenAIConfig{
+		APIKey:  "test-key",
+		BaseURL: server.URL,
+		Model:   "test-model",
+	}
+	agentCfg := &config.AgentConfig{
+		MaxIterations:        maxIterations,
+		LargeResultThreshold: 100,
+	}
+
+	agent := NewAgent(openAICfg, agentCfg, mcpServer, nil, logger, maxIterations)
+	if agent.memoryCompressor != nil {
+		agent.memoryCompressor.tokenCounter = fakeTokenCounter{}
+	}
+	return agent, mcpServer, fakeLLM
+}
+
+func registerFakeTool(mcpServer *mcp.Server, name string, handler mcp.ToolHandler) {
+	mcpServer.RegisterTool(mcp.Tool{
+		Name:        name,
+		Description: "测试用工具",
+		InputSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		},
+	}, handler)
+}
+
+func TestAgentLoop_ToolCallSuccess(t *testing.T) {
+	turns := []fakeLLMTurn{
+		{toolCalls: []fakeToolCall{{name: "scan_tool", args: map[string]interface{}{"target": "1.2.3.4"}}}},
+		{content: "扫描完成，未发现异常。", finishReason: "stop"},
+	}
+	agent, mcpServer, fakeLLM := setupAgentLoopTestAgent(t, turns, 10)
+
+	registerFakeTool(mcpServer, "scan_tool", func(ctx context.Context, args map[string]interface{}) (*mcp.ToolResult, error) {
+		return &mcp.ToolResult{Content: []mcp.Content{{Type: "text", Text: "端口 80/443 开放"}}}, nil
+	})
+
+	result, err := agent.AgentLoop(context.Background(), "扫描一下目标", nil)
+	if err != nil {
+		t.Fatalf("AgentLoop 返回错误: %v", err)
+	}
+	if !strings.Contains(result.Response, "扫描完成") {
+		t.Errorf("最终回复应包含模型总结内容，实际: %q", result.Response)
+	}
+	if fakeLLM.requestCount() != 2 {
+		t.Errorf("预期调用假 LLM 服务器 2 次（工具调用 + 最终回复），实际 %d 次", fakeLLM.requestCount())
+	}
+}
+
+func TestAgentLoop_ToolCallFailure(t *testing.T) {
+	turns := []fakeLLMTurn{
+		{toolCalls: []fakeToolCall{{name: "broken_tool", args: map[string]interface{}{}}}},
+		{content: "工具执行失败，已记录错误。", finishReason: "stop"},
+	}
+	agent, mcpServer, _ := setupAgentLoopTestAgent(t, turns, 10)
+
+	registerFakeTool(mcpServer, "broken_tool", func(ctx context.Context, args map[string]interface{}) (*mcp.ToolResult, error) {
+		return nil, fmt.Errorf("模拟工具执行失败")
+	})
+
+	result, err := agent.AgentLoop(context.Background(), "运行一个会失败的工具", nil)
+	if err != nil {
+		t.Fatalf("AgentLoop 不应因工具失败而返回错误: %v", err)
+	}
+	if !strings.Contains(result.Response, "工具执行失败") {
+		t.Errorf("最终回复应体现模型已感知工具失败，实际: %q", result.Response)
+	}
+}
+
+func TestAgentLoop_LargeResultOffloaded(t *testing.T) {
+	turns := []fakeLLMTurn{
+		{toolCalls: []fakeToolCall{{name: "dump_tool", args: map[string]interface{}{}}}},
+		{content: "已查看摘要，任务完成。", finishReason: "stop"},
+	}
+	agent, mcpServer, _ := setupAgentLoopTestAgent(t, turns, 10)
+
+	largeOutput := strings.Repeat("line of scan output\n", 1000) // 远超测试阈值(100字节)
+	registerFakeTool(mcpServer, "dump_tool", func(ctx context.Context, args map[string]interface{}) (*mcp.ToolResult, error) {
+		return &mcp.ToolResult{Content: []mcp.Content{{Type: "text", Text: largeOutput}}}, nil
+	})
+
+	result, err := agent.AgentLoop(context.Background(), "输出大量结果", nil)
+	if err != nil {
+		t.Fatalf("AgentLoop 返回错误: %v", err)
+	}
+	if len(result.MCPExecutionIDs) == 0 {
+		t.Fatalf("大结果场景应记录 MCP execution ID")
+	}
+	if !strings.Contains(result.Response, "任务完成") {
+		t.Errorf("最终回复应正常完成，实际: %q", result.Response)
+	}
+}
+
+func TestAgentLoop_CancellationStopsLoop(t *testing.T) {
+	turns := []fakeLLMTurn{
+		{toolCalls: []fakeToolCall{{name: "slow_tool", args: map[string]interface{}{}}}},
+	}
+	agent, mcpServer, _ := setupAgentLoopTestAgent(t, turns, 10)
+
+	toolStarted := make(chan struct{})
+	registerFakeTool(mcpServer, "slow_tool", func(ctx context.Context, args map[string]interface{}) (*mcp.ToolResult, error) {
+		close(toolStarted)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := agent.AgentLoop(ctx, "运行一个耗时任务", nil)
+		errCh <- err
+	}()
+
+	select {
+	case <-toolStarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待工具开始执行超时")
+	}
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Fatalf("取消后 AgentLoop 应返回错误")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待 AgentLoop 因取消而退出超时")
+	}
+}
+
+func TestAgentLoop_MaxIterationsReached(t *testing.T) {
+	// 模型每次都要求调用工具，永不返回 finish_reason=stop，触发最大迭代次数逻辑。
+	turns := []fakeLLMTurn{
+		{toolCalls: []fakeToolCall{{name: "loop_tool", args: map[string]interface{}{}}}},
+	}
+	agent, mcpServer, _ := setupAgentLoopTestAgent(t, turns, 2)
+
+	registerFakeTool(mcpServer, "loop_tool", func(ctx context.Context, args map[string]interface{}) (*mcp.ToolResult, error) {
+		return &mcp.ToolResult{Content: []mcp.Content{{Type: "text", Text: "ok"}}}, nil
+	})
+
+	result, err := agent.AgentLoop(context.Background(), "反复调用工具", nil)
+	if err != nil {
+		t.Fatalf("达到最大迭代次数不应返回错误: %v", err)
+	}
+	if !strings.Contains(result.Response, "最大迭代次数") {
+		t.Errorf("应提示已达到最大迭代次数，实际: %q", result.Response)
+	}
+}
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -79,6 +80,30 @@ func TestAgent_FormatMinimalNotification(t *testing.T) {
 	}
 }
 
+func TestAgent_FormatSummarizedNotification(t *testing.T) {
+	agent, testStorage := setupTestAgent(t)
+	_ = testStorage
+
+	executionID := "test_exec_002"
+	toolName := "nmap_scan"
+	size := 80000
+	lineCount := 2000
+	filePath := "tmp/test_exec_002.txt"
+	summary := "发现开放端口 22、80、443，未发现明显漏洞。"
+
+	notification := agent.formatSummarizedNotification(executionID, toolName, size, lineCount, filePath, summary)
+
+	if !strings.Contains(notification, executionID) {
+		t.Errorf("通知中应该包含执行ID: %s", executionID)
+	}
+	if !strings.Contains(notification, summary) {
+		t.Errorf("通知中应该包含摘要内容")
+	}
+	if !strings.Contains(notification, "query_execution_result") {
+		t.Errorf("通知中应该包含查询工具的使用说明")
+	}
+}
+
 func TestAgent_ExecuteToolViaMCP_LargeResult(t *testing.T) {
 	agent, _ := setupTestAgent(t)
 
@@ -283,3 +308,167 @@ func TestAgent_NewAgent_CustomConfig(t *testing.T) {
 		t.Errorf("阈值不匹配。期望: %d, 实际: %d", 100*1024, threshold)
 	}
 }
+
+func TestAgent_ResolveLanguage_ContextOverridesConfigDefault(t *testing.T) {
+	agent, _ := setupTestAgent(t)
+	agent.agentConfig.Language = "en"
+
+	if got := agent.resolveLanguage(context.Background()); got != "en" {
+		t.Errorf("未设置 context 覆盖时应回退到配置默认值。期望: en, 实际: %s", got)
+	}
+
+	ctx := WithLanguage(context.Background(), "ja")
+	if got := agent.resolveLanguage(ctx); got != "ja" {
+		t.Errorf("context 中的语言应覆盖配置默认值。期望: ja, 实际: %s", got)
+	}
+}
+
+func TestDefaultToolProfileFromContext(t *testing.T) {
+	if got := defaultToolProfileFromContext(context.Background(), "nmap"); got != "" {
+		t.Errorf("未注入时应返回空字符串，实际: %s", got)
+	}
+
+	ctx := WithDefaultToolProfiles(context.Background(), map[string]string{"nmap": "quick"})
+	if got := defaultToolProfileFromContext(ctx, "nmap"); got != "quick" {
+		t.Errorf("应返回注入的默认档位。期望: quick, 实际: %s", got)
+	}
+	if got := defaultToolProfileFromContext(ctx, "nuclei"); got != "" {
+		t.Errorf("未配置默认档位的工具应返回空字符串，实际: %s", got)
+	}
+
+	if got := WithDefaultToolProfiles(context.Background(), nil); got != context.Background() {
+		t.Error("空map时WithDefaultToolProfiles不应包装context")
+	}
+}
+
+func TestAgent_EstimateRequest_WithoutPricingConfigured(t *testing.T) {
+	agent, _ := setupTestAgent(t)
+
+	est := agent.EstimateRequest("scan example.com for open ports", nil)
+	if est == nil {
+		t.Fatal("EstimateRequest 不应返回 nil")
+	}
+	if est.PricingConfigured {
+		t.Errorf("未配置定价时 PricingConfigured 应为 false")
+	}
+	if est.MaxIterations != agent.maxIterations {
+		t.Errorf("MaxIterations 应与 agent.maxIterations 一致。期望: %d, 实际: %d", agent.maxIterations, est.MaxIterations)
+	}
+}
+
+func TestAgent_EstimateRequest_WithPricingConfigured(t *testing.T) {
+	agent, _ := setupTestAgent(t)
+	agent.config.PriceInputPerMillion = 2.0
+	agent.config.PriceOutputPerMillion = 8.0
+
+	est := agent.EstimateRequest("scan example.com for open ports", nil)
+	if !est.PricingConfigured {
+		t.Errorf("配置定价后 PricingConfigured 应为 true")
+	}
+	if est.MaxCostUSD < est.MinCostUSD {
+		t.Errorf("费用上界不应小于下界。min: %f, max: %f", est.MinCostUSD, est.MaxCostUSD)
+	}
+}
+
+func TestAgent_LanguageInstruction(t *testing.T) {
+	if got := languageInstruction("zh"); got != "" {
+		t.Errorf("中文默认不应追加语言提示，实际: %q", got)
+	}
+	if got := languageInstruction(""); got != "" {
+		t.Errorf("空语言不应追加语言提示，实际: %q", got)
+	}
+	if got := languageInstruction("en"); !strings.Contains(got, "English") {
+		t.Errorf("英文应追加 English 提示，实际: %q", got)
+	}
+	if got := languageInstruction("fr"); !strings.Contains(got, "fr") {
+		t.Errorf("其他语言应原样带入提示，实际: %q", got)
+	}
+}
+
+// fakeCheckpointSaver 用于测试 AgentLoopResumeFromCheckpoint 的错误分支，不依赖真实数据库
+type fakeCheckpointSaver struct {
+	messagesJSON  string
+	iteration     int
+	roleToolsJSON string
+	found         bool
+	getErr        error
+}
+
+func (f *fakeCheckpointSaver) SaveCheckpoint(conversationID, messagesJSON string, iteration int, roleToolsJSON string) error {
+	return nil
+}
+
+func (f *fakeCheckpointSaver) GetCheckpoint(conversationID string) (string, int, string, bool, error) {
+	return f.messagesJSON, f.iteration, f.roleToolsJSON, f.found, f.getErr
+}
+
+func (f *fakeCheckpointSaver) ClearCheckpoint(conversationID string) error {
+	return nil
+}
+
+func TestAgent_AgentLoopResumeFromCheckpoint_NoSaverConfigured(t *testing.T) {
+	agent, _ := setupTestAgent(t)
+
+	_, err := agent.AgentLoopResumeFromCheckpoint(context.Background(), "conv-1", nil)
+	if err == nil {
+		t.Fatal("未配置检查点存储时应返回 error")
+	}
+}
+
+func TestAgent_AgentLoopResumeFromCheckpoint_CheckpointNotFound(t *testing.T) {
+	agent, _ := setupTestAgent(t)
+	agent.SetCheckpointSaver(&fakeCheckpointSaver{found: false})
+
+	_, err := agent.AgentLoopResumeFromCheckpoint(context.Background(), "conv-1", nil)
+	if err == nil {
+		t.Fatal("检查点不存在时应返回 error")
+	}
+}
+
+func TestAgent_AgentLoopResumeFromCheckpoint_InvalidMessagesJSON(t *testing.T) {
+	agent, _ := setupTestAgent(t)
+	agent.SetCheckpointSaver(&fakeCheckpointSaver{found: true, messagesJSON: "not-json", iteration: 2})
+
+	_, err := agent.AgentLoopResumeFromCheckpoint(context.Background(), "conv-1", nil)
+	if err == nil {
+		t.Fatal("检查点消息无法解析时应返回 error")
+	}
+}
+
+func TestHashToolCall_SameToolAndArgsProduceSameHash(t *testing.T) {
+	args := map[string]interface{}{"target": "1.2.3.4", "port": float64(80)}
+	h1 := hashToolCall("nmap_scan", args)
+	h2 := hashToolCall("nmap_scan", map[string]interface{}{"port": float64(80), "target": "1.2.3.4"})
+	if h1 != h2 {
+		t.Errorf("相同工具名与参数（不同 key 顺序）应得到相同哈希，实际 %q != %q", h1, h2)
+	}
+}
+
+func TestHashToolCall_DifferentArgsProduceDifferentHash(t *testing.T) {
+	h1 := hashToolCall("nmap_scan", map[string]interface{}{"target": "1.2.3.4"})
+	h2 := hashToolCall("nmap_scan", map[string]interface{}{"target": "5.6.7.8"})
+	if h1 == h2 {
+		t.Error("不同参数不应得到相同哈希")
+	}
+}
+
+func TestHashToolCall_DifferentToolNameProducesDifferentHash(t *testing.T) {
+	args := map[string]interface{}{"target": "1.2.3.4"}
+	h1 := hashToolCall("nmap_scan", args)
+	h2 := hashToolCall("masscan_scan", args)
+	if h1 == h2 {
+		t.Error("不同工具名不应得到相同哈希")
+	}
+}
+
+func TestAgent_FormatDuplicateToolCallHint(t *testing.T) {
+	agent, _ := setupTestAgent(t)
+
+	hint := agent.formatDuplicateToolCallHint("nmap_scan", 4, "上一次的扫描结果")
+	if !strings.Contains(hint, "nmap_scan") {
+		t.Errorf("提示信息应包含工具名，实际: %s", hint)
+	}
+	if !strings.Contains(hint, "上一次的扫描结果") {
+		t.Errorf("提示信息应包含上一次的执行结果，实际: %s", hint)
+	}
+}
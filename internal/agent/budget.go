@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+)
+
+// autonomyBudgetStatus 时间盒自主模式下某一轮迭代的预算快照，由 evaluateAutonomyBudget 计算。
+type autonomyBudgetStatus struct {
+	enabled           bool    // AgentConfig.TimeBudgetMinutes / TokenBudget 至少一项 > 0
+	exhausted         bool    // 任一预算已逼近上限，应提前进入最终总结轮
+	timeRemainingSecs float64 // 剩余墙钟时间（秒），未启用时间预算时为 -1
+	tokenRemaining    int     // 剩余 token 预算，未启用 token 预算时为 -1
+	note              string  // 命中预算时的人类可读说明，用于进度事件
+}
+
+// describe 返回预算状态的简要描述，供 sendProgress 展示给前端；未命中预算时给出中性的剩余量提示。
+func (b autonomyBudgetStatus) describe() string {
+	if b.note != "" {
+		return b.note
+	}
+	parts := make([]string, 0, 2)
+	if b.timeRemainingSecs >= 0 {
+		parts = append(parts, fmt.Sprintf("剩余时间约 %.0f 秒", b.timeRemainingSecs))
+	}
+	if b.tokenRemaining >= 0 {
+		parts = append(parts, fmt.Sprintf("剩余 token 预算约 %d", b.tokenRemaining))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "时间盒自主模式：" + parts[0] + func() string {
+		if len(parts) > 1 {
+			return "，" + parts[1]
+		}
+		return ""
+	}()
+}
+
+// evaluateAutonomyBudget 计算时间盒自主模式（AgentConfig.TimeBudgetMinutes / TokenBudget）的剩余预算，
+// 并判断是否应提前收尾：始终为最终总结预留 FinalSummaryReserveSeconds 秒（默认 60），token 预算则预留其 5%
+// 作为总结与收尾工具结果的余量。两项均为 0 时视为未启用，调用方应继续按 MaxIterations 固定轮数运行。
+func (a *Agent) evaluateAutonomyBudget(runStart time.Time, currentTotalTokens int) autonomyBudgetStatus {
+	status := autonomyBudgetStatus{timeRemainingSecs: -1, tokenRemaining: -1}
+	if a.agentConfig == nil || (a.agentConfig.TimeBudgetMinutes <= 0 && a.agentConfig.TokenBudget <= 0) {
+		return status
+	}
+	status.enabled = true
+
+	reserveSeconds := a.agentConfig.FinalSummaryReserveSeconds
+	if reserveSeconds <= 0 {
+		reserveSeconds = 60
+	}
+	reserve := time.Duration(reserveSeconds) * time.Second
+
+	if a.agentConfig.TimeBudgetMinutes > 0 {
+		budget := time.Duration(a.agentConfig.TimeBudgetMinutes) * time.Minute
+		remaining := budget - time.Since(runStart)
+		status.timeRemainingSecs = remaining.Seconds()
+		if remaining <= reserve {
+			status.exhausted = true
+			status.note = fmt.Sprintf("时间预算即将耗尽（剩余约 %.0f 秒，已为总结预留 %d 秒），提前进入最终总结轮", remaining.Seconds(), reserveSeconds)
+		}
+	}
+
+	if a.agentConfig.TokenBudget > 0 {
+		remainingTokens := a.agentConfig.TokenBudget - currentTotalTokens
+		status.tokenRemaining = remainingTokens
+		tokenReserve := a.agentConfig.TokenBudget / 20 // 预留约 5% 作为总结与收尾工具结果的余量
+		if remainingTokens <= tokenReserve {
+			status.exhausted = true
+			if status.note != "" {
+				status.note += "；"
+			}
+			status.note += fmt.Sprintf("token 预算即将耗尽（当前上下文约 %d，预算 %d），提前进入最终总结轮", currentTotalTokens, a.agentConfig.TokenBudget)
+		}
+	}
+
+	return status
+}
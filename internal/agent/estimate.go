@@ -0,0 +1,64 @@
+package agent
+
+import "encoding/json"
+
+// CostEstimate 干跑（dry-run）预估结果：不调用 LLM，只统计首轮迭代会消耗的 Token 与可能的费用区间。
+type CostEstimate struct {
+	InputTokens          int      `json:"inputTokens"`          // system+user 消息 token 数
+	ToolsTokens          int      `json:"toolsTokens"`          // 本次会暴露给模型的工具定义 token 数
+	FirstIterationTokens int      `json:"firstIterationTokens"` // 首轮请求 = InputTokens + ToolsTokens
+	ToolNames            []string `json:"toolNames"`            // 本次会暴露给模型的工具名称列表
+	MaxIterations        int      `json:"maxIterations"`        // 该 Agent 配置的最大迭代次数，用于估算费用上界
+	PricingConfigured    bool     `json:"pricingConfigured"`    // config.yaml openai.price_* 是否已配置
+	MinCostUSD           float64  `json:"minCostUsd"`           // 下界：仅首轮输入，不含任何工具调用或输出
+	MaxCostUSD           float64  `json:"maxCostUsd"`           // 上界：假设每轮都重发首轮体量的上下文并持续到最大迭代次数
+}
+
+// EstimateRequest 在不调用 LLM 的情况下，预估一次 /api/agent-loop 请求的首轮 Token 消耗、
+// 将暴露的工具列表，以及基于 config.yaml 定价的费用区间，便于用户在启动昂贵的批量任务前先行核算。
+// roleTools 与 AgentLoopWithProgress 的角色工具过滤保持一致（为空表示不限制角色，使用全部已启用工具）。
+func (a *Agent) EstimateRequest(userInput string, roleTools []string) *CostEstimate {
+	systemPrompt := a.EinoSingleAgentSystemInstruction()
+	messages := []ChatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: userInput},
+	}
+
+	tools := a.getAvailableTools(roleTools)
+	toolNames := make([]string, 0, len(tools))
+	for _, t := range tools {
+		toolNames = append(toolNames, t.Function.Name)
+	}
+	toolsTokens := a.countToolsTokens(tools)
+
+	inputTokens := 0
+	if a.memoryCompressor != nil {
+		if data, err := json.Marshal(messages); err == nil {
+			inputTokens = a.memoryCompressor.CountTextTokens(string(data))
+		}
+	}
+
+	est := &CostEstimate{
+		InputTokens:          inputTokens,
+		ToolsTokens:          toolsTokens,
+		FirstIterationTokens: inputTokens + toolsTokens,
+		ToolNames:            toolNames,
+		MaxIterations:        a.maxIterations,
+	}
+
+	if a.config != nil && (a.config.PriceInputPerMillion > 0 || a.config.PriceOutputPerMillion > 0) {
+		est.PricingConfigured = true
+		first := float64(est.FirstIterationTokens)
+		est.MinCostUSD = first / 1_000_000 * a.config.PriceInputPerMillion
+
+		// 上界为悲观估算：假设每一轮都重新发送首轮体量的上下文（含累积历史与工具结果），
+		// 并持续到配置的最大迭代次数，每轮再假设产生约 500 输出 token。
+		const assumedOutputTokensPerIteration = 500
+		iterations := float64(a.maxIterations)
+		worstInputCost := first * iterations / 1_000_000 * a.config.PriceInputPerMillion
+		worstOutputCost := assumedOutputTokensPerIteration * iterations / 1_000_000 * a.config.PriceOutputPerMillion
+		est.MaxCostUSD = worstInputCost + worstOutputCost
+	}
+
+	return est
+}
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fakeToolCall 描述一次由假 LLM 服务器下发的工具调用（脚本化场景）。
+type fakeToolCall struct {
+	name string
+	args map[string]interface{}
+}
+
+// fakeLLMTurn 描述假 LLM 服务器对一次 /chat/completions 请求的响应：
+// 要么下发一个或多个工具调用（finishReason = "tool_calls"），要么直接返回文本（finishReason = "stop"）。
+type fakeLLMTurn struct {
+	content      string
+	toolCalls    []fakeToolCall
+	finishReason string // "stop" | "tool_calls"
+}
+
+// fakeLLMServer 是一个可脚本化的、兼容 OpenAI /chat/completions 流式接口的假服务器，
+// 用于在不发起真实网络请求的情况下驱动 AgentLoopWithProgress 完整跑通。
+// 每次收到请求就按顺序弹出一个 fakeLLMTurn；脚本耗尽后重复最后一个 turn（用于模拟"模型一直调用工具"的最大迭代场景）。
+type fakeLLMServer struct {
+	mu       sync.Mutex
+	turns    []fakeLLMTurn
+	callSeq  int
+	requests int
+}
+
+// newFakeLLMServer 启动假 LLM 服务器并在测试结束时自动关闭。
+func newFakeLLMServer(t *testing.T, turns []fakeLLMTurn) (*httptest.Server, *fakeLLMServer) {
+	t.Helper()
+	f := &fakeLLMServer{turns: turns}
+	server := httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(server.Close)
+	return server, f
+}
+
+func (f *fakeLLMServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	idx := f.callSeq
+	f.callSeq++
+	f.requests++
+	turn := f.turns[len(f.turns)-1]
+	if idx < len(f.turns) {
+		turn = f.turns[idx]
+	}
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	writeChunk := func(chunk map[string]interface{}) {
+		b, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", b)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if turn.content != "" {
+		writeChunk(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"delta": map[string]interface{}{"content": turn.content}},
+			},
+		})
+	}
+
+	for i, tc := range turn.toolCalls {
+		argsJSON, _ := json.Marshal(tc.args)
+		writeChunk(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{
+					"delta": map[string]interface{}{
+						"tool_calls": []map[string]interface{}{
+							{
+								"index": i,
+								"id":    fmt.Sprintf("call_%d_%d", idx, i),
+								"type":  "function",
+								"function": map[string]interface{}{
+									"name":      tc.name,
+									"arguments": string(argsJSON),
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	finishReason := turn.finishReason
+	if finishReason == "" {
+		if len(turn.toolCalls) > 0 {
+			finishReason = "tool_calls"
+		} else {
+			finishReason = "stop"
+		}
+	}
+	writeChunk(map[string]interface{}{
+		"choices": []map[string]interface{}{
+			{"delta": map[string]interface{}{}, "finish_reason": finishReason},
+		},
+	})
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// requestCount 返回假服务器目前收到的请求总数，供测试断言迭代/重试次数。
+func (f *fakeLLMServer) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
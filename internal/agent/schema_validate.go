@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// ToolArgValidationError 描述单个参数校验失败项，Field/Reason 均为机读字段，便于模型据此自我纠正。
+type ToolArgValidationError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// validateToolArguments 按 InputSchema（JSON Schema 子集：required/type/enum）校验模型生成的参数。
+// 只做「明显错误」的拦截（缺失必填、类型不符、枚举越界），不做过度严格的校验，避免误伤合法但schema描述不精确的调用。
+// schema 为 nil 时视为无需校验；未声明 properties 时只校验 required。
+func validateToolArguments(schema map[string]interface{}, args map[string]interface{}) []ToolArgValidationError {
+	if schema == nil {
+		return nil
+	}
+
+	var errs []ToolArgValidationError
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if name == "" {
+				continue
+			}
+			if v, present := args[name]; !present || v == nil {
+				errs = append(errs, ToolArgValidationError{Field: name, Reason: "缺少必填参数"})
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	if properties == nil {
+		return errs
+	}
+
+	for name, value := range args {
+		propRaw, ok := properties[name]
+		if !ok {
+			continue // 未在 schema 中声明的字段交由 strict 模式（additionalProperties）处理，这里不重复报错
+		}
+		prop, ok := propRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if enumRaw, ok := prop["enum"].([]interface{}); ok && len(enumRaw) > 0 {
+			matched := false
+			for _, e := range enumRaw {
+				if fmt.Sprintf("%v", e) == fmt.Sprintf("%v", value) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				errs = append(errs, ToolArgValidationError{Field: name, Reason: fmt.Sprintf("值不在允许的枚举范围内: %v", enumRaw)})
+			}
+		}
+
+		if typeStr, ok := prop["type"].(string); ok {
+			if !jsonValueMatchesType(value, typeStr) {
+				errs = append(errs, ToolArgValidationError{Field: name, Reason: fmt.Sprintf("类型不匹配，期望 %s，实际 %T", typeStr, value)})
+			}
+		}
+	}
+
+	return errs
+}
+
+// jsonValueMatchesType 校验解析后的 JSON 值是否符合 JSON Schema 的基础类型（string/number/integer/boolean/array/object）。
+func jsonValueMatchesType(value interface{}, typ string) bool {
+	switch typ {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		switch value.(type) {
+		case float64, int, int64:
+			return true
+		}
+		return false
+	case "integer":
+		switch v := value.(type) {
+		case float64:
+			return v == math.Trunc(v)
+		case int, int64:
+			return true
+		}
+		return false
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	default:
+		return true // 未知或未收窄的类型（如 openapi 扩展类型）不拦截
+	}
+}
+
+// formatValidationErrorForModel 将校验失败转换为可回传给 tool 消息的结构化 JSON，供模型据此自我纠正后重试调用。
+func formatValidationErrorForModel(toolName string, errs []ToolArgValidationError) string {
+	payload := map[string]interface{}{
+		"error":   "tool_argument_validation_failed",
+		"tool":    toolName,
+		"details": errs,
+		"hint":    "请修正上述字段后重新调用该工具，不要猜测缺失的必填参数。",
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Sprintf(`{"error":"tool_argument_validation_failed","tool":%q}`, toolName)
+	}
+	return string(b)
+}
+
+// makeStrictSchema 递归地为 JSON Schema 补充 additionalProperties:false，用于 OpenAI strict function schema。
+// 不改变入参，返回一份深拷贝，避免影响 MCP 内部持有的原始 schema。
+func makeStrictSchema(schema map[string]interface{}) map[string]interface{} {
+	if schema == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(schema)+1)
+	for k, v := range schema {
+		out[k] = v
+	}
+
+	if typeStr, _ := out["type"].(string); typeStr == "object" || out["properties"] != nil {
+		out["additionalProperties"] = false
+	}
+
+	if properties, ok := out["properties"].(map[string]interface{}); ok {
+		convertedProps := make(map[string]interface{}, len(properties))
+		for name, propRaw := range properties {
+			if prop, ok := propRaw.(map[string]interface{}); ok {
+				convertedProps[name] = makeStrictSchema(prop)
+			} else {
+				convertedProps[name] = propRaw
+			}
+		}
+		out["properties"] = convertedProps
+	}
+
+	if items, ok := out["items"].(map[string]interface{}); ok {
+		out["items"] = makeStrictSchema(items)
+	}
+
+	return out
+}
@@ -0,0 +1,84 @@
+package agent
+
+import "testing"
+
+func TestValidateToolArguments_MissingRequired(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"target"},
+		"properties": map[string]interface{}{
+			"target": map[string]interface{}{"type": "string"},
+		},
+	}
+
+	errs := validateToolArguments(schema, map[string]interface{}{})
+	if len(errs) != 1 || errs[0].Field != "target" {
+		t.Errorf("期望缺少必填参数 target，实际: %+v", errs)
+	}
+}
+
+func TestValidateToolArguments_TypeMismatch(t *testing.T) {
+	schema := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"port": map[string]interface{}{"type": "number"},
+		},
+	}
+
+	errs := validateToolArguments(schema, map[string]interface{}{"port": "not-a-number"})
+	if len(errs) != 1 || errs[0].Field != "port" {
+		t.Errorf("期望 port 类型不匹配，实际: %+v", errs)
+	}
+}
+
+func TestValidateToolArguments_EnumViolation(t *testing.T) {
+	schema := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"mode": map[string]interface{}{"type": "string", "enum": []interface{}{"quick", "deep"}},
+		},
+	}
+
+	errs := validateToolArguments(schema, map[string]interface{}{"mode": "unknown"})
+	if len(errs) != 1 || errs[0].Field != "mode" {
+		t.Errorf("期望 mode 枚举越界，实际: %+v", errs)
+	}
+}
+
+func TestValidateToolArguments_ValidArgsPass(t *testing.T) {
+	schema := map[string]interface{}{
+		"required": []interface{}{"target"},
+		"properties": map[string]interface{}{
+			"target": map[string]interface{}{"type": "string"},
+			"port":   map[string]interface{}{"type": "number"},
+		},
+	}
+
+	errs := validateToolArguments(schema, map[string]interface{}{"target": "example.com", "port": float64(443)})
+	if len(errs) != 0 {
+		t.Errorf("合法参数不应报错，实际: %+v", errs)
+	}
+}
+
+func TestMakeStrictSchema_AddsAdditionalPropertiesRecursively(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"nested": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"a": map[string]interface{}{"type": "string"}},
+			},
+		},
+	}
+
+	strict := makeStrictSchema(schema)
+	if strict["additionalProperties"] != false {
+		t.Errorf("顶层应设置 additionalProperties:false")
+	}
+	nested, ok := strict["properties"].(map[string]interface{})["nested"].(map[string]interface{})
+	if !ok || nested["additionalProperties"] != false {
+		t.Errorf("嵌套 object 也应设置 additionalProperties:false，实际: %+v", nested)
+	}
+	// 原始 schema 不应被修改
+	if _, tampered := schema["additionalProperties"]; tampered {
+		t.Errorf("makeStrictSchema 不应修改原始 schema")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/subtle"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -16,19 +17,28 @@ import (
 	"cyberstrike-ai/internal/c2"
 	"cyberstrike-ai/internal/config"
 	"cyberstrike-ai/internal/database"
+	"cyberstrike-ai/internal/defectdojo"
 	"cyberstrike-ai/internal/handler"
+	"cyberstrike-ai/internal/jira"
 	"cyberstrike-ai/internal/knowledge"
 	"cyberstrike-ai/internal/logger"
 	"cyberstrike-ai/internal/mcp"
 	"cyberstrike-ai/internal/mcp/builtin"
+	"cyberstrike-ai/internal/memory"
+	"cyberstrike-ai/internal/proxy"
 	"cyberstrike-ai/internal/robot"
+	"cyberstrike-ai/internal/scope"
 	"cyberstrike-ai/internal/security"
 	"cyberstrike-ai/internal/skillpackage"
 	"cyberstrike-ai/internal/storage"
+	"cyberstrike-ai/internal/tracing"
+	"cyberstrike-ai/internal/webhook"
+	"cyberstrike-ai/internal/worker"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 // App 应用
@@ -43,19 +53,20 @@ type App struct {
 	db                 *database.DB
 	knowledgeDB        *database.DB // 知识库数据库连接（如果使用独立数据库）
 	auth               *security.AuthManager
-	knowledgeManager   *knowledge.Manager        // 知识库管理器（用于动态初始化）
-	knowledgeRetriever *knowledge.Retriever      // 知识库检索器（用于动态初始化）
-	knowledgeIndexer   *knowledge.Indexer        // 知识库索引器（用于动态初始化）
-	knowledgeHandler   *handler.KnowledgeHandler // 知识库处理器（用于动态初始化）
-	agentHandler       *handler.AgentHandler     // Agent处理器（用于更新知识库管理器）
-	robotHandler       *handler.RobotHandler     // 机器人处理器（钉钉/飞书/企业微信）
-	robotMu            sync.Mutex                // 保护钉钉/飞书长连接的 cancel
-	dingCancel         context.CancelFunc        // 钉钉 Stream 取消函数，用于配置变更时重启
-	larkCancel         context.CancelFunc        // 飞书长连接取消函数，用于配置变更时重启
-	c2Manager          *c2.Manager               // C2 管理器（未启用 C2 时为 nil）
-	c2Watchdog         *c2.SessionWatchdog       // C2 会话看门狗
-	c2WatchdogCancel   context.CancelFunc        // 看门狗取消函数
-	c2Handler          *handler.C2Handler        // C2 REST（与 Manager 生命周期同步）
+	knowledgeManager   *knowledge.Manager          // 知识库管理器（用于动态初始化）
+	knowledgeRetriever *knowledge.Retriever        // 知识库检索器（用于动态初始化）
+	knowledgeIndexer   *knowledge.Indexer          // 知识库索引器（用于动态初始化）
+	knowledgeHandler   *handler.KnowledgeHandler   // 知识库处理器（用于动态初始化）
+	agentHandler       *handler.AgentHandler       // Agent处理器（用于更新知识库管理器）
+	robotHandler       *handler.RobotHandler       // 机器人处理器（钉钉/飞书/企业微信）
+	robotMu            sync.Mutex                  // 保护钉钉/飞书长连接的 cancel
+	dingCancel         context.CancelFunc          // 钉钉 Stream 取消函数，用于配置变更时重启
+	larkCancel         context.CancelFunc          // 飞书长连接取消函数，用于配置变更时重启
+	c2Manager          *c2.Manager                 // C2 管理器（未启用 C2 时为 nil）
+	c2Watchdog         *c2.SessionWatchdog         // C2 会话看门狗
+	c2WatchdogCancel   context.CancelFunc          // 看门狗取消函数
+	c2Handler          *handler.C2Handler          // C2 REST（与 Manager 生命周期同步）
+	tracingShutdown    func(context.Context) error // 关闭 OpenTelemetry TracerProvider，见 internal/tracing
 }
 
 // New 创建新应用
@@ -64,41 +75,101 @@ func New(cfg *config.Config, log *logger.Logger) (*App, error) {
 	router := gin.Default()
 
 	// CORS中间件
-	router.Use(corsMiddleware())
+	router.Use(corsMiddleware(cfg.Server.CORS))
 
 	// 认证管理器
-	authManager, err := security.NewAuthManager(cfg.Auth.Password, cfg.Auth.SessionDurationHours)
+	authManager, err := security.NewAuthManager(cfg.Auth.Password, cfg.Auth.SessionDurationHours, cfg.Auth.MaxLoginAttempts, cfg.Auth.LockoutBaseSeconds, cfg.Auth.LockoutMaxSeconds)
 	if err != nil {
 		return nil, fmt.Errorf("初始化认证失败: %w", err)
 	}
 
 	// 初始化数据库
-	dbPath := cfg.Database.Path
-	if dbPath == "" {
-		dbPath = "data/conversations.db"
-	}
-
-	// 确保目录存在
-	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
-		return nil, fmt.Errorf("创建数据库目录失败: %w", err)
+	dbCfg := cfg.Database
+	if dbCfg.Driver == "" || dbCfg.Driver == "sqlite" || dbCfg.Driver == "sqlite3" {
+		if dbCfg.Path == "" {
+			dbCfg.Path = "data/conversations.db"
+		}
+		// 确保目录存在（仅本地 SQLite 文件需要）
+		if err := os.MkdirAll(filepath.Dir(dbCfg.Path), 0755); err != nil {
+			return nil, fmt.Errorf("创建数据库目录失败: %w", err)
+		}
 	}
 
-	db, err := database.NewDB(dbPath, log.Logger)
+	db, err := database.NewDB(dbCfg, log.Logger)
 	if err != nil {
 		return nil, fmt.Errorf("初始化数据库失败: %w", err)
 	}
+	if cfg.Database.SlowQueryThresholdMs > 0 {
+		db.SetSlowQueryThreshold(time.Duration(cfg.Database.SlowQueryThresholdMs) * time.Millisecond)
+	}
+	if cfg.Audit.SyslogAddr != "" {
+		db.SetAuditForwarder(database.NewSyslogForwarder(cfg.Audit.SyslogNetwork, cfg.Audit.SyslogAddr, cfg.Audit.SyslogTag, log.Logger))
+	}
+
+	// 启动定时数据库备份后台任务（未配置 backup.dir 时任务自行跳过）
+	backupJob := database.NewBackupJob(db, log.Logger, cfg.Backup.Dir, cfg.Backup.IntervalHours, cfg.Backup.RetentionCount)
+	go backupJob.Run(context.Background())
 
 	// 创建MCP服务器（带数据库持久化）
 	mcpServer := mcp.NewServerWithStorage(log.Logger, db)
+	if cfg.Security.DefaultToolTimeoutMinutes > 0 {
+		mcpServer.SetDefaultToolTimeout(time.Duration(cfg.Security.DefaultToolTimeoutMinutes) * time.Minute)
+	}
+	mcpServer.SetMaxExecutionsInMemory(cfg.Security.MaxExecutionsInMemory)
+	if len(cfg.MCP.AuthTokens) > 0 {
+		authTokens := make([]mcp.AuthToken, 0, len(cfg.MCP.AuthTokens))
+		for _, t := range cfg.MCP.AuthTokens {
+			authTokens = append(authTokens, mcp.AuthToken{Token: t.Token, Scope: t.Scope})
+		}
+		mcpServer.SetAuthTokens(authTokens)
+	}
 
 	// 创建安全工具执行器
 	executor := security.NewExecutor(&cfg.Security, mcpServer, log.Logger)
 
+	// 启动健康检查：探测每个工具的 Command 是否存在于 PATH，自动禁用宿主机上缺失的工具，
+	// 避免 Agent 的工具列表里出现一个调用即失败的工具（见 GET /api/config/tools/health 的按需检查）
+	healthCtx, healthCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	executor.RunStartupHealthCheckAndDisable(healthCtx)
+	healthCancel()
+
 	// 注册工具
 	executor.RegisterTools(mcpServer)
 
+	// 配置 CVE/NVD 自动富化：漏洞记录（无论来自人工API、record_vulnerability工具还是LLM提取）标题/描述/
+	// 证明中出现的 CVE 编号会异步查询 NVD 详情（描述/CVSS/CWE/是否已被CISA KEV收录）回填，见
+	// handler.CVEEnrichmentTrigger；未启用 cfg.NVD.Enabled 时 cveEnrichmentTrigger 的 client 为 nil，
+	// TriggerAsync 直接跳过，行为与未修改前一致。
+	var cveEnrichmentTrigger *handler.CVEEnrichmentTrigger
+	if cfg.NVD.Enabled {
+		cacheTTLMinutes := cfg.NVD.CacheTTLMinutes
+		if cacheTTLMinutes <= 0 {
+			cacheTTLMinutes = 1440
+		}
+		nvdClient := security.NewNVDClient(cfg.NVD.BaseURL, cfg.NVD.APIKey, &http.Client{Timeout: 15 * time.Second})
+		cveEnrichmentTrigger = handler.NewCVEEnrichmentTrigger(nvdClient, security.NewCVECache(time.Duration(cacheTTLMinutes)*time.Minute), log.Logger)
+	} else {
+		cveEnrichmentTrigger = handler.NewCVEEnrichmentTrigger(nil, nil, log.Logger)
+	}
+
+	// 配置事件驱动的 outbound webhook 通知（任务完成/失败、发现漏洞），见 handler.WebhookTrigger；
+	// 未启用 cfg.Webhook.Enabled 时 endpoints 为空，TriggerAsync 直接跳过。
+	var webhookTrigger *handler.WebhookTrigger
+	if cfg.Webhook.Enabled {
+		webhookTrigger = handler.NewWebhookTrigger(webhook.NewClient(nil), cfg.Webhook.Endpoints, log.Logger)
+	} else {
+		webhookTrigger = handler.NewWebhookTrigger(nil, nil, log.Logger)
+	}
+
 	// 注册漏洞记录工具
-	registerVulnerabilityTool(mcpServer, db, log.Logger)
+	registerVulnerabilityTool(mcpServer, db, log.Logger, cveEnrichmentTrigger)
+
+	// 创建长期代理记忆存储（复用会话数据库）并注册记忆写入工具
+	memoryStore := memory.NewStore(db.DB, log.Logger)
+	registerMemoryTool(mcpServer, memoryStore, log.Logger)
+
+	// 注册资产台账查询工具
+	registerAssetTool(mcpServer, db, log.Logger)
 
 	if cfg.Auth.GeneratedPassword != "" {
 		config.PrintGeneratedPasswordWarning(cfg.Auth.GeneratedPassword, cfg.Auth.GeneratedPasswordPersisted, cfg.Auth.GeneratedPasswordPersistErr)
@@ -109,6 +180,7 @@ func New(cfg *config.Config, log *logger.Logger) (*App, error) {
 
 	// 创建外部MCP管理器（使用与内部MCP服务器相同的存储）
 	externalMCPMgr := mcp.NewExternalMCPManagerWithStorage(log.Logger, db)
+	externalMCPMgr.SetMaxExecutionsInMemory(cfg.Security.MaxExecutionsInMemory)
 	if cfg.ExternalMCP.Servers != nil {
 		externalMCPMgr.LoadConfigs(&cfg.ExternalMCP)
 		// 启动所有启用的外部MCP客户端
@@ -126,12 +198,38 @@ func New(cfg *config.Config, log *logger.Logger) (*App, error) {
 		return nil, fmt.Errorf("创建结果存储目录失败: %w", err)
 	}
 
-	// 创建结果存储实例
-	resultStorage, err := storage.NewFileResultStorage(resultStorageDir, log.Logger)
+	// 创建结果存储实例（默认本地文件系统，可通过 result_storage_backend 切换到 S3 兼容对象存储）
+	resultStorage, err := storage.NewResultStorage(resultStorageDir, log.Logger, cfg.Agent.ResultStorageBackend)
 	if err != nil {
 		return nil, fmt.Errorf("初始化结果存储失败: %w", err)
 	}
 
+	// 启动结果存储的保留策略/压缩清理后台任务（保留天数/总容量上限/压缩阈值均未配置时任务自行跳过）
+	retentionJob := storage.NewRetentionJob(
+		resultStorage,
+		log.Logger,
+		cfg.Agent.ResultRetentionDays,
+		int64(cfg.Agent.ResultMaxTotalSizeMB)*1024*1024,
+		int64(cfg.Agent.ResultCompressThresholdKB)*1024,
+	)
+	go retentionJob.Run(context.Background())
+
+	// 初始化产出文件存储（见 config.ToolConfig.OutputArtifacts）
+	artifactStorageDir := "tmp/artifacts"
+	if cfg.Agent.ArtifactStorageDir != "" {
+		artifactStorageDir = cfg.Agent.ArtifactStorageDir
+	}
+	artifactStorage, err := storage.NewFileArtifactStorage(artifactStorageDir, log.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("初始化产出文件存储失败: %w", err)
+	}
+
+	// 提前构建 LLM 漏洞提取客户端（须在 agent 包被下方局部变量遮蔽前完成包级调用）
+	var vulnExtractionClient *agent.OpenAICompletionClient
+	if strings.TrimSpace(cfg.OpenAI.VulnExtractionModel) != "" {
+		vulnExtractionClient = agent.NewOpenAICompletionClient(&cfg.OpenAI, &http.Client{Timeout: 2 * time.Minute}, log.Logger)
+	}
+
 	// 创建Agent
 	maxIterations := cfg.Agent.MaxIterations
 	if maxIterations <= 0 {
@@ -143,9 +241,45 @@ func New(cfg *config.Config, log *logger.Logger) (*App, error) {
 	// 设置结果存储到Agent
 	agent.SetResultStorage(resultStorage)
 
+	// 设置检查点存储，用于服务重启后通过 POST /api/agent-loop/resume 续跑被中断的任务
+	agent.SetCheckpointSaver(db)
+
+	if cfg.MCP.Sampling.Enabled {
+		mcpServer.SetSamplingHandler(agent)
+		mcpServer.SetSamplingLimits(cfg.MCP.Sampling.MaxTokens, cfg.MCP.Sampling.ModelAliases)
+	}
+
 	// 设置结果存储到Executor（用于查询工具）
 	executor.SetResultStorage(resultStorage)
 
+	// 设置产出文件存储到Executor，配置了 OutputArtifacts 的工具将获得按执行ID命名的暂存目录
+	executor.SetArtifactStorage(artifactStorage)
+
+	// 创建远程Worker管理器，供配置了 RemoteExec 的工具分派到匹配 Label/Region 的Worker执行
+	workerManager := worker.NewManager(log.Logger)
+	executor.SetWorkerManager(workerManager)
+
+	// 创建目标范围校验引擎，供各对话/项目配置允许的CIDR/域名/URL模式与拒绝列表
+	scopeEngine := scope.NewEngine()
+	executor.SetScopeEngine(scopeEngine)
+
+	// 创建代理路由配置引擎，供各对话配置 Burp/SOCKS 代理，注入到支持代理的工具执行环境/命令行
+	proxyEngine := proxy.NewEngine()
+	executor.SetProxyEngine(proxyEngine)
+
+	// 配置 LLM 漏洞提取管线：自动从工具原始输出中提取标准化漏洞记录（严重程度/受影响组件/证据/修复建议）
+	// 并写入漏洞库，替代此前完全依赖 Agent 自行判断是否调用 record_vulnerability 的方式；
+	// 未配置 openai.vuln_extraction_model 时不启用，行为与未修改前一致。
+	var llmVulnSink *handler.LLMVulnerabilitySink
+	if vulnExtractionClient != nil {
+		executor.SetVulnerabilityExtractor(vulnExtractionClient, cfg.OpenAI.VulnExtractionModel)
+		llmVulnSink = handler.NewLLMVulnerabilitySink(db, log.Logger, cveEnrichmentTrigger, webhookTrigger)
+		executor.SetVulnerabilitySink(llmVulnSink)
+	}
+
+	// 配置结构化解析结果（nmap/httpx 等）自动落库到资产台账，见 internal/database/asset.go
+	executor.SetAssetSink(db)
+
 	// 初始化知识库模块（如果启用）
 	var knowledgeManager *knowledge.Manager
 	var knowledgeRetriever *knowledge.Retriever
@@ -202,8 +336,14 @@ func New(cfg *config.Config, log *logger.Logger) (*App, error) {
 			SimilarityThreshold: cfg.Knowledge.Retrieval.SimilarityThreshold,
 			SubIndexFilter:      cfg.Knowledge.Retrieval.SubIndexFilter,
 			PostRetrieve:        cfg.Knowledge.Retrieval.PostRetrieve,
+			HybridSearch:        cfg.Knowledge.Retrieval.HybridSearchEffective(),
 		}
 		knowledgeRetriever = knowledge.NewRetriever(knowledgeDB, embedder, retrievalConfig, log.Logger)
+		if vs, vsErr := knowledge.NewVectorStore(knowledgeDB, &cfg.Knowledge.VectorStore); vsErr != nil {
+			log.Logger.Warn("初始化向量存储后端失败，检索将回退到内置 SQLite", zap.Error(vsErr))
+		} else {
+			knowledgeRetriever.SetVectorStore(vs)
+		}
 
 		// 创建索引器（Eino Compose 链）
 		knowledgeIndexer, err = knowledge.NewIndexer(context.Background(), knowledgeDB, embedder, log.Logger, &cfg.Knowledge)
@@ -288,6 +428,22 @@ func New(cfg *config.Config, log *logger.Logger) (*App, error) {
 				log.Logger.Warn("重建知识库索引失败", zap.Error(err))
 			}
 		}()
+
+		// 启动知识库目录监听：文件新增/修改/删除后自动增量扫描+索引，不再依赖手动点击"扫描"
+		// 或重启才能发现变更
+		knowledgeWatcher := knowledge.NewWatcher(knowledgeManager, knowledgeIndexer, log.Logger, 0)
+		go func() {
+			if err := knowledgeWatcher.Run(context.Background()); err != nil {
+				log.Logger.Warn("知识库目录监听已退出", zap.Error(err))
+			}
+		}()
+
+		// 定时拉取配置的 CVE/安全公告 feed（NVD、厂商公告、Nuclei templates changelog 等），
+		// 写入 advisories 分类并自动索引，使 Agent 能拿到较新的漏洞情报
+		if cfg.Knowledge.Feeds.Enabled && len(cfg.Knowledge.Feeds.Feeds) > 0 {
+			feedIngester := knowledge.NewFeedIngester(knowledgeManager, knowledgeIndexer, log.Logger, cfg.Knowledge.Feeds)
+			go feedIngester.Run(context.Background())
+		}
 	}
 
 	// 获取配置文件路径
@@ -316,33 +472,85 @@ func New(cfg *config.Config, log *logger.Logger) (*App, error) {
 
 	// 创建处理器
 	agentHandler := handler.NewAgentHandler(agent, db, cfg, log.Logger)
+	if llmVulnSink != nil {
+		llmVulnSink.SetChainNotifier(agentHandler)
+	}
 	agentHandler.SetAgentsMarkdownDir(agentsDir)
+	agentHandler.SetMemoryStore(memoryStore)
 	// 如果知识库已启用，设置知识库管理器到AgentHandler以便记录检索日志
 	if knowledgeManager != nil {
 		agentHandler.SetKnowledgeManager(knowledgeManager)
 	}
 	monitorHandler := handler.NewMonitorHandler(mcpServer, executor, db, log.Logger)
 	monitorHandler.SetExternalMCPManager(externalMCPMgr) // 设置外部MCP管理器，以便获取外部MCP执行记录
+	monitorHandler.SetArtifactStorage(artifactStorage)   // 设置产出文件存储，以便提供 /monitor/execution/:id/artifacts
+	monitorHandler.SetAgentTaskCanceller(agentHandler)   // 设置Agent任务取消器，以便 EmergencyStop 一并取消所有运行中的Agent任务
 	notificationHandler := handler.NewNotificationHandler(db, agentHandler, log.Logger)
 	groupHandler := handler.NewGroupHandler(db, log.Logger)
-	authHandler := handler.NewAuthHandler(authManager, cfg, configPath, log.Logger)
+	projectHandler := handler.NewProjectHandler(db, log.Logger)
+	assetHandler := handler.NewAssetHandler(db, log.Logger)
+	authHandler := handler.NewAuthHandler(authManager, db, cfg, configPath, log.Logger)
+	auditHandler := handler.NewAuditHandler(db, log.Logger)
+	if cfg.OIDC.Enabled {
+		authHandler.SetOIDCProvider(security.NewOIDCProvider(&cfg.OIDC, log.Logger))
+	}
 	attackChainHandler := handler.NewAttackChainHandler(db, &cfg.OpenAI, log.Logger)
-	vulnerabilityHandler := handler.NewVulnerabilityHandler(db, log.Logger)
+	vulnerabilityHandler := handler.NewVulnerabilityHandler(db, log.Logger, cveEnrichmentTrigger, webhookTrigger)
+	agentHandler.SetWebhookTrigger(webhookTrigger)
+	var defectDojoClient *defectdojo.Client
+	if cfg.DefectDojo.Enabled && cfg.DefectDojo.BaseURL != "" {
+		defectDojoClient = defectdojo.NewClient(cfg.DefectDojo.BaseURL, cfg.DefectDojo.APIKey, &http.Client{Timeout: 30 * time.Second})
+	}
+	defectDojoHandler := handler.NewDefectDojoHandler(db, log.Logger, defectDojoClient, cfg.DefectDojo.EngagementID, cfg.DefectDojo.ScanType)
+	if cfg.DefectDojo.PushOnCompletion {
+		agentHandler.SetDefectDojoTrigger(handler.NewDefectDojoTrigger(defectDojoClient, cfg.DefectDojo.EngagementID, cfg.DefectDojo.ScanType, log.Logger))
+	}
+	var jiraClient *jira.Client
+	if cfg.Jira.Enabled && cfg.Jira.BaseURL != "" {
+		jiraClient = jira.NewClient(cfg.Jira.BaseURL, cfg.Jira.Email, cfg.Jira.APIToken, &http.Client{Timeout: 30 * time.Second})
+	}
+	jiraHandler := handler.NewJiraHandler(db, log.Logger, jiraClient, cfg.Jira.ProjectKey, cfg.Jira.IssueType, cfg.Jira.FieldMapping)
+	reportHandler := handler.NewReportHandler(db, &cfg.OpenAI, cfg, configPath, log.Logger)
+	reportTemplateHandler := handler.NewReportTemplateHandler(cfg, configPath, log.Logger)
 	webshellHandler := handler.NewWebShellHandler(log.Logger, db)
 	chatUploadsHandler := handler.NewChatUploadsHandler(log.Logger)
 	registerWebshellTools(mcpServer, db, webshellHandler, log.Logger)
 	registerWebshellManagementTools(mcpServer, db, webshellHandler, log.Logger)
 	configHandler := handler.NewConfigHandler(configPath, cfg, mcpServer, executor, agent, attackChainHandler, externalMCPMgr, log.Logger)
+	configHandler.SetDB(db)
 	agentHandler.SetHitlToolWhitelistSaver(configHandler)
 	externalMCPHandler := handler.NewExternalMCPHandler(externalMCPMgr, cfg, configPath, log.Logger)
+	externalMCPHandler.SetDB(db)
 	roleHandler := handler.NewRoleHandler(cfg, configPath, log.Logger)
 	skillsHandler := handler.NewSkillsHandler(cfg, configPath, log.Logger)
+	promptsHandler := handler.NewPromptsHandler(cfg, configPath, mcpServer, log.Logger)
+	workerHandler := handler.NewWorkerHandler(workerManager, log.Logger)
+	scopeHandler := handler.NewScopeHandler(scopeEngine, log.Logger)
+	proxyHandler := handler.NewProxyHandler(proxyEngine, log.Logger)
 	fofaHandler := handler.NewFofaHandler(cfg, log.Logger)
+	agentHandler.SetFofaHandler(fofaHandler)
 	terminalHandler := handler.NewTerminalHandler(log.Logger)
 	if db != nil {
 		skillsHandler.SetDB(db) // 设置数据库连接以便获取调用统计
 	}
 
+	// 加载 PromptsDir 下的 YAML 提示词模板并注册为 MCP prompts（目录不存在时静默跳过）
+	promptsDir := cfg.PromptsDir
+	if promptsDir == "" {
+		promptsDir = "prompts"
+	}
+	if !filepath.IsAbs(promptsDir) {
+		promptsDir = filepath.Join(configDir, promptsDir)
+	}
+	loadedPrompts, promptLoadErrs := mcp.LoadPromptsFromDir(promptsDir)
+	for _, err := range promptLoadErrs {
+		log.Logger.Warn("加载提示词模板失败", zap.Error(err))
+	}
+	for i := range loadedPrompts {
+		mcpServer.RegisterPrompt(&loadedPrompts[i])
+	}
+	log.Logger.Info("提示词模板目录", zap.String("promptsDir", promptsDir), zap.Int("loaded", len(loadedPrompts)))
+
 	// ============================================================================
 	// 初始化 C2 模块（可按配置关闭，节省本机部署资源）
 	// ============================================================================
@@ -354,9 +562,18 @@ func New(cfg *config.Config, log *logger.Logger) (*App, error) {
 
 	// 创建OpenAPI处理器
 	conversationHandler := handler.NewConversationHandler(db, log.Logger)
+	backupHandler := handler.NewBackupHandler(db, &cfg.Backup, log.Logger)
 	robotHandler := handler.NewRobotHandler(cfg, db, agentHandler, log.Logger)
 	openAPIHandler := handler.NewOpenAPIHandler(db, log.Logger, resultStorage, conversationHandler, agentHandler)
 
+	// 初始化 OpenTelemetry 追踪：未启用 cfg.Tracing.Enabled 时 tracingShutdown 为空操作，
+	// AgentLoop/callOpenAI/mcp.Server.CallTool/security.Executor.ExecuteTool 中的 span 调用零开销。
+	tracingShutdown, err := tracing.Init(context.Background(), cfg.Tracing, log.Logger)
+	if err != nil {
+		log.Logger.Warn("初始化 OpenTelemetry 追踪失败，继续以未启用追踪的方式运行", zap.Error(err))
+		tracingShutdown = nil
+	}
+
 	// 创建 App 实例（部分字段稍后填充）
 	app := &App{
 		config:             cfg,
@@ -379,17 +596,32 @@ func New(cfg *config.Config, log *logger.Logger) (*App, error) {
 		c2Watchdog:         c2Watchdog,
 		c2WatchdogCancel:   watchdogCancel,
 		c2Handler:          c2Handler,
+		tracingShutdown:    tracingShutdown,
 	}
 	// 飞书/钉钉长连接（无需公网），启用时在后台启动；后续前端应用配置时会通过 RestartRobotConnections 重启
 	app.startRobotConnections()
 
 	// 设置漏洞工具注册器（内置工具，必须设置）
 	vulnerabilityRegistrar := func() error {
-		registerVulnerabilityTool(mcpServer, db, log.Logger)
+		registerVulnerabilityTool(mcpServer, db, log.Logger, cveEnrichmentTrigger)
 		return nil
 	}
 	configHandler.SetVulnerabilityToolRegistrar(vulnerabilityRegistrar)
 
+	// 设置长期代理记忆工具注册器（ApplyConfig 时重新注册）
+	memoryRegistrar := func() error {
+		registerMemoryTool(mcpServer, memoryStore, log.Logger)
+		return nil
+	}
+	configHandler.SetMemoryToolRegistrar(memoryRegistrar)
+
+	// 设置资产台账工具注册器（ApplyConfig 时重新注册）
+	assetToolRegistrar := func() error {
+		registerAssetTool(mcpServer, db, log.Logger)
+		return nil
+	}
+	configHandler.SetAssetToolRegistrar(assetToolRegistrar)
+
 	// 设置 WebShell 工具注册器（ApplyConfig 时重新注册）
 	webshellRegistrar := func() error {
 		registerWebshellTools(mcpServer, db, webshellHandler, log.Logger)
@@ -458,18 +690,27 @@ func New(cfg *config.Config, log *logger.Logger) (*App, error) {
 	// 设置路由（使用 App 实例以便动态获取 handler）
 	setupRoutes(
 		router,
+		db,
 		authHandler,
+		auditHandler,
 		agentHandler,
 		monitorHandler,
 		notificationHandler,
 		conversationHandler,
+		backupHandler,
 		robotHandler,
 		groupHandler,
+		projectHandler,
+		assetHandler,
 		configHandler,
 		externalMCPHandler,
 		attackChainHandler,
 		app, // 传递 App 实例以便动态获取 knowledgeHandler
 		vulnerabilityHandler,
+		defectDojoHandler,
+		jiraHandler,
+		reportHandler,
+		reportTemplateHandler,
 		webshellHandler,
 		chatUploadsHandler,
 		roleHandler,
@@ -481,6 +722,10 @@ func New(cfg *config.Config, log *logger.Logger) (*App, error) {
 		mcpServer,
 		authManager,
 		openAPIHandler,
+		promptsHandler,
+		workerHandler,
+		scopeHandler,
+		proxyHandler,
 	)
 
 	return app, nil
@@ -515,14 +760,13 @@ func (a *App) RunWithContext(ctx context.Context) error {
 	var mcpServer *http.Server
 	if a.config.MCP.Enabled {
 		mcpAddr := fmt.Sprintf("%s:%d", a.config.MCP.Host, a.config.MCP.Port)
-		a.logger.Info("启动MCP服务器", zap.String("address", mcpAddr))
 
 		mux := http.NewServeMux()
 		mux.HandleFunc("/mcp", a.mcpHandlerWithAuth)
 
 		mcpServer = &http.Server{Addr: mcpAddr, Handler: mux}
 		go func() {
-			if err := mcpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			if err := serveWithOptionalTLS(mcpServer, a.config.MCP.TLS, a.logger, "MCP"); err != nil && err != http.ErrServerClosed {
 				a.logger.Error("MCP服务器启动失败", zap.Error(err))
 			}
 		}()
@@ -530,13 +774,18 @@ func (a *App) RunWithContext(ctx context.Context) error {
 
 	// 启动主服务器
 	addr := fmt.Sprintf("%s:%d", a.config.Server.Host, a.config.Server.Port)
-	a.logger.Info("启动HTTP服务器", zap.String("address", addr))
 
 	srv := &http.Server{Addr: addr, Handler: a.router}
 
-	// 监听 context 取消，优雅关闭 HTTP 服务器
+	// 监听 context 取消，执行完整的优雅关闭序列：先停止接受新请求（不再产生新的 Agent
+	// 任务/工具调用），再有界等待正在执行中的工具调用排空，最后才落盘/关闭数据库等资源，
+	// 避免出现 HTTP 请求或工具执行仍在进行时数据库连接已被关闭的问题。
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		<-ctx.Done()
+		a.logger.Info("收到关闭信号，开始优雅关闭")
+
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		if err := srv.Shutdown(shutdownCtx); err != nil {
@@ -547,11 +796,21 @@ func (a *App) RunWithContext(ctx context.Context) error {
 				a.logger.Error("MCP服务器关闭失败", zap.Error(err))
 			}
 		}
+
+		// 有界等待正在执行的工具调用完成，超时后不再阻塞，继续走后续关闭步骤
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer drainCancel()
+		a.executor.Drain(drainCtx)
+
+		// 停止外部MCP客户端、关闭数据库等收尾工作
+		a.Shutdown()
 	}()
 
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	a.logger.Info("启动HTTP服务器", zap.String("address", addr), zap.Bool("tls", a.config.Server.TLS.Enabled()))
+	if err := serveWithOptionalTLS(srv, a.config.Server.TLS, a.logger, "HTTP"); err != nil && err != http.ErrServerClosed {
 		return err
 	}
+	<-shutdownDone
 	return nil
 }
 
@@ -589,6 +848,15 @@ func (a *App) Shutdown() {
 			a.logger.Logger.Warn("关闭主数据库连接失败", zap.Error(err))
 		}
 	}
+
+	// 刷新未导出完的 OpenTelemetry span 并关闭 TracerProvider
+	if a.tracingShutdown != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := a.tracingShutdown(shutdownCtx); err != nil {
+			a.logger.Logger.Warn("关闭 OpenTelemetry 追踪失败", zap.Error(err))
+		}
+	}
 }
 
 // startRobotConnections 根据当前配置启动钉钉/飞书长连接（不先关闭已有连接，仅用于首次启动）
@@ -628,18 +896,27 @@ func (a *App) RestartRobotConnections() {
 // setupRoutes 设置路由
 func setupRoutes(
 	router *gin.Engine,
+	db *database.DB,
 	authHandler *handler.AuthHandler,
+	auditHandler *handler.AuditHandler,
 	agentHandler *handler.AgentHandler,
 	monitorHandler *handler.MonitorHandler,
 	notificationHandler *handler.NotificationHandler,
 	conversationHandler *handler.ConversationHandler,
+	backupHandler *handler.BackupHandler,
 	robotHandler *handler.RobotHandler,
 	groupHandler *handler.GroupHandler,
+	projectHandler *handler.ProjectHandler,
+	assetHandler *handler.AssetHandler,
 	configHandler *handler.ConfigHandler,
 	externalMCPHandler *handler.ExternalMCPHandler,
 	attackChainHandler *handler.AttackChainHandler,
 	app *App, // 传递 App 实例以便动态获取 knowledgeHandler
 	vulnerabilityHandler *handler.VulnerabilityHandler,
+	defectDojoHandler *handler.DefectDojoHandler,
+	jiraHandler *handler.JiraHandler,
+	reportHandler *handler.ReportHandler,
+	reportTemplateHandler *handler.ReportTemplateHandler,
 	webshellHandler *handler.WebShellHandler,
 	chatUploadsHandler *handler.ChatUploadsHandler,
 	roleHandler *handler.RoleHandler,
@@ -651,6 +928,10 @@ func setupRoutes(
 	mcpServer *mcp.Server,
 	authManager *security.AuthManager,
 	openAPIHandler *handler.OpenAPIHandler,
+	promptsHandler *handler.PromptsHandler,
+	workerHandler *handler.WorkerHandler,
+	scopeHandler *handler.ScopeHandler,
+	proxyHandler *handler.ProxyHandler,
 ) {
 	// API路由
 	api := router.Group("/api")
@@ -659,11 +940,28 @@ func setupRoutes(
 	authRoutes := api.Group("/auth")
 	{
 		authRoutes.POST("/login", authHandler.Login)
-		authRoutes.POST("/logout", security.AuthMiddleware(authManager), authHandler.Logout)
-		authRoutes.POST("/change-password", security.AuthMiddleware(authManager), authHandler.ChangePassword)
-		authRoutes.GET("/validate", security.AuthMiddleware(authManager), authHandler.Validate)
+		authRoutes.POST("/logout", security.AuthMiddleware(authManager, db), authHandler.Logout)
+		authRoutes.POST("/change-password", security.AuthMiddleware(authManager, db), authHandler.ChangePassword)
+		authRoutes.GET("/validate", security.AuthMiddleware(authManager, db), authHandler.Validate)
+
+		// 个人 API Key 管理（创建/列出/撤销均需先以会话登录）
+		authRoutes.POST("/api-keys", security.AuthMiddleware(authManager, db), authHandler.CreateAPIKey)
+		authRoutes.GET("/api-keys", security.AuthMiddleware(authManager, db), authHandler.ListAPIKeys)
+		authRoutes.DELETE("/api-keys/:id", security.AuthMiddleware(authManager, db), authHandler.RevokeAPIKey)
+
+		// 企业 OIDC 单点登录，未在配置中启用时 authHandler 会对这两个接口返回「未启用」，不注册也可以，
+		// 但保留固定路由方便前端在启用前探测。
+		authRoutes.GET("/oidc/login", authHandler.OIDCLogin)
+		authRoutes.GET("/oidc/callback", authHandler.OIDCCallback)
+
+		// 会话管理：查看/踢掉其他登录设备
+		authRoutes.GET("/sessions", security.AuthMiddleware(authManager, db), authHandler.ListSessions)
+		authRoutes.DELETE("/sessions/:id", security.AuthMiddleware(authManager, db), authHandler.RevokeSession)
 	}
 
+	// 敏感操作审计日志查询（登录/配置修改/工具启停/外部 MCP 添加/任务启动取消/紧急停止）
+	api.GET("/audit", security.AuthMiddleware(authManager, db), auditHandler.ListAuditLog)
+
 	// 机器人回调（无需登录，供企业微信/钉钉/飞书服务器调用）
 	// 添加速率限制：每个 IP 每分钟最多 60 次请求，防止滥用
 	robotRL := security.NewRateLimiter(60, 1*time.Minute)
@@ -677,7 +975,7 @@ func setupRoutes(
 	}
 
 	protected := api.Group("")
-	protected.Use(security.AuthMiddleware(authManager))
+	protected.Use(security.AuthMiddleware(authManager, db))
 	{
 		// 机器人测试（需登录）：POST /api/robot/test，body: {"platform":"dingtalk","user_id":"test","text":"帮助"}，用于验证机器人逻辑
 		protected.POST("/robot/test", robotHandler.HandleRobotTest)
@@ -696,9 +994,15 @@ func setupRoutes(
 		protected.PUT("/hitl/config", agentHandler.UpsertHITLConversationConfig)
 		protected.POST("/hitl/tool-whitelist", agentHandler.MergeHITLGlobalToolWhitelist)
 		// Agent Loop 取消与任务列表
+		protected.POST("/agent-loop/estimate", agentHandler.EstimateCost)
+		protected.GET("/agent-loop/llm-retry-stats", agentHandler.GetLLMRetryStats)
+		protected.POST("/agent-loop/resume", agentHandler.ResumeAgentLoop)
 		protected.POST("/agent-loop/cancel", agentHandler.CancelAgentLoop)
 		protected.GET("/agent-loop/tasks", agentHandler.ListAgentTasks)
 		protected.GET("/agent-loop/task-events", agentHandler.SubscribeAgentTaskEvents)
+		// Agent Loop 的 WebSocket 版本：同一连接内可发送跟进消息、取消任务、审批工具调用，见 agent_ws.go
+		protected.GET("/agent-loop/ws", agentHandler.AgentLoopWS)
+		protected.GET("/agent-loop/tasks-feed", agentHandler.TasksFeed)
 		protected.GET("/agent-loop/tasks/completed", agentHandler.ListCompletedTasks)
 
 		// Eino DeepAgent 多代理（与单 Agent 并存，需 config.multi_agent.enabled）
@@ -715,6 +1019,8 @@ func setupRoutes(
 		protected.POST("/fofa/search", fofaHandler.Search)
 		// 信息收集 - 自然语言解析为 FOFA 语法（需人工确认后再查询）
 		protected.POST("/fofa/parse", fofaHandler.ParseNaturalLanguage)
+		// 信息收集 - 一键式流水线：自然语言/查询语法 -> FOFA搜索 -> 去重/存活校验 -> 创建批量任务队列 -> 可选立即执行
+		protected.POST("/fofa/pipeline", agentHandler.FofaScanPipeline)
 
 		// 批量任务管理
 		protected.POST("/batch-tasks", agentHandler.CreateBatchQueue)
@@ -731,6 +1037,13 @@ func setupRoutes(
 		protected.POST("/batch-tasks/:queueId/tasks", agentHandler.AddBatchTask)
 		protected.DELETE("/batch-tasks/:queueId/tasks/:taskId", agentHandler.DeleteBatchTask)
 
+		// 定时任务（Cron 调度）：底层复用批量任务队列，见 handler.ListSchedules/GetScheduleHistory
+		protected.GET("/schedules", agentHandler.ListSchedules)
+		protected.GET("/schedules/:queueId", agentHandler.GetBatchQueue)
+		protected.GET("/schedules/:queueId/history", agentHandler.GetScheduleHistory)
+		protected.PUT("/schedules/:queueId/schedule", agentHandler.UpdateBatchQueueSchedule)
+		protected.PUT("/schedules/:queueId/schedule-enabled", agentHandler.SetBatchQueueScheduleEnabled)
+
 		// 对话历史
 		protected.POST("/conversations", conversationHandler.CreateConversation)
 		protected.GET("/conversations", conversationHandler.ListConversations)
@@ -739,8 +1052,24 @@ func setupRoutes(
 		protected.PUT("/conversations/:id", conversationHandler.UpdateConversation)
 		protected.DELETE("/conversations/:id", conversationHandler.DeleteConversation)
 		protected.POST("/conversations/:id/delete-turn", conversationHandler.DeleteConversationTurn)
+		protected.POST("/conversations/merge", conversationHandler.MergeConversations)
+		protected.POST("/conversations/:id/split", conversationHandler.SplitConversation)
+		protected.GET("/conversations/:id/export", conversationHandler.ExportConversation)
+		protected.POST("/conversations/import", conversationHandler.ImportConversation)
 		protected.PUT("/conversations/:id/pinned", groupHandler.UpdateConversationPinned)
 
+		// 消息重新生成与分支
+		protected.POST("/conversations/:id/messages/:msgId/regenerate", agentHandler.RegenerateMessage)
+		protected.GET("/conversations/:id/messages/:msgId/branches", agentHandler.ListMessageBranches)
+		protected.POST("/conversations/:id/messages/:msgId/switch-branch", agentHandler.SwitchMessageBranch)
+
+		// 数据库备份管理
+		protected.GET("/admin/backups", backupHandler.ListBackups)
+		protected.POST("/admin/backups", backupHandler.CreateBackup)
+		protected.POST("/admin/backups/restore", backupHandler.RestoreBackup)
+		protected.GET("/conversations/:id/report", reportHandler.GetReport)
+		protected.PUT("/conversations/:id/report-template", reportHandler.SetReportTemplate)
+
 		// 对话分组
 		protected.POST("/groups", groupHandler.CreateGroup)
 		protected.GET("/groups", groupHandler.ListGroups)
@@ -754,14 +1083,38 @@ func setupRoutes(
 		protected.DELETE("/groups/:id/conversations/:conversationId", groupHandler.RemoveConversationFromGroup)
 		protected.PUT("/groups/:id/conversations/:conversationId/pinned", groupHandler.UpdateConversationPinnedInGroup)
 
+		// 项目/交战：多目标渗透测试项目的顶层分组，见 handler.ProjectHandler
+		protected.POST("/projects", projectHandler.CreateProject)
+		protected.GET("/projects", projectHandler.ListProjects)
+		protected.GET("/projects/:id", projectHandler.GetProject)
+		protected.PUT("/projects/:id", projectHandler.UpdateProject)
+		protected.DELETE("/projects/:id", projectHandler.DeleteProject)
+		protected.GET("/projects/:id/conversations", projectHandler.GetProjectConversations)
+		protected.GET("/projects/:id/vulnerabilities", projectHandler.GetProjectVulnerabilities)
+		protected.GET("/projects/:id/report", projectHandler.GetProjectReport)
+		protected.PUT("/conversations/:id/project", projectHandler.AssignConversationToProject)
+
+		// 资产台账：主机/域名/URL/服务，见 handler.AssetHandler
+		protected.POST("/assets", assetHandler.CreateAsset)
+		protected.GET("/assets", assetHandler.ListAssets)
+		protected.GET("/assets/search", assetHandler.SearchAssets)
+		protected.GET("/assets/:id", assetHandler.GetAsset)
+		protected.PUT("/assets/:id", assetHandler.UpdateAsset)
+		protected.DELETE("/assets/:id", assetHandler.DeleteAsset)
+
 		// 监控
 		protected.GET("/monitor", monitorHandler.Monitor)
 		protected.GET("/monitor/execution/:id", monitorHandler.GetExecution)
+		protected.GET("/monitor/execution/:id/reveal", monitorHandler.RevealExecutionArguments)
+		protected.GET("/monitor/execution/:id/artifacts", monitorHandler.GetExecutionArtifacts)
 		protected.POST("/monitor/execution/:id/cancel", monitorHandler.CancelExecution)
+		protected.POST("/monitor/emergency-stop", monitorHandler.EmergencyStop)
+		protected.POST("/monitor/emergency-resume", monitorHandler.EmergencyResume)
 		protected.POST("/monitor/executions/names", monitorHandler.BatchGetToolNames)
 		protected.DELETE("/monitor/execution/:id", monitorHandler.DeleteExecution)
 		protected.DELETE("/monitor/executions", monitorHandler.DeleteExecutions)
 		protected.GET("/monitor/stats", monitorHandler.GetStats)
+		protected.GET("/monitor/db-stats", monitorHandler.GetDBQueryStats)
 		protected.GET("/notifications/summary", notificationHandler.GetSummary)
 		protected.POST("/notifications/read", notificationHandler.MarkRead)
 
@@ -769,6 +1122,7 @@ func setupRoutes(
 		protected.GET("/config", configHandler.GetConfig)
 		protected.GET("/config/tools", configHandler.GetTools)
 		protected.GET("/config/tools/:name/schema", configHandler.GetToolSchema)
+		protected.GET("/config/tools/health", configHandler.GetToolsHealth)
 		protected.PUT("/config", configHandler.UpdateConfig)
 		protected.POST("/config/apply", configHandler.ApplyConfig)
 		protected.POST("/config/test-openai", configHandler.TestOpenAI)
@@ -790,6 +1144,13 @@ func setupRoutes(
 		// 攻击链可视化
 		protected.GET("/attack-chain/:conversationId", attackChainHandler.GetAttackChain)
 		protected.POST("/attack-chain/:conversationId/regenerate", attackChainHandler.RegenerateAttackChain)
+		protected.GET("/attack-chain/:conversationId/export", attackChainHandler.ExportAttackChain)
+		protected.POST("/attack-chain/:conversationId/nodes", attackChainHandler.CreateAttackChainNode)
+		protected.PUT("/attack-chain/:conversationId/nodes/:nodeId", attackChainHandler.UpdateAttackChainNode)
+		protected.DELETE("/attack-chain/:conversationId/nodes/:nodeId", attackChainHandler.DeleteAttackChainNode)
+		protected.POST("/attack-chain/:conversationId/edges", attackChainHandler.CreateAttackChainEdge)
+		protected.PUT("/attack-chain/:conversationId/edges/:edgeId", attackChainHandler.UpdateAttackChainEdge)
+		protected.DELETE("/attack-chain/:conversationId/edges/:edgeId", attackChainHandler.DeleteAttackChainEdge)
 
 		// 知识库管理（始终注册路由，通过 App 实例动态获取 handler）
 		knowledgeRoutes := protected.Group("/knowledge")
@@ -836,6 +1197,36 @@ func setupRoutes(
 				}
 				app.knowledgeHandler.CreateItem(c)
 			})
+			knowledgeRoutes.POST("/import", func(c *gin.Context) {
+				if app.knowledgeHandler == nil {
+					c.JSON(http.StatusOK, gin.H{
+						"enabled": false,
+						"error":   "知识库功能未启用，请前往系统设置启用知识检索功能",
+					})
+					return
+				}
+				app.knowledgeHandler.ImportKnowledge(c)
+			})
+			knowledgeRoutes.GET("/export", func(c *gin.Context) {
+				if app.knowledgeHandler == nil {
+					c.JSON(http.StatusOK, gin.H{
+						"enabled": false,
+						"error":   "知识库功能未启用，请前往系统设置启用知识检索功能",
+					})
+					return
+				}
+				app.knowledgeHandler.ExportBundle(c)
+			})
+			knowledgeRoutes.POST("/import-bundle", func(c *gin.Context) {
+				if app.knowledgeHandler == nil {
+					c.JSON(http.StatusOK, gin.H{
+						"enabled": false,
+						"error":   "知识库功能未启用，请前往系统设置启用知识检索功能",
+					})
+					return
+				}
+				app.knowledgeHandler.ImportBundle(c)
+			})
 			knowledgeRoutes.PUT("/items/:id", func(c *gin.Context) {
 				if app.knowledgeHandler == nil {
 					c.JSON(http.StatusOK, gin.H{
@@ -856,6 +1247,37 @@ func setupRoutes(
 				}
 				app.knowledgeHandler.DeleteItem(c)
 			})
+			knowledgeRoutes.GET("/items/:id/versions", func(c *gin.Context) {
+				if app.knowledgeHandler == nil {
+					c.JSON(http.StatusOK, gin.H{
+						"versions": []interface{}{},
+						"enabled":  false,
+						"message":  "知识库功能未启用，请前往系统设置启用知识检索功能",
+					})
+					return
+				}
+				app.knowledgeHandler.GetItemVersions(c)
+			})
+			knowledgeRoutes.GET("/items/:id/versions/:version/diff", func(c *gin.Context) {
+				if app.knowledgeHandler == nil {
+					c.JSON(http.StatusOK, gin.H{
+						"enabled": false,
+						"message": "知识库功能未启用，请前往系统设置启用知识检索功能",
+					})
+					return
+				}
+				app.knowledgeHandler.DiffItemVersion(c)
+			})
+			knowledgeRoutes.POST("/items/:id/versions/:version/restore", func(c *gin.Context) {
+				if app.knowledgeHandler == nil {
+					c.JSON(http.StatusOK, gin.H{
+						"enabled": false,
+						"error":   "知识库功能未启用，请前往系统设置启用知识检索功能",
+					})
+					return
+				}
+				app.knowledgeHandler.RestoreItemVersion(c)
+			})
 			knowledgeRoutes.GET("/index-status", func(c *gin.Context) {
 				if app.knowledgeHandler == nil {
 					c.JSON(http.StatusOK, gin.H{
@@ -934,6 +1356,17 @@ func setupRoutes(
 				}
 				app.knowledgeHandler.GetStats(c)
 			})
+			knowledgeRoutes.POST("/suggest-tool-params", func(c *gin.Context) {
+				if app.knowledgeHandler == nil {
+					c.JSON(http.StatusOK, gin.H{
+						"suggestions": []interface{}{},
+						"enabled":     false,
+						"message":     "知识库功能未启用，请前往系统设置启用知识检索功能",
+					})
+					return
+				}
+				app.knowledgeHandler.SuggestToolParameters(c)
+			})
 		}
 
 		// 漏洞管理
@@ -945,6 +1378,8 @@ func setupRoutes(
 		protected.POST("/vulnerabilities", vulnerabilityHandler.CreateVulnerability)
 		protected.PUT("/vulnerabilities/:id", vulnerabilityHandler.UpdateVulnerability)
 		protected.DELETE("/vulnerabilities/:id", vulnerabilityHandler.DeleteVulnerability)
+		protected.POST("/integrations/defectdojo/push", defectDojoHandler.PushConversation)
+		protected.POST("/vulnerabilities/:id/ticket", jiraHandler.CreateOrUpdateTicket)
 
 		// WebShell 管理（代理执行 + 连接配置存 SQLite）
 		protected.GET("/webshell/connections", webshellHandler.ListConnections)
@@ -1021,6 +1456,33 @@ func setupRoutes(
 		protected.PUT("/roles/:name", roleHandler.UpdateRole)
 		protected.DELETE("/roles/:name", roleHandler.DeleteRole)
 
+		// 提示词模板管理（PromptsDir 下的 YAML 定义，供 MCP prompts/list、prompts/get 使用）
+		protected.GET("/prompts", promptsHandler.GetPrompts)
+		protected.GET("/prompts/:name", promptsHandler.GetPrompt)
+		protected.POST("/prompts", promptsHandler.CreatePrompt)
+		protected.PUT("/prompts/:name", promptsHandler.UpdatePrompt)
+		protected.DELETE("/prompts/:name", promptsHandler.DeletePrompt)
+
+		protected.GET("/report-templates", reportTemplateHandler.ListReportTemplates)
+		protected.GET("/report-templates/:name", reportTemplateHandler.GetReportTemplate)
+		protected.POST("/report-templates", reportTemplateHandler.CreateReportTemplate)
+		protected.PUT("/report-templates/:name", reportTemplateHandler.UpdateReportTemplate)
+		protected.DELETE("/report-templates/:name", reportTemplateHandler.DeleteReportTemplate)
+
+		// 远程Worker管理（分布式执行子系统，见 internal/worker）
+		protected.POST("/workers/register", workerHandler.RegisterWorker)
+		protected.POST("/workers/:id/heartbeat", workerHandler.Heartbeat)
+		protected.DELETE("/workers/:id", workerHandler.Unregister)
+		protected.GET("/workers", workerHandler.ListWorkers)
+
+		// 目标范围配置（见 internal/scope），按对话ID限定该对话下工具调用允许的目标
+		protected.POST("/conversations/:id/scope", scopeHandler.SetScope)
+		protected.GET("/conversations/:id/scope", scopeHandler.GetScope)
+		protected.DELETE("/conversations/:id/scope", scopeHandler.ClearScope)
+		protected.POST("/conversations/:id/proxy", proxyHandler.SetProxy)
+		protected.GET("/conversations/:id/proxy", proxyHandler.GetProxy)
+		protected.DELETE("/conversations/:id/proxy", proxyHandler.ClearProxy)
+
 		// Skills管理（具体路径需注册在 /skills/:name 之前）
 		protected.GET("/skills", skillsHandler.GetSkills)
 		protected.GET("/skills/stats", skillsHandler.GetSkillStats)
@@ -1067,7 +1529,7 @@ func setupRoutes(
 }
 
 // registerVulnerabilityTool 注册漏洞记录工具到MCP服务器
-func registerVulnerabilityTool(mcpServer *mcp.Server, db *database.DB, logger *zap.Logger) {
+func registerVulnerabilityTool(mcpServer *mcp.Server, db *database.DB, logger *zap.Logger, cveTrigger *handler.CVEEnrichmentTrigger) {
 	tool := mcp.Tool{
 		Name:             builtin.ToolRecordVulnerability,
 		Description:      "记录发现的漏洞详情到漏洞管理系统。当发现有效漏洞时，使用此工具记录漏洞信息，包括标题、描述、严重程度、类型、目标、证明、影响和建议等。",
@@ -1108,6 +1570,10 @@ func registerVulnerabilityTool(mcpServer *mcp.Server, db *database.DB, logger *z
 					"type":        "string",
 					"description": "修复建议",
 				},
+				"cvss_vector": map[string]interface{}{
+					"type":        "string",
+					"description": "可选的 CVSS v3.1 向量字符串，如 \"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H\"；设置后严重程度将由计算出的评分覆盖 severity 参数",
+				},
 			},
 			"required": []string{"title", "severity"},
 		},
@@ -1205,7 +1671,12 @@ func registerVulnerabilityTool(mcpServer *mcp.Server, db *database.DB, logger *z
 			recommendation = r
 		}
 
-		// 创建漏洞记录
+		cvssVector := ""
+		if v, ok := args["cvss_vector"].(string); ok {
+			cvssVector = v
+		}
+
+		// 创建漏洞记录；若提供了 cvss_vector，CreateVulnerability 内部会计算评分并据此覆盖 severity
 		vuln := &database.Vulnerability{
 			ConversationID: conversationID,
 			Title:          title,
@@ -1217,6 +1688,7 @@ func registerVulnerabilityTool(mcpServer *mcp.Server, db *database.DB, logger *z
 			Proof:          proof,
 			Impact:         impact,
 			Recommendation: recommendation,
+			CVSSVector:     cvssVector,
 		}
 
 		created, err := db.CreateVulnerability(vuln)
@@ -1232,6 +1704,7 @@ func registerVulnerabilityTool(mcpServer *mcp.Server, db *database.DB, logger *z
 				IsError: true,
 			}, nil
 		}
+		cveTrigger.TriggerAsync(db, created)
 
 		logger.Info("漏洞记录成功",
 			zap.String("id", created.ID),
@@ -1255,6 +1728,171 @@ func registerVulnerabilityTool(mcpServer *mcp.Server, db *database.DB, logger *z
 	logger.Info("漏洞记录工具注册成功")
 }
 
+// registerMemoryTool 注册长期代理记忆工具，供 Agent 在运行过程中将发现的事实（开放端口、凭据、技术栈等）
+// 按 target 持久化，供后续针对同一 target 的对话在 system prompt 中注入摘要。
+func registerMemoryTool(mcpServer *mcp.Server, memStore *memory.Store, logger *zap.Logger) {
+	tool := mcp.Tool{
+		Name:             builtin.ToolRememberFact,
+		Description:      "将本次任务中发现的关于某个目标的事实（如开放端口、凭据、技术栈等）记入长期记忆，供后续针对同一目标的对话参考。",
+		ShortDescription: "记录关于目标的长期记忆事实",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"target": map[string]interface{}{
+					"type":        "string",
+					"description": "事实所属的目标（IP、域名、主机名等，必需）",
+				},
+				"fact_type": map[string]interface{}{
+					"type":        "string",
+					"description": "事实类型：open_port（开放端口）、credential（凭据）、tech_stack（技术栈）、note（其他备注）",
+					"enum":        []string{"open_port", "credential", "tech_stack", "note"},
+				},
+				"key": map[string]interface{}{
+					"type":        "string",
+					"description": "事实的键，用于在同一目标同一类型下去重覆盖（如端口号、用户名）",
+				},
+				"value": map[string]interface{}{
+					"type":        "string",
+					"description": "事实内容",
+				},
+			},
+			"required": []string{"target", "fact_type", "key", "value"},
+		},
+	}
+
+	handler := func(ctx context.Context, args map[string]interface{}) (*mcp.ToolResult, error) {
+		// 从参数中获取conversation_id（由Agent自动添加）
+		conversationID, _ := args["conversation_id"].(string)
+
+		target, ok := args["target"].(string)
+		if !ok || target == "" {
+			return &mcp.ToolResult{
+				Content: []mcp.Content{{Type: "text", Text: "错误: target 参数必需且不能为空"}},
+				IsError: true,
+			}, nil
+		}
+
+		factType, ok := args["fact_type"].(string)
+		if !ok || factType == "" {
+			return &mcp.ToolResult{
+				Content: []mcp.Content{{Type: "text", Text: "错误: fact_type 参数必需且不能为空"}},
+				IsError: true,
+			}, nil
+		}
+
+		key, ok := args["key"].(string)
+		if !ok || key == "" {
+			return &mcp.ToolResult{
+				Content: []mcp.Content{{Type: "text", Text: "错误: key 参数必需且不能为空"}},
+				IsError: true,
+			}, nil
+		}
+
+		value, ok := args["value"].(string)
+		if !ok || value == "" {
+			return &mcp.ToolResult{
+				Content: []mcp.Content{{Type: "text", Text: "错误: value 参数必需且不能为空"}},
+				IsError: true,
+			}, nil
+		}
+
+		if err := memStore.RememberFact(target, factType, key, value, conversationID); err != nil {
+			logger.Error("记录长期代理记忆失败", zap.Error(err))
+			return &mcp.ToolResult{
+				Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("记录记忆失败: %v", err)}},
+				IsError: true,
+			}, nil
+		}
+
+		logger.Info("长期代理记忆记录成功",
+			zap.String("target", target),
+			zap.String("fact_type", factType),
+			zap.String("key", key),
+			zap.String("conversation_id", conversationID),
+		)
+
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("已记入关于 %s 的长期记忆：[%s] %s = %s", target, factType, key, value)}},
+			IsError: false,
+		}, nil
+	}
+
+	mcpServer.RegisterTool(tool, handler)
+	logger.Info("长期代理记忆工具注册成功")
+}
+
+// registerAssetTool 注册资产台账查询工具，供模型查询由 nmap/httpx 解析结果与 FOFA 导入自动积累的资产
+// （见 internal/database/asset.go），无需再让模型自行回溯历史工具输出来记住扫描过的主机/服务。
+func registerAssetTool(mcpServer *mcp.Server, db *database.DB, logger *zap.Logger) {
+	tool := mcp.Tool{
+		Name:             builtin.ToolListAssets,
+		Description:      "查询资产台账中已积累的主机/域名/URL/服务，这些资产由 nmap/httpx 工具输出解析结果与 FOFA 导入自动积累，也可能包含手动录入的资产。可按类型、来源过滤，或按关键词搜索。",
+		ShortDescription: "查询资产台账",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"type": map[string]interface{}{
+					"type":        "string",
+					"description": "可选：按资产类型过滤",
+					"enum":        []string{"host", "domain", "url", "service"},
+				},
+				"source": map[string]interface{}{
+					"type":        "string",
+					"description": "可选：按来源过滤",
+					"enum":        []string{"nmap", "httpx", "fofa", "manual"},
+				},
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "可选：按关键词模糊搜索主机/标识/服务/备注；指定后忽略 type/source 过滤条件",
+				},
+			},
+		},
+	}
+
+	handler := func(ctx context.Context, args map[string]interface{}) (*mcp.ToolResult, error) {
+		var assets []*database.Asset
+		var err error
+
+		if query, ok := args["query"].(string); ok && query != "" {
+			assets, err = db.SearchAssets(query)
+		} else {
+			assetType, _ := args["type"].(string)
+			source, _ := args["source"].(string)
+			assets, err = db.ListAssets(assetType, source)
+		}
+		if err != nil {
+			logger.Error("查询资产台账失败", zap.Error(err))
+			return &mcp.ToolResult{
+				Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("查询资产台账失败: %v", err)}},
+				IsError: true,
+			}, nil
+		}
+
+		if len(assets) == 0 {
+			return &mcp.ToolResult{
+				Content: []mcp.Content{{Type: "text", Text: "资产台账中未找到符合条件的资产"}},
+				IsError: false,
+			}, nil
+		}
+
+		data, err := json.Marshal(assets)
+		if err != nil {
+			return &mcp.ToolResult{
+				Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("序列化资产列表失败: %v", err)}},
+				IsError: true,
+			}, nil
+		}
+
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: string(data)}},
+			IsError: false,
+		}, nil
+	}
+
+	mcpServer.RegisterTool(tool, handler)
+	logger.Info("资产台账查询工具注册成功")
+}
+
 // registerWebshellTools 注册 WebShell 相关 MCP 工具，供 AI 助手在指定连接上执行命令与文件操作
 func registerWebshellTools(mcpServer *mcp.Server, db *database.DB, webshellHandler *handler.WebShellHandler, logger *zap.Logger) {
 	if db == nil || webshellHandler == nil {
@@ -1825,8 +2463,14 @@ func initializeKnowledge(
 		SimilarityThreshold: cfg.Knowledge.Retrieval.SimilarityThreshold,
 		SubIndexFilter:      cfg.Knowledge.Retrieval.SubIndexFilter,
 		PostRetrieve:        cfg.Knowledge.Retrieval.PostRetrieve,
+		HybridSearch:        cfg.Knowledge.Retrieval.HybridSearchEffective(),
 	}
 	knowledgeRetriever := knowledge.NewRetriever(knowledgeDB, embedder, retrievalConfig, logger)
+	if vs, vsErr := knowledge.NewVectorStore(knowledgeDB, &cfg.Knowledge.VectorStore); vsErr != nil {
+		logger.Warn("初始化向量存储后端失败，检索将回退到内置 SQLite", zap.Error(vsErr))
+	} else {
+		knowledgeRetriever.SetVectorStore(vs)
+	}
 
 	// 创建索引器（Eino Compose 链）
 	knowledgeIndexer, err := knowledge.NewIndexer(context.Background(), knowledgeDB, embedder, logger, &cfg.Knowledge)
@@ -1839,6 +2483,13 @@ func initializeKnowledge(
 
 	// 创建知识库API处理器
 	knowledgeHandler := handler.NewKnowledgeHandler(knowledgeManager, knowledgeRetriever, knowledgeIndexer, db, logger)
+	knowledgeHandler.SetMCPServer(mcpServer)
+	mcpServer.RegisterResource(&mcp.Resource{
+		URI:         handler.KnowledgeResourceURI,
+		Name:        "知识库条目",
+		Description: "知识库条目集合；可通过 resources/subscribe 订阅，条目增删改时会推送 notifications/resources/updated",
+		MimeType:    "application/json",
+	})
 	logger.Info("知识库模块初始化完成", zap.Bool("handler_created", knowledgeHandler != nil))
 
 	// 设置知识库管理器到AgentHandler以便记录检索日志
@@ -1928,16 +2579,105 @@ func initializeKnowledge(
 		}
 	}()
 
+	// 启动知识库目录监听：文件新增/修改/删除后自动增量扫描+索引
+	knowledgeWatcher := knowledge.NewWatcher(knowledgeManager, knowledgeIndexer, logger, 0)
+	go func() {
+		if err := knowledgeWatcher.Run(context.Background()); err != nil {
+			logger.Warn("知识库目录监听已退出", zap.Error(err))
+		}
+	}()
+
+	// 定时拉取配置的 CVE/安全公告 feed，写入 advisories 分类并自动索引
+	if cfg.Knowledge.Feeds.Enabled && len(cfg.Knowledge.Feeds.Feeds) > 0 {
+		feedIngester := knowledge.NewFeedIngester(knowledgeManager, knowledgeIndexer, logger, cfg.Knowledge.Feeds)
+		go feedIngester.Run(context.Background())
+	}
+
 	return knowledgeHandler, nil
 }
 
-// corsMiddleware CORS中间件
-func corsMiddleware() gin.HandlerFunc {
+// serveWithOptionalTLS 按 tlsCfg 以 HTTPS 或明文 HTTP 方式启动 srv 并阻塞直至其关闭。
+// tlsCfg.AutoCert 时通过 autocert.Manager 为 tlsCfg.Domains 自动签发/续期 Let's Encrypt 证书；
+// 仅配置 CertFile/KeyFile 时使用手动证书；二者均未配置时退化为明文 HTTP（与未修改前行为一致）。
+// TLS 监听下 net/http 会自动通过 ALPN 协商 HTTP/2，无需额外配置。
+func serveWithOptionalTLS(srv *http.Server, tlsCfg config.TLSConfig, logger *logger.Logger, label string) error {
+	if !tlsCfg.Enabled() {
+		return srv.ListenAndServe()
+	}
+
+	if tlsCfg.AutoCert {
+		cacheDir := tlsCfg.ACMECacheDir
+		if cacheDir == "" {
+			cacheDir = "certs"
+		}
+		certManager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(tlsCfg.Domains...),
+			Cache:      autocert.DirCache(cacheDir),
+		}
+		srv.TLSConfig = certManager.TLSConfig()
+		logger.Info("以 ACME 自动证书方式启动 TLS 服务器", zap.String("server", label), zap.Strings("domains", tlsCfg.Domains))
+		return srv.ListenAndServeTLS("", "")
+	}
+
+	logger.Info("以手动证书方式启动 TLS 服务器", zap.String("server", label), zap.String("certFile", tlsCfg.CertFile))
+	return srv.ListenAndServeTLS(tlsCfg.CertFile, tlsCfg.KeyFile)
+}
+
+// defaultCORSAllowedHeaders/defaultCORSAllowedMethods 是 cfg.Server.CORS 未配置对应字段时使用的
+// 默认值，与本中间件历史上硬编码的值保持一致。
+var (
+	defaultCORSAllowedHeaders = []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "accept", "origin", "Cache-Control", "X-Requested-With"}
+	defaultCORSAllowedMethods = []string{"POST", "OPTIONS", "GET", "PUT", "DELETE"}
+)
+
+// corsMiddleware CORS中间件，策略见 config.CORSConfig。cfg 为零值时退化为历史行为
+// （允许所有来源、不带凭据），保持向后兼容。
+func corsMiddleware(cfg config.CORSConfig) gin.HandlerFunc {
+	allowedOrigins := cfg.AllowedOrigins
+	if len(allowedOrigins) == 0 {
+		allowedOrigins = []string{"*"}
+	}
+	allowAll := false
+	originSet := make(map[string]struct{}, len(allowedOrigins))
+	for _, o := range allowedOrigins {
+		if o == "*" {
+			allowAll = true
+		}
+		originSet[o] = struct{}{}
+	}
+
+	allowedHeaders := cfg.AllowedHeaders
+	if len(allowedHeaders) == 0 {
+		allowedHeaders = defaultCORSAllowedHeaders
+	}
+	allowedMethods := cfg.AllowedMethods
+	if len(allowedMethods) == 0 {
+		allowedMethods = defaultCORSAllowedMethods
+	}
+
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		origin := c.Request.Header.Get("Origin")
+
+		switch {
+		case allowAll:
+			// 浏览器规范禁止 "*" 与 Allow-Credentials 同时使用，即使配置了 AllowCredentials 也不下发该头。
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		case origin != "":
+			if _, ok := originSet[origin]; ok {
+				c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+				c.Writer.Header().Set("Vary", "Origin")
+				if cfg.AllowCredentials {
+					c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+		}
+
+		c.Writer.Header().Set("Access-Control-Allow-Headers", strings.Join(allowedHeaders, ", "))
+		c.Writer.Header().Set("Access-Control-Allow-Methods", strings.Join(allowedMethods, ", "))
+		if cfg.MaxAgeSeconds > 0 {
+			c.Writer.Header().Set("Access-Control-Max-Age", fmt.Sprintf("%d", cfg.MaxAgeSeconds))
+		}
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -16,7 +16,6 @@ import (
 	"cyberstrike-ai/internal/database"
 	"cyberstrike-ai/internal/openai"
 
-	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -755,11 +754,26 @@ func (b *Builder) buildSimplePrompt(reactInput, modelOutput string) string {
 现在开始分析并构建攻击链：`, reactInput, modelOutput)
 }
 
-// saveChain 保存攻击链到数据库
+// saveChain 保存攻击链到数据库；节点 ID 由 stableNodeID 确定性生成，因此同一逻辑节点在重新生成时
+// 会命中已有行并原地更新（SaveAttackChainNode 使用 INSERT OR REPLACE），而不是整链删除重建：
+// 前端已保存的节点引用、位置布局在重新生成后依然有效，也便于按 ID 比较新旧两版攻击链的差异。
+// 只有本轮生成结果中不再出现的旧节点才会被清理；边不承载用户标注，成本低，直接整体替换。
 func (b *Builder) saveChain(conversationID string, nodes []Node, edges []Edge) error {
-	// 先删除旧的攻击链数据
-	if err := b.db.DeleteAttackChain(conversationID); err != nil {
-		b.logger.Warn("删除旧攻击链失败", zap.Error(err))
+	existingNodes, err := b.db.LoadAttackChainNodes(conversationID)
+	if err != nil {
+		b.logger.Warn("加载旧攻击链节点失败，本次仅按新结果写入", zap.Error(err))
+	}
+
+	keepIDs := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		keepIDs[node.ID] = true
+	}
+	for _, old := range existingNodes {
+		if !keepIDs[old.ID] {
+			if err := b.db.DeleteAttackChainNode(conversationID, old.ID); err != nil {
+				b.logger.Warn("清理不再出现的旧攻击链节点失败", zap.String("nodeId", old.ID), zap.Error(err))
+			}
+		}
 	}
 
 	for _, node := range nodes {
@@ -769,7 +783,10 @@ func (b *Builder) saveChain(conversationID string, nodes []Node, edges []Edge) e
 		}
 	}
 
-	// 保存边
+	// 边整体替换：先清空再按当前生成结果重建
+	if err := b.db.DeleteAttackChainEdges(conversationID); err != nil {
+		b.logger.Warn("删除旧攻击链边失败", zap.Error(err))
+	}
 	for _, edge := range edges {
 		if err := b.db.SaveAttackChainEdge(conversationID, edge.ID, edge.Source, edge.Target, edge.Type, edge.Weight); err != nil {
 			b.logger.Warn("保存攻击链边失败", zap.String("edgeId", edge.ID), zap.Error(err))
@@ -779,6 +796,35 @@ func (b *Builder) saveChain(conversationID string, nodes []Node, edges []Edge) e
 	return nil
 }
 
+// AppendIncrementalNode 在运行过程中增量追加单个节点（工具执行动作或新发现的漏洞），并与该会话
+// 已有的最后一个节点连一条 leads_to 边，使攻击链在运行期间就能近实时增长，而不必等 ReAct 结束后
+// 再由 BuildChainFromConversation 一次性回溯生成。节点 ID 仍由 stableNodeID 确定性生成，因此后续
+// 整链重新生成时会命中同一行并原地更新，不会产生重复节点。
+func (b *Builder) AppendIncrementalNode(conversationID, nodeType, label string, metadata map[string]interface{}, riskScore int) (*Node, error) {
+	existingNodes, err := b.db.LoadAttackChainNodes(conversationID)
+	if err != nil {
+		b.logger.Warn("加载已有攻击链节点失败，跳过增量追加", zap.Error(err))
+	}
+
+	nodeID := stableNodeID(nodeType, label, metadata)
+	metadataJSON, _ := json.Marshal(metadata)
+	if err := b.db.SaveAttackChainNode(conversationID, nodeID, nodeType, label, "", string(metadataJSON), riskScore); err != nil {
+		return nil, fmt.Errorf("保存增量攻击链节点失败: %w", err)
+	}
+
+	if len(existingNodes) > 0 {
+		lastNode := existingNodes[len(existingNodes)-1]
+		if lastNode.ID != nodeID {
+			edgeID := stableEdgeID(lastNode.ID, nodeID, "leads_to")
+			if err := b.db.SaveAttackChainEdge(conversationID, edgeID, lastNode.ID, nodeID, "leads_to", 1); err != nil {
+				b.logger.Warn("保存增量攻击链边失败", zap.Error(err))
+			}
+		}
+	}
+
+	return &Node{ID: nodeID, Type: nodeType, Label: label, Metadata: metadata, RiskScore: riskScore}, nil
+}
+
 // LoadChainFromDatabase 从数据库加载攻击链
 func (b *Builder) LoadChainFromDatabase(conversationID string) (*Chain, error) {
 	nodes, err := b.db.LoadAttackChainNodes(conversationID)
@@ -870,6 +916,21 @@ type ChainJSON struct {
 	} `json:"edges"`
 }
 
+// stableNodeID 基于节点类型、标签与元数据计算确定性 ID，使同一逻辑节点在攻击链重新生成时保持不变 ID：
+// map 经 json.Marshal 后 key 按字典序排列，相同内容总是产出相同的 JSON，从而产出相同的哈希。
+// 这样前端已保存的节点引用、位置布局在重新生成后依然有效，也便于按 ID 对比新旧两版攻击链的差异。
+func stableNodeID(nodeType, label string, metadata map[string]interface{}) string {
+	metaJSON, _ := json.Marshal(metadata)
+	sum := sha256.Sum256([]byte(nodeType + "\x00" + label + "\x00" + string(metaJSON)))
+	return fmt.Sprintf("node_%s", hex.EncodeToString(sum[:])[:16])
+}
+
+// stableEdgeID 基于起止节点与边类型计算确定性 ID，原理同 stableNodeID。
+func stableEdgeID(sourceID, targetID, edgeType string) string {
+	sum := sha256.Sum256([]byte(sourceID + "\x00" + targetID + "\x00" + edgeType))
+	return fmt.Sprintf("edge_%s", hex.EncodeToString(sum[:])[:16])
+}
+
 // parseChainJSON 解析攻击链JSON
 func (b *Builder) parseChainJSON(chainJSON string) (*Chain, error) {
 	var chainData ChainJSON
@@ -877,26 +938,25 @@ func (b *Builder) parseChainJSON(chainJSON string) (*Chain, error) {
 		return nil, fmt.Errorf("解析JSON失败: %w", err)
 	}
 
-	// 创建节点ID映射（AI返回的ID -> 新的UUID）
+	// 创建节点ID映射（AI返回的临时ID -> 稳定ID）
 	nodeIDMap := make(map[string]string)
 
 	// 转换为Chain结构
 	nodes := make([]Node, 0, len(chainData.Nodes))
 	for _, n := range chainData.Nodes {
-		// 生成新的UUID节点ID
-		newNodeID := fmt.Sprintf("node_%s", uuid.New().String())
-		nodeIDMap[n.ID] = newNodeID
+		if n.Metadata == nil {
+			n.Metadata = make(map[string]interface{})
+		}
+		nodeID := stableNodeID(n.Type, n.Label, n.Metadata)
+		nodeIDMap[n.ID] = nodeID
 
 		node := Node{
-			ID:        newNodeID,
+			ID:        nodeID,
 			Type:      n.Type,
 			Label:     n.Label,
 			RiskScore: n.RiskScore,
 			Metadata:  n.Metadata,
 		}
-		if node.Metadata == nil {
-			node.Metadata = make(map[string]interface{})
-		}
 		nodes = append(nodes, node)
 	}
 
@@ -912,11 +972,8 @@ func (b *Builder) parseChainJSON(chainJSON string) (*Chain, error) {
 			continue
 		}
 
-		// 生成边的ID（前端需要）
-		edgeID := fmt.Sprintf("edge_%s", uuid.New().String())
-
 		edges = append(edges, Edge{
-			ID:     edgeID,
+			ID:     stableEdgeID(sourceID, targetID, e.Type),
 			Source: sourceID,
 			Target: targetID,
 			Type:   e.Type,
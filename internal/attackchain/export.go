@@ -0,0 +1,92 @@
+package attackchain
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// mermaidNodeShape 按节点类型选择 Mermaid 图形（方框/圆角/菱形），使攻击链的关键环节在图中一眼可辨
+func mermaidNodeShape(nodeType, label string) string {
+	safeLabel := strings.ReplaceAll(label, `"`, `'`)
+	switch nodeType {
+	case "vulnerability":
+		return fmt.Sprintf(`{"%s"}`, safeLabel)
+	case "target":
+		return fmt.Sprintf(`(["%s"])`, safeLabel)
+	default:
+		return fmt.Sprintf(`["%s"]`, safeLabel)
+	}
+}
+
+// RenderMermaid 把攻击链渲染为 Mermaid flowchart 语法，可直接嵌入支持 Mermaid 的文档/Wiki
+func RenderMermaid(chain *Chain) string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	for _, n := range chain.Nodes {
+		b.WriteString(fmt.Sprintf("    %s%s\n", mermaidSafeID(n.ID), mermaidNodeShape(n.Type, n.Label)))
+	}
+	for _, e := range chain.Edges {
+		arrowLabel := e.Type
+		if arrowLabel == "" {
+			b.WriteString(fmt.Sprintf("    %s --> %s\n", mermaidSafeID(e.Source), mermaidSafeID(e.Target)))
+		} else {
+			b.WriteString(fmt.Sprintf("    %s -->|%s| %s\n", mermaidSafeID(e.Source), arrowLabel, mermaidSafeID(e.Target)))
+		}
+	}
+	return b.String()
+}
+
+// mermaidSafeID 把节点 ID 中 Mermaid 不允许出现在裸标识符中的字符替换掉（如 uuid 中的连字符）
+func mermaidSafeID(id string) string {
+	replacer := strings.NewReplacer("-", "_", ".", "_", " ", "_")
+	return "n" + replacer.Replace(id)
+}
+
+// RenderDot 把攻击链渲染为 Graphviz DOT 语法
+func RenderDot(chain *Chain) string {
+	var b strings.Builder
+	b.WriteString("digraph attack_chain {\n")
+	b.WriteString("    rankdir=LR;\n")
+	b.WriteString("    node [shape=box, style=rounded];\n")
+	for _, n := range chain.Nodes {
+		shape := "box"
+		switch n.Type {
+		case "vulnerability":
+			shape = "diamond"
+		case "target":
+			shape = "ellipse"
+		}
+		b.WriteString(fmt.Sprintf("    %q [label=%q, shape=%s];\n", n.ID, n.Label, shape))
+	}
+	for _, e := range chain.Edges {
+		if e.Type == "" {
+			b.WriteString(fmt.Sprintf("    %q -> %q;\n", e.Source, e.Target))
+		} else {
+			b.WriteString(fmt.Sprintf("    %q -> %q [label=%q];\n", e.Source, e.Target, e.Type))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// RenderPNG 调用系统安装的 Graphviz `dot` 命令，把 DOT 源渲染为 PNG 图片。
+// 本项目不内置图形渲染库，若部署环境未安装 Graphviz，会返回明确的错误提示，调用方应回退到 dot/mermaid 文本格式。
+func RenderPNG(chain *Chain) ([]byte, error) {
+	dotPath, err := exec.LookPath("dot")
+	if err != nil {
+		return nil, fmt.Errorf("生成PNG需要系统安装 Graphviz（dot 命令），当前环境未检测到: %w", err)
+	}
+
+	cmd := exec.Command(dotPath, "-Tpng")
+	cmd.Stdin = strings.NewReader(RenderDot(chain))
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("渲染PNG失败: %w (%s)", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
@@ -12,6 +12,7 @@ import (
 	"testing"
 	"time"
 
+	"cyberstrike-ai/internal/config"
 	"cyberstrike-ai/internal/database"
 
 	"go.uber.org/zap"
@@ -21,7 +22,7 @@ import (
 func TestHTTPBeaconListener_CheckInMatrix(t *testing.T) {
 	tmp := t.TempDir()
 	dbPath := filepath.Join(tmp, "c2.sqlite")
-	db, err := database.NewDB(dbPath, zap.NewNop())
+	db, err := database.NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
 	if err != nil {
 		t.Fatal(err)
 	}
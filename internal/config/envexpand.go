@@ -50,7 +50,8 @@ func expandEnvVar(s string) string {
 }
 
 // ExpandConfigEnv 展开 ExternalMCPServerConfig 中所有支持环境变量的字段。
-// 展开范围：Command、Args、Env values、URL、Headers values。
+// 展开范围：Command、Args、Env values、URL、Headers values、WorkingDir、EnvSecretFiles values、
+// BearerToken、CACertPath、ClientCertPath、ClientKeyPath，以及 OAuth 的 TokenURL/ClientID/ClientSecret。
 func ExpandConfigEnv(cfg *ExternalMCPServerConfig) {
 	cfg.Command = expandEnvVar(cfg.Command)
 	for i, arg := range cfg.Args {
@@ -63,4 +64,17 @@ func ExpandConfigEnv(cfg *ExternalMCPServerConfig) {
 	for k, v := range cfg.Headers {
 		cfg.Headers[k] = expandEnvVar(v)
 	}
+	cfg.WorkingDir = expandEnvVar(cfg.WorkingDir)
+	for k, v := range cfg.EnvSecretFiles {
+		cfg.EnvSecretFiles[k] = expandEnvVar(v)
+	}
+	cfg.BearerToken = expandEnvVar(cfg.BearerToken)
+	cfg.CACertPath = expandEnvVar(cfg.CACertPath)
+	cfg.ClientCertPath = expandEnvVar(cfg.ClientCertPath)
+	cfg.ClientKeyPath = expandEnvVar(cfg.ClientKeyPath)
+	if cfg.OAuth != nil {
+		cfg.OAuth.TokenURL = expandEnvVar(cfg.OAuth.TokenURL)
+		cfg.OAuth.ClientID = expandEnvVar(cfg.OAuth.ClientID)
+		cfg.OAuth.ClientSecret = expandEnvVar(cfg.OAuth.ClientSecret)
+	}
 }
@@ -48,11 +48,16 @@ func TestExpandConfigEnv(t *testing.T) {
 	defer os.Unsetenv("TEST_MCP_TOKEN")
 
 	cfg := &ExternalMCPServerConfig{
-		Command: "${TEST_MCP_CMD}",
-		Args:    []string{"--token", "${TEST_MCP_TOKEN}", "${MISSING:-default_arg}"},
-		Env:     map[string]string{"API_KEY": "${TEST_MCP_TOKEN}", "LEVEL": "${MISSING:-INFO}"},
-		URL:     "https://${MISSING:-example.com}/mcp",
-		Headers: map[string]string{"Authorization": "Bearer ${TEST_MCP_TOKEN}"},
+		Command:     "${TEST_MCP_CMD}",
+		Args:        []string{"--token", "${TEST_MCP_TOKEN}", "${MISSING:-default_arg}"},
+		Env:         map[string]string{"API_KEY": "${TEST_MCP_TOKEN}", "LEVEL": "${MISSING:-INFO}"},
+		URL:         "https://${MISSING:-example.com}/mcp",
+		Headers:     map[string]string{"Authorization": "Bearer ${TEST_MCP_TOKEN}"},
+		BearerToken: "${TEST_MCP_TOKEN}",
+		OAuth: &ExternalMCPOAuthConfig{
+			TokenURL:     "https://${MISSING:-example.com}/oauth/token",
+			ClientSecret: "${TEST_MCP_TOKEN}",
+		},
 	}
 
 	ExpandConfigEnv(cfg)
@@ -78,4 +83,13 @@ func TestExpandConfigEnv(t *testing.T) {
 	if cfg.Headers["Authorization"] != "Bearer secret123" {
 		t.Errorf("Headers[Authorization] = %q, want %q", cfg.Headers["Authorization"], "Bearer secret123")
 	}
+	if cfg.BearerToken != "secret123" {
+		t.Errorf("BearerToken = %q, want %q", cfg.BearerToken, "secret123")
+	}
+	if cfg.OAuth.TokenURL != "https://example.com/oauth/token" {
+		t.Errorf("OAuth.TokenURL = %q, want %q", cfg.OAuth.TokenURL, "https://example.com/oauth/token")
+	}
+	if cfg.OAuth.ClientSecret != "secret123" {
+		t.Errorf("OAuth.ClientSecret = %q, want %q", cfg.OAuth.ClientSecret, "secret123")
+	}
 }
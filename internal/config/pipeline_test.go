@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPipelineFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "recon.yaml")
+	content := `
+name: "pipeline_test_recon"
+enabled: true
+description: "test pipeline"
+parameters:
+  - name: "domain"
+    type: "string"
+    required: true
+steps:
+  - name: "discover"
+    tool: "subfinder"
+    params:
+      domain: "{{input.domain}}"
+  - name: "scan"
+    tool: "nuclei"
+    params:
+      target: "{{steps.discover.output}}"
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	pipeline, err := LoadPipelineFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadPipelineFromFile: %v", err)
+	}
+	if pipeline.Name != "pipeline_test_recon" {
+		t.Errorf("expected name pipeline_test_recon, got %q", pipeline.Name)
+	}
+	if len(pipeline.Steps) != 2 {
+		t.Fatalf("expected 2 steps, got %d", len(pipeline.Steps))
+	}
+	if pipeline.Steps[1].Tool != "nuclei" {
+		t.Errorf("expected second step tool nuclei, got %q", pipeline.Steps[1].Tool)
+	}
+
+	tool := pipeline.ToToolConfig()
+	if tool.Command != "internal:pipeline:pipeline_test_recon" {
+		t.Errorf("unexpected compiled command: %q", tool.Command)
+	}
+	if len(tool.PipelineSteps) != 2 {
+		t.Errorf("expected compiled tool to carry 2 pipeline steps, got %d", len(tool.PipelineSteps))
+	}
+}
+
+func TestLoadPipelineFromFile_MissingStepTool(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bad.yaml")
+	content := `
+name: "pipeline_missing_tool"
+enabled: true
+steps:
+  - name: "discover"
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	if _, err := LoadPipelineFromFile(path); err == nil {
+		t.Fatal("expected error for step missing tool")
+	}
+}
+
+func TestLoadPipelinesFromDir_MissingDir(t *testing.T) {
+	pipelines, err := LoadPipelinesFromDir(filepath.Join(t.TempDir(), "does-not-exist"))
+	if err != nil {
+		t.Fatalf("expected no error for missing dir, got %v", err)
+	}
+	if len(pipelines) != 0 {
+		t.Errorf("expected empty pipelines slice, got %d", len(pipelines))
+	}
+}
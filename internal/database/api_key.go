@@ -0,0 +1,161 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// apiKeyRawPrefix 是原始 key 的固定前缀，便于在日志/请求头中一眼识别这是一个 API Key 而非会话
+// token，同时作为 AuthMiddleware 快速跳过会话校验、直接尝试 API Key 校验的判定依据。
+const apiKeyRawPrefix = "csk_"
+
+// APIKey 是一个长期有效、可撤销的编程访问凭证（见 wivd/CyberStrikeAI#synth-3090），
+// 用于 CI 流水线/脚本等无法完成交互式登录的场景调用受 AuthMiddleware 保护的接口。
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	KeyPrefix  string     `json:"keyPrefix"` // 原始 key 的前 12 位，供列表展示辨认，不足以还原完整 key
+	Scopes     []string   `json:"scopes,omitempty"`
+	Revoked    bool       `json:"revoked"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// hashAPIKey 对原始 key 取 sha256 十六进制摘要；与 vulnerability.go 的指纹计算一致，只保存哈希，
+// 数据库泄露也无法还原出可用的凭证。
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey 生成一个新的 API Key 并写入数据库；rawKey 只在本次调用返回，数据库中不保存明文，
+// 遗失后只能撤销重建。scopes 为空表示不限制范围（拥有与登录会话等价的完整权限）。
+func (db *DB) CreateAPIKey(name string, scopes []string) (key *APIKey, rawKey string, err error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("名称不能为空")
+	}
+
+	randomBytes := make([]byte, 24)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, "", fmt.Errorf("生成密钥失败: %w", err)
+	}
+	rawKey = apiKeyRawPrefix + hex.EncodeToString(randomBytes)
+	keyPrefix := rawKey[:len(apiKeyRawPrefix)+8]
+
+	var scopesJSON string
+	if len(scopes) > 0 {
+		if raw, err := json.Marshal(scopes); err == nil {
+			scopesJSON = string(raw)
+		}
+	}
+
+	id := uuid.New().String()
+	now := time.Now()
+	_, err = db.Exec(
+		"INSERT INTO api_keys (id, name, key_prefix, key_hash, scopes, revoked, created_at) VALUES (?, ?, ?, ?, ?, 0, ?)",
+		id, name, keyPrefix, hashAPIKey(rawKey), scopesJSON, now,
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("创建 API Key 失败: %w", err)
+	}
+
+	key = &APIKey{
+		ID:        id,
+		Name:      name,
+		KeyPrefix: keyPrefix,
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	return key, rawKey, nil
+}
+
+// ListAPIKeys 列出全部 API Key（不含哈希/原始密钥）。
+func (db *DB) ListAPIKeys() ([]*APIKey, error) {
+	rows, err := db.Query(
+		"SELECT id, name, key_prefix, scopes, revoked, created_at, last_used_at FROM api_keys ORDER BY created_at DESC",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询 API Key 失败: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		var k APIKey
+		var scopesJSON sql.NullString
+		var revoked int
+		var createdAt string
+		var lastUsedAt sql.NullString
+		if err := rows.Scan(&k.ID, &k.Name, &k.KeyPrefix, &scopesJSON, &revoked, &createdAt, &lastUsedAt); err != nil {
+			return nil, fmt.Errorf("扫描 API Key 失败: %w", err)
+		}
+		k.Revoked = revoked != 0
+		k.CreatedAt = parseFlexibleTime(createdAt)
+		if scopesJSON.Valid && scopesJSON.String != "" {
+			_ = json.Unmarshal([]byte(scopesJSON.String), &k.Scopes)
+		}
+		if lastUsedAt.Valid && lastUsedAt.String != "" {
+			t := parseFlexibleTime(lastUsedAt.String)
+			k.LastUsedAt = &t
+		}
+		keys = append(keys, &k)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey 撤销一个 API Key，此后携带该 key 的请求将被 AuthMiddleware 拒绝。撤销是终态操作，
+// 与会话 token 的 RevokeToken 不同，这里不支持重新启用，需要时应创建一个新的 key。
+func (db *DB) RevokeAPIKey(id string) error {
+	res, err := db.Exec("UPDATE api_keys SET revoked = 1 WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("撤销 API Key 失败: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("API Key 不存在")
+	}
+	return nil
+}
+
+// ValidateAPIKey 校验原始 key 并返回其 scopes；命中且未撤销时同步更新 last_used_at（更新失败只记录日志）。
+// 实现 security.APIKeyValidator 接口（结构化实现，security 包无需依赖 database 包，避免循环引用）。
+func (db *DB) ValidateAPIKey(rawKey string) (scopes []string, ok bool) {
+	if !strings.HasPrefix(rawKey, apiKeyRawPrefix) {
+		return nil, false
+	}
+
+	var id string
+	var scopesJSON sql.NullString
+	var revoked int
+	err := db.QueryRow(
+		"SELECT id, scopes, revoked FROM api_keys WHERE key_hash = ?",
+		hashAPIKey(rawKey),
+	).Scan(&id, &scopesJSON, &revoked)
+	if err != nil {
+		return nil, false
+	}
+	if revoked != 0 {
+		return nil, false
+	}
+	if scopesJSON.Valid && scopesJSON.String != "" {
+		_ = json.Unmarshal([]byte(scopesJSON.String), &scopes)
+	}
+
+	if _, err := db.Exec("UPDATE api_keys SET last_used_at = ? WHERE id = ?", time.Now(), id); err != nil {
+		db.logger.Warn("更新 API Key 最后使用时间失败", zap.Error(err))
+	}
+	return scopes, true
+}
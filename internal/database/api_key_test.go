@@ -0,0 +1,58 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"cyberstrike-ai/internal/config"
+
+	"go.uber.org/zap"
+)
+
+func TestAPIKey_CreateValidateAndRevoke(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "api_key_test.db")
+	db, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	key, rawKey, err := db.CreateAPIKey("ci-pipeline", []string{"/api/agent-loop"})
+	if err != nil {
+		t.Fatalf("创建 API Key 失败: %v", err)
+	}
+	if rawKey == "" || key.ID == "" {
+		t.Fatalf("期望返回原始 key 与 ID，实际: rawKey=%q key=%+v", rawKey, key)
+	}
+
+	scopes, ok := db.ValidateAPIKey(rawKey)
+	if !ok {
+		t.Fatal("期望新创建的 key 校验通过")
+	}
+	if len(scopes) != 1 || scopes[0] != "/api/agent-loop" {
+		t.Fatalf("scopes 不匹配，实际: %v", scopes)
+	}
+
+	keys, err := db.ListAPIKeys()
+	if err != nil {
+		t.Fatalf("列出 API Key 失败: %v", err)
+	}
+	if len(keys) != 1 || keys[0].LastUsedAt == nil {
+		t.Fatalf("期望列出 1 条已有使用记录的 key，实际: %+v", keys)
+	}
+
+	if _, ok := db.ValidateAPIKey("csk_not-a-real-key"); ok {
+		t.Fatal("期望未知 key 校验失败")
+	}
+
+	if err := db.RevokeAPIKey(key.ID); err != nil {
+		t.Fatalf("撤销 API Key 失败: %v", err)
+	}
+	if _, ok := db.ValidateAPIKey(rawKey); ok {
+		t.Fatal("期望撤销后的 key 校验失败")
+	}
+
+	if err := db.RevokeAPIKey("does-not-exist"); err == nil {
+		t.Fatal("期望撤销不存在的 key 返回错误")
+	}
+}
@@ -0,0 +1,204 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"cyberstrike-ai/internal/security"
+
+	"github.com/google/uuid"
+)
+
+// Asset 资产台账条目：主机/域名/URL/服务，来自 nmap/httpx 解析结果与 FOFA 导入自动积累，也支持手动创建
+type Asset struct {
+	ID             string    `json:"id"`
+	Type           string    `json:"type"`  // host/domain/url/service
+	Value          string    `json:"value"` // 主要标识：IP/域名/URL
+	Host           string    `json:"host,omitempty"`
+	Port           string    `json:"port,omitempty"`
+	Service        string    `json:"service,omitempty"`
+	Source         string    `json:"source"` // nmap/httpx/fofa/manual
+	ConversationID string    `json:"conversationId,omitempty"`
+	Detail         string    `json:"detail,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+func scanAsset(row interface{ Scan(...interface{}) error }) (*Asset, error) {
+	var a Asset
+	var createdAt, updatedAt string
+	if err := row.Scan(&a.ID, &a.Type, &a.Value, &a.Host, &a.Port, &a.Service, &a.Source,
+		&a.ConversationID, &a.Detail, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	a.CreatedAt = parseFlexibleTime(createdAt)
+	a.UpdatedAt = parseFlexibleTime(updatedAt)
+	return &a, nil
+}
+
+const assetSelectColumns = "id, type, value, COALESCE(host, ''), COALESCE(port, ''), COALESCE(service, ''), source, COALESCE(conversation_id, ''), COALESCE(detail, ''), created_at, updated_at"
+
+// CreateAsset 手动创建资产
+func (db *DB) CreateAsset(assetType, value, host, port, service, source, conversationID, detail string) (*Asset, error) {
+	if source == "" {
+		source = "manual"
+	}
+	id := uuid.New().String()
+	now := time.Now()
+
+	_, err := db.Exec(
+		"INSERT INTO assets (id, type, value, host, port, service, source, conversation_id, detail, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		id, assetType, value, host, port, service, source, nullableString(conversationID), detail, now, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("创建资产失败: %w", err)
+	}
+
+	return &Asset{
+		ID: id, Type: assetType, Value: value, Host: host, Port: port, Service: service,
+		Source: source, ConversationID: conversationID, Detail: detail, CreatedAt: now, UpdatedAt: now,
+	}, nil
+}
+
+// UpsertAsset 按 (type, value, port) 去重落库：已存在则刷新 source/service/detail/updated_at，否则新建。
+// 用于 nmap/httpx 解析结果与 FOFA 导入的自动积累场景，避免重复扫描/重复导入产生大量重复条目。
+func (db *DB) UpsertAsset(assetType, value, host, port, service, source, conversationID, detail string) (*Asset, error) {
+	var existingID string
+	err := db.QueryRow(
+		"SELECT id FROM assets WHERE type = ? AND value = ? AND port = ?",
+		assetType, value, port,
+	).Scan(&existingID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("查询已存在资产失败: %w", err)
+	}
+
+	if err == sql.ErrNoRows {
+		return db.CreateAsset(assetType, value, host, port, service, source, conversationID, detail)
+	}
+
+	now := time.Now()
+	_, err = db.Exec(
+		"UPDATE assets SET host = ?, service = ?, source = ?, detail = ?, updated_at = ? WHERE id = ?",
+		host, service, source, detail, now, existingID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("更新资产失败: %w", err)
+	}
+	return db.GetAsset(existingID)
+}
+
+// UpsertAssetsFromFindings 将解析器产出的标准化 Finding 批量落库为资产：有端口的记为 service 资产，
+// 仅有主机信息的记为 host 资产；空 Host 的 Finding 会被跳过。返回实际写入/更新的资产数量。
+func (db *DB) UpsertAssetsFromFindings(findings []security.Finding, source, conversationID string) (int, error) {
+	count := 0
+	for _, f := range findings {
+		if f.Host == "" {
+			continue
+		}
+		assetType := "host"
+		if f.Port != "" {
+			assetType = "service"
+		}
+		if _, err := db.UpsertAsset(assetType, f.Host, f.Host, f.Port, f.Service, source, conversationID, f.Detail); err != nil {
+			return count, fmt.Errorf("落库资产失败: %w", err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// ListAssets 列出资产，assetType/source 为空表示不按该字段过滤
+func (db *DB) ListAssets(assetType, source string) ([]*Asset, error) {
+	query := "SELECT " + assetSelectColumns + " FROM assets WHERE 1 = 1"
+	var args []interface{}
+	if assetType != "" {
+		query += " AND type = ?"
+		args = append(args, assetType)
+	}
+	if source != "" {
+		query += " AND source = ?"
+		args = append(args, source)
+	}
+	query += " ORDER BY updated_at DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询资产列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var assets []*Asset
+	for rows.Next() {
+		a, err := scanAsset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描资产失败: %w", err)
+		}
+		assets = append(assets, a)
+	}
+	return assets, nil
+}
+
+// SearchAssets 按主机/值/服务/备注模糊匹配资产
+func (db *DB) SearchAssets(query string) ([]*Asset, error) {
+	pattern := "%" + query + "%"
+	rows, err := db.Query(
+		"SELECT "+assetSelectColumns+" FROM assets WHERE value LIKE ? OR host LIKE ? OR service LIKE ? OR detail LIKE ? ORDER BY updated_at DESC",
+		pattern, pattern, pattern, pattern,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("搜索资产失败: %w", err)
+	}
+	defer rows.Close()
+
+	var assets []*Asset
+	for rows.Next() {
+		a, err := scanAsset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描资产失败: %w", err)
+		}
+		assets = append(assets, a)
+	}
+	return assets, nil
+}
+
+// GetAsset 获取资产
+func (db *DB) GetAsset(id string) (*Asset, error) {
+	row := db.QueryRow("SELECT "+assetSelectColumns+" FROM assets WHERE id = ?", id)
+	a, err := scanAsset(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("资产不存在")
+		}
+		return nil, fmt.Errorf("查询资产失败: %w", err)
+	}
+	return a, nil
+}
+
+// UpdateAsset 更新资产
+func (db *DB) UpdateAsset(id, assetType, value, host, port, service, detail string) error {
+	_, err := db.Exec(
+		"UPDATE assets SET type = ?, value = ?, host = ?, port = ?, service = ?, detail = ?, updated_at = ? WHERE id = ?",
+		assetType, value, host, port, service, detail, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("更新资产失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteAsset 删除资产
+func (db *DB) DeleteAsset(id string) error {
+	_, err := db.Exec("DELETE FROM assets WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("删除资产失败: %w", err)
+	}
+	return nil
+}
+
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
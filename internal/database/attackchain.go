@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -16,15 +17,21 @@ type AttackChainNode struct {
 	ToolExecutionID string                 `json:"tool_execution_id,omitempty"`
 	Metadata        map[string]interface{} `json:"metadata"`
 	RiskScore       int                    `json:"risk_score"`
+	// EditedBy/EditedAt 记录分析人员手动编辑该节点的审计信息（见 handler.AttackChainHandler 的手动编辑接口）；
+	// AI/增量生成写入的节点二者均为空，仅人工创建/修改/纠正后才会被置上。
+	EditedBy string     `json:"edited_by,omitempty"`
+	EditedAt *time.Time `json:"edited_at,omitempty"`
 }
 
 // AttackChainEdge 攻击链边
 type AttackChainEdge struct {
-	ID     string `json:"id"`
-	Source string `json:"source"`
-	Target string `json:"target"`
-	Type   string `json:"type"` // leads_to, exploits, enables, depends_on
-	Weight int    `json:"weight"`
+	ID       string     `json:"id"`
+	Source   string     `json:"source"`
+	Target   string     `json:"target"`
+	Type     string     `json:"type"` // leads_to, exploits, enables, depends_on
+	Weight   int        `json:"weight"`
+	EditedBy string     `json:"edited_by,omitempty"`
+	EditedAt *time.Time `json:"edited_at,omitempty"`
 }
 
 // SaveAttackChainNode 保存攻击链节点
@@ -74,7 +81,7 @@ func (db *DB) SaveAttackChainEdge(conversationID, edgeID, sourceNodeID, targetNo
 // LoadAttackChainNodes 加载攻击链节点
 func (db *DB) LoadAttackChainNodes(conversationID string) ([]AttackChainNode, error) {
 	query := `
-		SELECT id, node_type, node_name, tool_execution_id, metadata, risk_score
+		SELECT id, node_type, node_name, tool_execution_id, metadata, risk_score, edited_by, edited_at
 		FROM attack_chain_nodes
 		WHERE conversation_id = ?
 		ORDER BY created_at ASC
@@ -91,8 +98,10 @@ func (db *DB) LoadAttackChainNodes(conversationID string) ([]AttackChainNode, er
 		var node AttackChainNode
 		var toolExecID sql.NullString
 		var metadataJSON sql.NullString
+		var editedBy sql.NullString
+		var editedAt sql.NullTime
 
-		err := rows.Scan(&node.ID, &node.Type, &node.Label, &toolExecID, &metadataJSON, &node.RiskScore)
+		err := rows.Scan(&node.ID, &node.Type, &node.Label, &toolExecID, &metadataJSON, &node.RiskScore, &editedBy, &editedAt)
 		if err != nil {
 			db.logger.Warn("扫描攻击链节点失败", zap.Error(err))
 			continue
@@ -111,6 +120,14 @@ func (db *DB) LoadAttackChainNodes(conversationID string) ([]AttackChainNode, er
 			node.Metadata = make(map[string]interface{})
 		}
 
+		if editedBy.Valid {
+			node.EditedBy = editedBy.String
+		}
+		if editedAt.Valid {
+			t := editedAt.Time
+			node.EditedAt = &t
+		}
+
 		nodes = append(nodes, node)
 	}
 
@@ -120,7 +137,7 @@ func (db *DB) LoadAttackChainNodes(conversationID string) ([]AttackChainNode, er
 // LoadAttackChainEdges 加载攻击链边
 func (db *DB) LoadAttackChainEdges(conversationID string) ([]AttackChainEdge, error) {
 	query := `
-		SELECT id, source_node_id, target_node_id, edge_type, weight
+		SELECT id, source_node_id, target_node_id, edge_type, weight, edited_by, edited_at
 		FROM attack_chain_edges
 		WHERE conversation_id = ?
 		ORDER BY created_at ASC
@@ -135,19 +152,106 @@ func (db *DB) LoadAttackChainEdges(conversationID string) ([]AttackChainEdge, er
 	var edges []AttackChainEdge
 	for rows.Next() {
 		var edge AttackChainEdge
+		var editedBy sql.NullString
+		var editedAt sql.NullTime
 
-		err := rows.Scan(&edge.ID, &edge.Source, &edge.Target, &edge.Type, &edge.Weight)
+		err := rows.Scan(&edge.ID, &edge.Source, &edge.Target, &edge.Type, &edge.Weight, &editedBy, &editedAt)
 		if err != nil {
 			db.logger.Warn("扫描攻击链边失败", zap.Error(err))
 			continue
 		}
 
+		if editedBy.Valid {
+			edge.EditedBy = editedBy.String
+		}
+		if editedAt.Valid {
+			t := editedAt.Time
+			edge.EditedAt = &t
+		}
+
 		edges = append(edges, edge)
 	}
 
 	return edges, nil
 }
 
+// SaveAttackChainNodeEdited 供分析人员手动创建/修改攻击链节点使用，与 SaveAttackChainNode（AI/增量生成写入）
+// 的区别仅在于额外写入 edited_by/edited_at 审计字段，便于事后区分节点是模型产出还是人工纠正。
+func (db *DB) SaveAttackChainNodeEdited(conversationID, nodeID, nodeType, nodeName, toolExecutionID, metadata string, riskScore int, editedBy string) error {
+	var toolExecID sql.NullString
+	if toolExecutionID != "" {
+		toolExecID = sql.NullString{String: toolExecutionID, Valid: true}
+	}
+
+	var metadataJSON sql.NullString
+	if metadata != "" {
+		metadataJSON = sql.NullString{String: metadata, Valid: true}
+	}
+
+	query := `
+		INSERT OR REPLACE INTO attack_chain_nodes
+		(id, conversation_id, node_type, node_name, tool_execution_id, metadata, risk_score, created_at, edited_by, edited_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?,
+			COALESCE((SELECT created_at FROM attack_chain_nodes WHERE id = ?), CURRENT_TIMESTAMP),
+			?, CURRENT_TIMESTAMP)
+	`
+
+	_, err := db.Exec(query, nodeID, conversationID, nodeType, nodeName, toolExecID, metadataJSON, riskScore, nodeID, editedBy)
+	if err != nil {
+		db.logger.Error("保存人工编辑的攻击链节点失败", zap.Error(err), zap.String("nodeId", nodeID))
+		return err
+	}
+
+	return nil
+}
+
+// SaveAttackChainEdgeEdited 供分析人员手动创建/修改攻击链边使用，原理同 SaveAttackChainNodeEdited。
+func (db *DB) SaveAttackChainEdgeEdited(conversationID, edgeID, sourceNodeID, targetNodeID, edgeType string, weight int, editedBy string) error {
+	query := `
+		INSERT OR REPLACE INTO attack_chain_edges
+		(id, conversation_id, source_node_id, target_node_id, edge_type, weight, created_at, edited_by, edited_at)
+		VALUES (?, ?, ?, ?, ?, ?,
+			COALESCE((SELECT created_at FROM attack_chain_edges WHERE id = ?), CURRENT_TIMESTAMP),
+			?, CURRENT_TIMESTAMP)
+	`
+
+	_, err := db.Exec(query, edgeID, conversationID, sourceNodeID, targetNodeID, edgeType, weight, edgeID, editedBy)
+	if err != nil {
+		db.logger.Error("保存人工编辑的攻击链边失败", zap.Error(err), zap.String("edgeId", edgeID))
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAttackChainEdge 删除单条攻击链边，用于分析人员手动移除模型幻觉出的边。
+func (db *DB) DeleteAttackChainEdge(conversationID, edgeID string) error {
+	_, err := db.Exec("DELETE FROM attack_chain_edges WHERE conversation_id = ? AND id = ?", conversationID, edgeID)
+	if err != nil {
+		db.logger.Warn("删除攻击链边失败", zap.Error(err), zap.String("edgeId", edgeID))
+	}
+	return err
+}
+
+// DeleteAttackChainEdges 仅删除对话的攻击链边，不影响节点；攻击链重新生成时节点按稳定 ID 原地增量更新
+// （见 attackchain.Builder.saveChain），而边不承载用户标注、成本低，直接整体清空重建。
+func (db *DB) DeleteAttackChainEdges(conversationID string) error {
+	_, err := db.Exec("DELETE FROM attack_chain_edges WHERE conversation_id = ?", conversationID)
+	if err != nil {
+		db.logger.Warn("删除攻击链边失败", zap.Error(err), zap.String("conversationId", conversationID))
+	}
+	return err
+}
+
+// DeleteAttackChainNode 删除单个攻击链节点，用于攻击链重新生成时清理本轮结果中不再出现的旧节点。
+func (db *DB) DeleteAttackChainNode(conversationID, nodeID string) error {
+	_, err := db.Exec("DELETE FROM attack_chain_nodes WHERE conversation_id = ? AND id = ?", conversationID, nodeID)
+	if err != nil {
+		db.logger.Warn("删除攻击链节点失败", zap.Error(err), zap.String("nodeId", nodeID))
+	}
+	return err
+}
+
 // DeleteAttackChain 删除对话的攻击链数据
 func (db *DB) DeleteAttackChain(conversationID string) error {
 	// 先删除边（因为有外键约束）
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// AuditLogEntry 是一条敏感操作审计记录（见 wivd/CyberStrikeAI#synth-3095）：登录、配置修改、
+// 工具启停、外部 MCP 添加、任务启动/取消、紧急停止等均落入这张统一的追加写入表，
+// 与专用的 login_audit_log（每次密码登录尝试的记录）、sensitive_reveal_audit_log（敏感参数揭示）互不重叠。
+type AuditLogEntry struct {
+	ID        string    `json:"id"`
+	Actor     string    `json:"actor"`  // 操作者标识：会话 subject、"password-login" 或 API Key 名称，未知时为空
+	Action    string    `json:"action"` // 动作类型，如 login/login_failed/password_change/tool_enable/external_mcp_add/task_cancel/emergency_stop
+	Target    string    `json:"target"` // 动作作用的对象，如工具名/MCP名/会话ID，可为空
+	Detail    string    `json:"detail"` // 补充信息，自由文本
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AuditForwarder 在每条审计记录写入数据库后被异步调用一次，用于转发到外部系统（如 syslog）。
+// 转发失败只记录日志，不影响审计记录本身已经落库这一事实。
+type AuditForwarder interface {
+	Forward(entry AuditLogEntry)
+}
+
+// RecordAudit 写入一条审计记录并在配置了转发器时异步转发；本身不返回转发结果，调用方无需等待。
+func (db *DB) RecordAudit(actor, action, target, detail, ip string) error {
+	entry := AuditLogEntry{
+		ID:        uuid.New().String(),
+		Actor:     actor,
+		Action:    action,
+		Target:    target,
+		Detail:    detail,
+		IP:        ip,
+		CreatedAt: time.Now(),
+	}
+
+	_, err := db.Exec(
+		"INSERT INTO audit_log (id, actor, action, target, detail, ip, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		entry.ID, entry.Actor, entry.Action, entry.Target, entry.Detail, entry.IP, entry.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("写入审计日志失败: %w", err)
+	}
+
+	if db.auditForwarder != nil {
+		go db.auditForwarder.Forward(entry)
+	}
+	return nil
+}
+
+// SetAuditForwarder 注入审计日志转发器（如 syslog），未调用时审计记录只落库，不对外转发。
+func (db *DB) SetAuditForwarder(forwarder AuditForwarder) {
+	db.auditForwarder = forwarder
+}
+
+// AuditLogFilter 是 GET /api/audit 支持的筛选条件，字段为空/零值表示不筛选。
+type AuditLogFilter struct {
+	Actor  string
+	Action string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// ListAuditLog 按筛选条件查询审计日志，默认按时间倒序、最多返回 200 条。
+func (db *DB) ListAuditLog(filter AuditLogFilter) ([]AuditLogEntry, error) {
+	query := "SELECT id, actor, action, target, detail, ip, created_at FROM audit_log WHERE 1=1"
+	var args []interface{}
+
+	if filter.Actor != "" {
+		query += " AND actor = ?"
+		args = append(args, filter.Actor)
+	}
+	if filter.Action != "" {
+		query += " AND action = ?"
+		args = append(args, filter.Action)
+	}
+	if !filter.Since.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query += " AND created_at <= ?"
+		args = append(args, filter.Until)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > 1000 {
+		limit = 200
+	}
+	query += " ORDER BY created_at DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询审计日志失败: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []AuditLogEntry
+	for rows.Next() {
+		var e AuditLogEntry
+		var createdAt string
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.Target, &e.Detail, &e.IP, &createdAt); err != nil {
+			return nil, fmt.Errorf("扫描审计日志失败: %w", err)
+		}
+		e.CreatedAt = parseFlexibleTime(createdAt)
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// SyslogForwarder 通过 UDP 以 RFC 3164 格式将审计记录转发到外部 syslog 服务器；用手写的最小实现
+// 而非标准库 log/syslog，因为后者仅支持本机 Unix domain socket/本地 syslogd，无法指向远程地址，
+// 且不支持 Windows 构建。
+type SyslogForwarder struct {
+	network string // "udp" 或 "tcp"
+	addr    string
+	tag     string
+	logger  *zap.Logger
+}
+
+// NewSyslogForwarder 创建一个 syslog 转发器；每次 Forward 独立建立连接发送，不维护长连接，
+// 避免网络抖动导致的连接状态管理复杂度，审计场景对延迟不敏感。
+func NewSyslogForwarder(network, addr, tag string, logger *zap.Logger) *SyslogForwarder {
+	if network == "" {
+		network = "udp"
+	}
+	if tag == "" {
+		tag = "cyberstrike-ai"
+	}
+	return &SyslogForwarder{network: network, addr: addr, tag: tag, logger: logger}
+}
+
+// Forward 实现 AuditForwarder。RFC 3164 severity 固定用 notice(5)、facility 用 local0(16)，
+// 优先级 = facility*8 + severity = 133。
+func (f *SyslogForwarder) Forward(entry AuditLogEntry) {
+	conn, err := net.DialTimeout(f.network, f.addr, 3*time.Second)
+	if err != nil {
+		if f.logger != nil {
+			f.logger.Warn("审计日志转发 syslog 失败", zap.Error(err))
+		}
+		return
+	}
+	defer conn.Close()
+
+	msg := fmt.Sprintf("<133>%s %s[audit]: actor=%s action=%s target=%s ip=%s detail=%s",
+		entry.CreatedAt.UTC().Format(time.RFC3339), f.tag, entry.Actor, entry.Action, entry.Target, entry.IP,
+		strings.ReplaceAll(entry.Detail, "\n", " "))
+
+	if _, err := conn.Write([]byte(msg)); err != nil && f.logger != nil {
+		f.logger.Warn("审计日志转发 syslog 失败", zap.Error(err))
+	}
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cyberstrike-ai/internal/config"
+
+	"go.uber.org/zap"
+)
+
+func TestAuditLog_RecordAndFilter(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "audit_log_test.db")
+	db, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.RecordAudit("admin", "login", "", "", "1.2.3.4"); err != nil {
+		t.Fatalf("记录审计日志失败: %v", err)
+	}
+	if err := db.RecordAudit("", "tool_enable", "nmap", "", "1.2.3.4"); err != nil {
+		t.Fatalf("记录审计日志失败: %v", err)
+	}
+	if err := db.RecordAudit("admin", "login", "", "", "5.6.7.8"); err != nil {
+		t.Fatalf("记录审计日志失败: %v", err)
+	}
+
+	entries, err := db.ListAuditLog(AuditLogFilter{Action: "login"})
+	if err != nil {
+		t.Fatalf("查询审计日志失败: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("期望 2 条 login 审计记录，实际: %d", len(entries))
+	}
+
+	entries, err = db.ListAuditLog(AuditLogFilter{Actor: "admin", Action: "login"})
+	if err != nil {
+		t.Fatalf("查询审计日志失败: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("期望 2 条 actor=admin 的 login 审计记录，实际: %d", len(entries))
+	}
+
+	entries, err = db.ListAuditLog(AuditLogFilter{Action: "tool_enable"})
+	if err != nil {
+		t.Fatalf("查询审计日志失败: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Target != "nmap" {
+		t.Fatalf("期望 1 条 target=nmap 的 tool_enable 审计记录，实际: %+v", entries)
+	}
+}
+
+type fakeAuditForwarder struct {
+	forwarded chan AuditLogEntry
+}
+
+func (f *fakeAuditForwarder) Forward(entry AuditLogEntry) {
+	f.forwarded <- entry
+}
+
+func TestAuditLog_ForwardsToRegisteredForwarder(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "audit_log_forward_test.db")
+	db, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	forwarder := &fakeAuditForwarder{forwarded: make(chan AuditLogEntry, 1)}
+	db.SetAuditForwarder(forwarder)
+
+	if err := db.RecordAudit("admin", "emergency_stop", "", "", "127.0.0.1"); err != nil {
+		t.Fatalf("记录审计日志失败: %v", err)
+	}
+
+	select {
+	case entry := <-forwarder.forwarded:
+		if entry.Action != "emergency_stop" {
+			t.Fatalf("期望转发 emergency_stop 事件，实际: %+v", entry)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("超时未收到转发的审计记录")
+	}
+}
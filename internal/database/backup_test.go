@@ -0,0 +1,101 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"cyberstrike-ai/internal/config"
+
+	"go.uber.org/zap"
+)
+
+func TestBackupAndRestore_SQLiteRoundTrip(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "backup_test.db")
+	db, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	conv, err := db.CreateConversation("备份测试对话")
+	if err != nil {
+		t.Fatalf("创建对话失败: %v", err)
+	}
+
+	backupDir := filepath.Join(t.TempDir(), "backups")
+	info, err := db.Backup(backupDir)
+	if err != nil {
+		t.Fatalf("备份失败: %v", err)
+	}
+	if info.SizeBytes <= 0 {
+		t.Fatalf("备份文件大小应大于 0，实际: %d", info.SizeBytes)
+	}
+
+	backups, err := ListBackups(backupDir)
+	if err != nil {
+		t.Fatalf("列出备份失败: %v", err)
+	}
+	if len(backups) != 1 || backups[0].Filename != info.Filename {
+		t.Fatalf("期望列出刚创建的 1 份备份，实际: %+v", backups)
+	}
+
+	// 备份之后再创建一个新对话，模拟备份之后发生的数据变更；恢复后应该看不到它。
+	if _, err := db.CreateConversation("备份之后新增的对话"); err != nil {
+		t.Fatalf("创建对话失败: %v", err)
+	}
+
+	if err := db.Restore(filepath.Join(backupDir, info.Filename)); err != nil {
+		t.Fatalf("恢复失败: %v", err)
+	}
+
+	restoredConvs, err := db.ListConversations(10, 0, "")
+	if err != nil {
+		t.Fatalf("恢复后列出对话失败: %v", err)
+	}
+	if len(restoredConvs) != 1 || restoredConvs[0].ID != conv.ID {
+		t.Fatalf("恢复后应只剩备份时点的 1 个对话，实际: %+v", restoredConvs)
+	}
+}
+
+func TestBackupConfig_RetentionCountPrunesOldBackups(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "prune_test.db")
+	db, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	backupDir := filepath.Join(t.TempDir(), "backups")
+	var filenames []string
+	for i := 0; i < 3; i++ {
+		info, err := db.Backup(backupDir)
+		if err != nil {
+			t.Fatalf("第 %d 次备份失败: %v", i, err)
+		}
+		filenames = append(filenames, info.Filename)
+	}
+	if len(uniqueStrings(filenames)) != 3 {
+		t.Fatalf("3 次备份应产生 3 个不同文件名，实际: %v", filenames)
+	}
+
+	pruneBackups(backupDir, 1)
+	backups, err := ListBackups(backupDir)
+	if err != nil {
+		t.Fatalf("列出备份失败: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("保留数量为 1 时清理后应只剩 1 份备份，实际: %d", len(backups))
+	}
+}
+
+func uniqueStrings(in []string) []string {
+	seen := make(map[string]struct{})
+	var out []string
+	for _, s := range in {
+		if _, ok := seen[s]; !ok {
+			seen[s] = struct{}{}
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SaveCheckpoint 保存（覆盖）某会话正在运行任务的 messages 快照与迭代计数，实现 agent.CheckpointSaver。
+func (db *DB) SaveCheckpoint(conversationID, messagesJSON string, iteration int, roleToolsJSON string) error {
+	_, err := db.Exec(`
+		INSERT INTO agent_checkpoints (conversation_id, messages_json, iteration, role_tools_json, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(conversation_id) DO UPDATE SET
+			messages_json = excluded.messages_json,
+			iteration = excluded.iteration,
+			role_tools_json = excluded.role_tools_json,
+			updated_at = excluded.updated_at
+	`, conversationID, messagesJSON, iteration, roleToolsJSON, time.Now())
+	if err != nil {
+		return fmt.Errorf("保存Agent检查点失败: %w", err)
+	}
+	return nil
+}
+
+// GetCheckpoint 读取某会话的检查点；不存在时 found 为 false。
+func (db *DB) GetCheckpoint(conversationID string) (messagesJSON string, iteration int, roleToolsJSON string, found bool, err error) {
+	var roleTools sql.NullString
+	row := db.QueryRow(
+		"SELECT messages_json, iteration, role_tools_json FROM agent_checkpoints WHERE conversation_id = ?",
+		conversationID,
+	)
+	if scanErr := row.Scan(&messagesJSON, &iteration, &roleTools); scanErr != nil {
+		if scanErr == sql.ErrNoRows {
+			return "", 0, "", false, nil
+		}
+		return "", 0, "", false, fmt.Errorf("查询Agent检查点失败: %w", scanErr)
+	}
+	return messagesJSON, iteration, roleTools.String, true, nil
+}
+
+// ClearCheckpoint 删除某会话的检查点（任务正常结束后调用）。
+func (db *DB) ClearCheckpoint(conversationID string) error {
+	if _, err := db.Exec("DELETE FROM agent_checkpoints WHERE conversation_id = ?", conversationID); err != nil {
+		return fmt.Errorf("清除Agent检查点失败: %w", err)
+	}
+	return nil
+}
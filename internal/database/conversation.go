@@ -21,6 +21,11 @@ type Conversation struct {
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 	Messages  []Message `json:"messages,omitempty"`
+	// ReportTemplate 是该会话导出报告时使用的自定义模板名（reports/templates/ 下的文件名，不含扩展名），
+	// 留空表示使用内置的默认报告布局，见 handler.ReportTemplateHandler/report.RenderWithTemplate。
+	ReportTemplate string `json:"reportTemplate,omitempty"`
+	// ProjectID 是该会话归属的项目/交战 ID（见 database.Project），留空表示未归属任何项目。
+	ProjectID string `json:"projectId,omitempty"`
 }
 
 // Message 消息
@@ -31,8 +36,13 @@ type Message struct {
 	Content         string                   `json:"content"`
 	MCPExecutionIDs []string                 `json:"mcpExecutionIds,omitempty"`
 	ProcessDetails  []map[string]interface{} `json:"processDetails,omitempty"`
-	CreatedAt       time.Time                `json:"createdAt"`
-	UpdatedAt       time.Time                `json:"updatedAt"`
+	// ParentMessageID 非空时，本消息与其他共享同一 ParentMessageID 的消息互为重新生成产生的兄弟分支
+	// （见 RegenerateMessage）；对普通消息而言就是所在轮次的 user 消息 ID。
+	ParentMessageID string `json:"parentMessageId,omitempty"`
+	// IsActiveBranch 标记该分支当前是否为对外展示/参与后续对话历史的分支，见 SwitchMessageBranch。
+	IsActiveBranch bool      `json:"isActiveBranch"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
 }
 
 // CreateConversation 创建新对话
@@ -187,9 +197,9 @@ func (db *DB) GetConversation(id string) (*Conversation, error) {
 	var pinned int
 
 	err := db.QueryRow(
-		"SELECT id, title, pinned, created_at, updated_at FROM conversations WHERE id = ?",
+		"SELECT id, title, pinned, created_at, updated_at, COALESCE(report_template, ''), COALESCE(project_id, '') FROM conversations WHERE id = ?",
 		id,
-	).Scan(&conv.ID, &conv.Title, &pinned, &createdAt, &updatedAt)
+	).Scan(&conv.ID, &conv.Title, &pinned, &createdAt, &updatedAt, &conv.ReportTemplate, &conv.ProjectID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("对话不存在")
@@ -316,7 +326,7 @@ func (db *DB) ListConversations(limit, offset int, search string) ([]*Conversati
 		// 使用 EXISTS 子查询代替 LEFT JOIN + DISTINCT，避免大表笛卡尔积
 		searchPattern := "%" + search + "%"
 		rows, err = db.Query(
-			`SELECT c.id, c.title, COALESCE(c.pinned, 0), c.created_at, c.updated_at
+			`SELECT c.id, c.title, COALESCE(c.pinned, 0), c.created_at, c.updated_at, COALESCE(c.project_id, '')
 			 FROM conversations c
 			 WHERE c.title LIKE ?
 			    OR EXISTS (SELECT 1 FROM messages m WHERE m.conversation_id = c.id AND m.content LIKE ?)
@@ -326,7 +336,7 @@ func (db *DB) ListConversations(limit, offset int, search string) ([]*Conversati
 		)
 	} else {
 		rows, err = db.Query(
-			"SELECT id, title, COALESCE(pinned, 0), created_at, updated_at FROM conversations ORDER BY updated_at DESC LIMIT ? OFFSET ?",
+			"SELECT id, title, COALESCE(pinned, 0), created_at, updated_at, COALESCE(project_id, '') FROM conversations ORDER BY updated_at DESC LIMIT ? OFFSET ?",
 			limit, offset,
 		)
 	}
@@ -342,7 +352,7 @@ func (db *DB) ListConversations(limit, offset int, search string) ([]*Conversati
 		var createdAt, updatedAt string
 		var pinned int
 
-		if err := rows.Scan(&conv.ID, &conv.Title, &pinned, &createdAt, &updatedAt); err != nil {
+		if err := rows.Scan(&conv.ID, &conv.Title, &pinned, &createdAt, &updatedAt, &conv.ProjectID); err != nil {
 			return nil, fmt.Errorf("扫描对话失败: %w", err)
 		}
 
@@ -385,6 +395,19 @@ func (db *DB) UpdateConversationTitle(id, title string) error {
 	return nil
 }
 
+// UpdateConversationReportTemplate 设置该会话导出报告时使用的自定义模板名（reports/templates/ 下的文件名，不含扩展名）；
+// 传入空字符串表示恢复使用内置的默认报告布局。
+func (db *DB) UpdateConversationReportTemplate(id, templateName string) error {
+	_, err := db.Exec(
+		"UPDATE conversations SET report_template = ? WHERE id = ?",
+		templateName, id,
+	)
+	if err != nil {
+		return fmt.Errorf("更新报告模板失败: %w", err)
+	}
+	return nil
+}
+
 // UpdateConversationTime 更新对话时间
 func (db *DB) UpdateConversationTime(id string) error {
 	_, err := db.Exec(
@@ -484,6 +507,13 @@ func (db *DB) ConversationHasToolProcessDetails(conversationID string) (bool, er
 
 // AddMessage 添加消息
 func (db *DB) AddMessage(conversationID, role, content string, mcpExecutionIDs []string) (*Message, error) {
+	return db.AddMessageWithParent(conversationID, role, content, mcpExecutionIDs, "")
+}
+
+// AddMessageWithParent 与 AddMessage 相同，额外指定 parentMessageID（重新生成产生的分支消息需要
+// 与原消息共享同一 parentMessageID，见 RegenerateMessage）；parentMessageID 留空等价于 AddMessage。
+// 新消息总是以激活分支（IsActiveBranch=true）写入。
+func (db *DB) AddMessageWithParent(conversationID, role, content string, mcpExecutionIDs []string, parentMessageID string) (*Message, error) {
 	id := uuid.New().String()
 	now := time.Now()
 
@@ -497,9 +527,14 @@ func (db *DB) AddMessage(conversationID, role, content string, mcpExecutionIDs [
 		}
 	}
 
+	var parentID sql.NullString
+	if parentMessageID != "" {
+		parentID = sql.NullString{String: parentMessageID, Valid: true}
+	}
+
 	_, err := db.Exec(
-		"INSERT INTO messages (id, conversation_id, role, content, mcp_execution_ids, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
-		id, conversationID, role, content, mcpIDsJSON, now, now,
+		"INSERT INTO messages (id, conversation_id, role, content, mcp_execution_ids, parent_message_id, is_active_branch, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, 1, ?, ?)",
+		id, conversationID, role, content, mcpIDsJSON, parentID, now, now,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("添加消息失败: %w", err)
@@ -516,6 +551,8 @@ func (db *DB) AddMessage(conversationID, role, content string, mcpExecutionIDs [
 		Role:            role,
 		Content:         content,
 		MCPExecutionIDs: mcpExecutionIDs,
+		ParentMessageID: parentMessageID,
+		IsActiveBranch:  true,
 		CreatedAt:       now,
 		UpdatedAt:       now,
 	}
@@ -523,10 +560,10 @@ func (db *DB) AddMessage(conversationID, role, content string, mcpExecutionIDs [
 	return message, nil
 }
 
-// GetMessages 获取对话的所有消息
+// GetMessages 获取对话中当前激活分支的全部消息（未使用过重新生成的消息只有一个分支，始终激活）。
 func (db *DB) GetMessages(conversationID string) ([]Message, error) {
 	rows, err := db.Query(
-		"SELECT id, conversation_id, role, content, mcp_execution_ids, created_at, updated_at FROM messages WHERE conversation_id = ? ORDER BY created_at ASC",
+		"SELECT id, conversation_id, role, content, mcp_execution_ids, parent_message_id, is_active_branch, created_at, updated_at FROM messages WHERE conversation_id = ? AND is_active_branch = 1 ORDER BY created_at ASC",
 		conversationID,
 	)
 	if err != nil {
@@ -538,12 +575,16 @@ func (db *DB) GetMessages(conversationID string) ([]Message, error) {
 	for rows.Next() {
 		var msg Message
 		var mcpIDsJSON sql.NullString
+		var parentMessageID sql.NullString
+		var isActiveBranch int
 		var createdAt string
 		var updatedAt sql.NullString
 
-		if err := rows.Scan(&msg.ID, &msg.ConversationID, &msg.Role, &msg.Content, &mcpIDsJSON, &createdAt, &updatedAt); err != nil {
+		if err := rows.Scan(&msg.ID, &msg.ConversationID, &msg.Role, &msg.Content, &mcpIDsJSON, &parentMessageID, &isActiveBranch, &createdAt, &updatedAt); err != nil {
 			return nil, fmt.Errorf("扫描消息失败: %w", err)
 		}
+		msg.ParentMessageID = parentMessageID.String
+		msg.IsActiveBranch = isActiveBranch != 0
 
 		// 尝试多种时间格式解析
 		var err error
@@ -678,6 +719,318 @@ func (db *DB) DeleteConversationTurn(conversationID, anchorMessageID string) (de
 	return deletedIDs, nil
 }
 
+// RegenerateMessage 为「重新生成」准备一条新的 assistant 分支：anchorMessageID 必须是对话最后一轮的
+// assistant 回复（中间轮次重新生成会让后续轮次失去上下文依据，暂不支持，调用方可先用
+// DeleteConversationTurn 删除该轮之后的内容再重试）。原分支（anchorMessageID 及其同轮的其余 assistant
+// 消息，若因历史原因存在多条）被标记为非激活，随后插入一条共享同一 parentMessageID（该轮的 user
+// 消息 ID）的新 assistant 占位消息并返回；userMessage 是该轮的 user 消息，history 是它之前的全部
+// 上文（均不含被替换的旧回复），与 AgentHandler.ProcessMessageForRobot 里"上文 + 最新一条用户消息"
+// 分离传参给 agent.AgentLoopWithProgress 的方式一致，调用方应据此重新触发一次 agent 执行并把结果
+// 写回该占位消息。
+func (db *DB) RegenerateMessage(conversationID, anchorMessageID string) (placeholder *Message, userMessage *Message, history []Message, err error) {
+	msgs, err := db.GetMessages(conversationID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	idx := -1
+	for i := range msgs {
+		if msgs[i].ID == anchorMessageID {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil, nil, nil, fmt.Errorf("message not found")
+	}
+	if msgs[idx].Role != "assistant" {
+		return nil, nil, nil, fmt.Errorf("只能重新生成 assistant 消息")
+	}
+	start, end, err := turnSliceRange(msgs, anchorMessageID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if end != len(msgs) {
+		return nil, nil, nil, fmt.Errorf("仅支持重新生成最后一轮的回复")
+	}
+	parentMessageID := msgs[start].ID
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for i := start; i < end; i++ {
+		if msgs[i].Role != "assistant" {
+			continue
+		}
+		// 老数据的 assistant 消息没有 parent_message_id，首次重新生成时一并回填，
+		// 使其与新分支共享同一 parentMessageID，能被 ListMessageBranches 一起列出。
+		if _, err := tx.Exec(
+			"UPDATE messages SET is_active_branch = 0, parent_message_id = ? WHERE id = ?",
+			parentMessageID, msgs[i].ID,
+		); err != nil {
+			return nil, nil, nil, fmt.Errorf("停用旧分支失败: %w", err)
+		}
+	}
+
+	newID := uuid.New().String()
+	now := time.Now()
+	if _, err := tx.Exec(
+		"INSERT INTO messages (id, conversation_id, role, content, parent_message_id, is_active_branch, created_at, updated_at) VALUES (?, ?, 'assistant', ?, ?, 1, ?, ?)",
+		newID, conversationID, "处理中...", parentMessageID, now, now,
+	); err != nil {
+		return nil, nil, nil, fmt.Errorf("创建新分支失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, nil, fmt.Errorf("commit: %w", err)
+	}
+
+	if err := db.UpdateConversationTime(conversationID); err != nil {
+		db.logger.Warn("更新对话时间失败", zap.Error(err))
+	}
+
+	placeholder = &Message{
+		ID:              newID,
+		ConversationID:  conversationID,
+		Role:            "assistant",
+		Content:         "处理中...",
+		ParentMessageID: parentMessageID,
+		IsActiveBranch:  true,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	userMessage = &msgs[start]
+	history = msgs[:start]
+
+	db.logger.Info("message regenerated",
+		zap.String("conversationId", conversationID),
+		zap.String("anchorMessageId", anchorMessageID),
+		zap.String("newMessageId", newID),
+	)
+	return placeholder, userMessage, history, nil
+}
+
+// ListMessageBranches 列出 parentMessageID 下的全部兄弟分支（按创建时间升序），供前端展示
+// 「第 N/M 个回复」及切换分支的候选列表；未使用过重新生成的消息只有唯一一条记录。
+func (db *DB) ListMessageBranches(parentMessageID string) ([]Message, error) {
+	rows, err := db.Query(
+		"SELECT id, conversation_id, role, content, mcp_execution_ids, parent_message_id, is_active_branch, created_at, updated_at FROM messages WHERE parent_message_id = ? ORDER BY created_at ASC",
+		parentMessageID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询分支失败: %w", err)
+	}
+	defer rows.Close()
+
+	var branches []Message
+	for rows.Next() {
+		var msg Message
+		var mcpIDsJSON sql.NullString
+		var parentID sql.NullString
+		var isActiveBranch int
+		var createdAt string
+		var updatedAt sql.NullString
+		if err := rows.Scan(&msg.ID, &msg.ConversationID, &msg.Role, &msg.Content, &mcpIDsJSON, &parentID, &isActiveBranch, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("扫描分支失败: %w", err)
+		}
+		msg.ParentMessageID = parentID.String
+		msg.IsActiveBranch = isActiveBranch != 0
+		msg.CreatedAt = parseFlexibleTime(createdAt)
+		if updatedAt.Valid && strings.TrimSpace(updatedAt.String) != "" {
+			msg.UpdatedAt = parseFlexibleTime(updatedAt.String)
+		} else {
+			msg.UpdatedAt = msg.CreatedAt
+		}
+		if mcpIDsJSON.Valid && mcpIDsJSON.String != "" {
+			if err := json.Unmarshal([]byte(mcpIDsJSON.String), &msg.MCPExecutionIDs); err != nil {
+				db.logger.Warn("解析MCP执行ID失败", zap.Error(err))
+			}
+		}
+		branches = append(branches, msg)
+	}
+	return branches, nil
+}
+
+// SwitchMessageBranch 把 messageID 所在分支设为激活分支，同时停用同一 parentMessageID 下的其余
+// 兄弟分支；纯粹的可见性切换，不会重新调用 agent，也不影响任何分支的历史内容。
+func (db *DB) SwitchMessageBranch(conversationID, messageID string) error {
+	var parentMessageID sql.NullString
+	err := db.QueryRow("SELECT parent_message_id FROM messages WHERE id = ? AND conversation_id = ?", messageID, conversationID).Scan(&parentMessageID)
+	if err != nil {
+		return fmt.Errorf("消息不存在: %w", err)
+	}
+	if !parentMessageID.Valid || parentMessageID.String == "" {
+		return fmt.Errorf("该消息没有可切换的分支")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec("UPDATE messages SET is_active_branch = 0 WHERE parent_message_id = ?", parentMessageID.String); err != nil {
+		return fmt.Errorf("停用兄弟分支失败: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE messages SET is_active_branch = 1 WHERE id = ?", messageID); err != nil {
+		return fmt.Errorf("激活分支失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	if err := db.UpdateConversationTime(conversationID); err != nil {
+		db.logger.Warn("更新对话时间失败", zap.Error(err))
+	}
+	return nil
+}
+
+// MergeConversations 将 sourceID 对话合并进 targetID：消息、过程详情、漏洞记录一并迁移，合并后按
+// GetMessages 固有的 created_at 排序自然按时间戳交错，无需额外处理。双方原有的攻击链拓扑均以合并前
+// 各自独立的消息集合为前提，合并后不再成立，一并清除，交由调用方通过攻击链重新生成接口重建。
+// 合并完成后 sourceID 被删除，返回合并后的 targetID 对话。
+func (db *DB) MergeConversations(sourceID, targetID string) (*Conversation, error) {
+	if sourceID == "" || targetID == "" {
+		return nil, fmt.Errorf("sourceId 和 targetId 均不能为空")
+	}
+	if sourceID == targetID {
+		return nil, fmt.Errorf("不能将对话与自身合并")
+	}
+	if _, err := db.GetConversationLite(sourceID); err != nil {
+		return nil, fmt.Errorf("源对话不存在: %w", err)
+	}
+	if _, err := db.GetConversationLite(targetID); err != nil {
+		return nil, fmt.Errorf("目标对话不存在: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec("UPDATE messages SET conversation_id = ? WHERE conversation_id = ?", targetID, sourceID); err != nil {
+		return nil, fmt.Errorf("迁移消息失败: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE process_details SET conversation_id = ? WHERE conversation_id = ?", targetID, sourceID); err != nil {
+		return nil, fmt.Errorf("迁移过程详情失败: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE vulnerabilities SET conversation_id = ? WHERE conversation_id = ?", targetID, sourceID); err != nil {
+		return nil, fmt.Errorf("迁移漏洞记录失败: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM attack_chain_edges WHERE conversation_id IN (?, ?)", sourceID, targetID); err != nil {
+		return nil, fmt.Errorf("清理攻击链边失败: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM attack_chain_nodes WHERE conversation_id IN (?, ?)", sourceID, targetID); err != nil {
+		return nil, fmt.Errorf("清理攻击链节点失败: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM knowledge_retrieval_logs WHERE conversation_id = ?", sourceID); err != nil {
+		db.logger.Warn("删除知识检索日志失败", zap.String("conversationId", sourceID), zap.Error(err))
+	}
+	if _, err := tx.Exec("DELETE FROM conversations WHERE id = ?", sourceID); err != nil {
+		return nil, fmt.Errorf("删除源对话失败: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE conversations SET updated_at = ? WHERE id = ?", time.Now(), targetID); err != nil {
+		return nil, fmt.Errorf("更新目标对话时间失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	db.logger.Info("对话合并完成", zap.String("sourceId", sourceID), zap.String("targetId", targetID))
+	return db.GetConversationLite(targetID)
+}
+
+// SplitConversation 在锚点消息所在轮次的起点将对话一分为二：锚点所在轮次及其后的消息移入新建对话，
+// 原对话保留锚点之前的消息；newTitle 为空时沿用原标题并追加"（拆分）"后缀。漏洞记录仍归属原对话
+// （其发现时机与轮次边界无直接对应关系），但两侧的攻击链拓扑随消息集合变化而失效，原对话一侧一并
+// 清除，交由调用方通过攻击链重新生成接口重建；新对话此时还没有攻击链数据，无需清理。
+func (db *DB) SplitConversation(conversationID, anchorMessageID, newTitle string) (*Conversation, error) {
+	msgs, err := db.GetMessages(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	start, _, err := turnSliceRange(msgs, anchorMessageID)
+	if err != nil {
+		return nil, err
+	}
+	if start <= 0 {
+		return nil, fmt.Errorf("锚点位于对话开头，无法拆分")
+	}
+
+	movedIDs := make([]string, 0, len(msgs)-start)
+	for i := start; i < len(msgs); i++ {
+		movedIDs = append(movedIDs, msgs[i].ID)
+	}
+	if len(movedIDs) == 0 {
+		return nil, fmt.Errorf("锚点之后没有可拆分的消息")
+	}
+
+	if newTitle == "" {
+		orig, err := db.GetConversationLite(conversationID)
+		if err != nil {
+			return nil, fmt.Errorf("原对话不存在: %w", err)
+		}
+		newTitle = orig.Title + "（拆分）"
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	newID := uuid.New().String()
+	now := time.Now()
+	if _, err := tx.Exec(
+		"INSERT INTO conversations (id, title, created_at, updated_at) VALUES (?, ?, ?, ?)",
+		newID, newTitle, now, now,
+	); err != nil {
+		return nil, fmt.Errorf("创建新对话失败: %w", err)
+	}
+
+	ph := strings.Repeat("?,", len(movedIDs))
+	ph = ph[:len(ph)-1]
+	idArgs := make([]interface{}, len(movedIDs))
+	for i, id := range movedIDs {
+		idArgs[i] = id
+	}
+
+	msgArgs := append([]interface{}{newID}, idArgs...)
+	if _, err := tx.Exec("UPDATE messages SET conversation_id = ? WHERE id IN ("+ph+")", msgArgs...); err != nil {
+		return nil, fmt.Errorf("迁移消息失败: %w", err)
+	}
+
+	pdArgs := append([]interface{}{newID}, idArgs...)
+	if _, err := tx.Exec("UPDATE process_details SET conversation_id = ? WHERE message_id IN ("+ph+")", pdArgs...); err != nil {
+		return nil, fmt.Errorf("迁移过程详情失败: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM attack_chain_edges WHERE conversation_id = ?", conversationID); err != nil {
+		return nil, fmt.Errorf("清理攻击链边失败: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM attack_chain_nodes WHERE conversation_id = ?", conversationID); err != nil {
+		return nil, fmt.Errorf("清理攻击链节点失败: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE conversations SET updated_at = ? WHERE id = ?", now, conversationID); err != nil {
+		return nil, fmt.Errorf("更新原对话时间失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	db.logger.Info("对话拆分完成",
+		zap.String("conversationId", conversationID),
+		zap.String("newConversationId", newID),
+		zap.Int("movedMessageCount", len(movedIDs)),
+	)
+	return db.GetConversationLite(newID)
+}
+
 // ProcessDetail 过程详情事件
 type ProcessDetail struct {
 	ID             string    `json:"id"`
@@ -0,0 +1,243 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"cyberstrike-ai/internal/mcp"
+
+	"go.uber.org/zap"
+)
+
+// ConversationArchiveFormatVersion 是导出归档 JSON 的结构版本号，随 ConversationArchive
+// 字段发生不兼容变化时递增；ImportConversationArchive 拒绝导入版本号大于当前值的归档，
+// 避免旧版本静默丢字段导入一份看似成功、实则残缺的会话。
+const ConversationArchiveFormatVersion = 1
+
+// ConversationArchive 是单个对话及其关联数据的可迁移快照，用于 GET /api/conversations/:id/export
+// 与 POST /api/conversations/import：把一次渗透测试会话（对话、消息、过程详情、命中的漏洞、
+// 攻击链）整体导出为一份 JSON 文件，供跨实例备份或转移到另一套部署。
+type ConversationArchive struct {
+	FormatVersion    int                  `json:"formatVersion"`
+	ExportedAt       time.Time            `json:"exportedAt"`
+	Conversation     *Conversation        `json:"conversation"`
+	ToolExecutions   []*mcp.ToolExecution `json:"toolExecutions,omitempty"`
+	Vulnerabilities  []*Vulnerability     `json:"vulnerabilities,omitempty"`
+	AttackChainNodes []AttackChainNode    `json:"attackChainNodes,omitempty"`
+	AttackChainEdges []AttackChainEdge    `json:"attackChainEdges,omitempty"`
+}
+
+// ExportConversationArchive 汇总一次对话的完整可迁移快照：对话本身（含消息与过程详情，由
+// GetConversation 加载）、消息中引用到的工具执行记录、该对话下的全部漏洞、以及已保存的攻击链。
+// 工具执行记录按 ID 逐条最优努力获取，单条缺失不影响整体导出（历史数据可能已被清理）。
+func (db *DB) ExportConversationArchive(conversationID string) (*ConversationArchive, error) {
+	conv, err := db.GetConversation(conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	execIDSet := make(map[string]struct{})
+	for _, msg := range conv.Messages {
+		for _, id := range msg.MCPExecutionIDs {
+			execIDSet[id] = struct{}{}
+		}
+	}
+	execIDs := make([]string, 0, len(execIDSet))
+	for id := range execIDSet {
+		execIDs = append(execIDs, id)
+	}
+	toolExecutions, err := db.GetToolExecutionsByIds(execIDs)
+	if err != nil {
+		db.logger.Warn("导出对话时加载工具执行记录失败", zap.String("conversationId", conversationID), zap.Error(err))
+		toolExecutions = nil
+	}
+
+	var vulns []*Vulnerability
+	total, err := db.CountVulnerabilities("", conversationID, "", "", "", "", "")
+	if err != nil {
+		db.logger.Warn("导出对话时统计漏洞数量失败", zap.String("conversationId", conversationID), zap.Error(err))
+	} else if total > 0 {
+		vulns, err = db.ListVulnerabilities(total, 0, "", conversationID, "", "", "", "", "")
+		if err != nil {
+			db.logger.Warn("导出对话时加载漏洞列表失败", zap.String("conversationId", conversationID), zap.Error(err))
+			vulns = nil
+		}
+	}
+
+	nodes, err := db.LoadAttackChainNodes(conversationID)
+	if err != nil {
+		db.logger.Warn("导出对话时加载攻击链节点失败", zap.String("conversationId", conversationID), zap.Error(err))
+		nodes = nil
+	}
+	edges, err := db.LoadAttackChainEdges(conversationID)
+	if err != nil {
+		db.logger.Warn("导出对话时加载攻击链边失败", zap.String("conversationId", conversationID), zap.Error(err))
+		edges = nil
+	}
+
+	return &ConversationArchive{
+		FormatVersion:    ConversationArchiveFormatVersion,
+		ExportedAt:       time.Now(),
+		Conversation:     conv,
+		ToolExecutions:   toolExecutions,
+		Vulnerabilities:  vulns,
+		AttackChainNodes: nodes,
+		AttackChainEdges: edges,
+	}, nil
+}
+
+// ImportConversationArchive 将 ExportConversationArchive 产出的归档写回数据库，保留原始 ID 以维持
+// Message.MCPExecutionIDs/ProcessDetail.MessageID/AttackChainNode.ToolExecutionID 等交叉引用不失效。
+// 对话/消息/过程详情是核心数据，写入失败即整体失败并回滚；工具执行记录/漏洞/攻击链视为辅助数据，
+// 单条写入失败仅记录警告并跳过该条、继续写入其余记录，不影响对话主体的导入结果（与 ExportConversationArchive
+// 对工具执行记录的最优努力加载策略保持一致）。已存在同 ID 记录时按 INSERT OR REPLACE 语义覆盖，
+// 重复导入同一份归档是幂等的。
+func (db *DB) ImportConversationArchive(archive *ConversationArchive) (*Conversation, error) {
+	if archive == nil || archive.Conversation == nil {
+		return nil, fmt.Errorf("归档内容为空")
+	}
+	if archive.FormatVersion > ConversationArchiveFormatVersion {
+		return nil, fmt.Errorf("归档格式版本 %d 高于当前支持的版本 %d，请升级后再导入", archive.FormatVersion, ConversationArchiveFormatVersion)
+	}
+
+	conv := archive.Conversation
+	if conv.ID == "" {
+		return nil, fmt.Errorf("归档中的对话缺少 ID")
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	insertConv := rewritePlaceholders(
+		"INSERT OR REPLACE INTO conversations (id, title, pinned, created_at, updated_at, report_template) VALUES (?, ?, ?, ?, ?, ?)",
+		db.dialect,
+	)
+	if _, err := tx.Exec(insertConv, conv.ID, conv.Title, boolToInt(conv.Pinned), conv.CreatedAt, conv.UpdatedAt, conv.ReportTemplate); err != nil {
+		return nil, fmt.Errorf("导入对话失败: %w", err)
+	}
+
+	insertMsg := rewritePlaceholders(
+		"INSERT OR REPLACE INTO messages (id, conversation_id, role, content, mcp_execution_ids, parent_message_id, is_active_branch, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		db.dialect,
+	)
+	insertDetail := rewritePlaceholders(
+		"INSERT OR REPLACE INTO process_details (id, message_id, conversation_id, event_type, message, data, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		db.dialect,
+	)
+	for _, msg := range conv.Messages {
+		var mcpIDsJSON string
+		if len(msg.MCPExecutionIDs) > 0 {
+			if jsonData, err := json.Marshal(msg.MCPExecutionIDs); err == nil {
+				mcpIDsJSON = string(jsonData)
+			}
+		}
+		var parentID sql.NullString
+		if msg.ParentMessageID != "" {
+			parentID = sql.NullString{String: msg.ParentMessageID, Valid: true}
+		}
+		if _, err := tx.Exec(insertMsg, msg.ID, conv.ID, msg.Role, msg.Content, mcpIDsJSON, parentID, boolToInt(msg.IsActiveBranch), msg.CreatedAt, msg.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("导入消息 %s 失败: %w", msg.ID, err)
+		}
+		for _, detail := range msg.ProcessDetails {
+			id, _ := detail["id"].(string)
+			if id == "" {
+				continue
+			}
+			eventType, _ := detail["eventType"].(string)
+			message, _ := detail["message"].(string)
+			createdAt := conv.UpdatedAt
+			if ts, ok := detail["createdAt"].(string); ok {
+				if parsed, perr := time.Parse(time.RFC3339, ts); perr == nil {
+					createdAt = parsed
+				}
+			}
+			var dataJSON string
+			if data, ok := detail["data"]; ok && data != nil {
+				if raw, mErr := json.Marshal(data); mErr == nil {
+					dataJSON = string(raw)
+				}
+			}
+			if _, err := tx.Exec(insertDetail, id, msg.ID, conv.ID, eventType, message, dataJSON, createdAt); err != nil {
+				return nil, fmt.Errorf("导入过程详情 %s 失败: %w", id, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交对话导入事务失败: %w", err)
+	}
+
+	for _, exec := range archive.ToolExecutions {
+		if exec == nil {
+			continue
+		}
+		if err := db.SaveToolExecution(exec); err != nil {
+			db.logger.Warn("导入工具执行记录失败，已跳过", zap.String("id", exec.ID), zap.Error(err))
+		}
+	}
+	for _, vuln := range archive.Vulnerabilities {
+		if vuln == nil {
+			continue
+		}
+		if err := db.upsertImportedVulnerability(vuln); err != nil {
+			db.logger.Warn("导入漏洞记录失败，已跳过", zap.String("id", vuln.ID), zap.Error(err))
+		}
+	}
+	for _, node := range archive.AttackChainNodes {
+		metadataJSON := "{}"
+		if node.Metadata != nil {
+			if raw, mErr := json.Marshal(node.Metadata); mErr == nil {
+				metadataJSON = string(raw)
+			}
+		}
+		if err := db.SaveAttackChainNode(conv.ID, node.ID, node.Type, node.Label, node.ToolExecutionID, metadataJSON, node.RiskScore); err != nil {
+			db.logger.Warn("导入攻击链节点失败，已跳过", zap.String("id", node.ID), zap.Error(err))
+		}
+	}
+	for _, edge := range archive.AttackChainEdges {
+		if err := db.SaveAttackChainEdge(conv.ID, edge.ID, edge.Source, edge.Target, edge.Type, edge.Weight); err != nil {
+			db.logger.Warn("导入攻击链边失败，已跳过", zap.String("id", edge.ID), zap.Error(err))
+		}
+	}
+
+	return db.GetConversation(conv.ID)
+}
+
+// upsertImportedVulnerability 以 INSERT OR REPLACE 语义写入一条漏洞记录，保留归档中的原始 ID 与
+// 指纹/去重计数字段；不同于 CreateVulnerability，这里不做指纹去重合并，因为导入的数据本身就是
+// 某次导出时点的最终状态，重新走一遍去重合并逻辑只会丢失 OccurrenceCount 等已确定的历史信息。
+func (db *DB) upsertImportedVulnerability(vuln *Vulnerability) error {
+	if vuln.ID == "" {
+		return fmt.Errorf("漏洞记录缺少 ID")
+	}
+	query := `
+		INSERT OR REPLACE INTO vulnerabilities (
+			id, conversation_id, conversation_tag, task_tag, title, description, severity, status,
+			vulnerability_type, target, proof, impact, recommendation, cvss_vector, cvss_score,
+			enrichment_status, fingerprint, occurrence_count, first_seen_at, last_seen_at, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := db.Exec(
+		query,
+		vuln.ID, vuln.ConversationID, vuln.ConversationTag, vuln.TaskTag, vuln.Title, vuln.Description,
+		vuln.Severity, vuln.Status, vuln.Type, vuln.Target,
+		vuln.Proof, vuln.Impact, vuln.Recommendation, vuln.CVSSVector, vuln.CVSSScore,
+		vuln.EnrichmentStatus, vuln.Fingerprint, vuln.OccurrenceCount, vuln.FirstSeenAt, vuln.LastSeenAt, vuln.CreatedAt, vuln.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("写入漏洞记录失败: %w", err)
+	}
+	return nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
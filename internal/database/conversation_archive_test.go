@@ -0,0 +1,115 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"cyberstrike-ai/internal/config"
+
+	"go.uber.org/zap"
+)
+
+func TestConversationArchive_ExportImportRoundTrip(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "archive_test.db")
+	db, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	conv, err := db.CreateConversation("导出测试对话")
+	if err != nil {
+		t.Fatalf("创建对话失败: %v", err)
+	}
+	if _, err := db.AddMessage(conv.ID, "user", "扫描一下目标", nil); err != nil {
+		t.Fatalf("添加消息失败: %v", err)
+	}
+	if _, err := db.CreateVulnerability(&Vulnerability{
+		ConversationID: conv.ID,
+		Title:          "SQL 注入",
+		Severity:       "high",
+		Type:           "sqli",
+		Target:         "http://example.com/login",
+	}); err != nil {
+		t.Fatalf("创建漏洞失败: %v", err)
+	}
+	if err := db.SaveAttackChainNode(conv.ID, "node-1", "target", "example.com", "", "{}", 5); err != nil {
+		t.Fatalf("保存攻击链节点失败: %v", err)
+	}
+
+	archive, err := db.ExportConversationArchive(conv.ID)
+	if err != nil {
+		t.Fatalf("导出归档失败: %v", err)
+	}
+	if archive.FormatVersion != ConversationArchiveFormatVersion {
+		t.Fatalf("期望格式版本 %d，实际: %d", ConversationArchiveFormatVersion, archive.FormatVersion)
+	}
+	if len(archive.Conversation.Messages) != 1 {
+		t.Fatalf("期望导出 1 条消息，实际: %d", len(archive.Conversation.Messages))
+	}
+	if len(archive.Vulnerabilities) != 1 {
+		t.Fatalf("期望导出 1 条漏洞，实际: %d", len(archive.Vulnerabilities))
+	}
+	if len(archive.AttackChainNodes) != 1 {
+		t.Fatalf("期望导出 1 个攻击链节点，实际: %d", len(archive.AttackChainNodes))
+	}
+
+	if err := db.DeleteConversation(conv.ID); err != nil {
+		t.Fatalf("删除对话失败: %v", err)
+	}
+	if _, err := db.GetConversation(conv.ID); err == nil {
+		t.Fatal("删除后对话应不存在")
+	}
+
+	restored, err := db.ImportConversationArchive(archive)
+	if err != nil {
+		t.Fatalf("导入归档失败: %v", err)
+	}
+	if restored.ID != conv.ID {
+		t.Fatalf("导入后对话 ID 应保持不变，期望: %s，实际: %s", conv.ID, restored.ID)
+	}
+	if len(restored.Messages) != 1 || restored.Messages[0].Content != "扫描一下目标" {
+		t.Fatalf("导入后消息内容不符，实际: %+v", restored.Messages)
+	}
+
+	total, err := db.CountVulnerabilities("", conv.ID, "", "", "", "", "")
+	if err != nil {
+		t.Fatalf("统计漏洞失败: %v", err)
+	}
+	if total != 1 {
+		t.Fatalf("导入后漏洞数量应为 1，实际: %d", total)
+	}
+
+	nodes, err := db.LoadAttackChainNodes(conv.ID)
+	if err != nil {
+		t.Fatalf("加载攻击链节点失败: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].ID != "node-1" {
+		t.Fatalf("导入后攻击链节点不符，实际: %+v", nodes)
+	}
+
+	// 重复导入同一份归档应是幂等的，不产生重复行或报错。
+	if _, err := db.ImportConversationArchive(archive); err != nil {
+		t.Fatalf("重复导入归档应成功，实际: %v", err)
+	}
+	if total, err := db.CountVulnerabilities("", conv.ID, "", "", "", "", ""); err != nil || total != 1 {
+		t.Fatalf("重复导入后漏洞数量应仍为 1，实际: %d, err: %v", total, err)
+	}
+}
+
+func TestImportConversationArchive_RejectsFutureFormatVersion(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "archive_version_test.db")
+	db, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	archive := &ConversationArchive{
+		FormatVersion: ConversationArchiveFormatVersion + 1,
+		Conversation:  &Conversation{ID: "conv-future"},
+	}
+	if _, err := db.ImportConversationArchive(archive); err == nil {
+		t.Fatal("导入高于当前支持版本的归档应报错")
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"cyberstrike-ai/internal/config"
+
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 	"go.uber.org/zap"
 )
@@ -25,11 +29,41 @@ type DB struct {
 	*sql.DB
 	logger                   *zap.Logger
 	conversationArtifactsDir string
+	dialect                  dialect
+	// path/dsn 记录打开连接时使用的数据源，供 backup.go 的 Backup/Restore 使用
+	// （sqlite 用 path 定位本地文件，postgres 用 dsn 传给 pg_dump/psql）。
+	path string
+	dsn  string
+
+	// 慢查询统计：见 instrumentation.go 中的 Query/QueryRow/Exec 包装方法
+	statsMu            sync.Mutex
+	slowQueryThreshold time.Duration
+	queryStats         map[string]*queryStat
+
+	// auditForwarder 见 SetAuditForwarder/RecordAudit，为 nil 时审计记录只落库不对外转发。
+	auditForwarder AuditForwarder
 }
 
-// NewDB 创建数据库连接
-func NewDB(dbPath string, logger *zap.Logger) (*DB, error) {
-	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_foreign_keys=1&_busy_timeout=5000&_synchronous=NORMAL")
+// NewDB 创建数据库连接。cfg.Driver 为空或 "sqlite" 时使用 cfg.Path 打开本地 SQLite 文件
+// （默认行为，兼容未配置 driver 的旧配置）；cfg.Driver 为 "postgres" 时改用 cfg.DSN 连接
+// Postgres，多个 CyberStrikeAI 实例可共享同一个 Postgres 库。两种驱动下业务代码统一使用
+// "?" 占位符，由 dialect.go 在唯一的查询入口处按需转换，无需按驱动分别拼 SQL。
+func NewDB(cfg config.DatabaseConfig, logger *zap.Logger) (*DB, error) {
+	d, err := parseDialect(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	var db *sql.DB
+	if d == dialectPostgres {
+		dsn := strings.TrimSpace(cfg.DSN)
+		if dsn == "" {
+			return nil, fmt.Errorf("postgres 驱动需要配置 database.dsn")
+		}
+		db, err = sql.Open("postgres", dsn)
+	} else {
+		db, err = sql.Open("sqlite3", cfg.Path+"?_journal_mode=WAL&_foreign_keys=1&_busy_timeout=5000&_synchronous=NORMAL")
+	}
 	if err != nil {
 		return nil, fmt.Errorf("打开数据库失败: %w", err)
 	}
@@ -41,22 +75,35 @@ func NewDB(dbPath string, logger *zap.Logger) (*DB, error) {
 	}
 
 	database := &DB{
-		DB:     db,
-		logger: logger,
+		DB:      db,
+		logger:  logger,
+		dialect: d,
+		path:    cfg.Path,
+		dsn:     cfg.DSN,
 	}
 	// Keep conversation-scoped artifacts near database files, so cleanup can follow conversation lifecycle.
-	baseDir := filepath.Join(filepath.Dir(dbPath), "conversation_artifacts")
-	if mkErr := os.MkdirAll(baseDir, 0o755); mkErr == nil {
-		database.conversationArtifactsDir = baseDir
-	} else if logger != nil {
-		logger.Warn("创建 conversation artifacts 目录失败", zap.String("dir", baseDir), zap.Error(mkErr))
+	// Postgres 部署下没有本地数据库文件；cfg.Path 留空即可，此时该目录不可用（功能按 best-effort 跳过）。
+	if strings.TrimSpace(cfg.Path) != "" {
+		baseDir := filepath.Join(filepath.Dir(cfg.Path), "conversation_artifacts")
+		if mkErr := os.MkdirAll(baseDir, 0o755); mkErr == nil {
+			database.conversationArtifactsDir = baseDir
+		} else if logger != nil {
+			logger.Warn("创建 conversation artifacts 目录失败", zap.String("dir", baseDir), zap.Error(mkErr))
+		}
 	}
 
-	// 初始化表
+	// 初始化表（历史遗留的 CREATE TABLE IF NOT EXISTS / ALTER TABLE 集合，逐步迁移到下面的
+	// 版本化 migrations 框架，见 internal/database/migrations 包）
 	if err := database.initTables(); err != nil {
 		return nil, fmt.Errorf("初始化表失败: %w", err)
 	}
 
+	// 启动时强制执行 schema 迁移检查：新的表结构变更从这里开始版本化管理，迁移失败直接返回
+	// error，避免带着不完整的 schema 启动。
+	if err := database.runMigrations(); err != nil {
+		return nil, fmt.Errorf("执行数据库迁移失败: %w", err)
+	}
+
 	return database, nil
 }
 
@@ -81,6 +128,8 @@ func (db *DB) initTables() error {
 		role TEXT NOT NULL,
 		content TEXT NOT NULL,
 		mcp_execution_ids TEXT,
+		parent_message_id TEXT,
+		is_active_branch INTEGER NOT NULL DEFAULT 1,
 		created_at DATETIME NOT NULL,
 		updated_at DATETIME NOT NULL,
 		FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
@@ -115,6 +164,69 @@ func (db *DB) initTables() error {
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 	);`
 
+	// 创建敏感参数揭示审计日志表：记录谁在何时揭示了某次执行记录的原始敏感参数（Cookie/Token 等）
+	createSensitiveRevealAuditLogTable := `
+	CREATE TABLE IF NOT EXISTS sensitive_reveal_audit_log (
+		id TEXT PRIMARY KEY,
+		execution_id TEXT NOT NULL,
+		tool_name TEXT NOT NULL,
+		remote_addr TEXT,
+		revealed_at DATETIME NOT NULL,
+		FOREIGN KEY (execution_id) REFERENCES tool_executions(id) ON DELETE CASCADE
+	);`
+
+	// 创建 API Key 表：为 CI/脚本等非交互场景提供长期有效、可撤销的访问凭证，见 api_key.go。
+	// 只保存凭证的哈希（key_hash），原始 key 仅在创建时返回一次。
+	createAPIKeysTable := `
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		key_prefix TEXT NOT NULL,
+		key_hash TEXT NOT NULL,
+		scopes TEXT,
+		revoked INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL,
+		last_used_at DATETIME
+	);`
+
+	// 创建登录审计日志表：由 /api/auth/login（AuthHandler.Login）记录每一次登录尝试（成功/失败），
+	// 供事后追查使用；登录锁定由 security.AuthManager 内存中的 loginThrottle 判定，不读取本表。
+	createLoginAuditLogTable := `
+	CREATE TABLE IF NOT EXISTS login_audit_log (
+		id TEXT PRIMARY KEY,
+		ip TEXT NOT NULL,
+		user_agent TEXT,
+		success INTEGER NOT NULL,
+		reason TEXT,
+		created_at DATETIME NOT NULL
+	);`
+
+	// 创建通用审计日志表：登录、配置修改、工具启停、外部 MCP 添加、任务启动/取消、紧急停止等敏感
+	// 操作的追加写入记录，见 audit_log.go。
+	createAuditLogTable := `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id TEXT PRIMARY KEY,
+		actor TEXT,
+		action TEXT NOT NULL,
+		target TEXT,
+		detail TEXT,
+		ip TEXT,
+		created_at DATETIME NOT NULL
+	);`
+
+	// 创建定时任务触发历史表：每次 cron 触发批量任务队列执行完成后追加一条记录，队列本身的
+	// Tasks 会在下次触发前被 ResetQueueForRerun 重置，见 schedule_run.go。
+	createScheduleRunHistoryTable := `
+	CREATE TABLE IF NOT EXISTS schedule_run_history (
+		id TEXT PRIMARY KEY,
+		queue_id TEXT NOT NULL,
+		conversation_ids TEXT,
+		status TEXT NOT NULL,
+		error TEXT,
+		started_at DATETIME NOT NULL,
+		completed_at DATETIME NOT NULL
+	);`
+
 	// 创建工具统计表
 	createToolStatsTable := `
 	CREATE TABLE IF NOT EXISTS tool_stats (
@@ -148,6 +260,8 @@ func (db *DB) initTables() error {
 		metadata TEXT,
 		risk_score INTEGER DEFAULT 0,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		edited_by TEXT,
+		edited_at DATETIME,
 		FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
 		FOREIGN KEY (tool_execution_id) REFERENCES tool_executions(id) ON DELETE SET NULL
 	);`
@@ -162,6 +276,8 @@ func (db *DB) initTables() error {
 		edge_type TEXT NOT NULL,
 		weight INTEGER DEFAULT 1,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		edited_by TEXT,
+		edited_at DATETIME,
 		FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
 		FOREIGN KEY (source_node_id) REFERENCES attack_chain_nodes(id) ON DELETE CASCADE,
 		FOREIGN KEY (target_node_id) REFERENCES attack_chain_nodes(id) ON DELETE CASCADE
@@ -234,6 +350,64 @@ func (db *DB) initTables() error {
 		FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
 	);`
 
+	// 项目/交战：多目标渗透测试项目的顶层分组，对话通过 conversations.project_id 归属到项目，
+	// 见 internal/database/project.go 的项目级漏洞/对话聚合查询。
+	createProjectsTable := `
+	CREATE TABLE IF NOT EXISTS projects (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		client TEXT,
+		scope TEXT,
+		start_date TEXT,
+		end_date TEXT,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);`
+
+	// 资产台账：主机/域名/URL/服务，由 nmap/httpx 工具输出解析结果与 FOFA 导入自动积累（见
+	// internal/database/asset.go 的 UpsertAsset/UpsertAssetsFromFindings），也支持手动创建；
+	// (type, value, port) 用于自动积累时的去重判定。
+	createAssetsTable := `
+	CREATE TABLE IF NOT EXISTS assets (
+		id TEXT PRIMARY KEY,
+		type TEXT NOT NULL,
+		value TEXT NOT NULL,
+		host TEXT,
+		port TEXT,
+		service TEXT,
+		source TEXT NOT NULL,
+		conversation_id TEXT,
+		detail TEXT,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);`
+
+	// 长期代理记忆：按 target（IP/域名/主机等）持久化跨对话的事实（开放端口、凭据、技术栈等），
+	// 供 internal/memory 在新对话开始时注入一份精简摘要到 system prompt。
+	createAgentMemoryFactsTable := `
+	CREATE TABLE IF NOT EXISTS agent_memory_facts (
+		id TEXT PRIMARY KEY,
+		target TEXT NOT NULL,
+		fact_type TEXT NOT NULL,
+		fact_key TEXT NOT NULL,
+		fact_value TEXT NOT NULL,
+		conversation_id TEXT,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(target, fact_type, fact_key)
+	);`
+
+	// Agent运行中任务的检查点：每轮迭代覆盖保存 messages 快照与迭代计数，
+	// 供服务重启后通过 POST /api/agent-loop/resume 从最后一次迭代续跑；任务正常结束后会被清除。
+	createAgentCheckpointsTable := `
+	CREATE TABLE IF NOT EXISTS agent_checkpoints (
+		conversation_id TEXT PRIMARY KEY,
+		messages_json TEXT NOT NULL,
+		iteration INTEGER NOT NULL DEFAULT 0,
+		role_tools_json TEXT,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
 	// 创建批量任务队列表
 	createBatchTaskQueuesTable := `
 	CREATE TABLE IF NOT EXISTS batch_task_queues (
@@ -427,6 +601,7 @@ func (db *DB) initTables() error {
 	CREATE INDEX IF NOT EXISTS idx_vulnerabilities_severity ON vulnerabilities(severity);
 	CREATE INDEX IF NOT EXISTS idx_vulnerabilities_status ON vulnerabilities(status);
 	CREATE INDEX IF NOT EXISTS idx_vulnerabilities_created_at ON vulnerabilities(created_at);
+	CREATE INDEX IF NOT EXISTS idx_vulnerabilities_fingerprint ON vulnerabilities(conversation_id, fingerprint);
 	CREATE INDEX IF NOT EXISTS idx_batch_tasks_queue_id ON batch_tasks(queue_id);
 	CREATE INDEX IF NOT EXISTS idx_batch_task_queues_created_at ON batch_task_queues(created_at);
 	CREATE INDEX IF NOT EXISTS idx_batch_task_queues_title ON batch_task_queues(title);
@@ -463,6 +638,26 @@ func (db *DB) initTables() error {
 		return fmt.Errorf("创建tool_executions表失败: %w", err)
 	}
 
+	if _, err := db.Exec(createAPIKeysTable); err != nil {
+		return fmt.Errorf("创建api_keys表失败: %w", err)
+	}
+
+	if _, err := db.Exec(createSensitiveRevealAuditLogTable); err != nil {
+		return fmt.Errorf("创建sensitive_reveal_audit_log表失败: %w", err)
+	}
+
+	if _, err := db.Exec(createLoginAuditLogTable); err != nil {
+		return fmt.Errorf("创建login_audit_log表失败: %w", err)
+	}
+
+	if _, err := db.Exec(createAuditLogTable); err != nil {
+		return fmt.Errorf("创建audit_log表失败: %w", err)
+	}
+
+	if _, err := db.Exec(createScheduleRunHistoryTable); err != nil {
+		return fmt.Errorf("创建schedule_run_history表失败: %w", err)
+	}
+
 	if _, err := db.Exec(createToolStatsTable); err != nil {
 		return fmt.Errorf("创建tool_stats表失败: %w", err)
 	}
@@ -494,10 +689,26 @@ func (db *DB) initTables() error {
 		return fmt.Errorf("创建robot_user_sessions表失败: %w", err)
 	}
 
+	if _, err := db.Exec(createProjectsTable); err != nil {
+		return fmt.Errorf("创建projects表失败: %w", err)
+	}
+
+	if _, err := db.Exec(createAssetsTable); err != nil {
+		return fmt.Errorf("创建assets表失败: %w", err)
+	}
+
 	if _, err := db.Exec(createVulnerabilitiesTable); err != nil {
 		return fmt.Errorf("创建vulnerabilities表失败: %w", err)
 	}
 
+	if _, err := db.Exec(createAgentMemoryFactsTable); err != nil {
+		return fmt.Errorf("创建agent_memory_facts表失败: %w", err)
+	}
+
+	if _, err := db.Exec(createAgentCheckpointsTable); err != nil {
+		return fmt.Errorf("创建agent_checkpoints表失败: %w", err)
+	}
+
 	if _, err := db.Exec(createBatchTaskQueuesTable); err != nil {
 		return fmt.Errorf("创建batch_task_queues表失败: %w", err)
 	}
@@ -538,6 +749,11 @@ func (db *DB) initTables() error {
 		// 不返回错误，允许继续运行
 	}
 
+	if err := db.migrateMessageBranchingColumns(); err != nil {
+		db.logger.Warn("迁移messages分支字段失败", zap.Error(err))
+		// 不返回错误，允许继续运行
+	}
+
 	if err := db.migrateConversationGroupsTable(); err != nil {
 		db.logger.Warn("迁移conversation_groups表失败", zap.Error(err))
 		// 不返回错误，允许继续运行
@@ -562,6 +778,16 @@ func (db *DB) initTables() error {
 		// 不返回错误，允许继续运行
 	}
 
+	if err := db.migrateAttackChainTables(); err != nil {
+		db.logger.Warn("迁移attack_chain_nodes/attack_chain_edges表失败", zap.Error(err))
+		// 不返回错误，允许继续运行
+	}
+
+	if err := db.migrateToolExecutionsTable(); err != nil {
+		db.logger.Warn("迁移tool_executions表失败", zap.Error(err))
+		// 不返回错误，允许继续运行
+	}
+
 	if _, err := db.Exec(createIndexes); err != nil {
 		return fmt.Errorf("创建索引失败: %w", err)
 	}
@@ -597,6 +823,77 @@ func (db *DB) migrateMessagesTable() error {
 	return nil
 }
 
+// migrateMessageBranchingColumns 迁移 messages 表，补充消息重新生成/分支所需的
+// parent_message_id（同一轮内共享该值的多条 assistant 消息互为兄弟分支）与
+// is_active_branch（标记当前对外展示的分支，见 RegenerateMessage/SwitchMessageBranch）字段。
+// 已有数据视为「无分支」的单一激活分支，parent_message_id 留空、is_active_branch 默认为 1。
+func (db *DB) migrateMessageBranchingColumns() error {
+	columns := []struct {
+		name string
+		stmt string
+	}{
+		{"parent_message_id", "ALTER TABLE messages ADD COLUMN parent_message_id TEXT"},
+		{"is_active_branch", "ALTER TABLE messages ADD COLUMN is_active_branch INTEGER NOT NULL DEFAULT 1"},
+	}
+	for _, col := range columns {
+		var count int
+		err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('messages') WHERE name=?", col.name).Scan(&count)
+		if err == nil && count > 0 {
+			continue
+		}
+		if _, err := db.Exec(col.stmt); err != nil {
+			errMsg := strings.ToLower(err.Error())
+			if !strings.Contains(errMsg, "duplicate column") && !strings.Contains(errMsg, "already exists") {
+				return fmt.Errorf("添加 messages.%s 字段失败: %w", col.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// migrateToolExecutionsTable 迁移 tool_executions 表，补充 raw_arguments、trace_id 字段。
+// raw_arguments 保存未掩码的真实参数（可能包含 Cookie、Token 等敏感值），
+// 仅供管理员揭示接口读取，普通查询接口不会返回该字段。
+// trace_id 关联本次调用所属的 OpenTelemetry trace（见 internal/tracing），未启用追踪时为空。
+func (db *DB) migrateToolExecutionsTable() error {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('tool_executions') WHERE name='raw_arguments'").Scan(&count)
+	if err != nil {
+		if _, addErr := db.Exec("ALTER TABLE tool_executions ADD COLUMN raw_arguments TEXT"); addErr != nil {
+			errMsg := strings.ToLower(addErr.Error())
+			if !strings.Contains(errMsg, "duplicate column") && !strings.Contains(errMsg, "already exists") {
+				return fmt.Errorf("添加 tool_executions.raw_arguments 字段失败: %w", addErr)
+			}
+		}
+	} else if count == 0 {
+		if _, err := db.Exec("ALTER TABLE tool_executions ADD COLUMN raw_arguments TEXT"); err != nil {
+			errMsg := strings.ToLower(err.Error())
+			if !strings.Contains(errMsg, "duplicate column") && !strings.Contains(errMsg, "already exists") {
+				return fmt.Errorf("添加 tool_executions.raw_arguments 字段失败: %w", err)
+			}
+		}
+	}
+
+	err = db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('tool_executions') WHERE name='trace_id'").Scan(&count)
+	if err != nil {
+		if _, addErr := db.Exec("ALTER TABLE tool_executions ADD COLUMN trace_id TEXT"); addErr != nil {
+			errMsg := strings.ToLower(addErr.Error())
+			if !strings.Contains(errMsg, "duplicate column") && !strings.Contains(errMsg, "already exists") {
+				return fmt.Errorf("添加 tool_executions.trace_id 字段失败: %w", addErr)
+			}
+		}
+	} else if count == 0 {
+		if _, err := db.Exec("ALTER TABLE tool_executions ADD COLUMN trace_id TEXT"); err != nil {
+			errMsg := strings.ToLower(err.Error())
+			if !strings.Contains(errMsg, "duplicate column") && !strings.Contains(errMsg, "already exists") {
+				return fmt.Errorf("添加 tool_executions.trace_id 字段失败: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
 // migrateConversationsTable 迁移conversations表，添加新字段
 func (db *DB) migrateConversationsTable() error {
 	// 检查last_react_input字段是否存在
@@ -669,6 +966,36 @@ func (db *DB) migrateConversationsTable() error {
 		}
 	}
 
+	// 检查 report_template 字段是否存在（该会话导出报告时使用的自定义模板名，见 handler.ReportTemplateHandler）
+	err = db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('conversations') WHERE name='report_template'").Scan(&count)
+	if err != nil {
+		if _, addErr := db.Exec("ALTER TABLE conversations ADD COLUMN report_template TEXT"); addErr != nil {
+			errMsg := strings.ToLower(addErr.Error())
+			if !strings.Contains(errMsg, "duplicate column") && !strings.Contains(errMsg, "already exists") {
+				db.logger.Warn("添加report_template字段失败", zap.Error(addErr))
+			}
+		}
+	} else if count == 0 {
+		if _, err := db.Exec("ALTER TABLE conversations ADD COLUMN report_template TEXT"); err != nil {
+			db.logger.Warn("添加report_template字段失败", zap.Error(err))
+		}
+	}
+
+	// 检查 project_id 字段是否存在（该会话归属的项目/交战，见 database.Project）
+	err = db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('conversations') WHERE name='project_id'").Scan(&count)
+	if err != nil {
+		if _, addErr := db.Exec("ALTER TABLE conversations ADD COLUMN project_id TEXT"); addErr != nil {
+			errMsg := strings.ToLower(addErr.Error())
+			if !strings.Contains(errMsg, "duplicate column") && !strings.Contains(errMsg, "already exists") {
+				db.logger.Warn("添加project_id字段失败", zap.Error(addErr))
+			}
+		}
+	} else if count == 0 {
+		if _, err := db.Exec("ALTER TABLE conversations ADD COLUMN project_id TEXT"); err != nil {
+			db.logger.Warn("添加project_id字段失败", zap.Error(err))
+		}
+	}
+
 	return nil
 }
 
@@ -896,6 +1223,23 @@ func (db *DB) migrateVulnerabilitiesTable() error {
 	}{
 		{name: "conversation_tag", stmt: "ALTER TABLE vulnerabilities ADD COLUMN conversation_tag TEXT"},
 		{name: "task_tag", stmt: "ALTER TABLE vulnerabilities ADD COLUMN task_tag TEXT"},
+		// fingerprint 用于跨重复扫描去重合并（见 computeVulnerabilityFingerprint），历史行留空，
+		// 由 backfillVulnerabilityFingerprints 补齐后才能参与去重匹配。
+		{name: "fingerprint", stmt: "ALTER TABLE vulnerabilities ADD COLUMN fingerprint TEXT"},
+		{name: "occurrence_count", stmt: "ALTER TABLE vulnerabilities ADD COLUMN occurrence_count INTEGER NOT NULL DEFAULT 1"},
+		{name: "first_seen_at", stmt: "ALTER TABLE vulnerabilities ADD COLUMN first_seen_at DATETIME"},
+		{name: "last_seen_at", stmt: "ALTER TABLE vulnerabilities ADD COLUMN last_seen_at DATETIME"},
+		// cvss_vector/cvss_score 用于 CVSS v3.1 定量评分（见 security.ComputeCVSSBaseScore），
+		// 留空表示未评分，此时 severity 仍沿用调用方传入的自由文本值。
+		{name: "cvss_vector", stmt: "ALTER TABLE vulnerabilities ADD COLUMN cvss_vector TEXT"},
+		{name: "cvss_score", stmt: "ALTER TABLE vulnerabilities ADD COLUMN cvss_score REAL NOT NULL DEFAULT 0"},
+		// cve_enrichments/enrichment_status 用于 CVE/NVD 自动富化（见 security.EnrichVulnerabilityCVEsAsync
+		// 与 DB.ApplyCVEEnrichment），cve_enrichments 存储 JSON 数组，enrichment_status 为 none/pending/enriched。
+		{name: "cve_enrichments", stmt: "ALTER TABLE vulnerabilities ADD COLUMN cve_enrichments TEXT"},
+		{name: "enrichment_status", stmt: "ALTER TABLE vulnerabilities ADD COLUMN enrichment_status TEXT NOT NULL DEFAULT 'none'"},
+		// jira_issue_key 记录该漏洞在 Jira 中对应的工单编号（见 handler.JiraHandler.CreateOrUpdateTicket），
+		// 留空表示尚未创建工单；重复调用创建接口时据此改为更新已有工单而非重复创建。
+		{name: "jira_issue_key", stmt: "ALTER TABLE vulnerabilities ADD COLUMN jira_issue_key TEXT"},
 	}
 
 	for _, col := range columns {
@@ -916,6 +1260,78 @@ func (db *DB) migrateVulnerabilitiesTable() error {
 			}
 		}
 	}
+
+	// 回填历史行：first_seen_at/last_seen_at 缺省时退化为 created_at/updated_at，
+	// fingerprint 缺省时按当前算法补算，使旧数据在下一次相同发现写入时也能命中去重合并。
+	_, _ = db.Exec("UPDATE vulnerabilities SET first_seen_at = created_at WHERE first_seen_at IS NULL OR first_seen_at = ''")
+	_, _ = db.Exec("UPDATE vulnerabilities SET last_seen_at = updated_at WHERE last_seen_at IS NULL OR last_seen_at = ''")
+	if err := db.backfillVulnerabilityFingerprints(); err != nil {
+		db.logger.Warn("回填vulnerabilities.fingerprint失败", zap.Error(err))
+	}
+	return nil
+}
+
+// migrateAttackChainTables 为 attack_chain_nodes/attack_chain_edges 补充人工编辑审计字段
+// （edited_by/edited_at），见 handler.AttackChainHandler 的手动节点/边编辑接口。
+func (db *DB) migrateAttackChainTables() error {
+	tables := []string{"attack_chain_nodes", "attack_chain_edges"}
+	columns := []struct {
+		name string
+		stmt string
+	}{
+		{name: "edited_by", stmt: "ADD COLUMN edited_by TEXT"},
+		{name: "edited_at", stmt: "ADD COLUMN edited_at DATETIME"},
+	}
+
+	for _, table := range tables {
+		for _, col := range columns {
+			var count int
+			err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM pragma_table_info('%s') WHERE name=?", table), col.name).Scan(&count)
+			if err != nil {
+				if _, addErr := db.Exec(fmt.Sprintf("ALTER TABLE %s %s", table, col.stmt)); addErr != nil {
+					errMsg := strings.ToLower(addErr.Error())
+					if !strings.Contains(errMsg, "duplicate column") && !strings.Contains(errMsg, "already exists") {
+						db.logger.Warn("添加攻击链审计字段失败", zap.String("table", table), zap.String("field", col.name), zap.Error(addErr))
+					}
+				}
+				continue
+			}
+			if count == 0 {
+				if _, addErr := db.Exec(fmt.Sprintf("ALTER TABLE %s %s", table, col.stmt)); addErr != nil {
+					db.logger.Warn("添加攻击链审计字段失败", zap.String("table", table), zap.String("field", col.name), zap.Error(addErr))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// backfillVulnerabilityFingerprints 为 fingerprint 为空的历史漏洞行补算指纹（见 computeVulnerabilityFingerprint），
+// 使其能在下次相同类型/目标的发现写入时参与去重合并，而不是永远被视为"从未出现过"。
+func (db *DB) backfillVulnerabilityFingerprints() error {
+	rows, err := db.Query("SELECT id, vulnerability_type, target FROM vulnerabilities WHERE fingerprint IS NULL OR fingerprint = ''")
+	if err != nil {
+		return fmt.Errorf("查询待回填指纹的漏洞失败: %w", err)
+	}
+	type idFingerprint struct {
+		id          string
+		fingerprint string
+	}
+	var updates []idFingerprint
+	for rows.Next() {
+		var id, vulnType, target string
+		if scanErr := rows.Scan(&id, &vulnType, &target); scanErr != nil {
+			continue
+		}
+		updates = append(updates, idFingerprint{id: id, fingerprint: computeVulnerabilityFingerprint(vulnType, target)})
+	}
+	rows.Close()
+
+	for _, u := range updates {
+		if _, err := db.Exec("UPDATE vulnerabilities SET fingerprint = ? WHERE id = ?", u.fingerprint, u.id); err != nil {
+			db.logger.Warn("回填单条vulnerabilities.fingerprint失败", zap.String("id", u.id), zap.Error(err))
+		}
+	}
 	return nil
 }
 
@@ -1017,6 +1433,41 @@ func (db *DB) initKnowledgeTables() error {
 		created_at DATETIME NOT NULL
 	);`
 
+	// 创建知识项版本历史表：UpdateItem 覆盖内容前会将旧版本快照写入这里，用于审计和回滚
+	createKnowledgeItemVersionsTable := `
+	CREATE TABLE IF NOT EXISTS knowledge_item_versions (
+		id TEXT PRIMARY KEY,
+		item_id TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		category TEXT NOT NULL,
+		title TEXT NOT NULL,
+		content TEXT,
+		author TEXT,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (item_id) REFERENCES knowledge_base_items(id) ON DELETE CASCADE
+	);`
+
+	// 创建知识项标签表：一个知识项可打多个标签，用于分类筛选和检索时的标签匹配加权（见
+	// knowledge.Manager.SetItemTags、knowledge.Retriever 的标签评分）
+	createKnowledgeItemTagsTable := `
+	CREATE TABLE IF NOT EXISTS knowledge_item_tags (
+		item_id TEXT NOT NULL,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (item_id, tag),
+		FOREIGN KEY (item_id) REFERENCES knowledge_base_items(id) ON DELETE CASCADE
+	);`
+
+	// 创建知识项元数据表：存放 CWE 编号、受影响产品等结构化的键值信息（见
+	// knowledge.Manager.SetItemMetadata），与自由文本标签分开管理
+	createKnowledgeItemMetadataTable := `
+	CREATE TABLE IF NOT EXISTS knowledge_item_metadata (
+		item_id TEXT NOT NULL,
+		key TEXT NOT NULL,
+		value TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (item_id, key),
+		FOREIGN KEY (item_id) REFERENCES knowledge_base_items(id) ON DELETE CASCADE
+	);`
+
 	// 创建索引
 	createIndexes := `
 	CREATE INDEX IF NOT EXISTS idx_knowledge_items_category ON knowledge_base_items(category);
@@ -1024,6 +1475,8 @@ func (db *DB) initKnowledgeTables() error {
 	CREATE INDEX IF NOT EXISTS idx_knowledge_retrieval_logs_conversation ON knowledge_retrieval_logs(conversation_id);
 	CREATE INDEX IF NOT EXISTS idx_knowledge_retrieval_logs_message ON knowledge_retrieval_logs(message_id);
 	CREATE INDEX IF NOT EXISTS idx_knowledge_retrieval_logs_created_at ON knowledge_retrieval_logs(created_at);
+	CREATE INDEX IF NOT EXISTS idx_knowledge_item_versions_item_id ON knowledge_item_versions(item_id);
+	CREATE INDEX IF NOT EXISTS idx_knowledge_item_tags_tag ON knowledge_item_tags(tag);
 	`
 
 	if _, err := db.Exec(createKnowledgeBaseItemsTable); err != nil {
@@ -1038,6 +1491,18 @@ func (db *DB) initKnowledgeTables() error {
 		return fmt.Errorf("创建knowledge_retrieval_logs表失败: %w", err)
 	}
 
+	if _, err := db.Exec(createKnowledgeItemVersionsTable); err != nil {
+		return fmt.Errorf("创建knowledge_item_versions表失败: %w", err)
+	}
+
+	if _, err := db.Exec(createKnowledgeItemTagsTable); err != nil {
+		return fmt.Errorf("创建knowledge_item_tags表失败: %w", err)
+	}
+
+	if _, err := db.Exec(createKnowledgeItemMetadataTable); err != nil {
+		return fmt.Errorf("创建knowledge_item_metadata表失败: %w", err)
+	}
+
 	if _, err := db.Exec(createIndexes); err != nil {
 		return fmt.Errorf("创建索引失败: %w", err)
 	}
@@ -1046,10 +1511,55 @@ func (db *DB) initKnowledgeTables() error {
 		return fmt.Errorf("迁移 knowledge_embeddings 列失败: %w", err)
 	}
 
+	if err := db.migrateKnowledgeFTS(); err != nil {
+		return fmt.Errorf("初始化 knowledge_fts 全文索引失败: %w", err)
+	}
+
 	db.logger.Info("知识库数据库表初始化完成")
 	return nil
 }
 
+// migrateKnowledgeFTS 建立 knowledge_base_items.content 的 FTS5 全文索引（BM25），供 knowledge.Retriever
+// 做向量+全文的混合检索（RRF 融合）。依赖 mattn/go-sqlite3 编译时启用 fts5 扩展（构建时加 -tags sqlite_fts5，
+// 见 run.sh 的 go build 命令）；未启用时 CREATE VIRTUAL TABLE 返回 "no such module: fts5"，
+// 这里降级为跳过，Retriever 侧会据此自动退化为纯向量检索，不影响其余功能。
+func (db *DB) migrateKnowledgeFTS() error {
+	if _, err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS knowledge_fts USING fts5(item_id UNINDEXED, content)`); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "no such module") {
+			db.logger.Warn("SQLite 未编译 FTS5 扩展，跳过全文索引创建，知识库混合检索将退化为纯向量检索（如需启用，构建时加 -tags sqlite_fts5）")
+			return nil
+		}
+		return fmt.Errorf("创建 knowledge_fts 表失败: %w", err)
+	}
+
+	triggers := []string{
+		`CREATE TRIGGER IF NOT EXISTS knowledge_fts_ai AFTER INSERT ON knowledge_base_items BEGIN
+			INSERT INTO knowledge_fts(item_id, content) VALUES (new.id, coalesce(new.content, ''));
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS knowledge_fts_ad AFTER DELETE ON knowledge_base_items BEGIN
+			DELETE FROM knowledge_fts WHERE item_id = old.id;
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS knowledge_fts_au AFTER UPDATE ON knowledge_base_items BEGIN
+			DELETE FROM knowledge_fts WHERE item_id = old.id;
+			INSERT INTO knowledge_fts(item_id, content) VALUES (new.id, coalesce(new.content, ''));
+		END;`,
+	}
+	for _, stmt := range triggers {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("创建 knowledge_fts 同步触发器失败: %w", err)
+		}
+	}
+
+	// 回填触发器生效前已存在的知识项（触发器只对之后的写入生效）
+	if _, err := db.Exec(`INSERT INTO knowledge_fts(item_id, content)
+		SELECT id, coalesce(content, '') FROM knowledge_base_items
+		WHERE id NOT IN (SELECT item_id FROM knowledge_fts)`); err != nil {
+		db.logger.Warn("回填 knowledge_fts 全文索引失败", zap.Error(err))
+	}
+
+	return nil
+}
+
 // migrateKnowledgeEmbeddingsColumns 为已有库补充 sub_indexes、embedding_model、embedding_dim。
 func (db *DB) migrateKnowledgeEmbeddingsColumns() error {
 	var n int
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dialect 标识当前连接实际使用的 SQL 方言。整个包只有一个统一的查询入口
+// （见 instrumentation.go 中的 Query/QueryRow/Exec 包装方法），方言相关的差异
+// 也集中在那里处理，业务代码里遍布的 "?" 占位符因此无需逐处改写即可同时兼容
+// SQLite 与 Postgres。
+type dialect int
+
+const (
+	dialectSQLite dialect = iota
+	dialectPostgres
+)
+
+// parseDialect 把配置里的 driver 字符串解析为 dialect；空字符串按 SQLite 处理，
+// 兼容未显式配置 database.driver 的旧配置文件。
+func parseDialect(driver string) (dialect, error) {
+	switch strings.ToLower(strings.TrimSpace(driver)) {
+	case "", "sqlite", "sqlite3":
+		return dialectSQLite, nil
+	case "postgres", "postgresql":
+		return dialectPostgres, nil
+	default:
+		return dialectSQLite, fmt.Errorf("不支持的数据库驱动: %s（当前支持 sqlite、postgres）", driver)
+	}
+}
+
+// rewriteColumnTypes 把 schema 中使用的 SQLite 专有类型名换成 Postgres 的等价类型。目前只有
+// DATETIME 需要处理：SQLite 把它当作 TEXT 的别名接受，Postgres 没有这个类型名，等价类型是
+// TIMESTAMP。包内所有 DATETIME 出现位置都是大写的列类型声明（建表/加列语句），不会与字符串
+// 字面量或标识符混淆，因此用简单的整词替换即可。
+func rewriteColumnTypes(query string, d dialect) string {
+	if d != dialectPostgres || !strings.Contains(query, "DATETIME") {
+		return query
+	}
+	return strings.ReplaceAll(query, "DATETIME", "TIMESTAMP")
+}
+
+// rewritePlaceholders 把业务代码统一使用的 "?" 占位符转换为目标方言实际接受的形式。
+// SQLite 原生支持 "?"，无需转换；Postgres 要求 "$1"、"$2" 形式的按位置占位符，这里按
+// 出现顺序编号替换。转换过程会跳过单引号字符串字面量内的 "?"，避免误伤 SQL 文本内容
+// （本包内的 SQL 均为常量拼接，不存在字面量内混入未转义单引号的情况）。
+func rewritePlaceholders(query string, d dialect) string {
+	if d != dialectPostgres || !strings.ContainsRune(query, '?') {
+		return query
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(query) + 8)
+	n := 0
+	inString := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			inString = !inString
+			sb.WriteByte(c)
+		case c == '?' && !inString:
+			n++
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(n))
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"database/sql"
+	"runtime"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultSlowQueryThreshold 未通过 SetSlowQueryThreshold 显式配置时使用的兜底阈值
+const defaultSlowQueryThreshold = 200 * time.Millisecond
+
+// queryStat 单条 SQL（按调用位置聚合）的累计耗时统计
+type queryStat struct {
+	Caller      string        `json:"caller"`
+	Query       string        `json:"query"`
+	Count       int64         `json:"count"`
+	SlowCount   int64         `json:"slowCount"`
+	TotalTime   time.Duration `json:"-"`
+	MaxTime     time.Duration `json:"maxTimeMs"`
+	TotalTimeMs int64         `json:"totalTimeMs"`
+}
+
+// QueryStatSnapshot 对外暴露的单条统计快照，AvgMs 由 TotalMs/Count 计算得出
+type QueryStatSnapshot struct {
+	Caller    string `json:"caller"`
+	Query     string `json:"query"`
+	Count     int64  `json:"count"`
+	SlowCount int64  `json:"slowCount"`
+	TotalMs   int64  `json:"totalMs"`
+	AvgMs     int64  `json:"avgMs"`
+	MaxMs     int64  `json:"maxMs"`
+}
+
+// SetSlowQueryThreshold 设置慢查询日志阈值，超过该耗时的查询会以 WARN 级别记录 SQL 与调用位置。
+// d <= 0 时恢复内置默认值（200ms）。
+func (db *DB) SetSlowQueryThreshold(d time.Duration) {
+	db.statsMu.Lock()
+	defer db.statsMu.Unlock()
+	db.slowQueryThreshold = d
+}
+
+// recordQuery 记录一次查询的耗时，累加进按调用位置聚合的统计，并在超过慢查询阈值时记录告警日志。
+// skip 为相对 recordQuery 自身的调用栈跳过层数，用于定位真正发起查询的业务代码位置（而非本文件内的包装方法）。
+func (db *DB) recordQuery(query string, start time.Time, skip int) {
+	duration := time.Since(start)
+
+	threshold := db.slowQueryThreshold
+	if threshold <= 0 {
+		threshold = defaultSlowQueryThreshold
+	}
+
+	caller := "unknown"
+	if _, file, line, ok := runtime.Caller(skip); ok {
+		caller = shortCallerPath(file, line)
+	}
+
+	db.statsMu.Lock()
+	if db.queryStats == nil {
+		db.queryStats = make(map[string]*queryStat)
+	}
+	key := caller + "|" + query
+	stat, exists := db.queryStats[key]
+	if !exists {
+		stat = &queryStat{Caller: caller, Query: query}
+		db.queryStats[key] = stat
+	}
+	stat.Count++
+	stat.TotalTime += duration
+	stat.TotalTimeMs = stat.TotalTime.Milliseconds()
+	if duration > stat.MaxTime {
+		stat.MaxTime = duration
+	}
+	if duration >= threshold {
+		stat.SlowCount++
+	}
+	db.statsMu.Unlock()
+
+	if duration >= threshold && db.logger != nil {
+		db.logger.Warn("检测到慢查询",
+			zap.Duration("duration", duration),
+			zap.Duration("threshold", threshold),
+			zap.String("caller", caller),
+			zap.String("sql", query),
+		)
+	}
+}
+
+// shortCallerPath 截取文件路径的最后两段（目录/文件名），避免日志中出现构建机器上的完整绝对路径。
+func shortCallerPath(file string, line int) string {
+	depth := 0
+	for i := len(file) - 1; i >= 0; i-- {
+		if file[i] == '/' {
+			depth++
+			if depth == 2 {
+				file = file[i+1:]
+				break
+			}
+		}
+	}
+	return file + ":" + strconv.Itoa(line)
+}
+
+// Query 包装 *sql.DB.Query，记录本次查询耗时并按方言转换占位符；调用方式与标准库完全一致，
+// 调用方始终使用 "?" 占位符，转换为 Postgres 的 "$N" 形式由本方法透明完成（见 dialect.go）。
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	defer db.recordQuery(query, time.Now(), 2)
+	return db.DB.Query(rewritePlaceholders(query, db.dialect), args...)
+}
+
+// QueryRow 包装 *sql.DB.QueryRow，记录本次查询耗时并按方言转换占位符；调用方式与标准库完全一致。
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	defer db.recordQuery(query, time.Now(), 2)
+	return db.DB.QueryRow(rewritePlaceholders(query, db.dialect), args...)
+}
+
+// Exec 包装 *sql.DB.Exec，记录本次执行耗时并按方言转换占位符与建表用的类型名；调用方式与
+// 标准库完全一致。建表/加列语句只经由 Exec 执行，因此类型名转换（如 DATETIME -> TIMESTAMP）
+// 放在这里而不是 Query/QueryRow。
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	defer db.recordQuery(query, time.Now(), 2)
+	query = rewritePlaceholders(query, db.dialect)
+	query = rewriteColumnTypes(query, db.dialect)
+	return db.DB.Exec(query, args...)
+}
+
+// QueryStats 返回按调用位置聚合的查询耗时统计快照，供维护类接口展示（如 GET /monitor/db-stats）。
+func (db *DB) QueryStats() []QueryStatSnapshot {
+	db.statsMu.Lock()
+	defer db.statsMu.Unlock()
+
+	snapshots := make([]QueryStatSnapshot, 0, len(db.queryStats))
+	for _, stat := range db.queryStats {
+		avg := int64(0)
+		if stat.Count > 0 {
+			avg = stat.TotalTimeMs / stat.Count
+		}
+		snapshots = append(snapshots, QueryStatSnapshot{
+			Caller:    stat.Caller,
+			Query:     stat.Query,
+			Count:     stat.Count,
+			SlowCount: stat.SlowCount,
+			TotalMs:   stat.TotalTimeMs,
+			AvgMs:     avg,
+			MaxMs:     stat.MaxTime.Milliseconds(),
+		})
+	}
+	return snapshots
+}
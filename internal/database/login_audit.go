@@ -0,0 +1,38 @@
+package database
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginAuditEntry 是一次登录尝试的审计记录，见 login_audit_log 表；由
+// handler.AuthHandler.Login 在每次密码登录尝试后记录（wivd/CyberStrikeAI#synth-3094）。
+type LoginAuditEntry struct {
+	ID        string    `json:"id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// RecordLoginAttempt 写入一条登录尝试审计记录。
+func (db *DB) RecordLoginAttempt(ip, userAgent string, success bool, reason string) error {
+	_, err := db.Exec(
+		"INSERT INTO login_audit_log (id, ip, user_agent, success, reason, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		uuid.New().String(), ip, userAgent, boolToInt(success), reason, time.Now(),
+	)
+	return err
+}
+
+// CountRecentFailedLogins 统计某个 IP 从 since 起的失败登录总次数（其间的成功登录不会清零计数）。
+// security.AuthManager 的登录锁定由其内存中的 loginThrottle 判定，不调用本方法。
+func (db *DB) CountRecentFailedLogins(ip string, since time.Time) (int, error) {
+	var count int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM login_audit_log WHERE ip = ? AND success = 0 AND created_at >= ?",
+		ip, since,
+	).Scan(&count)
+	return count, err
+}
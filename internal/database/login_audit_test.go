@@ -0,0 +1,39 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cyberstrike-ai/internal/config"
+
+	"go.uber.org/zap"
+)
+
+func TestLoginAudit_RecordAndCountRecentFailures(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "login_audit_test.db")
+	db, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	since := time.Now().Add(-time.Minute)
+	if err := db.RecordLoginAttempt("1.2.3.4", "ua", false, "invalid password"); err != nil {
+		t.Fatalf("记录登录失败: %v", err)
+	}
+	if err := db.RecordLoginAttempt("1.2.3.4", "ua", false, "invalid password"); err != nil {
+		t.Fatalf("记录登录失败: %v", err)
+	}
+	if err := db.RecordLoginAttempt("1.2.3.4", "ua", true, ""); err != nil {
+		t.Fatalf("记录登录失败: %v", err)
+	}
+
+	count, err := db.CountRecentFailedLogins("1.2.3.4", since)
+	if err != nil {
+		t.Fatalf("统计失败登录次数失败: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("期望 2 次失败登录，实际: %d", count)
+	}
+}
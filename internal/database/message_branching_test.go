@@ -0,0 +1,109 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"cyberstrike-ai/internal/config"
+
+	"go.uber.org/zap"
+)
+
+func TestRegenerateMessage_CreatesInactiveOldBranchAndActiveNewBranch(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "regenerate_test.db")
+	db, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	conv, err := db.CreateConversation("分支测试对话")
+	if err != nil {
+		t.Fatalf("创建对话失败: %v", err)
+	}
+	userMsg, err := db.AddMessage(conv.ID, "user", "第一个问题", nil)
+	if err != nil {
+		t.Fatalf("添加用户消息失败: %v", err)
+	}
+	assistantMsg, err := db.AddMessage(conv.ID, "assistant", "第一次回答", nil)
+	if err != nil {
+		t.Fatalf("添加助手消息失败: %v", err)
+	}
+
+	placeholder, gotUserMsg, history, err := db.RegenerateMessage(conv.ID, assistantMsg.ID)
+	if err != nil {
+		t.Fatalf("RegenerateMessage 失败: %v", err)
+	}
+	if gotUserMsg.ID != userMsg.ID {
+		t.Fatalf("期望 userMessage 为 %s，实际: %s", userMsg.ID, gotUserMsg.ID)
+	}
+	if len(history) != 0 {
+		t.Fatalf("期望第一轮的 history 为空，实际: %d 条", len(history))
+	}
+	if placeholder.ParentMessageID != userMsg.ID {
+		t.Fatalf("期望新分支的 parentMessageId 为 %s，实际: %s", userMsg.ID, placeholder.ParentMessageID)
+	}
+
+	// 当前激活分支应只剩新占位消息，旧回复不再出现在 GetMessages 结果中
+	activeMessages, err := db.GetMessages(conv.ID)
+	if err != nil {
+		t.Fatalf("GetMessages 失败: %v", err)
+	}
+	if len(activeMessages) != 2 {
+		t.Fatalf("期望激活分支下有 2 条消息，实际: %d", len(activeMessages))
+	}
+	if activeMessages[1].ID != placeholder.ID {
+		t.Fatalf("期望激活的 assistant 消息为新分支 %s，实际: %s", placeholder.ID, activeMessages[1].ID)
+	}
+
+	branches, err := db.ListMessageBranches(userMsg.ID)
+	if err != nil {
+		t.Fatalf("ListMessageBranches 失败: %v", err)
+	}
+	if len(branches) != 2 {
+		t.Fatalf("期望共有 2 个分支，实际: %d", len(branches))
+	}
+
+	// 切换回旧分支后，GetMessages 应重新看到原始回复
+	if err := db.SwitchMessageBranch(conv.ID, assistantMsg.ID); err != nil {
+		t.Fatalf("SwitchMessageBranch 失败: %v", err)
+	}
+	activeMessages, err = db.GetMessages(conv.ID)
+	if err != nil {
+		t.Fatalf("GetMessages 失败: %v", err)
+	}
+	if len(activeMessages) != 2 || activeMessages[1].ID != assistantMsg.ID {
+		t.Fatalf("切换分支后期望恢复原始回复 %s，实际: %+v", assistantMsg.ID, activeMessages)
+	}
+}
+
+func TestRegenerateMessage_RejectsNonLastTurn(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "regenerate_reject_test.db")
+	db, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	conv, err := db.CreateConversation("分支测试对话")
+	if err != nil {
+		t.Fatalf("创建对话失败: %v", err)
+	}
+	if _, err := db.AddMessage(conv.ID, "user", "第一个问题", nil); err != nil {
+		t.Fatalf("添加用户消息失败: %v", err)
+	}
+	firstAssistant, err := db.AddMessage(conv.ID, "assistant", "第一次回答", nil)
+	if err != nil {
+		t.Fatalf("添加助手消息失败: %v", err)
+	}
+	if _, err := db.AddMessage(conv.ID, "user", "第二个问题", nil); err != nil {
+		t.Fatalf("添加用户消息失败: %v", err)
+	}
+	if _, err := db.AddMessage(conv.ID, "assistant", "第二次回答", nil); err != nil {
+		t.Fatalf("添加助手消息失败: %v", err)
+	}
+
+	if _, _, _, err := db.RegenerateMessage(conv.ID, firstAssistant.ID); err == nil {
+		t.Fatal("期望重新生成非最后一轮的回复返回错误")
+	}
+}
@@ -0,0 +1,235 @@
+package database
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cyberstrike-ai/internal/database/migrations"
+
+	"go.uber.org/zap"
+)
+
+// migrationFile 描述一个从内嵌文件系统解析出的迁移脚本。
+type migrationFile struct {
+	version     int
+	description string
+	upSQL       string
+	downSQL     string
+}
+
+// loadMigrations 从 migrations.FS 加载全部迁移，按版本号升序排序；版本号解析失败或某个版本
+// 缺少 up.sql 视为打包错误，直接返回 error（迁移文件由代码仓库维护，不应该出现这类问题）。
+func loadMigrations() ([]migrationFile, error) {
+	entries, err := fs.ReadDir(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("读取内嵌迁移文件失败: %w", err)
+	}
+
+	byVersion := make(map[int]*migrationFile)
+	for _, entry := range entries {
+		name := entry.Name()
+		isDown := strings.HasSuffix(name, ".down.sql")
+		isUp := strings.HasSuffix(name, ".up.sql")
+		if entry.IsDir() || (!isUp && !isDown) {
+			continue
+		}
+
+		base := strings.TrimSuffix(strings.TrimSuffix(name, ".down.sql"), ".up.sql")
+		parts := strings.SplitN(base, "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("迁移文件名版本号非法: %s", name)
+		}
+
+		content, err := migrations.FS.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("读取迁移文件失败: %s: %w", name, err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			description := base
+			if len(parts) == 2 {
+				description = parts[1]
+			}
+			m = &migrationFile{version: version, description: description}
+			byVersion[version] = m
+		}
+		if isUp {
+			m.upSQL = string(content)
+		} else {
+			m.downSQL = string(content)
+		}
+	}
+
+	result := make([]migrationFile, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.upSQL) == "" {
+			return nil, fmt.Errorf("迁移 %04d 缺少 up.sql 文件", m.version)
+		}
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].version < result[j].version })
+	return result, nil
+}
+
+// schemaMigrationsTableSQL 记录已应用迁移版本，需要在第一次运行迁移框架之前就幂等创建好。
+const schemaMigrationsTableSQL = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	description TEXT NOT NULL,
+	applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);`
+
+// runMigrations 应用所有尚未记录在 schema_migrations 中的迁移，按版本号升序依次执行。这是
+// NewDB 启动时的强制检查：某个迁移执行失败会直接返回 error 导致启动失败，而不是带着不完整的
+// schema 继续运行；已成功应用的迁移不受后续失败影响，修复问题后重启即可从断点继续。
+func (db *DB) runMigrations() error {
+	if _, err := db.Exec(schemaMigrationsTableSQL); err != nil {
+		return fmt.Errorf("创建 schema_migrations 表失败: %w", err)
+	}
+
+	all, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	applied, err := db.appliedMigrationVersions()
+	if err != nil {
+		return err
+	}
+
+	appliedCount := 0
+	for _, m := range all {
+		if applied[m.version] {
+			continue
+		}
+		if err := db.applyMigration(m); err != nil {
+			return fmt.Errorf("应用迁移 %04d_%s 失败: %w", m.version, m.description, err)
+		}
+		appliedCount++
+		if db.logger != nil {
+			db.logger.Info("已应用数据库迁移", zap.Int("version", m.version), zap.String("description", m.description))
+		}
+	}
+	if appliedCount > 0 && db.logger != nil {
+		db.logger.Info("数据库迁移检查完成", zap.Int("applied", appliedCount), zap.Int("total", len(all)))
+	}
+	return nil
+}
+
+// appliedMigrationVersions 返回 schema_migrations 中已记录的版本号集合。
+func (db *DB) appliedMigrationVersions() (map[int]bool, error) {
+	rows, err := db.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("读取已应用迁移记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("解析已应用迁移记录失败: %w", err)
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// applyMigration 在单个事务中执行一个迁移的 up 脚本并记录版本号，保证「执行 DDL」与
+// 「登记版本」要么都成功要么都不生效。up.sql 直接通过 *sql.Tx.Exec 提交给驱动，SQLite 与
+// Postgres 的默认驱动均支持在一次 Exec 调用中执行以分号分隔的多条语句。
+func (db *DB) applyMigration(m migrationFile) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	upSQL := rewriteColumnTypes(rewritePlaceholders(m.upSQL, db.dialect), db.dialect)
+	if _, err := tx.Exec(upSQL); err != nil {
+		return err
+	}
+	insertSQL := rewritePlaceholders("INSERT INTO schema_migrations (version, description) VALUES (?, ?)", db.dialect)
+	if _, err := tx.Exec(insertSQL, m.version, m.description); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MigrateDown 回滚最近 steps 个已应用的迁移（steps <= 0 时为 no-op），按版本号降序依次执行
+// 对应的 down.sql。用于人工排障时撤销一次有问题的迁移，正常启动流程不会调用。
+func (db *DB) MigrateDown(steps int) error {
+	if steps <= 0 {
+		return nil
+	}
+
+	all, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	byVersion := make(map[int]migrationFile, len(all))
+	for _, m := range all {
+		byVersion[m.version] = m
+	}
+
+	query := rewritePlaceholders("SELECT version FROM schema_migrations ORDER BY version DESC LIMIT ?", db.dialect)
+	rows, err := db.Query(query, steps)
+	if err != nil {
+		return fmt.Errorf("读取已应用迁移记录失败: %w", err)
+	}
+	var versions []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return err
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	for _, v := range versions {
+		m, ok := byVersion[v]
+		if !ok {
+			return fmt.Errorf("找不到迁移 %04d 对应的脚本文件，无法回滚", v)
+		}
+		if strings.TrimSpace(m.downSQL) == "" {
+			return fmt.Errorf("迁移 %04d 未提供 down.sql，无法回滚", v)
+		}
+		if err := db.revertMigration(m); err != nil {
+			return fmt.Errorf("回滚迁移 %04d_%s 失败: %w", m.version, m.description, err)
+		}
+		if db.logger != nil {
+			db.logger.Info("已回滚数据库迁移", zap.Int("version", m.version), zap.String("description", m.description))
+		}
+	}
+	return nil
+}
+
+// revertMigration 在单个事务中执行一个迁移的 down 脚本并删除对应的版本记录。
+func (db *DB) revertMigration(m migrationFile) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	downSQL := rewriteColumnTypes(rewritePlaceholders(m.downSQL, db.dialect), db.dialect)
+	if _, err := tx.Exec(downSQL); err != nil {
+		return err
+	}
+	deleteSQL := rewritePlaceholders("DELETE FROM schema_migrations WHERE version = ?", db.dialect)
+	if _, err := tx.Exec(deleteSQL, m.version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
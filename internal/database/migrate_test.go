@@ -0,0 +1,95 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"cyberstrike-ai/internal/config"
+
+	"go.uber.org/zap"
+)
+
+func TestNewDB_AppliesEmbeddedMigrations(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "migrate_test.db")
+	db, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&count); err != nil {
+		t.Fatalf("查询 schema_migrations 失败: %v", err)
+	}
+	if count < 2 {
+		t.Fatalf("期望至少应用 2 个迁移，实际记录数: %d", count)
+	}
+
+	var indexName string
+	err = db.QueryRow(
+		"SELECT name FROM sqlite_master WHERE type='index' AND name=?",
+		"idx_sensitive_reveal_audit_log_execution_id",
+	).Scan(&indexName)
+	if err != nil {
+		t.Fatalf("迁移 0002 创建的索引不存在: %v", err)
+	}
+}
+
+func TestNewDB_MigrationsAreIdempotentAcrossRestarts(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "migrate_restart_test.db")
+
+	db1, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("第一次创建数据库失败: %v", err)
+	}
+	db1.Close()
+
+	// 重新以同一个文件打开，模拟进程重启；已应用的迁移不应重复执行或报错。
+	db2, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("重启后再次打开数据库失败: %v", err)
+	}
+	defer db2.Close()
+
+	var count int
+	if err := db2.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&count); err != nil {
+		t.Fatalf("查询 schema_migrations 失败: %v", err)
+	}
+	all, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("加载内嵌迁移失败: %v", err)
+	}
+	if count != len(all) {
+		t.Fatalf("重启后迁移记录数应保持不变。期望: %d, 实际: %d", len(all), count)
+	}
+}
+
+func TestMigrateDown_RevertsLastMigration(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "migrate_down_test.db")
+	db, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.MigrateDown(1); err != nil {
+		t.Fatalf("回滚迁移失败: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE version = 2").Scan(&count); err != nil {
+		t.Fatalf("查询 schema_migrations 失败: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("回滚后版本 2 不应再出现在 schema_migrations 中")
+	}
+
+	var indexName string
+	err = db.QueryRow(
+		"SELECT name FROM sqlite_master WHERE type='index' AND name=?",
+		"idx_sensitive_reveal_audit_log_execution_id",
+	).Scan(&indexName)
+	if err == nil {
+		t.Fatalf("回滚后索引 %s 不应再存在", indexName)
+	}
+}
@@ -0,0 +1,11 @@
+// Package migrations 内嵌数据库 schema 迁移脚本，按版本号顺序管理表结构变更。
+// 迁移文件命名为 NNNN_description.up.sql / NNNN_description.down.sql，NNNN 为四位版本号，
+// 版本号必须严格递增且不重复；internal/database 包按版本号排序依次执行，已应用的版本记录在
+// schema_migrations 表中，重启时只会执行尚未应用的部分。新的表结构变更应通过新增一对
+// up/down 文件提交，而不是直接修改 database.go 里历史遗留的 initTables()。
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -3,11 +3,13 @@ package database
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"time"
 
 	"cyberstrike-ai/internal/mcp"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -19,6 +21,16 @@ func (db *DB) SaveToolExecution(exec *mcp.ToolExecution) error {
 		argsJSON = []byte("{}")
 	}
 
+	var rawArgsJSON sql.NullString
+	if exec.RawArguments != nil {
+		rawArgsBytes, err := json.Marshal(exec.RawArguments)
+		if err != nil {
+			db.logger.Warn("序列化原始执行参数失败", zap.Error(err))
+		} else {
+			rawArgsJSON = sql.NullString{String: string(rawArgsBytes), Valid: true}
+		}
+	}
+
 	var resultJSON sql.NullString
 	if exec.Result != nil {
 		resultBytes, err := json.Marshal(exec.Result)
@@ -44,16 +56,22 @@ func (db *DB) SaveToolExecution(exec *mcp.ToolExecution) error {
 		durationMs = sql.NullInt64{Int64: exec.Duration.Milliseconds(), Valid: true}
 	}
 
+	var traceID sql.NullString
+	if exec.TraceID != "" {
+		traceID = sql.NullString{String: exec.TraceID, Valid: true}
+	}
+
 	query := `
-		INSERT OR REPLACE INTO tool_executions 
-		(id, tool_name, arguments, status, result, error, start_time, end_time, duration_ms, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT OR REPLACE INTO tool_executions
+		(id, tool_name, arguments, raw_arguments, status, result, error, start_time, end_time, duration_ms, created_at, trace_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err = db.Exec(query,
 		exec.ID,
 		exec.ToolName,
 		string(argsJSON),
+		rawArgsJSON,
 		exec.Status,
 		resultJSON,
 		errorText,
@@ -61,6 +79,7 @@ func (db *DB) SaveToolExecution(exec *mcp.ToolExecution) error {
 		endTime,
 		durationMs,
 		time.Now(),
+		traceID,
 	)
 
 	if err != nil {
@@ -118,7 +137,7 @@ func (db *DB) LoadToolExecutionsWithPagination(offset, limit int, status, toolNa
 	}
 
 	query := `
-		SELECT id, tool_name, arguments, status, result, error, start_time, end_time, duration_ms
+		SELECT id, tool_name, arguments, status, result, error, start_time, end_time, duration_ms, trace_id
 		FROM tool_executions
 	`
 	args := []interface{}{}
@@ -155,6 +174,7 @@ func (db *DB) LoadToolExecutionsWithPagination(offset, limit int, status, toolNa
 		var errorText sql.NullString
 		var endTime sql.NullTime
 		var durationMs sql.NullInt64
+		var traceID sql.NullString
 
 		err := rows.Scan(
 			&exec.ID,
@@ -166,6 +186,7 @@ func (db *DB) LoadToolExecutionsWithPagination(offset, limit int, status, toolNa
 			&exec.StartTime,
 			&endTime,
 			&durationMs,
+			&traceID,
 		)
 		if err != nil {
 			db.logger.Warn("加载执行记录失败", zap.Error(err))
@@ -203,6 +224,10 @@ func (db *DB) LoadToolExecutionsWithPagination(offset, limit int, status, toolNa
 			exec.Duration = time.Duration(durationMs.Int64) * time.Millisecond
 		}
 
+		if traceID.Valid {
+			exec.TraceID = traceID.String
+		}
+
 		executions = append(executions, &exec)
 	}
 
@@ -212,7 +237,7 @@ func (db *DB) LoadToolExecutionsWithPagination(offset, limit int, status, toolNa
 // GetToolExecution 根据ID获取单条工具执行记录
 func (db *DB) GetToolExecution(id string) (*mcp.ToolExecution, error) {
 	query := `
-		SELECT id, tool_name, arguments, status, result, error, start_time, end_time, duration_ms
+		SELECT id, tool_name, arguments, status, result, error, start_time, end_time, duration_ms, trace_id
 		FROM tool_executions
 		WHERE id = ?
 	`
@@ -225,6 +250,7 @@ func (db *DB) GetToolExecution(id string) (*mcp.ToolExecution, error) {
 	var errorText sql.NullString
 	var endTime sql.NullTime
 	var durationMs sql.NullInt64
+	var traceID sql.NullString
 
 	err := row.Scan(
 		&exec.ID,
@@ -236,6 +262,7 @@ func (db *DB) GetToolExecution(id string) (*mcp.ToolExecution, error) {
 		&exec.StartTime,
 		&endTime,
 		&durationMs,
+		&traceID,
 	)
 	if err != nil {
 		return nil, err
@@ -267,9 +294,49 @@ func (db *DB) GetToolExecution(id string) (*mcp.ToolExecution, error) {
 		exec.Duration = time.Duration(durationMs.Int64) * time.Millisecond
 	}
 
+	if traceID.Valid {
+		exec.TraceID = traceID.String
+	}
+
 	return &exec, nil
 }
 
+// RevealToolExecutionArguments 读取指定执行记录的原始（未掩码）参数，仅供管理员揭示接口使用。
+// 与 GetToolExecution 等常规查询接口分离，避免敏感参数被普通接口意外携带返回。
+func (db *DB) RevealToolExecutionArguments(id string) (map[string]interface{}, error) {
+	var toolName string
+	var rawArgsJSON sql.NullString
+	err := db.QueryRow(`SELECT tool_name, raw_arguments FROM tool_executions WHERE id = ?`, id).Scan(&toolName, &rawArgsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rawArgsJSON.Valid || rawArgsJSON.String == "" {
+		return nil, nil
+	}
+
+	var rawArgs map[string]interface{}
+	if err := json.Unmarshal([]byte(rawArgsJSON.String), &rawArgs); err != nil {
+		return nil, fmt.Errorf("解析原始执行参数失败: %w", err)
+	}
+
+	return rawArgs, nil
+}
+
+// LogSensitiveReveal 记录一次敏感参数揭示操作，写入审计日志表
+func (db *DB) LogSensitiveReveal(executionID, toolName, remoteAddr string) error {
+	query := `
+		INSERT INTO sensitive_reveal_audit_log (id, execution_id, tool_name, remote_addr, revealed_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err := db.Exec(query, uuid.New().String(), executionID, toolName, remoteAddr, time.Now())
+	if err != nil {
+		db.logger.Error("记录敏感参数揭示审计日志失败", zap.Error(err), zap.String("executionId", executionID))
+		return err
+	}
+	return nil
+}
+
 // DeleteToolExecution 删除工具执行记录
 func (db *DB) DeleteToolExecution(id string) error {
 	query := `DELETE FROM tool_executions WHERE id = ?`
@@ -319,7 +386,7 @@ func (db *DB) GetToolExecutionsByIds(ids []string) ([]*mcp.ToolExecution, error)
 	}
 
 	query := `
-		SELECT id, tool_name, arguments, status, result, error, start_time, end_time, duration_ms
+		SELECT id, tool_name, arguments, status, result, error, start_time, end_time, duration_ms, trace_id
 		FROM tool_executions
 		WHERE id IN (` + strings.Join(placeholders, ",") + `)
 	`
@@ -338,6 +405,7 @@ func (db *DB) GetToolExecutionsByIds(ids []string) ([]*mcp.ToolExecution, error)
 		var errorText sql.NullString
 		var endTime sql.NullTime
 		var durationMs sql.NullInt64
+		var traceID sql.NullString
 
 		err := rows.Scan(
 			&exec.ID,
@@ -349,6 +417,7 @@ func (db *DB) GetToolExecutionsByIds(ids []string) ([]*mcp.ToolExecution, error)
 			&exec.StartTime,
 			&endTime,
 			&durationMs,
+			&traceID,
 		)
 		if err != nil {
 			db.logger.Warn("加载执行记录失败", zap.Error(err))
@@ -386,6 +455,10 @@ func (db *DB) GetToolExecutionsByIds(ids []string) ([]*mcp.ToolExecution, error)
 			exec.Duration = time.Duration(durationMs.Int64) * time.Millisecond
 		}
 
+		if traceID.Valid {
+			exec.TraceID = traceID.String
+		}
+
 		executions = append(executions, &exec)
 	}
 
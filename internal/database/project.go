@@ -0,0 +1,220 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Project 渗透测试项目/合同（多目标交战的顶层分组，对话通过 conversations.project_id 归属到项目）
+type Project struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Client    string    `json:"client"`
+	Scope     string    `json:"scope"`
+	StartDate string    `json:"startDate,omitempty"` // YYYY-MM-DD，为空表示未设置
+	EndDate   string    `json:"endDate,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func scanProject(row interface{ Scan(...interface{}) error }) (*Project, error) {
+	var p Project
+	var createdAt, updatedAt string
+	if err := row.Scan(&p.ID, &p.Name, &p.Client, &p.Scope, &p.StartDate, &p.EndDate, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	p.CreatedAt = parseFlexibleTime(createdAt)
+	p.UpdatedAt = parseFlexibleTime(updatedAt)
+	return &p, nil
+}
+
+// CreateProject 创建项目
+func (db *DB) CreateProject(name, client, scope, startDate, endDate string) (*Project, error) {
+	id := uuid.New().String()
+	now := time.Now()
+
+	_, err := db.Exec(
+		"INSERT INTO projects (id, name, client, scope, start_date, end_date, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		id, name, client, scope, startDate, endDate, now, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("创建项目失败: %w", err)
+	}
+
+	return &Project{
+		ID:        id,
+		Name:      name,
+		Client:    client,
+		Scope:     scope,
+		StartDate: startDate,
+		EndDate:   endDate,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// ListProjects 列出所有项目
+func (db *DB) ListProjects() ([]*Project, error) {
+	rows, err := db.Query(
+		"SELECT id, name, client, scope, COALESCE(start_date, ''), COALESCE(end_date, ''), created_at, updated_at FROM projects ORDER BY created_at DESC",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询项目列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []*Project
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描项目失败: %w", err)
+		}
+		projects = append(projects, p)
+	}
+	return projects, nil
+}
+
+// GetProject 获取项目
+func (db *DB) GetProject(id string) (*Project, error) {
+	row := db.QueryRow(
+		"SELECT id, name, client, scope, COALESCE(start_date, ''), COALESCE(end_date, ''), created_at, updated_at FROM projects WHERE id = ?",
+		id,
+	)
+	p, err := scanProject(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("项目不存在")
+		}
+		return nil, fmt.Errorf("查询项目失败: %w", err)
+	}
+	return p, nil
+}
+
+// UpdateProject 更新项目
+func (db *DB) UpdateProject(id, name, client, scope, startDate, endDate string) error {
+	_, err := db.Exec(
+		"UPDATE projects SET name = ?, client = ?, scope = ?, start_date = ?, end_date = ?, updated_at = ? WHERE id = ?",
+		name, client, scope, startDate, endDate, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("更新项目失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteProject 删除项目；关联对话的 project_id 会被置空（不级联删除对话本身）
+func (db *DB) DeleteProject(id string) error {
+	if _, err := db.Exec("UPDATE conversations SET project_id = NULL WHERE project_id = ?", id); err != nil {
+		return fmt.Errorf("解除项目关联的对话失败: %w", err)
+	}
+	if _, err := db.Exec("DELETE FROM projects WHERE id = ?", id); err != nil {
+		return fmt.Errorf("删除项目失败: %w", err)
+	}
+	return nil
+}
+
+// AssignConversationToProject 将对话归属到项目；projectID 为空表示解除归属
+func (db *DB) AssignConversationToProject(conversationID, projectID string) error {
+	var err error
+	if projectID == "" {
+		_, err = db.Exec("UPDATE conversations SET project_id = NULL WHERE id = ?", conversationID)
+	} else {
+		_, err = db.Exec("UPDATE conversations SET project_id = ? WHERE id = ?", projectID, conversationID)
+	}
+	if err != nil {
+		return fmt.Errorf("更新对话项目归属失败: %w", err)
+	}
+	return nil
+}
+
+// GetProjectConversations 获取项目下的所有对话
+func (db *DB) GetProjectConversations(projectID string) ([]*Conversation, error) {
+	rows, err := db.Query(
+		"SELECT id, title, COALESCE(pinned, 0), created_at, updated_at FROM conversations WHERE project_id = ? ORDER BY updated_at DESC",
+		projectID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询项目对话失败: %w", err)
+	}
+	defer rows.Close()
+
+	var conversations []*Conversation
+	for rows.Next() {
+		var conv Conversation
+		var createdAt, updatedAt string
+		var pinned int
+		if err := rows.Scan(&conv.ID, &conv.Title, &pinned, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("扫描对话失败: %w", err)
+		}
+		conv.CreatedAt = parseFlexibleTime(createdAt)
+		conv.UpdatedAt = parseFlexibleTime(updatedAt)
+		conv.Pinned = pinned != 0
+		conversations = append(conversations, &conv)
+	}
+	return conversations, nil
+}
+
+// GetProjectVulnerabilities 获取项目下所有对话汇总的漏洞（通过 conversations.project_id 关联）
+func (db *DB) GetProjectVulnerabilities(projectID string) ([]*Vulnerability, error) {
+	rows, err := db.Query(
+		`SELECT v.id, v.conversation_id, COALESCE(v.conversation_tag, ''), COALESCE(v.task_tag, ''),
+			v.title, v.description, v.severity, v.status, COALESCE(v.vulnerability_type, ''),
+			COALESCE(v.target, ''), COALESCE(v.proof, ''), COALESCE(v.impact, ''), COALESCE(v.recommendation, ''),
+			v.created_at, v.updated_at
+		 FROM vulnerabilities v
+		 INNER JOIN conversations c ON c.id = v.conversation_id
+		 WHERE c.project_id = ?
+		 ORDER BY v.created_at DESC`,
+		projectID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询项目漏洞失败: %w", err)
+	}
+	defer rows.Close()
+
+	var vulns []*Vulnerability
+	for rows.Next() {
+		var v Vulnerability
+		var createdAt, updatedAt string
+		if err := rows.Scan(&v.ID, &v.ConversationID, &v.ConversationTag, &v.TaskTag,
+			&v.Title, &v.Description, &v.Severity, &v.Status, &v.Type,
+			&v.Target, &v.Proof, &v.Impact, &v.Recommendation,
+			&createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("扫描漏洞失败: %w", err)
+		}
+		v.CreatedAt = parseFlexibleTime(createdAt)
+		v.UpdatedAt = parseFlexibleTime(updatedAt)
+		vulns = append(vulns, &v)
+	}
+	return vulns, nil
+}
+
+// GetProjectVulnerabilityStats 获取项目下漏洞按严重程度的统计，用于项目概览/报告聚合
+func (db *DB) GetProjectVulnerabilityStats(projectID string) (map[string]int, error) {
+	rows, err := db.Query(
+		`SELECT v.severity, COUNT(*)
+		 FROM vulnerabilities v
+		 INNER JOIN conversations c ON c.id = v.conversation_id
+		 WHERE c.project_id = ?
+		 GROUP BY v.severity`,
+		projectID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("统计项目漏洞失败: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make(map[string]int)
+	for rows.Next() {
+		var severity string
+		var count int
+		if err := rows.Scan(&severity, &count); err != nil {
+			continue
+		}
+		stats[severity] = count
+	}
+	return stats, nil
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScheduleRunRecord 是一次 cron 触发的批量任务队列执行记录（见 wivd/CyberStrikeAI#synth-3097），
+// 用于 GET /api/schedules/:queueId/history；队列本身的 Tasks 会在每次 cron 触发前被
+// ResetQueueForRerun 重置，不保留历史，因此需要单独落库。
+type ScheduleRunRecord struct {
+	ID              string    `json:"id"`
+	QueueID         string    `json:"queueId"`
+	ConversationIDs string    `json:"conversationIds"` // JSON 数组字符串，本次触发下各子任务创建的对话ID
+	Status          string    `json:"status"`          // completed | failed
+	Error           string    `json:"error,omitempty"`
+	StartedAt       time.Time `json:"startedAt"`
+	CompletedAt     time.Time `json:"completedAt"`
+}
+
+// RecordScheduleRun 写入一条 cron 触发的执行记录。
+func (db *DB) RecordScheduleRun(queueID, conversationIDsJSON, status, errMsg string, startedAt, completedAt time.Time) error {
+	_, err := db.Exec(
+		"INSERT INTO schedule_run_history (id, queue_id, conversation_ids, status, error, started_at, completed_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		uuid.New().String(), queueID, conversationIDsJSON, status, errMsg, startedAt, completedAt,
+	)
+	return err
+}
+
+// ListScheduleRuns 按队列ID查询触发历史，按时间倒序，最多返回 limit 条（limit <= 0 或超过 200 时取 50）。
+func (db *DB) ListScheduleRuns(queueID string, limit int) ([]ScheduleRunRecord, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	rows, err := db.Query(
+		"SELECT id, queue_id, conversation_ids, status, error, started_at, completed_at FROM schedule_run_history WHERE queue_id = ? ORDER BY started_at DESC LIMIT ?",
+		queueID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []ScheduleRunRecord
+	for rows.Next() {
+		var r ScheduleRunRecord
+		var startedAt, completedAt string
+		if err := rows.Scan(&r.ID, &r.QueueID, &r.ConversationIDs, &r.Status, &r.Error, &startedAt, &completedAt); err != nil {
+			return nil, err
+		}
+		r.StartedAt = parseFlexibleTime(startedAt)
+		r.CompletedAt = parseFlexibleTime(completedAt)
+		records = append(records, r)
+	}
+	return records, nil
+}
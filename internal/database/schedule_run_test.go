@@ -0,0 +1,43 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cyberstrike-ai/internal/config"
+
+	"go.uber.org/zap"
+)
+
+func TestScheduleRun_RecordAndList(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "schedule_run_test.db")
+	db, err := NewDB(config.DatabaseConfig{Path: dbPath}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	firstStarted := time.Now().Add(-time.Hour)
+	secondStarted := time.Now()
+	if err := db.RecordScheduleRun("queue-1", `["conv-1","conv-2"]`, "completed", "", firstStarted, firstStarted); err != nil {
+		t.Fatalf("写入定时任务触发历史失败: %v", err)
+	}
+	if err := db.RecordScheduleRun("queue-1", `["conv-3"]`, "failed", "工具执行失败", secondStarted, secondStarted); err != nil {
+		t.Fatalf("写入定时任务触发历史失败: %v", err)
+	}
+	if err := db.RecordScheduleRun("queue-2", `[]`, "completed", "", secondStarted, secondStarted); err != nil {
+		t.Fatalf("写入定时任务触发历史失败: %v", err)
+	}
+
+	records, err := db.ListScheduleRuns("queue-1", 10)
+	if err != nil {
+		t.Fatalf("查询定时任务触发历史失败: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("期望 2 条 queue-1 的触发记录，实际: %d", len(records))
+	}
+	if records[0].Status != "failed" {
+		t.Fatalf("期望最近一条记录按时间倒序排在最前，实际: %+v", records[0])
+	}
+}
@@ -1,37 +1,204 @@
 package database
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
+	"cyberstrike-ai/internal/security"
+
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 // Vulnerability 漏洞
 type Vulnerability struct {
-	ID              string    `json:"id"`
-	ConversationID  string    `json:"conversation_id"`
-	ConversationTag string    `json:"conversation_tag,omitempty"`
-	TaskTag         string    `json:"task_tag,omitempty"`
-	TaskID          string    `json:"task_id,omitempty"`
-	TaskQueueID     string    `json:"task_queue_id,omitempty"`
-	Title           string    `json:"title"`
-	Description     string    `json:"description"`
-	Severity        string    `json:"severity"` // critical, high, medium, low, info
-	Status          string    `json:"status"`   // open, confirmed, fixed, false_positive
-	Type            string    `json:"type"`
-	Target          string    `json:"target"`
-	Proof           string    `json:"proof"`
-	Impact          string    `json:"impact"`
-	Recommendation  string    `json:"recommendation"`
+	ID              string `json:"id"`
+	ConversationID  string `json:"conversation_id"`
+	ConversationTag string `json:"conversation_tag,omitempty"`
+	TaskTag         string `json:"task_tag,omitempty"`
+	TaskID          string `json:"task_id,omitempty"`
+	TaskQueueID     string `json:"task_queue_id,omitempty"`
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+	Severity        string `json:"severity"` // critical, high, medium, low, info
+	Status          string `json:"status"`   // open, confirmed, fixed, false_positive
+	Type            string `json:"type"`
+	Target          string `json:"target"`
+	Proof           string `json:"proof"`
+	Impact          string `json:"impact"`
+	Recommendation  string `json:"recommendation"`
+	// CVSSVector 是 CVSS v3.1 向量字符串（如 "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"），
+	// 可由 AI 提取管线或人工通过 API 设置；留空表示未评分，此时 Severity 沿用调用方传入的自由文本值。
+	CVSSVector string `json:"cvss_vector,omitempty"`
+	// CVSSScore 是根据 CVSSVector 计算出的 Base Score（0.0-10.0），CVSSVector 为空时该字段为 0。
+	// 设置了 CVSSVector 时，Severity 由 CVSSScore 派生（见 security.SeverityFromCVSSScore），不再采用调用方传入的取值。
+	CVSSScore float64 `json:"cvss_score,omitempty"`
+	// CVEEnrichments 是标题/描述/证明中出现的 CVE 编号自动查询 NVD 后回填的详情列表，见 ApplyCVEEnrichment；
+	// 由 security.EnrichVulnerabilityCVEsAsync 在创建时异步写回，创建瞬间该字段一般为空。
+	CVEEnrichments []security.CVERecord `json:"cve_enrichments,omitempty"`
+	// EnrichmentStatus 标记CVE富化状态：none（未发现CVE编号或未启用富化）、pending（已提交富化，等待写回）、
+	// enriched（至少一个CVE编号富化成功）。前端可据此展示"富化中"等待态而非误判为无匹配。
+	EnrichmentStatus string `json:"enrichment_status,omitempty"`
+	// JiraIssueKey 是该漏洞在 Jira 中对应的工单编号（见 handler.JiraHandler.CreateOrUpdateTicket），
+	// 留空表示尚未创建工单；已存在时再次调用创建接口会改为更新该工单而非重复创建。
+	JiraIssueKey string `json:"jira_issue_key,omitempty"`
+	// Fingerprint 由 Type+Target 计算得出（见 computeVulnerabilityFingerprint），用于跨重复扫描去重合并，
+	// 前端一般无需关心此字段，仅在需要人工核对去重命中原因时使用。
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// OccurrenceCount 该指纹在本对话下被发现的累计次数（重复扫描命中同一指纹时递增，而非插入新行）。
+	OccurrenceCount int       `json:"occurrence_count"`
+	FirstSeenAt     time.Time `json:"first_seen_at"`
+	LastSeenAt      time.Time `json:"last_seen_at"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 }
 
-// CreateVulnerability 创建漏洞
+// applyCVSSScoring 若设置了 CVSSVector，则计算并回填 CVSSScore，同时用 security.SeverityFromCVSSScore
+// 覆盖 Severity——CVSS评分是比自由文本更客观的依据，避免同一漏洞在有评分的情况下仍出现severity口径不一致。
+// 向量非法时保留调用方原始输入并记录警告，不阻断创建/更新流程。
+func (db *DB) applyCVSSScoring(vuln *Vulnerability) {
+	if strings.TrimSpace(vuln.CVSSVector) == "" {
+		return
+	}
+	score, err := security.ComputeCVSSBaseScore(vuln.CVSSVector)
+	if err != nil {
+		db.logger.Warn("解析CVSS向量失败，保留原始severity", zap.String("cvss_vector", vuln.CVSSVector), zap.Error(err))
+		return
+	}
+	vuln.CVSSScore = score
+	vuln.Severity = security.SeverityFromCVSSScore(score)
+}
+
+// computeVulnerabilityFingerprint 按 类型+目标（通常已包含端口/参数，如 "host:port" 或
+// "http://x/page?id=1"）计算去重指纹：小写+去首尾空白后取 sha256，避免大小写/多余空格造成的误判未命中。
+// 同一对话内指纹相同的发现视为同一漏洞的重复出现，合并为一行并递增 occurrence_count。
+func computeVulnerabilityFingerprint(vulnType, target string) string {
+	normalized := strings.ToLower(strings.TrimSpace(vulnType)) + "|" + strings.ToLower(strings.TrimSpace(target))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// seedEnrichmentStatus 在漏洞标题/描述/证明中扫描 CVE 编号，若发现则将 EnrichmentStatus 置为
+// "pending"（等待 security.EnrichVulnerabilityCVEsAsync 异步回填），否则置为 "none"。
+func seedEnrichmentStatus(vuln *Vulnerability) {
+	text := vuln.Title + "\n" + vuln.Description + "\n" + vuln.Proof
+	if len(security.ExtractCVEIDs(text)) > 0 {
+		vuln.EnrichmentStatus = "pending"
+	} else {
+		vuln.EnrichmentStatus = "none"
+	}
+}
+
+// marshalCVEEnrichments 将富化结果列表序列化为 JSON 字符串用于落库；列表为空时返回空字符串。
+func marshalCVEEnrichments(records []security.CVERecord) string {
+	if len(records) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// unmarshalCVEEnrichments 解析落库的 JSON 字符串；为空或解析失败时返回 nil。
+func unmarshalCVEEnrichments(raw string) []security.CVERecord {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var records []security.CVERecord
+	if err := json.Unmarshal([]byte(raw), &records); err != nil {
+		return nil
+	}
+	return records
+}
+
+// ApplyCVEEnrichment 实现 security.CVEEnrichmentSink：将查询到的单个 CVE 详情合并进指定漏洞记录的
+// CVEEnrichments（按 ID 去重，命中则覆盖为最新查询结果），并将 EnrichmentStatus 置为 enriched。
+func (db *DB) ApplyCVEEnrichment(vulnerabilityID string, record security.CVERecord) error {
+	vuln, err := db.GetVulnerability(vulnerabilityID)
+	if err != nil {
+		return fmt.Errorf("查询待富化的漏洞失败: %w", err)
+	}
+
+	merged := make([]security.CVERecord, 0, len(vuln.CVEEnrichments)+1)
+	replaced := false
+	for _, existing := range vuln.CVEEnrichments {
+		if existing.ID == record.ID {
+			merged = append(merged, record)
+			replaced = true
+			continue
+		}
+		merged = append(merged, existing)
+	}
+	if !replaced {
+		merged = append(merged, record)
+	}
+
+	_, err = db.Exec(
+		"UPDATE vulnerabilities SET cve_enrichments = ?, enrichment_status = ? WHERE id = ?",
+		marshalCVEEnrichments(merged), "enriched", vulnerabilityID,
+	)
+	if err != nil {
+		return fmt.Errorf("写入CVE富化信息失败: %w", err)
+	}
+	return nil
+}
+
+// UpdateVulnerabilityJiraIssueKey 在成功创建/更新 Jira 工单后，将工单编号写回漏洞记录；
+// 由 handler.JiraHandler.CreateOrUpdateTicket 调用，issueKey 已存在时表示是本次更新命中的已有工单。
+func (db *DB) UpdateVulnerabilityJiraIssueKey(vulnerabilityID, issueKey string) error {
+	_, err := db.Exec(
+		"UPDATE vulnerabilities SET jira_issue_key = ?, updated_at = ? WHERE id = ?",
+		issueKey, time.Now(), vulnerabilityID,
+	)
+	if err != nil {
+		return fmt.Errorf("写入Jira工单编号失败: %w", err)
+	}
+	return nil
+}
+
+// GetVulnerabilityByFingerprint 按对话+指纹查找已存在的漏洞记录，未找到返回 (nil, nil)。
+func (db *DB) GetVulnerabilityByFingerprint(conversationID, fingerprint string) (*Vulnerability, error) {
+	if fingerprint == "" {
+		return nil, nil
+	}
+	var id string
+	err := db.QueryRow(
+		"SELECT id FROM vulnerabilities WHERE conversation_id = ? AND fingerprint = ? ORDER BY created_at ASC LIMIT 1",
+		conversationID, fingerprint,
+	).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("按指纹查找漏洞失败: %w", err)
+	}
+	return db.GetVulnerability(id)
+}
+
+// CreateVulnerability 创建漏洞；若同一对话下已存在相同指纹（Type+Target）的记录，则不再插入新行，
+// 而是合并为一次重复出现：递增 occurrence_count、刷新 last_seen_at，first_seen_at 保持首次发现时间不变。
+// 这避免了重复扫描（如定时任务反复跑同一个 nuclei 模板）在漏洞列表中堆积大量完全重复的行。
 func (db *DB) CreateVulnerability(vuln *Vulnerability) (*Vulnerability, error) {
+	db.applyCVSSScoring(vuln)
+	seedEnrichmentStatus(vuln)
+	vuln.Fingerprint = computeVulnerabilityFingerprint(vuln.Type, vuln.Target)
+
+	if existing, err := db.GetVulnerabilityByFingerprint(vuln.ConversationID, vuln.Fingerprint); err != nil {
+		db.logger.Warn("查询漏洞去重指纹失败，回退为新建记录", zap.Error(err))
+	} else if existing != nil {
+		if mergeErr := db.mergeVulnerabilityOccurrence(existing.ID); mergeErr != nil {
+			return nil, fmt.Errorf("合并重复漏洞记录失败: %w", mergeErr)
+		}
+		return db.GetVulnerability(existing.ID)
+	}
+
 	if vuln.ID == "" {
 		vuln.ID = uuid.New().String()
 	}
@@ -43,21 +210,24 @@ func (db *DB) CreateVulnerability(vuln *Vulnerability) (*Vulnerability, error) {
 		vuln.CreatedAt = now
 	}
 	vuln.UpdatedAt = now
+	vuln.FirstSeenAt = vuln.CreatedAt
+	vuln.LastSeenAt = vuln.CreatedAt
+	vuln.OccurrenceCount = 1
 
 	query := `
 		INSERT INTO vulnerabilities (
 			id, conversation_id, conversation_tag, task_tag, title, description, severity, status,
-			vulnerability_type, target, proof, impact, recommendation,
-			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			vulnerability_type, target, proof, impact, recommendation, cvss_vector, cvss_score,
+			enrichment_status, fingerprint, occurrence_count, first_seen_at, last_seen_at, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := db.Exec(
 		query,
 		vuln.ID, vuln.ConversationID, vuln.ConversationTag, vuln.TaskTag, vuln.Title, vuln.Description,
 		vuln.Severity, vuln.Status, vuln.Type, vuln.Target,
-		vuln.Proof, vuln.Impact, vuln.Recommendation,
-		vuln.CreatedAt, vuln.UpdatedAt,
+		vuln.Proof, vuln.Impact, vuln.Recommendation, vuln.CVSSVector, vuln.CVSSScore,
+		vuln.EnrichmentStatus, vuln.Fingerprint, vuln.OccurrenceCount, vuln.FirstSeenAt, vuln.LastSeenAt, vuln.CreatedAt, vuln.UpdatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("创建漏洞失败: %w", err)
@@ -66,6 +236,20 @@ func (db *DB) CreateVulnerability(vuln *Vulnerability) (*Vulnerability, error) {
 	return vuln, nil
 }
 
+// mergeVulnerabilityOccurrence 将一次新的重复发现合并到已存在的漏洞记录：occurrence_count+1，
+// last_seen_at/updated_at 刷新为当前时间，first_seen_at 与其余描述性字段保持不变。
+func (db *DB) mergeVulnerabilityOccurrence(id string) error {
+	now := time.Now()
+	_, err := db.Exec(
+		"UPDATE vulnerabilities SET occurrence_count = occurrence_count + 1, last_seen_at = ?, updated_at = ? WHERE id = ?",
+		now, now, id,
+	)
+	if err != nil {
+		return fmt.Errorf("更新漏洞出现次数失败: %w", err)
+	}
+	return nil
+}
+
 // GetVulnerability 获取漏洞
 func (db *DB) GetVulnerability(id string) (*Vulnerability, error) {
 	var vuln Vulnerability
@@ -74,16 +258,27 @@ func (db *DB) GetVulnerability(id string) (*Vulnerability, error) {
 		       conversation_tag, task_tag, vulnerability_type, target, proof, impact, recommendation,
 		       COALESCE((SELECT bt.id FROM batch_tasks bt WHERE bt.conversation_id = vulnerabilities.conversation_id LIMIT 1), '') AS task_id,
 		       COALESCE((SELECT bt.queue_id FROM batch_tasks bt WHERE bt.conversation_id = vulnerabilities.conversation_id LIMIT 1), '') AS task_queue_id,
+		       COALESCE(cvss_vector, ''), COALESCE(cvss_score, 0),
+		       COALESCE(cve_enrichments, ''), COALESCE(enrichment_status, 'none'),
+		       COALESCE(fingerprint, ''), COALESCE(occurrence_count, 1),
+		       COALESCE(first_seen_at, created_at), COALESCE(last_seen_at, updated_at),
+		       COALESCE(jira_issue_key, ''),
 		       created_at, updated_at
 		FROM vulnerabilities
 		WHERE id = ?
 	`
 
+	var cveEnrichmentsRaw string
+	var firstSeenAt, lastSeenAt string
 	err := db.QueryRow(query, id).Scan(
 		&vuln.ID, &vuln.ConversationID, &vuln.Title, &vuln.Description,
 		&vuln.Severity, &vuln.Status, &vuln.ConversationTag, &vuln.TaskTag, &vuln.Type, &vuln.Target,
 		&vuln.Proof, &vuln.Impact, &vuln.Recommendation,
 		&vuln.TaskID, &vuln.TaskQueueID,
+		&vuln.CVSSVector, &vuln.CVSSScore,
+		&cveEnrichmentsRaw, &vuln.EnrichmentStatus,
+		&vuln.Fingerprint, &vuln.OccurrenceCount, &firstSeenAt, &lastSeenAt,
+		&vuln.JiraIssueKey,
 		&vuln.CreatedAt, &vuln.UpdatedAt,
 	)
 	if err != nil {
@@ -92,10 +287,26 @@ func (db *DB) GetVulnerability(id string) (*Vulnerability, error) {
 		}
 		return nil, fmt.Errorf("获取漏洞失败: %w", err)
 	}
+	vuln.CVEEnrichments = unmarshalCVEEnrichments(cveEnrichmentsRaw)
+	vuln.FirstSeenAt = parseFlexibleTime(firstSeenAt)
+	vuln.LastSeenAt = parseFlexibleTime(lastSeenAt)
 
 	return &vuln, nil
 }
 
+// parseFlexibleTime 解析 COALESCE(...) 查询结果中失去列类型信息、驱动无法自动转换为 time.Time
+// 的时间字符串；依次尝试 SQLite 默认写入格式与 RFC3339，均失败时返回零值（调用方按空值处理）。
+func parseFlexibleTime(s string) time.Time {
+	if t, err := time.Parse("2006-01-02 15:04:05.999999999-07:00", s); err == nil {
+		return t
+	}
+	if t, err := time.Parse("2006-01-02 15:04:05", s); err == nil {
+		return t
+	}
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
+
 // ListVulnerabilities 列出漏洞
 func (db *DB) ListVulnerabilities(limit, offset int, id, conversationID, severity, status, taskID, conversationTag, taskTag string) ([]*Vulnerability, error) {
 	query := `
@@ -103,6 +314,11 @@ func (db *DB) ListVulnerabilities(limit, offset int, id, conversationID, severit
 		       vulnerability_type, target, proof, impact, recommendation,
 		       COALESCE((SELECT bt.id FROM batch_tasks bt WHERE bt.conversation_id = vulnerabilities.conversation_id LIMIT 1), '') AS task_id,
 		       COALESCE((SELECT bt.queue_id FROM batch_tasks bt WHERE bt.conversation_id = vulnerabilities.conversation_id LIMIT 1), '') AS task_queue_id,
+		       COALESCE(cvss_vector, ''), COALESCE(cvss_score, 0),
+		       COALESCE(cve_enrichments, ''), COALESCE(enrichment_status, 'none'),
+		       COALESCE(fingerprint, ''), COALESCE(occurrence_count, 1),
+		       COALESCE(first_seen_at, created_at), COALESCE(last_seen_at, updated_at),
+		       COALESCE(jira_issue_key, ''),
 		       created_at, updated_at
 		FROM vulnerabilities
 		WHERE 1=1
@@ -150,17 +366,26 @@ func (db *DB) ListVulnerabilities(limit, offset int, id, conversationID, severit
 	var vulnerabilities []*Vulnerability
 	for rows.Next() {
 		var vuln Vulnerability
+		var cveEnrichmentsRaw string
+		var firstSeenAt, lastSeenAt string
 		err := rows.Scan(
 			&vuln.ID, &vuln.ConversationID, &vuln.Title, &vuln.Description,
 			&vuln.Severity, &vuln.Status, &vuln.ConversationTag, &vuln.TaskTag, &vuln.Type, &vuln.Target,
 			&vuln.Proof, &vuln.Impact, &vuln.Recommendation,
 			&vuln.TaskID, &vuln.TaskQueueID,
+			&vuln.CVSSVector, &vuln.CVSSScore,
+			&cveEnrichmentsRaw, &vuln.EnrichmentStatus,
+			&vuln.Fingerprint, &vuln.OccurrenceCount, &firstSeenAt, &lastSeenAt,
+			&vuln.JiraIssueKey,
 			&vuln.CreatedAt, &vuln.UpdatedAt,
 		)
 		if err != nil {
 			db.logger.Warn("扫描漏洞记录失败", zap.Error(err))
 			continue
 		}
+		vuln.CVEEnrichments = unmarshalCVEEnrichments(cveEnrichmentsRaw)
+		vuln.FirstSeenAt = parseFlexibleTime(firstSeenAt)
+		vuln.LastSeenAt = parseFlexibleTime(lastSeenAt)
 		vulnerabilities = append(vulnerabilities, &vuln)
 	}
 
@@ -213,12 +438,16 @@ func (db *DB) CountVulnerabilities(id, conversationID, severity, status, taskID,
 // UpdateVulnerability 更新漏洞
 func (db *DB) UpdateVulnerability(id string, vuln *Vulnerability) error {
 	vuln.UpdatedAt = time.Now()
+	// CVSS向量可能在本次更新中被新设置或修改，需重新计算评分并据此派生severity，见 applyCVSSScoring。
+	db.applyCVSSScoring(vuln)
+	// 类型/目标可能在人工核实后被修正，同步重算指纹，避免旧指纹继续匹配到错误的重复扫描结果。
+	vuln.Fingerprint = computeVulnerabilityFingerprint(vuln.Type, vuln.Target)
 
 	query := `
 		UPDATE vulnerabilities
 		SET conversation_tag = ?, task_tag = ?, title = ?, description = ?, severity = ?, status = ?,
 		    vulnerability_type = ?, target = ?, proof = ?, impact = ?,
-		    recommendation = ?, updated_at = ?
+		    recommendation = ?, cvss_vector = ?, cvss_score = ?, fingerprint = ?, updated_at = ?
 		WHERE id = ?
 	`
 
@@ -226,7 +455,7 @@ func (db *DB) UpdateVulnerability(id string, vuln *Vulnerability) error {
 		query,
 		vuln.ConversationTag, vuln.TaskTag, vuln.Title, vuln.Description, vuln.Severity, vuln.Status,
 		vuln.Type, vuln.Target, vuln.Proof, vuln.Impact,
-		vuln.Recommendation, vuln.UpdatedAt, id,
+		vuln.Recommendation, vuln.CVSSVector, vuln.CVSSScore, vuln.Fingerprint, vuln.UpdatedAt, id,
 	)
 	if err != nil {
 		return fmt.Errorf("更新漏洞失败: %w", err)
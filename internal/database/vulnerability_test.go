@@ -0,0 +1,27 @@
+package database
+
+import "testing"
+
+func TestComputeVulnerabilityFingerprint_SameInputSameFingerprint(t *testing.T) {
+	a := computeVulnerabilityFingerprint("SQL注入", "http://example.com/page?id=1")
+	b := computeVulnerabilityFingerprint("SQL注入", "http://example.com/page?id=1")
+	if a != b {
+		t.Errorf("相同type+target应产生相同指纹，实际: %s != %s", a, b)
+	}
+}
+
+func TestComputeVulnerabilityFingerprint_CaseAndSpaceInsensitive(t *testing.T) {
+	a := computeVulnerabilityFingerprint("XSS", " 192.168.1.1:8080 ")
+	b := computeVulnerabilityFingerprint("xss", "192.168.1.1:8080")
+	if a != b {
+		t.Errorf("大小写/首尾空白不应影响指纹，实际: %s != %s", a, b)
+	}
+}
+
+func TestComputeVulnerabilityFingerprint_DifferentTargetDifferentFingerprint(t *testing.T) {
+	a := computeVulnerabilityFingerprint("SQL注入", "http://example.com/page?id=1")
+	b := computeVulnerabilityFingerprint("SQL注入", "http://example.com/page?id=2")
+	if a == b {
+		t.Error("不同target应产生不同指纹")
+	}
+}
@@ -0,0 +1,135 @@
+// Package defectdojo 提供与 DefectDojo 的集成能力：将本地漏洞记录以
+// Generic Findings Import 格式推送为一次 Engagement Import。
+package defectdojo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultScanType = "Generic Findings Import"
+
+// Finding 对应 DefectDojo Generic Findings JSON 格式中的单条记录。
+// 字段命名遵循 DefectDojo 官方文档（https://docs.defectdojo.com/en/connecting_your_tools/parsers/file/generic/），
+// 未使用 omitempty 以外的字段均保留默认值，避免因缺字段导致导入端解析失败。
+type Finding struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Severity    string  `json:"severity"` // Critical, High, Medium, Low, Info
+	Date        string  `json:"date,omitempty"`
+	Mitigation  string  `json:"mitigation,omitempty"`
+	Impact      string  `json:"impact,omitempty"`
+	References  string  `json:"references,omitempty"`
+	Active      bool    `json:"active"`
+	Verified    bool    `json:"verified"`
+	CVSSVector  string  `json:"cvssv3,omitempty"`
+	CVSSScore   float64 `json:"cvssv3_score,omitempty"`
+}
+
+type genericFindingsDocument struct {
+	Findings []Finding `json:"findings"`
+}
+
+// Client 是 DefectDojo REST API v2 的最小客户端，仅实现 import-scan 推送所需的能力。
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+}
+
+// NewClient 创建 DefectDojo 客户端；httpClient 为 nil 时使用默认超时的 http.Client。
+func NewClient(baseURL, apiKey string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{
+		httpClient: httpClient,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+	}
+}
+
+// SeverityFromInternal 把内部自由文本 severity（critical/high/medium/low/info）映射为
+// DefectDojo Generic Findings 要求的首字母大写形式；无法识别的取值回退为 "Info"。
+func SeverityFromInternal(severity string) string {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "critical":
+		return "Critical"
+	case "high":
+		return "High"
+	case "medium":
+		return "Medium"
+	case "low":
+		return "Low"
+	default:
+		return "Info"
+	}
+}
+
+// ImportEngagementFindings 将 findings 以 Generic Findings JSON 文件的形式推送到
+// /api/v2/import-scan/，落在指定的 engagementID 下。
+func (c *Client) ImportEngagementFindings(ctx context.Context, engagementID int, scanType string, findings []Finding) error {
+	if c == nil || c.baseURL == "" {
+		return fmt.Errorf("defectdojo 客户端未配置 base_url")
+	}
+	if scanType == "" {
+		scanType = defaultScanType
+	}
+	doc := genericFindingsDocument{Findings: findings}
+	fileBytes, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("序列化 Generic Findings JSON 失败: %w", err)
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	fields := map[string]string{
+		"engagement": strconv.Itoa(engagementID),
+		"scan_type":  scanType,
+		"active":     "true",
+		"verified":   "false",
+	}
+	for k, v := range fields {
+		if err := writer.WriteField(k, v); err != nil {
+			return fmt.Errorf("写入表单字段 %s 失败: %w", k, err)
+		}
+	}
+	part, err := writer.CreateFormFile("file", "cyberstrike-findings.json")
+	if err != nil {
+		return fmt.Errorf("创建上传文件字段失败: %w", err)
+	}
+	if _, err := part.Write(fileBytes); err != nil {
+		return fmt.Errorf("写入上传文件内容失败: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("关闭 multipart writer 失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v2/import-scan/", body)
+	if err != nil {
+		return fmt.Errorf("构造 DefectDojo 请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Token "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 DefectDojo import-scan 接口失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("DefectDojo import-scan 返回状态码 %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
@@ -17,9 +17,11 @@ import (
 	"unicode/utf8"
 
 	"cyberstrike-ai/internal/agent"
+	"cyberstrike-ai/internal/attackchain"
 	"cyberstrike-ai/internal/config"
 	"cyberstrike-ai/internal/database"
 	"cyberstrike-ai/internal/mcp/builtin"
+	"cyberstrike-ai/internal/memory"
 	"cyberstrike-ai/internal/multiagent"
 
 	"github.com/gin-gonic/gin"
@@ -128,6 +130,14 @@ type AgentHandler struct {
 	batchRunning      map[string]struct{}
 	// hitlWhitelistSaver 侧栏「应用」HITL 时将会话增量白名单合并写入 config.yaml（可选）
 	hitlWhitelistSaver HitlToolWhitelistSaver
+	// memoryStore 长期代理记忆（按 target 持久化的事实），请求携带 target 时用于注入摘要；未设置时不启用该能力
+	memoryStore *memory.Store
+	// fofaHandler FOFA 查询能力，供一键式 FOFA -> 批量扫描流水线复用（未设置时流水线接口不可用）
+	fofaHandler *FofaHandler
+	// defectDojoTrigger 会话运行完成后自动推送漏洞到 DefectDojo（未设置或未启用时跳过）
+	defectDojoTrigger *DefectDojoTrigger
+	// webhookTrigger 任务完成/失败时向订阅端点投递 outbound webhook（未设置或未启用时跳过）
+	webhookTrigger *WebhookTrigger
 }
 
 // HitlToolWhitelistSaver 合并 HITL 免审批工具到全局配置并落盘
@@ -179,6 +189,42 @@ func (h *AgentHandler) SetAgentsMarkdownDir(absDir string) {
 	h.agentsMarkdownDir = strings.TrimSpace(absDir)
 }
 
+// SetMemoryStore 设置长期代理记忆存储，用于按请求携带的 target 注入既往事实摘要。
+func (h *AgentHandler) SetMemoryStore(store *memory.Store) {
+	h.memoryStore = store
+}
+
+// SetFofaHandler 设置 FOFA 处理器，供 FOFA -> 批量扫描一键流水线复用其自然语言解析与查询能力。
+func (h *AgentHandler) SetFofaHandler(fofaHandler *FofaHandler) {
+	h.fofaHandler = fofaHandler
+}
+
+// SetDefectDojoTrigger 设置会话运行完成后自动推送 DefectDojo 的触发器；不设置或 client 为空时该能力跳过。
+func (h *AgentHandler) SetDefectDojoTrigger(trigger *DefectDojoTrigger) {
+	h.defectDojoTrigger = trigger
+}
+
+// SetWebhookTrigger 设置任务完成/失败时投递 outbound webhook 的触发器；不设置或未启用时该能力跳过。
+func (h *AgentHandler) SetWebhookTrigger(trigger *WebhookTrigger) {
+	h.webhookTrigger = trigger
+}
+
+// applyMemoryContext 若请求携带 target 且记忆存储已配置，将该 target 的既往事实摘要前置到消息中。
+func (h *AgentHandler) applyMemoryContext(message, target string) string {
+	if h.memoryStore == nil || strings.TrimSpace(target) == "" {
+		return message
+	}
+	summary, err := h.memoryStore.Summary(target, 10)
+	if err != nil {
+		h.logger.Warn("读取长期代理记忆失败", zap.String("target", target), zap.Error(err))
+		return message
+	}
+	if summary == "" {
+		return message
+	}
+	return summary + "\n\n" + message
+}
+
 // SetHitlToolWhitelistSaver 设置 HITL 白名单落盘（与 ConfigHandler 配合，避免循环引用用接口）
 func (h *AgentHandler) SetHitlToolWhitelistSaver(s HitlToolWhitelistSaver) {
 	h.hitlWhitelistSaver = s
@@ -210,6 +256,13 @@ type ChatRequest struct {
 	Hitl                 *HITLRequest     `json:"hitl,omitempty"`
 	// Orchestration 仅对 /api/multi-agent、/api/multi-agent/stream：deep | plan_execute | supervisor；空则等同 deep。机器人/批量等无请求体时由服务端默认 deep。/api/eino-agent* 不使用此字段。
 	Orchestration string `json:"orchestration,omitempty"`
+	// Language 本次会话输出语言（如 zh、en），覆盖 config.yaml 中 agent.language 的全局默认值；留空使用全局默认。
+	Language string `json:"language,omitempty"`
+	// Target 本次任务的目标（IP/域名/主机名），携带时会从长期代理记忆中查找该 target 的既往事实摘要注入上下文。
+	Target string `json:"target,omitempty"`
+	// QueueIfBusy 为 true 时，若会话已有任务在执行，本次消息不再直接返回 task_already_running 错误，
+	// 而是排队等待当前任务结束后自动执行；排队位置可通过 GET /api/agent-loop/tasks 的 queuedTasks 字段查看。
+	QueueIfBusy bool `json:"queueIfBusy,omitempty"`
 }
 
 type HITLRequest struct {
@@ -466,6 +519,118 @@ type ChatResponse struct {
 	Time            time.Time `json:"time"`
 }
 
+// EstimateRequest 干跑（dry-run）开销预估请求体
+type EstimateRequest struct {
+	Message string `json:"message" binding:"required"`
+	Role    string `json:"role,omitempty"` // 角色名称，用于按角色过滤会暴露的工具列表
+}
+
+// EstimateCost 在不实际执行 Agent Loop 的情况下，预估首轮迭代的 Token 消耗、会暴露的工具列表，
+// 以及基于 config.yaml 中 openai.price_* 定价的费用区间，便于用户在启动昂贵批量任务前先行核算。
+func (h *AgentHandler) EstimateCost(c *gin.Context) {
+	var req EstimateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	finalMessage := req.Message
+	var roleTools []string
+	if req.Role != "" && req.Role != "默认" && h.config.Roles != nil {
+		if role, exists := h.config.Roles[req.Role]; exists && role.Enabled {
+			if role.UserPrompt != "" {
+				finalMessage = role.UserPrompt + "\n\n" + req.Message
+			}
+			roleTools = role.Tools
+		}
+	}
+
+	estimate := h.agent.EstimateRequest(finalMessage, roleTools)
+	c.JSON(http.StatusOK, estimate)
+}
+
+// LLMRetryStatsResponse LLM 调用重试统计响应
+type LLMRetryStatsResponse struct {
+	TotalRetries int64            `json:"totalRetries"` // 累计重试次数（不含首次尝试）
+	ByStatusCode map[string]int64 `json:"byStatusCode"` // 按触发状态码拆分的重试次数，键 "0" 表示网络类等非 HTTP 错误
+}
+
+// GetLLMRetryStats 返回自进程启动以来 OpenAI 兼容接口调用的累计重试次数，用于观测重试/退避策略是否生效。
+func (h *AgentHandler) GetLLMRetryStats(c *gin.Context) {
+	total, byStatus := h.agent.GetLLMRetryStats()
+	resp := LLMRetryStatsResponse{
+		TotalRetries: total,
+		ByStatusCode: make(map[string]int64, len(byStatus)),
+	}
+	for code, count := range byStatus {
+		resp.ByStatusCode[strconv.Itoa(code)] = count
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ResumeRequest 续跑请求体
+type ResumeRequest struct {
+	ConversationID string `json:"conversationId" binding:"required"`
+}
+
+// ResumeAgentLoop 从检查点恢复此前因服务重启等原因中途中断的 Agent Loop（POST /api/agent-loop/resume）。
+// 检查点由 Agent 在运行过程中每轮迭代自动保存（见 agent.CheckpointSaver），任务正常结束后会被清除；
+// 找不到检查点或该会话已有任务在跑时返回错误，不会静默创建新任务。
+func (h *AgentHandler) ResumeAgentLoop(c *gin.Context) {
+	var req ResumeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	conversationID := strings.TrimSpace(req.ConversationID)
+
+	if h.tasks.GetTask(conversationID) != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "该会话已有任务正在执行，请勿重复续跑"})
+		return
+	}
+
+	// 使用独立上下文执行，避免客户端断开连接导致续跑任务被取消
+	baseCtx, cancelWithCause := context.WithCancelCause(context.Background())
+	taskCtx, timeoutCancel := context.WithTimeout(baseCtx, 600*time.Minute)
+	defer timeoutCancel()
+	defer cancelWithCause(nil)
+	progressCallback := h.createProgressCallback(taskCtx, cancelWithCause, conversationID, "", nil)
+
+	if _, err := h.tasks.StartTask(conversationID, "(从检查点续跑)", cancelWithCause); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	taskStatus := "completed"
+	defer h.tasks.FinishTask(conversationID, taskStatus)
+
+	result, err := h.agent.AgentLoopResumeFromCheckpoint(taskCtx, conversationID, progressCallback)
+	if err != nil {
+		h.logger.Error("续跑Agent Loop失败", zap.String("conversationId", conversationID), zap.Error(err))
+		taskStatus = "failed"
+		if result != nil && (result.LastAgentTraceInput != "" || result.LastAgentTraceOutput != "") {
+			if saveErr := h.db.SaveAgentTrace(conversationID, result.LastAgentTraceInput, result.LastAgentTraceOutput); saveErr != nil {
+				h.logger.Warn("保存续跑任务的代理轨迹失败", zap.Error(saveErr))
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.SaveAgentTrace(conversationID, result.LastAgentTraceInput, result.LastAgentTraceOutput); err != nil {
+		h.logger.Warn("保存代理轨迹失败", zap.Error(err))
+	}
+	if _, err := h.db.AddMessage(conversationID, "assistant", result.Response, nil); err != nil {
+		h.logger.Warn("保存续跑结果消息失败", zap.Error(err))
+	}
+
+	c.JSON(http.StatusOK, ChatResponse{
+		Response:        result.Response,
+		MCPExecutionIDs: result.MCPExecutionIDs,
+		ConversationID:  conversationID,
+		Time:            time.Now(),
+	})
+}
+
 // AgentLoop 处理Agent Loop请求
 func (h *AgentHandler) AgentLoop(c *gin.Context) {
 	var req ChatRequest
@@ -594,6 +759,7 @@ func (h *AgentHandler) AgentLoop(c *gin.Context) {
 		}
 	}
 	finalMessage = appendAttachmentsToMessage(finalMessage, req.Attachments, savedPaths)
+	finalMessage = h.applyMemoryContext(finalMessage, req.Target)
 
 	// 保存用户消息：有附件时一并保存附件名与路径，刷新后显示、继续对话时大模型也能从历史中拿到路径
 	userContent := userMessageContentForStorage(req.Message, req.Attachments, savedPaths)
@@ -610,6 +776,12 @@ func (h *AgentHandler) AgentLoop(c *gin.Context) {
 	defer timeoutCancel()
 	progressCallback := h.createProgressCallback(taskCtx, cancelWithCause, conversationID, "", nil)
 	taskCtx = h.injectReactHITLInterceptor(taskCtx, cancelWithCause, conversationID, "", nil)
+	taskCtx = agent.WithLanguage(taskCtx, req.Language)
+	if req.Role != "" && req.Role != "默认" && h.config.Roles != nil {
+		if role, exists := h.config.Roles[req.Role]; exists && role.Enabled && len(role.DefaultToolProfiles) > 0 {
+			taskCtx = agent.WithDefaultToolProfiles(taskCtx, role.DefaultToolProfiles)
+		}
+	}
 
 	// 执行Agent Loop，传入历史消息和对话ID（使用包含角色提示词的finalMessage和角色工具列表）
 	result, err := h.agent.AgentLoopWithProgress(taskCtx, finalMessage, agentHistoryMessages, conversationID, progressCallback, roleTools)
@@ -777,8 +949,8 @@ func (h *AgentHandler) ProcessMessageForRobot(ctx context.Context, conversationI
 			mcpIDsJSON = string(jsonData)
 		}
 		_, err = h.db.Exec(
-		"UPDATE messages SET content = ?, mcp_execution_ids = ?, updated_at = ? WHERE id = ?",
-		result.Response, mcpIDsJSON, time.Now(), assistantMessageID,
+			"UPDATE messages SET content = ?, mcp_execution_ids = ?, updated_at = ? WHERE id = ?",
+			result.Response, mcpIDsJSON, time.Now(), assistantMessageID,
 		)
 		if err != nil {
 			h.logger.Warn("机器人：更新助手消息失败", zap.Error(err))
@@ -794,6 +966,107 @@ func (h *AgentHandler) ProcessMessageForRobot(ctx context.Context, conversationI
 	return result.Response, conversationID, nil
 }
 
+// RegenerateMessage 处理 POST /api/conversations/:id/messages/:msgId/regenerate：以分支（而非覆盖历史）
+// 的方式重新生成对话最后一轮的 assistant 回复。落库部分见 database.DB.RegenerateMessage；本方法负责
+// 用截断到该轮之前的历史重新调用一次 agent，写法与 ProcessMessageForRobot 保持一致（同步非流式，
+// 通过 createProgressCallback(..., nil) 写过程详情但不发送 SSE），未复用 AgentLoopStream 的流式实现。
+func (h *AgentHandler) RegenerateMessage(c *gin.Context) {
+	conversationID := c.Param("id")
+	msgID := c.Param("msgId")
+
+	placeholder, userMessage, history, err := h.db.RegenerateMessage(conversationID, msgID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	agentHistoryMessages := make([]agent.ChatMessage, 0, len(history))
+	for _, msg := range history {
+		agentHistoryMessages = append(agentHistoryMessages, agent.ChatMessage{Role: msg.Role, Content: msg.Content})
+	}
+
+	ctx := c.Request.Context()
+	progressCallback := h.createProgressCallback(ctx, nil, conversationID, placeholder.ID, nil)
+
+	result, err := h.agent.AgentLoopWithProgress(ctx, userMessage.Content, agentHistoryMessages, conversationID, progressCallback, nil)
+	if err != nil {
+		errMsg := "执行失败: " + err.Error()
+		_, _ = h.db.Exec("UPDATE messages SET content = ?, updated_at = ? WHERE id = ?", errMsg, time.Now(), placeholder.ID)
+		_ = h.db.AddProcessDetail(placeholder.ID, conversationID, "error", errMsg, nil)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	mcpIDsJSON := ""
+	if len(result.MCPExecutionIDs) > 0 {
+		jsonData, _ := json.Marshal(result.MCPExecutionIDs)
+		mcpIDsJSON = string(jsonData)
+	}
+	if _, err := h.db.Exec(
+		"UPDATE messages SET content = ?, mcp_execution_ids = ?, updated_at = ? WHERE id = ?",
+		result.Response, mcpIDsJSON, time.Now(), placeholder.ID,
+	); err != nil {
+		h.logger.Warn("重新生成：更新助手消息失败", zap.Error(err))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":              placeholder.ID,
+		"parentMessageId": placeholder.ParentMessageID,
+		"content":         result.Response,
+		"mcpExecutionIds": result.MCPExecutionIDs,
+	})
+}
+
+// ListMessageBranches 处理 GET /api/conversations/:id/messages/:msgId/branches：列出 msgId 所在
+// parentMessageId 下的全部兄弟分支，供前端渲染「上一个/下一个回复」的切换控件。
+func (h *AgentHandler) ListMessageBranches(c *gin.Context) {
+	conversationID := c.Param("id")
+	msgID := c.Param("msgId")
+
+	messages, err := h.db.GetMessages(conversationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var parentMessageID string
+	found := false
+	for _, msg := range messages {
+		if msg.ID == msgID {
+			parentMessageID = msg.ParentMessageID
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+		return
+	}
+	if parentMessageID == "" {
+		c.JSON(http.StatusOK, gin.H{"branches": []interface{}{}})
+		return
+	}
+
+	branches, err := h.db.ListMessageBranches(parentMessageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"branches": branches})
+}
+
+// SwitchMessageBranch 处理 POST /api/conversations/:id/messages/:msgId/switch-branch：把 msgId 切换为
+// 其所在分支组的激活分支，纯粹的可见性切换，不重新调用 agent。
+func (h *AgentHandler) SwitchMessageBranch(c *gin.Context) {
+	conversationID := c.Param("id")
+	msgID := c.Param("msgId")
+
+	if err := h.db.SwitchMessageBranch(conversationID, msgID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已切换分支"})
+}
+
 // StreamEvent 流式事件
 type StreamEvent struct {
 	Type    string      `json:"type"`    // conversation, progress, tool_call, tool_result, response, error, cancelled, done
@@ -801,6 +1074,38 @@ type StreamEvent struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// broadcastTaskEvent 供操作台聚合看板（GET /api/agent-loop/tasks-feed）使用的事件信封，
+// 显式携带 conversationId：StreamEvent 本身只在部分事件类型的 Data 里放会话 ID，
+// 聚合多任务展示时需要统一、可靠地按会话归类。
+type broadcastTaskEvent struct {
+	ConversationID string      `json:"conversationId"`
+	Type           string      `json:"type"`
+	Message        string      `json:"message"`
+	Data           interface{} `json:"data,omitempty"`
+}
+
+// publishGlobalTaskEvent 把一次进度事件同时镜像到跨会话的全局订阅（操作台聚合看板），
+// 与按会话镜像的 TaskEventBus.Publish 相互独立，互不影响。
+func (h *AgentHandler) publishGlobalTaskEvent(conversationID, eventType, message string, data interface{}) {
+	if h.taskEventBus == nil || conversationID == "" {
+		return
+	}
+	b, err := json.Marshal(broadcastTaskEvent{
+		ConversationID: conversationID,
+		Type:           eventType,
+		Message:        message,
+		Data:           data,
+	})
+	if err != nil {
+		return
+	}
+	line := make([]byte, 0, len(b)+8)
+	line = append(line, []byte("data: ")...)
+	line = append(line, b...)
+	line = append(line, '\n', '\n')
+	h.taskEventBus.PublishGlobal(conversationID, line)
+}
+
 // createProgressCallback 创建进度回调函数，用于保存processDetails
 // sendEventFunc: 可选的流式事件发送函数，如果为nil则不发送流式事件
 func (h *AgentHandler) createProgressCallback(runCtx context.Context, cancelRun context.CancelCauseFunc, conversationID, assistantMessageID string, sendEventFunc func(eventType, message string, data interface{})) agent.ProgressCallback {
@@ -1082,6 +1387,14 @@ func (h *AgentHandler) createProgressCallback(runCtx context.Context, cancelRun
 			}
 		}
 
+		// 增量攻击链：每次工具执行成功后即时追加一个动作节点并广播 chain_updated，
+		// 不必等 ReAct 结束后由 BuildChainFromConversation 一次性回溯生成整条链。
+		if eventType == "tool_result" && h.db != nil {
+			if dataMap, ok := data.(map[string]interface{}); ok {
+				h.appendAttackChainToolNode(conversationID, dataMap, sendEventFunc)
+			}
+		}
+
 		// 子代理回复流式增量不落库；结束时合并为一条 eino_agent_reply
 		if assistantMessageID != "" && eventType == "eino_agent_reply_stream_end" {
 			flushResponsePlan()
@@ -1203,6 +1516,51 @@ func (h *AgentHandler) createProgressCallback(runCtx context.Context, cancelRun
 	}
 }
 
+// NotifyChainUpdated 实现 ChainNotifier，把攻击链增量更新广播给所有订阅该会话的 SSE 连接
+// （包括刷新后重新订阅的连接，见 taskEventBus.PublishGlobal）；供 LLMVulnerabilitySink 等
+// 运行于 HTTP 请求上下文之外的后台管线调用。
+func (h *AgentHandler) NotifyChainUpdated(conversationID string, node interface{}) {
+	h.publishGlobalTaskEvent(conversationID, "chain_updated", "", map[string]interface{}{
+		"conversationId": conversationID,
+		"node":           node,
+	})
+}
+
+// appendAttackChainToolNode 把一次成功的工具执行追加为攻击链动作节点，并广播 chain_updated 事件，
+// 供前端在运行过程中近实时刷新攻击链视图；失败或缺少工具名的执行不追加节点。
+func (h *AgentHandler) appendAttackChainToolNode(conversationID string, dataMap map[string]interface{}, sendEventFunc func(eventType, message string, data interface{})) {
+	toolName, _ := dataMap["toolName"].(string)
+	if toolName == "" {
+		return
+	}
+	success, ok := dataMap["success"].(bool)
+	if !ok {
+		if isError, okErr := dataMap["isError"].(bool); okErr {
+			success = !isError
+		} else {
+			success = true
+		}
+	}
+	if !success {
+		return
+	}
+
+	args, _ := dataMap["argumentsObj"].(map[string]interface{})
+	openAIConfig := &h.config.OpenAI
+	builder := attackchain.NewBuilder(h.db, openAIConfig, h.logger)
+	node, err := builder.AppendIncrementalNode(conversationID, "tool", toolName, args, 0)
+	if err != nil {
+		h.logger.Warn("增量追加攻击链节点失败", zap.String("tool", toolName), zap.Error(err))
+		return
+	}
+	if sendEventFunc != nil {
+		sendEventFunc("chain_updated", "", map[string]interface{}{
+			"conversationId": conversationID,
+			"node":           node,
+		})
+	}
+}
+
 // AgentLoopStream 处理Agent Loop流式请求
 func (h *AgentHandler) AgentLoopStream(c *gin.Context) {
 	var req ChatRequest
@@ -1229,6 +1587,10 @@ func (h *AgentHandler) AgentLoopStream(c *gin.Context) {
 		zap.String("conversationId", req.ConversationID),
 	)
 
+	if h.db != nil {
+		_ = h.db.RecordAudit("", "task_start", req.ConversationID, "", c.ClientIP())
+	}
+
 	// 设置SSE响应头
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
@@ -1303,6 +1665,7 @@ func (h *AgentHandler) AgentLoopStream(c *gin.Context) {
 		sseLine = append(sseLine, '\n', '\n')
 		if ssePublishConversationID != "" && h.taskEventBus != nil {
 			h.taskEventBus.Publish(ssePublishConversationID, sseLine)
+			h.publishGlobalTaskEvent(ssePublishConversationID, eventType, message, data)
 		}
 
 		// 如果客户端已断开，不再写入 HTTP（镜像订阅仍可收到事件）
@@ -1457,6 +1820,7 @@ func (h *AgentHandler) AgentLoopStream(c *gin.Context) {
 	}
 	// 仅将附件保存路径追加到 finalMessage，避免将文件内容内联到大模型上下文中
 	finalMessage = appendAttachmentsToMessage(finalMessage, req.Attachments, savedPaths)
+	finalMessage = h.applyMemoryContext(finalMessage, req.Target)
 	// 如果roleTools为空，表示使用所有工具（默认角色或未配置工具的角色）
 
 	// 保存用户消息：有附件时一并保存附件名与路径，刷新后显示、继续对话时大模型也能从历史中拿到路径
@@ -1497,8 +1861,38 @@ func (h *AgentHandler) AgentLoopStream(c *gin.Context) {
 	defer cancelWithCause(nil)
 	progressCallback := h.createProgressCallback(taskCtx, cancelWithCause, conversationID, assistantMessageID, sendEvent)
 	taskCtx = h.injectReactHITLInterceptor(taskCtx, cancelWithCause, conversationID, assistantMessageID, sendEvent)
+	taskCtx = agent.WithLanguage(taskCtx, req.Language)
+	if req.Role != "" && req.Role != "默认" && h.config.Roles != nil {
+		if role, exists := h.config.Roles[req.Role]; exists && role.Enabled && len(role.DefaultToolProfiles) > 0 {
+			taskCtx = agent.WithDefaultToolProfiles(taskCtx, role.DefaultToolProfiles)
+		}
+	}
 
-	if _, err := h.tasks.StartTask(conversationID, req.Message, cancelWithCause); err != nil {
+	_, err = h.tasks.StartTask(conversationID, req.Message, cancelWithCause)
+	if err != nil && errors.Is(err, ErrTaskAlreadyRunning) && req.QueueIfBusy {
+		// opt-in 队列模式：不直接失败，排队等待当前任务结束后自动交接执行（见 AgentTaskManager.EnqueueTask）
+		startedTask, qt := h.tasks.EnqueueTask(conversationID, req.Message, cancelWithCause)
+		switch {
+		case startedTask != nil:
+			// 排队时槽位恰好释放，EnqueueTask 已直接为我们启动
+			err = nil
+		case qt != nil:
+			position := h.tasks.QueuePosition(qt)
+			sendEvent("queued", fmt.Sprintf("⏳ 当前会话有任务正在执行，本次消息已排队（第 %d 位），将在当前任务结束后自动开始。", position), map[string]interface{}{
+				"conversationId": conversationID,
+				"queuePosition":  position,
+			})
+			select {
+			case <-qt.Ready():
+				h.tasks.AttachCancel(conversationID, cancelWithCause)
+				err = nil
+			case <-taskCtx.Done():
+				h.tasks.CancelQueuedTask(qt)
+				err = context.Cause(taskCtx)
+			}
+		}
+	}
+	if err != nil {
 		var errorMsg string
 		if errors.Is(err, ErrTaskAlreadyRunning) {
 			errorMsg = "⚠️ 当前会话已有任务正在执行中，请等待当前任务完成或点击「停止任务」按钮后再尝试。"
@@ -1659,6 +2053,11 @@ func (h *AgentHandler) AgentLoopStream(c *gin.Context) {
 				}
 			}
 
+			h.webhookTrigger.TriggerAsync("task_failed", "", map[string]interface{}{
+				"conversationId": conversationID,
+				"error":          errorMsg,
+			})
+
 			sendEvent("error", errorMsg, map[string]interface{}{
 				"conversationId": conversationID,
 				"messageId":      assistantMessageID,
@@ -1704,6 +2103,14 @@ func (h *AgentHandler) AgentLoopStream(c *gin.Context) {
 		}
 	}
 
+	// 会话运行完成，若已配置则自动推送本次新增的漏洞到 DefectDojo
+	h.defectDojoTrigger.TriggerAsync(h.db, conversationID)
+
+	// 会话运行完成，若已配置 webhook 则投递 task_completed 事件
+	h.webhookTrigger.TriggerAsync("task_completed", "", map[string]interface{}{
+		"conversationId": conversationID,
+	})
+
 	// 发送最终响应
 	sendEvent("response", result.Response, map[string]interface{}{
 		"mcpExecutionIds": result.MCPExecutionIDs,
@@ -1756,16 +2163,29 @@ func (h *AgentHandler) CancelAgentLoop(c *gin.Context) {
 		return
 	}
 
+	if h.db != nil {
+		_ = h.db.RecordAudit("", "task_cancel", req.ConversationID, req.Reason, c.ClientIP())
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"status":           "cancelling",
-		"conversationId":   req.ConversationID,
-		"message":          msg,
-		"continueAfter":    req.ContinueAfter,
+		"status":            "cancelling",
+		"conversationId":    req.ConversationID,
+		"message":           msg,
+		"continueAfter":     req.ContinueAfter,
 		"interruptWithNote": req.ContinueAfter,
 	})
 }
 
+// CancelAllAgentTasks 取消所有正在运行的Agent任务，供 EmergencyStopHandler 全局紧急停止时调用；
+// 返回被取消的会话ID列表。
+func (h *AgentHandler) CancelAllAgentTasks() []string {
+	return h.tasks.CancelAllTasks(ErrTaskCancelled)
+}
+
 // SubscribeAgentTaskEvents GET SSE：订阅指定会话当前运行中任务的事件镜像（帧格式与 POST .../stream 一致），用于刷新页面或断线后接续 UI。
+// 支持标准 SSE 断线重连协议：浏览器重连时会自动带上 Last-Event-ID 请求头（也可用 ?lastEventId= 兼容非
+// EventSource 客户端），据此从事件缓冲区（见 TaskEventBus.SubscribeWithReplay）补发错过的事件后再接上实时流；
+// 若错过的部分已超出缓冲窗口，先发一条 replay_gap 事件提示客户端改为拉取 REST 接口的过程详情兜底。
 func (h *AgentHandler) SubscribeAgentTaskEvents(c *gin.Context) {
 	conversationID := strings.TrimSpace(c.Query("conversationId"))
 	if conversationID == "" {
@@ -1781,14 +2201,79 @@ func (h *AgentHandler) SubscribeAgentTaskEvents(c *gin.Context) {
 		return
 	}
 
+	var lastEventID uint64
+	if raw := strings.TrimSpace(c.GetHeader("Last-Event-ID")); raw != "" {
+		lastEventID, _ = strconv.ParseUint(raw, 10, 64)
+	} else if raw := strings.TrimSpace(c.Query("lastEventId")); raw != "" {
+		lastEventID, _ = strconv.ParseUint(raw, 10, 64)
+	}
+
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
 	c.Header("X-Accel-Buffering", "no")
 
-	sub, ch := h.taskEventBus.Subscribe(conversationID)
+	sub, ch, replay, replayOK := h.taskEventBus.SubscribeWithReplay(conversationID, lastEventID)
 	defer h.taskEventBus.Unsubscribe(conversationID, sub)
 
+	flusher, _ := c.Writer.(http.Flusher)
+
+	if lastEventID > 0 && !replayOK {
+		if _, err := c.Writer.Write([]byte("data: {\"type\":\"replay_gap\",\"message\":\"部分事件已超出重放缓冲区，请重新拉取该会话的过程详情\"}\n\n")); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	for _, chunk := range replay {
+		if _, err := c.Writer.Write(chunk); err != nil {
+			return
+		}
+	}
+	if len(replay) > 0 && flusher != nil {
+		flusher.Flush()
+	}
+
+	ctx := c.Request.Context()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case chunk, ok := <-ch:
+			if !ok {
+				return
+			}
+			if _, err := c.Writer.Write(chunk); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// TasksFeed GET SSE：聚合当前实例所有运行中任务的进度/思考事件，供操作台一次性观察多个自动化任务的执行情况，
+// 无需逐个打开会话。可选 query 参数 conversationId（可重复，如 ?conversationId=a&conversationId=b）按任务过滤；
+// 不传则接收全部运行中任务的事件。事件帧格式为 broadcastTaskEvent（比会话内 SSE 多一个顶层 conversationId 字段）。
+func (h *AgentHandler) TasksFeed(c *gin.Context) {
+	if h.taskEventBus == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "task event bus unavailable"})
+		return
+	}
+
+	conversationIDs := c.QueryArray("conversationId")
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	sub, ch := h.taskEventBus.SubscribeGlobal(conversationIDs)
+	defer h.taskEventBus.UnsubscribeGlobal(sub)
+
 	flusher, _ := c.Writer.(http.Flusher)
 	ctx := c.Request.Context()
 
@@ -1810,10 +2295,31 @@ func (h *AgentHandler) SubscribeAgentTaskEvents(c *gin.Context) {
 	}
 }
 
-// ListAgentTasks 列出所有运行中的任务
+// queuedTaskView 是 QueuedTask 附带排队位置的对外展示形式（见 AgentTaskManager.EnqueueTask）
+type queuedTaskView struct {
+	ID             string    `json:"id"`
+	ConversationID string    `json:"conversationId"`
+	Message        string    `json:"message,omitempty"`
+	EnqueuedAt     time.Time `json:"enqueuedAt"`
+	QueuePosition  int       `json:"queuePosition"`
+}
+
+// ListAgentTasks 列出所有运行中的任务，以及 opt-in 队列模式下等待中的消息（含排队位置）
 func (h *AgentHandler) ListAgentTasks(c *gin.Context) {
+	queued := h.tasks.GetAllQueuedTasks()
+	queuedViews := make([]queuedTaskView, 0, len(queued))
+	for _, qt := range queued {
+		queuedViews = append(queuedViews, queuedTaskView{
+			ID:             qt.ID,
+			ConversationID: qt.ConversationID,
+			Message:        qt.Message,
+			EnqueuedAt:     qt.EnqueuedAt,
+			QueuePosition:  h.tasks.QueuePosition(qt),
+		})
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"tasks": h.tasks.GetActiveTasks(),
+		"tasks":       h.tasks.GetActiveTasks(),
+		"queuedTasks": queuedViews,
 	})
 }
 
@@ -2029,6 +2535,48 @@ func (h *AgentHandler) ListBatchQueues(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// ListSchedulesResponse /api/schedules 列表响应
+type ListSchedulesResponse struct {
+	Schedules []*BatchTaskQueue `json:"schedules"`
+	Total     int               `json:"total"`
+}
+
+// ListSchedules 列出所有已配置 cron 调度的批量任务队列（即"定时任务"，见 wivd/CyberStrikeAI#synth-3097）；
+// 复用批量任务队列作为底层存储，仅按 scheduleMode=="cron" 过滤展示，与手工批量任务共用同一套执行引擎。
+func (h *AgentHandler) ListSchedules(c *gin.Context) {
+	const maxScanned = 500
+	queues, _, err := h.batchTaskManager.ListQueues(maxScanned, 0, "all", "")
+	if err != nil {
+		h.logger.Error("获取定时任务列表失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	schedules := make([]*BatchTaskQueue, 0, len(queues))
+	for _, q := range queues {
+		if q != nil && q.ScheduleMode == "cron" {
+			schedules = append(schedules, q)
+		}
+	}
+	c.JSON(http.StatusOK, ListSchedulesResponse{Schedules: schedules, Total: len(schedules)})
+}
+
+// GetScheduleHistory 查询指定定时任务的历次 cron 触发记录（见 database.ListScheduleRuns）。
+func (h *AgentHandler) GetScheduleHistory(c *gin.Context) {
+	queueID := c.Param("queueId")
+	if _, exists := h.batchTaskManager.GetBatchQueue(queueID); !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "定时任务不存在"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	records, err := h.db.ListScheduleRuns(queueID, limit)
+	if err != nil {
+		h.logger.Error("查询定时任务触发历史失败", zap.String("queueId", queueID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"history": records})
+}
+
 // StartBatchQueue 开始执行批量任务队列
 func (h *AgentHandler) StartBatchQueue(c *gin.Context) {
 	queueID := c.Param("queueId")
@@ -2349,7 +2897,7 @@ func (h *AgentHandler) startBatchQueueExecution(queueID string, scheduled bool)
 		}
 	}
 
-	go h.executeBatchQueue(queueID)
+	go h.executeBatchQueue(queueID, scheduled)
 	return true, nil
 }
 
@@ -2382,10 +2930,12 @@ func (h *AgentHandler) batchQueueSchedulerLoop() {
 	}
 }
 
-// executeBatchQueue 执行批量任务队列
-func (h *AgentHandler) executeBatchQueue(queueID string) {
+// executeBatchQueue 执行批量任务队列；scheduled 为 true 表示本次由 cron 触发，用于决定完成后
+// 是否写入 schedule_run_history（见 database.RecordScheduleRun），手工启动/重跑不记录触发历史。
+func (h *AgentHandler) executeBatchQueue(queueID string, scheduled bool) {
 	defer h.unmarkBatchQueueRunning(queueID)
 	h.logger.Info("开始执行批量任务队列", zap.String("queueId", queueID))
+	startedAt := time.Now()
 
 	for {
 		// 检查队列状态
@@ -2400,16 +2950,30 @@ func (h *AgentHandler) executeBatchQueue(queueID string) {
 			// 所有任务完成：汇总子任务失败信息便于排障
 			q, ok := h.batchTaskManager.GetBatchQueue(queueID)
 			lastRunErr := ""
+			conversationIDs := make([]string, 0)
 			if ok {
 				for _, t := range q.Tasks {
 					if t.Status == "failed" && t.Error != "" {
 						lastRunErr = t.Error
 					}
+					if t.ConversationID != "" {
+						conversationIDs = append(conversationIDs, t.ConversationID)
+					}
 				}
 			}
 			h.batchTaskManager.SetLastRunError(queueID, lastRunErr)
 			h.batchTaskManager.UpdateQueueStatus(queueID, "completed")
 			h.logger.Info("批量任务队列执行完成", zap.String("queueId", queueID))
+			if scheduled {
+				status := "completed"
+				if lastRunErr != "" {
+					status = "failed"
+				}
+				idsJSON, _ := json.Marshal(conversationIDs)
+				if err := h.db.RecordScheduleRun(queueID, string(idsJSON), status, lastRunErr, startedAt, time.Now()); err != nil {
+					h.logger.Warn("写入定时任务触发历史失败", zap.String("queueId", queueID), zap.Error(err))
+				}
+			}
 			break
 		}
 
@@ -2497,6 +3061,7 @@ func (h *AgentHandler) executeBatchQueue(queueID string) {
 						if b, err := json.Marshal(ev); err == nil {
 							h.taskEventBus.Publish(conversationID, append(append([]byte("data: "), b...), '\n', '\n'))
 						}
+						h.publishGlobalTaskEvent(conversationID, "done", "", map[string]interface{}{"conversationId": conversationID})
 					}
 					h.tasks.FinishTask(conversationID, finishStatus)
 				}
@@ -2518,6 +3083,7 @@ func (h *AgentHandler) executeBatchQueue(queueID string) {
 				line = append(line, b...)
 				line = append(line, '\n', '\n')
 				h.taskEventBus.Publish(conversationID, line)
+				h.publishGlobalTaskEvent(conversationID, eventType, message, data)
 			}
 
 			if _, err := h.tasks.StartTask(conversationID, task.Message, cancelWithCause); err != nil {
@@ -2608,110 +3174,110 @@ func (h *AgentHandler) executeBatchQueue(queueID string) {
 				}
 
 				if isCancelled {
-				h.logger.Info("批量任务被取消", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.String("conversationId", conversationID))
-				cancelMsg := "任务已被用户取消，后续操作已停止。"
-				// 如果执行结果中有更具体的取消消息，使用它
-				if partialResp != "" && (strings.Contains(partialResp, "任务已被取消") || strings.Contains(partialResp, "任务执行中断")) {
-					cancelMsg = partialResp
-				}
-				// 更新助手消息内容
-				if assistantMessageID != "" {
-					if _, updateErr := h.db.Exec(
-						"UPDATE messages SET content = ?, updated_at = ? WHERE id = ?",
-						cancelMsg,
-						time.Now(), assistantMessageID,
-					); updateErr != nil {
-						h.logger.Warn("更新取消后的助手消息失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.Error(updateErr))
+					h.logger.Info("批量任务被取消", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.String("conversationId", conversationID))
+					cancelMsg := "任务已被用户取消，后续操作已停止。"
+					// 如果执行结果中有更具体的取消消息，使用它
+					if partialResp != "" && (strings.Contains(partialResp, "任务已被取消") || strings.Contains(partialResp, "任务执行中断")) {
+						cancelMsg = partialResp
 					}
-					// 保存取消详情到数据库
-					if err := h.db.AddProcessDetail(assistantMessageID, conversationID, "cancelled", cancelMsg, nil); err != nil {
-						h.logger.Warn("保存取消详情失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.Error(err))
+					// 更新助手消息内容
+					if assistantMessageID != "" {
+						if _, updateErr := h.db.Exec(
+							"UPDATE messages SET content = ?, updated_at = ? WHERE id = ?",
+							cancelMsg,
+							time.Now(), assistantMessageID,
+						); updateErr != nil {
+							h.logger.Warn("更新取消后的助手消息失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.Error(updateErr))
+						}
+						// 保存取消详情到数据库
+						if err := h.db.AddProcessDetail(assistantMessageID, conversationID, "cancelled", cancelMsg, nil); err != nil {
+							h.logger.Warn("保存取消详情失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.Error(err))
+						}
+					} else {
+						// 如果没有预先创建的助手消息，创建一个新的
+						_, errMsg := h.db.AddMessage(conversationID, "assistant", cancelMsg, nil)
+						if errMsg != nil {
+							h.logger.Warn("保存取消消息失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.Error(errMsg))
+						}
 					}
+					h.batchTaskManager.UpdateTaskStatusWithConversationID(queueID, task.ID, "cancelled", cancelMsg, "", conversationID)
 				} else {
-					// 如果没有预先创建的助手消息，创建一个新的
-					_, errMsg := h.db.AddMessage(conversationID, "assistant", cancelMsg, nil)
-					if errMsg != nil {
-						h.logger.Warn("保存取消消息失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.Error(errMsg))
+					h.logger.Error("批量任务执行失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.String("conversationId", conversationID), zap.Error(runErr))
+					errorMsg := "执行失败: " + runErr.Error()
+					// 更新助手消息内容
+					if assistantMessageID != "" {
+						if _, updateErr := h.db.Exec(
+							"UPDATE messages SET content = ?, updated_at = ? WHERE id = ?",
+							errorMsg,
+							time.Now(), assistantMessageID,
+						); updateErr != nil {
+							h.logger.Warn("更新失败后的助手消息失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.Error(updateErr))
+						}
+						// 保存错误详情到数据库
+						if err := h.db.AddProcessDetail(assistantMessageID, conversationID, "error", errorMsg, nil); err != nil {
+							h.logger.Warn("保存错误详情失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.Error(err))
+						}
 					}
+					h.batchTaskManager.UpdateTaskStatus(queueID, task.ID, "failed", "", runErr.Error())
 				}
-				h.batchTaskManager.UpdateTaskStatusWithConversationID(queueID, task.ID, "cancelled", cancelMsg, "", conversationID)
 			} else {
-				h.logger.Error("批量任务执行失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.String("conversationId", conversationID), zap.Error(runErr))
-				errorMsg := "执行失败: " + runErr.Error()
+				h.logger.Info("批量任务执行成功", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.String("conversationId", conversationID))
+
+				var resText string
+				var mcpIDs []string
+				var lastIn, lastOut string
+				if useRunResult {
+					resText = resultMA.Response
+					mcpIDs = resultMA.MCPExecutionIDs
+					lastIn = resultMA.LastAgentTraceInput
+					lastOut = resultMA.LastAgentTraceOutput
+				} else {
+					resText = result.Response
+					mcpIDs = result.MCPExecutionIDs
+					lastIn = result.LastAgentTraceInput
+					lastOut = result.LastAgentTraceOutput
+				}
+
 				// 更新助手消息内容
 				if assistantMessageID != "" {
+					mcpIDsJSON := ""
+					if len(mcpIDs) > 0 {
+						jsonData, _ := json.Marshal(mcpIDs)
+						mcpIDsJSON = string(jsonData)
+					}
 					if _, updateErr := h.db.Exec(
-						"UPDATE messages SET content = ?, updated_at = ? WHERE id = ?",
-						errorMsg,
+						"UPDATE messages SET content = ?, mcp_execution_ids = ?, updated_at = ? WHERE id = ?",
+						resText,
+						mcpIDsJSON,
 						time.Now(), assistantMessageID,
 					); updateErr != nil {
-						h.logger.Warn("更新失败后的助手消息失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.Error(updateErr))
-					}
-					// 保存错误详情到数据库
-					if err := h.db.AddProcessDetail(assistantMessageID, conversationID, "error", errorMsg, nil); err != nil {
-						h.logger.Warn("保存错误详情失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.Error(err))
+						h.logger.Warn("更新助手消息失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.Error(updateErr))
+						// 如果更新失败，尝试创建新消息
+						_, err = h.db.AddMessage(conversationID, "assistant", resText, mcpIDs)
+						if err != nil {
+							h.logger.Error("保存助手消息失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.String("conversationId", conversationID), zap.Error(err))
+						}
 					}
-				}
-				h.batchTaskManager.UpdateTaskStatus(queueID, task.ID, "failed", "", runErr.Error())
-			}
-		} else {
-			h.logger.Info("批量任务执行成功", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.String("conversationId", conversationID))
-
-			var resText string
-			var mcpIDs []string
-			var lastIn, lastOut string
-			if useRunResult {
-				resText = resultMA.Response
-				mcpIDs = resultMA.MCPExecutionIDs
-				lastIn = resultMA.LastAgentTraceInput
-				lastOut = resultMA.LastAgentTraceOutput
-			} else {
-				resText = result.Response
-				mcpIDs = result.MCPExecutionIDs
-				lastIn = result.LastAgentTraceInput
-				lastOut = result.LastAgentTraceOutput
-			}
-
-			// 更新助手消息内容
-			if assistantMessageID != "" {
-				mcpIDsJSON := ""
-				if len(mcpIDs) > 0 {
-					jsonData, _ := json.Marshal(mcpIDs)
-					mcpIDsJSON = string(jsonData)
-				}
-				if _, updateErr := h.db.Exec(
-					"UPDATE messages SET content = ?, mcp_execution_ids = ?, updated_at = ? WHERE id = ?",
-					resText,
-					mcpIDsJSON,
-					time.Now(), assistantMessageID,
-				); updateErr != nil {
-					h.logger.Warn("更新助手消息失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.Error(updateErr))
-					// 如果更新失败，尝试创建新消息
+				} else {
+					// 如果没有预先创建的助手消息，创建一个新的
 					_, err = h.db.AddMessage(conversationID, "assistant", resText, mcpIDs)
 					if err != nil {
 						h.logger.Error("保存助手消息失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.String("conversationId", conversationID), zap.Error(err))
 					}
 				}
-			} else {
-				// 如果没有预先创建的助手消息，创建一个新的
-				_, err = h.db.AddMessage(conversationID, "assistant", resText, mcpIDs)
-				if err != nil {
-					h.logger.Error("保存助手消息失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.String("conversationId", conversationID), zap.Error(err))
-				}
-			}
 
-			// 保存代理轨迹
-			if lastIn != "" || lastOut != "" {
-				if err := h.db.SaveAgentTrace(conversationID, lastIn, lastOut); err != nil {
-					h.logger.Warn("保存代理轨迹失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.Error(err))
-				} else {
-					h.logger.Info("已保存代理轨迹", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.String("conversationId", conversationID))
+				// 保存代理轨迹
+				if lastIn != "" || lastOut != "" {
+					if err := h.db.SaveAgentTrace(conversationID, lastIn, lastOut); err != nil {
+						h.logger.Warn("保存代理轨迹失败", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.Error(err))
+					} else {
+						h.logger.Info("已保存代理轨迹", zap.String("queueId", queueID), zap.String("taskId", task.ID), zap.String("conversationId", conversationID))
+					}
 				}
-			}
 
-			// 保存结果
-			h.batchTaskManager.UpdateTaskStatusWithConversationID(queueID, task.ID, "completed", resText, "", conversationID)
-		}
+				// 保存结果
+				h.batchTaskManager.UpdateTaskStatusWithConversationID(queueID, task.ID, "completed", resText, "", conversationID)
+			}
 		}()
 
 		// 移动到下一个任务
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// agentWSUpgrader 用于 /api/agent-loop/ws；认证已在上层路由组的 JWT 中间件完成，这里放宽 Origin。
+var agentWSUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// agentWSFrame 是 WebSocket 通道上客户端 -> 服务端的统一信封。服务端 -> 客户端方向不做二次包装，
+// 直接转发 AgentLoopStream 产生的原始 SSE 帧（"data: {...}\n\n"），前端沿用已有的 SSE JSON 解析逻辑即可。
+type agentWSFrame struct {
+	Type string `json:"type"` // message | cancel | interrupt_continue | hitl_decision
+
+	// type=message：字段含义与 POST /api/agent-loop/stream 的请求体一致
+	ConversationID string           `json:"conversationId,omitempty"`
+	Message        string           `json:"message,omitempty"`
+	Role           string           `json:"role,omitempty"`
+	Attachments    []ChatAttachment `json:"attachments,omitempty"`
+	Orchestration  string           `json:"orchestration,omitempty"`
+	Language       string           `json:"language,omitempty"`
+	Target         string           `json:"target,omitempty"`
+	QueueIfBusy    bool             `json:"queueIfBusy,omitempty"`
+
+	// type=cancel / interrupt_continue
+	Reason string `json:"reason,omitempty"`
+
+	// type=hitl_decision，字段含义与 POST /api/hitl/decision 一致
+	InterruptID     string                 `json:"interruptId,omitempty"`
+	Decision        string                 `json:"decision,omitempty"`
+	Comment         string                 `json:"comment,omitempty"`
+	EditedArguments map[string]interface{} `json:"editedArguments,omitempty"`
+}
+
+// wsResponseWriter 把 http.ResponseWriter 的写入桥接为 WebSocket 文本帧，使已有的
+// AgentLoopStream（本为 SSE 设计）可以原样复用：每次 c.Writer.Write(sseLine) 都会被转发为一帧，
+// 客户端收到的内容与走 SSE 时完全一致，只是换成不会被反向代理缓冲/截断的 WebSocket 传输。
+type wsResponseWriter struct {
+	conn   *websocket.Conn
+	connMu *sync.Mutex
+	header http.Header
+	status int
+}
+
+func newWSResponseWriter(conn *websocket.Conn, connMu *sync.Mutex) *wsResponseWriter {
+	return &wsResponseWriter{conn: conn, connMu: connMu, header: make(http.Header), status: http.StatusOK}
+}
+
+func (w *wsResponseWriter) Header() http.Header { return w.header }
+
+func (w *wsResponseWriter) Write(b []byte) (int, error) {
+	w.connMu.Lock()
+	defer w.connMu.Unlock()
+	if err := w.conn.WriteMessage(websocket.TextMessage, append([]byte(nil), b...)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *wsResponseWriter) WriteHeader(statusCode int) { w.status = statusCode }
+
+// Flush 实现 http.Flusher；WriteMessage 已是逐帧即时发送，无需额外操作。
+func (w *wsResponseWriter) Flush() {}
+
+// writeWSJSON 在 connMu 保护下向 WebSocket 写入一条控制类 JSON 消息（cancel/hitl_decision 的执行结果等），
+// 与 AgentLoopStream 通过 wsResponseWriter 写入的事件帧共用同一把锁，避免并发写破坏 WebSocket 帧边界。
+func writeWSJSON(conn *websocket.Conn, connMu *sync.Mutex, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	connMu.Lock()
+	defer connMu.Unlock()
+	return conn.WriteMessage(websocket.TextMessage, b)
+}
+
+// AgentLoopWS 是 /api/agent-loop 的 WebSocket 版本：单个长连接上既能收到与 SSE 完全一致的进度事件，
+// 也能双向发送控制帧（跟进消息、取消任务、审批工具调用），无需为每种操作单独发起 HTTP 请求，
+// 对经过缓冲 SSE 的反向代理更友好。帧格式见 agentWSFrame；服务端下行事件为原始 SSE 行，
+// 复用 AgentLoopStream 本身以避免与其中的附件保存、角色工具、进度回调等逻辑产生两套实现。
+func (h *AgentHandler) AgentLoopWS(c *gin.Context) {
+	conn, err := agentWSUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var connMu sync.Mutex
+	remoteAddr := c.Request.RemoteAddr
+
+	for {
+		var frame agentWSFrame
+		if err := conn.ReadJSON(&frame); err != nil {
+			return
+		}
+
+		switch frame.Type {
+		case "message":
+			if frame.ConversationID == "" || frame.Message == "" {
+				_ = writeWSJSON(conn, &connMu, gin.H{"type": "error", "message": "conversationId 和 message 不能为空"})
+				continue
+			}
+			go h.handleAgentWSMessage(conn, &connMu, remoteAddr, frame)
+
+		case "cancel":
+			ok, cancelErr := h.tasks.CancelTask(frame.ConversationID, ErrTaskCancelled)
+			if cancelErr != nil {
+				_ = writeWSJSON(conn, &connMu, gin.H{"type": "error", "message": cancelErr.Error()})
+				continue
+			}
+			_ = writeWSJSON(conn, &connMu, gin.H{"type": "cancel_result", "data": gin.H{"conversationId": frame.ConversationID, "ok": ok}})
+
+		case "interrupt_continue":
+			if !h.tasks.SetInterruptContinueReason(frame.ConversationID, frame.Reason) {
+				_ = writeWSJSON(conn, &connMu, gin.H{"type": "error", "message": "未找到正在执行的任务，无法提交中断说明"})
+				continue
+			}
+			ok, cancelErr := h.tasks.CancelTask(frame.ConversationID, ErrUserInterruptContinue)
+			if cancelErr != nil {
+				_ = writeWSJSON(conn, &connMu, gin.H{"type": "error", "message": cancelErr.Error()})
+				continue
+			}
+			_ = writeWSJSON(conn, &connMu, gin.H{"type": "cancel_result", "data": gin.H{"conversationId": frame.ConversationID, "ok": ok}})
+
+		case "hitl_decision":
+			if h.hitlManager == nil {
+				_ = writeWSJSON(conn, &connMu, gin.H{"type": "error", "message": "hitl manager unavailable"})
+				continue
+			}
+			if err := h.hitlManager.ResolveInterrupt(frame.InterruptID, frame.Decision, frame.Comment, frame.EditedArguments); err != nil {
+				_ = writeWSJSON(conn, &connMu, gin.H{"type": "error", "message": err.Error()})
+				continue
+			}
+			_ = writeWSJSON(conn, &connMu, gin.H{"type": "hitl_decision_result", "data": gin.H{"interruptId": frame.InterruptID, "ok": true}})
+
+		default:
+			_ = writeWSJSON(conn, &connMu, gin.H{"type": "error", "message": "unknown frame type: " + frame.Type})
+		}
+	}
+}
+
+// handleAgentWSMessage 把一条 WebSocket "message" 帧桥接为对 AgentLoopStream 的一次调用：
+// 用 gin.CreateTestContext 构造一个独立的 *gin.Context，其 ResponseWriter 换成 wsResponseWriter，
+// 这样 AgentLoopStream 内部原有的 SSE 写入、断线检测（写失败即判定为断开）都无需改动即可直接复用。
+func (h *AgentHandler) handleAgentWSMessage(conn *websocket.Conn, connMu *sync.Mutex, remoteAddr string, frame agentWSFrame) {
+	body, err := json.Marshal(ChatRequest{
+		Message:        frame.Message,
+		ConversationID: frame.ConversationID,
+		Role:           frame.Role,
+		Attachments:    frame.Attachments,
+		Orchestration:  frame.Orchestration,
+		Language:       frame.Language,
+		Target:         frame.Target,
+		QueueIfBusy:    frame.QueueIfBusy,
+	})
+	if err != nil {
+		_ = writeWSJSON(conn, connMu, gin.H{"type": "error", "message": err.Error()})
+		return
+	}
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/agent-loop/stream", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.RemoteAddr = remoteAddr
+	// 独立的可取消 context：WebSocket 断开时停止本次桥接请求的 keepalive/断连检测；
+	// 任务本身仍使用 AgentLoopStream 内部创建的、不随此 context 取消的独立执行上下文。
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	httpReq = httpReq.WithContext(ctx)
+
+	ginCtx, _ := gin.CreateTestContext(newWSResponseWriter(conn, connMu))
+	ginCtx.Request = httpReq
+
+	h.AgentLoopStream(ginCtx)
+}
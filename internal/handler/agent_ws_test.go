@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWSResponseWriter_ForwardsWritesAsTextFrames(t *testing.T) {
+	var serverConn *websocket.Conn
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("升级 WebSocket 失败: %v", err)
+			return
+		}
+		serverConn = conn
+
+		var connMu sync.Mutex
+		wsw := newWSResponseWriter(conn, &connMu)
+		if _, err := wsw.Write([]byte("data: {\"type\":\"progress\"}\n\n")); err != nil {
+			t.Errorf("写入 wsResponseWriter 失败: %v", err)
+		}
+		if err := writeWSJSON(conn, &connMu, map[string]string{"type": "done"}); err != nil {
+			t.Errorf("writeWSJSON 失败: %v", err)
+		}
+	}))
+	defer srv.Close()
+	defer func() {
+		if serverConn != nil {
+			serverConn.Close()
+		}
+	}()
+
+	wsURL := "ws" + srv.URL[len("http"):]
+	clientConn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("建立 WebSocket 连接失败: %v", err)
+	}
+	defer clientConn.Close()
+
+	_ = clientConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, first, err := clientConn.ReadMessage()
+	if err != nil {
+		t.Fatalf("读取第一条消息失败: %v", err)
+	}
+	if string(first) != "data: {\"type\":\"progress\"}\n\n" {
+		t.Fatalf("期望原样转发 SSE 帧，实际: %q", first)
+	}
+
+	_, second, err := clientConn.ReadMessage()
+	if err != nil {
+		t.Fatalf("读取第二条消息失败: %v", err)
+	}
+	if string(second) != `{"type":"done"}` {
+		t.Fatalf("期望 writeWSJSON 输出的 JSON 帧，实际: %q", second)
+	}
+}
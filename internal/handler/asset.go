@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"net/http"
+
+	"cyberstrike-ai/internal/database"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AssetHandler 资产台账处理器：主机/域名/URL/服务的 CRUD 及搜索
+type AssetHandler struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+// NewAssetHandler 创建新的资产处理器
+func NewAssetHandler(db *database.DB, logger *zap.Logger) *AssetHandler {
+	return &AssetHandler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateAssetRequest 创建资产请求
+type CreateAssetRequest struct {
+	Type           string `json:"type"`
+	Value          string `json:"value"`
+	Host           string `json:"host"`
+	Port           string `json:"port"`
+	Service        string `json:"service"`
+	Source         string `json:"source"`
+	ConversationID string `json:"conversationId"`
+	Detail         string `json:"detail"`
+}
+
+// CreateAsset 创建资产
+func (h *AssetHandler) CreateAsset(c *gin.Context) {
+	var req CreateAssetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Type == "" || req.Value == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "资产类型和标识不能为空"})
+		return
+	}
+
+	asset, err := h.db.CreateAsset(req.Type, req.Value, req.Host, req.Port, req.Service, req.Source, req.ConversationID, req.Detail)
+	if err != nil {
+		h.logger.Error("创建资产失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, asset)
+}
+
+// ListAssets 列出资产，支持按 type/source 过滤
+// GET /api/assets?type=host&source=nmap
+func (h *AssetHandler) ListAssets(c *gin.Context) {
+	assets, err := h.db.ListAssets(c.Query("type"), c.Query("source"))
+	if err != nil {
+		h.logger.Error("获取资产列表失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, assets)
+}
+
+// SearchAssets 按关键词搜索资产（主机/值/服务/备注模糊匹配）
+// GET /api/assets/search?q=10.0.0
+func (h *AssetHandler) SearchAssets(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "搜索关键词不能为空"})
+		return
+	}
+
+	assets, err := h.db.SearchAssets(query)
+	if err != nil {
+		h.logger.Error("搜索资产失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, assets)
+}
+
+// GetAsset 获取资产
+func (h *AssetHandler) GetAsset(c *gin.Context) {
+	id := c.Param("id")
+
+	asset, err := h.db.GetAsset(id)
+	if err != nil {
+		h.logger.Error("获取资产失败", zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "资产不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, asset)
+}
+
+// UpdateAssetRequest 更新资产请求
+type UpdateAssetRequest struct {
+	Type    string `json:"type"`
+	Value   string `json:"value"`
+	Host    string `json:"host"`
+	Port    string `json:"port"`
+	Service string `json:"service"`
+	Detail  string `json:"detail"`
+}
+
+// UpdateAsset 更新资产
+func (h *AssetHandler) UpdateAsset(c *gin.Context) {
+	id := c.Param("id")
+
+	var req UpdateAssetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Type == "" || req.Value == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "资产类型和标识不能为空"})
+		return
+	}
+
+	if err := h.db.UpdateAsset(id, req.Type, req.Value, req.Host, req.Port, req.Service, req.Detail); err != nil {
+		h.logger.Error("更新资产失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	asset, err := h.db.GetAsset(id)
+	if err != nil {
+		h.logger.Error("获取更新后的资产失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, asset)
+}
+
+// DeleteAsset 删除资产
+func (h *AssetHandler) DeleteAsset(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.db.DeleteAsset(id); err != nil {
+		h.logger.Error("删除资产失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
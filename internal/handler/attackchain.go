@@ -2,6 +2,8 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
@@ -11,6 +13,7 @@ import (
 	"cyberstrike-ai/internal/database"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -119,6 +122,194 @@ func (h *AttackChainHandler) GetAttackChain(c *gin.Context) {
 	c.JSON(http.StatusOK, chain)
 }
 
+// ExportAttackChain 导出攻击链为 Mermaid/DOT/PNG，便于嵌入报告或Wiki而无需截图
+// GET /api/attack-chain/:conversationId/export?format=mermaid|dot|png（默认 mermaid）
+func (h *AttackChainHandler) ExportAttackChain(c *gin.Context) {
+	conversationID := c.Param("conversationId")
+	if conversationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "conversationId is required"})
+		return
+	}
+	format := c.DefaultQuery("format", "mermaid")
+	if format != "mermaid" && format != "dot" && format != "png" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format 仅支持 mermaid、dot 或 png"})
+		return
+	}
+
+	openAIConfig := h.getOpenAIConfig()
+	builder := attackchain.NewBuilder(h.db, openAIConfig, h.logger)
+	chain, err := builder.LoadChainFromDatabase(conversationID)
+	if err != nil || len(chain.Nodes) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "攻击链尚未生成，请先调用 GET /api/attack-chain/:conversationId"})
+		return
+	}
+
+	switch format {
+	case "mermaid":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=attack-chain-%s.mmd", conversationID))
+		c.String(http.StatusOK, attackchain.RenderMermaid(chain))
+	case "dot":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=attack-chain-%s.dot", conversationID))
+		c.String(http.StatusOK, attackchain.RenderDot(chain))
+	case "png":
+		pngBytes, err := attackchain.RenderPNG(chain)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=attack-chain-%s.png", conversationID))
+		c.Data(http.StatusOK, "image/png", pngBytes)
+	}
+}
+
+// attackChainNodeRequest CreateAttackChainNode/UpdateAttackChainNode 的请求体
+type attackChainNodeRequest struct {
+	Type      string                 `json:"type" binding:"required"` // tool, vulnerability, target, exploit
+	Label     string                 `json:"label" binding:"required"`
+	Metadata  map[string]interface{} `json:"metadata"`
+	RiskScore int                    `json:"risk_score"`
+	EditedBy  string                 `json:"edited_by"` // 留空时记为 "analyst"，本项目为单用户鉴权，无法从会话取得具体用户名
+}
+
+func (req *attackChainNodeRequest) editor() string {
+	if req.EditedBy == "" {
+		return "analyst"
+	}
+	return req.EditedBy
+}
+
+// CreateAttackChainNode 手动新增攻击链节点（补上模型遗漏的步骤）
+// POST /api/attack-chain/:conversationId/nodes
+func (h *AttackChainHandler) CreateAttackChainNode(c *gin.Context) {
+	conversationID := c.Param("conversationId")
+	var req attackChainNodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	metadataJSON, _ := json.Marshal(req.Metadata)
+	nodeID := stableAttackChainNodeID()
+	if err := h.db.SaveAttackChainNodeEdited(conversationID, nodeID, req.Type, req.Label, "", string(metadataJSON), req.RiskScore, req.editor()); err != nil {
+		h.logger.Error("创建攻击链节点失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建攻击链节点失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": nodeID, "message": "节点已创建"})
+}
+
+// UpdateAttackChainNode 手动修改攻击链节点（如纠正模型对节点类型/标签的误判）
+// PUT /api/attack-chain/:conversationId/nodes/:nodeId
+func (h *AttackChainHandler) UpdateAttackChainNode(c *gin.Context) {
+	conversationID := c.Param("conversationId")
+	nodeID := c.Param("nodeId")
+	var req attackChainNodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	metadataJSON, _ := json.Marshal(req.Metadata)
+	if err := h.db.SaveAttackChainNodeEdited(conversationID, nodeID, req.Type, req.Label, "", string(metadataJSON), req.RiskScore, req.editor()); err != nil {
+		h.logger.Error("更新攻击链节点失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新攻击链节点失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "节点已更新"})
+}
+
+// DeleteAttackChainNode 手动删除攻击链节点（移除模型幻觉出的步骤）
+// DELETE /api/attack-chain/:conversationId/nodes/:nodeId
+func (h *AttackChainHandler) DeleteAttackChainNode(c *gin.Context) {
+	conversationID := c.Param("conversationId")
+	nodeID := c.Param("nodeId")
+
+	if err := h.db.DeleteAttackChainNode(conversationID, nodeID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除攻击链节点失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "节点已删除"})
+}
+
+// attackChainEdgeRequest CreateAttackChainEdge/UpdateAttackChainEdge 的请求体
+type attackChainEdgeRequest struct {
+	Source   string `json:"source" binding:"required"`
+	Target   string `json:"target" binding:"required"`
+	Type     string `json:"type"`
+	Weight   int    `json:"weight"`
+	EditedBy string `json:"edited_by"`
+}
+
+func (req *attackChainEdgeRequest) editor() string {
+	if req.EditedBy == "" {
+		return "analyst"
+	}
+	return req.EditedBy
+}
+
+// CreateAttackChainEdge 手动新增攻击链边
+// POST /api/attack-chain/:conversationId/edges
+func (h *AttackChainHandler) CreateAttackChainEdge(c *gin.Context) {
+	conversationID := c.Param("conversationId")
+	var req attackChainEdgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	edgeID := stableAttackChainNodeID()
+	if err := h.db.SaveAttackChainEdgeEdited(conversationID, edgeID, req.Source, req.Target, req.Type, req.Weight, req.editor()); err != nil {
+		h.logger.Error("创建攻击链边失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建攻击链边失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": edgeID, "message": "边已创建"})
+}
+
+// UpdateAttackChainEdge 手动修改攻击链边
+// PUT /api/attack-chain/:conversationId/edges/:edgeId
+func (h *AttackChainHandler) UpdateAttackChainEdge(c *gin.Context) {
+	conversationID := c.Param("conversationId")
+	edgeID := c.Param("edgeId")
+	var req attackChainEdgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	if err := h.db.SaveAttackChainEdgeEdited(conversationID, edgeID, req.Source, req.Target, req.Type, req.Weight, req.editor()); err != nil {
+		h.logger.Error("更新攻击链边失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新攻击链边失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "边已更新"})
+}
+
+// DeleteAttackChainEdge 手动删除攻击链边
+// DELETE /api/attack-chain/:conversationId/edges/:edgeId
+func (h *AttackChainHandler) DeleteAttackChainEdge(c *gin.Context) {
+	conversationID := c.Param("conversationId")
+	edgeID := c.Param("edgeId")
+
+	if err := h.db.DeleteAttackChainEdge(conversationID, edgeID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除攻击链边失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "边已删除"})
+}
+
+// stableAttackChainNodeID 为手动创建的节点/边生成随机 ID（与 AI 生成路径使用的 stableNodeID 确定性哈希
+// 不同：人工新增的节点没有可复现的规范化输入，直接用 UUID 即可）。
+func stableAttackChainNodeID() string {
+	return "manual_" + uuid.New().String()
+}
+
 // RegenerateAttackChain 重新生成攻击链
 // POST /api/attack-chain/:conversationId/regenerate
 func (h *AttackChainHandler) RegenerateAttackChain(c *gin.Context) {
@@ -136,10 +327,8 @@ func (h *AttackChainHandler) RegenerateAttackChain(c *gin.Context) {
 		return
 	}
 
-	// 删除旧的攻击链
-	if err := h.db.DeleteAttackChain(conversationID); err != nil {
-		h.logger.Warn("删除旧攻击链失败", zap.Error(err))
-	}
+	// 不再在此处整链删除：节点 ID 由 stableNodeID 确定性生成，重新生成时会在 Builder.saveChain 中
+	// 原地更新已有节点、仅清理本轮结果中消失的旧节点，从而保留前端已保存的节点引用与手动标注。
 
 	// 使用锁机制防止并发生成
 	lockInterface, _ := h.generatingLocks.LoadOrStore(conversationID, &sync.Mutex{})
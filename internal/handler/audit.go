@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"cyberstrike-ai/internal/database"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AuditHandler 提供敏感操作审计日志的查询接口，见 database.RecordAudit（wivd/CyberStrikeAI#synth-3095）。
+type AuditHandler struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+// NewAuditHandler creates a new AuditHandler.
+func NewAuditHandler(db *database.DB, logger *zap.Logger) *AuditHandler {
+	return &AuditHandler{db: db, logger: logger}
+}
+
+// ListAuditLog 支持按 actor/action/since/until/limit 筛选（GET /api/audit），均为可选查询参数，
+// since/until 使用 RFC3339 格式。
+func (h *AuditHandler) ListAuditLog(c *gin.Context) {
+	filter := database.AuditLogFilter{
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+	}
+
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since 参数格式无效，应为 RFC3339 时间字符串"})
+			return
+		}
+		filter.Since = t
+	}
+	if until := c.Query("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until 参数格式无效，应为 RFC3339 时间字符串"})
+			return
+		}
+		filter.Until = t
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			filter.Limit = limit
+		}
+	}
+
+	entries, err := h.db.ListAuditLog(filter)
+	if err != nil {
+		if h.logger != nil {
+			h.logger.Error("查询审计日志失败", zap.Error(err))
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"cyberstrike-ai/internal/config"
+	"cyberstrike-ai/internal/database"
 	"cyberstrike-ai/internal/security"
 
 	"github.com/gin-gonic/gin"
@@ -15,21 +17,31 @@ import (
 // AuthHandler handles authentication-related endpoints.
 type AuthHandler struct {
 	manager    *security.AuthManager
+	db         *database.DB
 	config     *config.Config
 	configPath string
 	logger     *zap.Logger
+
+	oidc *security.OIDCProvider
 }
 
 // NewAuthHandler creates a new AuthHandler.
-func NewAuthHandler(manager *security.AuthManager, cfg *config.Config, configPath string, logger *zap.Logger) *AuthHandler {
+func NewAuthHandler(manager *security.AuthManager, db *database.DB, cfg *config.Config, configPath string, logger *zap.Logger) *AuthHandler {
 	return &AuthHandler{
 		manager:    manager,
+		db:         db,
 		config:     cfg,
 		configPath: configPath,
 		logger:     logger,
 	}
 }
 
+// SetOIDCProvider 注入 OIDC 单点登录处理器；仅在 cfg.OIDC.Enabled 时由 app 装配层调用，
+// 未调用时 Login/Callback 均返回「未启用」错误，密码登录不受影响。
+func (h *AuthHandler) SetOIDCProvider(provider *security.OIDCProvider) {
+	h.oidc = provider
+}
+
 type loginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
@@ -47,11 +59,26 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, expiresAt, err := h.manager.Authenticate(req.Password)
+	ip := c.ClientIP()
+	userAgent := c.Request.UserAgent()
+
+	token, expiresAt, err := h.manager.Authenticate(req.Password, ip, userAgent)
 	if err != nil {
+		if h.db != nil {
+			_ = h.db.RecordLoginAttempt(ip, userAgent, false, err.Error())
+			_ = h.db.RecordAudit("password-login", "login_failed", "", err.Error(), ip)
+		}
+		if errors.Is(err, security.ErrAccountLocked) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "密码错误"})
 		return
 	}
+	if h.db != nil {
+		_ = h.db.RecordLoginAttempt(ip, userAgent, true, "")
+		_ = h.db.RecordAudit("password-login", "login", "", "", ip)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"token":               token,
@@ -131,6 +158,9 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	if h.logger != nil {
 		h.logger.Info("登录密码已更新，所有会话已失效")
 	}
+	if h.db != nil {
+		_ = h.db.RecordAudit("password-login", "password_change", "", "", c.ClientIP())
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "密码已更新，请使用新密码重新登录"})
 }
@@ -149,8 +179,162 @@ func (h *AuthHandler) Validate(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"token":      session.Token,
 		"expires_at": session.ExpiresAt.UTC().Format(time.RFC3339),
+	}
+	// Subject/Role 仅在会话由 OIDC 登录创建时非空，见 AuthManager.CreateSSOSession。
+	if session.Subject != "" {
+		resp["subject"] = session.Subject
+		resp["role"] = session.Role
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type createAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes"`
+}
+
+// CreateAPIKey 创建一个新的长期访问凭证（POST /api/auth/api-keys）；原始 key 只在响应中返回一次，
+// 数据库中只保存哈希，遗失后需要撤销重建，见 database.DB.CreateAPIKey。
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "名称不能为空"})
+		return
+	}
+
+	key, rawKey, err := h.db.CreateAPIKey(req.Name, req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.logger != nil {
+		h.logger.Info("创建 API Key", zap.String("id", key.ID), zap.String("name", key.Name))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":        key.ID,
+		"name":      key.Name,
+		"keyPrefix": key.KeyPrefix,
+		"scopes":    key.Scopes,
+		"createdAt": key.CreatedAt.UTC().Format(time.RFC3339),
+		"key":       rawKey,
+	})
+}
+
+// ListAPIKeys 列出全部 API Key（不含哈希/原始密钥），GET /api/auth/api-keys。
+func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.db.ListAPIKeys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"apiKeys": keys})
+}
+
+// RevokeAPIKey 撤销一个 API Key（DELETE /api/auth/api-keys/:id），撤销后立即失效且不可恢复。
+func (h *AuthHandler) RevokeAPIKey(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.db.RevokeAPIKey(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if h.logger != nil {
+		h.logger.Info("撤销 API Key", zap.String("id", id))
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已撤销"})
+}
+
+// ListSessions 列出全部未过期的登录会话（GET /api/auth/sessions），用于在 Web 控制台展示登录设备/
+// 来源以便识别异常登录；不回显 Token 本身，标识改用 AuthManager.ListSessions 生成的 ID。
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	currentToken := c.GetString(security.ContextAuthTokenKey)
+	sessions := h.manager.ListSessions()
+
+	result := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, gin.H{
+			"id":        s.ID,
+			"ip":        s.IP,
+			"userAgent": s.UserAgent,
+			"createdAt": s.CreatedAt.UTC().Format(time.RFC3339),
+			"expiresAt": s.ExpiresAt.UTC().Format(time.RFC3339),
+			"subject":   s.Subject,
+			"role":      s.Role,
+			"isCurrent": s.Token == currentToken,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": result})
+}
+
+// RevokeSession 撤销指定会话（DELETE /api/auth/sessions/:id），用于在 Web 控制台踢掉其他设备的登录。
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.manager.RevokeSessionByID(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已撤销该会话"})
+}
+
+// OIDCLogin 将浏览器重定向到企业 IdP 的授权端点（GET /api/auth/oidc/login），发起单点登录，
+// 见 security.OIDCProvider.BeginLogin。
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	if h.oidc == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "OIDC 单点登录未启用"})
+		return
+	}
+
+	authURL, _, err := h.oidc.BeginLogin()
+	if err != nil {
+		if h.logger != nil {
+			h.logger.Error("发起 OIDC 登录失败", zap.Error(err))
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "连接身份提供商失败: " + err.Error()})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 处理 IdP 回调（GET /api/auth/oidc/callback），换取并校验 ID Token 后创建一个
+// 与密码登录等价的会话，见 security.OIDCProvider.ExchangeCallback、AuthManager.CreateSSOSession。
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	if h.oidc == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "OIDC 单点登录未启用"})
+		return
+	}
+
+	code := c.Query("code")
+	state := c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "回调缺少 code 或 state 参数"})
+		return
+	}
+
+	claims, err := h.oidc.ExchangeCallback(code, state)
+	if err != nil {
+		if h.logger != nil {
+			h.logger.Warn("OIDC 登录失败", zap.Error(err))
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, expiresAt := h.manager.CreateSSOSession(claims.Subject, claims.Role, c.ClientIP(), c.Request.UserAgent())
+	if h.logger != nil {
+		h.logger.Info("OIDC 单点登录成功", zap.String("subject", claims.Subject), zap.String("role", claims.Role))
+	}
+	if h.db != nil {
+		_ = h.db.RecordAudit(claims.Subject, "login", "", "oidc", c.ClientIP())
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"token":               token,
+		"expires_at":          expiresAt.UTC().Format(time.RFC3339),
+		"session_duration_hr": h.manager.SessionDurationHours(),
+		"subject":             claims.Subject,
+		"role":                claims.Role,
 	})
 }
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"cyberstrike-ai/internal/config"
+	"cyberstrike-ai/internal/database"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// BackupHandler 提供 /api/admin/backups 下的数据库备份管理接口，底层复用 database.Backup/
+// database.Restore/database.ListBackups；实际定时执行由 database.BackupJob 在后台完成，
+// 见 app.NewApp。
+type BackupHandler struct {
+	db     *database.DB
+	cfg    *config.BackupConfig
+	logger *zap.Logger
+}
+
+// NewBackupHandler 创建备份管理处理器。
+func NewBackupHandler(db *database.DB, cfg *config.BackupConfig, logger *zap.Logger) *BackupHandler {
+	return &BackupHandler{db: db, cfg: cfg, logger: logger}
+}
+
+// ListBackups 列出已存在的备份文件（GET /api/admin/backups）。
+func (h *BackupHandler) ListBackups(c *gin.Context) {
+	dir := h.cfg.Dir
+	if dir == "" {
+		c.JSON(http.StatusOK, gin.H{"backups": []*database.BackupInfo{}})
+		return
+	}
+
+	backups, err := database.ListBackups(dir)
+	if err != nil {
+		h.logger.Error("列出备份失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"backups": backups})
+}
+
+// CreateBackup 立即执行一次备份（POST /api/admin/backups）。
+func (h *BackupHandler) CreateBackup(c *gin.Context) {
+	dir := h.cfg.Dir
+	if dir == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未配置 backup.dir，无法创建备份"})
+		return
+	}
+
+	info, err := h.db.Backup(dir)
+	if err != nil {
+		h.logger.Error("创建备份失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// RestoreBackupRequest 恢复备份请求体。
+type RestoreBackupRequest struct {
+	Filename string `json:"filename"`
+}
+
+// RestoreBackup 用指定的备份文件覆盖当前数据库（POST /api/admin/backups/restore）；这是一个
+// 破坏性操作，恢复期间正在执行的查询可能短暂报错，见 database.DB.Restore 的说明。
+func (h *BackupHandler) RestoreBackup(c *gin.Context) {
+	var req RestoreBackupRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "filename required"})
+		return
+	}
+	if h.cfg.Dir == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未配置 backup.dir，无法恢复备份"})
+		return
+	}
+
+	// Clean 去除路径穿越，恢复只允许作用于配置目录下的文件
+	backupPath := filepath.Join(h.cfg.Dir, filepath.Base(req.Filename))
+	if err := h.db.Restore(backupPath); err != nil {
+		h.logger.Error("恢复备份失败", zap.String("filename", req.Filename), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "恢复成功"})
+}
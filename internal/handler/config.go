@@ -15,6 +15,7 @@ import (
 
 	"cyberstrike-ai/internal/agents"
 	"cyberstrike-ai/internal/config"
+	"cyberstrike-ai/internal/database"
 	"cyberstrike-ai/internal/knowledge"
 	"cyberstrike-ai/internal/mcp"
 	"cyberstrike-ai/internal/mcp/builtin"
@@ -32,6 +33,12 @@ type KnowledgeToolRegistrar func() error
 // VulnerabilityToolRegistrar 漏洞工具注册器接口
 type VulnerabilityToolRegistrar func() error
 
+// MemoryToolRegistrar 长期代理记忆工具注册器接口
+type MemoryToolRegistrar func() error
+
+// AssetToolRegistrar 资产台账工具注册器接口
+type AssetToolRegistrar func() error
+
 // WebshellToolRegistrar WebShell 工具注册器接口（ApplyConfig 时重新注册）
 type WebshellToolRegistrar func() error
 
@@ -78,6 +85,8 @@ type ConfigHandler struct {
 	externalMCPMgr             *mcp.ExternalMCPManager    // 外部MCP管理器
 	knowledgeToolRegistrar     KnowledgeToolRegistrar     // 知识库工具注册器（可选）
 	vulnerabilityToolRegistrar VulnerabilityToolRegistrar // 漏洞工具注册器（可选）
+	memoryToolRegistrar        MemoryToolRegistrar        // 长期代理记忆工具注册器（可选）
+	assetToolRegistrar         AssetToolRegistrar         // 资产台账工具注册器（可选）
 	webshellToolRegistrar      WebshellToolRegistrar      // WebShell 工具注册器（可选）
 	skillsToolRegistrar        SkillsToolRegistrar        // Skills工具注册器（可选）
 	batchTaskToolRegistrar     BatchTaskToolRegistrar     // 批量任务 MCP 工具（可选）
@@ -90,6 +99,12 @@ type ConfigHandler struct {
 	logger                     *zap.Logger
 	mu                         sync.RWMutex
 	lastEmbeddingConfig        *config.EmbeddingConfig // 上一次的嵌入模型配置（用于检测变更）
+	db                         *database.DB            // 见 SetDB；为 nil 时工具启停不写审计日志
+}
+
+// SetDB 注入数据库连接，用于记录工具启停等配置变更的审计日志（见 database.RecordAudit）。
+func (h *ConfigHandler) SetDB(db *database.DB) {
+	h.db = db
 }
 
 // AttackChainUpdater 攻击链处理器更新接口
@@ -143,6 +158,20 @@ func (h *ConfigHandler) SetVulnerabilityToolRegistrar(registrar VulnerabilityToo
 	h.vulnerabilityToolRegistrar = registrar
 }
 
+// SetMemoryToolRegistrar 设置长期代理记忆工具注册器
+func (h *ConfigHandler) SetMemoryToolRegistrar(registrar MemoryToolRegistrar) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.memoryToolRegistrar = registrar
+}
+
+// SetAssetToolRegistrar 设置资产台账工具注册器
+func (h *ConfigHandler) SetAssetToolRegistrar(registrar AssetToolRegistrar) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.assetToolRegistrar = registrar
+}
+
 // SetWebshellToolRegistrar 设置 WebShell 工具注册器
 func (h *ConfigHandler) SetWebshellToolRegistrar(registrar WebshellToolRegistrar) {
 	h.mu.Lock()
@@ -217,7 +246,7 @@ type GetConfigResponse struct {
 	Knowledge  config.KnowledgeConfig  `json:"knowledge"`
 	Robots     config.RobotsConfig     `json:"robots,omitempty"`
 	MultiAgent config.MultiAgentPublic `json:"multi_agent,omitempty"`
-	C2         config.C2Public          `json:"c2"`
+	C2         config.C2Public         `json:"c2"`
 }
 
 // ToolConfigInfo 工具配置信息
@@ -617,7 +646,7 @@ type UpdateConfigRequest struct {
 	Knowledge  *config.KnowledgeConfig     `json:"knowledge,omitempty"`
 	Robots     *config.RobotsConfig        `json:"robots,omitempty"`
 	MultiAgent *config.MultiAgentAPIUpdate `json:"multi_agent,omitempty"`
-	C2         *config.C2APIUpdate          `json:"c2,omitempty"`
+	C2         *config.C2APIUpdate         `json:"c2,omitempty"`
 }
 
 // ToolEnableStatus 工具启用状态
@@ -834,6 +863,10 @@ func (h *ConfigHandler) UpdateConfig(c *gin.Context) {
 				}
 			}
 		}
+
+		if h.db != nil {
+			_ = h.db.RecordAudit("", "tool_enable", "", "", c.ClientIP())
+		}
 	}
 
 	// 保存配置到文件
@@ -1059,6 +1092,26 @@ func (h *ConfigHandler) ApplyConfig(c *gin.Context) {
 		}
 	}
 
+	// 重新注册长期代理记忆工具（内置工具，必须注册）
+	if h.memoryToolRegistrar != nil {
+		h.logger.Info("重新注册长期代理记忆工具")
+		if err := h.memoryToolRegistrar(); err != nil {
+			h.logger.Error("重新注册长期代理记忆工具失败", zap.Error(err))
+		} else {
+			h.logger.Info("长期代理记忆工具已重新注册")
+		}
+	}
+
+	// 重新注册资产台账工具（内置工具，必须注册）
+	if h.assetToolRegistrar != nil {
+		h.logger.Info("重新注册资产台账工具")
+		if err := h.assetToolRegistrar(); err != nil {
+			h.logger.Error("重新注册资产台账工具失败", zap.Error(err))
+		} else {
+			h.logger.Info("资产台账工具已重新注册")
+		}
+	}
+
 	// 重新注册 WebShell 工具（内置工具，必须注册）
 	if h.webshellToolRegistrar != nil {
 		h.logger.Info("重新注册 WebShell 工具")
@@ -1130,6 +1183,7 @@ func (h *ConfigHandler) ApplyConfig(c *gin.Context) {
 			SimilarityThreshold: h.config.Knowledge.Retrieval.SimilarityThreshold,
 			SubIndexFilter:      h.config.Knowledge.Retrieval.SubIndexFilter,
 			PostRetrieve:        h.config.Knowledge.Retrieval.PostRetrieve,
+			HybridSearch:        h.config.Knowledge.Retrieval.HybridSearchEffective(),
 		}
 		h.retrieverUpdater.UpdateConfig(retrievalConfig)
 		h.logger.Info("检索器配置已更新",
@@ -1789,6 +1843,40 @@ func (h *ConfigHandler) GetToolSchema(c *gin.Context) {
 	c.JSON(http.StatusNotFound, gin.H{"error": "工具未找到"})
 }
 
+// GetToolsHealthResponse 工具健康检查响应
+type GetToolsHealthResponse struct {
+	Tools     []security.ToolHealth `json:"tools"`
+	Total     int                   `json:"total"`
+	Available int                   `json:"available"`
+}
+
+// GetToolsHealth 按需重新探测所有工具的可用性（命令是否存在于 PATH、版本号），与启动时的自动禁用
+// 检查（见 security.Executor.RunStartupHealthCheckAndDisable）共用同一探测逻辑，但不修改 Enabled 状态，
+// 仅供前端展示当前哪些工具实际不可用。
+func (h *ConfigHandler) GetToolsHealth(c *gin.Context) {
+	if h.executor == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "安全工具执行器未初始化"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	results := h.executor.CheckToolsHealth(ctx)
+	available := 0
+	for _, health := range results {
+		if health.Available {
+			available++
+		}
+	}
+
+	c.JSON(http.StatusOK, GetToolsHealthResponse{
+		Tools:     results,
+		Total:     len(results),
+		Available: available,
+	})
+}
+
 // buildInputSchemaFromParams 从 YAML 工具的 ParameterConfig 构建 JSON Schema（用于前端展示）。
 // 不依赖 MCP 服务器注册状态，所有工具（包括未启用的）都能返回参数定义。
 func buildInputSchemaFromParams(params []config.ParameterConfig) map[string]interface{} {
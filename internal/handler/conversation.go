@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -230,3 +231,100 @@ func (h *ConversationHandler) DeleteConversationTurn(c *gin.Context) {
 		"message":           "ok",
 	})
 }
+
+// MergeConversationsRequest 合并对话请求
+type MergeConversationsRequest struct {
+	SourceID string `json:"sourceId"`
+	TargetID string `json:"targetId"`
+}
+
+// MergeConversations 将 sourceId 对话合并进 targetId，消息按时间戳交错，合并后删除 sourceId。
+func (h *ConversationHandler) MergeConversations(c *gin.Context) {
+	var req MergeConversationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.SourceID == "" || req.TargetID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sourceId 和 targetId 均为必填"})
+		return
+	}
+
+	conv, err := h.db.MergeConversations(req.SourceID, req.TargetID)
+	if err != nil {
+		h.logger.Warn("合并对话失败",
+			zap.String("sourceId", req.SourceID),
+			zap.String("targetId", req.TargetID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, conv)
+}
+
+// SplitConversationRequest 拆分对话请求
+type SplitConversationRequest struct {
+	MessageID string `json:"messageId"` // 拆分锚点：该消息所在轮次起，连同其后的消息移入新对话
+	NewTitle  string `json:"newTitle"`  // 新对话标题，留空则沿用原标题
+}
+
+// SplitConversation 在锚点消息所在轮次的起点将对话拆分为两段，返回新建的对话。
+func (h *ConversationHandler) SplitConversation(c *gin.Context) {
+	conversationID := c.Param("id")
+	if conversationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "conversation id required"})
+		return
+	}
+
+	var req SplitConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.MessageID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "messageId required"})
+		return
+	}
+
+	newConv, err := h.db.SplitConversation(conversationID, req.MessageID, req.NewTitle)
+	if err != nil {
+		h.logger.Warn("拆分对话失败",
+			zap.String("conversationId", conversationID),
+			zap.String("messageId", req.MessageID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newConv)
+}
+
+// ExportConversation 导出对话及其关联数据（消息、过程详情、漏洞、攻击链）为可迁移的 JSON 归档，
+// 供备份或转移到另一套部署后通过 ImportConversation 还原。
+func (h *ConversationHandler) ExportConversation(c *gin.Context) {
+	id := c.Param("id")
+
+	archive, err := h.db.ExportConversationArchive(id)
+	if err != nil {
+		h.logger.Error("导出对话失败", zap.String("conversationId", id), zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="conversation-%s.json"`, id))
+	c.JSON(http.StatusOK, archive)
+}
+
+// ImportConversation 从 ExportConversation 产出的 JSON 归档还原对话，保留原始 ID；
+// 已存在同 ID 对话时按归档内容覆盖，重复导入同一份归档是幂等的。
+func (h *ConversationHandler) ImportConversation(c *gin.Context) {
+	var archive database.ConversationArchive
+	if err := c.ShouldBindJSON(&archive); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conv, err := h.db.ImportConversationArchive(&archive)
+	if err != nil {
+		h.logger.Warn("导入对话失败", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, conv)
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cyberstrike-ai/internal/database"
+	"cyberstrike-ai/internal/defectdojo"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// DefectDojoHandler 提供手动触发的 DefectDojo 推送接口（POST /api/integrations/defectdojo/push）。
+type DefectDojoHandler struct {
+	db     *database.DB
+	logger *zap.Logger
+	client *defectdojo.Client
+	// engagementID/scanType 为配置中的默认值，请求体可逐次覆盖 engagement_id
+	engagementID int
+	scanType     string
+}
+
+// NewDefectDojoHandler 创建 DefectDojoHandler；client 为 nil 表示未配置 base_url，推送接口会返回错误。
+func NewDefectDojoHandler(db *database.DB, logger *zap.Logger, client *defectdojo.Client, engagementID int, scanType string) *DefectDojoHandler {
+	return &DefectDojoHandler{
+		db:           db,
+		logger:       logger,
+		client:       client,
+		engagementID: engagementID,
+		scanType:     scanType,
+	}
+}
+
+type defectDojoPushRequest struct {
+	ConversationID string `json:"conversation_id" binding:"required"`
+	EngagementID   int    `json:"engagement_id,omitempty"`
+}
+
+// PushConversation 将指定会话下的漏洞记录以 Generic Findings JSON 推送为一次 DefectDojo Engagement Import。
+func (h *DefectDojoHandler) PushConversation(c *gin.Context) {
+	if h.client == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "DefectDojo 集成未配置 base_url，请检查 defectdojo 配置"})
+		return
+	}
+
+	var req defectDojoPushRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	engagementID := req.EngagementID
+	if engagementID == 0 {
+		engagementID = h.engagementID
+	}
+	if engagementID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未指定 engagement_id，且未配置默认值"})
+		return
+	}
+
+	pushed, err := pushConversationVulnerabilities(c.Request.Context(), h.db, h.client, req.ConversationID, engagementID, h.scanType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": req.ConversationID,
+		"engagement_id":   engagementID,
+		"pushed_count":    pushed,
+	})
+}
+
+// pushConversationVulnerabilities 查询会话下的全部漏洞并推送，返回推送的漏洞数量；无漏洞时不发起请求。
+func pushConversationVulnerabilities(ctx context.Context, db *database.DB, client *defectdojo.Client, conversationID string, engagementID int, scanType string) (int, error) {
+	total, err := db.CountVulnerabilities("", conversationID, "", "", "", "", "")
+	if err != nil {
+		return 0, fmt.Errorf("统计会话漏洞数量失败: %w", err)
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	items, err := db.ListVulnerabilities(total, 0, "", conversationID, "", "", "", "", "")
+	if err != nil {
+		return 0, fmt.Errorf("查询会话漏洞列表失败: %w", err)
+	}
+
+	findings := make([]defectdojo.Finding, 0, len(items))
+	for _, v := range items {
+		findings = append(findings, defectdojo.Finding{
+			Title:       v.Title,
+			Description: v.Description,
+			Severity:    defectdojo.SeverityFromInternal(v.Severity),
+			Date:        v.CreatedAt.Format("2006-01-02"),
+			Mitigation:  v.Recommendation,
+			Impact:      v.Impact,
+			References:  v.Proof,
+			Active:      v.Status != "fixed" && v.Status != "false_positive",
+			Verified:    v.Status == "confirmed" || v.Status == "fixed",
+			CVSSVector:  v.CVSSVector,
+			CVSSScore:   v.CVSSScore,
+		})
+	}
+
+	if err := client.ImportEngagementFindings(ctx, engagementID, scanType, findings); err != nil {
+		return 0, fmt.Errorf("推送 DefectDojo 失败: %w", err)
+	}
+	return len(findings), nil
+}
+
+// DefectDojoTrigger 在会话运行完成后自动推送该会话的漏洞记录到 DefectDojo；由 app.go 按
+// cfg.DefectDojo 是否启用/是否开启 push_on_completion 决定是否构造，未配置时 client 为 nil，
+// TriggerAsync 直接跳过，与 CVEEnrichmentTrigger 采用相同的"可选增强，永不阻塞主流程"约定。
+type DefectDojoTrigger struct {
+	client       *defectdojo.Client
+	engagementID int
+	scanType     string
+	logger       *zap.Logger
+}
+
+// NewDefectDojoTrigger 创建 DefectDojoTrigger
+func NewDefectDojoTrigger(client *defectdojo.Client, engagementID int, scanType string, logger *zap.Logger) *DefectDojoTrigger {
+	return &DefectDojoTrigger{client: client, engagementID: engagementID, scanType: scanType, logger: logger}
+}
+
+// TriggerAsync 异步推送 conversationID 下的全部漏洞，失败仅记录日志，不影响会话主流程。
+func (t *DefectDojoTrigger) TriggerAsync(db *database.DB, conversationID string) {
+	if t == nil || t.client == nil || conversationID == "" {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		pushed, err := pushConversationVulnerabilities(ctx, db, t.client, conversationID, t.engagementID, t.scanType)
+		if err != nil {
+			t.logger.Warn("会话完成后自动推送 DefectDojo 失败", zap.String("conversationId", conversationID), zap.Error(err))
+			return
+		}
+		if pushed > 0 {
+			t.logger.Info("会话完成后已自动推送 DefectDojo", zap.String("conversationId", conversationID), zap.Int("count", pushed))
+		}
+	}()
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"cyberstrike-ai/internal/config"
+	"cyberstrike-ai/internal/database"
 	"cyberstrike-ai/internal/mcp"
 
 	"github.com/gin-gonic/gin"
@@ -21,6 +22,7 @@ type ExternalMCPHandler struct {
 	configPath string
 	logger     *zap.Logger
 	mu         sync.RWMutex
+	db         *database.DB // 见 SetDB；为 nil 时新增/更新外部 MCP 不写审计日志
 }
 
 // NewExternalMCPHandler 创建外部MCP处理器
@@ -33,6 +35,11 @@ func NewExternalMCPHandler(manager *mcp.ExternalMCPManager, cfg *config.Config,
 	}
 }
 
+// SetDB 注入数据库连接，用于记录新增/更新外部 MCP 的审计日志（见 database.RecordAudit）。
+func (h *ExternalMCPHandler) SetDB(db *database.DB) {
+	h.db = db
+}
+
 // GetExternalMCPs 获取所有外部MCP配置
 func (h *ExternalMCPHandler) GetExternalMCPs(c *gin.Context) {
 	h.mu.RLock()
@@ -180,6 +187,9 @@ func (h *ExternalMCPHandler) AddOrUpdateExternalMCP(c *gin.Context) {
 	}
 
 	h.logger.Info("外部MCP配置已更新", zap.String("name", name))
+	if h.db != nil {
+		_ = h.db.RecordAudit("", "external_mcp_add", name, "", c.ClientIP())
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "配置已更新"})
 }
 
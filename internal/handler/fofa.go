@@ -107,6 +107,33 @@ func (h *FofaHandler) resolveBaseURL() string {
 	return "https://fofa.info/api/v1/search/all"
 }
 
+// apiError 携带 HTTP 状态码的内部错误，供 gin handler 与流水线共用的内部方法向上返回
+type apiError struct {
+	status  int
+	message string
+	extra   gin.H
+}
+
+func (e *apiError) Error() string { return e.message }
+
+func newAPIError(status int, message string, extra gin.H) *apiError {
+	return &apiError{status: status, message: message, extra: extra}
+}
+
+// writeAPIError 将 apiError（或普通 error）写入响应；普通 error 一律按 500 处理
+func writeAPIError(c *gin.Context, err error) {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		body := gin.H{"error": apiErr.message}
+		for k, v := range apiErr.extra {
+			body[k] = v
+		}
+		c.JSON(apiErr.status, body)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
 // ParseNaturalLanguage 将自然语言解析为 FOFA 查询语法（仅生成，不执行查询）
 func (h *FofaHandler) ParseNaturalLanguage(c *gin.Context) {
 	var req fofaParseRequest
@@ -120,20 +147,27 @@ func (h *FofaHandler) ParseNaturalLanguage(c *gin.Context) {
 		return
 	}
 
-	if h.cfg == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "系统配置未初始化"})
+	parsed, err := h.parseNaturalLanguage(c.Request.Context(), req.Text)
+	if err != nil {
+		writeAPIError(c, err)
 		return
 	}
+
+	c.JSON(http.StatusOK, parsed)
+}
+
+// parseNaturalLanguage 自然语言 -> FOFA 查询语法，供 ParseNaturalLanguage handler 与流水线接口共用
+func (h *FofaHandler) parseNaturalLanguage(ctx context.Context, text string) (*fofaParseResponse, error) {
+	if h.cfg == nil {
+		return nil, newAPIError(http.StatusInternalServerError, "系统配置未初始化", nil)
+	}
 	if strings.TrimSpace(h.cfg.OpenAI.APIKey) == "" || strings.TrimSpace(h.cfg.OpenAI.Model) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "未配置 AI 模型：请在系统设置中填写 openai.api_key 与 openai.model（支持 OpenAI 兼容 API，如 DeepSeek）",
-			"need":  []string{"openai.api_key", "openai.model"},
+		return nil, newAPIError(http.StatusBadRequest, "未配置 AI 模型：请在系统设置中填写 openai.api_key 与 openai.model（支持 OpenAI 兼容 API，如 DeepSeek）", gin.H{
+			"need": []string{"openai.api_key", "openai.model"},
 		})
-		return
 	}
 	if h.openAIClient == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "AI 客户端未初始化"})
-		return
+		return nil, newAPIError(http.StatusInternalServerError, "AI 客户端未初始化", nil)
 	}
 
 	systemPrompt := strings.TrimSpace(`
@@ -260,7 +294,7 @@ func (h *FofaHandler) ParseNaturalLanguage(c *gin.Context) {
 - 当用户缺少关键条件导致范围过大或歧义（如地点/协议/端口/服务类型未说明），允许 query 为空字符串，并在 warnings 里明确需要补充的信息
 `)
 
-	userPrompt := fmt.Sprintf("自然语言意图：%s", req.Text)
+	userPrompt := fmt.Sprintf("自然语言意图：%s", text)
 
 	requestBody := map[string]interface{}{
 		"model": h.cfg.OpenAI.Model,
@@ -281,22 +315,19 @@ func (h *FofaHandler) ParseNaturalLanguage(c *gin.Context) {
 		} `json:"choices"`
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 90*time.Second)
+	llmCtx, cancel := context.WithTimeout(ctx, 90*time.Second)
 	defer cancel()
 
-	if err := h.openAIClient.ChatCompletion(ctx, requestBody, &apiResponse); err != nil {
+	if err := h.openAIClient.ChatCompletion(llmCtx, requestBody, &apiResponse); err != nil {
 		var apiErr *openaiClient.APIError
 		if errors.As(err, &apiErr) {
 			h.logger.Warn("FOFA自然语言解析：LLM返回错误", zap.Int("status", apiErr.StatusCode))
-			c.JSON(http.StatusBadGateway, gin.H{"error": "AI 解析失败（上游返回非 200），请检查模型配置或稍后重试"})
-			return
+			return nil, newAPIError(http.StatusBadGateway, "AI 解析失败（上游返回非 200），请检查模型配置或稍后重试", nil)
 		}
-		c.JSON(http.StatusBadGateway, gin.H{"error": "AI 解析失败: " + err.Error()})
-		return
+		return nil, newAPIError(http.StatusBadGateway, "AI 解析失败: "+err.Error(), nil)
 	}
 	if len(apiResponse.Choices) == 0 {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "AI 未返回有效结果"})
-		return
+		return nil, newAPIError(http.StatusBadGateway, "AI 未返回有效结果", nil)
 	}
 
 	content := strings.TrimSpace(apiResponse.Choices[0].Message.Content)
@@ -313,11 +344,9 @@ func (h *FofaHandler) ParseNaturalLanguage(c *gin.Context) {
 		if len(snippet) > 1200 {
 			snippet = snippet[:1200]
 		}
-		c.JSON(http.StatusBadGateway, gin.H{
-			"error":   "AI 返回内容无法解析为 JSON，请稍后重试或换个描述方式",
+		return nil, newAPIError(http.StatusBadGateway, "AI 返回内容无法解析为 JSON，请稍后重试或换个描述方式", gin.H{
 			"snippet": snippet,
 		})
-		return
 	}
 	parsed.Query = strings.TrimSpace(parsed.Query)
 	if parsed.Query == "" {
@@ -327,7 +356,7 @@ func (h *FofaHandler) ParseNaturalLanguage(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, parsed)
+	return &parsed, nil
 }
 
 // Search FOFA 查询（后端代理，避免前端暴露 key）
@@ -338,10 +367,20 @@ func (h *FofaHandler) Search(c *gin.Context) {
 		return
 	}
 
+	resp, err := h.search(c.Request.Context(), req)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// search 执行 FOFA 查询，供 Search handler 与流水线接口共用
+func (h *FofaHandler) search(ctx context.Context, req fofaSearchRequest) (*fofaSearchResponse, error) {
 	req.Query = strings.TrimSpace(req.Query)
 	if req.Query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "query 不能为空"})
-		return
+		return nil, newAPIError(http.StatusBadRequest, "query 不能为空", nil)
 	}
 	if req.Size <= 0 {
 		req.Size = 100
@@ -359,12 +398,10 @@ func (h *FofaHandler) Search(c *gin.Context) {
 
 	email, apiKey := h.resolveCredentials()
 	if email == "" || apiKey == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "FOFA 未配置：请在系统设置中填写 FOFA Email/API Key，或设置环境变量 FOFA_EMAIL/FOFA_API_KEY",
+		return nil, newAPIError(http.StatusBadRequest, "FOFA 未配置：请在系统设置中填写 FOFA Email/API Key，或设置环境变量 FOFA_EMAIL/FOFA_API_KEY", gin.H{
 			"need":    []string{"fofa.email", "fofa.api_key"},
 			"env_key": []string{"FOFA_EMAIL", "FOFA_API_KEY"},
 		})
-		return
 	}
 
 	baseURL := h.resolveBaseURL()
@@ -372,8 +409,7 @@ func (h *FofaHandler) Search(c *gin.Context) {
 
 	u, err := url.Parse(baseURL)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "FOFA base_url 无效: " + err.Error()})
-		return
+		return nil, newAPIError(http.StatusInternalServerError, "FOFA base_url 无效: "+err.Error(), nil)
 	}
 
 	params := u.Query()
@@ -391,36 +427,31 @@ func (h *FofaHandler) Search(c *gin.Context) {
 	}
 	u.RawQuery = params.Encode()
 
-	httpReq, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, u.String(), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建请求失败: " + err.Error()})
-		return
+		return nil, newAPIError(http.StatusInternalServerError, "创建请求失败: "+err.Error(), nil)
 	}
 
 	resp, err := h.client.Do(httpReq)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "请求 FOFA 失败: " + err.Error()})
-		return
+		return nil, newAPIError(http.StatusBadGateway, "请求 FOFA 失败: "+err.Error(), nil)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("FOFA 返回非 2xx: %d", resp.StatusCode)})
-		return
+		return nil, newAPIError(http.StatusBadGateway, fmt.Sprintf("FOFA 返回非 2xx: %d", resp.StatusCode), nil)
 	}
 
 	var apiResp fofaAPIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "解析 FOFA 响应失败: " + err.Error()})
-		return
+		return nil, newAPIError(http.StatusBadGateway, "解析 FOFA 响应失败: "+err.Error(), nil)
 	}
 	if apiResp.Error {
 		msg := strings.TrimSpace(apiResp.ErrMsg)
 		if msg == "" {
 			msg = "FOFA 返回错误"
 		}
-		c.JSON(http.StatusBadGateway, gin.H{"error": msg})
-		return
+		return nil, newAPIError(http.StatusBadGateway, msg, nil)
 	}
 
 	fields := splitAndCleanCSV(req.Fields)
@@ -437,7 +468,7 @@ func (h *FofaHandler) Search(c *gin.Context) {
 		results = append(results, item)
 	}
 
-	c.JSON(http.StatusOK, fofaSearchResponse{
+	return &fofaSearchResponse{
 		Query:        req.Query,
 		Size:         apiResp.Size,
 		Page:         apiResp.Page,
@@ -445,7 +476,7 @@ func (h *FofaHandler) Search(c *gin.Context) {
 		Fields:       fields,
 		ResultsCount: len(results),
 		Results:      results,
-	})
+	}, nil
 }
 
 func splitAndCleanCSV(s string) []string {
@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// FofaPipelineRequest 一键式 FOFA -> 批量扫描流水线请求
+type FofaPipelineRequest struct {
+	Text             string `json:"text,omitempty"`             // 自然语言意图，与 query 二选一（优先使用 query）
+	Query            string `json:"query,omitempty"`            // 已知的 FOFA 查询语法，提供后跳过自然语言解析
+	Size             int    `json:"size,omitempty"`             // FOFA 查询条数，默认 100
+	ValidateLive     bool   `json:"validateLive,omitempty"`     // 是否对命中资产做存活探测（HTTP），剔除无法连接的资产
+	IncludeHoneypots bool   `json:"includeHoneypots,omitempty"` // 覆盖默认行为，允许被判定为蜜罐/仿冒资产的目标也进入批量任务
+	Title            string `json:"title,omitempty"`            // 批量任务队列标题，默认使用查询语法
+	Role             string `json:"role,omitempty"`             // 批量任务使用的角色（可选）
+	AgentMode        string `json:"agentMode,omitempty"`        // 批量任务使用的 Agent 模式（可选）
+	TaskTemplate     string `json:"taskTemplate,omitempty"`     // 每个资产生成的任务消息模板，{{host}} 会被替换为资产地址；为空时使用默认模板
+	ExecuteNow       bool   `json:"executeNow,omitempty"`       // 创建队列后是否立即开始批量执行
+}
+
+// FofaPipelineAsset 流水线中一个去重后的资产
+type FofaPipelineAsset struct {
+	Host       string `json:"host"`
+	IP         string `json:"ip,omitempty"`
+	Port       string `json:"port,omitempty"`
+	Alive      *bool  `json:"alive,omitempty"`      // 仅 validateLive=true 时填充
+	IsHoneypot bool   `json:"isHoneypot,omitempty"` // FOFA 标记为蜜罐，或命中蜜罐特征启发式
+	IsFraud    bool   `json:"isFraud,omitempty"`    // FOFA 标记为仿冒/欺诈资产
+}
+
+// FofaPipelineResponse 流水线执行结果
+type FofaPipelineResponse struct {
+	Query            string              `json:"query"`
+	ParseWarnings    []string            `json:"parseWarnings,omitempty"`
+	TotalFound       int                 `json:"totalFound"`
+	Assets           []FofaPipelineAsset `json:"assets"`
+	HoneypotFiltered int                 `json:"honeypotFiltered,omitempty"` // 被判定为蜜罐/仿冒并从批量任务中排除的资产数（includeHoneypots=false 时）
+	QueueID          string              `json:"queueId,omitempty"`
+	Queue            *BatchTaskQueue     `json:"queue,omitempty"`
+	Started          bool                `json:"started"`
+}
+
+const defaultFofaPipelineTaskTemplate = "对资产 {{host}} 进行安全测试，识别可利用的漏洞并给出验证与修复建议"
+
+// FofaScanPipeline 一键式流水线：自然语言/查询语法 -> FOFA 搜索 -> 去重与存活校验 -> 创建批量任务队列 -> 可选立即执行
+func (h *AgentHandler) FofaScanPipeline(c *gin.Context) {
+	if h.fofaHandler == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "FOFA 功能未初始化"})
+		return
+	}
+
+	var req FofaPipelineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	query := strings.TrimSpace(req.Query)
+	var parseWarnings []string
+	if query == "" {
+		text := strings.TrimSpace(req.Text)
+		if text == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "text 与 query 至少填写一个"})
+			return
+		}
+		parsed, err := h.fofaHandler.parseNaturalLanguage(ctx, text)
+		if err != nil {
+			writeAPIError(c, err)
+			return
+		}
+		query = strings.TrimSpace(parsed.Query)
+		parseWarnings = parsed.Warnings
+		if query == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":    "自然语言未能解析出有效的 FOFA 查询语法，请补充关键条件后重试",
+				"warnings": parseWarnings,
+			})
+			return
+		}
+	}
+
+	searchResp, err := h.fofaHandler.search(ctx, fofaSearchRequest{
+		Query:  query,
+		Size:   req.Size,
+		Fields: "host,ip,port,domain,title,protocol,country,province,city,server,banner,is_honeypot,is_fraud",
+	})
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+
+	assets := dedupeFofaAssets(searchResp.Results)
+	if req.ValidateLive {
+		assets = probeFofaAssetsLiveness(ctx, assets)
+	}
+	h.persistFofaAssetsToInventory(assets)
+
+	resp := FofaPipelineResponse{
+		Query:         query,
+		ParseWarnings: parseWarnings,
+		TotalFound:    searchResp.Total,
+		Assets:        assets,
+	}
+
+	if len(assets) == 0 {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	template := strings.TrimSpace(req.TaskTemplate)
+	if template == "" {
+		template = defaultFofaPipelineTaskTemplate
+	}
+	taskMessages := make([]string, 0, len(assets))
+	for _, asset := range assets {
+		if req.ValidateLive && asset.Alive != nil && !*asset.Alive {
+			continue
+		}
+		if !req.IncludeHoneypots && (asset.IsHoneypot || asset.IsFraud) {
+			resp.HoneypotFiltered++
+			continue
+		}
+		taskMessages = append(taskMessages, strings.ReplaceAll(template, "{{host}}", asset.Host))
+	}
+	if len(taskMessages) == 0 {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = fmt.Sprintf("FOFA流水线: %s", query)
+	}
+	agentMode := normalizeBatchQueueAgentMode(req.AgentMode)
+
+	queue, err := h.batchTaskManager.CreateBatchQueue(title, req.Role, agentMode, "manual", "", nil, taskMessages)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp.QueueID = queue.ID
+	resp.Queue = queue
+
+	if req.ExecuteNow {
+		ok, startErr := h.startBatchQueueExecution(queue.ID, false)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "队列不存在"})
+			return
+		}
+		if startErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": startErr.Error(), "queueId": queue.ID})
+			return
+		}
+		resp.Started = true
+		if refreshed, exists := h.batchTaskManager.GetBatchQueue(queue.ID); exists {
+			resp.Queue = refreshed
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// persistFofaAssetsToInventory 将 FOFA 命中资产落库到资产台账（见 internal/database/asset.go），
+// 使其能通过 /api/assets 与 list_assets 工具查询到，而不仅仅是本次流水线的临时结果。蜜罐/仿冒资产
+// 一并落库（仅在创建批量任务时才被过滤），交由使用方自行按 IsHoneypot/IsFraud 甄别。
+func (h *AgentHandler) persistFofaAssetsToInventory(assets []FofaPipelineAsset) {
+	if h.db == nil {
+		return
+	}
+	for _, asset := range assets {
+		if asset.Host == "" {
+			continue
+		}
+		detail := ""
+		if asset.IsHoneypot {
+			detail = "疑似蜜罐"
+		}
+		if asset.IsFraud {
+			if detail != "" {
+				detail += "; "
+			}
+			detail += "疑似仿冒/欺诈资产"
+		}
+		if _, err := h.db.UpsertAsset("host", asset.Host, asset.Host, asset.Port, "", "fofa", "", detail); err != nil {
+			h.logger.Warn("落库 FOFA 资产失败", zap.String("host", asset.Host), zap.Error(err))
+		}
+	}
+}
+
+// dedupeFofaAssets 按 host（缺失时退化为 ip:port）对 FOFA 结果去重
+func dedupeFofaAssets(results []map[string]interface{}) []FofaPipelineAsset {
+	seen := make(map[string]struct{}, len(results))
+	assets := make([]FofaPipelineAsset, 0, len(results))
+	for _, row := range results {
+		host := stringField(row, "host")
+		ip := stringField(row, "ip")
+		port := stringField(row, "port")
+		if host == "" {
+			if ip == "" {
+				continue
+			}
+			if port != "" {
+				host = ip + ":" + port
+			} else {
+				host = ip
+			}
+		}
+		if _, ok := seen[host]; ok {
+			continue
+		}
+		seen[host] = struct{}{}
+		assets = append(assets, FofaPipelineAsset{
+			Host:       host,
+			IP:         ip,
+			Port:       port,
+			IsHoneypot: parseBoolish(stringField(row, "is_honeypot")) || looksLikeHoneypot(row),
+			IsFraud:    parseBoolish(stringField(row, "is_fraud")),
+		})
+	}
+	return assets
+}
+
+func stringField(row map[string]interface{}, key string) string {
+	v, ok := row[key]
+	if !ok || v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	return strings.TrimSpace(fmt.Sprintf("%v", v))
+}
+
+// parseBoolish 解析 FOFA 返回的类布尔字段（可能是 "true"/"false"、"1"/"0" 等字符串形式）
+func parseBoolish(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "1", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// honeypotSignatures 常见蜜罐软件的标题/Banner 特征词，用于在 FOFA 未显式标记 is_honeypot 时兜底识别
+var honeypotSignatures = []string{
+	"honeypot", "honey pot", "opencanary", "cowrie", "dionaea", "conpot", "t-pot", "glutton", "honeytrap",
+}
+
+// looksLikeHoneypot 基于标题/Banner 关键词的启发式判断，用于补充 FOFA 未显式标记的蜜罐资产
+func looksLikeHoneypot(row map[string]interface{}) bool {
+	haystack := strings.ToLower(stringField(row, "title") + " " + stringField(row, "banner") + " " + stringField(row, "server"))
+	if haystack == "" {
+		return false
+	}
+	for _, sig := range honeypotSignatures {
+		if strings.Contains(haystack, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeFofaAssetsLiveness 并发对每个资产做一次轻量 HTTP 探测，填充 Alive 字段
+func probeFofaAssetsLiveness(ctx context.Context, assets []FofaPipelineAsset) []FofaPipelineAsset {
+	const maxConcurrency = 20
+	sem := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	for i := range assets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(idx int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			alive := probeFofaAsset(ctx, client, assets[idx].Host)
+			assets[idx].Alive = &alive
+		}(i)
+	}
+	wg.Wait()
+	return assets
+}
+
+func probeFofaAsset(ctx context.Context, client *http.Client, host string) bool {
+	target := host
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(reqCtx, http.MethodHead, target, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(httpReq)
+	if err == nil {
+		resp.Body.Close()
+		return true
+	}
+
+	// 部分服务不支持 HEAD，回退为 GET 再确认一次
+	httpReq, err = http.NewRequestWithContext(reqCtx, http.MethodGet, target, nil)
+	if err != nil {
+		return false
+	}
+	resp, err = client.Do(httpReq)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
@@ -0,0 +1,77 @@
+package handler
+
+import "testing"
+
+func TestDedupeFofaAssets(t *testing.T) {
+	results := []map[string]interface{}{
+		{"host": "https://1.2.3.4:8443", "ip": "1.2.3.4", "port": "8443"},
+		{"host": "https://1.2.3.4:8443", "ip": "1.2.3.4", "port": "8443"}, // 重复
+		{"host": "", "ip": "5.6.7.8", "port": "80"},                       // 无 host，回退 ip:port
+		{"host": "", "ip": "", "port": "80"},                              // 无 host 也无 ip，丢弃
+	}
+
+	assets := dedupeFofaAssets(results)
+	if len(assets) != 2 {
+		t.Fatalf("期望去重后剩 2 个资产，实际 %d: %+v", len(assets), assets)
+	}
+	if assets[0].Host != "https://1.2.3.4:8443" {
+		t.Errorf("第一个资产 host 不符，实际 %q", assets[0].Host)
+	}
+	if assets[1].Host != "5.6.7.8:80" {
+		t.Errorf("第二个资产应回退为 ip:port，实际 %q", assets[1].Host)
+	}
+}
+
+func TestDedupeFofaAssets_HoneypotFlags(t *testing.T) {
+	results := []map[string]interface{}{
+		{"host": "1.2.3.4:22", "ip": "1.2.3.4", "port": "22", "is_honeypot": "true"},
+		{"host": "5.6.7.8:80", "ip": "5.6.7.8", "port": "80", "title": "Cowrie SSH Honeypot"},
+		{"host": "9.9.9.9:443", "ip": "9.9.9.9", "port": "443", "is_fraud": "1"},
+		{"host": "10.0.0.1:80", "ip": "10.0.0.1", "port": "80", "title": "Apache2 Ubuntu Default Page"},
+	}
+
+	assets := dedupeFofaAssets(results)
+	if len(assets) != 4 {
+		t.Fatalf("期望 4 个资产，实际 %d", len(assets))
+	}
+	if !assets[0].IsHoneypot {
+		t.Error("显式 is_honeypot=true 的资产应被标记为蜜罐")
+	}
+	if !assets[1].IsHoneypot {
+		t.Error("标题命中蜜罐特征词的资产应被启发式标记为蜜罐")
+	}
+	if !assets[2].IsFraud {
+		t.Error("显式 is_fraud=1 的资产应被标记为仿冒")
+	}
+	if assets[3].IsHoneypot || assets[3].IsFraud {
+		t.Error("正常资产不应被误标记为蜜罐/仿冒")
+	}
+}
+
+func TestParseBoolish(t *testing.T) {
+	cases := map[string]bool{
+		"true": true, "TRUE": true, "1": true, "yes": true,
+		"false": false, "0": false, "": false, "no": false,
+	}
+	for in, want := range cases {
+		if got := parseBoolish(in); got != want {
+			t.Errorf("parseBoolish(%q) = %v，期望 %v", in, got, want)
+		}
+	}
+}
+
+func TestStringField(t *testing.T) {
+	row := map[string]interface{}{"port": float64(443), "title": "示例", "missing": nil}
+	if got := stringField(row, "port"); got != "443" {
+		t.Errorf("数字字段应转为字符串，期望 443，实际 %q", got)
+	}
+	if got := stringField(row, "title"); got != "示例" {
+		t.Errorf("字符串字段应原样返回，实际 %q", got)
+	}
+	if got := stringField(row, "missing"); got != "" {
+		t.Errorf("缺失字段应返回空字符串，实际 %q", got)
+	}
+	if got := stringField(row, "absent-key"); got != "" {
+		t.Errorf("不存在的 key 应返回空字符串，实际 %q", got)
+	}
+}
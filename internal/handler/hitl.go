@@ -218,6 +218,18 @@ func (h *AgentHandler) hitlRequestWithMergedConfigWhitelist(req *HITLRequest) *H
 }
 
 func (m *HITLManager) shouldInterrupt(conversationID, toolName string) (hitlRuntimeConfig, bool) {
+	// exec 工具（任意 shell 命令执行）一律强制审批，不可通过免审批白名单跳过，也不可通过该会话从未开启
+	// 人机协同来绕过，与 security.SecurityConfig.ExecToolEnabled 共同构成"显式开启 + 每次审批"的双重门控。
+	normalizedName := strings.ToLower(strings.TrimSpace(toolName))
+	if normalizedName == "exec" {
+		m.mu.RLock()
+		cfg, ok := m.runtime[conversationID]
+		m.mu.RUnlock()
+		if !ok {
+			cfg = hitlRuntimeConfig{Enabled: true, Mode: normalizeHitlMode("")}
+		}
+		return cfg, true
+	}
 	m.mu.RLock()
 	cfg, ok := m.runtime[conversationID]
 	m.mu.RUnlock()
@@ -229,7 +241,7 @@ func (m *HITLManager) shouldInterrupt(conversationID, toolName string) (hitlRunt
 	if len(cfg.SensitiveTools) == 0 {
 		return cfg, true
 	}
-	_, inWhitelist := cfg.SensitiveTools[strings.ToLower(strings.TrimSpace(toolName))]
+	_, inWhitelist := cfg.SensitiveTools[normalizedName]
 	return cfg, !inWhitelist
 }
 
@@ -0,0 +1,35 @@
+package handler
+
+import "testing"
+
+func TestHITLManager_NeedsToolApproval_ExecAlwaysRequiresApproval(t *testing.T) {
+	m := NewHITLManager(nil, nil)
+	m.ActivateConversation("conv1", &HITLRequest{
+		Enabled:        true,
+		Mode:           "approval",
+		SensitiveTools: []string{"exec", "nmap"}, // 尝试把 exec 放进免审批白名单
+	})
+
+	if !m.NeedsToolApproval("conv1", "exec") {
+		t.Fatal("exec tool must always require approval, even when whitelisted")
+	}
+	if !m.NeedsToolApproval("conv1", "EXEC") {
+		t.Fatal("exec tool name matching must be case-insensitive")
+	}
+	if m.NeedsToolApproval("conv1", "nmap") {
+		t.Fatal("whitelisted non-exec tool should not require approval")
+	}
+	if !m.NeedsToolApproval("conv1", "httpx") {
+		t.Fatal("non-whitelisted tool should require approval")
+	}
+}
+
+func TestHITLManager_NeedsToolApproval_DisabledConversation(t *testing.T) {
+	m := NewHITLManager(nil, nil)
+	if !m.NeedsToolApproval("unknown-conv", "exec") {
+		t.Fatal("exec tool must require approval even for a conversation that never activated HITL")
+	}
+	if m.NeedsToolApproval("unknown-conv", "nmap") {
+		t.Fatal("conversation without HITL activation should not require approval for non-exec tools")
+	}
+}
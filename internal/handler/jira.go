@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"cyberstrike-ai/internal/database"
+	"cyberstrike-ai/internal/jira"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// JiraHandler 提供为漏洞创建/更新 Jira 工单的接口（POST /api/vulnerabilities/:id/ticket）。
+type JiraHandler struct {
+	db           *database.DB
+	logger       *zap.Logger
+	client       *jira.Client
+	projectKey   string
+	issueType    string
+	fieldMapping map[string]string
+}
+
+// NewJiraHandler 创建 JiraHandler；client 为 nil 表示未配置 base_url，接口会返回错误。
+func NewJiraHandler(db *database.DB, logger *zap.Logger, client *jira.Client, projectKey, issueType string, fieldMapping map[string]string) *JiraHandler {
+	return &JiraHandler{
+		db:           db,
+		logger:       logger,
+		client:       client,
+		projectKey:   projectKey,
+		issueType:    issueType,
+		fieldMapping: fieldMapping,
+	}
+}
+
+type jiraTicketRequest struct {
+	ProjectKey string `json:"project_key,omitempty"`
+	IssueType  string `json:"issue_type,omitempty"`
+}
+
+// buildIssueDescription 按漏洞字段拼装工单描述，格式与 vulnerability.go 的 Markdown 导出保持同一套字段顺序。
+func buildIssueDescription(v *database.Vulnerability) string {
+	desc := fmt.Sprintf("*目标*: %s\n*类型*: %s\n*严重程度*: %s\n\n*描述*:\n%s\n\n*影响*:\n%s\n\n*修复建议*:\n%s",
+		v.Target, v.Type, v.Severity, v.Description, v.Impact, v.Recommendation)
+	if v.CVSSVector != "" {
+		desc += fmt.Sprintf("\n\n*CVSS向量*: %s (评分: %.1f)", v.CVSSVector, v.CVSSScore)
+	}
+	return desc
+}
+
+// buildIssueExtraFields 按 fieldMapping 配置把内部字段映射为 Jira 自定义字段，仅映射已配置的字段。
+func (h *JiraHandler) buildIssueExtraFields(v *database.Vulnerability) map[string]interface{} {
+	values := map[string]string{
+		"severity": v.Severity,
+		"status":   v.Status,
+		"target":   v.Target,
+		"type":     v.Type,
+	}
+	extra := map[string]interface{}{}
+	for key, fieldID := range h.fieldMapping {
+		if value, ok := values[key]; ok && value != "" {
+			extra[fieldID] = value
+		}
+	}
+	return extra
+}
+
+// CreateOrUpdateTicket 为指定漏洞创建 Jira 工单（若已存在工单则改为更新），并附带证据附件。
+func (h *JiraHandler) CreateOrUpdateTicket(c *gin.Context) {
+	if h.client == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Jira 集成未配置 base_url，请检查 jira 配置"})
+		return
+	}
+
+	id := c.Param("id")
+	vuln, err := h.db.GetVulnerability(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req jiraTicketRequest
+	_ = c.ShouldBindJSON(&req)
+	projectKey := firstNonEmpty(req.ProjectKey, h.projectKey)
+	issueType := firstNonEmpty(req.IssueType, h.issueType)
+	if projectKey == "" || issueType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未指定 project_key/issue_type，且未配置默认值"})
+		return
+	}
+
+	summary := fmt.Sprintf("[%s] %s", vuln.Severity, vuln.Title)
+	description := buildIssueDescription(vuln)
+	extraFields := h.buildIssueExtraFields(vuln)
+
+	ctx := c.Request.Context()
+	issueKey := vuln.JiraIssueKey
+	if issueKey != "" {
+		if err := h.client.UpdateIssue(ctx, issueKey, summary, description, extraFields); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新Jira工单失败: %s", err.Error())})
+			return
+		}
+	} else {
+		issueKey, err = h.client.CreateIssue(ctx, projectKey, issueType, summary, description, extraFields)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建Jira工单失败: %s", err.Error())})
+			return
+		}
+		if err := h.db.UpdateVulnerabilityJiraIssueKey(vuln.ID, issueKey); err != nil {
+			h.logger.Warn("写回Jira工单编号失败", zap.String("vulnerabilityId", vuln.ID), zap.Error(err))
+		}
+	}
+
+	if vuln.Proof != "" {
+		if err := h.client.AddAttachment(ctx, issueKey, "evidence.txt", []byte(vuln.Proof)); err != nil {
+			h.logger.Warn("上传Jira证据附件失败", zap.String("issueKey", issueKey), zap.Error(err))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"vulnerability_id": vuln.ID,
+		"issue_key":        issueKey,
+	})
+}
@@ -4,15 +4,33 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"cyberstrike-ai/internal/database"
 	"cyberstrike-ai/internal/knowledge"
+	"cyberstrike-ai/internal/mcp"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
+// KnowledgeResourceURI 知识库条目集合对应的 MCP 资源 URI，供 resources/subscribe 订阅，
+// 条目增删改时通过 NotifyResourceUpdated 推送 notifications/resources/updated。
+const KnowledgeResourceURI = "knowledge://items"
+
+// KnowledgeItemResourcePrefix 单条知识库条目对应的 MCP 资源 URI 前缀，完整形式为
+// "knowledge://<category>/<title>"（分类与标题各自做 URL 转义，避免其中的 "/" 破坏路径结构）。
+const KnowledgeItemResourcePrefix = "knowledge://item/"
+
+// knowledgeItemResourceURI 构造单条知识项对应的资源 URI
+func knowledgeItemResourceURI(category, title string) string {
+	return KnowledgeItemResourcePrefix + url.PathEscape(category) + "/" + url.PathEscape(title)
+}
+
 // KnowledgeHandler 知识库处理器
 type KnowledgeHandler struct {
 	manager   *knowledge.Manager
@@ -20,6 +38,8 @@ type KnowledgeHandler struct {
 	indexer   *knowledge.Indexer
 	db        *database.DB
 	logger    *zap.Logger
+
+	mcpServer *mcp.Server // 可选，见 SetMCPServer；用于知识库条目变更时推送 MCP 资源更新通知
 }
 
 // NewKnowledgeHandler 创建新的知识库处理器
@@ -39,6 +59,67 @@ func NewKnowledgeHandler(
 	}
 }
 
+// SetMCPServer 设置 MCP 服务器引用：知识库条目创建/更新/删除后通过 KnowledgeResourceURI 推送
+// notifications/resources/updated 给订阅方，并将每条知识项注册为独立的 knowledge://item/... 资源，
+// 供外部 MCP 客户端通过 resources/list、resources/read 浏览知识库；未设置时静默跳过，不影响 REST API 本身。
+func (h *KnowledgeHandler) SetMCPServer(mcpServer *mcp.Server) {
+	h.mcpServer = mcpServer
+	mcpServer.RegisterResourceReader(KnowledgeItemResourcePrefix, h)
+	h.syncItemResources()
+}
+
+// notifyItemsChanged 在知识库条目发生增删改后调用：向订阅了 KnowledgeResourceURI 的 MCP 客户端推送更新通知，
+// 并重新同步逐条知识项资源列表（新增/重命名/删除都会改变 knowledge://item/... 的 URI 集合）。
+func (h *KnowledgeHandler) notifyItemsChanged() {
+	if h.mcpServer == nil {
+		return
+	}
+	h.mcpServer.NotifyResourceUpdated(KnowledgeResourceURI)
+	h.syncItemResources()
+}
+
+// syncItemResources 用当前所有知识项重建 knowledge://item/... 资源集合
+func (h *KnowledgeHandler) syncItemResources() {
+	items, _, err := h.manager.GetItemsSummary("", 0, 0)
+	if err != nil {
+		h.logger.Warn("同步知识库MCP资源失败", zap.Error(err))
+		return
+	}
+
+	h.mcpServer.ClearResourcesWithPrefix(KnowledgeItemResourcePrefix)
+	for _, item := range items {
+		h.mcpServer.RegisterResource(&mcp.Resource{
+			URI:         knowledgeItemResourceURI(item.Category, item.Title),
+			Name:        item.Title,
+			Description: fmt.Sprintf("知识库条目 [%s] %s", item.Category, item.Title),
+			MimeType:    "text/markdown",
+		})
+	}
+}
+
+// ReadResource 实现 mcp.ResourceReader：解析 knowledge://item/<category>/<title> 并返回条目的 markdown 内容。
+func (h *KnowledgeHandler) ReadResource(uri string) (string, error) {
+	rest := strings.TrimPrefix(uri, KnowledgeItemResourcePrefix)
+	parts := strings.SplitN(rest, "/", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("无效的知识库资源URI: %s", uri)
+	}
+	category, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("解析分类失败: %w", err)
+	}
+	title, err := url.PathUnescape(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("解析标题失败: %w", err)
+	}
+
+	item, err := h.manager.GetItemByCategoryTitle(category, title)
+	if err != nil {
+		return "", err
+	}
+	return item.Content, nil
+}
+
 // GetCategories 获取所有分类
 func (h *KnowledgeHandler) GetCategories(c *gin.Context) {
 	categories, err := h.manager.GetCategories()
@@ -55,6 +136,23 @@ func (h *KnowledgeHandler) GetCategories(c *gin.Context) {
 func (h *KnowledgeHandler) GetItems(c *gin.Context) {
 	category := c.Query("category")
 	searchKeyword := c.Query("search") // 搜索关键字
+	tagFilter := c.Query("tag")        // 按标签精确过滤
+
+	// 如果提供了标签过滤，按标签查询（与搜索关键字互斥，标签优先）
+	if tagFilter != "" {
+		items, err := h.manager.GetItemsByTag(tagFilter)
+		if err != nil {
+			h.logger.Error("按标签查询知识项失败", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"items": items,
+			"total": len(items),
+			"tag":   tagFilter,
+		})
+		return
+	}
 
 	// 如果提供了搜索关键字，执行关键字搜索（在所有数据中搜索）
 	if searchKeyword != "" {
@@ -232,9 +330,11 @@ func (h *KnowledgeHandler) GetItem(c *gin.Context) {
 // CreateItem 创建知识项
 func (h *KnowledgeHandler) CreateItem(c *gin.Context) {
 	var req struct {
-		Category string `json:"category" binding:"required"`
-		Title    string `json:"title" binding:"required"`
-		Content  string `json:"content" binding:"required"`
+		Category string            `json:"category" binding:"required"`
+		Title    string            `json:"title" binding:"required"`
+		Content  string            `json:"content" binding:"required"`
+		Tags     []string          `json:"tags,omitempty"`
+		Metadata map[string]string `json:"metadata,omitempty"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -242,7 +342,7 @@ func (h *KnowledgeHandler) CreateItem(c *gin.Context) {
 		return
 	}
 
-	item, err := h.manager.CreateItem(req.Category, req.Title, req.Content)
+	item, err := h.manager.CreateItemWithTags(req.Category, req.Title, req.Content, req.Tags, req.Metadata)
 	if err != nil {
 		h.logger.Error("创建知识项失败", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -257,6 +357,7 @@ func (h *KnowledgeHandler) CreateItem(c *gin.Context) {
 		}
 	}()
 
+	h.notifyItemsChanged()
 	c.JSON(http.StatusOK, item)
 }
 
@@ -268,6 +369,7 @@ func (h *KnowledgeHandler) UpdateItem(c *gin.Context) {
 		Category string `json:"category" binding:"required"`
 		Title    string `json:"title" binding:"required"`
 		Content  string `json:"content" binding:"required"`
+		Author   string `json:"author,omitempty"` // 可选：编辑者标识，用于版本历史审计
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -275,7 +377,7 @@ func (h *KnowledgeHandler) UpdateItem(c *gin.Context) {
 		return
 	}
 
-	item, err := h.manager.UpdateItem(id, req.Category, req.Title, req.Content)
+	item, err := h.manager.UpdateItem(id, req.Category, req.Title, req.Content, req.Author)
 	if err != nil {
 		h.logger.Error("更新知识项失败", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -290,6 +392,72 @@ func (h *KnowledgeHandler) UpdateItem(c *gin.Context) {
 		}
 	}()
 
+	h.notifyItemsChanged()
+	c.JSON(http.StatusOK, item)
+}
+
+// GetItemVersions 获取知识项的历史版本列表
+func (h *KnowledgeHandler) GetItemVersions(c *gin.Context) {
+	id := c.Param("id")
+
+	versions, err := h.manager.GetItemVersions(id)
+	if err != nil {
+		h.logger.Error("获取知识项历史版本失败", zap.String("itemId", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// DiffItemVersion 比较知识项某个历史版本与当前内容的差异
+func (h *KnowledgeHandler) DiffItemVersion(c *gin.Context) {
+	id := c.Param("id")
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "version 必须为整数"})
+		return
+	}
+
+	diff, err := h.manager.DiffItemVersion(id, version)
+	if err != nil {
+		h.logger.Error("比较知识项历史版本失败", zap.String("itemId", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"diff": diff})
+}
+
+// RestoreItemVersion 将知识项回滚到指定历史版本（当前内容会先被存为新版本，不会丢失）
+func (h *KnowledgeHandler) RestoreItemVersion(c *gin.Context) {
+	id := c.Param("id")
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "version 必须为整数"})
+		return
+	}
+
+	var req struct {
+		Author string `json:"author,omitempty"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	item, err := h.manager.RestoreItemVersion(id, version, req.Author)
+	if err != nil {
+		h.logger.Error("回滚知识项版本失败", zap.String("itemId", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	go func() {
+		ctx := context.Background()
+		if err := h.indexer.IndexItem(ctx, item.ID); err != nil {
+			h.logger.Warn("重新索引知识项失败", zap.String("itemId", item.ID), zap.Error(err))
+		}
+	}()
+
+	h.notifyItemsChanged()
 	c.JSON(http.StatusOK, item)
 }
 
@@ -303,6 +471,7 @@ func (h *KnowledgeHandler) DeleteItem(c *gin.Context) {
 		return
 	}
 
+	h.notifyItemsChanged()
 	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
 }
 
@@ -493,6 +662,92 @@ func (h *KnowledgeHandler) Search(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"results": results})
 }
 
+// ToolParameterSuggestionRequest 工具参数建议请求：手动测试表单填好工具和目标后，向知识库要推荐参数。
+type ToolParameterSuggestionRequest struct {
+	Tool   string `json:"tool" binding:"required"`
+	Target string `json:"target,omitempty"`
+}
+
+// ToolParameterSuggestion 单条知识片段及从中提取出的可直接套用的命令行预设。
+type ToolParameterSuggestion struct {
+	ItemID     string   `json:"itemId"`
+	Title      string   `json:"title"`
+	Snippet    string   `json:"snippet"`
+	Similarity float64  `json:"similarity"`
+	Presets    []string `json:"presets,omitempty"` // 从知识片段代码块中提取的现成命令，target 会替换为实际目标
+}
+
+// codeBlockPattern 匹配 Markdown 围栏代码块（```lang\n...\n```），知识库条目里的现成命令大多以此形式记录。
+var codeBlockPattern = regexp.MustCompile("(?s)```[a-zA-Z0-9]*\\n(.*?)```")
+
+// extractCommandPresets 从知识片段中提取围栏代码块作为参数预设，target 非空时替换掉常见的占位符（<target>、<url> 等）。
+func extractCommandPresets(text, target string) []string {
+	matches := codeBlockPattern.FindAllStringSubmatch(text, -1)
+	presets := make([]string, 0, len(matches))
+	placeholderPattern := regexp.MustCompile(`(?i)<(target|url|host|ip|domain)>`)
+	for _, m := range matches {
+		block := strings.TrimSpace(m[1])
+		if block == "" {
+			continue
+		}
+		if target != "" {
+			block = placeholderPattern.ReplaceAllString(block, target)
+		}
+		presets = append(presets, block)
+	}
+	return presets
+}
+
+// SuggestToolParameters 结合知识库检索为手动工具测试表单提供参数建议（如推荐的 nuclei tags、sqlmap 参数组合等）。
+func (h *KnowledgeHandler) SuggestToolParameters(c *gin.Context) {
+	var req ToolParameterSuggestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tool := strings.TrimSpace(req.Tool)
+	if tool == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tool is required"})
+		return
+	}
+
+	query := tool
+	target := strings.TrimSpace(req.Target)
+	if target != "" {
+		query = fmt.Sprintf("%s %s", tool, target)
+	}
+
+	results, err := h.retriever.Search(c.Request.Context(), &knowledge.SearchRequest{
+		Query: query,
+		TopK:  5,
+	})
+	if err != nil {
+		h.logger.Error("检索工具参数建议失败", zap.String("tool", tool), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	suggestions := make([]ToolParameterSuggestion, 0, len(results))
+	for _, r := range results {
+		if r.Chunk == nil {
+			continue
+		}
+		suggestion := ToolParameterSuggestion{
+			Snippet:    r.Chunk.ChunkText,
+			Similarity: r.Similarity,
+			Presets:    extractCommandPresets(r.Chunk.ChunkText, target),
+		}
+		if r.Item != nil {
+			suggestion.ItemID = r.Item.ID
+			suggestion.Title = r.Item.Title
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tool": tool, "target": target, "suggestions": suggestions})
+}
+
 // GetStats 获取知识库统计信息
 func (h *KnowledgeHandler) GetStats(c *gin.Context) {
 	totalCategories, totalItems, err := h.manager.GetStats()
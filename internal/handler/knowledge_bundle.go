@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ExportBundle 把知识库全部条目（含内容和已生成的向量）导出为 tar.gz，供离线分享给团队
+// 或迁移到另一套部署使用。
+func (h *KnowledgeHandler) ExportBundle(c *gin.Context) {
+	data, err := h.manager.ExportBundle()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("knowledge-bundle-%s.tar.gz", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "application/gzip", data)
+}
+
+// ImportBundle 导入 ExportBundle 产出的 tar.gz 包：按原 ID 写回知识项，overwrite 表单字段为
+// "true" 时覆盖本地已存在的同 ID 条目，否则跳过。包内没有随附向量的知识项会被异步排队重新索引。
+func (h *KnowledgeHandler) ImportBundle(c *gin.Context) {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "必须上传 file 字段（ExportBundle 导出的 tar.gz 包）"})
+		return
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("打开上传文件失败: %v", err)})
+		return
+	}
+	defer f.Close()
+
+	data, err := readLimited(f, importMaxBodyBytes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	overwrite := c.PostForm("overwrite") == "true"
+	imported, skipped, needsIndex, err := h.manager.ImportBundle(data, overwrite)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, id := range needsIndex {
+		itemID := id
+		go func() {
+			if err := h.indexer.IndexItem(context.Background(), itemID); err != nil {
+				h.logger.Warn("索引导入的知识项失败", zap.String("itemId", itemID), zap.Error(err))
+			}
+		}()
+	}
+
+	h.notifyItemsChanged()
+	c.JSON(http.StatusOK, gin.H{"imported": imported, "skipped": skipped, "queued_for_indexing": len(needsIndex)})
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cyberstrike-ai/internal/knowledge"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// importMaxBodyBytes 限制单次导入源（上传文件或 URL 响应体）的大小，避免超大 PDF/网页把内存打爆。
+const importMaxBodyBytes = 20 * 1024 * 1024 // 20MB
+
+// importedItemInfo 单条导入结果，返回给前端展示。
+type importedItemInfo struct {
+	ID       string `json:"id"`
+	Category string `json:"category"`
+	Title    string `json:"title"`
+}
+
+// ImportKnowledge 从文件上传或 URL 导入知识库条目：PDF/HTML 先转成 Markdown，再按标题切分成多条知识项，
+// 写入 basePath 下对应分类目录并异步排队索引（与 CreateItem 的索引方式一致）。
+// 请求为 multipart/form-data：file（可选，二选一）+ url（可选，二选一）+ category（可选，默认 "imported"）。
+func (h *KnowledgeHandler) ImportKnowledge(c *gin.Context) {
+	category := strings.TrimSpace(c.PostForm("category"))
+	if category == "" {
+		category = "imported"
+	}
+
+	fh, fileErr := c.FormFile("file")
+	sourceURL := strings.TrimSpace(c.PostForm("url"))
+
+	var (
+		raw          []byte
+		defaultTitle string
+		kind         string // "pdf" | "html" | "text"
+	)
+
+	switch {
+	case fileErr == nil && fh != nil:
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("打开上传文件失败: %v", err)})
+			return
+		}
+		defer f.Close()
+
+		data, err := readLimited(f, importMaxBodyBytes)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		raw = data
+		defaultTitle = strings.TrimSuffix(filepath.Base(fh.Filename), filepath.Ext(fh.Filename))
+		kind = importKindFromExt(fh.Filename)
+
+	case sourceURL != "":
+		data, contentType, err := fetchImportURL(c.Request.Context(), sourceURL)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		raw = data
+		defaultTitle = strings.TrimSuffix(filepath.Base(sourceURL), filepath.Ext(sourceURL))
+		if defaultTitle == "" || defaultTitle == "." || defaultTitle == "/" {
+			defaultTitle = sourceURL
+		}
+		kind = importKindFromContentType(contentType, sourceURL)
+
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "必须提供 file 或 url 之一"})
+		return
+	}
+
+	markdown, err := convertImportSource(raw, kind)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	sections := knowledge.SplitMarkdownByHeading(markdown, defaultTitle)
+	if len(sections) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "未能从导入源提取到任何内容"})
+		return
+	}
+
+	created := make([]importedItemInfo, 0, len(sections))
+	for _, sec := range sections {
+		item, err := h.manager.CreateItem(category, sec.Title, sec.Content)
+		if err != nil {
+			h.logger.Warn("写入导入的知识项失败", zap.String("title", sec.Title), zap.Error(err))
+			continue
+		}
+		created = append(created, importedItemInfo{ID: item.ID, Category: item.Category, Title: item.Title})
+
+		itemID := item.ID
+		go func() {
+			ctx := context.Background()
+			if err := h.indexer.IndexItem(ctx, itemID); err != nil {
+				h.logger.Warn("索引导入的知识项失败", zap.String("itemId", itemID), zap.Error(err))
+			}
+		}()
+	}
+
+	if len(created) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "所有切分出的知识项均写入失败"})
+		return
+	}
+
+	h.notifyItemsChanged()
+	c.JSON(http.StatusOK, gin.H{"items": created, "count": len(created)})
+}
+
+// readLimited 读取最多 limit+1 字节，超出时返回明确错误，避免一次性 io.ReadAll 撑爆内存。
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取内容失败: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("内容超过大小上限（%d MB）", limit/1024/1024)
+	}
+	return data, nil
+}
+
+// fetchImportURL 抓取导入源 URL；限定 http/https，带超时与大小上限。
+// 本功能面向已登录的分析人员主动指定的导入地址，与 agent 工具箱里面向不可信目标的抓取场景不同，
+// 因此这里只做基础的 scheme 校验，不额外做内网 IP 黑名单。
+func fetchImportURL(ctx context.Context, rawURL string) (data []byte, contentType string, err error) {
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		return nil, "", fmt.Errorf("仅支持 http/https URL")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("构造请求失败: %w", err)
+	}
+	req.Header.Set("User-Agent", "CyberStrikeAI-KnowledgeImporter/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("请求 URL 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("请求 URL 返回状态码 %d", resp.StatusCode)
+	}
+
+	body, err := readLimited(resp.Body, importMaxBodyBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// importKindFromExt 按文件扩展名判断导入源类型。
+func importKindFromExt(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".pdf":
+		return "pdf"
+	case ".html", ".htm":
+		return "html"
+	default:
+		return "text"
+	}
+}
+
+// importKindFromContentType 优先按响应 Content-Type 判断，缺失或不明确时回退到 URL 扩展名。
+func importKindFromContentType(contentType, rawURL string) string {
+	ct := strings.ToLower(contentType)
+	switch {
+	case strings.Contains(ct, "pdf"):
+		return "pdf"
+	case strings.Contains(ct, "html"):
+		return "html"
+	case strings.Contains(ct, "text/") || strings.Contains(ct, "markdown"):
+		return "text"
+	default:
+		return importKindFromExt(rawURL)
+	}
+}
+
+// convertImportSource 把原始字节按类型转成 Markdown：PDF 走 knowledge.PDFToText，HTML 走
+// knowledge.HTMLToMarkdown，纯文本/Markdown 原样返回。
+func convertImportSource(raw []byte, kind string) (string, error) {
+	switch kind {
+	case "pdf":
+		text, err := knowledge.PDFToText(raw)
+		if err != nil {
+			return "", err
+		}
+		return text, nil
+	case "html":
+		md, err := knowledge.HTMLToMarkdown(string(raw))
+		if err != nil {
+			return "", err
+		}
+		return md, nil
+	default:
+		return string(raw), nil
+	}
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strconv"
@@ -18,11 +19,19 @@ import (
 
 // MonitorHandler 监控处理器
 type MonitorHandler struct {
-	mcpServer      *mcp.Server
-	externalMCPMgr *mcp.ExternalMCPManager
-	executor       *security.Executor
-	db             *database.DB
-	logger         *zap.Logger
+	mcpServer          *mcp.Server
+	externalMCPMgr     *mcp.ExternalMCPManager
+	executor           *security.Executor
+	db                 *database.DB
+	logger             *zap.Logger
+	artifactStorage    security.ArtifactStorage // 产出文件存储，见 SetArtifactStorage；为 nil 时 GetExecutionArtifacts 返回 404
+	agentTaskCanceller AgentTaskCanceller       // 见 SetAgentTaskCanceller；为 nil 时 EmergencyStop 不取消 Agent 任务，仅暂停工具执行队列
+}
+
+// AgentTaskCanceller 由 AgentHandler 实现，供 EmergencyStop 全局紧急停止时取消所有正在运行的 Agent 任务，
+// 单独声明为接口以避免 MonitorHandler 直接依赖 AgentHandler 的完整定义。
+type AgentTaskCanceller interface {
+	CancelAllAgentTasks() []string
 }
 
 // NewMonitorHandler 创建新的监控处理器
@@ -41,6 +50,17 @@ func (h *MonitorHandler) SetExternalMCPManager(mgr *mcp.ExternalMCPManager) {
 	h.externalMCPMgr = mgr
 }
 
+// SetArtifactStorage 设置产出文件存储，之后 GetExecutionArtifacts 才能列出/下载工具产出文件
+func (h *MonitorHandler) SetArtifactStorage(storage security.ArtifactStorage) {
+	h.artifactStorage = storage
+}
+
+// SetAgentTaskCanceller 设置 Agent 任务取消器（通常是 AgentHandler），之后 EmergencyStop 才会一并
+// 取消所有正在运行的 Agent 任务，而不只是暂停工具执行队列
+func (h *MonitorHandler) SetAgentTaskCanceller(canceller AgentTaskCanceller) {
+	h.agentTaskCanceller = canceller
+}
+
 // MonitorResponse 监控响应
 type MonitorResponse struct {
 	Executions []*mcp.ToolExecution      `json:"executions"`
@@ -248,6 +268,93 @@ func (h *MonitorHandler) GetExecution(c *gin.Context) {
 	c.JSON(http.StatusNotFound, gin.H{"error": "执行记录未找到"})
 }
 
+// GetExecutionArtifacts 列出/下载某次执行的产出文件（见 config.ToolConfig.OutputArtifacts）。
+// 不带 file 查询参数时返回该执行下所有产出文件的列表；带 file 参数时以附件形式下载对应文件。
+func (h *MonitorHandler) GetExecutionArtifacts(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "执行记录ID不能为空"})
+		return
+	}
+	if h.artifactStorage == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "产出文件存储未启用"})
+		return
+	}
+
+	if filename := c.Query("file"); filename != "" {
+		path, err := h.artifactStorage.ArtifactPath(id, filename)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.FileAttachment(path, filename)
+		return
+	}
+
+	artifacts, err := h.artifactStorage.ListArtifacts(id)
+	if err != nil {
+		h.logger.Error("列出产出文件失败", zap.Error(err), zap.String("executionId", id))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "列出产出文件失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"executionId": id, "artifacts": artifacts})
+}
+
+// RevealExecutionArguments 揭示执行记录的原始（未掩码）参数，用于查看 Cookie、Token 等敏感字段的真实值。
+// 每次揭示都会写入审计日志（执行ID、工具名、调用方地址、时间），供事后追溯。
+func (h *MonitorHandler) RevealExecutionArguments(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "执行记录ID不能为空"})
+		return
+	}
+
+	// 先从内部MCP服务器查找（进程内运行的执行记录持有真实参数）
+	if exec, exists := h.mcpServer.GetExecution(id); exists {
+		h.auditReveal(c, id, exec.ToolName)
+		c.JSON(http.StatusOK, gin.H{"executionId": id, "toolName": exec.ToolName, "arguments": exec.RawArguments})
+		return
+	}
+
+	// 再从外部MCP管理器查找
+	if h.externalMCPMgr != nil {
+		if exec, exists := h.externalMCPMgr.GetExecution(id); exists {
+			h.auditReveal(c, id, exec.ToolName)
+			c.JSON(http.StatusOK, gin.H{"executionId": id, "toolName": exec.ToolName, "arguments": exec.RawArguments})
+			return
+		}
+	}
+
+	// 最后从数据库查找已持久化的原始参数
+	if h.db != nil {
+		exec, err := h.db.GetToolExecution(id)
+		if err == nil && exec != nil {
+			rawArgs, err := h.db.RevealToolExecutionArguments(id)
+			if err != nil {
+				h.logger.Error("读取原始执行参数失败", zap.Error(err), zap.String("executionId", id))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "读取原始执行参数失败: " + err.Error()})
+				return
+			}
+			h.auditReveal(c, id, exec.ToolName)
+			c.JSON(http.StatusOK, gin.H{"executionId": id, "toolName": exec.ToolName, "arguments": rawArgs})
+			return
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "执行记录未找到"})
+}
+
+// auditReveal 记录一次敏感参数揭示操作，仅记录日志，不影响响应结果
+func (h *MonitorHandler) auditReveal(c *gin.Context, executionID, toolName string) {
+	h.logger.Warn("敏感参数已被揭示", zap.String("executionId", executionID), zap.String("toolName", toolName), zap.String("remoteAddr", c.ClientIP()))
+	if h.db != nil {
+		if err := h.db.LogSensitiveReveal(executionID, toolName, c.ClientIP()); err != nil {
+			h.logger.Warn("记录敏感参数揭示审计日志失败", zap.Error(err), zap.String("executionId", executionID))
+		}
+	}
+}
+
 // CancelExecution 手动取消进行中的 MCP 工具调用（仅取消该次 tools/call 的上下文，不停止整条 Agent / 迭代任务）
 // 请求体可选 JSON：{ "note": "用户说明" }，将与工具已返回输出合并交给模型（含「用户终止说明」标题块，与命令行原文区分）。
 func (h *MonitorHandler) CancelExecution(c *gin.Context) {
@@ -279,6 +386,54 @@ func (h *MonitorHandler) CancelExecution(c *gin.Context) {
 	c.JSON(http.StatusNotFound, gin.H{"error": "未找到进行中的工具执行，或该任务已结束"})
 }
 
+// EmergencyStop 全局紧急停止（"一键熔断"）：暂停工具执行队列（拒绝一切新的工具调用），取消所有
+// 正在运行的 Agent 任务，并向所有进行中的内部/外部 MCP 工具执行发送取消信号（进程组级别 kill，
+// 见 security.applyProcessGroup），用于扫描误伤到范围外资产时立即止损。需显式调用 EmergencyResume
+// 才能恢复接受新的工具调用。
+func (h *MonitorHandler) EmergencyStop(c *gin.Context) {
+	h.executor.Pause()
+
+	var cancelledConversations []string
+	if h.agentTaskCanceller != nil {
+		cancelledConversations = h.agentTaskCanceller.CancelAllAgentTasks()
+	}
+
+	cancelledInternal := h.mcpServer.CancelAllToolExecutions()
+	cancelledExternal := 0
+	if h.externalMCPMgr != nil {
+		cancelledExternal = h.externalMCPMgr.CancelAllToolExecutions()
+	}
+
+	h.logger.Warn("已触发全局紧急停止",
+		zap.Int("cancelledConversations", len(cancelledConversations)),
+		zap.Int("cancelledInternalExecutions", cancelledInternal),
+		zap.Int("cancelledExternalExecutions", cancelledExternal),
+	)
+	if h.db != nil {
+		_ = h.db.RecordAudit("", "emergency_stop", "",
+			fmt.Sprintf("cancelledConversations=%d cancelledInternal=%d cancelledExternal=%d", len(cancelledConversations), cancelledInternal, cancelledExternal),
+			c.ClientIP())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":                 "paused",
+		"message":                "已暂停执行队列并取消所有进行中的任务/工具调用，需调用 /api/monitor/emergency-resume 才能恢复",
+		"cancelledConversations": cancelledConversations,
+		"cancelledInternalCount": cancelledInternal,
+		"cancelledExternalCount": cancelledExternal,
+	})
+}
+
+// EmergencyResume 解除 EmergencyStop 触发的全局紧急停止，恢复执行队列接受新的工具调用。
+func (h *MonitorHandler) EmergencyResume(c *gin.Context) {
+	h.executor.Resume()
+	h.logger.Info("已解除全局紧急停止，恢复执行队列")
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "resumed",
+		"message": "执行队列已恢复，可继续接受新的工具调用",
+	})
+}
+
 // BatchGetToolNames 批量获取工具执行的工具名称（消除前端 N+1 请求）
 func (h *MonitorHandler) BatchGetToolNames(c *gin.Context) {
 	var req struct {
@@ -320,6 +475,15 @@ func (h *MonitorHandler) GetStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetDBQueryStats 返回按调用位置聚合的 SQL 查询耗时统计，用于定位高频/慢查询以指导优化（如加索引、批量化）。
+func (h *MonitorHandler) GetDBQueryStats(c *gin.Context) {
+	if h.db == nil {
+		c.JSON(http.StatusOK, gin.H{"stats": []database.QueryStatSnapshot{}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": h.db.QueryStats()})
+}
+
 // DeleteExecution 删除执行记录
 func (h *MonitorHandler) DeleteExecution(c *gin.Context) {
 	id := c.Param("id")
@@ -1665,6 +1665,18 @@ func (h *OpenAPIHandler) GetOpenAPISpec(c *gin.Context) {
 					},
 				},
 			},
+			"/api/agent-loop/ws": map[string]interface{}{
+				"get": map[string]interface{}{
+					"tags":        []string{"对话交互"},
+					"summary":     "WebSocket 版 Agent Loop",
+					"description": "与 `POST /api/agent-loop/stream` 等价的 WebSocket 版本：单连接内既接收与 SSE 完全一致的事件帧（\"data: {...}\\n\\n\"），也可发送控制帧双向交互，避免为跟进消息/取消/审批分别发起 HTTP 请求，对缓冲 SSE 的反向代理更友好。客户端下行帧: `{\"type\":\"message\",\"conversationId\":\"...\",\"message\":\"...\"}` 发送/跟进消息；`{\"type\":\"cancel\",\"conversationId\":\"...\"}` 取消任务；`{\"type\":\"interrupt_continue\",\"conversationId\":\"...\",\"reason\":\"...\"}` 中断并说明继续；`{\"type\":\"hitl_decision\",\"interruptId\":\"...\",\"decision\":\"approve|reject\"}` 审批工具调用。",
+					"operationId": "agentLoopWS",
+					"responses": map[string]interface{}{
+						"101": map[string]interface{}{"description": "WebSocket连接已建立"},
+						"401": map[string]interface{}{"description": "未授权"},
+					},
+				},
+			},
 			"/api/agent-loop/cancel": map[string]interface{}{
 				"post": map[string]interface{}{
 					"tags":        []string{"对话交互"},
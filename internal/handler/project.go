@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"net/http"
+
+	"cyberstrike-ai/internal/database"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ProjectHandler 项目/交战处理器：多目标渗透测试项目的 CRUD 及项目级对话/漏洞聚合
+type ProjectHandler struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+// NewProjectHandler 创建新的项目处理器
+func NewProjectHandler(db *database.DB, logger *zap.Logger) *ProjectHandler {
+	return &ProjectHandler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateProjectRequest 创建项目请求
+type CreateProjectRequest struct {
+	Name      string `json:"name"`
+	Client    string `json:"client"`
+	Scope     string `json:"scope"`
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+}
+
+// CreateProject 创建项目
+func (h *ProjectHandler) CreateProject(c *gin.Context) {
+	var req CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "项目名称不能为空"})
+		return
+	}
+
+	project, err := h.db.CreateProject(req.Name, req.Client, req.Scope, req.StartDate, req.EndDate)
+	if err != nil {
+		h.logger.Error("创建项目失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// ListProjects 列出所有项目
+func (h *ProjectHandler) ListProjects(c *gin.Context) {
+	projects, err := h.db.ListProjects()
+	if err != nil {
+		h.logger.Error("获取项目列表失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, projects)
+}
+
+// GetProject 获取项目
+func (h *ProjectHandler) GetProject(c *gin.Context) {
+	id := c.Param("id")
+
+	project, err := h.db.GetProject(id)
+	if err != nil {
+		h.logger.Error("获取项目失败", zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "项目不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// UpdateProjectRequest 更新项目请求
+type UpdateProjectRequest struct {
+	Name      string `json:"name"`
+	Client    string `json:"client"`
+	Scope     string `json:"scope"`
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+}
+
+// UpdateProject 更新项目
+func (h *ProjectHandler) UpdateProject(c *gin.Context) {
+	id := c.Param("id")
+
+	var req UpdateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "项目名称不能为空"})
+		return
+	}
+
+	if err := h.db.UpdateProject(id, req.Name, req.Client, req.Scope, req.StartDate, req.EndDate); err != nil {
+		h.logger.Error("更新项目失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.db.GetProject(id)
+	if err != nil {
+		h.logger.Error("获取更新后的项目失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// DeleteProject 删除项目；归属该项目的对话不会被删除，仅解除 project_id 关联
+func (h *ProjectHandler) DeleteProject(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.db.DeleteProject(id); err != nil {
+		h.logger.Error("删除项目失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// AssignConversationToProjectRequest 变更对话项目归属请求
+type AssignConversationToProjectRequest struct {
+	ProjectID string `json:"projectId"`
+}
+
+// AssignConversationToProject 将对话归属到项目；ProjectID 传空字符串表示解除归属
+// PUT /api/conversations/:id/project
+func (h *ProjectHandler) AssignConversationToProject(c *gin.Context) {
+	conversationID := c.Param("id")
+
+	var req AssignConversationToProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.AssignConversationToProject(conversationID, req.ProjectID); err != nil {
+		h.logger.Error("更新对话项目归属失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "更新成功"})
+}
+
+// GetProjectConversations 获取项目下的所有对话
+// GET /api/projects/:id/conversations
+func (h *ProjectHandler) GetProjectConversations(c *gin.Context) {
+	id := c.Param("id")
+
+	conversations, err := h.db.GetProjectConversations(id)
+	if err != nil {
+		h.logger.Error("获取项目对话失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, conversations)
+}
+
+// GetProjectVulnerabilities 获取项目下所有对话汇总的漏洞
+// GET /api/projects/:id/vulnerabilities
+func (h *ProjectHandler) GetProjectVulnerabilities(c *gin.Context) {
+	id := c.Param("id")
+
+	vulns, err := h.db.GetProjectVulnerabilities(id)
+	if err != nil {
+		h.logger.Error("获取项目漏洞失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, vulns)
+}
+
+// GetProjectReport 汇总项目下所有对话的报告要素：对话列表 + 漏洞列表 + 按严重程度统计，供前端拼装
+// 项目级报告导出（本仓库的报告本身是按会话即时生成的 Markdown/HTML/PDF，见 ReportHandler，
+// 项目级报告在此基础上先聚合出跨会话的素材，再由前端/后续版本决定拼装样式）
+// GET /api/projects/:id/report
+func (h *ProjectHandler) GetProjectReport(c *gin.Context) {
+	id := c.Param("id")
+
+	project, err := h.db.GetProject(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "项目不存在"})
+		return
+	}
+
+	conversations, err := h.db.GetProjectConversations(id)
+	if err != nil {
+		h.logger.Error("获取项目对话失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	vulns, err := h.db.GetProjectVulnerabilities(id)
+	if err != nil {
+		h.logger.Error("获取项目漏洞失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := h.db.GetProjectVulnerabilityStats(id)
+	if err != nil {
+		h.logger.Error("获取项目漏洞统计失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"project":            project,
+		"conversations":      conversations,
+		"vulnerabilities":    vulns,
+		"vulnerabilityStats": stats,
+	})
+}
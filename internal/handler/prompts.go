@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"cyberstrike-ai/internal/config"
+	"cyberstrike-ai/internal/mcp"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// PromptsHandler MCP 提示词模板处理器：管理 PromptsDir 下的 YAML 定义文件，
+// 每次增删改都会同步到运行中的 mcp.Server（RegisterPrompt/RemovePrompt），无需重启即可生效。
+type PromptsHandler struct {
+	config     *config.Config
+	configPath string
+	mcpServer  *mcp.Server
+	logger     *zap.Logger
+}
+
+// NewPromptsHandler 创建新的提示词处理器
+func NewPromptsHandler(cfg *config.Config, configPath string, mcpServer *mcp.Server, logger *zap.Logger) *PromptsHandler {
+	return &PromptsHandler{
+		config:     cfg,
+		configPath: configPath,
+		mcpServer:  mcpServer,
+		logger:     logger,
+	}
+}
+
+func (h *PromptsHandler) promptsRootAbs() string {
+	promptsDir := h.config.PromptsDir
+	if promptsDir == "" {
+		promptsDir = "prompts"
+	}
+	configDir := filepath.Dir(h.configPath)
+	if !filepath.IsAbs(promptsDir) {
+		promptsDir = filepath.Join(configDir, promptsDir)
+	}
+	return promptsDir
+}
+
+func (h *PromptsHandler) promptFilePath(name string) string {
+	return filepath.Join(h.promptsRootAbs(), sanitizeFileName(name)+".yaml")
+}
+
+// GetPrompts 获取所有提示词模板
+func (h *PromptsHandler) GetPrompts(c *gin.Context) {
+	prompts, errs := mcp.LoadPromptsFromDir(h.promptsRootAbs())
+	for _, err := range errs {
+		h.logger.Warn("加载提示词模板失败", zap.Error(err))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"prompts": prompts,
+	})
+}
+
+// GetPrompt 获取单个提示词模板
+func (h *PromptsHandler) GetPrompt(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "提示词名称不能为空"})
+		return
+	}
+
+	prompt, err := mcp.LoadPromptFromFile(h.promptFilePath(name))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "提示词不存在: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"prompt": prompt,
+	})
+}
+
+// promptRequest CreatePrompt/UpdatePrompt 的请求体，字段与 mcp.PromptFileConfig 保持一致
+type promptRequest struct {
+	Name        string               `json:"name" binding:"required"`
+	Description string               `json:"description"`
+	Role        string               `json:"role"`
+	Template    string               `json:"template" binding:"required"`
+	Arguments   []mcp.PromptArgument `json:"arguments"`
+}
+
+func (h *PromptsHandler) savePromptFile(req promptRequest) error {
+	promptsDir := h.promptsRootAbs()
+	if err := os.MkdirAll(promptsDir, 0755); err != nil {
+		return fmt.Errorf("创建提示词目录失败: %w", err)
+	}
+
+	fileCfg := mcp.PromptFileConfig{
+		Name:        req.Name,
+		Description: req.Description,
+		Role:        req.Role,
+		Template:    req.Template,
+		Arguments:   req.Arguments,
+	}
+	data, err := yaml.Marshal(&fileCfg)
+	if err != nil {
+		return fmt.Errorf("序列化提示词配置失败: %w", err)
+	}
+
+	return os.WriteFile(h.promptFilePath(req.Name), data, 0644)
+}
+
+// registerPromptFile 从磁盘重新加载指定文件并注册到 mcpServer，供创建/更新后调用
+func (h *PromptsHandler) registerPromptFile(name string) error {
+	prompt, err := mcp.LoadPromptFromFile(h.promptFilePath(name))
+	if err != nil {
+		return err
+	}
+	h.mcpServer.RegisterPrompt(prompt)
+	return nil
+}
+
+// CreatePrompt 创建新的提示词模板
+func (h *PromptsHandler) CreatePrompt(c *gin.Context) {
+	var req promptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	if _, err := os.Stat(h.promptFilePath(req.Name)); err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "提示词已存在"})
+		return
+	}
+
+	if err := h.savePromptFile(req); err != nil {
+		h.logger.Error("保存提示词配置失败", zap.String("name", req.Name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存提示词配置失败: " + err.Error()})
+		return
+	}
+	if err := h.registerPromptFile(req.Name); err != nil {
+		h.logger.Warn("注册提示词到MCP服务器失败", zap.String("name", req.Name), zap.Error(err))
+	}
+
+	h.logger.Info("创建提示词模板", zap.String("name", req.Name))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "提示词已创建",
+	})
+}
+
+// UpdatePrompt 更新提示词模板
+func (h *PromptsHandler) UpdatePrompt(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "提示词名称不能为空"})
+		return
+	}
+
+	var req promptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+	if req.Name == "" {
+		req.Name = name
+	}
+
+	if _, err := os.Stat(h.promptFilePath(name)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "提示词不存在"})
+		return
+	}
+
+	// 名称改变时，先删除旧文件与旧的服务器注册
+	if req.Name != name {
+		if err := os.Remove(h.promptFilePath(name)); err != nil {
+			h.logger.Warn("删除旧提示词文件失败", zap.String("name", name), zap.Error(err))
+		}
+		h.mcpServer.RemovePrompt(name)
+	}
+
+	if err := h.savePromptFile(req); err != nil {
+		h.logger.Error("保存提示词配置失败", zap.String("name", req.Name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存提示词配置失败: " + err.Error()})
+		return
+	}
+	if err := h.registerPromptFile(req.Name); err != nil {
+		h.logger.Warn("注册提示词到MCP服务器失败", zap.String("name", req.Name), zap.Error(err))
+	}
+
+	h.logger.Info("更新提示词模板", zap.String("oldName", name), zap.String("newName", req.Name))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "提示词已更新",
+	})
+}
+
+// DeletePrompt 删除提示词模板
+func (h *PromptsHandler) DeletePrompt(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "提示词名称不能为空"})
+		return
+	}
+
+	filePath := h.promptFilePath(name)
+	if _, err := os.Stat(filePath); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "提示词不存在"})
+		return
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		h.logger.Error("删除提示词文件失败", zap.String("name", name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除提示词文件失败: " + err.Error()})
+		return
+	}
+	h.mcpServer.RemovePrompt(name)
+
+	h.logger.Info("删除提示词模板", zap.String("name", name))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "提示词已删除",
+	})
+}
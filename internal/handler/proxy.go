@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+
+	"cyberstrike-ai/internal/proxy"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ProxyHandler 按对话配置的工具流量代理路由接口：设置后，该对话下所有支持代理的工具调用都会
+// 自动注入 HTTP_PROXY 环境变量，并对声明了 ProxyFlag 的工具追加对应的代理命令行参数，
+// 使生成的流量可被 Burp Suite 截获或经由 SOCKS 跳板转发（见 security.Executor.SetProxyEngine）。
+type ProxyHandler struct {
+	engine *proxy.Engine
+	logger *zap.Logger
+}
+
+// NewProxyHandler 创建代理路由配置处理器
+func NewProxyHandler(engine *proxy.Engine, logger *zap.Logger) *ProxyHandler {
+	return &ProxyHandler{engine: engine, logger: logger}
+}
+
+// SetProxy 处理设置指定对话代理配置的请求
+func (h *ProxyHandler) SetProxy(c *gin.Context) {
+	conversationID := c.Param("id")
+	var cfg proxy.Config
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	if err := h.engine.SetProxy(conversationID, cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "代理配置已更新"})
+}
+
+// GetProxy 处理查询指定对话代理配置的请求
+func (h *ProxyHandler) GetProxy(c *gin.Context) {
+	conversationID := c.Param("id")
+	cfg, exists := h.engine.GetProxy(conversationID)
+	if !exists {
+		c.JSON(http.StatusOK, gin.H{"configured": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"configured": true, "proxy": cfg})
+}
+
+// ClearProxy 处理取消指定对话代理配置的请求
+func (h *ProxyHandler) ClearProxy(c *gin.Context) {
+	conversationID := c.Param("id")
+	h.engine.ClearProxy(conversationID)
+	c.JSON(http.StatusOK, gin.H{"message": "代理配置已取消"})
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"cyberstrike-ai/internal/config"
+	"cyberstrike-ai/internal/database"
+	"cyberstrike-ai/internal/report"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ReportHandler 生成会话级渗透测试报告（Markdown/HTML/PDF），并支持套用 ReportTemplatesDir 下的自定义模板。
+type ReportHandler struct {
+	db           *database.DB
+	openAIConfig *config.OpenAIConfig
+	config       *config.Config
+	configPath   string
+	logger       *zap.Logger
+}
+
+// NewReportHandler 创建 ReportHandler
+func NewReportHandler(db *database.DB, openAIConfig *config.OpenAIConfig, cfg *config.Config, configPath string, logger *zap.Logger) *ReportHandler {
+	return &ReportHandler{db: db, openAIConfig: openAIConfig, config: cfg, configPath: configPath, logger: logger}
+}
+
+// updateReportTemplateRequest SetReportTemplate 的请求体
+type updateReportTemplateRequest struct {
+	Template string `json:"template"`
+}
+
+// SetReportTemplate 设置该会话导出报告时使用的自定义模板名，传空字符串恢复默认布局
+// PUT /api/conversations/:id/report-template
+func (h *ReportHandler) SetReportTemplate(c *gin.Context) {
+	conversationID := c.Param("id")
+
+	var req updateReportTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.UpdateConversationReportTemplate(conversationID, req.Template); err != nil {
+		h.logger.Error("更新报告模板选择失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "更新成功"})
+}
+
+// renderCustomTemplate 加载并渲染指定名称的自定义报告模板（见 handler.ReportTemplateHandler 管理的模板文件）
+func (h *ReportHandler) renderCustomTemplate(name string, data *report.Data) (string, error) {
+	templatesDir := h.config.ReportTemplatesDir
+	if templatesDir == "" {
+		templatesDir = "reports/templates"
+	}
+	configDir := filepath.Dir(h.configPath)
+	if !filepath.IsAbs(templatesDir) {
+		templatesDir = filepath.Join(configDir, templatesDir)
+	}
+
+	content, err := os.ReadFile(filepath.Join(templatesDir, sanitizeFileName(name)+".tmpl"))
+	if err != nil {
+		return "", fmt.Errorf("报告模板不存在: %s", name)
+	}
+
+	rendered, err := report.RenderWithTemplate(string(content), data)
+	if err != nil {
+		return "", fmt.Errorf("渲染报告模板失败: %w", err)
+	}
+	return rendered, nil
+}
+
+// GetReport 生成并返回会话报告
+// GET /api/conversations/:id/report?format=pdf|md|html（默认 md）
+func (h *ReportHandler) GetReport(c *gin.Context) {
+	conversationID := c.Param("id")
+	if conversationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "conversationId is required"})
+		return
+	}
+	format := c.DefaultQuery("format", "md")
+	if format != "md" && format != "html" && format != "pdf" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format 仅支持 md、html 或 pdf"})
+		return
+	}
+
+	conv, err := h.db.GetConversation(conversationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "对话不存在"})
+		return
+	}
+
+	generator := report.NewGenerator(h.db, h.openAIConfig, h.logger)
+	data, err := generator.Compile(c.Request.Context(), conversationID)
+	if err != nil {
+		h.logger.Error("生成报告失败", zap.String("conversationId", conversationID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("生成报告失败: %s", err.Error())})
+		return
+	}
+
+	// 优先使用请求指定的模板，其次使用该会话已选定的模板；仅对 md/html 输出有意义（PDF 排版依赖固定的纯文本流程）
+	templateName := c.Query("template")
+	if templateName == "" {
+		templateName = conv.ReportTemplate
+	}
+	if templateName != "" && format != "pdf" {
+		content, err := h.renderCustomTemplate(templateName, data)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ext := "md"
+		contentType := "text/markdown; charset=utf-8"
+		if format == "html" {
+			ext = "html"
+			contentType = "text/html; charset=utf-8"
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=report-%s.%s", conversationID, ext))
+		c.Data(http.StatusOK, contentType, []byte(content))
+		return
+	}
+
+	filenameBase := fmt.Sprintf("report-%s", conversationID)
+	switch format {
+	case "md":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.md", filenameBase))
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(report.RenderMarkdown(data)))
+	case "html":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.html", filenameBase))
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(report.RenderHTML(data)))
+	case "pdf":
+		pdfBytes, err := report.RenderPDF(data)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("生成PDF失败: %s", err.Error())})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.pdf", filenameBase))
+		c.Data(http.StatusOK, "application/pdf", pdfBytes)
+	}
+}
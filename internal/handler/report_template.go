@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cyberstrike-ai/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ReportTemplateHandler 管理 ReportTemplatesDir 下的自定义报告模板文件（Go模板或Markdown骨架），
+// 供 report.RenderWithTemplate 在生成报告时渲染，见 ReportHandler.GetReport。
+type ReportTemplateHandler struct {
+	config     *config.Config
+	configPath string
+	logger     *zap.Logger
+}
+
+// NewReportTemplateHandler 创建新的报告模板处理器
+func NewReportTemplateHandler(cfg *config.Config, configPath string, logger *zap.Logger) *ReportTemplateHandler {
+	return &ReportTemplateHandler{config: cfg, configPath: configPath, logger: logger}
+}
+
+func (h *ReportTemplateHandler) templatesRootAbs() string {
+	templatesDir := h.config.ReportTemplatesDir
+	if templatesDir == "" {
+		templatesDir = "reports/templates"
+	}
+	configDir := filepath.Dir(h.configPath)
+	if !filepath.IsAbs(templatesDir) {
+		templatesDir = filepath.Join(configDir, templatesDir)
+	}
+	return templatesDir
+}
+
+func (h *ReportTemplateHandler) templateFilePath(name string) string {
+	return filepath.Join(h.templatesRootAbs(), sanitizeFileName(name)+".tmpl")
+}
+
+// ListReportTemplates 列出所有报告模板名
+func (h *ReportTemplateHandler) ListReportTemplates(c *gin.Context) {
+	entries, err := os.ReadDir(h.templatesRootAbs())
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.JSON(http.StatusOK, gin.H{"templates": []string{}})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取报告模板目录失败: " + err.Error()})
+		return
+	}
+
+	templates := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tmpl") {
+			continue
+		}
+		templates = append(templates, strings.TrimSuffix(entry.Name(), ".tmpl"))
+	}
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+// GetReportTemplate 获取单个报告模板内容
+func (h *ReportTemplateHandler) GetReportTemplate(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "模板名称不能为空"})
+		return
+	}
+
+	content, err := os.ReadFile(h.templateFilePath(name))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "报告模板不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"name": name, "content": string(content)})
+}
+
+// reportTemplateRequest CreateReportTemplate/UpdateReportTemplate 的请求体
+type reportTemplateRequest struct {
+	Name    string `json:"name" binding:"required"`
+	Content string `json:"content" binding:"required"`
+}
+
+// CreateReportTemplate 创建新的报告模板
+func (h *ReportTemplateHandler) CreateReportTemplate(c *gin.Context) {
+	var req reportTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	if _, err := os.Stat(h.templateFilePath(req.Name)); err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "报告模板已存在"})
+		return
+	}
+
+	if err := h.saveTemplateFile(req.Name, req.Content); err != nil {
+		h.logger.Error("保存报告模板失败", zap.String("name", req.Name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存报告模板失败: " + err.Error()})
+		return
+	}
+
+	h.logger.Info("创建报告模板", zap.String("name", req.Name))
+	c.JSON(http.StatusOK, gin.H{"message": "报告模板已创建"})
+}
+
+// UpdateReportTemplate 更新报告模板
+func (h *ReportTemplateHandler) UpdateReportTemplate(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "模板名称不能为空"})
+		return
+	}
+
+	var req reportTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+	if req.Name == "" {
+		req.Name = name
+	}
+
+	if _, err := os.Stat(h.templateFilePath(name)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "报告模板不存在"})
+		return
+	}
+
+	// 名称改变时，先删除旧文件
+	if req.Name != name {
+		if err := os.Remove(h.templateFilePath(name)); err != nil {
+			h.logger.Warn("删除旧报告模板文件失败", zap.String("name", name), zap.Error(err))
+		}
+	}
+
+	if err := h.saveTemplateFile(req.Name, req.Content); err != nil {
+		h.logger.Error("保存报告模板失败", zap.String("name", req.Name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存报告模板失败: " + err.Error()})
+		return
+	}
+
+	h.logger.Info("更新报告模板", zap.String("oldName", name), zap.String("newName", req.Name))
+	c.JSON(http.StatusOK, gin.H{"message": "报告模板已更新"})
+}
+
+// DeleteReportTemplate 删除报告模板
+func (h *ReportTemplateHandler) DeleteReportTemplate(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "模板名称不能为空"})
+		return
+	}
+
+	filePath := h.templateFilePath(name)
+	if _, err := os.Stat(filePath); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "报告模板不存在"})
+		return
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		h.logger.Error("删除报告模板文件失败", zap.String("name", name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除报告模板文件失败: " + err.Error()})
+		return
+	}
+
+	h.logger.Info("删除报告模板", zap.String("name", name))
+	c.JSON(http.StatusOK, gin.H{"message": "报告模板已删除"})
+}
+
+func (h *ReportTemplateHandler) saveTemplateFile(name, content string) error {
+	templatesDir := h.templatesRootAbs()
+	if err := os.MkdirAll(templatesDir, 0755); err != nil {
+		return fmt.Errorf("创建报告模板目录失败: %w", err)
+	}
+	return os.WriteFile(h.templateFilePath(name), []byte(content), 0644)
+}
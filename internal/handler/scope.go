@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+
+	"cyberstrike-ai/internal/scope"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ScopeHandler 目标范围配置接口：按对话设置允许的 CIDR/域名/URL 模式与显式拒绝列表，
+// 之后该对话下所有工具调用中带有目标语义的参数都会经 security.Executor 校验是否越界。
+type ScopeHandler struct {
+	engine *scope.Engine
+	logger *zap.Logger
+}
+
+// NewScopeHandler 创建目标范围配置处理器
+func NewScopeHandler(engine *scope.Engine, logger *zap.Logger) *ScopeHandler {
+	return &ScopeHandler{engine: engine, logger: logger}
+}
+
+// SetScope 处理设置指定对话范围配置的请求
+func (h *ScopeHandler) SetScope(c *gin.Context) {
+	conversationID := c.Param("id")
+	var cfg scope.Config
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	if err := h.engine.SetScope(conversationID, cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "范围配置已更新"})
+}
+
+// GetScope 处理查询指定对话范围配置的请求
+func (h *ScopeHandler) GetScope(c *gin.Context) {
+	conversationID := c.Param("id")
+	cfg, exists := h.engine.GetScope(conversationID)
+	if !exists {
+		c.JSON(http.StatusOK, gin.H{"configured": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"configured": true, "scope": cfg})
+}
+
+// ClearScope 处理取消指定对话范围限制的请求
+func (h *ScopeHandler) ClearScope(c *gin.Context) {
+	conversationID := c.Param("id")
+	h.engine.ClearScope(conversationID)
+	c.JSON(http.StatusOK, gin.H{"message": "范围限制已取消"})
+}
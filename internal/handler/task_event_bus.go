@@ -1,12 +1,32 @@
 package handler
 
-import "sync"
+import (
+	"fmt"
+	"sync"
+)
+
+// maxEventHistoryPerConversation 每个会话保留的最近事件帧数量上限，用于断线重连回放（见 SubscribeWithReplay）；
+// 超出后按 FIFO 淘汰最旧的帧，重连方若请求的 Last-Event-ID 早于当前缓冲窗口则回放不完整（replayOK=false）。
+const maxEventHistoryPerConversation = 500
+
+// bufferedEvent 是缓冲区中的一帧已编号事件，id 为该会话内单调递增序号（对应 SSE 的 id: 字段）。
+type bufferedEvent struct {
+	id   uint64
+	line []byte
+}
 
 // TaskEventBus 将主 SSE 连接上的事件镜像给后订阅的客户端（例如刷新页面后、HITL 审批通过需继续收事件）。
-// 每个 payload 为完整 SSE 行： "data: {...}\n\n"
+// 每个 payload 为完整 SSE 行： "id: <n>\ndata: {...}\n\n"；同时按会话保留最近若干帧，供带 Last-Event-ID
+// 重连的客户端回放期间错过的事件（见 SubscribeWithReplay）。
 type TaskEventBus struct {
-	mu   sync.RWMutex
-	subs map[string]map[*taskEventSub]struct{}
+	mu         sync.Mutex
+	subs       map[string]map[*taskEventSub]struct{}
+	history    map[string][]bufferedEvent
+	historySeq map[string]uint64
+
+	// global 承载跨会话的全局订阅（操作台“正在运行的任务”聚合看板），与 subs 按会话镜像相互独立。
+	globalMu sync.RWMutex
+	global   map[*broadcastSub]struct{}
 }
 
 type taskEventSub struct {
@@ -47,11 +67,15 @@ func (s *taskEventSub) closeOnce() {
 
 func NewTaskEventBus() *TaskEventBus {
 	return &TaskEventBus{
-		subs: make(map[string]map[*taskEventSub]struct{}),
+		subs:       make(map[string]map[*taskEventSub]struct{}),
+		history:    make(map[string][]bufferedEvent),
+		historySeq: make(map[string]uint64),
+		global:     make(map[*broadcastSub]struct{}),
 	}
 }
 
-// Subscribe 注册订阅；cancel 时需调用 Unsubscribe。
+// Subscribe 注册订阅；cancel 时需调用 Unsubscribe。不回放历史事件，仅用于已知不需要补流的场景；
+// 断线重连需要回放时应改用 SubscribeWithReplay。
 func (b *TaskEventBus) Subscribe(conversationID string) (sub *taskEventSub, ch <-chan []byte) {
 	chBuf := make(chan []byte, 256)
 	sub = &taskEventSub{ch: chBuf}
@@ -64,6 +88,32 @@ func (b *TaskEventBus) Subscribe(conversationID string) (sub *taskEventSub, ch <
 	return sub, chBuf
 }
 
+// SubscribeWithReplay 原子地注册订阅并返回 afterID（不含）之后已缓冲的事件帧，避免「注册订阅」与
+// 「读取历史缓冲」之间的空窗导致漏放或重放重复。afterID 为 0 时回放全部缓冲历史。
+// replayOK 为 false 表示 afterID 早于当前缓冲窗口最旧的事件（已被淘汰），历史无法完整回放，
+// 调用方应提示客户端改为从 REST 接口拉取完整过程详情兜底。
+func (b *TaskEventBus) SubscribeWithReplay(conversationID string, afterID uint64) (sub *taskEventSub, ch <-chan []byte, replay [][]byte, replayOK bool) {
+	chBuf := make(chan []byte, 256)
+	sub = &taskEventSub{ch: chBuf}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[conversationID] == nil {
+		b.subs[conversationID] = make(map[*taskEventSub]struct{})
+	}
+	b.subs[conversationID][sub] = struct{}{}
+
+	buf := b.history[conversationID]
+	replayOK = afterID == 0 || len(buf) == 0 || buf[0].id <= afterID+1
+	if replayOK {
+		for _, e := range buf {
+			if e.id > afterID {
+				replay = append(replay, e.line)
+			}
+		}
+	}
+	return sub, chBuf, replay, replayOK
+}
+
 func (b *TaskEventBus) Unsubscribe(conversationID string, sub *taskEventSub) {
 	if sub == nil {
 		return
@@ -82,26 +132,37 @@ func (b *TaskEventBus) Unsubscribe(conversationID string, sub *taskEventSub) {
 	sub.closeOnce()
 }
 
-// Publish 非阻塞投递；慢消费者丢帧（HITL 场景以最新状态为准，丢帧可接受）。
+// Publish 非阻塞投递并缓冲一份带序号的事件帧供断线重连回放（见 SubscribeWithReplay）；
+// 慢消费者丢帧（HITL 场景以最新状态为准，丢帧可接受，回放缓冲区不受影响）。
 func (b *TaskEventBus) Publish(conversationID string, line []byte) {
 	if b == nil || conversationID == "" || len(line) == 0 {
 		return
 	}
-	b.mu.RLock()
+	b.mu.Lock()
+	b.historySeq[conversationID]++
+	id := b.historySeq[conversationID]
+	framed := make([]byte, 0, len(line)+16)
+	framed = append(framed, []byte(fmt.Sprintf("id: %d\n", id))...)
+	framed = append(framed, line...)
+	buf := append(b.history[conversationID], bufferedEvent{id: id, line: framed})
+	if len(buf) > maxEventHistoryPerConversation {
+		buf = buf[len(buf)-maxEventHistoryPerConversation:]
+	}
+	b.history[conversationID] = buf
+
 	m := b.subs[conversationID]
 	subs := make([]*taskEventSub, 0, len(m))
 	for s := range m {
 		subs = append(subs, s)
 	}
-	b.mu.RUnlock()
+	b.mu.Unlock()
 
-	cp := append([]byte(nil), line...)
 	for _, s := range subs {
-		s.sendNonBlocking(cp)
+		s.sendNonBlocking(framed)
 	}
 }
 
-// CloseConversation 任务结束时关闭该会话所有订阅 channel。
+// CloseConversation 任务结束时关闭该会话所有订阅 channel，并清空其事件回放缓冲区。
 func (b *TaskEventBus) CloseConversation(conversationID string) {
 	if b == nil || conversationID == "" {
 		return
@@ -109,8 +170,105 @@ func (b *TaskEventBus) CloseConversation(conversationID string) {
 	b.mu.Lock()
 	m := b.subs[conversationID]
 	delete(b.subs, conversationID)
+	delete(b.history, conversationID)
+	delete(b.historySeq, conversationID)
 	b.mu.Unlock()
 	for sub := range m {
 		sub.closeOnce()
 	}
 }
+
+// broadcastSub 跨会话的全局订阅者，供操作台聚合看板一次性观察所有运行中任务；
+// filter 非空时只接收指定会话 ID 的事件，为空表示接收全部会话的事件。
+type broadcastSub struct {
+	mu     sync.Mutex
+	ch     chan []byte
+	closed bool
+	filter map[string]bool
+}
+
+func (s *broadcastSub) sendNonBlocking(line []byte) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	select {
+	case s.ch <- line:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *broadcastSub) closeOnce() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.ch)
+}
+
+// SubscribeGlobal 注册一个跨会话的全局订阅；conversationIDs 非空时只接收这些会话的事件（按任务过滤），
+// 为空时接收所有正在运行任务的事件。cancel 时需调用 UnsubscribeGlobal。
+func (b *TaskEventBus) SubscribeGlobal(conversationIDs []string) (sub *broadcastSub, ch <-chan []byte) {
+	chBuf := make(chan []byte, 512)
+	var filter map[string]bool
+	if len(conversationIDs) > 0 {
+		filter = make(map[string]bool, len(conversationIDs))
+		for _, id := range conversationIDs {
+			if id != "" {
+				filter[id] = true
+			}
+		}
+	}
+	sub = &broadcastSub{ch: chBuf, filter: filter}
+	b.globalMu.Lock()
+	b.global[sub] = struct{}{}
+	b.globalMu.Unlock()
+	return sub, chBuf
+}
+
+// UnsubscribeGlobal 注销一个全局订阅。
+func (b *TaskEventBus) UnsubscribeGlobal(sub *broadcastSub) {
+	if sub == nil {
+		return
+	}
+	b.globalMu.Lock()
+	delete(b.global, sub)
+	b.globalMu.Unlock()
+	sub.closeOnce()
+}
+
+// PublishGlobal 向所有全局订阅者广播一条已携带 conversationID 的事件行，按订阅者的 filter 过滤；
+// 非阻塞投递，慢消费者丢帧（聚合看板以最新状态为准，丢帧可接受）。
+func (b *TaskEventBus) PublishGlobal(conversationID string, line []byte) {
+	if b == nil || len(line) == 0 {
+		return
+	}
+	b.globalMu.RLock()
+	subs := make([]*broadcastSub, 0, len(b.global))
+	for s := range b.global {
+		subs = append(subs, s)
+	}
+	b.globalMu.RUnlock()
+	if len(subs) == 0 {
+		return
+	}
+
+	cp := append([]byte(nil), line...)
+	for _, s := range subs {
+		if len(s.filter) > 0 && !s.filter[conversationID] {
+			continue
+		}
+		s.sendNonBlocking(cp)
+	}
+}
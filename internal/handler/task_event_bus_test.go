@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTaskEventBus_SubscribeWithReplay_ReplaysMissedEvents(t *testing.T) {
+	b := NewTaskEventBus()
+	convID := "conv-events-1"
+
+	b.Publish(convID, []byte("data: {\"seq\":1}\n\n"))
+	b.Publish(convID, []byte("data: {\"seq\":2}\n\n"))
+
+	// 模拟断线：lastEventID=1，重连时应只补放 seq=2 及之后的事件
+	sub, ch, replay, ok := b.SubscribeWithReplay(convID, 1)
+	defer b.Unsubscribe(convID, sub)
+	if !ok {
+		t.Fatalf("期望 replayOK=true")
+	}
+	if len(replay) != 1 {
+		t.Fatalf("期望补放1条事件，实际: %d", len(replay))
+	}
+
+	b.Publish(convID, []byte("data: {\"seq\":3}\n\n"))
+	select {
+	case chunk := <-ch:
+		if !bytes.HasPrefix(chunk, []byte("id: 3\n")) {
+			t.Fatalf("期望实时事件帧携带 id，实际: %q", chunk)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("应收到订阅后新发布的事件")
+	}
+}
+
+func TestTaskEventBus_SubscribeWithReplay_GapWhenEvicted(t *testing.T) {
+	b := NewTaskEventBus()
+	convID := "conv-events-2"
+
+	for i := 0; i < maxEventHistoryPerConversation+10; i++ {
+		b.Publish(convID, []byte("data: {}\n\n"))
+	}
+
+	// 请求的 lastEventID 早于当前缓冲窗口最旧的事件，无法完整回放
+	sub, _, _, ok := b.SubscribeWithReplay(convID, 1)
+	defer b.Unsubscribe(convID, sub)
+	if ok {
+		t.Fatalf("期望 replayOK=false，因请求的事件已被淘汰")
+	}
+}
+
+func TestTaskEventBus_CloseConversation_ClearsHistory(t *testing.T) {
+	b := NewTaskEventBus()
+	convID := "conv-events-3"
+	b.Publish(convID, []byte("data: {}\n\n"))
+
+	b.CloseConversation(convID)
+
+	sub, _, replay, ok := b.SubscribeWithReplay(convID, 0)
+	defer b.Unsubscribe(convID, sub)
+	if !ok || len(replay) != 0 {
+		t.Fatalf("会话结束后历史缓冲应已清空，实际 ok=%v replay=%v", ok, replay)
+	}
+}
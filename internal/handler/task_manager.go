@@ -6,6 +6,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // ErrTaskCancelled 用户取消任务的错误
@@ -49,14 +51,27 @@ type CompletedTask struct {
 	Status         string    `json:"status"`
 }
 
+// QueuedTask 描述一条 opt-in 排队模式下等待当前任务结束才会执行的消息（见 EnqueueTask）。
+// ready 在轮到它时被关闭：此时 FinishTask 已为它占住会话槽位，等待方据此收到通知后调用 AttachCancel
+// 绑定 cancel 并执行任务，不应再调用 StartTask（槽位已被占用，会返回 ErrTaskAlreadyRunning）。
+type QueuedTask struct {
+	ID             string    `json:"id"`
+	ConversationID string    `json:"conversationId"`
+	Message        string    `json:"message,omitempty"`
+	EnqueuedAt     time.Time `json:"enqueuedAt"`
+
+	ready chan struct{}
+}
+
 // AgentTaskManager 管理正在运行的Agent任务
 type AgentTaskManager struct {
 	mu               sync.RWMutex
 	tasks            map[string]*AgentTask
-	completedTasks   []*CompletedTask // 最近完成的任务历史
-	maxHistorySize   int              // 最大历史记录数
-	historyRetention time.Duration    // 历史记录保留时间
-	eventBus         *TaskEventBus    // 可选：任务结束时关闭镜像 SSE 订阅
+	queues           map[string][]*QueuedTask // 按会话ID分组的排队消息（opt-in 队列模式，先进先出）
+	completedTasks   []*CompletedTask         // 最近完成的任务历史
+	maxHistorySize   int                      // 最大历史记录数
+	historyRetention time.Duration            // 历史记录保留时间
+	eventBus         *TaskEventBus            // 可选：任务结束时关闭镜像 SSE 订阅
 }
 
 const (
@@ -72,6 +87,7 @@ const (
 func NewAgentTaskManager() *AgentTaskManager {
 	m := &AgentTaskManager{
 		tasks:            make(map[string]*AgentTask),
+		queues:           make(map[string][]*QueuedTask),
 		completedTasks:   make([]*CompletedTask, 0),
 		maxHistorySize:   50,             // 最多保留50条历史记录
 		historyRetention: 24 * time.Hour, // 保留24小时
@@ -156,6 +172,114 @@ func (m *AgentTaskManager) StartTask(conversationID, message string, cancel cont
 	return task, nil
 }
 
+// startTaskLocked 是 StartTask 的加锁版本，调用方需持有 m.mu 的写锁。cancel 可为 nil（占位，稍后由
+// AttachCancel 补上），用于 EnqueueTask/FinishTask 交接排队任务时先占住会话槽位，避免出现「已出队但尚未
+// 调用 StartTask」的窗口期内又被别的请求抢占。
+func (m *AgentTaskManager) startTaskLocked(conversationID, message string, cancel context.CancelCauseFunc) *AgentTask {
+	task := &AgentTask{
+		ConversationID: conversationID,
+		Message:        message,
+		StartedAt:      time.Now(),
+		Status:         "running",
+		cancel: func(err error) {
+			if cancel != nil {
+				cancel(err)
+			}
+		},
+	}
+	m.tasks[conversationID] = task
+	return task
+}
+
+// AttachCancel 为已通过排队交接占位的任务补上真正的 cancel 函数（见 EnqueueTask）。
+func (m *AgentTaskManager) AttachCancel(conversationID string, cancel context.CancelCauseFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if task, ok := m.tasks[conversationID]; ok {
+		task.cancel = func(err error) {
+			if cancel != nil {
+				cancel(err)
+			}
+		}
+	}
+}
+
+// EnqueueTask 是 opt-in 队列模式的入口：若会话当前无运行中任务则直接启动并返回该任务；否则将消息追加到
+// 该会话的等待队列末尾，返回排队句柄，调用方应等待 qt.Ready() 后再调用 AttachCancel 绑定 cancel 并执行任务
+// （此时槽位已由 FinishTask 交接占位，无需也不应再调用 StartTask）。
+func (m *AgentTaskManager) EnqueueTask(conversationID, message string, cancel context.CancelCauseFunc) (*AgentTask, *QueuedTask) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.tasks[conversationID]; !exists {
+		return m.startTaskLocked(conversationID, message, cancel), nil
+	}
+
+	qt := &QueuedTask{
+		ID:             uuid.New().String(),
+		ConversationID: conversationID,
+		Message:        message,
+		EnqueuedAt:     time.Now(),
+		ready:          make(chan struct{}),
+	}
+	m.queues[conversationID] = append(m.queues[conversationID], qt)
+	return nil, qt
+}
+
+// Ready 返回排队任务轮到自己执行时会被关闭的通道。
+func (qt *QueuedTask) Ready() <-chan struct{} {
+	return qt.ready
+}
+
+// QueuePosition 返回排队任务当前的位置（从1开始，1表示队首，下一个完成即轮到它）；
+// 若已不在队列中（已开始或已被取消），返回0。
+func (m *AgentTaskManager) QueuePosition(qt *QueuedTask) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i, q := range m.queues[qt.ConversationID] {
+		if q == qt {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// GetQueuedTasks 返回指定会话当前排队中的消息列表（不含正在运行的那一个）。
+func (m *AgentTaskManager) GetQueuedTasks(conversationID string) []*QueuedTask {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	queue := m.queues[conversationID]
+	result := make([]*QueuedTask, len(queue))
+	copy(result, queue)
+	return result
+}
+
+// GetAllQueuedTasks 返回所有会话当前排队中的消息，供 GET /api/agent-loop/tasks 展示排队位置。
+func (m *AgentTaskManager) GetAllQueuedTasks() []*QueuedTask {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var result []*QueuedTask
+	for _, queue := range m.queues {
+		result = append(result, queue...)
+	}
+	return result
+}
+
+// CancelQueuedTask 将一条尚未轮到的排队消息从队列中移除（例如客户端断开连接）。
+// 若该消息已被 FinishTask 交接出队（即将/已经开始运行），返回 false，调用方不应再假设它未执行。
+func (m *AgentTaskManager) CancelQueuedTask(qt *QueuedTask) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	queue := m.queues[qt.ConversationID]
+	for i, q := range queue {
+		if q == qt {
+			m.queues[qt.ConversationID] = append(queue[:i], queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 // SetInterruptContinueReason 在发起 ErrUserInterruptContinue 取消前写入用户说明（须任务仍存在）。
 func (m *AgentTaskManager) SetInterruptContinueReason(conversationID, reason string) bool {
 	m.mu.Lock()
@@ -228,6 +352,25 @@ func (m *AgentTaskManager) CancelTask(conversationID string, cause error) (bool,
 	return true, nil
 }
 
+// CancelAllTasks 取消所有正在运行的任务，用于全局紧急停止（见 EmergencyStopHandler）；
+// 返回被取消的会话ID列表。
+func (m *AgentTaskManager) CancelAllTasks(cause error) []string {
+	m.mu.RLock()
+	conversationIDs := make([]string, 0, len(m.tasks))
+	for conversationID := range m.tasks {
+		conversationIDs = append(conversationIDs, conversationID)
+	}
+	m.mu.RUnlock()
+
+	cancelled := make([]string, 0, len(conversationIDs))
+	for _, conversationID := range conversationIDs {
+		if ok, _ := m.CancelTask(conversationID, cause); ok {
+			cancelled = append(cancelled, conversationID)
+		}
+	}
+	return cancelled
+}
+
 // UpdateTaskStatus 更新任务状态但不删除任务（用于在发送事件前更新状态）
 func (m *AgentTaskManager) UpdateTaskStatus(conversationID string, status string) {
 	m.mu.Lock()
@@ -271,10 +414,23 @@ func (m *AgentTaskManager) FinishTask(conversationID string, finalStatus string)
 	// 清理过期和过多的历史记录
 	m.cleanupHistory()
 
-	// 从运行任务中移除
+	// 从运行任务中移除；若该会话有排队消息，立即交接槽位给队首（占位 cancel=nil，由等待方 AttachCancel 补上），
+	// 避免出队与 StartTask 之间的窗口期被其他请求抢占
 	delete(m.tasks, conversationID)
+	var woken *QueuedTask
+	if queue := m.queues[conversationID]; len(queue) > 0 {
+		woken = queue[0]
+		m.queues[conversationID] = queue[1:]
+		if len(m.queues[conversationID]) == 0 {
+			delete(m.queues, conversationID)
+		}
+		m.startTaskLocked(conversationID, woken.Message, nil)
+	}
 	bus := m.eventBus
 	m.mu.Unlock()
+	if woken != nil {
+		close(woken.ready)
+	}
 	if bus != nil {
 		bus.CloseConversation(conversationID)
 	}
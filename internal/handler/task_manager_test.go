@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAgentTaskManager_EnqueueTask_RunsSequentiallyInOrder(t *testing.T) {
+	m := NewAgentTaskManager()
+	convID := "conv-queue-1"
+
+	if _, err := m.StartTask(convID, "first", func(error) {}); err != nil {
+		t.Fatalf("启动首个任务失败: %v", err)
+	}
+
+	startedTask, qt := m.EnqueueTask(convID, "second", func(error) {})
+	if startedTask != nil {
+		t.Fatalf("会话已有任务运行时，EnqueueTask 不应直接启动")
+	}
+	if qt == nil {
+		t.Fatalf("会话已有任务运行时，EnqueueTask 应返回排队句柄")
+	}
+	if pos := m.QueuePosition(qt); pos != 1 {
+		t.Fatalf("期望排队位置为1，实际: %d", pos)
+	}
+
+	select {
+	case <-qt.Ready():
+		t.Fatalf("首个任务尚未结束，排队任务不应被唤醒")
+	default:
+	}
+
+	// 完成首个任务后，排队任务应被交接到运行槽位并收到通知
+	m.FinishTask(convID, "completed")
+
+	select {
+	case <-qt.Ready():
+	case <-time.After(time.Second):
+		t.Fatalf("完成首个任务后，排队任务应立即被唤醒")
+	}
+
+	if task := m.GetTask(convID); task == nil || task.Message != "second" {
+		t.Fatalf("排队任务出队后应已占据运行槽位，实际: %+v", task)
+	}
+	if _, err := m.StartTask(convID, "third", func(error) {}); !errors.Is(err, ErrTaskAlreadyRunning) {
+		t.Fatalf("排队任务交接后槽位应仍处于占用状态，实际错误: %v", err)
+	}
+}
+
+func TestAgentTaskManager_EnqueueTask_StartsImmediatelyWhenIdle(t *testing.T) {
+	m := NewAgentTaskManager()
+	task, qt := m.EnqueueTask("conv-queue-2", "hello", func(error) {})
+	if task == nil {
+		t.Fatalf("会话空闲时 EnqueueTask 应直接启动任务")
+	}
+	if qt != nil {
+		t.Fatalf("会话空闲时不应返回排队句柄")
+	}
+}
+
+func TestAgentTaskManager_CancelQueuedTask(t *testing.T) {
+	m := NewAgentTaskManager()
+	convID := "conv-queue-3"
+	if _, err := m.StartTask(convID, "first", func(error) {}); err != nil {
+		t.Fatalf("启动首个任务失败: %v", err)
+	}
+	_, qt := m.EnqueueTask(convID, "second", func(error) {})
+	if qt == nil {
+		t.Fatalf("期望返回排队句柄")
+	}
+	if !m.CancelQueuedTask(qt) {
+		t.Fatalf("尚未出队的排队任务应可被取消")
+	}
+	if pos := m.QueuePosition(qt); pos != 0 {
+		t.Fatalf("取消后排队位置应为0，实际: %d", pos)
+	}
+	if m.CancelQueuedTask(qt) {
+		t.Fatalf("重复取消应返回 false")
+	}
+}
+
+func TestAgentTaskManager_AttachCancel(t *testing.T) {
+	m := NewAgentTaskManager()
+	convID := "conv-queue-4"
+	if _, err := m.StartTask(convID, "first", func(error) {}); err != nil {
+		t.Fatalf("启动首个任务失败: %v", err)
+	}
+	_, qt := m.EnqueueTask(convID, "second", nil)
+	m.FinishTask(convID, "completed")
+	<-qt.Ready()
+
+	cancelled := false
+	m.AttachCancel(convID, func(err error) {
+		if errors.Is(err, context.Canceled) {
+			cancelled = true
+		}
+	})
+	if ok, _ := m.CancelTask(convID, context.Canceled); !ok {
+		t.Fatalf("交接后的任务应可正常取消")
+	}
+	if !cancelled {
+		t.Fatalf("AttachCancel 绑定的 cancel 函数应被调用")
+	}
+}
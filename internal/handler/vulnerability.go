@@ -7,23 +7,131 @@ import (
 	"strings"
 	"time"
 
+	"cyberstrike-ai/internal/attackchain"
 	"cyberstrike-ai/internal/database"
+	"cyberstrike-ai/internal/security"
+
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
+// ChainNotifier 在攻击链增量追加节点后广播 chain_updated 事件，由 AgentHandler 实现
+// （通过 taskEventBus.PublishGlobal 推送给所有订阅该会话的 SSE 连接）。
+type ChainNotifier interface {
+	NotifyChainUpdated(conversationID string, node interface{})
+}
+
+// vulnRiskScore 把漏洞严重程度映射为攻击链节点的风险分，用于前端按风险着色/排序
+func vulnRiskScore(severity string) int {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "critical":
+		return 100
+	case "high":
+		return 75
+	case "medium":
+		return 50
+	case "low":
+		return 25
+	default:
+		return 10
+	}
+}
+
 // VulnerabilityHandler 漏洞处理器
 type VulnerabilityHandler struct {
-	db     *database.DB
-	logger *zap.Logger
+	db             *database.DB
+	logger         *zap.Logger
+	cveTrigger     *CVEEnrichmentTrigger
+	webhookTrigger *WebhookTrigger
 }
 
-// NewVulnerabilityHandler 创建新的漏洞处理器
-func NewVulnerabilityHandler(db *database.DB, logger *zap.Logger) *VulnerabilityHandler {
+// NewVulnerabilityHandler 创建新的漏洞处理器；cveTrigger/webhookTrigger 为 nil 表示对应能力未启用。
+func NewVulnerabilityHandler(db *database.DB, logger *zap.Logger, cveTrigger *CVEEnrichmentTrigger, webhookTrigger *WebhookTrigger) *VulnerabilityHandler {
 	return &VulnerabilityHandler{
-		db:     db,
-		logger: logger,
+		db:             db,
+		logger:         logger,
+		cveTrigger:     cveTrigger,
+		webhookTrigger: webhookTrigger,
+	}
+}
+
+// CVEEnrichmentTrigger 在漏洞创建后触发 CVE/NVD 自动富化（见 security.EnrichVulnerabilityCVEsAsync）；
+// 由 app.go 按 cfg.NVD 是否启用决定是否构造，未配置时 client 为 nil，TriggerAsync 直接跳过，
+// 使得漏洞创建路径（API/record_vulnerability工具/LLM提取）都能复用同一份富化触发逻辑。
+type CVEEnrichmentTrigger struct {
+	client security.CVELookupClient
+	cache  *security.CVECache
+	logger *zap.Logger
+}
+
+// NewCVEEnrichmentTrigger 创建 CVEEnrichmentTrigger
+func NewCVEEnrichmentTrigger(client security.CVELookupClient, cache *security.CVECache, logger *zap.Logger) *CVEEnrichmentTrigger {
+	return &CVEEnrichmentTrigger{client: client, cache: cache, logger: logger}
+}
+
+// TriggerAsync 从漏洞标题/描述/证明中提取 CVE 编号并异步查询富化，结果通过 db.ApplyCVEEnrichment 写回。
+func (t *CVEEnrichmentTrigger) TriggerAsync(db *database.DB, vuln *database.Vulnerability) {
+	if t == nil || t.client == nil || vuln == nil {
+		return
 	}
+	text := vuln.Title + "\n" + vuln.Description + "\n" + vuln.Proof
+	security.EnrichVulnerabilityCVEsAsync(t.client, t.cache, db, t.logger, vuln.ID, text)
+}
+
+// LLMVulnerabilitySink 把 security.Executor 的 LLM 漏洞提取管线（见 SetVulnerabilityExtractor）产出的
+// 标准化记录适配落库，实现 security.VulnerabilitySink；单独建表字段与手动 record_vulnerability 工具复用
+// 同一张 vulnerabilities 表，Description 标注来源以便与人工/Agent记录区分。
+type LLMVulnerabilitySink struct {
+	db             *database.DB
+	logger         *zap.Logger
+	cveTrigger     *CVEEnrichmentTrigger
+	chainNotifier  ChainNotifier
+	webhookTrigger *WebhookTrigger
+}
+
+// NewLLMVulnerabilitySink 创建 LLMVulnerabilitySink；cveTrigger/webhookTrigger 为 nil 表示对应能力未启用。
+func NewLLMVulnerabilitySink(db *database.DB, logger *zap.Logger, cveTrigger *CVEEnrichmentTrigger, webhookTrigger *WebhookTrigger) *LLMVulnerabilitySink {
+	return &LLMVulnerabilitySink{db: db, logger: logger, cveTrigger: cveTrigger, webhookTrigger: webhookTrigger}
+}
+
+// SetChainNotifier 设置攻击链增量更新的广播回调（通常为 AgentHandler），未设置时仅落库不广播 SSE。
+func (s *LLMVulnerabilitySink) SetChainNotifier(notifier ChainNotifier) {
+	s.chainNotifier = notifier
+}
+
+// RecordVulnerability 实现 security.VulnerabilitySink
+func (s *LLMVulnerabilitySink) RecordVulnerability(conversationID, toolName string, vuln security.ExtractedVulnerability) error {
+	if strings.TrimSpace(vuln.Title) == "" {
+		return fmt.Errorf("提取的漏洞记录缺少标题")
+	}
+	created, err := s.db.CreateVulnerability(&database.Vulnerability{
+		ConversationID: conversationID,
+		Title:          vuln.Title,
+		Description:    fmt.Sprintf("由 LLM 自动从工具 %s 的输出中提取", toolName),
+		Severity:       vuln.Severity,
+		Type:           toolName,
+		Target:         vuln.Component,
+		Proof:          vuln.Evidence,
+		Recommendation: vuln.Remediation,
+		CVSSVector:     vuln.CVSSVector,
+	})
+	if err != nil {
+		return err
+	}
+	s.cveTrigger.TriggerAsync(s.db, created)
+	s.webhookTrigger.TriggerAsync("vulnerability_found", created.Severity, created)
+
+	// 增量追加为攻击链漏洞节点，使前端在运行过程中就能看到新发现的漏洞，而不必等整链重新生成
+	builder := attackchain.NewBuilder(s.db, nil, s.logger)
+	metadata := map[string]interface{}{"severity": created.Severity, "target": created.Target}
+	node, appendErr := builder.AppendIncrementalNode(conversationID, "vulnerability", created.Title, metadata, vulnRiskScore(created.Severity))
+	if appendErr != nil {
+		s.logger.Warn("增量追加攻击链漏洞节点失败", zap.Error(appendErr))
+	} else if s.chainNotifier != nil {
+		s.chainNotifier.NotifyChainUpdated(conversationID, node)
+	}
+
+	return nil
 }
 
 // CreateVulnerabilityRequest 创建漏洞请求
@@ -40,6 +148,8 @@ type CreateVulnerabilityRequest struct {
 	Proof           string `json:"proof"`
 	Impact          string `json:"impact"`
 	Recommendation  string `json:"recommendation"`
+	// CVSSVector 为 CVSS v3.1 向量字符串，设置后 Severity 将由计算出的评分派生，见 database.applyCVSSScoring。
+	CVSSVector string `json:"cvss_vector"`
 }
 
 // CreateVulnerability 创建漏洞
@@ -63,6 +173,7 @@ func (h *VulnerabilityHandler) CreateVulnerability(c *gin.Context) {
 		Proof:           req.Proof,
 		Impact:          req.Impact,
 		Recommendation:  req.Recommendation,
+		CVSSVector:      req.CVSSVector,
 	}
 
 	created, err := h.db.CreateVulnerability(vuln)
@@ -71,6 +182,8 @@ func (h *VulnerabilityHandler) CreateVulnerability(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.cveTrigger.TriggerAsync(h.db, created)
+	h.webhookTrigger.TriggerAsync("vulnerability_found", created.Severity, created)
 
 	c.JSON(http.StatusOK, created)
 }
@@ -181,6 +294,7 @@ type UpdateVulnerabilityRequest struct {
 	Proof           string `json:"proof"`
 	Impact          string `json:"impact"`
 	Recommendation  string `json:"recommendation"`
+	CVSSVector      string `json:"cvss_vector"`
 }
 
 // UpdateVulnerability 更新漏洞
@@ -234,6 +348,9 @@ func (h *VulnerabilityHandler) UpdateVulnerability(c *gin.Context) {
 	if req.Recommendation != "" {
 		existing.Recommendation = req.Recommendation
 	}
+	if req.CVSSVector != "" {
+		existing.CVSSVector = req.CVSSVector
+	}
 
 	if err := h.db.UpdateVulnerability(id, existing); err != nil {
 		h.logger.Error("更新漏洞失败", zap.Error(err))
@@ -393,6 +510,9 @@ func appendVulnerabilityMarkdown(b *strings.Builder, v *database.Vulnerability,
 	b.WriteString(fmt.Sprintf("%s %s\n\n", titleHeading, v.Title))
 	b.WriteString(fmt.Sprintf("- 漏洞ID: `%s`\n", v.ID))
 	b.WriteString(fmt.Sprintf("- 严重程度: %s\n", v.Severity))
+	if v.CVSSVector != "" {
+		b.WriteString(fmt.Sprintf("- CVSS向量: `%s` (评分: %.1f)\n", v.CVSSVector, v.CVSSScore))
+	}
 	b.WriteString(fmt.Sprintf("- 状态: %s\n", v.Status))
 	if v.Type != "" {
 		b.WriteString(fmt.Sprintf("- 类型: %s\n", v.Type))
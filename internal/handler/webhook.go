@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"strings"
+	"time"
+
+	"cyberstrike-ai/internal/config"
+	"cyberstrike-ai/internal/webhook"
+
+	"go.uber.org/zap"
+)
+
+// WebhookTrigger 在任务完成/失败、发现漏洞时向 cfg.Webhook.Endpoints 中订阅了对应事件类型
+// （且满足 MinSeverity 过滤条件）的端点异步投递签名 JSON；由 app.go 按 cfg.Webhook.Enabled
+// 决定是否构造，未启用时 endpoints 为空，TriggerAsync 直接跳过，与 DefectDojoTrigger/
+// CVEEnrichmentTrigger 采用相同的"可选增强，永不阻塞主流程"约定。
+type WebhookTrigger struct {
+	client    *webhook.Client
+	endpoints []config.WebhookEndpoint
+	logger    *zap.Logger
+}
+
+// NewWebhookTrigger 创建 WebhookTrigger
+func NewWebhookTrigger(client *webhook.Client, endpoints []config.WebhookEndpoint, logger *zap.Logger) *WebhookTrigger {
+	return &WebhookTrigger{client: client, endpoints: endpoints, logger: logger}
+}
+
+// severityRank 把严重程度映射为可比较的等级，未识别的取值视为最低（info）。
+func severityRank(severity string) int {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "critical":
+		return 4
+	case "high":
+		return 3
+	case "medium":
+		return 2
+	case "low":
+		return 1
+	default:
+		return 0
+	}
+}
+
+func webhookSubscribes(ep config.WebhookEndpoint, eventType string) bool {
+	if len(ep.Events) == 0 {
+		return true
+	}
+	for _, e := range ep.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// TriggerAsync 异步向所有订阅了 eventType 的端点投递事件；severity 仅对 vulnerability_found 用于
+// 按端点的 MinSeverity 过滤，其余事件类型传空字符串即可，失败仅记录日志，不影响调用方主流程。
+func (t *WebhookTrigger) TriggerAsync(eventType, severity string, data interface{}) {
+	if t == nil || t.client == nil || len(t.endpoints) == 0 {
+		return
+	}
+	for _, ep := range t.endpoints {
+		if !webhookSubscribes(ep, eventType) {
+			continue
+		}
+		if eventType == "vulnerability_found" && ep.MinSeverity != "" && severityRank(severity) < severityRank(ep.MinSeverity) {
+			continue
+		}
+		endpoint := ep
+		go func() {
+			event := webhook.Event{
+				Type:      eventType,
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+				Data:      data,
+			}
+			if err := t.client.Post(endpoint.URL, endpoint.Secret, event); err != nil {
+				t.logger.Warn("投递 webhook 事件失败",
+					zap.String("url", endpoint.URL),
+					zap.String("event", eventType),
+					zap.Error(err),
+				)
+			}
+		}()
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+
+	"cyberstrike-ai/internal/worker"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// WorkerHandler 远程Worker管理接口：Worker通过 RegisterWorker/Heartbeat 向主服务上报自身，
+// 主服务据此在 Executor.ExecuteTool 中将配置了 RemoteExec 的工具分派给匹配的Worker执行。
+type WorkerHandler struct {
+	manager *worker.Manager
+	logger  *zap.Logger
+}
+
+// NewWorkerHandler 创建Worker管理处理器
+func NewWorkerHandler(manager *worker.Manager, logger *zap.Logger) *WorkerHandler {
+	return &WorkerHandler{manager: manager, logger: logger}
+}
+
+// RegisterWorker 处理Worker注册请求
+func (h *WorkerHandler) RegisterWorker(c *gin.Context) {
+	var req worker.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	w, err := h.manager.RegisterWorker(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "worker已注册",
+		"worker":  w,
+	})
+}
+
+// Heartbeat 处理Worker心跳请求
+func (h *WorkerHandler) Heartbeat(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.manager.Heartbeat(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "心跳已更新"})
+}
+
+// Unregister 处理Worker主动下线请求
+func (h *WorkerHandler) Unregister(c *gin.Context) {
+	id := c.Param("id")
+	h.manager.RemoveWorker(id)
+	c.JSON(http.StatusOK, gin.H{"message": "worker已移除"})
+}
+
+// ListWorkers 返回所有已注册的Worker，供前端展示在线状态
+func (h *WorkerHandler) ListWorkers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"workers": h.manager.ListWorkers(),
+	})
+}
@@ -0,0 +1,181 @@
+// Package jira 提供与 Jira 的集成能力：为确认的漏洞创建/更新工单并上传证据附件。
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client 是 Jira REST API v2 的最小客户端，仅实现工单创建/更新/附件上传所需的能力。
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	email      string
+	apiToken   string
+}
+
+// NewClient 创建 Jira 客户端；httpClient 为 nil 时使用默认超时的 http.Client。
+// 认证方式为 Jira Cloud 的 Basic Auth（email + API Token）。
+func NewClient(baseURL, email, apiToken string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{
+		httpClient: httpClient,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		email:      email,
+		apiToken:   apiToken,
+	}
+}
+
+type issueFields struct {
+	Project     *issueProjectRef `json:"project,omitempty"`
+	Summary     string           `json:"summary,omitempty"`
+	Description string           `json:"description,omitempty"`
+	IssueType   *issueTypeRef    `json:"issuetype,omitempty"`
+}
+
+type issueProjectRef struct {
+	Key string `json:"key"`
+}
+
+type issueTypeRef struct {
+	Name string `json:"name"`
+}
+
+type createIssueRequest struct {
+	Fields map[string]interface{} `json:"fields"`
+}
+
+type createIssueResponse struct {
+	Key string `json:"key"`
+}
+
+// mergedFields 把标准字段与 extraFields（field mapping 配置产出的自定义字段）合并为一个 JSON 对象。
+func mergedFields(base issueFields, extraFields map[string]interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	data, _ := json.Marshal(base)
+	_ = json.Unmarshal(data, &fields)
+	for k, v := range extraFields {
+		fields[k] = v
+	}
+	return fields
+}
+
+// CreateIssue 在指定项目下创建一个新工单，返回新工单的 Key（如 "SEC-123"）。
+func (c *Client) CreateIssue(ctx context.Context, projectKey, issueType, summary, description string, extraFields map[string]interface{}) (string, error) {
+	if c == nil || c.baseURL == "" {
+		return "", fmt.Errorf("jira 客户端未配置 base_url")
+	}
+	fields := mergedFields(issueFields{
+		Project:     &issueProjectRef{Key: projectKey},
+		Summary:     summary,
+		Description: description,
+		IssueType:   &issueTypeRef{Name: issueType},
+	}, extraFields)
+
+	reqBody, err := json.Marshal(createIssueRequest{Fields: fields})
+	if err != nil {
+		return "", fmt.Errorf("序列化Jira创建请求失败: %w", err)
+	}
+
+	respBody, err := c.do(ctx, http.MethodPost, "/rest/api/2/issue", "application/json", bytes.NewReader(reqBody))
+	if err != nil {
+		return "", err
+	}
+	var created createIssueResponse
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return "", fmt.Errorf("解析Jira创建响应失败: %w", err)
+	}
+	if created.Key == "" {
+		return "", fmt.Errorf("Jira创建响应缺少工单编号")
+	}
+	return created.Key, nil
+}
+
+// UpdateIssue 更新已有工单的 summary/description/自定义字段。
+func (c *Client) UpdateIssue(ctx context.Context, issueKey, summary, description string, extraFields map[string]interface{}) error {
+	if c == nil || c.baseURL == "" {
+		return fmt.Errorf("jira 客户端未配置 base_url")
+	}
+	fields := mergedFields(issueFields{Summary: summary, Description: description}, extraFields)
+	reqBody, err := json.Marshal(createIssueRequest{Fields: fields})
+	if err != nil {
+		return fmt.Errorf("序列化Jira更新请求失败: %w", err)
+	}
+	_, err = c.do(ctx, http.MethodPut, "/rest/api/2/issue/"+issueKey, "application/json", bytes.NewReader(reqBody))
+	return err
+}
+
+// AddAttachment 向指定工单上传一个附件（例如漏洞证明截图/日志）。
+func (c *Client) AddAttachment(ctx context.Context, issueKey, filename string, content []byte) error {
+	if c == nil || c.baseURL == "" {
+		return fmt.Errorf("jira 客户端未配置 base_url")
+	}
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return fmt.Errorf("创建附件表单字段失败: %w", err)
+	}
+	if _, err := part.Write(content); err != nil {
+		return fmt.Errorf("写入附件内容失败: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("关闭 multipart writer 失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/rest/api/2/issue/"+issueKey+"/attachments", body)
+	if err != nil {
+		return fmt.Errorf("构造Jira附件请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	// Jira 要求上传附件类请求携带该头以绕过 XSRF 校验
+	req.Header.Set("X-Atlassian-Token", "no-check")
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求Jira附件接口失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("Jira附件接口返回状态码 %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+func (c *Client) authorize(req *http.Request) {
+	if c.email != "" && c.apiToken != "" {
+		req.SetBasicAuth(c.email, c.apiToken)
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path, contentType string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("构造Jira请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求Jira接口失败: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 65536))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("Jira接口返回状态码 %d: %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
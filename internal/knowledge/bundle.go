@@ -0,0 +1,247 @@
+package knowledge
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// bundleManifestVersion 是导出包 manifest.json 的结构版本号，导入时用于判断兼容性。
+const bundleManifestVersion = 1
+
+// bundleManifestFileName 是 tar.gz 导出包内 manifest 条目的文件名。
+const bundleManifestFileName = "manifest.json"
+
+// BundleEmbedding 是导出包中随知识项一起打包的向量分片，供导入方在使用相同 embedding 模型时
+// 直接复用现成向量、跳过重新调用嵌入 API（省 token 和时间）；模型不一致时由调用方决定是否重新索引。
+type BundleEmbedding struct {
+	ChunkIndex     int             `json:"chunk_index"`
+	ChunkText      string          `json:"chunk_text"`
+	Embedding      json.RawMessage `json:"embedding"`
+	SubIndexes     string          `json:"sub_indexes,omitempty"`
+	EmbeddingModel string          `json:"embedding_model,omitempty"`
+	EmbeddingDim   int             `json:"embedding_dim,omitempty"`
+}
+
+// BundleItem 是导出包中的一条知识项，字段与 knowledge_base_items 一一对应；导入时按原 ID 写回，
+// 以保留跨实例的引用关系（如检索日志里记录的 item ID）。
+type BundleItem struct {
+	ID         string            `json:"id"`
+	Category   string            `json:"category"`
+	Title      string            `json:"title"`
+	Content    string            `json:"content"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	Embeddings []BundleEmbedding `json:"embeddings,omitempty"`
+}
+
+// bundleManifest 是 tar.gz 导出包内唯一条目 manifest.json 的内容。
+type bundleManifest struct {
+	Version    int          `json:"version"`
+	ExportedAt time.Time    `json:"exported_at"`
+	Items      []BundleItem `json:"items"`
+}
+
+// ExportBundle 把全部知识项（含内容与已生成的向量分片）打包成 tar.gz，供离线分享给团队成员
+// 或迁移到另一套部署使用。
+func (m *Manager) ExportBundle() ([]byte, error) {
+	items, err := m.GetItems("")
+	if err != nil {
+		return nil, fmt.Errorf("读取知识项失败: %w", err)
+	}
+
+	manifest := bundleManifest{
+		Version:    bundleManifestVersion,
+		ExportedAt: time.Now(),
+		Items:      make([]BundleItem, 0, len(items)),
+	}
+
+	for _, item := range items {
+		embeddings, err := m.getEmbeddingsForBundle(item.ID)
+		if err != nil {
+			return nil, fmt.Errorf("读取知识项 %s 的向量失败: %w", item.ID, err)
+		}
+		manifest.Items = append(manifest.Items, BundleItem{
+			ID:         item.ID,
+			Category:   item.Category,
+			Title:      item.Title,
+			Content:    item.Content,
+			CreatedAt:  item.CreatedAt,
+			UpdatedAt:  item.UpdatedAt,
+			Embeddings: embeddings,
+		})
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化导出包失败: %w", err)
+	}
+
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzWriter)
+
+	if err := tarWriter.WriteHeader(&tar.Header{
+		Name: bundleManifestFileName,
+		Mode: 0644,
+		Size: int64(len(manifestJSON)),
+	}); err != nil {
+		return nil, fmt.Errorf("写入导出包头失败: %w", err)
+	}
+	if _, err := tarWriter.Write(manifestJSON); err != nil {
+		return nil, fmt.Errorf("写入导出包内容失败: %w", err)
+	}
+	if err := tarWriter.Close(); err != nil {
+		return nil, fmt.Errorf("关闭 tar 写入器失败: %w", err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		return nil, fmt.Errorf("关闭 gzip 写入器失败: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// getEmbeddingsForBundle 读取某个知识项的全部向量分片，按 chunk_index 排序。
+func (m *Manager) getEmbeddingsForBundle(itemID string) ([]BundleEmbedding, error) {
+	rows, err := m.db.Query(
+		"SELECT chunk_index, chunk_text, embedding, sub_indexes, embedding_model, embedding_dim FROM knowledge_embeddings WHERE item_id = ? ORDER BY chunk_index",
+		itemID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []BundleEmbedding
+	for rows.Next() {
+		var e BundleEmbedding
+		var embeddingText string
+		if err := rows.Scan(&e.ChunkIndex, &e.ChunkText, &embeddingText, &e.SubIndexes, &e.EmbeddingModel, &e.EmbeddingDim); err != nil {
+			return nil, err
+		}
+		e.Embedding = json.RawMessage(embeddingText)
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// ImportBundle 解析 ExportBundle 产出的 tar.gz，把其中的知识项与向量写回数据库：ID 已存在时
+// 仅在 overwriteExisting 为 true 时覆盖，否则跳过（避免覆盖导入方本地的修改）。文件内容按
+// category/title.md 写回 basePath，保证目录结构和 ScanKnowledgeBase 的视角一致。
+// 返回导入、跳过的数量，以及导入包中没有随附向量、需要调用方另行触发索引的知识项 ID 列表。
+func (m *Manager) ImportBundle(bundleData []byte, overwriteExisting bool) (imported, skipped int, needsIndex []string, err error) {
+	manifest, err := parseBundleManifest(bundleData)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	for _, item := range manifest.Items {
+		exists, existsErr := m.itemExists(item.ID)
+		if existsErr != nil {
+			return imported, skipped, needsIndex, fmt.Errorf("检查知识项 %s 是否存在失败: %w", item.ID, existsErr)
+		}
+		if exists && !overwriteExisting {
+			skipped++
+			continue
+		}
+
+		filePath := filepath.Join(m.basePath, item.Category, item.Title+".md")
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return imported, skipped, needsIndex, fmt.Errorf("创建目录失败: %w", err)
+		}
+		if err := os.WriteFile(filePath, []byte(item.Content), 0644); err != nil {
+			return imported, skipped, needsIndex, fmt.Errorf("写入文件失败: %w", err)
+		}
+
+		if err := m.upsertBundleItem(item, filePath); err != nil {
+			return imported, skipped, needsIndex, err
+		}
+		imported++
+		if len(item.Embeddings) == 0 {
+			needsIndex = append(needsIndex, item.ID)
+		}
+	}
+
+	return imported, skipped, needsIndex, nil
+}
+
+// itemExists 检查指定 ID 的知识项是否已存在。
+func (m *Manager) itemExists(id string) (bool, error) {
+	var count int
+	if err := m.db.QueryRow("SELECT COUNT(*) FROM knowledge_base_items WHERE id = ?", id).Scan(&count); err != nil {
+		return false, fmt.Errorf("查询知识项失败: %w", err)
+	}
+	return count > 0, nil
+}
+
+// upsertBundleItem 在一个事务里写入/覆盖知识项及其向量分片。
+func (m *Manager) upsertBundleItem(item BundleItem, filePath string) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`INSERT INTO knowledge_base_items (id, category, title, file_path, content, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET category=excluded.category, title=excluded.title, file_path=excluded.file_path,
+			content=excluded.content, updated_at=excluded.updated_at`,
+		item.ID, item.Category, item.Title, filePath, item.Content, item.CreatedAt, item.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("写入知识项 %s 失败: %w", item.ID, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM knowledge_embeddings WHERE item_id = ?", item.ID); err != nil {
+		return fmt.Errorf("清理知识项 %s 旧向量失败: %w", item.ID, err)
+	}
+	for _, e := range item.Embeddings {
+		if _, err := tx.Exec(
+			`INSERT INTO knowledge_embeddings (id, item_id, chunk_index, chunk_text, embedding, sub_indexes, embedding_model, embedding_dim, created_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			uuid.New().String(), item.ID, e.ChunkIndex, e.ChunkText, string(e.Embedding), e.SubIndexes, e.EmbeddingModel, e.EmbeddingDim, time.Now(),
+		); err != nil {
+			return fmt.Errorf("写入知识项 %s 向量失败: %w", item.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// parseBundleManifest 从 tar.gz 导入包中提取并解析 manifest.json。
+func parseBundleManifest(data []byte) (*bundleManifest, error) {
+	gzReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解压导入包失败: %w", err)
+	}
+	defer gzReader.Close()
+
+	tarReader := tar.NewReader(gzReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取导入包失败: %w", err)
+		}
+		if header.Name != bundleManifestFileName {
+			continue
+		}
+		var manifest bundleManifest
+		if err := json.NewDecoder(tarReader).Decode(&manifest); err != nil {
+			return nil, fmt.Errorf("解析导入包 manifest 失败: %w", err)
+		}
+		return &manifest, nil
+	}
+	return nil, fmt.Errorf("导入包中未找到 %s，不是有效的知识库导出包", bundleManifestFileName)
+}
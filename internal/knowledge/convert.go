@@ -0,0 +1,201 @@
+package knowledge
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ImportSection 是从导入源（PDF/HTML/URL）按标题切分出的一个待写入知识库的片段。
+type ImportSection struct {
+	Title   string
+	Content string
+}
+
+// HTMLToMarkdown 把一段 HTML 转成粗粒度 Markdown：标题映射为 "#" 层级、段落/列表项换行、
+// <pre>/<code> 保留为代码块、<a> 保留为 "[text](href)"。不追求还原样式，只保证语义结构
+// （标题层级、段落边界）足够支撑后续按标题切分知识项。
+func HTMLToMarkdown(htmlSrc string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(htmlSrc))
+	if err != nil {
+		return "", fmt.Errorf("解析 HTML 失败: %w", err)
+	}
+
+	var b strings.Builder
+	var walk func(n *html.Node)
+	inPre := false
+
+	writeLine := func(s string) {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return
+		}
+		b.WriteString(s)
+		b.WriteString("\n\n")
+	}
+
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Script, atom.Style, atom.Head, atom.Nav, atom.Footer:
+				return // 跳过脚本/样式/导航等对知识内容无意义的节点
+			case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+				level := int(n.DataAtom - atom.H1 + 1)
+				writeLine(strings.Repeat("#", level) + " " + collectText(n))
+				return
+			case atom.P:
+				writeLine(collectInlineText(n))
+				return
+			case atom.Li:
+				writeLine("- " + collectInlineText(n))
+				return
+			case atom.Br:
+				b.WriteString("\n")
+				return
+			case atom.Pre:
+				inPre = true
+				writeLine("```\n" + collectText(n) + "\n```")
+				inPre = false
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == html.TextNode && !inPre {
+			t := strings.TrimSpace(n.Data)
+			if t != "" && n.Parent != nil && n.Parent.DataAtom == atom.Body {
+				writeLine(t)
+			}
+		}
+	}
+	walk(doc)
+
+	md := strings.TrimSpace(b.String())
+	if md == "" {
+		return "", fmt.Errorf("HTML 中未提取到任何文本内容")
+	}
+	return md, nil
+}
+
+// collectText 收集节点及所有后代的纯文本（用于标题、代码块，不处理内联标记）。
+func collectText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.TrimSpace(b.String())
+}
+
+// collectInlineText 收集段落/列表项内的文本，把 <a href> 转成 "[text](href)"，其余内联标签仅取文本。
+func collectInlineText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			return
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			href := ""
+			for _, a := range n.Attr {
+				if a.Key == "href" {
+					href = a.Val
+					break
+				}
+			}
+			text := collectText(n)
+			if href != "" && text != "" {
+				b.WriteString(fmt.Sprintf("[%s](%s)", text, href))
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// SplitMarkdownByHeading 按最高级出现的标题（"# "优先，否则退化到文档中第一次出现的标题级别）
+// 把一份 Markdown 切成多个知识项片段；标题前的内容（若有）单独成一节，标题名为 defaultTitle。
+// 全文没有任何标题时，整份内容作为唯一一节返回。
+func SplitMarkdownByHeading(markdown, defaultTitle string) []ImportSection {
+	lines := strings.Split(markdown, "\n")
+
+	splitLevel := 0
+	for _, line := range lines {
+		if lvl := headingLevel(line); lvl > 0 {
+			if splitLevel == 0 || lvl < splitLevel {
+				splitLevel = lvl
+			}
+		}
+	}
+
+	if splitLevel == 0 {
+		content := strings.TrimSpace(markdown)
+		if content == "" {
+			return nil
+		}
+		return []ImportSection{{Title: defaultTitle, Content: content}}
+	}
+
+	var sections []ImportSection
+	var curTitle string
+	var curLines []string
+	flush := func() {
+		content := strings.TrimSpace(strings.Join(curLines, "\n"))
+		if content == "" {
+			return
+		}
+		title := strings.TrimSpace(curTitle)
+		if title == "" {
+			title = defaultTitle
+		}
+		sections = append(sections, ImportSection{Title: title, Content: content})
+	}
+
+	prefix := strings.Repeat("#", splitLevel) + " "
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), prefix) {
+			flush()
+			curTitle = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), prefix))
+			curLines = nil
+			continue
+		}
+		curLines = append(curLines, line)
+	}
+	flush()
+
+	if len(sections) == 0 {
+		content := strings.TrimSpace(markdown)
+		if content == "" {
+			return nil
+		}
+		return []ImportSection{{Title: defaultTitle, Content: content}}
+	}
+	return sections
+}
+
+// headingLevel 返回一行 Markdown ATX 标题（"# "..."###### "）的级别，非标题行返回 0。
+func headingLevel(line string) int {
+	line = strings.TrimSpace(line)
+	level := 0
+	for level < len(line) && level < 6 && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level >= len(line) || line[level] != ' ' {
+		return 0
+	}
+	return level
+}
@@ -114,7 +114,7 @@ func (h *VectorEinoRetriever) Retrieve(ctx context.Context, query string, opts .
 		_ = callbacks.OnEnd(ctx, &retriever.CallbackOutput{Docs: out})
 	}()
 
-	results, err := h.inner.vectorSearch(ctx, &searchReq)
+	results, err := h.inner.hybridSearch(ctx, &searchReq)
 	if err != nil {
 		return nil, err
 	}
@@ -16,7 +16,48 @@ import (
 	"golang.org/x/time/rate"
 )
 
-// Embedder 使用 CloudWeGo Eino 的 OpenAI Embedding 组件，并保留速率限制与重试。
+// newProviderEmbedder 按 cfg.Embedding.Provider 选择底层 [embedding.Embedder] 实现：
+// "openai"（默认，含 OpenAI 兼容 API，通过 BaseURL 指向自建/代理端点）或 "local"（本地嵌入 sidecar，
+// 见 localSidecarEmbedder，用于离线/内网环境下不依赖任何外部 API 完成知识库索引）。
+func newProviderEmbedder(ctx context.Context, cfg *config.KnowledgeConfig, openAIConfig *config.OpenAIConfig, model string, timeout time.Duration) (embedding.Embedder, error) {
+	provider := strings.ToLower(strings.TrimSpace(cfg.Embedding.Provider))
+
+	switch provider {
+	case "local", "sidecar", "onnx":
+		return newLocalSidecarEmbedder(cfg.Embedding.BaseURL, model, timeout)
+	case "", "openai":
+		baseURL := strings.TrimSpace(cfg.Embedding.BaseURL)
+		baseURL = strings.TrimSuffix(baseURL, "/")
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+
+		apiKey := strings.TrimSpace(cfg.Embedding.APIKey)
+		if apiKey == "" && openAIConfig != nil {
+			apiKey = strings.TrimSpace(openAIConfig.APIKey)
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("embedding API key 未配置")
+		}
+
+		httpClient := &http.Client{Timeout: timeout}
+		inner, err := einoembedopenai.NewEmbedder(ctx, &einoembedopenai.EmbeddingConfig{
+			APIKey:     apiKey,
+			BaseURL:    baseURL,
+			ByAzure:    false,
+			Model:      model,
+			HTTPClient: httpClient,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("eino OpenAI embedder: %w", err)
+		}
+		return inner, nil
+	default:
+		return nil, fmt.Errorf("未知的 embedding provider: %s（支持 openai/local）", cfg.Embedding.Provider)
+	}
+}
+
+// Embedder 使用 CloudWeGo Eino 的 OpenAI Embedding 组件或本地嵌入 sidecar，并保留速率限制与重试。
 type Embedder struct {
 	eino   embedding.Embedder
 	config *config.KnowledgeConfig
@@ -64,35 +105,14 @@ func NewEmbedder(ctx context.Context, cfg *config.KnowledgeConfig, openAIConfig
 		model = "text-embedding-3-small"
 	}
 
-	baseURL := strings.TrimSpace(cfg.Embedding.BaseURL)
-	baseURL = strings.TrimSuffix(baseURL, "/")
-	if baseURL == "" {
-		baseURL = "https://api.openai.com/v1"
-	}
-
-	apiKey := strings.TrimSpace(cfg.Embedding.APIKey)
-	if apiKey == "" && openAIConfig != nil {
-		apiKey = strings.TrimSpace(openAIConfig.APIKey)
-	}
-	if apiKey == "" {
-		return nil, fmt.Errorf("embedding API key 未配置")
-	}
-
 	timeout := 120 * time.Second
 	if cfg.Indexing.RequestTimeoutSeconds > 0 {
 		timeout = time.Duration(cfg.Indexing.RequestTimeoutSeconds) * time.Second
 	}
-	httpClient := &http.Client{Timeout: timeout}
 
-	inner, err := einoembedopenai.NewEmbedder(ctx, &einoembedopenai.EmbeddingConfig{
-		APIKey:     apiKey,
-		BaseURL:    baseURL,
-		ByAzure:    false,
-		Model:      model,
-		HTTPClient: httpClient,
-	})
+	inner, err := newProviderEmbedder(ctx, cfg, openAIConfig, model, timeout)
 	if err != nil {
-		return nil, fmt.Errorf("eino OpenAI embedder: %w", err)
+		return nil, err
 	}
 
 	return &Embedder{
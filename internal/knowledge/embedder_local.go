@@ -0,0 +1,103 @@
+package knowledge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// localSidecarEmbedder 实现 [embedding.Embedder]，通过 HTTP 调用本地部署的嵌入 sidecar
+// （如基于 ONNX Runtime 跑 bge-small 的小服务），使知识库索引在无法访问外部 OpenAI 兼容 API 时也能离线工作。
+// sidecar 约定：POST {BaseURL}/embed，请求体 {"model": "...", "input": [...]}，
+// 响应体 {"embeddings": [[float64, ...], ...]}（与 OpenAI Embeddings API 的常见简化形态一致，
+// 便于用现成的本地嵌入服务框架实现 sidecar，而不必为此项目定制协议）。
+type localSidecarEmbedder struct {
+	endpoint   string
+	model      string
+	httpClient *http.Client
+}
+
+func newLocalSidecarEmbedder(baseURL, model string, timeout time.Duration) (*localSidecarEmbedder, error) {
+	baseURL = strings.TrimSpace(baseURL)
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("local embedding sidecar base_url 未配置")
+	}
+	if timeout <= 0 {
+		timeout = 120 * time.Second
+	}
+	return &localSidecarEmbedder{
+		endpoint:   baseURL + "/embed",
+		model:      model,
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+type localSidecarRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+type localSidecarResponse struct {
+	Embeddings [][]float64 `json:"embeddings"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// EmbedStrings 实现 [embedding.Embedder]；opts 中的 WithModel 会覆盖构造时的默认模型名。
+func (e *localSidecarEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	model := e.model
+	options := embedding.GetCommonOptions(&embedding.Options{}, opts...)
+	if options.Model != nil && *options.Model != "" {
+		model = *options.Model
+	}
+
+	body, err := json.Marshal(localSidecarRequest{Model: model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("序列化本地嵌入请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("构造本地嵌入请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("本地嵌入 sidecar 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取本地嵌入 sidecar 响应失败: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("本地嵌入 sidecar 返回 %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var parsed localSidecarResponse
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return nil, fmt.Errorf("解析本地嵌入 sidecar 响应失败: %w", err)
+	}
+	if parsed.Error != "" {
+		return nil, fmt.Errorf("本地嵌入 sidecar 报错: %s", parsed.Error)
+	}
+	if len(parsed.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("本地嵌入 sidecar 返回向量数(%d)与输入文本数(%d)不一致", len(parsed.Embeddings), len(texts))
+	}
+
+	return parsed.Embeddings, nil
+}
@@ -0,0 +1,281 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cyberstrike-ai/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// advisoryCategory 是 FeedIngester 写入知识项时统一使用的分类，与手动整理的知识条目分开存放，
+// 便于按分类过滤检索或人工审阅。
+const advisoryCategory = "advisories"
+
+// defaultFeedInterval 是未配置 interval_minutes 时的默认拉取间隔。
+const defaultFeedInterval = 60 * time.Minute
+
+// FeedIngester 定期拉取配置的 CVE/安全公告 feed（NVD CVE API、通用 RSS/Atom），把新条目转成
+// advisories 分类下的知识项并触发索引，使 Agent 能拿到较新的漏洞情报，无需人工整理。
+type FeedIngester struct {
+	manager    *Manager
+	indexer    *Indexer
+	logger     *zap.Logger
+	httpClient *http.Client
+	feeds      []config.AdvisoryFeed
+	interval   time.Duration
+}
+
+// NewFeedIngester 创建 feed 拉取器；cfg.IntervalMinutes <= 0 时使用默认间隔（60 分钟）。
+func NewFeedIngester(manager *Manager, indexer *Indexer, logger *zap.Logger, cfg config.AdvisoryFeedsConfig) *FeedIngester {
+	interval := time.Duration(cfg.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = defaultFeedInterval
+	}
+	return &FeedIngester{
+		manager:    manager,
+		indexer:    indexer,
+		logger:     logger,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		feeds:      cfg.Feeds,
+		interval:   interval,
+	}
+}
+
+// Run 启动时立即拉取一次全部 feed，之后按配置的间隔重复拉取，直到 ctx 被取消。调用方通常应以
+// `go ingester.Run(ctx)` 的方式在后台常驻运行。
+func (f *FeedIngester) Run(ctx context.Context) {
+	if len(f.feeds) == 0 {
+		return
+	}
+	f.ingestAll(ctx)
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.ingestAll(ctx)
+		}
+	}
+}
+
+func (f *FeedIngester) ingestAll(ctx context.Context) {
+	for _, feed := range f.feeds {
+		if err := f.ingestFeed(ctx, feed); err != nil {
+			f.logger.Warn("拉取安全公告 feed 失败", zap.String("feed", feed.Name), zap.String("url", feed.URL), zap.Error(err))
+		}
+	}
+}
+
+// ingestFeed 拉取单个 feed，把标题此前未出现过的条目写入知识库（advisories 下条目视为不可变，
+// 已存在同标题的项直接跳过，不做覆盖）。
+func (f *FeedIngester) ingestFeed(ctx context.Context, feed config.AdvisoryFeed) error {
+	entries, err := f.fetchEntries(ctx, feed)
+	if err != nil {
+		return err
+	}
+
+	created := 0
+	for _, e := range entries {
+		title := strings.TrimSpace(e.Title)
+		if title == "" {
+			continue
+		}
+		if _, err := f.manager.GetItemByCategoryTitle(advisoryCategory, title); err == nil {
+			continue
+		}
+
+		item, err := f.manager.CreateItem(advisoryCategory, title, formatAdvisoryContent(feed, e))
+		if err != nil {
+			f.logger.Warn("写入公告知识项失败", zap.String("title", title), zap.Error(err))
+			continue
+		}
+		created++
+
+		if f.indexer != nil {
+			itemID := item.ID
+			go func() {
+				if err := f.indexer.IndexItem(context.Background(), itemID); err != nil {
+					f.logger.Warn("索引公告知识项失败", zap.String("itemId", itemID), zap.Error(err))
+				}
+			}()
+		}
+	}
+
+	if created > 0 {
+		f.logger.Info("安全公告 feed 拉取完成", zap.String("feed", feed.Name), zap.Int("newItems", created))
+	}
+	return nil
+}
+
+// feedEntry 是从各 feed 格式归一化出的一条公告/漏洞记录。
+type feedEntry struct {
+	Title     string
+	Link      string
+	Summary   string
+	Published string
+}
+
+func formatAdvisoryContent(feed config.AdvisoryFeed, e feedEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", e.Title)
+	if e.Published != "" {
+		fmt.Fprintf(&b, "- 发布时间: %s\n", e.Published)
+	}
+	if e.Link != "" {
+		fmt.Fprintf(&b, "- 来源链接: %s\n", e.Link)
+	}
+	fmt.Fprintf(&b, "- Feed: %s\n\n", feed.Name)
+	if strings.TrimSpace(e.Summary) != "" {
+		b.WriteString(strings.TrimSpace(e.Summary))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (f *FeedIngester) fetchEntries(ctx context.Context, feed config.AdvisoryFeed) ([]feedEntry, error) {
+	switch strings.ToLower(strings.TrimSpace(feed.Type)) {
+	case "nvd":
+		return f.fetchNVD(ctx, feed.URL)
+	case "rss", "atom", "":
+		return f.fetchRSS(ctx, feed.URL)
+	default:
+		return nil, fmt.Errorf("未知的 feed 类型: %s（支持 nvd/rss）", feed.Type)
+	}
+}
+
+func (f *FeedIngester) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("构造请求失败: %w", err)
+	}
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("返回状态码 %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// nvdResponse 是 NVD CVE API 2.0 响应的最小子集，见
+// https://nvd.nist.gov/developers/vulnerabilities。
+type nvdResponse struct {
+	Vulnerabilities []struct {
+		CVE struct {
+			ID           string `json:"id"`
+			Published    string `json:"published"`
+			Descriptions []struct {
+				Lang  string `json:"lang"`
+				Value string `json:"value"`
+			} `json:"descriptions"`
+			References []struct {
+				URL string `json:"url"`
+			} `json:"references"`
+		} `json:"cve"`
+	} `json:"vulnerabilities"`
+}
+
+func (f *FeedIngester) fetchNVD(ctx context.Context, url string) ([]feedEntry, error) {
+	body, err := f.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	var resp nvdResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("解析 NVD 响应失败: %w", err)
+	}
+
+	entries := make([]feedEntry, 0, len(resp.Vulnerabilities))
+	for _, v := range resp.Vulnerabilities {
+		summary := ""
+		for _, d := range v.CVE.Descriptions {
+			if d.Lang == "en" {
+				summary = d.Value
+				break
+			}
+		}
+		link := ""
+		if len(v.CVE.References) > 0 {
+			link = v.CVE.References[0].URL
+		}
+		entries = append(entries, feedEntry{
+			Title:     v.CVE.ID,
+			Link:      link,
+			Summary:   summary,
+			Published: v.CVE.Published,
+		})
+	}
+	return entries, nil
+}
+
+// rssFeed 是 RSS 2.0 的最小子集。
+type rssFeed struct {
+	Channel struct {
+		Items []struct {
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			Description string `xml:"description"`
+			PubDate     string `xml:"pubDate"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// atomFeed 是 Atom 的最小子集（Nuclei templates changelog 等 GitHub releases.atom 用此格式）。
+type atomFeed struct {
+	Entries []struct {
+		Title string `xml:"title"`
+		Link  struct {
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+		Summary string `xml:"summary"`
+		Updated string `xml:"updated"`
+	} `xml:"entry"`
+}
+
+func (f *FeedIngester) fetchRSS(ctx context.Context, url string) ([]feedEntry, error) {
+	body, err := f.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var rss rssFeed
+	if err := xml.Unmarshal(body, &rss); err == nil && len(rss.Channel.Items) > 0 {
+		entries := make([]feedEntry, 0, len(rss.Channel.Items))
+		for _, item := range rss.Channel.Items {
+			entries = append(entries, feedEntry{
+				Title:     item.Title,
+				Link:      item.Link,
+				Summary:   item.Description,
+				Published: item.PubDate,
+			})
+		}
+		return entries, nil
+	}
+
+	var atom atomFeed
+	if err := xml.Unmarshal(body, &atom); err != nil {
+		return nil, fmt.Errorf("解析 RSS/Atom 失败: %w", err)
+	}
+	entries := make([]feedEntry, 0, len(atom.Entries))
+	for _, e := range atom.Entries {
+		entries = append(entries, feedEntry{
+			Title:     e.Title,
+			Link:      e.Link.Href,
+			Summary:   e.Summary,
+			Published: e.Updated,
+		})
+	}
+	return entries, nil
+}
@@ -2,7 +2,6 @@ package knowledge
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"strings"
 
@@ -31,15 +30,15 @@ func normalizeChunkStrategy(s string) string {
 func buildKnowledgeIndexChain(
 	ctx context.Context,
 	indexingCfg *config.IndexingConfig,
-	db *sql.DB,
+	store VectorStore,
 	recursive document.Transformer,
 	embeddingModel string,
 ) (compose.Runnable[[]*schema.Document, []string], error) {
 	if recursive == nil {
 		return nil, fmt.Errorf("recursive transformer is nil")
 	}
-	if db == nil {
-		return nil, fmt.Errorf("db is nil")
+	if store == nil {
+		return nil, fmt.Errorf("vector store is nil")
 	}
 	strategy := normalizeChunkStrategy("markdown_then_recursive")
 	batch := 64
@@ -52,7 +51,7 @@ func buildKnowledgeIndexChain(
 		maxChunks = indexingCfg.MaxChunksPerItem
 	}
 
-	si := NewSQLiteIndexer(db, batch, embeddingModel)
+	si := NewVectorStoreIndexer(store, batch, embeddingModel)
 	ch := compose.NewChain[[]*schema.Document, []string]()
 	if strategy != "recursive" {
 		md, err := newMarkdownHeaderSplitter(ctx)
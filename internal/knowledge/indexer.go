@@ -18,7 +18,7 @@ import (
 	"go.uber.org/zap"
 )
 
-// Indexer 使用 Eino Compose 索引链（Markdown/递归分块、Lambda  enrich、SQLite 索引）与嵌入写入。
+// Indexer 使用 Eino Compose 索引链（Markdown/递归分块、Lambda  enrich、向量存储写入）与嵌入写入。
 type Indexer struct {
 	db          *sql.DB
 	embedder    *Embedder
@@ -26,6 +26,7 @@ type Indexer struct {
 	chunkSize   int
 	overlap     int
 	indexingCfg *config.IndexingConfig
+	store       VectorStore
 
 	indexChain compose.Runnable[[]*schema.Document, []string]
 	fileLoader *fileloader.FileLoader
@@ -76,7 +77,12 @@ func NewIndexer(ctx context.Context, db *sql.DB, embedder *Embedder, logger *zap
 		return nil, fmt.Errorf("eino recursive splitter: %w", err)
 	}
 
-	chain, err := buildKnowledgeIndexChain(ctx, indexingCfg, db, splitter, embedModel)
+	store, err := NewVectorStore(db, &kcfg.VectorStore)
+	if err != nil {
+		return nil, fmt.Errorf("向量存储初始化失败: %w", err)
+	}
+
+	chain, err := buildKnowledgeIndexChain(ctx, indexingCfg, store, splitter, embedModel)
 	if err != nil {
 		return nil, fmt.Errorf("knowledge index chain: %w", err)
 	}
@@ -98,25 +104,37 @@ func NewIndexer(ctx context.Context, db *sql.DB, embedder *Embedder, logger *zap
 		chunkSize:   chunkSize,
 		overlap:     overlap,
 		indexingCfg: indexingCfg,
+		store:       store,
 		indexChain:  chain,
 		fileLoader:  fl,
 	}, nil
 }
 
-// RecompileIndexChain 在配置或嵌入模型变更后重建 Eino 索引链（无需重启进程）。
-func (idx *Indexer) RecompileIndexChain(ctx context.Context) error {
+// RecompileIndexChain 在配置或嵌入模型变更后重建 Eino 索引链（无需重启进程）；kcfg 非空时同时
+// 按新配置重新构造向量存储后端（例如运行期切换到 Qdrant/pgvector）。
+func (idx *Indexer) RecompileIndexChain(ctx context.Context, kcfg *config.KnowledgeConfig) error {
 	if idx == nil || idx.db == nil || idx.embedder == nil {
 		return fmt.Errorf("indexer 未初始化")
 	}
 	if err := EnsureKnowledgeEmbeddingsSchema(idx.db); err != nil {
 		return err
 	}
+	if kcfg != nil {
+		store, err := NewVectorStore(idx.db, &kcfg.VectorStore)
+		if err != nil {
+			return fmt.Errorf("向量存储初始化失败: %w", err)
+		}
+		if idx.store != nil {
+			_ = idx.store.Close()
+		}
+		idx.store = store
+	}
 	embedModel := idx.embedder.EmbeddingModelName()
 	splitter, err := newKnowledgeSplitter(idx.chunkSize, idx.overlap, embedModel)
 	if err != nil {
 		return fmt.Errorf("eino recursive splitter: %w", err)
 	}
-	chain, err := buildKnowledgeIndexChain(ctx, idx.indexingCfg, idx.db, splitter, embedModel)
+	chain, err := buildKnowledgeIndexChain(ctx, idx.indexingCfg, idx.store, splitter, embedModel)
 	if err != nil {
 		return fmt.Errorf("knowledge index chain: %w", err)
 	}
@@ -139,8 +157,10 @@ func (idx *Indexer) IndexItem(ctx context.Context, itemID string) error {
 		return fmt.Errorf("获取知识项失败：%w", err)
 	}
 
-	if _, err := idx.db.Exec("DELETE FROM knowledge_embeddings WHERE item_id = ?", itemID); err != nil {
-		return fmt.Errorf("删除旧向量失败：%w", err)
+	if idx.store != nil {
+		if err := idx.store.DeleteItem(ctx, itemID); err != nil {
+			return fmt.Errorf("删除旧向量失败：%w", err)
+		}
 	}
 
 	body := strings.TrimSpace(content)
@@ -205,8 +225,10 @@ func (idx *Indexer) IndexItem(ctx context.Context, itemID string) error {
 
 // HasIndex 检查是否存在索引
 func (idx *Indexer) HasIndex() (bool, error) {
-	var count int
-	err := idx.db.QueryRow("SELECT COUNT(*) FROM knowledge_embeddings").Scan(&count)
+	if idx.store == nil {
+		return false, fmt.Errorf("向量存储未初始化")
+	}
+	count, err := idx.store.Count(context.Background())
 	if err != nil {
 		return false, fmt.Errorf("检查索引失败：%w", err)
 	}
@@ -341,6 +341,12 @@ func (m *Manager) GetItemsWithOptions(category string, limit, offset int, includ
 			item.UpdatedAt = item.CreatedAt
 		}
 
+		tags, err := m.loadItemTags(item.ID)
+		if err != nil {
+			return nil, err
+		}
+		item.Tags = tags
+
 		items = append(items, item)
 	}
 
@@ -531,12 +537,174 @@ func (m *Manager) GetItemsSummary(category string, limit, offset int) ([]*Knowle
 			item.UpdatedAt = item.CreatedAt
 		}
 
+		tags, err := m.loadItemTags(item.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		item.Tags = tags
+
 		items = append(items, item)
 	}
 
 	return items, total, nil
 }
 
+// loadItemTags 加载某知识项的标签列表（按标签名排序，便于展示稳定）
+func (m *Manager) loadItemTags(itemID string) ([]string, error) {
+	rows, err := m.db.Query("SELECT tag FROM knowledge_item_tags WHERE item_id = ? ORDER BY tag", itemID)
+	if err != nil {
+		return nil, fmt.Errorf("查询知识项标签失败: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("扫描知识项标签失败: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// loadItemMetadata 加载某知识项的元数据键值对
+func (m *Manager) loadItemMetadata(itemID string) (map[string]string, error) {
+	rows, err := m.db.Query("SELECT key, value FROM knowledge_item_metadata WHERE item_id = ?", itemID)
+	if err != nil {
+		return nil, fmt.Errorf("查询知识项元数据失败: %w", err)
+	}
+	defer rows.Close()
+
+	metadata := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("扫描知识项元数据失败: %w", err)
+		}
+		metadata[key] = value
+	}
+	return metadata, rows.Err()
+}
+
+// SetItemTags 覆盖设置知识项标签（先清空旧标签再写入新标签，空切片等价于清空全部标签）
+func (m *Manager) SetItemTags(itemID string, tags []string) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM knowledge_item_tags WHERE item_id = ?", itemID); err != nil {
+		return fmt.Errorf("清空旧标签失败: %w", err)
+	}
+
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		if _, err := tx.Exec("INSERT INTO knowledge_item_tags (item_id, tag) VALUES (?, ?)", itemID, tag); err != nil {
+			return fmt.Errorf("写入标签失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// SetItemMetadata 覆盖设置知识项元数据（先清空旧值再写入新值，空 map 等价于清空全部元数据）
+func (m *Manager) SetItemMetadata(itemID string, metadata map[string]string) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM knowledge_item_metadata WHERE item_id = ?", itemID); err != nil {
+		return fmt.Errorf("清空旧元数据失败: %w", err)
+	}
+
+	for key, value := range metadata {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if _, err := tx.Exec("INSERT INTO knowledge_item_metadata (item_id, key, value) VALUES (?, ?, ?)", itemID, key, value); err != nil {
+			return fmt.Errorf("写入元数据失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// GetItemsByTag 按标签查找知识项摘要（tag 需与 knowledge_item_tags 中的值完全一致）
+func (m *Manager) GetItemsByTag(tag string) ([]*KnowledgeItemSummary, error) {
+	rows, err := m.db.Query(`
+		SELECT i.id, i.category, i.title, i.file_path, i.created_at, i.updated_at
+		FROM knowledge_base_items i
+		JOIN knowledge_item_tags t ON t.item_id = i.id
+		WHERE t.tag = ?
+		ORDER BY i.category, i.title`, tag)
+	if err != nil {
+		return nil, fmt.Errorf("按标签查询知识项失败: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*KnowledgeItemSummary
+	for rows.Next() {
+		item := &KnowledgeItemSummary{}
+		var createdAt, updatedAt string
+		if err := rows.Scan(&item.ID, &item.Category, &item.Title, &item.FilePath, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("扫描知识项失败: %w", err)
+		}
+
+		timeFormats := []string{
+			"2006-01-02 15:04:05.999999999-07:00",
+			"2006-01-02 15:04:05.999999999",
+			"2006-01-02T15:04:05.999999999Z07:00",
+			"2006-01-02T15:04:05Z",
+			"2006-01-02 15:04:05",
+			time.RFC3339,
+			time.RFC3339Nano,
+		}
+		if createdAt != "" {
+			for _, format := range timeFormats {
+				if parsed, err := time.Parse(format, createdAt); err == nil && !parsed.IsZero() {
+					item.CreatedAt = parsed
+					break
+				}
+			}
+		}
+		if updatedAt != "" {
+			for _, format := range timeFormats {
+				if parsed, err := time.Parse(format, updatedAt); err == nil && !parsed.IsZero() {
+					item.UpdatedAt = parsed
+					break
+				}
+			}
+		}
+		if item.UpdatedAt.IsZero() && !item.CreatedAt.IsZero() {
+			item.UpdatedAt = item.CreatedAt
+		}
+
+		tags, err := m.loadItemTags(item.ID)
+		if err != nil {
+			return nil, err
+		}
+		item.Tags = tags
+		items = append(items, item)
+	}
+	return items, nil
+}
+
 // GetItem 获取单个知识项
 func (m *Manager) GetItem(id string) (*KnowledgeItem, error) {
 	item := &KnowledgeItem{}
@@ -591,9 +759,52 @@ func (m *Manager) GetItem(id string) (*KnowledgeItem, error) {
 		item.UpdatedAt = item.CreatedAt
 	}
 
+	tags, err := m.loadItemTags(item.ID)
+	if err != nil {
+		return nil, err
+	}
+	item.Tags = tags
+
+	metadata, err := m.loadItemMetadata(item.ID)
+	if err != nil {
+		return nil, err
+	}
+	item.Metadata = metadata
+
 	return item, nil
 }
 
+// GetItemByFilePath 按文件路径查找知识项，供 Watcher 在检测到磁盘文件被删除/重命名后
+// 定位对应记录使用。
+func (m *Manager) GetItemByFilePath(path string) (*KnowledgeItem, error) {
+	var id string
+	err := m.db.QueryRow("SELECT id FROM knowledge_base_items WHERE file_path = ?", path).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("知识项不存在")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询知识项失败: %w", err)
+	}
+	return m.GetItem(id)
+}
+
+// GetItemByCategoryTitle 按分类+标题查找知识项，供 MCP knowledge:// 资源（URI 形如
+// knowledge://category/title，比内部 ID 对外部 MCP 客户端更易读）按需读取内容使用。
+func (m *Manager) GetItemByCategoryTitle(category, title string) (*KnowledgeItem, error) {
+	var id string
+	err := m.db.QueryRow(
+		"SELECT id FROM knowledge_base_items WHERE category = ? AND title = ?",
+		category, title,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("知识项不存在")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询知识项失败: %w", err)
+	}
+	return m.GetItem(id)
+}
+
 // CreateItem 创建知识项
 func (m *Manager) CreateItem(category, title, content string) (*KnowledgeItem, error) {
 	id := uuid.New().String()
@@ -632,14 +843,40 @@ func (m *Manager) CreateItem(category, title, content string) (*KnowledgeItem, e
 	}, nil
 }
 
-// UpdateItem 更新知识项
-func (m *Manager) UpdateItem(id, category, title, content string) (*KnowledgeItem, error) {
+// CreateItemWithTags 创建知识项并同时写入标签与元数据（CWE 编号、受影响产品等），
+// 语义等价于 CreateItem 后再调用 SetItemTags/SetItemMetadata。
+func (m *Manager) CreateItemWithTags(category, title, content string, tags []string, metadata map[string]string) (*KnowledgeItem, error) {
+	item, err := m.CreateItem(category, title, content)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		if err := m.SetItemTags(item.ID, tags); err != nil {
+			return nil, err
+		}
+		item.Tags = tags
+	}
+	if len(metadata) > 0 {
+		if err := m.SetItemMetadata(item.ID, metadata); err != nil {
+			return nil, err
+		}
+		item.Metadata = metadata
+	}
+	return item, nil
+}
+
+// UpdateItem 更新知识项，并在覆盖前将原内容存为一条历史版本（author 为空时记为"未知"）
+func (m *Manager) UpdateItem(id, category, title, content, author string) (*KnowledgeItem, error) {
 	// 获取现有项
 	item, err := m.GetItem(id)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := m.snapshotVersion(item, author); err != nil {
+		m.logger.Warn("保存知识项历史版本失败", zap.String("itemId", id), zap.Error(err))
+	}
+
 	// 构建新文件路径
 	newFilePath := filepath.Join(m.basePath, category, title+".md")
 
@@ -690,6 +927,139 @@ func (m *Manager) UpdateItem(id, category, title, content string) (*KnowledgeIte
 	return m.GetItem(id)
 }
 
+// snapshotVersion 将 item 的当前内容存为下一个版本号的历史快照
+func (m *Manager) snapshotVersion(item *KnowledgeItem, author string) error {
+	if author == "" {
+		author = "未知"
+	}
+	var nextVersion int
+	if err := m.db.QueryRow(
+		"SELECT COALESCE(MAX(version), 0) + 1 FROM knowledge_item_versions WHERE item_id = ?", item.ID,
+	).Scan(&nextVersion); err != nil {
+		return fmt.Errorf("计算版本号失败: %w", err)
+	}
+
+	_, err := m.db.Exec(
+		"INSERT INTO knowledge_item_versions (id, item_id, version, category, title, content, author, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		uuid.New().String(), item.ID, nextVersion, item.Category, item.Title, item.Content, author, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("插入历史版本失败: %w", err)
+	}
+	return nil
+}
+
+// GetItemVersions 获取知识项的历史版本列表（按版本号倒序，不含内容以减小响应体积）
+func (m *Manager) GetItemVersions(itemID string) ([]*KnowledgeItemVersion, error) {
+	rows, err := m.db.Query(
+		"SELECT id, item_id, version, category, title, author, created_at FROM knowledge_item_versions WHERE item_id = ? ORDER BY version DESC",
+		itemID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询历史版本失败: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make([]*KnowledgeItemVersion, 0)
+	for rows.Next() {
+		v := &KnowledgeItemVersion{}
+		var createdAt time.Time
+		if err := rows.Scan(&v.ID, &v.ItemID, &v.Version, &v.Category, &v.Title, &v.Author, &createdAt); err != nil {
+			return nil, fmt.Errorf("扫描历史版本失败: %w", err)
+		}
+		v.CreatedAt = createdAt
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// GetItemVersion 获取知识项某个历史版本的完整内容
+func (m *Manager) GetItemVersion(itemID string, version int) (*KnowledgeItemVersion, error) {
+	v := &KnowledgeItemVersion{}
+	var createdAt time.Time
+	err := m.db.QueryRow(
+		"SELECT id, item_id, version, category, title, content, author, created_at FROM knowledge_item_versions WHERE item_id = ? AND version = ?",
+		itemID, version,
+	).Scan(&v.ID, &v.ItemID, &v.Version, &v.Category, &v.Title, &v.Content, &v.Author, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("版本不存在")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询历史版本失败: %w", err)
+	}
+	v.CreatedAt = createdAt
+	return v, nil
+}
+
+// DiffItemVersion 比较知识项某个历史版本与当前内容的逐行差异；version 为 0 时表示与最新一个历史版本比较
+func (m *Manager) DiffItemVersion(itemID string, version int) ([]DiffLine, error) {
+	oldContent, err := m.GetItemVersion(itemID, version)
+	if err != nil {
+		return nil, err
+	}
+	current, err := m.GetItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+	return diffLines(oldContent.Content, current.Content), nil
+}
+
+// RestoreItemVersion 将知识项回滚到指定历史版本：当前内容先被存为新版本，再用历史内容覆盖
+func (m *Manager) RestoreItemVersion(itemID string, version int, author string) (*KnowledgeItem, error) {
+	target, err := m.GetItemVersion(itemID, version)
+	if err != nil {
+		return nil, err
+	}
+	return m.UpdateItem(itemID, target.Category, target.Title, target.Content, author)
+}
+
+// diffLines 基于最长公共子序列的逐行 diff，返回 equal/add/remove 三种操作组成的行序列
+func diffLines(oldText, newText string) []DiffLine {
+	oldLines := strings.Split(oldText, "\n")
+	newLines := strings.Split(newText, "\n")
+	n, m2 := len(oldLines), len(newLines)
+
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m2+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m2 - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	result := make([]DiffLine, 0, n+m2)
+	i, j := 0, 0
+	for i < n && j < m2 {
+		switch {
+		case oldLines[i] == newLines[j]:
+			result = append(result, DiffLine{Op: "equal", Text: oldLines[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			result = append(result, DiffLine{Op: "remove", Text: oldLines[i]})
+			i++
+		default:
+			result = append(result, DiffLine{Op: "add", Text: newLines[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		result = append(result, DiffLine{Op: "remove", Text: oldLines[i]})
+	}
+	for ; j < m2; j++ {
+		result = append(result, DiffLine{Op: "add", Text: newLines[j]})
+	}
+	return result
+}
+
 // DeleteItem 删除知识项
 func (m *Manager) DeleteItem(id string) error {
 	// 获取文件路径
@@ -0,0 +1,41 @@
+package knowledge
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// PDFToText 调用系统安装的 poppler-utils `pdftotext` 命令，把 PDF 转成纯文本，供知识库导入管线
+// 进一步按标题切分。本项目不内置 PDF 解析库（与 attackchain.RenderPNG 依赖系统 Graphviz 是同一思路），
+// 若部署环境未安装 poppler-utils，会返回明确的错误提示而不是静默失败或伪造内容。
+func PDFToText(pdfBytes []byte) (string, error) {
+	pdftotextPath, err := exec.LookPath("pdftotext")
+	if err != nil {
+		return "", fmt.Errorf("导入 PDF 需要系统安装 poppler-utils（pdftotext 命令），当前环境未检测到: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp("", "knowledge-import-*.pdf")
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(pdfBytes); err != nil {
+		tmpFile.Close()
+		return "", fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return "", fmt.Errorf("关闭临时文件失败: %w", err)
+	}
+
+	// "-" 表示输出到 stdout，保留段落换行（-layout 会尝试还原原始排版，标题切分对此不敏感，故不启用）
+	cmd := exec.Command(pdftotextPath, tmpFile.Name(), "-")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("解析 PDF 失败: %w (%s)", err, stderr.String())
+	}
+	return stdout.String(), nil
+}
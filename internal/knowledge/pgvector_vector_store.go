@@ -0,0 +1,200 @@
+package knowledge
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"cyberstrike-ai/internal/config"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+const defaultPgvectorTable = "knowledge_vectors"
+
+// pgvectorVectorStore 通过 Postgres + pgvector 扩展实现 VectorStore，用 pgvector 的 `<=>`
+// 余弦距离操作符在数据库侧排序，比 SQLite 侧把向量整行取出再在 Go 里算余弦更适合大规模知识库。
+// 需要目标 Postgres 已安装 pgvector 扩展（本进程只会 CREATE EXTENSION IF NOT EXISTS，无法在
+// 数据库不支持时自行安装），这与 pdftotext/Graphviz 等"调用外部能力失败则如实报错"的做法一致。
+type pgvectorVectorStore struct {
+	db    *sql.DB
+	table string
+}
+
+func newPgvectorVectorStore(cfg config.PgvectorConfig) (*pgvectorVectorStore, error) {
+	dsn := strings.TrimSpace(cfg.DSN)
+	if dsn == "" {
+		return nil, fmt.Errorf("pgvector dsn 未配置")
+	}
+	table := strings.TrimSpace(cfg.Table)
+	if table == "" {
+		table = defaultPgvectorTable
+	}
+	if !isValidPgIdentifier(table) {
+		return nil, fmt.Errorf("pgvector table 名称非法: %s", table)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("连接 pgvector 数据库失败: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("连接 pgvector 数据库失败: %w", err)
+	}
+
+	store := &pgvectorVectorStore{db: db, table: table}
+	if err := store.ensureSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// isValidPgIdentifier 只允许字母数字下划线且不以数字开头，防止 table 名被拼接进 SQL 造成注入
+// （表名无法用占位符参数化，只能在构造阶段校验）。
+func isValidPgIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_'
+		isDigit := r >= '0' && r <= '9'
+		if i == 0 && !isLetter {
+			return false
+		}
+		if !isLetter && !isDigit {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *pgvectorVectorStore) ensureSchema() error {
+	if _, err := p.db.Exec(`CREATE EXTENSION IF NOT EXISTS vector`); err != nil {
+		return fmt.Errorf("启用 pgvector 扩展失败（需数据库已安装 pgvector）: %w", err)
+	}
+	if _, err := p.db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			item_id TEXT NOT NULL,
+			chunk_index INTEGER NOT NULL,
+			chunk_text TEXT NOT NULL,
+			category TEXT NOT NULL DEFAULT '',
+			title TEXT NOT NULL DEFAULT '',
+			sub_indexes TEXT NOT NULL DEFAULT '',
+			embedding_model TEXT NOT NULL DEFAULT '',
+			embedding vector NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`, p.table)); err != nil {
+		return fmt.Errorf("创建 %s 表失败: %w", p.table, err)
+	}
+	if _, err := p.db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_item_id_idx ON %s(item_id)`, p.table, p.table)); err != nil {
+		return fmt.Errorf("创建 %s 索引失败: %w", p.table, err)
+	}
+	return nil
+}
+
+// vectorLiteral 把 []float32 编码为 pgvector 接受的文本字面量 "[v1,v2,...]"。
+func vectorLiteral(v []float32) string {
+	parts := make([]string, len(v))
+	for i, f := range v {
+		parts[i] = strconv.FormatFloat(float64(f), 'g', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+func (p *pgvectorVectorStore) Upsert(ctx context.Context, itemID string, chunks []VectorChunk) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("pgvector: begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE item_id = $1`, p.table), itemID); err != nil {
+		return fmt.Errorf("pgvector: 删除旧分块失败: %w", err)
+	}
+
+	insertSQL := fmt.Sprintf(`INSERT INTO %s (id, item_id, chunk_index, chunk_text, category, title, sub_indexes, embedding_model, embedding)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::vector)`, p.table)
+	for i, c := range chunks {
+		id := c.ChunkID
+		if id == "" {
+			id = uuid.New().String()
+		}
+		if _, err := tx.ExecContext(ctx, insertSQL,
+			id, itemID, c.ChunkIndex, c.ChunkText, c.Category, c.Title, c.SubIndexes, c.Model, vectorLiteral(c.Embedding),
+		); err != nil {
+			return fmt.Errorf("pgvector: 写入分块 %d 失败: %w", i, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (p *pgvectorVectorStore) DeleteItem(ctx context.Context, itemID string) error {
+	if _, err := p.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE item_id = $1`, p.table), itemID); err != nil {
+		return fmt.Errorf("pgvector: 删除知识项分块失败: %w", err)
+	}
+	return nil
+}
+
+func (p *pgvectorVectorStore) Search(ctx context.Context, queryVector []float32, limit int, filter VectorSearchFilter) ([]VectorHit, error) {
+	q := fmt.Sprintf(`SELECT id, item_id, chunk_index, chunk_text, category, title, sub_indexes, embedding_model,
+		1 - (embedding <=> $1::vector) AS similarity
+		FROM %s WHERE 1=1`, p.table)
+	args := []interface{}{vectorLiteral(queryVector)}
+	argIdx := 2
+	if cat := strings.TrimSpace(filter.Category); cat != "" {
+		q += fmt.Sprintf(" AND category = $%d", argIdx)
+		args = append(args, cat)
+		argIdx++
+	}
+	if model := strings.TrimSpace(filter.Model); model != "" {
+		q += fmt.Sprintf(" AND (embedding_model = '' OR embedding_model = $%d)", argIdx)
+		args = append(args, model)
+		argIdx++
+	}
+	q += fmt.Sprintf(" ORDER BY embedding <=> $1::vector LIMIT $%d", argIdx)
+	args = append(args, limit)
+
+	rows, err := p.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("pgvector: 查询失败: %w", err)
+	}
+	defer rows.Close()
+
+	tag := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(filter.SubIndexFilter), " ", ""))
+	var hits []VectorHit
+	for rows.Next() {
+		var h VectorHit
+		var subIdx, model string
+		if err := rows.Scan(&h.ChunkID, &h.ItemID, &h.ChunkIndex, &h.ChunkText, &h.Category, &h.Title, &subIdx, &model, &h.Similarity); err != nil {
+			return nil, fmt.Errorf("pgvector: 扫描结果失败: %w", err)
+		}
+		if tag != "" && strings.TrimSpace(subIdx) != "" {
+			if !strings.Contains(","+strings.ToLower(strings.ReplaceAll(subIdx, " ", ""))+",", ","+tag+",") {
+				continue
+			}
+		}
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
+
+func (p *pgvectorVectorStore) Count(ctx context.Context) (int, error) {
+	var count int
+	err := p.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(DISTINCT item_id) FROM %s`, p.table)).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("pgvector: count: %w", err)
+	}
+	return count, nil
+}
+
+func (p *pgvectorVectorStore) Close() error {
+	return p.db.Close()
+}
+
+var _ VectorStore = (*pgvectorVectorStore)(nil)
@@ -0,0 +1,243 @@
+package knowledge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cyberstrike-ai/internal/config"
+
+	"github.com/google/uuid"
+)
+
+const defaultQdrantCollection = "cyberstrike_knowledge"
+
+// qdrantVectorStore 通过 Qdrant 的 REST API 实现 VectorStore，不引入额外的 Qdrant 客户端库。
+// 每个分块作为一个 point 存入，payload 里冗余保存 chunk 正文与展示元数据（category/title/
+// sub_indexes/embedding_model），使 Qdrant 后端自给自足，不依赖 SQLite 继续保有分块数据。
+type qdrantVectorStore struct {
+	baseURL    string
+	collection string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newQdrantVectorStore(cfg config.QdrantConfig) (*qdrantVectorStore, error) {
+	baseURL := strings.TrimRight(strings.TrimSpace(cfg.URL), "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("qdrant url 未配置")
+	}
+	collection := strings.TrimSpace(cfg.Collection)
+	if collection == "" {
+		collection = defaultQdrantCollection
+	}
+	return &qdrantVectorStore{
+		baseURL:    baseURL,
+		collection: collection,
+		apiKey:     strings.TrimSpace(cfg.APIKey),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+type qdrantPayload struct {
+	ItemID     string `json:"item_id"`
+	ChunkIndex int    `json:"chunk_index"`
+	ChunkText  string `json:"chunk_text"`
+	Category   string `json:"category"`
+	Title      string `json:"title"`
+	SubIndexes string `json:"sub_indexes"`
+	Model      string `json:"embedding_model"`
+}
+
+// doJSON 发送一个 JSON 请求并把响应体解析进 out（out 为 nil 时忽略响应体），非 2xx 状态码
+// 视为错误，错误信息里附带响应体前 512 字节以便排查。
+func (q *qdrantVectorStore) doJSON(ctx context.Context, method, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("qdrant: 序列化请求体失败: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, q.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("qdrant: 构造请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if q.apiKey != "" {
+		req.Header.Set("api-key", q.apiKey)
+	}
+
+	resp, err := q.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("qdrant: 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet := string(respBody)
+		if len(snippet) > 512 {
+			snippet = snippet[:512]
+		}
+		return fmt.Errorf("qdrant: %s %s 返回 %d: %s", method, path, resp.StatusCode, snippet)
+	}
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("qdrant: 解析响应失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// ensureCollection 确保集合存在，不存在则以 dim 维、余弦距离创建；已存在时直接跳过。
+func (q *qdrantVectorStore) ensureCollection(ctx context.Context, dim int) error {
+	err := q.doJSON(ctx, http.MethodGet, "/collections/"+q.collection, nil, nil)
+	if err == nil {
+		return nil
+	}
+	if dim <= 0 {
+		return fmt.Errorf("qdrant: 集合 %s 不存在且无法推断向量维度", q.collection)
+	}
+	createBody := map[string]any{
+		"vectors": map[string]any{
+			"size":     dim,
+			"distance": "Cosine",
+		},
+	}
+	return q.doJSON(ctx, http.MethodPut, "/collections/"+q.collection, createBody, nil)
+}
+
+func (q *qdrantVectorStore) Upsert(ctx context.Context, itemID string, chunks []VectorChunk) error {
+	if err := q.DeleteItem(ctx, itemID); err != nil {
+		return err
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+	if err := q.ensureCollection(ctx, len(chunks[0].Embedding)); err != nil {
+		return fmt.Errorf("qdrant: 确保集合存在失败: %w", err)
+	}
+
+	points := make([]map[string]any, 0, len(chunks))
+	for _, c := range chunks {
+		id := c.ChunkID
+		if id == "" {
+			id = uuid.New().String()
+		}
+		points = append(points, map[string]any{
+			"id":     id,
+			"vector": c.Embedding,
+			"payload": qdrantPayload{
+				ItemID:     itemID,
+				ChunkIndex: c.ChunkIndex,
+				ChunkText:  c.ChunkText,
+				Category:   c.Category,
+				Title:      c.Title,
+				SubIndexes: c.SubIndexes,
+				Model:      c.Model,
+			},
+		})
+	}
+
+	return q.doJSON(ctx, http.MethodPut, "/collections/"+q.collection+"/points?wait=true", map[string]any{"points": points}, nil)
+}
+
+func (q *qdrantVectorStore) DeleteItem(ctx context.Context, itemID string) error {
+	body := map[string]any{
+		"filter": map[string]any{
+			"must": []map[string]any{
+				{"key": "item_id", "match": map[string]any{"value": itemID}},
+			},
+		},
+	}
+	err := q.doJSON(ctx, http.MethodPost, "/collections/"+q.collection+"/points/delete?wait=true", body, nil)
+	if err != nil && strings.Contains(err.Error(), "404") {
+		// 集合尚未创建，视为"本来就没有"
+		return nil
+	}
+	return err
+}
+
+func (q *qdrantVectorStore) Search(ctx context.Context, queryVector []float32, limit int, filter VectorSearchFilter) ([]VectorHit, error) {
+	must := make([]map[string]any, 0, 2)
+	if cat := strings.TrimSpace(filter.Category); cat != "" {
+		must = append(must, map[string]any{"key": "category", "match": map[string]any{"value": cat}})
+	}
+	if model := strings.TrimSpace(filter.Model); model != "" {
+		must = append(must, map[string]any{"key": "embedding_model", "match": map[string]any{"value": model}})
+	}
+
+	body := map[string]any{
+		"vector":       queryVector,
+		"limit":        limit,
+		"with_payload": true,
+	}
+	if len(must) > 0 {
+		body["filter"] = map[string]any{"must": must}
+	}
+
+	var resp struct {
+		Result []struct {
+			ID      string        `json:"id"`
+			Score   float64       `json:"score"`
+			Payload qdrantPayload `json:"payload"`
+		} `json:"result"`
+	}
+	if err := q.doJSON(ctx, http.MethodPost, "/collections/"+q.collection+"/points/search", body, &resp); err != nil {
+		if strings.Contains(err.Error(), "404") {
+			// 集合尚未创建，等价于"暂无索引"
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Qdrant filter DSL 不便表达 sub_indexes 逗号列表的"包含其一"语义，客户端侧过滤。
+	tag := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(filter.SubIndexFilter), " ", ""))
+	hits := make([]VectorHit, 0, len(resp.Result))
+	for _, r := range resp.Result {
+		if tag != "" && strings.TrimSpace(r.Payload.SubIndexes) != "" {
+			if !strings.Contains(","+strings.ToLower(strings.ReplaceAll(r.Payload.SubIndexes, " ", ""))+",", ","+tag+",") {
+				continue
+			}
+		}
+		hits = append(hits, VectorHit{
+			ChunkID:    r.ID,
+			ItemID:     r.Payload.ItemID,
+			ChunkIndex: r.Payload.ChunkIndex,
+			ChunkText:  r.Payload.ChunkText,
+			Category:   r.Payload.Category,
+			Title:      r.Payload.Title,
+			Similarity: r.Score,
+		})
+	}
+	return hits, nil
+}
+
+func (q *qdrantVectorStore) Count(ctx context.Context) (int, error) {
+	var resp struct {
+		Result struct {
+			PointsCount int `json:"points_count"`
+		} `json:"result"`
+	}
+	if err := q.doJSON(ctx, http.MethodGet, "/collections/"+q.collection, nil, &resp); err != nil {
+		if strings.Contains(err.Error(), "404") {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return resp.Result.PointsCount, nil
+}
+
+func (q *qdrantVectorStore) Close() error {
+	return nil
+}
+
+var _ VectorStore = (*qdrantVectorStore)(nil)
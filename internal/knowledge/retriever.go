@@ -3,7 +3,6 @@ package knowledge
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"math"
 	"sort"
@@ -25,6 +24,9 @@ type Retriever struct {
 	config   *RetrievalConfig
 	logger   *zap.Logger
 
+	storeMu sync.RWMutex
+	store   VectorStore
+
 	rerankMu sync.RWMutex
 	reranker DocumentReranker
 }
@@ -36,18 +38,45 @@ type RetrievalConfig struct {
 	// SubIndexFilter 非空时仅检索 sub_indexes 包含该标签（逗号分隔之一）的行；空 sub_indexes 的旧行仍保留以兼容。
 	SubIndexFilter string
 	PostRetrieve   config.PostRetrieveConfig
+	// HybridSearch 为 true 时用 RRF 融合向量检索与 FTS5 BM25 全文检索（见 hybridSearch）；
+	// FTS5 未编译时自动降级为纯向量检索，因此默认开启也是安全的。
+	HybridSearch bool
 }
 
-// NewRetriever 创建新的检索器
+// NewRetriever 创建新的检索器；默认使用内置 SQLite 向量存储，运行期可通过 SetVectorStore
+// 切换到 Qdrant/pgvector 等后端（见 knowledge.NewVectorStore）。
 func NewRetriever(db *sql.DB, embedder *Embedder, config *RetrievalConfig, logger *zap.Logger) *Retriever {
 	return &Retriever{
 		db:       db,
 		embedder: embedder,
 		config:   config,
 		logger:   logger,
+		store:    newSQLiteVectorStore(db),
 	}
 }
 
+// SetVectorStore 注入可选的向量存储后端（并发安全）；nil 时回落到内置 SQLite 后端。
+func (r *Retriever) SetVectorStore(store VectorStore) {
+	if r == nil {
+		return
+	}
+	r.storeMu.Lock()
+	defer r.storeMu.Unlock()
+	if store == nil {
+		store = newSQLiteVectorStore(r.db)
+	}
+	r.store = store
+}
+
+func (r *Retriever) vectorStore() VectorStore {
+	if r == nil {
+		return nil
+	}
+	r.storeMu.RLock()
+	defer r.storeMu.RUnlock()
+	return r.store
+}
+
 // UpdateConfig 更新检索配置
 func (r *Retriever) UpdateConfig(cfg *RetrievalConfig) {
 	if cfg != nil {
@@ -57,6 +86,7 @@ func (r *Retriever) UpdateConfig(cfg *RetrievalConfig) {
 				zap.Int("top_k", cfg.TopK),
 				zap.Float64("similarity_threshold", cfg.SimilarityThreshold),
 				zap.String("sub_index_filter", cfg.SubIndexFilter),
+				zap.Bool("hybrid_search", cfg.HybridSearch),
 				zap.Int("post_retrieve_prefetch_top_k", cfg.PostRetrieve.PrefetchTopK),
 				zap.Int("post_retrieve_max_context_chars", cfg.PostRetrieve.MaxContextChars),
 				zap.Int("post_retrieve_max_context_tokens", cfg.PostRetrieve.MaxContextTokens),
@@ -146,31 +176,9 @@ func (r *Retriever) EinoRetrieve(ctx context.Context, query string, opts ...retr
 	return NewVectorEinoRetriever(r).Retrieve(ctx, query, opts...)
 }
 
-func (r *Retriever) knowledgeEmbeddingSelectSQL(riskType, subIndexFilter string) (string, []interface{}) {
-	q := `SELECT e.id, e.item_id, e.chunk_index, e.chunk_text, e.embedding, e.embedding_model, e.embedding_dim, i.category, i.title
-FROM knowledge_embeddings e
-JOIN knowledge_base_items i ON e.item_id = i.id
-WHERE 1=1`
-	var args []interface{}
-	if strings.TrimSpace(riskType) != "" {
-		q += ` AND TRIM(i.category) = TRIM(?) COLLATE NOCASE`
-		args = append(args, riskType)
-	}
-	if tag := strings.TrimSpace(subIndexFilter); tag != "" {
-		tag = strings.ToLower(strings.ReplaceAll(tag, " ", ""))
-		q += ` AND (TRIM(COALESCE(e.sub_indexes,'')) = '' OR INSTR(',' || LOWER(REPLACE(e.sub_indexes,' ','')) || ',', ',' || ? || ',') > 0)`
-		args = append(args, tag)
-	}
-	return q, args
-}
-
-// vectorSearch 纯向量检索：余弦相似度排序，按相似度阈值与 TopK 截断（无 BM25、无混合分、无邻块扩展）。
-func (r *Retriever) vectorSearch(ctx context.Context, req *SearchRequest) ([]*RetrievalResult, error) {
-	if req.Query == "" {
-		return nil, fmt.Errorf("查询不能为空")
-	}
-
-	topK := req.TopK
+// resolveSearchParams 解析请求携带的 TopK/相似度阈值/子索引过滤，缺省时回落到检索器配置，再回落到内置默认值。
+func (r *Retriever) resolveSearchParams(req *SearchRequest) (topK int, threshold float64, subIdxFilter string) {
+	topK = req.TopK
 	if topK <= 0 && r.config != nil {
 		topK = r.config.TopK
 	}
@@ -178,7 +186,7 @@ func (r *Retriever) vectorSearch(ctx context.Context, req *SearchRequest) ([]*Re
 		topK = 5
 	}
 
-	threshold := req.Threshold
+	threshold = req.Threshold
 	if threshold <= 0 && r.config != nil {
 		threshold = r.config.SimilarityThreshold
 	}
@@ -186,117 +194,344 @@ func (r *Retriever) vectorSearch(ctx context.Context, req *SearchRequest) ([]*Re
 		threshold = 0.7
 	}
 
-	subIdxFilter := strings.TrimSpace(req.SubIndexFilter)
+	subIdxFilter = strings.TrimSpace(req.SubIndexFilter)
 	if subIdxFilter == "" && r.config != nil {
 		subIdxFilter = strings.TrimSpace(r.config.SubIndexFilter)
 	}
+	return topK, threshold, subIdxFilter
+}
 
+// maxVectorCandidates 限制 vectorCandidates 单次向 VectorStore 取回的候选数量。SQLite 后端此前
+// 直接全表扫描、不做任何截断，但这正是可插拔向量存储要解决的规模瓶颈，所以这里统一给三个后端
+// 一个足够宽（覆盖 vectorSearch 的 TopK 截断与 hybridSearch 的 RRF 预取窗口）但非无限的上限。
+const maxVectorCandidates = 500
+
+// vectorCandidates 按余弦相似度降序返回候选（不做阈值截断，仅受 maxVectorCandidates 限制），
+// 供 vectorSearch 与 hybridSearch（RRF 融合前的向量侧候选集）复用；实际存取经由可插拔的
+// [VectorStore]（默认 SQLite，见 NewVectorStore/SetVectorStore）。
+func (r *Retriever) vectorCandidates(ctx context.Context, req *SearchRequest, subIdxFilter string) ([]*RetrievalResult, error) {
 	queryText := FormatQueryEmbeddingText(req.RiskType, req.Query)
 	queryEmbedding, err := r.embedder.EmbedText(ctx, queryText)
 	if err != nil {
 		return nil, fmt.Errorf("向量化查询失败: %w", err)
 	}
-	queryDim := len(queryEmbedding)
+	queryVec := make([]float32, len(queryEmbedding))
+	for i, v := range queryEmbedding {
+		queryVec[i] = float32(v)
+	}
 	expectedModel := ""
 	if r.embedder != nil {
 		expectedModel = r.embedder.EmbeddingModelName()
 	}
 
-	sqlStr, sqlArgs := r.knowledgeEmbeddingSelectSQL(strings.TrimSpace(req.RiskType), subIdxFilter)
-	rows, err := r.db.QueryContext(ctx, sqlStr, sqlArgs...)
+	store := r.vectorStore()
+	if store == nil {
+		return nil, fmt.Errorf("向量存储未初始化")
+	}
+	hits, err := store.Search(ctx, queryVec, maxVectorCandidates, VectorSearchFilter{
+		Category:       strings.TrimSpace(req.RiskType),
+		SubIndexFilter: subIdxFilter,
+		Model:          expectedModel,
+		Dim:            len(queryVec),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("查询向量失败: %w", err)
 	}
-	defer rows.Close()
 
-	type candidate struct {
-		chunk      *KnowledgeChunk
-		item       *KnowledgeItem
-		similarity float64
+	results := make([]*RetrievalResult, len(hits))
+	for i, h := range hits {
+		results[i] = &RetrievalResult{
+			Chunk: &KnowledgeChunk{
+				ID:         h.ChunkID,
+				ItemID:     h.ItemID,
+				ChunkIndex: h.ChunkIndex,
+				ChunkText:  h.ChunkText,
+			},
+			Item: &KnowledgeItem{
+				ID:       h.ItemID,
+				Category: h.Category,
+				Title:    h.Title,
+			},
+			Similarity: h.Similarity,
+			Score:      h.Similarity,
+		}
 	}
+	return results, nil
+}
 
-	candidates := make([]candidate, 0)
-	rowNum := 0
+// vectorSearch 纯向量检索：余弦相似度排序，按相似度阈值与 TopK 截断（无 BM25、无混合分、无邻块扩展）。
+func (r *Retriever) vectorSearch(ctx context.Context, req *SearchRequest) ([]*RetrievalResult, error) {
+	if req.Query == "" {
+		return nil, fmt.Errorf("查询不能为空")
+	}
+
+	topK, threshold, subIdxFilter := r.resolveSearchParams(req)
+
+	candidates, err := r.vectorCandidates(ctx, req, subIdxFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]*RetrievalResult, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Similarity >= threshold {
+			filtered = append(filtered, c)
+		}
+	}
+	filtered = r.applyTagFilterAndBoost(ctx, req, filtered)
+	if len(filtered) > topK {
+		filtered = filtered[:topK]
+	}
+	return filtered, nil
+}
+
+// tagMatchBoost 是查询词命中知识项标签时叠加到 Score 上的增量，使标签匹配的知识项排序更靠前；
+// 不改变 Similarity（阈值截断仍按真实余弦相似度进行）。
+const tagMatchBoost = 0.03
+
+// itemTags 返回某知识项的标签列表（见 knowledge_item_tags，由 Manager.SetItemTags 维护）；
+// 表不存在（极端情况下数据库未迁移到位）时静默返回空，不影响检索主流程。
+func (r *Retriever) itemTags(ctx context.Context, itemID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT tag FROM knowledge_item_tags WHERE item_id = ?`, itemID)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "no such table") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
 	for rows.Next() {
-		rowNum++
-		if rowNum%48 == 0 {
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			default:
-			}
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
 		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
 
-		var chunkID, itemID, chunkText, embeddingJSON, category, title, rowModel string
-		var chunkIndex, rowDim int
+// applyTagFilterAndBoost 按 req.TagFilter 过滤候选（语义与 SubIndexFilter 一致：仅保留标签命中
+// 过滤集合之一的项，未打标的旧项仍保留以兼容），并对标签在查询词中出现的项叠加 tagMatchBoost、
+// 按 Score 重新排序，使标签匹配的知识项在结果中更靠前。
+func (r *Retriever) applyTagFilterAndBoost(ctx context.Context, req *SearchRequest, results []*RetrievalResult) []*RetrievalResult {
+	tagFilter := strings.TrimSpace(req.TagFilter)
+	var wanted map[string]bool
+	if tagFilter != "" {
+		wanted = make(map[string]bool)
+		for _, t := range strings.Split(tagFilter, ",") {
+			if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+				wanted[t] = true
+			}
+		}
+	}
+	queryLower := strings.ToLower(req.Query)
+
+	filtered := make([]*RetrievalResult, 0, len(results))
+	for _, res := range results {
+		tags, err := r.itemTags(ctx, res.Item.ID)
+		if err != nil {
+			r.logger.Warn("查询知识项标签失败", zap.String("itemId", res.Item.ID), zap.Error(err))
+			tags = nil
+		}
 
-		if err := rows.Scan(&chunkID, &itemID, &chunkIndex, &chunkText, &embeddingJSON, &rowModel, &rowDim, &category, &title); err != nil {
-			r.logger.Warn("扫描向量失败", zap.Error(err))
-			continue
+		if len(wanted) > 0 {
+			matched := len(tags) == 0
+			for _, t := range tags {
+				if wanted[strings.ToLower(t)] {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
 		}
 
-		var embedding []float32
-		if err := json.Unmarshal([]byte(embeddingJSON), &embedding); err != nil {
-			r.logger.Warn("解析向量失败", zap.Error(err))
-			continue
+		for _, t := range tags {
+			if t != "" && strings.Contains(queryLower, strings.ToLower(t)) {
+				res.Score += tagMatchBoost
+				break
+			}
 		}
+		filtered = append(filtered, res)
+	}
 
-		if rowDim > 0 && len(embedding) != rowDim {
-			r.logger.Debug("跳过维度不一致的向量行", zap.String("chunkId", chunkID), zap.Int("rowDim", rowDim), zap.Int("got", len(embedding)))
-			continue
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Score > filtered[j].Score
+	})
+	return filtered
+}
+
+// ftsCandidate 是 knowledge_fts（FTS5 BM25 全文索引，见 database.DB.migrateKnowledgeFTS）命中的知识项，
+// item 粒度而非 chunk 粒度：FTS5 索引建在 knowledge_base_items.content 上。
+type ftsCandidate struct {
+	itemID string
+	rank   int // 1-based，按 BM25 排序后的名次，用于 RRF 融合
+}
+
+// ftsSearch 对 knowledge_fts 做 BM25 全文检索；表不存在时（SQLite 编译未启用 fts5 扩展，见
+// database.DB.migrateKnowledgeFTS 的降级说明）返回空结果而非报错，调用方据此静默退化为纯向量检索。
+func (r *Retriever) ftsSearch(ctx context.Context, query string, limit int) ([]ftsCandidate, error) {
+	q := strings.TrimSpace(query)
+	if q == "" || limit <= 0 {
+		return nil, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT item_id FROM knowledge_fts WHERE knowledge_fts MATCH ? ORDER BY bm25(knowledge_fts) LIMIT ?`,
+		ftsMatchExpr(q), limit)
+	if err != nil {
+		errMsg := strings.ToLower(err.Error())
+		if strings.Contains(errMsg, "no such table") || strings.Contains(errMsg, "no such module") {
+			return nil, nil
 		}
-		if queryDim > 0 && len(embedding) != queryDim {
-			r.logger.Debug("跳过与查询维度不一致的向量", zap.String("chunkId", chunkID), zap.Int("queryDim", queryDim), zap.Int("got", len(embedding)))
+		return nil, fmt.Errorf("全文检索失败: %w", err)
+	}
+	defer rows.Close()
+
+	var out []ftsCandidate
+	rank := 0
+	for rows.Next() {
+		var itemID string
+		if err := rows.Scan(&itemID); err != nil {
+			r.logger.Warn("扫描全文检索结果失败", zap.Error(err))
 			continue
 		}
-		if expectedModel != "" && strings.TrimSpace(rowModel) != "" && strings.TrimSpace(rowModel) != expectedModel {
-			r.logger.Debug("跳过嵌入模型不一致的行", zap.String("chunkId", chunkID), zap.String("rowModel", rowModel), zap.String("expected", expectedModel))
-			continue
+		rank++
+		out = append(out, ftsCandidate{itemID: itemID, rank: rank})
+	}
+	return out, rows.Err()
+}
+
+// ftsMatchExpr 把用户查询转成 FTS5 MATCH 表达式：按空白切词后逐词加双引号、用 OR 连接。
+// 逐词加引号既保留了对 CVE 编号、工具参数（含 "-"、"."、"/" 等符号）的原样匹配，
+// 又避免了 FTS5 查询语法把裸露符号解释成运算符导致 MATCH 直接报语法错误。
+func ftsMatchExpr(q string) string {
+	fields := strings.Fields(q)
+	if len(fields) == 0 {
+		return `""`
+	}
+	quoted := make([]string, len(fields))
+	for i, f := range fields {
+		quoted[i] = `"` + strings.ReplaceAll(f, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " OR ")
+}
+
+// bestChunkForItem 取某知识项的首个分块，用于把「FTS 命中但向量候选集里没有」的知识项也补进混合检索结果
+// （否则这类项只有 item_id、没有可展示的 chunk 内容，无法生成 RetrievalResult）。
+func (r *Retriever) bestChunkForItem(ctx context.Context, itemID string) (*RetrievalResult, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT e.id, e.chunk_index, e.chunk_text, i.category, i.title
+		FROM knowledge_embeddings e
+		JOIN knowledge_base_items i ON e.item_id = i.id
+		WHERE e.item_id = ?
+		ORDER BY e.chunk_index ASC
+		LIMIT 1`, itemID)
+
+	var chunkID, chunkText, category, title string
+	var chunkIndex int
+	if err := row.Scan(&chunkID, &chunkIndex, &chunkText, &category, &title); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
 		}
+		return nil, err
+	}
 
-		similarity := cosineSimilarity(queryEmbedding, embedding)
-		candidates = append(candidates, candidate{
-			chunk: &KnowledgeChunk{
-				ID:         chunkID,
-				ItemID:     itemID,
-				ChunkIndex: chunkIndex,
-				ChunkText:  chunkText,
-				Embedding:  embedding,
-			},
-			item: &KnowledgeItem{
-				ID:       itemID,
-				Category: category,
-				Title:    title,
-			},
-			similarity: similarity,
-		})
+	return &RetrievalResult{
+		Chunk: &KnowledgeChunk{ID: chunkID, ItemID: itemID, ChunkIndex: chunkIndex, ChunkText: chunkText},
+		Item:  &KnowledgeItem{ID: itemID, Category: category, Title: title},
+	}, nil
+}
+
+// rrfK 是倒数排名融合（reciprocal rank fusion）的平滑常数，取常见默认值 60（名次差异对头部结果影响更平滑）。
+const rrfK = 60
+
+// hybridSearch 用 RRF 融合向量检索（chunk 粒度余弦相似度排序）与 FTS5 BM25 全文检索（item 粒度）：
+// 对 CVE 编号、工具命令行参数等嵌入模型经常算不准、但字符串精确匹配能命中的查询有明显召回提升。
+// 由于两路排名量纲不同，融合后不再按原始余弦阈值截断，只按 RRF 分数排序取 TopK。
+// r.config.HybridSearch 为 false，或 FTS5 未编译（knowledge_fts 表不存在）时静默退化为 vectorSearch。
+func (r *Retriever) hybridSearch(ctx context.Context, req *SearchRequest) ([]*RetrievalResult, error) {
+	if req.Query == "" {
+		return nil, fmt.Errorf("查询不能为空")
+	}
+	if r.config == nil || !r.config.HybridSearch {
+		return r.vectorSearch(ctx, req)
 	}
 
-	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].similarity > candidates[j].similarity
-	})
+	topK, _, subIdxFilter := r.resolveSearchParams(req)
+	prefetchK := topK * 4
+	if prefetchK < 20 {
+		prefetchK = 20
+	}
 
-	filtered := make([]candidate, 0, len(candidates))
-	for _, c := range candidates {
-		if c.similarity >= threshold {
-			filtered = append(filtered, c)
+	vectorResults, err := r.vectorCandidates(ctx, req, subIdxFilter)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectorResults) > prefetchK {
+		vectorResults = vectorResults[:prefetchK]
+	}
+
+	ftsHits, err := r.ftsSearch(ctx, req.Query, prefetchK)
+	if err != nil {
+		r.logger.Warn("全文检索失败，降级为纯向量检索", zap.Error(err))
+		ftsHits = nil
+	}
+	if len(ftsHits) == 0 {
+		if len(vectorResults) > topK {
+			vectorResults = vectorResults[:topK]
 		}
+		return vectorResults, nil
 	}
 
-	if len(filtered) > topK {
-		filtered = filtered[:topK]
+	ftsRank := make(map[string]int, len(ftsHits))
+	seenItems := make(map[string]bool, len(vectorResults))
+	for _, h := range ftsHits {
+		if _, exists := ftsRank[h.itemID]; !exists {
+			ftsRank[h.itemID] = h.rank
+		}
 	}
 
-	results := make([]*RetrievalResult, len(filtered))
-	for i, c := range filtered {
-		results[i] = &RetrievalResult{
-			Chunk:      c.chunk,
-			Item:       c.item,
-			Similarity: c.similarity,
-			Score:      c.similarity,
+	type fused struct {
+		result *RetrievalResult
+		score  float64
+	}
+	fusedList := make([]fused, 0, len(vectorResults)+len(ftsHits))
+	for i, res := range vectorResults {
+		seenItems[res.Item.ID] = true
+		score := 1.0 / float64(rrfK+i+1)
+		if rank, ok := ftsRank[res.Item.ID]; ok {
+			score += 1.0 / float64(rrfK+rank)
 		}
+		fusedList = append(fusedList, fused{result: res, score: score})
 	}
-	return results, nil
+
+	// 补上只被 FTS 命中、向量候选集里没有的知识项（如查询词是精确字符串但语义上离向量空间中心较远）。
+	for _, h := range ftsHits {
+		if seenItems[h.itemID] {
+			continue
+		}
+		seenItems[h.itemID] = true
+		res, err := r.bestChunkForItem(ctx, h.itemID)
+		if err != nil || res == nil {
+			continue
+		}
+		fusedList = append(fusedList, fused{result: res, score: 1.0 / float64(rrfK+h.rank)})
+	}
+
+	ranked := make([]*RetrievalResult, len(fusedList))
+	for i, f := range fusedList {
+		f.result.Score = f.score
+		ranked[i] = f.result
+	}
+	ranked = r.applyTagFilterAndBoost(ctx, req, ranked)
+	if len(ranked) > topK {
+		ranked = ranked[:topK]
+	}
+	return ranked, nil
 }
 
 // AsEinoRetriever 将纯向量检索暴露为 Eino [retriever.Retriever]。
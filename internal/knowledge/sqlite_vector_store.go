@@ -0,0 +1,152 @@
+package knowledge
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// sqliteVectorStore 是默认的向量存储后端，直接读写既有的 knowledge_embeddings 表，
+// 行为与重构前内联在 eino_sqlite_indexer.go/retriever.go 中的 SQL 完全一致。
+type sqliteVectorStore struct {
+	db *sql.DB
+}
+
+func newSQLiteVectorStore(db *sql.DB) *sqliteVectorStore {
+	return &sqliteVectorStore{db: db}
+}
+
+func (s *sqliteVectorStore) Upsert(ctx context.Context, itemID string, chunks []VectorChunk) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite vector store: begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM knowledge_embeddings WHERE item_id = ?", itemID); err != nil {
+		return fmt.Errorf("sqlite vector store: 删除旧分块失败: %w", err)
+	}
+
+	for i, c := range chunks {
+		chunkID := c.ChunkID
+		if chunkID == "" {
+			chunkID = uuid.New().String()
+		}
+		embeddingJSON, err := json.Marshal(c.Embedding)
+		if err != nil {
+			return fmt.Errorf("sqlite vector store: 序列化向量失败: %w", err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO knowledge_embeddings (id, item_id, chunk_index, chunk_text, embedding, sub_indexes, embedding_model, embedding_dim, created_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))`,
+			chunkID, itemID, c.ChunkIndex, c.ChunkText, string(embeddingJSON), c.SubIndexes, c.Model, c.Dim,
+		)
+		if err != nil {
+			return fmt.Errorf("sqlite vector store: 写入分块 %d 失败: %w", i, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqliteVectorStore) DeleteItem(ctx context.Context, itemID string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM knowledge_embeddings WHERE item_id = ?", itemID); err != nil {
+		return fmt.Errorf("sqlite vector store: 删除知识项分块失败: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteVectorStore) Search(ctx context.Context, queryVector []float32, limit int, filter VectorSearchFilter) ([]VectorHit, error) {
+	q := `SELECT e.id, e.item_id, e.chunk_index, e.chunk_text, e.embedding, e.embedding_model, e.embedding_dim, i.category, i.title
+FROM knowledge_embeddings e
+JOIN knowledge_base_items i ON e.item_id = i.id
+WHERE 1=1`
+	var args []interface{}
+	if cat := strings.TrimSpace(filter.Category); cat != "" {
+		q += ` AND TRIM(i.category) = TRIM(?) COLLATE NOCASE`
+		args = append(args, cat)
+	}
+	if tag := strings.TrimSpace(filter.SubIndexFilter); tag != "" {
+		tag = strings.ToLower(strings.ReplaceAll(tag, " ", ""))
+		q += ` AND (TRIM(COALESCE(e.sub_indexes,'')) = '' OR INSTR(',' || LOWER(REPLACE(e.sub_indexes,' ','')) || ',', ',' || ? || ',') > 0)`
+		args = append(args, tag)
+	}
+
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite vector store: 查询向量失败: %w", err)
+	}
+	defer rows.Close()
+
+	queryDim := len(queryVector)
+	var hits []VectorHit
+	rowNum := 0
+	for rows.Next() {
+		rowNum++
+		if rowNum%48 == 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			default:
+			}
+		}
+
+		var chunkID, itemID, chunkText, embeddingJSON, rowModel, category, title string
+		var chunkIndex, rowDim int
+		if err := rows.Scan(&chunkID, &itemID, &chunkIndex, &chunkText, &embeddingJSON, &rowModel, &rowDim, &category, &title); err != nil {
+			continue
+		}
+
+		var embedding []float32
+		if err := json.Unmarshal([]byte(embeddingJSON), &embedding); err != nil {
+			continue
+		}
+		if rowDim > 0 && len(embedding) != rowDim {
+			continue
+		}
+		if queryDim > 0 && len(embedding) != queryDim {
+			continue
+		}
+		if filter.Model != "" && strings.TrimSpace(rowModel) != "" && strings.TrimSpace(rowModel) != filter.Model {
+			continue
+		}
+
+		hits = append(hits, VectorHit{
+			ChunkID:    chunkID,
+			ItemID:     itemID,
+			ChunkIndex: chunkIndex,
+			ChunkText:  chunkText,
+			Category:   category,
+			Title:      title,
+			Similarity: cosineSimilarity(queryVector, embedding),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sortHitsBySimilarityDesc(hits)
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+func (s *sqliteVectorStore) Count(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(DISTINCT item_id) FROM knowledge_embeddings").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite vector store: count: %w", err)
+	}
+	return count, nil
+}
+
+func (s *sqliteVectorStore) Close() error {
+	return nil
+}
+
+var _ VectorStore = (*sqliteVectorStore)(nil)
@@ -15,13 +15,15 @@ func formatTime(t time.Time) string {
 
 // KnowledgeItem 知识库项
 type KnowledgeItem struct {
-	ID        string    `json:"id"`
-	Category  string    `json:"category"` // 风险类型（文件夹名）
-	Title     string    `json:"title"`    // 标题（文件名）
-	FilePath  string    `json:"filePath"` // 文件路径
-	Content   string    `json:"content"`  // 文件内容
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID        string            `json:"id"`
+	Category  string            `json:"category"`           // 风险类型（文件夹名）
+	Title     string            `json:"title"`              // 标题（文件名）
+	FilePath  string            `json:"filePath"`           // 文件路径
+	Content   string            `json:"content"`            // 文件内容
+	Tags      []string          `json:"tags,omitempty"`     // 标签（见 knowledge_item_tags，Manager.SetItemTags 维护）
+	Metadata  map[string]string `json:"metadata,omitempty"` // 任意键值元数据，如 CWE 编号、受影响产品
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
 }
 
 // KnowledgeItemSummary 知识库项摘要（用于列表，不包含完整内容）
@@ -31,6 +33,7 @@ type KnowledgeItemSummary struct {
 	Title     string    `json:"title"`
 	FilePath  string    `json:"filePath"`
 	Content   string    `json:"content,omitempty"` // 可选：内容预览（如果提供，通常只包含前 150 字符）
+	Tags      []string  `json:"tags,omitempty"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
@@ -113,11 +116,42 @@ type CategoryWithItems struct {
 	Items     []*KnowledgeItemSummary `json:"items"`     // 该分类下的知识项列表
 }
 
+// KnowledgeItemVersion 知识项历史版本快照
+type KnowledgeItemVersion struct {
+	ID        string    `json:"id"`
+	ItemID    string    `json:"itemId"`
+	Version   int       `json:"version"`
+	Category  string    `json:"category"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// MarshalJSON 自定义 JSON 序列化，确保时间格式正确
+func (v *KnowledgeItemVersion) MarshalJSON() ([]byte, error) {
+	type Alias KnowledgeItemVersion
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt string `json:"createdAt"`
+	}{
+		Alias:     (*Alias)(v),
+		CreatedAt: formatTime(v.CreatedAt),
+	})
+}
+
+// DiffLine 逐行文本差异中的一行
+type DiffLine struct {
+	Op   string `json:"op"` // "equal" | "add" | "remove"
+	Text string `json:"text"`
+}
+
 // SearchRequest 搜索请求
 type SearchRequest struct {
 	Query          string  `json:"query"`
 	RiskType       string  `json:"riskType,omitempty"`       // 可选：指定风险类型
 	SubIndexFilter string  `json:"subIndexFilter,omitempty"` // 可选：仅保留 sub_indexes 含该标签的行（含未打标旧数据）
+	TagFilter      string  `json:"tagFilter,omitempty"`      // 可选：仅保留标签含该标签之一的知识项（逗号分隔，含未打标旧数据）
 	TopK           int     `json:"topK,omitempty"`           // 返回 Top-K 结果，默认 5
 	Threshold      float64 `json:"threshold,omitempty"`      // 相似度阈值，默认 0.7
 }
@@ -0,0 +1,155 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+)
+
+// VectorStoreIndexer implements [indexer.Indexer], embedding documents and writing chunk rows
+// through a pluggable [VectorStore] (SQLite by default; Qdrant/pgvector for larger knowledge
+// bases, see NewVectorStore). A single Store call normally carries the chunks for one knowledge
+// item, but is grouped by kb_item_id defensively in case a future caller batches several items.
+type VectorStoreIndexer struct {
+	store          VectorStore
+	batchSize      int
+	embeddingModel string
+}
+
+// NewVectorStoreIndexer returns an indexer that writes chunk rows through store.
+// batchSize is the embedding batch size; if <= 0, default 64 is used.
+// embeddingModel is persisted per row for retrieval-time consistency checks (may be empty).
+func NewVectorStoreIndexer(store VectorStore, batchSize int, embeddingModel string) *VectorStoreIndexer {
+	return &VectorStoreIndexer{store: store, batchSize: batchSize, embeddingModel: strings.TrimSpace(embeddingModel)}
+}
+
+// GetType implements eino callback run info.
+func (s *VectorStoreIndexer) GetType() string {
+	return "VectorStoreKnowledgeIndexer"
+}
+
+// Store embeds documents and upserts rows. Each doc must carry MetaData:
+// kb_item_id, kb_category, kb_title, kb_chunk_index (int). Content is chunk text only.
+func (s *VectorStoreIndexer) Store(ctx context.Context, docs []*schema.Document, opts ...indexer.Option) (ids []string, err error) {
+	options := indexer.GetCommonOptions(nil, opts...)
+	if options.Embedding == nil {
+		return nil, fmt.Errorf("vector store indexer: embedding is required")
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	ctx = callbacks.EnsureRunInfo(ctx, s.GetType(), components.ComponentOfIndexer)
+	ctx = callbacks.OnStart(ctx, &indexer.CallbackInput{Docs: docs})
+	defer func() {
+		if err != nil {
+			_ = callbacks.OnError(ctx, err)
+			return
+		}
+		_ = callbacks.OnEnd(ctx, &indexer.CallbackOutput{IDs: ids})
+	}()
+
+	subIdxStr := strings.Join(options.SubIndexes, ",")
+
+	texts := make([]string, len(docs))
+	for i, d := range docs {
+		if d == nil {
+			return nil, fmt.Errorf("vector store indexer: nil document at %d", i)
+		}
+		cat := MetaLookupString(d.MetaData, metaKBCategory)
+		title := MetaLookupString(d.MetaData, metaKBTitle)
+		texts[i] = FormatEmbeddingInput(cat, title, d.Content)
+	}
+
+	bs := s.batchSize
+	if bs <= 0 {
+		bs = 64
+	}
+
+	var allVecs [][]float64
+	for start := 0; start < len(texts); start += bs {
+		end := start + bs
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch := texts[start:end]
+		vecs, embedErr := options.Embedding.EmbedStrings(ctx, batch)
+		if embedErr != nil {
+			return nil, fmt.Errorf("vector store indexer: embed batch %d-%d: %w", start, end, embedErr)
+		}
+		if len(vecs) != len(batch) {
+			return nil, fmt.Errorf("vector store indexer: embed count mismatch: got %d want %d", len(vecs), len(batch))
+		}
+		allVecs = append(allVecs, vecs...)
+	}
+
+	embedDim := 0
+	if len(allVecs) > 0 {
+		embedDim = len(allVecs[0])
+	}
+
+	type itemGroup struct {
+		chunks   []VectorChunk
+		chunkIDs []string
+	}
+	groups := make(map[string]*itemGroup)
+	order := make([]string, 0, 4)
+
+	for i, d := range docs {
+		chunkID := uuid.New().String()
+		itemID, metaErr := RequireMetaString(d.MetaData, metaKBItemID)
+		if metaErr != nil {
+			return nil, fmt.Errorf("vector store indexer: doc %d: %w", i, metaErr)
+		}
+		chunkIdx, metaErr := RequireMetaInt(d.MetaData, metaKBChunkIndex)
+		if metaErr != nil {
+			return nil, fmt.Errorf("vector store indexer: doc %d: %w", i, metaErr)
+		}
+		vec := allVecs[i]
+		if embedDim > 0 && len(vec) != embedDim {
+			return nil, fmt.Errorf("vector store indexer: inconsistent embedding dim at doc %d: got %d want %d", i, len(vec), embedDim)
+		}
+		vec32 := make([]float32, len(vec))
+		for j, v := range vec {
+			vec32[j] = float32(v)
+		}
+
+		g, ok := groups[itemID]
+		if !ok {
+			g = &itemGroup{}
+			groups[itemID] = g
+			order = append(order, itemID)
+		}
+		g.chunks = append(g.chunks, VectorChunk{
+			ChunkID:    chunkID,
+			ChunkIndex: chunkIdx,
+			ChunkText:  d.Content,
+			Embedding:  vec32,
+			SubIndexes: subIdxStr,
+			Category:   MetaLookupString(d.MetaData, metaKBCategory),
+			Title:      MetaLookupString(d.MetaData, metaKBTitle),
+			Model:      s.embeddingModel,
+			Dim:        embedDim,
+		})
+		g.chunkIDs = append(g.chunkIDs, chunkID)
+	}
+
+	ids = make([]string, 0, len(docs))
+	for _, itemID := range order {
+		g := groups[itemID]
+		if err := s.store.Upsert(ctx, itemID, g.chunks); err != nil {
+			return nil, fmt.Errorf("vector store indexer: upsert item %s: %w", itemID, err)
+		}
+		ids = append(ids, g.chunkIDs...)
+	}
+
+	return ids, nil
+}
+
+var _ indexer.Indexer = (*VectorStoreIndexer)(nil)
@@ -0,0 +1,88 @@
+package knowledge
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cyberstrike-ai/internal/config"
+)
+
+// VectorChunk 是写入 VectorStore 的一个分块及其向量与展示元数据。各后端实现都要求自身
+// 独立保存 ChunkText/Category/Title/SubIndexes（而不是仅存向量），这样切换后端时不需要
+// SQLite 继续保留分块正文——见各实现的注释。
+type VectorChunk struct {
+	ChunkID    string // 为空时由实现自行生成
+	ChunkIndex int
+	ChunkText  string
+	Embedding  []float32
+	SubIndexes string
+	Category   string
+	Title      string
+	Model      string
+	Dim        int
+}
+
+// VectorHit 是一次 Search 命中的结果。
+type VectorHit struct {
+	ChunkID    string
+	ItemID     string
+	ChunkIndex int
+	ChunkText  string
+	Category   string
+	Title      string
+	Similarity float64
+}
+
+// VectorSearchFilter 是 Search 的过滤条件，语义与 Retriever.knowledgeEmbeddingSelectSQL 一致。
+type VectorSearchFilter struct {
+	Category       string // 精确匹配知识项分类（大小写不敏感），为空表示不过滤
+	SubIndexFilter string // 命中 sub_indexes 逗号列表中的一个标签，为空表示不过滤
+	Model          string // 嵌入模型一致性检查，为空表示不过滤
+	Dim            int    // 向量维度一致性检查，<=0 表示不过滤
+}
+
+// VectorStore 是知识库向量存储的可插拔后端接口：默认内置 SQLite（knowledge_embeddings 表），
+// 知识库规模较大时可切换到 Qdrant 或 pgvector 以获得更好的检索性能。写入路径
+// （eino_sqlite_indexer.go 的索引写入、Indexer 的删除重建）与检索路径（Retriever.vectorCandidates）
+// 都经由这个接口。导出/导入包（bundle.go）与 FTS-only 命中恢复（retriever.go 的 bestChunkForItem）
+// 仍直接读写 SQLite 表，属于 SQLite 后端专属的便利功能，非 SQLite 后端下会优雅降级（导出包不含
+// 向量、FTS-only 恢复被跳过），不在这个抽象的覆盖范围内。
+type VectorStore interface {
+	// Upsert 覆盖写入某个知识项的全部分块（先删旧后插入，与调用方是否提前 DELETE 无关，幂等）。
+	Upsert(ctx context.Context, itemID string, chunks []VectorChunk) error
+	// DeleteItem 删除某个知识项的全部分块。
+	DeleteItem(ctx context.Context, itemID string) error
+	// Search 按余弦相似度降序返回最多 limit 条命中。
+	Search(ctx context.Context, queryVector []float32, limit int, filter VectorSearchFilter) ([]VectorHit, error)
+	// Count 返回已建索引的知识项数量（去重按 item_id），用于 HasIndex/GetIndexStatus。
+	Count(ctx context.Context) (int, error)
+	// Close 释放后端持有的连接等资源；SQLite 后端复用外部传入的 *sql.DB，Close 为空操作。
+	Close() error
+}
+
+// NewVectorStore 按配置构造向量存储后端；cfg 为空或 Type 为空/"sqlite" 时使用内置 SQLite。
+func NewVectorStore(db *sql.DB, cfg *config.VectorStoreConfig) (VectorStore, error) {
+	if cfg == nil {
+		return newSQLiteVectorStore(db), nil
+	}
+	switch strings.ToLower(strings.TrimSpace(cfg.Type)) {
+	case "", "sqlite":
+		return newSQLiteVectorStore(db), nil
+	case "qdrant":
+		return newQdrantVectorStore(cfg.Qdrant)
+	case "pgvector":
+		return newPgvectorVectorStore(cfg.Pgvector)
+	default:
+		return nil, fmt.Errorf("未知的向量存储后端类型: %s", cfg.Type)
+	}
+}
+
+// sortHitsBySimilarityDesc 按相似度降序原地排序，供各后端的 Search 实现复用。
+func sortHitsBySimilarityDesc(hits []VectorHit) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Similarity > hits[j].Similarity
+	})
+}
@@ -0,0 +1,163 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// defaultWatcherDebounce 是文件事件的防抖时间：编辑器保存文件通常会触发多次写事件（截断+写入），
+// 合并到一次扫描里能避免短时间内重复扫描/索引同一批文件。
+const defaultWatcherDebounce = 1500 * time.Millisecond
+
+// Watcher 基于 fsnotify 监听知识库目录，自动感知 .md 文件的新增/修改/删除并触发
+// ScanKnowledgeBase + 增量索引，替代此前依赖手动调用 ScanKnowledgeBase（点击"扫描"按钮或启动时扫一次）
+// 才能发现变更的方式。
+type Watcher struct {
+	manager  *Manager
+	indexer  *Indexer
+	logger   *zap.Logger
+	debounce time.Duration
+}
+
+// NewWatcher 创建知识库目录监听器；debounce <= 0 时使用默认值（1.5 秒）。
+func NewWatcher(manager *Manager, indexer *Indexer, logger *zap.Logger, debounce time.Duration) *Watcher {
+	if debounce <= 0 {
+		debounce = defaultWatcherDebounce
+	}
+	return &Watcher{manager: manager, indexer: indexer, logger: logger, debounce: debounce}
+}
+
+// Run 启动监听并阻塞直到 ctx 被取消。knowledge base 根目录及其所有已存在的子目录都会被监听；
+// 运行过程中新建的子目录（新分类）会被自动补充监听。调用方通常应以 `go watcher.Run(ctx)` 的方式
+// 在后台常驻运行，覆盖整个应用生命周期。
+func (w *Watcher) Run(ctx context.Context) error {
+	if w.manager.basePath == "" {
+		return fmt.Errorf("知识库路径未配置，无法启动目录监听")
+	}
+	if err := os.MkdirAll(w.manager.basePath, 0755); err != nil {
+		return fmt.Errorf("创建知识库目录失败: %w", err)
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建文件系统监听器失败: %w", err)
+	}
+	defer fsWatcher.Close()
+
+	if err := w.addRecursive(fsWatcher, w.manager.basePath); err != nil {
+		return fmt.Errorf("监听知识库目录失败: %w", err)
+	}
+	w.logger.Info("知识库目录监听已启动", zap.String("basePath", w.manager.basePath))
+
+	var debounceTimer *time.Timer
+	trigger := make(chan struct{}, 1)
+	scheduleScan := func() {
+		if debounceTimer != nil {
+			debounceTimer.Stop()
+		}
+		debounceTimer = time.AfterFunc(w.debounce, func() {
+			select {
+			case trigger <- struct{}{}:
+			default:
+			}
+		})
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-fsWatcher.Events:
+			if !ok {
+				return nil
+			}
+			w.handleEvent(fsWatcher, event, scheduleScan)
+
+		case err, ok := <-fsWatcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.logger.Warn("知识库目录监听出错", zap.Error(err))
+
+		case <-trigger:
+			w.scanAndIndex(ctx)
+		}
+	}
+}
+
+// handleEvent 处理单条文件系统事件：新建目录时补充监听；.md 文件被删除/重命名走出时立即从数据库
+// 移除对应知识项；其余 .md 文件变更（新增/写入）合并进防抖后的批量扫描。
+func (w *Watcher) handleEvent(fsWatcher *fsnotify.Watcher, event fsnotify.Event, scheduleScan func()) {
+	if event.Op&(fsnotify.Create) != 0 {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if err := w.addRecursive(fsWatcher, event.Name); err != nil {
+				w.logger.Warn("监听新增目录失败", zap.String("dir", event.Name), zap.Error(err))
+			}
+			return
+		}
+	}
+
+	if !strings.HasSuffix(strings.ToLower(event.Name), ".md") {
+		return
+	}
+
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		item, err := w.manager.GetItemByFilePath(event.Name)
+		if err != nil {
+			// 文件此前可能就不在数据库中（如临时文件），忽略
+			return
+		}
+		if err := w.manager.DeleteItem(item.ID); err != nil {
+			w.logger.Warn("移除已删除的知识项失败", zap.String("path", event.Name), zap.Error(err))
+			return
+		}
+		w.logger.Info("检测到知识库文件被删除，已移除对应知识项", zap.String("path", event.Name), zap.String("itemId", item.ID))
+		return
+	}
+
+	scheduleScan()
+}
+
+// addRecursive 递归监听 root 及其所有子目录（fsnotify 本身不支持递归监听）。
+func (w *Watcher) addRecursive(fsWatcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if err := fsWatcher.Add(path); err != nil {
+				return fmt.Errorf("监听目录 %s 失败: %w", path, err)
+			}
+		}
+		return nil
+	})
+}
+
+// scanAndIndex 复用 ScanKnowledgeBase 做增量的新增/更新检测（删除由 handleEvent 单独实时处理），
+// 并对新增/变更的知识项触发索引。
+func (w *Watcher) scanAndIndex(ctx context.Context) {
+	itemsToIndex, err := w.manager.ScanKnowledgeBase()
+	if err != nil {
+		w.logger.Warn("知识库目录变更后重新扫描失败", zap.Error(err))
+		return
+	}
+	if len(itemsToIndex) == 0 {
+		return
+	}
+
+	w.logger.Info("检测到知识库目录变更，开始增量索引", zap.Int("count", len(itemsToIndex)))
+	for _, itemID := range itemsToIndex {
+		if err := w.indexer.IndexItem(ctx, itemID); err != nil {
+			w.logger.Warn("增量索引知识项失败", zap.String("itemId", itemID), zap.Error(err))
+		}
+	}
+}
@@ -6,10 +6,16 @@ const (
 	// 漏洞管理工具
 	ToolRecordVulnerability = "record_vulnerability"
 
+	// 长期代理记忆工具
+	ToolRememberFact = "remember_fact"
+
 	// 知识库工具
 	ToolListKnowledgeRiskTypes = "list_knowledge_risk_types"
 	ToolSearchKnowledgeBase    = "search_knowledge_base"
 
+	// 资产台账工具（见 internal/database/asset.go），供模型查询由 nmap/httpx 解析结果与 FOFA 导入自动积累的资产
+	ToolListAssets = "list_assets"
+
 	// WebShell 助手工具（AI 在 WebShell 管理 - AI 助手 中使用）
 	ToolWebshellExec      = "webshell_exec"
 	ToolWebshellFileList  = "webshell_file_list"
@@ -53,6 +59,7 @@ const (
 func IsBuiltinTool(toolName string) bool {
 	switch toolName {
 	case ToolRecordVulnerability,
+		ToolRememberFact,
 		ToolListKnowledgeRiskTypes,
 		ToolSearchKnowledgeBase,
 		ToolWebshellExec,
@@ -85,7 +92,8 @@ func IsBuiltinTool(toolName string) bool {
 		ToolC2Payload,
 		ToolC2Event,
 		ToolC2Profile,
-		ToolC2File:
+		ToolC2File,
+		ToolListAssets:
 		return true
 	default:
 		return false
@@ -96,6 +104,7 @@ func IsBuiltinTool(toolName string) bool {
 func GetAllBuiltinTools() []string {
 	return []string{
 		ToolRecordVulnerability,
+		ToolRememberFact,
 		ToolListKnowledgeRiskTypes,
 		ToolSearchKnowledgeBase,
 		ToolWebshellExec,
@@ -129,5 +138,6 @@ func GetAllBuiltinTools() []string {
 		ToolC2Event,
 		ToolC2Profile,
 		ToolC2File,
+		ToolListAssets,
 	}
 }
@@ -3,9 +3,12 @@ package mcp
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"strings"
@@ -44,18 +47,22 @@ func newSDKClientFromSession(session *mcp.ClientSession, client *mcp.Client, log
 
 // lazySDKClient 延迟连接：Initialize() 时才调用官方 SDK 建立连接，对外实现 ExternalMCPClient
 type lazySDKClient struct {
-	serverCfg config.ExternalMCPServerConfig
-	logger    *zap.Logger
-	inner     ExternalMCPClient // 连接成功后为 *sdkClient
-	mu        sync.RWMutex
-	status    string
+	name              string
+	serverCfg         config.ExternalMCPServerConfig
+	logger            *zap.Logger
+	inner             ExternalMCPClient // 连接成功后为 *sdkClient
+	mu                sync.RWMutex
+	status            string
+	onToolListChanged func(name string) // 收到 tools/list_changed 通知时回调，用于使工具列表缓存失效
 }
 
-func newLazySDKClient(serverCfg config.ExternalMCPServerConfig, logger *zap.Logger) *lazySDKClient {
+func newLazySDKClient(name string, serverCfg config.ExternalMCPServerConfig, logger *zap.Logger, onToolListChanged func(name string)) *lazySDKClient {
 	return &lazySDKClient{
-		serverCfg: serverCfg,
-		logger:    logger,
-		status:    "connecting",
+		name:              name,
+		serverCfg:         serverCfg,
+		logger:            logger,
+		status:            "connecting",
+		onToolListChanged: onToolListChanged,
 	}
 }
 
@@ -92,7 +99,7 @@ func (c *lazySDKClient) Initialize(ctx context.Context) error {
 	}
 	c.mu.Unlock()
 
-	inner, err := createSDKClient(ctx, c.serverCfg, c.logger)
+	inner, err := createSDKClient(ctx, c.serverCfg, c.logger, c.name, c.onToolListChanged)
 	if err != nil {
 		c.setStatus("error")
 		return err
@@ -266,8 +273,9 @@ func mustJSON(v interface{}) []byte {
 }
 
 // createSDKClient 根据配置创建并连接外部 MCP 客户端（使用官方 SDK），返回实现 ExternalMCPClient 的 *sdkClient
-// 若连接失败返回 (nil, error)。ctx 用于连接超时与取消。
-func createSDKClient(ctx context.Context, serverCfg config.ExternalMCPServerConfig, logger *zap.Logger) (ExternalMCPClient, error) {
+// 若连接失败返回 (nil, error)。ctx 用于连接超时与取消。name/onToolListChanged 用于在收到
+// tools/list_changed 通知时使工具列表缓存失效（onToolListChanged 可为 nil，表示不关心该通知）。
+func createSDKClient(ctx context.Context, serverCfg config.ExternalMCPServerConfig, logger *zap.Logger, name string, onToolListChanged func(name string)) (ExternalMCPClient, error) {
 	timeout := time.Duration(serverCfg.Timeout) * time.Second
 	if timeout <= 0 {
 		timeout = 30 * time.Second
@@ -278,13 +286,21 @@ func createSDKClient(ctx context.Context, serverCfg config.ExternalMCPServerConf
 		return nil, fmt.Errorf("配置缺少 command 或 url，且未指定 type/transport")
 	}
 
-	// 构造 ClientOptions：KeepAlive 心跳
+	// 构造 ClientOptions：KeepAlive 心跳、tools/list_changed 通知回调
 	var clientOpts *mcp.ClientOptions
 	if serverCfg.KeepAlive > 0 {
 		clientOpts = &mcp.ClientOptions{
 			KeepAlive: time.Duration(serverCfg.KeepAlive) * time.Second,
 		}
 	}
+	if onToolListChanged != nil {
+		if clientOpts == nil {
+			clientOpts = &mcp.ClientOptions{}
+		}
+		clientOpts.ToolListChangedHandler = func(_ context.Context, _ *mcp.ToolListChangedRequest) {
+			onToolListChanged(name)
+		}
+	}
 
 	client := mcp.NewClient(&mcp.Implementation{
 		Name:    clientName,
@@ -300,8 +316,15 @@ func createSDKClient(ctx context.Context, serverCfg config.ExternalMCPServerConf
 		// 必须用 exec.Command 而非 CommandContext：doConnect 返回后 ctx 会被 cancel，
 		// 若用 CommandContext(ctx) 会立刻杀掉子进程，导致 ListTools 等后续请求失败、显示 0 工具
 		cmd := exec.Command(serverCfg.Command, serverCfg.Args...)
-		if len(serverCfg.Env) > 0 {
-			cmd.Env = append(cmd.Env, envMapToSlice(serverCfg.Env)...)
+		if serverCfg.WorkingDir != "" {
+			cmd.Dir = serverCfg.WorkingDir
+		}
+		secretEnv, err := loadEnvSecretFiles(serverCfg.EnvSecretFiles)
+		if err != nil {
+			return nil, fmt.Errorf("加载环境变量密钥文件失败: %w", err)
+		}
+		if len(serverCfg.Env) > 0 || len(secretEnv) > 0 {
+			cmd.Env = append(cmd.Env, envMapToSlice(serverCfg.Env, secretEnv)...)
 		}
 		ct := &mcp.CommandTransport{Command: cmd}
 		if serverCfg.TerminateDuration > 0 {
@@ -314,7 +337,10 @@ func createSDKClient(ctx context.Context, serverCfg config.ExternalMCPServerConf
 		}
 		// SSE 是长连接（GET 流持续打开），不能设置 http.Client.Timeout（会在超时后杀掉整个连接导致 EOF）。
 		// 超时由每次 ListTools/CallTool 的 context 单独控制。
-		httpClient := httpClientForLongLived(serverCfg.Headers)
+		httpClient, err := httpClientForLongLived(serverCfg, logger)
+		if err != nil {
+			return nil, err
+		}
 		t = &mcp.SSEClientTransport{
 			Endpoint:   serverCfg.URL,
 			HTTPClient: httpClient,
@@ -323,7 +349,10 @@ func createSDKClient(ctx context.Context, serverCfg config.ExternalMCPServerConf
 		if serverCfg.URL == "" {
 			return nil, fmt.Errorf("http 模式需要配置 url")
 		}
-		httpClient := httpClientWithTimeoutAndHeaders(timeout, serverCfg.Headers)
+		httpClient, err := httpClientWithTimeoutAndHeaders(timeout, serverCfg, logger)
+		if err != nil {
+			return nil, err
+		}
 		st := &mcp.StreamableClientTransport{
 			Endpoint:   serverCfg.URL,
 			HTTPClient: httpClient,
@@ -344,15 +373,19 @@ func createSDKClient(ctx context.Context, serverCfg config.ExternalMCPServerConf
 	return newSDKClientFromSession(session, client, logger), nil
 }
 
-func envMapToSlice(env map[string]string) []string {
+// envMapToSlice 合并宿主环境变量与一个或多个覆盖 map（后者优先，后面的覆盖前面的），
+// 返回 exec.Cmd.Env 可用的 "KEY=VALUE" 列表。
+func envMapToSlice(overrides ...map[string]string) []string {
 	m := make(map[string]string)
 	for _, s := range os.Environ() {
 		if i := strings.IndexByte(s, '='); i > 0 {
 			m[s[:i]] = s[i+1:]
 		}
 	}
-	for k, v := range env {
-		m[k] = v
+	for _, env := range overrides {
+		for k, v := range env {
+			m[k] = v
+		}
 	}
 	out := make([]string, 0, len(m))
 	for k, v := range m {
@@ -361,35 +394,107 @@ func envMapToSlice(env map[string]string) []string {
 	return out
 }
 
-func httpClientWithTimeoutAndHeaders(timeout time.Duration, headers map[string]string) *http.Client {
-	transport := http.DefaultTransport
-	if len(headers) > 0 {
-		transport = &headerRoundTripper{
-			headers: headers,
-			base:    http.DefaultTransport,
+// loadEnvSecretFiles 按 EnvSecretFiles 配置从磁盘读取密钥文件内容（去除首尾空白），
+// 返回环境变量名 -> 密钥值；任一文件读取失败即返回错误，避免服务以缺失密钥的方式静默启动。
+func loadEnvSecretFiles(files map[string]string) (map[string]string, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]string, len(files))
+	for envName, filePath := range files {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("读取密钥文件 %s 失败: %w", filePath, err)
 		}
+		result[envName] = strings.TrimSpace(string(data))
+	}
+	return result, nil
+}
+
+func httpClientWithTimeoutAndHeaders(timeout time.Duration, serverCfg config.ExternalMCPServerConfig, logger *zap.Logger) (*http.Client, error) {
+	transport, err := buildHTTPTransport(serverCfg, logger)
+	if err != nil {
+		return nil, err
 	}
 	return &http.Client{
 		Timeout:   timeout,
 		Transport: transport,
-	}
+	}, nil
 }
 
 // httpClientForLongLived 创建不设超时的 HTTP 客户端，用于 SSE 等长连接传输。
 // SSE 的 GET 流会持续打开，http.Client.Timeout 会在超时后强制关闭连接导致 EOF。
 // 超时由调用方通过 context 控制。
-func httpClientForLongLived(headers map[string]string) *http.Client {
-	transport := http.DefaultTransport
-	if len(headers) > 0 {
-		transport = &headerRoundTripper{
-			headers: headers,
-			base:    http.DefaultTransport,
-		}
+func httpClientForLongLived(serverCfg config.ExternalMCPServerConfig, logger *zap.Logger) (*http.Client, error) {
+	transport, err := buildHTTPTransport(serverCfg, logger)
+	if err != nil {
+		return nil, err
 	}
 	return &http.Client{
 		Transport: transport,
 		// 不设 Timeout，SSE 长连接的超时由 per-request context 控制
+	}, nil
+}
+
+// buildHTTPTransport 按 ExternalMCPServerConfig 中的 Headers/BearerToken/TLS/OAuth 配置组装最终的
+// http.RoundTripper：先套 TLS（InsecureSkipVerify/CACertPath/mTLS 证书），再套认证层
+// （OAuth 优先于 BearerToken，均优先于 Headers 中手写的 Authorization）。
+func buildHTTPTransport(serverCfg config.ExternalMCPServerConfig, logger *zap.Logger) (http.RoundTripper, error) {
+	base, err := tlsTransport(serverCfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var rt http.RoundTripper = base
+	if len(serverCfg.Headers) > 0 {
+		rt = &headerRoundTripper{headers: serverCfg.Headers, base: rt}
+	}
+	if serverCfg.BearerToken != "" {
+		rt = &headerRoundTripper{headers: map[string]string{"Authorization": "Bearer " + serverCfg.BearerToken}, base: rt}
+	}
+	if serverCfg.OAuth != nil {
+		rt = newOAuthClientCredentialsTransport(serverCfg.OAuth, base, logger)
+	}
+	return rt, nil
+}
+
+// tlsTransport 按 InsecureSkipVerify/CACertPath/ClientCertPath+ClientKeyPath 构造 http.Transport；
+// 三者均未配置时直接复用 http.DefaultTransport，不做任何改动。
+func tlsTransport(serverCfg config.ExternalMCPServerConfig, logger *zap.Logger) (http.RoundTripper, error) {
+	if !serverCfg.InsecureSkipVerify && serverCfg.CACertPath == "" && serverCfg.ClientCertPath == "" {
+		return http.DefaultTransport, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if serverCfg.InsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
+		logger.Warn("外部MCP HTTP客户端已关闭TLS证书校验（insecure_skip_verify），仅应在受信任的自建网关调试环境使用")
+	}
+	if serverCfg.CACertPath != "" {
+		caCert, err := os.ReadFile(serverCfg.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取 ca_cert_path 失败: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("ca_cert_path 未包含有效的 PEM 证书: %s", serverCfg.CACertPath)
+		}
+		tlsConfig.RootCAs = pool
 	}
+	if serverCfg.ClientCertPath != "" && serverCfg.ClientKeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(serverCfg.ClientCertPath, serverCfg.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("加载 mTLS 客户端证书失败: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
 }
 
 type headerRoundTripper struct {
@@ -403,3 +508,83 @@ func (h *headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error
 	}
 	return h.base.RoundTrip(req)
 }
+
+// oauthClientCredentialsTransport 在每次请求前确保持有一个未过期的 OAuth2 访问令牌（首次或过期后
+// 通过 client_credentials 授权模式换取），并以 Authorization: Bearer 附加到请求上。
+type oauthClientCredentialsTransport struct {
+	cfg    *config.ExternalMCPOAuthConfig
+	base   http.RoundTripper
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func newOAuthClientCredentialsTransport(cfg *config.ExternalMCPOAuthConfig, base http.RoundTripper, logger *zap.Logger) *oauthClientCredentialsTransport {
+	return &oauthClientCredentialsTransport{cfg: cfg, base: base, logger: logger}
+}
+
+func (o *oauthClientCredentialsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := o.currentToken(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("获取OAuth访问令牌失败: %w", err)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return o.base.RoundTrip(req)
+}
+
+// currentToken 复用未过期的缓存令牌，提前 30 秒视为过期以避免临界点上的请求失败。
+func (o *oauthClientCredentialsTransport) currentToken(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token != "" && time.Now().Before(o.expiresAt.Add(-30*time.Second)) {
+		return o.token, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", o.cfg.ClientID)
+	form.Set("client_secret", o.cfg.ClientSecret)
+	if len(o.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.cfg.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint 返回状态码 %d", resp.StatusCode)
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("解析token响应失败: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", fmt.Errorf("token响应缺少 access_token")
+	}
+
+	o.token = tokenResp.AccessToken
+	if tokenResp.ExpiresIn > 0 {
+		o.expiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	} else {
+		o.expiresAt = time.Now().Add(5 * time.Minute)
+	}
+	o.logger.Info("已获取外部MCP OAuth访问令牌", zap.String("tokenURL", o.cfg.TokenURL))
+	return o.token, nil
+}
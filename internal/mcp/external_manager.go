@@ -17,23 +17,25 @@ import (
 
 // ExternalMCPManager 外部MCP管理器
 type ExternalMCPManager struct {
-	clients      map[string]ExternalMCPClient
-	configs      map[string]config.ExternalMCPServerConfig
-	logger       *zap.Logger
-	storage      MonitorStorage            // 可选的持久化存储
-	executions   map[string]*ToolExecution // 执行记录
-	stats        map[string]*ToolStats     // 工具统计信息
-	errors       map[string]string         // 错误信息
-	toolCounts   map[string]int            // 工具数量缓存
-	toolCountsMu sync.RWMutex              // 工具数量缓存的锁
-	toolCache    map[string][]Tool         // 工具列表缓存：MCP名称 -> 工具列表
-	toolCacheMu  sync.RWMutex              // 工具列表缓存的锁
-	stopRefresh  chan struct{}             // 停止后台刷新的信号
-	refreshWg    sync.WaitGroup            // 等待后台刷新goroutine完成
-	refreshing   atomic.Bool               // 防止 refreshToolCounts 并发堆积
-	mu           sync.RWMutex
-	runningCancels map[string]context.CancelFunc
-	abortUserNotes map[string]string
+	clients               map[string]ExternalMCPClient
+	configs               map[string]config.ExternalMCPServerConfig
+	logger                *zap.Logger
+	storage               MonitorStorage            // 可选的持久化存储
+	executions            map[string]*ToolExecution // 执行记录
+	stats                 map[string]*ToolStats     // 工具统计信息
+	errors                map[string]string         // 错误信息
+	toolCounts            map[string]int            // 工具数量缓存
+	toolCountsMu          sync.RWMutex              // 工具数量缓存的锁
+	toolCache             map[string][]Tool         // 工具列表缓存：MCP名称 -> 工具列表
+	toolCacheTime         map[string]time.Time      // 工具列表缓存的写入时间：MCP名称 -> 时间，配合 ToolsCacheTTLSeconds 判断新鲜度
+	toolCacheMu           sync.RWMutex              // 工具列表缓存的锁
+	stopRefresh           chan struct{}             // 停止后台刷新的信号
+	refreshWg             sync.WaitGroup            // 等待后台刷新goroutine完成
+	refreshing            atomic.Bool               // 防止 refreshToolCounts 并发堆积
+	mu                    sync.RWMutex
+	runningCancels        map[string]context.CancelFunc
+	abortUserNotes        map[string]string
+	maxExecutionsInMemory int // 内存中最大执行记录数，见 SetMaxExecutionsInMemory
 }
 
 // NewExternalMCPManager 创建外部MCP管理器
@@ -44,18 +46,20 @@ func NewExternalMCPManager(logger *zap.Logger) *ExternalMCPManager {
 // NewExternalMCPManagerWithStorage 创建外部MCP管理器（带持久化存储）
 func NewExternalMCPManagerWithStorage(logger *zap.Logger, storage MonitorStorage) *ExternalMCPManager {
 	manager := &ExternalMCPManager{
-		clients:        make(map[string]ExternalMCPClient),
-		configs:        make(map[string]config.ExternalMCPServerConfig),
-		logger:         logger,
-		storage:        storage,
-		executions:     make(map[string]*ToolExecution),
-		stats:          make(map[string]*ToolStats),
-		errors:         make(map[string]string),
-		toolCounts:     make(map[string]int),
-		toolCache:      make(map[string][]Tool),
-		stopRefresh:    make(chan struct{}),
-		runningCancels: make(map[string]context.CancelFunc),
-		abortUserNotes: make(map[string]string),
+		clients:               make(map[string]ExternalMCPClient),
+		configs:               make(map[string]config.ExternalMCPServerConfig),
+		logger:                logger,
+		storage:               storage,
+		executions:            make(map[string]*ToolExecution),
+		stats:                 make(map[string]*ToolStats),
+		errors:                make(map[string]string),
+		toolCounts:            make(map[string]int),
+		toolCache:             make(map[string][]Tool),
+		toolCacheTime:         make(map[string]time.Time),
+		stopRefresh:           make(chan struct{}),
+		runningCancels:        make(map[string]context.CancelFunc),
+		abortUserNotes:        make(map[string]string),
+		maxExecutionsInMemory: 1000, // 默认最多在内存中保留1000条执行记录，可通过 SetMaxExecutionsInMemory 覆盖
 	}
 	// 启动后台刷新工具数量的goroutine
 	manager.startToolCountRefresh()
@@ -177,8 +181,11 @@ func (m *ExternalMCPManager) StartClient(name string) error {
 	delete(m.errors, name)
 	m.mu.Unlock()
 
+	// 重新启动，之前缓存的工具列表已不可信，强制下次拉取实时数据
+	m.invalidateToolCache(name)
+
 	// 立即创建客户端并设置为"connecting"状态，这样前端可以立即看到状态
-	client := m.createClient(serverCfg)
+	client := m.createClient(name, serverCfg)
 	if client == nil {
 		return fmt.Errorf("无法创建客户端：不支持的传输模式")
 	}
@@ -249,6 +256,9 @@ func (m *ExternalMCPManager) StopClient(name string) error {
 	m.toolCounts[name] = 0
 	m.toolCountsMu.Unlock()
 
+	// 停止后清除工具列表缓存，避免重启后短暂返回已失效的旧工具列表
+	m.invalidateToolCache(name)
+
 	// 更新配置为禁用
 	serverCfg.ExternalMCPEnable = false
 	m.configs[name] = serverCfg
@@ -306,7 +316,8 @@ func (m *ExternalMCPManager) GetAllTools(ctx context.Context) ([]Tool, error) {
 			continue
 		}
 
-		// 为工具添加前缀，避免冲突
+		// 应用配置中的工具覆盖（重命名/精简描述/参数描述），再添加前缀避免与其他MCP冲突
+		tools = m.applyToolOverrides(name, tools)
 		for _, tool := range tools {
 			tool.Name = fmt.Sprintf("%s::%s", name, tool.Name)
 			allTools = append(allTools, tool)
@@ -321,6 +332,74 @@ func (m *ExternalMCPManager) GetAllTools(ctx context.Context) ([]Tool, error) {
 	return allTools, nil
 }
 
+// applyToolOverrides 应用 ExternalMCPServerConfig.ToolOverrides 中配置的重命名/描述/参数描述覆盖，
+// 用于精简部分上游 MCP 服务器冗长或欠佳的工具描述。返回处理后的新切片，不修改 tools（可能是缓存中的
+// 原始数据）本身，覆盖表中未提及的工具原样返回。
+func (m *ExternalMCPManager) applyToolOverrides(name string, tools []Tool) []Tool {
+	m.mu.RLock()
+	serverCfg, exists := m.configs[name]
+	m.mu.RUnlock()
+	if !exists || len(serverCfg.ToolOverrides) == 0 {
+		return tools
+	}
+
+	result := make([]Tool, len(tools))
+	for i, tool := range tools {
+		override, ok := serverCfg.ToolOverrides[tool.Name]
+		if !ok {
+			result[i] = tool
+			continue
+		}
+		if override.Rename != "" {
+			tool.Name = override.Rename
+		}
+		if override.Description != "" {
+			tool.Description = override.Description
+		}
+		if override.ShortDescription != "" {
+			tool.ShortDescription = override.ShortDescription
+		}
+		if len(override.ParameterDescriptions) > 0 {
+			tool.InputSchema = overrideSchemaParameterDescriptions(tool.InputSchema, override.ParameterDescriptions)
+		}
+		result[i] = tool
+	}
+	return result
+}
+
+// overrideSchemaParameterDescriptions 返回将 properties 下指定参数的 description 替换后的 JSON Schema 深拷贝，
+// 避免原地修改可能与缓存共享的 schema。overrides 中未提及的参数、schema 中不存在 properties 时原样返回。
+func overrideSchemaParameterDescriptions(schema map[string]interface{}, overrides map[string]string) map[string]interface{} {
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		return schema
+	}
+
+	newSchema := make(map[string]interface{}, len(schema))
+	for k, v := range schema {
+		newSchema[k] = v
+	}
+
+	newProperties := make(map[string]interface{}, len(properties))
+	for paramName, paramSchemaRaw := range properties {
+		desc, hasOverride := overrides[paramName]
+		paramSchema, isMap := paramSchemaRaw.(map[string]interface{})
+		if !hasOverride || !isMap {
+			newProperties[paramName] = paramSchemaRaw
+			continue
+		}
+
+		newParamSchema := make(map[string]interface{}, len(paramSchema))
+		for k, v := range paramSchema {
+			newParamSchema[k] = v
+		}
+		newParamSchema["description"] = desc
+		newProperties[paramName] = newParamSchema
+	}
+	newSchema["properties"] = newProperties
+	return newSchema
+}
+
 // getToolsForClient 获取指定客户端的工具列表
 // 返回工具列表和错误（如果完全无法获取）
 func (m *ExternalMCPManager) getToolsForClient(name string, client ExternalMCPClient, ctx context.Context) ([]Tool, error) {
@@ -335,8 +414,14 @@ func (m *ExternalMCPManager) getToolsForClient(name string, client ExternalMCPCl
 		return nil, fmt.Errorf("外部MCP连接失败: %s", name)
 	}
 
-	// 已连接：尝试获取最新工具列表
+	// 已连接：优先使用未过期的缓存，跳过一次 ListTools 往返以降低延迟；否则拉取最新工具列表
 	if client.IsConnected() {
+		if ttl := m.toolsCacheTTL(name); ttl > 0 {
+			if tools, fresh := m.freshCachedTools(name, ttl); fresh {
+				return tools, nil
+			}
+		}
+
 		tools, err := client.ListTools(ctx)
 		if err != nil {
 			// 获取失败，尝试使用缓存
@@ -384,10 +469,47 @@ func (m *ExternalMCPManager) getCachedTools(name, reason string, originalErr err
 	return nil, fmt.Errorf("外部MCP无缓存工具: %s", name)
 }
 
+// toolsCacheTTL 返回指定外部MCP配置的工具列表缓存有效期，未配置时为0（不启用TTL缓存）
+func (m *ExternalMCPManager) toolsCacheTTL(name string) time.Duration {
+	m.mu.RLock()
+	serverCfg, exists := m.configs[name]
+	m.mu.RUnlock()
+	if !exists || serverCfg.ToolsCacheTTLSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(serverCfg.ToolsCacheTTLSeconds) * time.Second
+}
+
+// freshCachedTools 返回指定外部MCP在有效期内的缓存工具列表；缓存不存在或已过期时 fresh 为 false
+func (m *ExternalMCPManager) freshCachedTools(name string, ttl time.Duration) (tools []Tool, fresh bool) {
+	m.toolCacheMu.RLock()
+	defer m.toolCacheMu.RUnlock()
+
+	cachedAt, hasTime := m.toolCacheTime[name]
+	if !hasTime || time.Since(cachedAt) >= ttl {
+		return nil, false
+	}
+	cachedTools, hasCache := m.toolCache[name]
+	if !hasCache || len(cachedTools) == 0 {
+		return nil, false
+	}
+	return cachedTools, true
+}
+
+// invalidateToolCache 清除指定外部MCP的工具列表缓存及其时间戳，用于服务器启动/停止或收到
+// tools/list_changed 通知时强制下次 GetAllTools 重新拉取最新工具列表
+func (m *ExternalMCPManager) invalidateToolCache(name string) {
+	m.toolCacheMu.Lock()
+	delete(m.toolCache, name)
+	delete(m.toolCacheTime, name)
+	m.toolCacheMu.Unlock()
+}
+
 // updateToolCache 更新工具列表缓存
 func (m *ExternalMCPManager) updateToolCache(name string, tools []Tool) {
 	m.toolCacheMu.Lock()
 	m.toolCache[name] = tools
+	m.toolCacheTime[name] = time.Now()
 	m.toolCacheMu.Unlock()
 
 	// 如果返回空列表，记录警告
@@ -572,9 +694,22 @@ func (m *ExternalMCPManager) takeAbortUserNote(id string) string {
 	return n
 }
 
+// SetMaxExecutionsInMemory 设置内存中最多保留的执行记录数，覆盖默认值（1000）。d <= 0 时忽略。
+func (m *ExternalMCPManager) SetMaxExecutionsInMemory(n int) {
+	if n <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxExecutionsInMemory = n
+}
+
 // cleanupOldExecutions 清理旧的执行记录（保持内存中的记录数量在限制内）
 func (m *ExternalMCPManager) cleanupOldExecutions() {
-	const maxExecutionsInMemory = 1000
+	maxExecutionsInMemory := m.maxExecutionsInMemory
+	if maxExecutionsInMemory <= 0 {
+		maxExecutionsInMemory = 1000
+	}
 	if len(m.executions) <= maxExecutionsInMemory {
 		return
 	}
@@ -661,6 +796,24 @@ func (m *ExternalMCPManager) CancelToolExecution(id string) bool {
 	return m.CancelToolExecutionWithNote(id, "")
 }
 
+// CancelAllToolExecutions 取消当前所有正在运行的外部 MCP 工具调用，用于全局紧急停止（见
+// handler.EmergencyStopHandler）；返回被取消的执行数量。
+func (m *ExternalMCPManager) CancelAllToolExecutions() int {
+	m.mu.Lock()
+	cancels := make([]context.CancelFunc, 0, len(m.runningCancels))
+	for _, cancel := range m.runningCancels {
+		if cancel != nil {
+			cancels = append(cancels, cancel)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return len(cancels)
+}
+
 // updateStats 更新统计信息
 func (m *ExternalMCPManager) updateStats(toolName string, failed bool) {
 	now := time.Now()
@@ -980,7 +1133,7 @@ func (m *ExternalMCPManager) triggerToolCountRefresh() {
 }
 
 // createClient 创建客户端（不连接）。统一使用官方 MCP Go SDK 的 lazy 客户端，连接在 Initialize 时完成。
-func (m *ExternalMCPManager) createClient(serverCfg config.ExternalMCPServerConfig) ExternalMCPClient {
+func (m *ExternalMCPManager) createClient(name string, serverCfg config.ExternalMCPServerConfig) ExternalMCPClient {
 	transport := serverCfg.GetTransportType()
 
 	switch transport {
@@ -988,23 +1141,23 @@ func (m *ExternalMCPManager) createClient(serverCfg config.ExternalMCPServerConf
 		if serverCfg.URL == "" {
 			return nil
 		}
-		return newLazySDKClient(serverCfg, m.logger)
+		return newLazySDKClient(name, serverCfg, m.logger, m.invalidateToolCache)
 	case "stdio":
 		if serverCfg.Command == "" {
 			return nil
 		}
-		return newLazySDKClient(serverCfg, m.logger)
+		return newLazySDKClient(name, serverCfg, m.logger, m.invalidateToolCache)
 	case "sse":
 		if serverCfg.URL == "" {
 			return nil
 		}
-		return newLazySDKClient(serverCfg, m.logger)
+		return newLazySDKClient(name, serverCfg, m.logger, m.invalidateToolCache)
 	default:
 		if transport == "" {
 			return nil
 		}
 		// 未知传输类型也尝试使用 lazy client
-		return newLazySDKClient(serverCfg, m.logger)
+		return newLazySDKClient(name, serverCfg, m.logger, m.invalidateToolCache)
 	}
 }
 
@@ -1039,7 +1192,7 @@ func (m *ExternalMCPManager) setClientStatus(client ExternalMCPClient, status st
 
 // connectClient 连接客户端（异步）- 保留用于向后兼容
 func (m *ExternalMCPManager) connectClient(name string, serverCfg config.ExternalMCPServerConfig) error {
-	client := m.createClient(serverCfg)
+	client := m.createClient(name, serverCfg)
 	if client == nil {
 		return fmt.Errorf("无法创建客户端：不支持的传输模式")
 	}
@@ -157,7 +157,7 @@ func TestLazySDKClient_InitializeFails(t *testing.T) {
 		URL:     "http://127.0.0.1:19999/nonexistent",
 		Timeout: 2,
 	}
-	c := newLazySDKClient(cfg, logger)
+	c := newLazySDKClient("test", cfg, logger, nil)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	err := c.Initialize(ctx)
@@ -233,3 +233,74 @@ func TestExternalMCPManager_GetAllTools(t *testing.T) {
 		t.Logf("获取到%d个工具", len(tools))
 	}
 }
+
+func TestExternalMCPManager_ApplyToolOverrides(t *testing.T) {
+	logger := zap.NewNop()
+	manager := NewExternalMCPManager(logger)
+
+	cfg := config.ExternalMCPServerConfig{
+		Command: "python3",
+		ToolOverrides: map[string]config.ToolOverrideConfig{
+			"search": {
+				Rename:           "web_search",
+				ShortDescription: "搜索",
+				ParameterDescriptions: map[string]string{
+					"query": "搜索关键词",
+				},
+			},
+		},
+	}
+	if err := manager.AddOrUpdateConfig("test-overrides", cfg); err != nil {
+		t.Fatalf("添加配置失败: %v", err)
+	}
+
+	tools := []Tool{
+		{
+			Name:        "search",
+			Description: "冗长的原始描述……",
+			InputSchema: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{"type": "string", "description": "原始参数描述"},
+					"limit": map[string]interface{}{"type": "number"},
+				},
+			},
+		},
+		{Name: "no-override-tool"},
+	}
+
+	result := manager.applyToolOverrides("test-overrides", tools)
+	if len(result) != 2 {
+		t.Fatalf("期望2个工具，实际%d个", len(result))
+	}
+
+	overridden := result[0]
+	if overridden.Name != "web_search" {
+		t.Errorf("Name = %q, want %q", overridden.Name, "web_search")
+	}
+	if overridden.ShortDescription != "搜索" {
+		t.Errorf("ShortDescription = %q, want %q", overridden.ShortDescription, "搜索")
+	}
+	if overridden.Description != tools[0].Description {
+		t.Errorf("未配置 Description 覆盖时不应改变原描述")
+	}
+
+	properties := overridden.InputSchema["properties"].(map[string]interface{})
+	query := properties["query"].(map[string]interface{})
+	if query["description"] != "搜索关键词" {
+		t.Errorf("query description = %v, want %q", query["description"], "搜索关键词")
+	}
+	limit := properties["limit"].(map[string]interface{})
+	if _, hasDesc := limit["description"]; hasDesc {
+		t.Errorf("未配置覆盖的参数不应新增 description 字段")
+	}
+
+	// 原始 tools 切片中的 schema 不应被就地修改（深拷贝）
+	originalQuery := tools[0].InputSchema["properties"].(map[string]interface{})["query"].(map[string]interface{})
+	if originalQuery["description"] != "原始参数描述" {
+		t.Errorf("applyToolOverrides 不应修改原始 tools 的 schema")
+	}
+
+	if result[1].Name != "no-override-tool" {
+		t.Errorf("未在覆盖表中的工具应原样返回")
+	}
+}
@@ -0,0 +1,37 @@
+package mcp
+
+// SensitiveValueMask 敏感参数在执行记录、日志与 SSE 事件中显示的掩码值
+const SensitiveValueMask = "******"
+
+// MaskArguments 返回 args 的副本，其中 sensitiveParams 列出的字段被替换为掩码。
+// 不修改原始 args，真实值仍会原样传递给被执行的进程。
+func MaskArguments(args map[string]interface{}, sensitiveParams []string) map[string]interface{} {
+	if len(sensitiveParams) == 0 || len(args) == 0 {
+		return args
+	}
+
+	sensitiveSet := make(map[string]struct{}, len(sensitiveParams))
+	for _, name := range sensitiveParams {
+		sensitiveSet[name] = struct{}{}
+	}
+
+	masked := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if _, ok := sensitiveSet[k]; ok {
+			masked[k] = SensitiveValueMask
+			continue
+		}
+		masked[k] = v
+	}
+	return masked
+}
+
+// MaskToolArguments 根据工具定义中登记的敏感参数名，对 args 做掩码处理。
+// 用于工具尚未开始执行前，构建对外展示（日志、SSE、执行记录）的参数副本。
+func (s *Server) MaskToolArguments(toolName string, args map[string]interface{}) map[string]interface{} {
+	tool, exists := s.GetToolDef(toolName)
+	if !exists {
+		return args
+	}
+	return MaskArguments(args, tool.SensitiveParams)
+}
@@ -0,0 +1,32 @@
+package mcp
+
+import "testing"
+
+func TestMaskArguments_MasksOnlySensitiveKeys(t *testing.T) {
+	args := map[string]interface{}{
+		"target": "1.2.3.4",
+		"cookie": "session=abc123",
+	}
+
+	masked := MaskArguments(args, []string{"cookie"})
+
+	if masked["cookie"] != SensitiveValueMask {
+		t.Errorf("cookie 应被掩码，实际 %v", masked["cookie"])
+	}
+	if masked["target"] != "1.2.3.4" {
+		t.Errorf("非敏感字段不应被修改，实际 %v", masked["target"])
+	}
+	if args["cookie"] != "session=abc123" {
+		t.Errorf("原始 args 不应被修改，实际 %v", args["cookie"])
+	}
+}
+
+func TestMaskArguments_NoSensitiveParamsReturnsOriginal(t *testing.T) {
+	args := map[string]interface{}{"target": "1.2.3.4"}
+
+	masked := MaskArguments(args, nil)
+
+	if len(masked) != 1 || masked["target"] != "1.2.3.4" {
+		t.Errorf("无敏感参数时应原样返回，实际 %v", masked)
+	}
+}
@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PromptFileConfig 对应 PromptsDir 下单个 YAML 文件的结构，供团队按渗透测试项目自定义提示词模板，
+// 无需改代码、重新编译即可增删。字段命名与 Prompt/PromptArgument 保持一致，便于直接互相转换。
+type PromptFileConfig struct {
+	Name        string           `yaml:"name"`
+	Description string           `yaml:"description,omitempty"`
+	Role        string           `yaml:"role,omitempty"` // 生成消息的 role，留空默认为 "user"
+	Template    string           `yaml:"template"`       // 提示词正文，用 {{argName}} 引用 Arguments 中的参数
+	Arguments   []PromptArgument `yaml:"arguments,omitempty"`
+}
+
+// templateVarPattern 匹配模板中的 {{argName}} 占位符，argName 允许字母、数字、下划线。
+var templateVarPattern = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// LoadPromptsFromDir 从目录加载所有提示词模板文件（*.yaml / *.yml），目录不存在时返回空列表、不报错，
+// 单个文件解析失败时记录到 errs 但不中断其余文件的加载，与 config.LoadToolsFromDir 的容错策略一致。
+func LoadPromptsFromDir(dir string) ([]Prompt, []error) {
+	var prompts []Prompt
+	var errs []error
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return prompts, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, []error{fmt.Errorf("读取提示词目录失败: %w", err)}
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".yaml") && !strings.HasSuffix(name, ".yml") {
+			continue
+		}
+
+		filePath := filepath.Join(dir, name)
+		prompt, err := LoadPromptFromFile(filePath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("加载提示词文件 %s 失败: %w", filePath, err))
+			continue
+		}
+		prompts = append(prompts, *prompt)
+	}
+
+	return prompts, errs
+}
+
+// LoadPromptFromFile 从单个 YAML 文件加载提示词模板
+func LoadPromptFromFile(path string) (*Prompt, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+
+	var cfg PromptFileConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析提示词配置失败: %w", err)
+	}
+
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("提示词名称不能为空")
+	}
+	if cfg.Template == "" {
+		return nil, fmt.Errorf("提示词模板正文不能为空")
+	}
+
+	role := cfg.Role
+	if role == "" {
+		role = "user"
+	}
+
+	return &Prompt{
+		Name:        cfg.Name,
+		Description: cfg.Description,
+		Arguments:   cfg.Arguments,
+		Template:    cfg.Template,
+		Role:        role,
+	}, nil
+}
+
+// renderPromptTemplate 用 args 对 template 中的 {{argName}} 占位符做替换；未提供的参数保留占位符原样，
+// 便于调用方（或使用者）注意到遗漏，而不是静默替换为空字符串。
+func renderPromptTemplate(template string, args map[string]interface{}) string {
+	return templateVarPattern.ReplaceAllStringFunc(template, func(match string) string {
+		key := templateVarPattern.FindStringSubmatch(match)[1]
+		if v, ok := args[key]; ok {
+			return fmt.Sprintf("%v", v)
+		}
+		return match
+	})
+}
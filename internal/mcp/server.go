@@ -3,6 +3,7 @@ package mcp
 import (
 	"bufio"
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,6 +15,8 @@ import (
 	"sync"
 	"time"
 
+	"cyberstrike-ai/internal/tracing"
+
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
@@ -41,9 +44,165 @@ type Server struct {
 	logger                *zap.Logger
 	maxExecutionsInMemory int // 内存中最大执行记录数
 	sseClients            map[string]*sseClient
-	runningCancels        map[string]context.CancelFunc
-	runningCancelsMu      sync.Mutex
-	abortUserNotes        map[string]string // 监控页终止时附带的用户说明，与 executionID 对应
+	// streamableSessions 承载 Streamable HTTP 传输（MCP 2025-03-26）的会话：POST 携带 Mcp-Session-Id 头延续同一
+	// 会话，GET 携带同一头部打开服务器→客户端通知流；与旧版 2024-11-05 SSE 握手（sseClients，靠查询参数 sessionid
+	// 绑定）相互独立、互不影响。
+	streamableSessions map[string]*sseClient
+	runningCancels     map[string]context.CancelFunc
+	runningCancelsMu   sync.Mutex
+	abortUserNotes     map[string]string // 监控页终止时附带的用户说明，与 executionID 对应
+	defaultToolTimeout time.Duration     // tools/call 服务端默认超时，未设置时 handleCallTool 回退到 defaultToolTimeout常量
+	authTokens         []AuthToken       // 独立 MCP 端口（HandleHTTP）的 API Key 列表，见 SetAuthTokens
+
+	// resourceSubscribers 记录 resources/subscribe 建立的订阅关系：URI -> 订阅该 URI 的连接 ID 集合。
+	// 连接 ID 即 sseClients/streamableSessions 的 key（stdio 传输为进程内固定 ID），用于 notifyResourceUpdated
+	// 精确推送到订阅方，而 notifyResourcesListChanged 则广播给所有当前连接。
+	resourceSubscribers map[string]map[string]struct{}
+	stdioNotify         map[string]func(*Message) // stdio 传输的连接 ID -> 通知写入函数，供资源变更通知复用
+
+	samplingHandler      SamplingHandler   // 实际执行 sampling/request 的 LLM 调用方，见 SetSamplingHandler
+	samplingMaxTokens    int               // sampling/request 的服务端 maxTokens 强制上限，0 表示不限制
+	samplingModelAliases map[string]string // 请求中的模型偏好提示 -> Agent 实际模型名，见 SetSamplingLimits
+
+	// resourceReaders 按 URI 前缀委托动态资源内容的读取：mcp 包不反向依赖具体业务包（如 knowledge，
+	// 后者已经依赖 mcp），业务方实现 ResourceReader 后通过 RegisterResourceReader 注册前缀即可，
+	// 与 SamplingHandler 是同一种依赖倒置写法。
+	resourceReaders map[string]ResourceReader
+}
+
+// ResourceReader 为按前缀委托的动态资源（如逐条注册的知识库条目）提供按需读取的内容，
+// 见 RegisterResourceReader；resource.Description 静态文案不适用的场景改用此接口。
+type ResourceReader interface {
+	ReadResource(uri string) (string, error)
+}
+
+// RegisterResourceReader 为以 prefix 开头的资源 URI 注册动态内容读取器，见 generateResourceContent。
+func (s *Server) RegisterResourceReader(prefix string, reader ResourceReader) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resourceReaders[prefix] = reader
+}
+
+// SamplingHandler 由持有真实 LLM 客户端的一方（当前为 *agent.Agent）实现，供 handleSamplingRequest
+// 将外部 MCP 客户端的 sampling/request 转发为一次真实的补全调用。
+type SamplingHandler interface {
+	CreateSamplingCompletion(ctx context.Context, req SamplingRequest) (SamplingResponse, error)
+}
+
+// SetSamplingHandler 设置 sampling/request 的实际处理方；未设置时 handleSamplingRequest 返回错误响应。
+func (s *Server) SetSamplingHandler(handler SamplingHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samplingHandler = handler
+}
+
+// SetSamplingLimits 配置 sampling/request 的服务端 maxTokens 上限与模型别名映射表；
+// maxTokens 为 0 表示不做截断，modelAliases 为 nil 表示不做映射（由 SamplingHandler 自行决定默认模型）。
+func (s *Server) SetSamplingLimits(maxTokens int, modelAliases map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samplingMaxTokens = maxTokens
+	s.samplingModelAliases = modelAliases
+}
+
+// AuthToken 表示一个可用于访问 MCP HTTP 端点（HandleHTTP，即独立的 mcp.port 监听）的 API Key 及其权限范围。
+type AuthToken struct {
+	Token string
+	Scope string // MCPScopeList 或 MCPScopeCall，留空按 MCPScopeCall 处理
+}
+
+const (
+	// MCPScopeList 仅允许调用 tools/list 等只读方法
+	MCPScopeList = "list"
+	// MCPScopeCall 额外允许调用 tools/call（同时隐含 sampling/request 权限）
+	MCPScopeCall = "call"
+	// MCPScopeSampling 只允许调用 sampling/request，不允许 tools/call；用于只需要借用本机 LLM
+	// 做补全、但不应执行真实工具的外部 MCP 客户端。
+	MCPScopeSampling = "sampling"
+)
+
+// SetAuthTokens 配置 HandleHTTP 的 API Key 校验列表；为空时不做校验（兼容历史行为，即仅依赖
+// AuthHeader/AuthHeaderValue 或完全不鉴权）。非空后所有请求都必须在 Authorization: Bearer <token>
+// 或 X-API-Key 头中携带其中一个 token，且 scope 为 list 的 key 不能调用 tools/call。
+func (s *Server) SetAuthTokens(tokens []AuthToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authTokens = tokens
+}
+
+// authenticateHTTPRequest 从请求头解析 API Key 并返回其 scope；未配置任何 token 时视为不鉴权，直接放行。
+func (s *Server) authenticateHTTPRequest(r *http.Request) (scope string, authenticated bool) {
+	s.mu.RLock()
+	tokens := s.authTokens
+	s.mu.RUnlock()
+	if len(tokens) == 0 {
+		return MCPScopeCall, true
+	}
+
+	provided := extractAPIToken(r)
+	if provided == "" {
+		return "", false
+	}
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(provided)) == 1 {
+			sc := t.Scope
+			if sc == "" {
+				sc = MCPScopeCall
+			}
+			return sc, true
+		}
+	}
+	return "", false
+}
+
+// extractAPIToken 从 Authorization: Bearer 或 X-API-Key 头中提取 API Key
+func extractAPIToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if rest, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(rest)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// authorizeMethod 判断给定 scope 是否允许调用指定 JSON-RPC 方法：tools/call 需要 MCPScopeCall，其余只读方法放行。
+func authorizeMethod(scope, method string) bool {
+	switch method {
+	case "tools/call":
+		return scope == MCPScopeCall
+	case "sampling/request":
+		return scope == MCPScopeCall || scope == MCPScopeSampling
+	default:
+		return true
+	}
+}
+
+// writeUnauthorized 向未通过 API Key 校验的请求返回 401
+func writeUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	_, _ = w.Write([]byte(`{"error":"unauthorized"}`))
+}
+
+// defaultToolTimeout 未通过 SetDefaultToolTimeout 显式配置时使用的兜底超时
+const defaultToolTimeoutFallback = 30 * time.Minute
+
+// SetDefaultToolTimeout 设置 tools/call 的服务端默认超时，供未在 Tool.TimeoutSeconds 中单独指定超时的工具使用。
+// d <= 0 时恢复内置默认值（30 分钟）。
+func (s *Server) SetDefaultToolTimeout(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultToolTimeout = d
+}
+
+// SetMaxExecutionsInMemory 设置内存中最多保留的执行记录数，覆盖默认值（1000）。d <= 0 时忽略。
+func (s *Server) SetMaxExecutionsInMemory(n int) {
+	if n <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxExecutionsInMemory = n
 }
 
 type sseClient struct {
@@ -54,6 +213,51 @@ type sseClient struct {
 // ToolHandler 工具处理函数
 type ToolHandler func(ctx context.Context, args map[string]interface{}) (*ToolResult, error)
 
+// ProgressCallback 用于工具执行过程中上报进度（百分比 + 说明文字），通过 context 传递给 ToolHandler，
+// 避免修改 ToolHandler 签名影响所有已注册工具；仅在调用方（如 security.Executor）读取并主动调用时才有效。
+type ProgressCallback func(percentage float64, message string)
+
+type progressCallbackCtxKey struct{}
+
+// WithProgressCallback 将进度回调写入 context，供 handler 在执行过程中调用上报进度。
+func WithProgressCallback(ctx context.Context, cb ProgressCallback) context.Context {
+	return context.WithValue(ctx, progressCallbackCtxKey{}, cb)
+}
+
+// ProgressCallbackFromContext 读取 context 中的进度回调，未设置时返回 nil，调用方需自行判空。
+func ProgressCallbackFromContext(ctx context.Context) ProgressCallback {
+	cb, _ := ctx.Value(progressCallbackCtxKey{}).(ProgressCallback)
+	return cb
+}
+
+type executionIDCtxKey struct{}
+
+// WithExecutionID 将本次 tools/call 的 executionID 写入 context，供 handler 在执行过程中
+// （如 security.Executor 边执行边写入结果存储）关联到同一条执行记录，语义与 WithProgressCallback 一致。
+func WithExecutionID(ctx context.Context, executionID string) context.Context {
+	return context.WithValue(ctx, executionIDCtxKey{}, executionID)
+}
+
+// ExecutionIDFromContext 读取 context 中的 executionID，未设置时返回空字符串。
+func ExecutionIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(executionIDCtxKey{}).(string)
+	return id
+}
+
+type conversationIDCtxKey struct{}
+
+// WithConversationID 将当前对话ID写入 context，供 handler 在执行过程中（如 security.Executor 的
+// 目标范围校验）关联到发起调用的对话/项目，语义与 WithExecutionID 一致。
+func WithConversationID(ctx context.Context, conversationID string) context.Context {
+	return context.WithValue(ctx, conversationIDCtxKey{}, conversationID)
+}
+
+// ConversationIDFromContext 读取 context 中的对话ID，未设置时返回空字符串。
+func ConversationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(conversationIDCtxKey{}).(string)
+	return id
+}
+
 func executionStatusAndMessage(err error) (status string, errMsg string) {
 	if errors.Is(err, context.Canceled) {
 		return "cancelled", "已手动终止（MCP 监控）"
@@ -79,8 +283,12 @@ func NewServerWithStorage(logger *zap.Logger, storage MonitorStorage) *Server {
 		logger:                logger,
 		maxExecutionsInMemory: 1000, // 默认最多在内存中保留1000条执行记录
 		sseClients:            make(map[string]*sseClient),
+		streamableSessions:    make(map[string]*sseClient),
 		runningCancels:        make(map[string]context.CancelFunc),
 		abortUserNotes:        make(map[string]string),
+		resourceSubscribers:   make(map[string]map[string]struct{}),
+		stdioNotify:           make(map[string]func(*Message)),
+		resourceReaders:       make(map[string]ResourceReader),
 	}
 
 	// 初始化默认提示词和资源
@@ -93,7 +301,6 @@ func NewServerWithStorage(logger *zap.Logger, storage MonitorStorage) *Server {
 // RegisterTool 注册工具
 func (s *Server) RegisterTool(tool Tool, handler ToolHandler) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.tools[tool.Name] = handler
 	s.toolDefs[tool.Name] = tool
 
@@ -105,12 +312,29 @@ func (s *Server) RegisterTool(tool Tool, handler ToolHandler) {
 		Description: tool.Description,
 		MimeType:    "text/plain",
 	}
+	s.mu.Unlock()
+
+	s.notifyResourcesListChanged()
+}
+
+// toolExecutionTimeout 返回指定工具在 tools/call 中应使用的执行超时：优先使用工具自身注册时携带的
+// TimeoutSeconds，未设置则回退到 SetDefaultToolTimeout 配置的服务端默认值，两者都未设置时使用内置的 30 分钟。
+func (s *Server) toolExecutionTimeout(toolName string) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if def, ok := s.toolDefs[toolName]; ok && def.TimeoutSeconds > 0 {
+		return time.Duration(def.TimeoutSeconds) * time.Second
+	}
+	if s.defaultToolTimeout > 0 {
+		return s.defaultToolTimeout
+	}
+	return defaultToolTimeoutFallback
 }
 
 // ClearTools 清空所有工具（用于重新加载配置）
 func (s *Server) ClearTools() {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	// 清空工具和工具定义
 	s.tools = make(map[string]ToolHandler)
@@ -125,10 +349,33 @@ func (s *Server) ClearTools() {
 		}
 	}
 	s.resources = newResources
+	s.mu.Unlock()
+
+	s.notifyResourcesListChanged()
 }
 
 // HandleHTTP 处理HTTP请求
 func (s *Server) HandleHTTP(w http.ResponseWriter, r *http.Request) {
+	scope, authenticated := s.authenticateHTTPRequest(r)
+	if !authenticated {
+		writeUnauthorized(w)
+		return
+	}
+
+	// Streamable HTTP（MCP 2025-03-26）：带 Mcp-Session-Id 头的 GET/DELETE 属于该传输，与旧版
+	// 2024-11-05 SSE 握手（靠查询参数 sessionid 绑定）走不同分支，互不影响。
+	if streamSessionID := r.Header.Get("Mcp-Session-Id"); streamSessionID != "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.handleStreamableGET(w, r, streamSessionID)
+			return
+		case http.MethodDelete:
+			s.closeStreamableSession(streamSessionID)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
 	if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
 		s.handleSSE(w, r)
 		return
@@ -141,7 +388,7 @@ func (s *Server) HandleHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// 官方 MCP SSE 规范：带 sessionid 的 POST 表示消息发往该 SSE 会话，响应通过 SSE 流返回
 	if sessionID := r.URL.Query().Get("sessionid"); sessionID != "" {
-		s.serveSSESessionMessage(w, r, sessionID)
+		s.serveSSESessionMessage(w, r, sessionID, scope)
 		return
 	}
 
@@ -158,13 +405,43 @@ func (s *Server) HandleHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := s.handleMessage(&msg)
+	if !authorizeMethod(scope, msg.Method) {
+		s.sendError(w, msg.ID.Value(), -32001, "insufficient scope", "此 API Key 无权调用 tools/call")
+		return
+	}
+
+	// Streamable HTTP 的会话 ID（若已建立）作为 clientID，使该会话上的 resources/subscribe 能定位到
+	// 对应的 streamableSessions 推送通道；尚未建立会话（如首次 initialize）时为空，此时订阅请求会被拒绝。
+	response := s.handleMessageNotify(&msg, r.Header.Get("Mcp-Session-Id"), nil)
+
+	// Streamable HTTP：initialize 请求若未携带会话 ID，则新建一个并通过响应头下发，供客户端后续
+	// POST/GET/DELETE 复用同一会话。
+	streamSessionID := r.Header.Get("Mcp-Session-Id")
+	if msg.Method == "initialize" && streamSessionID == "" {
+		streamSessionID = s.newStreamableSession()
+	}
+	if streamSessionID != "" {
+		w.Header().Set("Mcp-Session-Id", streamSessionID)
+	}
+
+	// 客户端要求以 SSE 形式接收本次响应时，将单条 JSON-RPC 响应作为一帧 event: message 下发，
+	// 使长时间运行的 tools/call 请求不必以普通 JSON 响应阻塞连接。
+	if response != nil && strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.writeStreamableResponse(w, response)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
+	if response == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
 	json.NewEncoder(w).Encode(response)
 }
 
-// serveSSESessionMessage 处理发往 SSE 会话的 POST：读取 JSON-RPC 请求，处理后将响应通过该会话的 SSE 流推送
-func (s *Server) serveSSESessionMessage(w http.ResponseWriter, r *http.Request, sessionID string) {
+// serveSSESessionMessage 处理发往 SSE 会话的 POST：读取 JSON-RPC 请求，处理后将响应通过该会话的 SSE 流推送；
+// scope 为调用方在 HandleHTTP 入口处鉴权得到的权限范围，用于限制该会话内能否调用 tools/call。
+func (s *Server) serveSSESessionMessage(w http.ResponseWriter, r *http.Request, sessionID string, scope string) {
 	s.mu.RLock()
 	client, exists := s.sseClients[sessionID]
 	s.mu.RUnlock()
@@ -185,7 +462,23 @@ func (s *Server) serveSSESessionMessage(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	response := s.handleMessage(&msg)
+	if !authorizeMethod(scope, msg.Method) {
+		s.sendError(w, msg.ID.Value(), -32001, "insufficient scope", "此 API Key 无权调用 tools/call")
+		return
+	}
+
+	notify := func(notification *Message) {
+		notifyBytes, err := json.Marshal(notification)
+		if err != nil {
+			return
+		}
+		select {
+		case client.send <- notifyBytes:
+		default:
+			// 通知非关键信息，会话发送缓冲已满时直接丢弃，不影响最终响应
+		}
+	}
+	response := s.handleMessageNotify(&msg, sessionID, notify)
 	if response == nil {
 		w.WriteHeader(http.StatusAccepted)
 		return
@@ -278,8 +571,108 @@ func (s *Server) removeSSEClient(id string) {
 	}
 }
 
+// newStreamableSession 创建一个 Streamable HTTP 会话并返回其 ID，供 initialize 响应下发。
+func (s *Server) newStreamableSession() string {
+	sessionID := uuid.New().String()
+	s.mu.Lock()
+	s.streamableSessions[sessionID] = &sseClient{
+		id:   sessionID,
+		send: make(chan []byte, 32),
+	}
+	s.mu.Unlock()
+	return sessionID
+}
+
+// closeStreamableSession 终止指定的 Streamable HTTP 会话，释放其发送通道（DELETE 请求触发）。
+func (s *Server) closeStreamableSession(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if client, exists := s.streamableSessions[sessionID]; exists {
+		close(client.send)
+		delete(s.streamableSessions, sessionID)
+	}
+}
+
+// writeStreamableResponse 将单条 JSON-RPC 响应以 SSE 形式写回 POST 请求的响应体，
+// 用于 Streamable HTTP 传输中 POST 响应"升级"为单帧事件流的场景。
+func (s *Server) writeStreamableResponse(w http.ResponseWriter, response *Message) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	respBytes, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprintf(w, "event: message\ndata: %s\n\n", respBytes)
+	flusher.Flush()
+}
+
+// handleStreamableGET 处理 Streamable HTTP 传输中携带 Mcp-Session-Id 的 GET 请求，为该会话打开一条
+// 长连接 SSE 流用于服务器主动下发的消息；不发送旧版 2024-11-05 规范的 event: endpoint 握手事件。
+func (s *Server) handleStreamableGET(w http.ResponseWriter, r *http.Request, sessionID string) {
+	s.mu.RLock()
+	client, exists := s.streamableSessions[sessionID]
+	s.mu.RUnlock()
+	if !exists {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.Header().Set("Mcp-Session-Id", sessionID)
+	flusher.Flush()
+
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg, ok := <-client.send:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg)
+			flusher.Flush()
+		case <-ticker.C:
+			fmt.Fprintf(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
 // handleMessage 处理MCP消息
+// handleMessage 处理不需要在工具执行期间主动推送通知的调用方（如同步 POST）。
 func (s *Server) handleMessage(msg *Message) *Message {
+	return s.handleMessageNotify(msg, "", nil)
+}
+
+// handleMessageNotify 处理 MCP 消息；notify 非空时，tools/call 若带 progressToken，
+// 执行期间产生的 notifications/progress 会通过 notify 推送给调用方（SSE 会话、stdio 连接等
+// 具备独立于响应之外主动推送能力的传输）。notify 为 nil 时静默跳过进度上报，不影响正常调用。
+// clientID 标识发起该消息的连接（sseClients/streamableSessions 的 key，或 stdio 连接 ID），
+// 用于 resources/subscribe、resources/unsubscribe 记录订阅关系；无持久连接（如无会话的简单 POST）
+// 时可传空字符串，此时订阅请求会被拒绝（没有可推送通知的连接）。
+func (s *Server) handleMessageNotify(msg *Message, clientID string, notify func(*Message)) *Message {
 	// 检查是否是通知（notification）- 通知没有id字段，不需要响应
 	isNotification := msg.ID.Value() == nil || msg.ID.String() == ""
 
@@ -294,7 +687,9 @@ func (s *Server) handleMessage(msg *Message) *Message {
 	case "tools/list":
 		return s.handleListTools(msg)
 	case "tools/call":
-		return s.handleCallTool(msg)
+		return s.handleCallTool(msg, notify)
+	case "tools/cancel":
+		return s.handleCancelTool(msg)
 	case "prompts/list":
 		return s.handleListPrompts(msg)
 	case "prompts/get":
@@ -303,6 +698,10 @@ func (s *Server) handleMessage(msg *Message) *Message {
 		return s.handleListResources(msg)
 	case "resources/read":
 		return s.handleReadResource(msg)
+	case "resources/subscribe":
+		return s.handleSubscribeResource(msg, clientID)
+	case "resources/unsubscribe":
+		return s.handleUnsubscribeResource(msg, clientID)
 	case "sampling/request":
 		return s.handleSamplingRequest(msg)
 	case "notifications/initialized":
@@ -394,8 +793,9 @@ func (s *Server) handleListTools(msg *Message) *Message {
 	}
 }
 
-// handleCallTool 处理工具调用请求
-func (s *Server) handleCallTool(msg *Message) *Message {
+// handleCallTool 处理工具调用请求；notify 非空且请求携带 _meta.progressToken 时，执行期间
+// handler 通过 mcp.ProgressCallbackFromContext 上报的进度会被封装为 notifications/progress 推送。
+func (s *Server) handleCallTool(msg *Message, notify func(*Message)) *Message {
 	var req CallToolRequest
 	if err := json.Unmarshal(msg.Params, &req); err != nil {
 		return &Message{
@@ -408,11 +808,12 @@ func (s *Server) handleCallTool(msg *Message) *Message {
 
 	executionID := uuid.New().String()
 	execution := &ToolExecution{
-		ID:        executionID,
-		ToolName:  req.Name,
-		Arguments: req.Arguments,
-		Status:    "running",
-		StartTime: time.Now(),
+		ID:           executionID,
+		ToolName:     req.Name,
+		Arguments:    s.MaskToolArguments(req.Name, req.Arguments),
+		RawArguments: req.Arguments,
+		Status:       "running",
+		StartTime:    time.Now(),
 	}
 
 	s.mu.Lock()
@@ -457,18 +858,39 @@ func (s *Server) handleCallTool(msg *Message) *Message {
 		}
 	}
 
-	baseCtx, timeoutCancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	baseCtx, timeoutCancel := context.WithTimeout(context.Background(), s.toolExecutionTimeout(req.Name))
 	defer timeoutCancel()
 	execCtx, runCancel := context.WithCancel(baseCtx)
+	execCtx = WithExecutionID(execCtx, executionID)
 	s.registerRunningCancel(executionID, runCancel)
 	defer func() {
 		runCancel()
 		s.unregisterRunningCancel(executionID)
 	}()
 
+	if notify != nil && req.Meta != nil && req.Meta.ProgressToken != nil {
+		progressToken := req.Meta.ProgressToken
+		execCtx = WithProgressCallback(execCtx, func(percentage float64, message string) {
+			params, err := json.Marshal(ProgressNotificationParams{
+				ProgressToken: progressToken,
+				Progress:      percentage,
+				Message:       message,
+			})
+			if err != nil {
+				return
+			}
+			notify(&Message{
+				Type:    MessageTypeNotify,
+				Method:  "notifications/progress",
+				Version: "2.0",
+				Params:  params,
+			})
+		})
+	}
+
 	s.logger.Info("开始执行工具",
 		zap.String("toolName", req.Name),
-		zap.Any("arguments", req.Arguments),
+		zap.Any("arguments", execution.Arguments),
 	)
 
 	result, err := handler(execCtx, req.Arguments)
@@ -769,8 +1191,21 @@ func (s *Server) GetAllTools() []Tool {
 	return tools
 }
 
+// GetToolDef 按名称查找单个内置工具定义（用于调用前的参数校验），未找到返回 false。
+func (s *Server) GetToolDef(toolName string) (Tool, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tool, exists := s.toolDefs[toolName]
+	return tool, exists
+}
+
 // CallTool 直接调用工具（用于内部调用）
 func (s *Server) CallTool(ctx context.Context, toolName string, args map[string]interface{}) (*ToolResult, string, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "mcp.Server.CallTool")
+	defer span.End()
+	span.SetAttributes(tracing.StringAttr("tool.name", toolName))
+
 	s.mu.RLock()
 	handler, exists := s.tools[toolName]
 	s.mu.RUnlock()
@@ -787,6 +1222,7 @@ func (s *Server) CallTool(ctx context.Context, toolName string, args map[string]
 		Arguments: args,
 		Status:    "running",
 		StartTime: time.Now(),
+		TraceID:   tracing.TraceIDFromContext(ctx),
 	}
 
 	s.mu.Lock()
@@ -802,6 +1238,7 @@ func (s *Server) CallTool(ctx context.Context, toolName string, args map[string]
 	}
 
 	execCtx, runCancel := context.WithCancel(ctx)
+	execCtx = WithExecutionID(execCtx, executionID)
 	s.registerRunningCancel(executionID, runCancel)
 	defer func() {
 		runCancel()
@@ -930,6 +1367,17 @@ func (s *Server) unregisterRunningCancel(id string) {
 	s.runningCancelsMu.Unlock()
 }
 
+// SetExecutionStatus 更新内存中执行记录的状态，供 security.Executor 在并发限流排队等待期间
+// 把状态置为 "queued"、拿到执行名额后再置回 "running"，使监控页面能实时看到排队中的调用。
+// 仅更新内存态（不落库），执行结束时既有的完成/失败流程仍会照常持久化最终状态。
+func (s *Server) SetExecutionStatus(executionID, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if exec, ok := s.executions[executionID]; ok {
+		exec.Status = status
+	}
+}
+
 func (s *Server) readAbortUserNote(id string) string {
 	s.runningCancelsMu.Lock()
 	defer s.runningCancelsMu.Unlock()
@@ -1000,6 +1448,52 @@ func (s *Server) CancelToolExecution(id string) bool {
 	return s.CancelToolExecutionWithNote(id, "")
 }
 
+// CancelAllToolExecutions 取消当前所有正在运行的内部工具调用，用于全局紧急停止（见
+// handler.EmergencyStopHandler）；返回被取消的执行数量。
+func (s *Server) CancelAllToolExecutions() int {
+	s.runningCancelsMu.Lock()
+	cancels := make([]context.CancelFunc, 0, len(s.runningCancels))
+	for _, cancel := range s.runningCancels {
+		if cancel != nil {
+			cancels = append(cancels, cancel)
+		}
+	}
+	s.runningCancelsMu.Unlock()
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return len(cancels)
+}
+
+// handleCancelTool 处理 tools/cancel 请求：向指定 executionId 对应的运行中工具调用发送取消信号，
+// 与 REST 侧 /api/monitor/execution/:id/cancel 共用同一底层机制（CancelToolExecutionWithNote），
+// 使外部 MCP 客户端也能主动终止一次长时间运行的 tools/call，而不必等待其自然结束。
+func (s *Server) handleCancelTool(msg *Message) *Message {
+	var req CancelToolRequest
+	if err := json.Unmarshal(msg.Params, &req); err != nil || strings.TrimSpace(req.ExecutionID) == "" {
+		return &Message{
+			ID:      msg.ID,
+			Type:    MessageTypeError,
+			Version: "2.0",
+			Error:   &Error{Code: -32602, Message: "Invalid params"},
+		}
+	}
+
+	cancelled := s.CancelToolExecutionWithNote(req.ExecutionID, req.Note)
+	if cancelled {
+		s.logger.Info("已通过 MCP tools/cancel 请求取消工具执行", zap.String("executionId", req.ExecutionID))
+	}
+
+	result, _ := json.Marshal(CancelToolResponse{Cancelled: cancelled})
+	return &Message{
+		ID:      msg.ID,
+		Type:    MessageTypeResponse,
+		Version: "2.0",
+		Result:  result,
+	}
+}
+
 // initDefaultPrompts 初始化默认提示词模板
 func (s *Server) initDefaultPrompts() {
 	s.mu.Lock()
@@ -1093,8 +1587,15 @@ func (s *Server) handleGetPrompt(msg *Message) *Message {
 	}
 }
 
-// generatePromptMessages 生成提示词消息
+// generatePromptMessages 生成提示词消息：来自 PromptsDir 的模板（Template 非空）统一走 {{var}} 替换，
+// 硬编码的内置提示词（security_scan、penetration_test）仍走各自的 switch-case 分支。
 func (s *Server) generatePromptMessages(prompt *Prompt, args map[string]interface{}) []PromptMessage {
+	if prompt.Template != "" {
+		return []PromptMessage{
+			{Role: prompt.Role, Content: renderPromptTemplate(prompt.Template, args)},
+		}
+	}
+
 	messages := []PromptMessage{}
 
 	switch prompt.Name {
@@ -1202,6 +1703,139 @@ func (s *Server) handleReadResource(msg *Message) *Message {
 	}
 }
 
+// handleSubscribeResource 处理 resources/subscribe 请求：记录 clientID 对 req.URI 的订阅关系，
+// 之后该 URI 的内容通过 notifyResourceUpdated 变更时会推送 notifications/resources/updated 给 clientID。
+// 无持久连接（clientID 为空，如未建立会话的简单 POST）时无法接收后续推送，直接返回错误。
+func (s *Server) handleSubscribeResource(msg *Message, clientID string) *Message {
+	var req SubscribeResourceRequest
+	if err := json.Unmarshal(msg.Params, &req); err != nil || req.URI == "" {
+		return &Message{ID: msg.ID, Type: MessageTypeError, Version: "2.0", Error: &Error{Code: -32602, Message: "Invalid params"}}
+	}
+	if clientID == "" {
+		return &Message{ID: msg.ID, Type: MessageTypeError, Version: "2.0", Error: &Error{Code: -32602, Message: "subscribe requires a persistent connection (SSE / Streamable HTTP session / stdio)"}}
+	}
+
+	s.mu.Lock()
+	if _, exists := s.resources[req.URI]; !exists {
+		s.mu.Unlock()
+		return &Message{ID: msg.ID, Type: MessageTypeError, Version: "2.0", Error: &Error{Code: -32601, Message: "Resource not found"}}
+	}
+	if s.resourceSubscribers[req.URI] == nil {
+		s.resourceSubscribers[req.URI] = make(map[string]struct{})
+	}
+	s.resourceSubscribers[req.URI][clientID] = struct{}{}
+	s.mu.Unlock()
+
+	s.logger.Info("已订阅资源变更", zap.String("uri", req.URI), zap.String("clientId", clientID))
+	return &Message{ID: msg.ID, Type: MessageTypeResponse, Version: "2.0", Result: json.RawMessage("{}")}
+}
+
+// handleUnsubscribeResource 处理 resources/unsubscribe 请求，撤销 handleSubscribeResource 建立的订阅关系。
+func (s *Server) handleUnsubscribeResource(msg *Message, clientID string) *Message {
+	var req UnsubscribeResourceRequest
+	if err := json.Unmarshal(msg.Params, &req); err != nil || req.URI == "" {
+		return &Message{ID: msg.ID, Type: MessageTypeError, Version: "2.0", Error: &Error{Code: -32602, Message: "Invalid params"}}
+	}
+
+	s.mu.Lock()
+	if subs, exists := s.resourceSubscribers[req.URI]; exists {
+		delete(subs, clientID)
+		if len(subs) == 0 {
+			delete(s.resourceSubscribers, req.URI)
+		}
+	}
+	s.mu.Unlock()
+
+	return &Message{ID: msg.ID, Type: MessageTypeResponse, Version: "2.0", Result: json.RawMessage("{}")}
+}
+
+// pushToClient 将一条通知推送给指定连接：可能是 sseClients、streamableSessions 或 stdio 连接，
+// 三者用同一套 clientID 命名空间（互不相交），发送失败/缓冲区满时静默丢弃，不影响正常调用。
+func (s *Server) pushToClient(clientID string, notification *Message) {
+	data, err := json.Marshal(notification)
+	if err != nil {
+		return
+	}
+
+	s.mu.RLock()
+	legacyClient, isSSE := s.sseClients[clientID]
+	streamClient, isStream := s.streamableSessions[clientID]
+	stdioNotify, isStdio := s.stdioNotify[clientID]
+	s.mu.RUnlock()
+
+	switch {
+	case isSSE:
+		select {
+		case legacyClient.send <- data:
+		default:
+		}
+	case isStream:
+		select {
+		case streamClient.send <- data:
+		default:
+		}
+	case isStdio:
+		stdioNotify(notification)
+	}
+}
+
+// notifyResourcesListChanged 广播 notifications/resources/list_changed 给所有当前连接（SSE、Streamable
+// HTTP、stdio），在工具/资源集合发生变化时调用（RegisterTool、ClearTools、RegisterResource）。
+func (s *Server) notifyResourcesListChanged() {
+	notification := &Message{Type: MessageTypeNotify, Version: "2.0", Method: "notifications/resources/list_changed"}
+
+	s.mu.RLock()
+	clientIDs := make([]string, 0, len(s.sseClients)+len(s.streamableSessions)+len(s.stdioNotify))
+	for id := range s.sseClients {
+		clientIDs = append(clientIDs, id)
+	}
+	for id := range s.streamableSessions {
+		clientIDs = append(clientIDs, id)
+	}
+	for id := range s.stdioNotify {
+		clientIDs = append(clientIDs, id)
+	}
+	s.mu.RUnlock()
+
+	for _, id := range clientIDs {
+		s.pushToClient(id, notification)
+	}
+}
+
+// NotifyResourceUpdated 推送 notifications/resources/updated 给订阅了指定 URI 的连接，供资源内容
+// 提供方（如知识库）在条目变更后调用；未被任何连接订阅时是无操作。
+func (s *Server) NotifyResourceUpdated(uri string) {
+	params, _ := json.Marshal(ResourceUpdatedNotificationParams{URI: uri})
+	notification := &Message{Type: MessageTypeNotify, Version: "2.0", Method: "notifications/resources/updated", Params: params}
+
+	s.mu.RLock()
+	subscribers := make([]string, 0, len(s.resourceSubscribers[uri]))
+	for id := range s.resourceSubscribers[uri] {
+		subscribers = append(subscribers, id)
+	}
+	s.mu.RUnlock()
+
+	for _, id := range subscribers {
+		s.pushToClient(id, notification)
+	}
+}
+
+// matchResourceReader 按最长前缀匹配查找 uri 对应的 ResourceReader，未命中返回 nil。
+func (s *Server) matchResourceReader(uri string) ResourceReader {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var best ResourceReader
+	bestLen := -1
+	for prefix, reader := range s.resourceReaders {
+		if strings.HasPrefix(uri, prefix) && len(prefix) > bestLen {
+			best = reader
+			bestLen = len(prefix)
+		}
+	}
+	return best
+}
+
 // generateResourceContent 生成资源内容
 func (s *Server) generateResourceContent(resource *Resource) ResourceContent {
 	content := ResourceContent{
@@ -1209,13 +1843,23 @@ func (s *Server) generateResourceContent(resource *Resource) ResourceContent {
 		MimeType: resource.MimeType,
 	}
 
-	// 如果是工具资源，生成详细文档
-	if strings.HasPrefix(resource.URI, "tool://") {
+	switch {
+	case strings.HasPrefix(resource.URI, "tool://"):
+		// 工具资源，生成详细文档
 		toolName := strings.TrimPrefix(resource.URI, "tool://")
 		content.Text = s.generateToolDocumentation(toolName, resource)
-	} else {
-		// 其他资源使用描述或默认内容
-		content.Text = resource.Description
+	default:
+		if reader := s.matchResourceReader(resource.URI); reader != nil {
+			text, err := reader.ReadResource(resource.URI)
+			if err != nil {
+				content.Text = fmt.Sprintf("读取资源失败: %v", err)
+			} else {
+				content.Text = text
+			}
+		} else {
+			// 其他资源使用描述或默认内容
+			content.Text = resource.Description
+		}
 	}
 
 	return content
@@ -1249,7 +1893,9 @@ func (s *Server) generateToolDocumentation(toolName string, resource *Resource)
 	return resource.Description
 }
 
-// handleSamplingRequest 处理采样请求
+// handleSamplingRequest 处理 sampling/request：将外部 MCP 客户端的采样请求转发给 SetSamplingHandler
+// 配置的真实 LLM（当前为 Agent 的 OpenAI 客户端），并在转发前按 SetSamplingLimits 做 maxTokens 截断与
+// 模型偏好别名映射。未配置 samplingHandler 时返回明确的错误响应，而不是伪造一段占位文本。
 func (s *Server) handleSamplingRequest(msg *Message) *Message {
 	var req SamplingRequest
 	if err := json.Unmarshal(msg.Params, &req); err != nil {
@@ -1261,21 +1907,42 @@ func (s *Server) handleSamplingRequest(msg *Message) *Message {
 		}
 	}
 
-	// 注意：采样功能通常需要连接到实际的LLM服务
-	// 这里返回一个占位符响应，实际实现需要集成LLM API
-	s.logger.Warn("Sampling request received but not fully implemented",
-		zap.Any("request", req),
-	)
+	s.mu.RLock()
+	handler := s.samplingHandler
+	maxTokens := s.samplingMaxTokens
+	modelAliases := s.samplingModelAliases
+	s.mu.RUnlock()
 
-	response := SamplingResponse{
-		Content: []SamplingContent{
-			{
-				Type: "text",
-				Text: "采样功能需要配置LLM服务。请使用Agent Loop API进行AI对话。",
-			},
-		},
-		StopReason: "length",
+	if handler == nil {
+		return &Message{
+			ID:      msg.ID,
+			Type:    MessageTypeError,
+			Version: "2.0",
+			Error:   &Error{Code: -32601, Message: "sampling/request 未配置：请在 mcp.sampling.enabled 中启用并连接 Agent"},
+		}
 	}
+
+	if maxTokens > 0 && (req.MaxTokens <= 0 || req.MaxTokens > maxTokens) {
+		req.MaxTokens = maxTokens
+	}
+	if alias, ok := modelAliases[req.Model]; ok {
+		req.Model = alias
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	response, err := handler.CreateSamplingCompletion(ctx, req)
+	if err != nil {
+		s.logger.Error("sampling/request 调用失败", zap.Error(err))
+		return &Message{
+			ID:      msg.ID,
+			Type:    MessageTypeError,
+			Version: "2.0",
+			Error:   &Error{Code: -32603, Message: "sampling completion failed: " + err.Error()},
+		}
+	}
+
 	result, _ := json.Marshal(response)
 	return &Message{
 		ID:      msg.ID,
@@ -1292,21 +1959,75 @@ func (s *Server) RegisterPrompt(prompt *Prompt) {
 	s.prompts[prompt.Name] = prompt
 }
 
+// RemovePrompt 移除提示词模板，供 PromptsHandler 在磁盘上的定义文件被删除时同步移除
+func (s *Server) RemovePrompt(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.prompts, name)
+}
+
 // RegisterResource 注册资源
 func (s *Server) RegisterResource(resource *Resource) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.resources[resource.URI] = resource
+	s.mu.Unlock()
+
+	s.notifyResourcesListChanged()
+}
+
+// ClearResourcesWithPrefix 移除所有 URI 以 prefix 开头的资源，供批量重新注册前先清空使用
+// （如知识库条目集合整体重建时，避免残留已删除条目对应的资源）。
+func (s *Server) ClearResourcesWithPrefix(prefix string) {
+	s.mu.Lock()
+	for uri := range s.resources {
+		if strings.HasPrefix(uri, prefix) {
+			delete(s.resources, uri)
+		}
+	}
+	s.mu.Unlock()
+
+	s.notifyResourcesListChanged()
 }
 
 // HandleStdio 处理标准输入输出（用于 stdio 传输模式）
-// MCP 协议使用换行分隔的 JSON-RPC 消息；管道下需每次写入后 Flush，否则客户端会读不到响应
+// MCP 协议使用换行分隔的 JSON-RPC 消息；管道下需每次写入后 Flush，否则客户端会读不到响应。
+// 每条消息在独立 goroutine 中处理（与 HandleHTTP 每个请求天然并发一致），避免一次耗时较长的
+// 工具调用（如端口扫描）阻塞同一连接上后续的 tools/list、其他并发 tools/call 等请求；
+// stdout 写入通过 writeMu 互斥，防止并发响应交织成非法 JSON。
 func (s *Server) HandleStdio() error {
 	decoder := json.NewDecoder(os.Stdin)
 	stdout := bufio.NewWriter(os.Stdout)
 	encoder := json.NewEncoder(stdout)
 	// 注意：不设置缩进，MCP 协议期望紧凑的 JSON 格式
 
+	var writeMu sync.Mutex
+	writeResponse := func(resp *Message) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+		return stdout.Flush()
+	}
+
+	// stdio 一个进程只服务一个客户端连接，使用固定 ID 即可满足 resources/subscribe 的连接标识需求；
+	// 注册后 notifyResourcesListChanged/notifyResourceUpdated 才能把资源变更通知推送到 stdout。
+	stdioClientID := uuid.New().String()
+	stdioNotify := func(notification *Message) {
+		if err := writeResponse(notification); err != nil {
+			s.logger.Warn("发送资源变更通知失败", zap.Error(err))
+		}
+	}
+	s.mu.Lock()
+	s.stdioNotify[stdioClientID] = stdioNotify
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.stdioNotify, stdioClientID)
+		s.mu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
 	for {
 		var msg Message
 		if err := decoder.Decode(&msg); err != nil {
@@ -1316,38 +2037,38 @@ func (s *Server) HandleStdio() error {
 			// 日志输出到 stderr，避免干扰 stdout 的 JSON-RPC 通信
 			s.logger.Error("读取消息失败", zap.Error(err))
 			// 发送错误响应
-			errorMsg := Message{
+			errorMsg := &Message{
 				ID:      msg.ID,
 				Type:    MessageTypeError,
 				Version: "2.0",
 				Error:   &Error{Code: -32700, Message: "Parse error", Data: err.Error()},
 			}
-			if err := encoder.Encode(errorMsg); err != nil {
+			if err := writeResponse(errorMsg); err != nil {
 				return fmt.Errorf("发送错误响应失败: %w", err)
 			}
-			if err := stdout.Flush(); err != nil {
-				return fmt.Errorf("刷新 stdout 失败: %w", err)
-			}
 			continue
 		}
 
-		// 处理消息
-		response := s.handleMessage(&msg)
-
-		// 如果是通知（response 为 nil），不需要发送响应
-		if response == nil {
-			continue
-		}
-
-		// 发送响应
-		if err := encoder.Encode(response); err != nil {
-			return fmt.Errorf("发送响应失败: %w", err)
-		}
-		if err := stdout.Flush(); err != nil {
-			return fmt.Errorf("刷新 stdout 失败: %w", err)
-		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			notify := func(notification *Message) {
+				if err := writeResponse(notification); err != nil {
+					s.logger.Warn("发送进度通知失败", zap.Error(err))
+				}
+			}
+			response := s.handleMessageNotify(&msg, stdioClientID, notify)
+			// 如果是通知（response 为 nil），不需要发送响应
+			if response == nil {
+				return
+			}
+			if err := writeResponse(response); err != nil {
+				s.logger.Error("发送响应失败", zap.Error(err))
+			}
+		}()
 	}
 
+	wg.Wait()
 	return nil
 }
 
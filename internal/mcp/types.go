@@ -104,6 +104,8 @@ type Tool struct {
 	Description      string                 `json:"description"`                // 详细描述
 	ShortDescription string                 `json:"shortDescription,omitempty"` // 简短描述（用于工具列表，减少token消耗）
 	InputSchema      map[string]interface{} `json:"inputSchema"`
+	SensitiveParams  []string               `json:"-"` // 敏感参数名列表（如 Cookie、Token），执行记录/日志/SSE 事件中会被掩码，不对外暴露
+	TimeoutSeconds   int                    `json:"-"` // 单次执行超时（秒），0 表示使用 Server 的默认超时（见 Server.SetDefaultToolTimeout）
 }
 
 // ToolCall 表示工具调用
@@ -180,6 +182,20 @@ type ListResourcesResponse struct {
 type CallToolRequest struct {
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments"`
+	Meta      *RequestMeta           `json:"_meta,omitempty"` // MCP 规范约定的元数据字段，当前仅用于进度令牌
+}
+
+// RequestMeta 对应 JSON-RPC 请求 params 中的 _meta 字段
+type RequestMeta struct {
+	ProgressToken interface{} `json:"progressToken,omitempty"` // 客户端要求上报进度时携带的令牌，原样回传
+}
+
+// ProgressNotificationParams notifications/progress 通知的 params
+type ProgressNotificationParams struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      float64     `json:"progress"`
+	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
 }
 
 // CallToolResponse 调用工具响应
@@ -188,17 +204,31 @@ type CallToolResponse struct {
 	IsError bool      `json:"isError,omitempty"`
 }
 
+// CancelToolRequest tools/cancel 请求：向指定 executionId 对应的运行中工具调用发送取消信号
+type CancelToolRequest struct {
+	ExecutionID string `json:"executionId"`
+	Note        string `json:"note,omitempty"` // 用户说明，将与工具已产生的输出合并交给模型
+}
+
+// CancelToolResponse tools/cancel 响应
+type CancelToolResponse struct {
+	Cancelled bool `json:"cancelled"` // 未找到对应的运行中执行（已结束/ID 有误）时为 false
+}
+
 // ToolExecution 工具执行记录
 type ToolExecution struct {
-	ID        string                 `json:"id"`
-	ToolName  string                 `json:"toolName"`
-	Arguments map[string]interface{} `json:"arguments"`
-	Status    string                 `json:"status"` // pending, running, completed, failed, cancelled
-	Result    *ToolResult            `json:"result,omitempty"`
-	Error     string                 `json:"error,omitempty"`
-	StartTime time.Time              `json:"startTime"`
-	EndTime   *time.Time             `json:"endTime,omitempty"`
-	Duration  time.Duration          `json:"duration,omitempty"`
+	ID           string                 `json:"id"`
+	ToolName     string                 `json:"toolName"`
+	Arguments    map[string]interface{} `json:"arguments"` // 展示用参数：命中 sensitive 的字段已被掩码
+	RawArguments map[string]interface{} `json:"-"`         // 真实参数（含敏感值），仅用于持久化与管理员揭示接口，不随普通响应下发
+	Status       string                 `json:"status"`    // pending, queued, running, completed, failed, cancelled
+	Result       *ToolResult            `json:"result,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+	StartTime    time.Time              `json:"startTime"`
+	EndTime      *time.Time             `json:"endTime,omitempty"`
+	Duration     time.Duration          `json:"duration,omitempty"`
+	// TraceID 是本次调用所属的 OpenTelemetry trace ID（见 internal/tracing），未启用追踪时为空
+	TraceID string `json:"traceId,omitempty"`
 }
 
 // ToolStats 工具统计信息
@@ -215,6 +245,11 @@ type Prompt struct {
 	Name        string           `json:"name"`
 	Description string           `json:"description,omitempty"`
 	Arguments   []PromptArgument `json:"arguments,omitempty"`
+	// Template 与 Role 仅供来自 PromptsDir 的 YAML 定义使用，不通过 prompts/list 对外暴露（与 Tool.TimeoutSeconds
+	// 同样使用 json:"-"）：generatePromptMessages 渲染时用 Arguments 对 Template 做 {{var}} 替换生成消息内容。
+	// 硬编码的内置提示词（security_scan、penetration_test）不设置这两个字段，走各自的 switch-case 分支生成消息。
+	Template string `json:"-"`
+	Role     string `json:"-"`
 }
 
 // PromptArgument 提示词参数
@@ -259,6 +294,21 @@ type ReadResourceResponse struct {
 	Contents []ResourceContent `json:"contents"`
 }
 
+// SubscribeResourceRequest resources/subscribe 请求参数
+type SubscribeResourceRequest struct {
+	URI string `json:"uri"`
+}
+
+// UnsubscribeResourceRequest resources/unsubscribe 请求参数
+type UnsubscribeResourceRequest struct {
+	URI string `json:"uri"`
+}
+
+// ResourceUpdatedNotificationParams notifications/resources/updated 的通知参数
+type ResourceUpdatedNotificationParams struct {
+	URI string `json:"uri"`
+}
+
 // ResourceContent 资源内容
 type ResourceContent struct {
 	URI      string `json:"uri"`
@@ -0,0 +1,128 @@
+// Package memory 实现长期代理记忆：把运行过程中发现的事实（开放端口、凭据、技术栈等）
+// 按 target（IP、域名、主机名等）持久化，供后续针对同一 target 的对话在 system prompt 中注入一份精简摘要。
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Fact 一条按 target 归档的事实记录
+type Fact struct {
+	ID             string    `json:"id"`
+	Target         string    `json:"target"`
+	FactType       string    `json:"factType"` // open_port | credential | tech_stack | note ...
+	Key            string    `json:"key"`      // 同一 target+factType 下唯一，用于去重覆盖（如端口号、用户名）
+	Value          string    `json:"value"`    // 事实内容
+	ConversationID string    `json:"conversationId,omitempty"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Store 长期代理记忆存储，直接操作会话数据库中的 agent_memory_facts 表。
+type Store struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewStore 创建记忆存储；db 通常复用主会话数据库连接（database.DB.DB）。
+func NewStore(db *sql.DB, logger *zap.Logger) *Store {
+	return &Store{db: db, logger: logger}
+}
+
+// RememberFact 记录或更新一条事实（按 target+factType+key 去重覆盖，value 会被最新值替换）。
+func (s *Store) RememberFact(target, factType, key, value, conversationID string) error {
+	target = strings.TrimSpace(target)
+	factType = strings.TrimSpace(factType)
+	key = strings.TrimSpace(key)
+	if target == "" || factType == "" || key == "" {
+		return fmt.Errorf("target、fact_type、key 均不能为空")
+	}
+
+	now := time.Now()
+	_, err := s.db.Exec(`
+		INSERT INTO agent_memory_facts (id, target, fact_type, fact_key, fact_value, conversation_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(target, fact_type, fact_key) DO UPDATE SET
+			fact_value = excluded.fact_value,
+			conversation_id = excluded.conversation_id,
+			updated_at = excluded.updated_at
+	`, uuid.New().String(), target, factType, key, value, conversationID, now, now)
+	if err != nil {
+		return fmt.Errorf("写入记忆事实失败: %w", err)
+	}
+	return nil
+}
+
+// ListFacts 返回某个 target 下的全部事实，按 fact_type、fact_key 排序。
+func (s *Store) ListFacts(target string) ([]*Fact, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, nil
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, target, fact_type, fact_key, fact_value, COALESCE(conversation_id, ''), updated_at
+		FROM agent_memory_facts WHERE target = ?
+		ORDER BY fact_type ASC, fact_key ASC
+	`, target)
+	if err != nil {
+		return nil, fmt.Errorf("查询记忆事实失败: %w", err)
+	}
+	defer rows.Close()
+
+	var facts []*Fact
+	for rows.Next() {
+		f := &Fact{}
+		if err := rows.Scan(&f.ID, &f.Target, &f.FactType, &f.Key, &f.Value, &f.ConversationID, &f.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("读取记忆事实失败: %w", err)
+		}
+		facts = append(facts, f)
+	}
+	return facts, rows.Err()
+}
+
+// Summary 为 target 生成一份紧凑的摘要文本（按 factType 分组、每组最多 maxPerType 条），
+// 供注入 system prompt；没有记忆时返回空字符串。maxPerType<=0 时默认 10。
+func (s *Store) Summary(target string, maxPerType int) (string, error) {
+	facts, err := s.ListFacts(target)
+	if err != nil {
+		return "", err
+	}
+	if len(facts) == 0 {
+		return "", nil
+	}
+	if maxPerType <= 0 {
+		maxPerType = 10
+	}
+
+	byType := make(map[string][]*Fact)
+	for _, f := range facts {
+		byType[f.FactType] = append(byType[f.FactType], f)
+	}
+
+	types := make([]string, 0, len(byType))
+	for t := range byType {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("关于目标 %s 的既往记忆（来自历史任务，仅供参考，请以本次实际探测结果为准）：\n", target))
+	for _, t := range types {
+		items := byType[t]
+		if len(items) > maxPerType {
+			items = items[:maxPerType]
+		}
+		b.WriteString(fmt.Sprintf("- %s:\n", t))
+		for _, f := range items {
+			b.WriteString(fmt.Sprintf("  - %s: %s\n", f.Key, f.Value))
+		}
+	}
+	return b.String(), nil
+}
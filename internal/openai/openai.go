@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,18 +22,41 @@ type Client struct {
 	httpClient *http.Client
 	config     *config.OpenAIConfig
 	logger     *zap.Logger
+	traffic    *trafficLogger // 原始请求/响应报文调试落盘，默认关闭，见 config.OpenAIConfig.TrafficLog
 }
 
 // APIError 表示OpenAI接口返回的非200错误。
 type APIError struct {
 	StatusCode int
 	Body       string
+	RetryAfter time.Duration // 从响应 Retry-After 头解析出的建议等待时长；未提供或无法解析时为 0
 }
 
 func (e *APIError) Error() string {
 	return fmt.Sprintf("openai api error: status=%d body=%s", e.StatusCode, e.Body)
 }
 
+// parseRetryAfter 解析响应头 Retry-After（RFC 7231）：支持"等待秒数"与"HTTP-date"两种格式，
+// 无法解析、缺失或结果为负数时返回 0，交由调用方自行使用退避策略。
+func parseRetryAfter(header http.Header) time.Duration {
+	v := strings.TrimSpace(header.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
 // NewClient 创建一个新的OpenAI客户端。
 func NewClient(cfg *config.OpenAIConfig, httpClient *http.Client, logger *zap.Logger) *Client {
 	if httpClient == nil {
@@ -45,12 +69,14 @@ func NewClient(cfg *config.OpenAIConfig, httpClient *http.Client, logger *zap.Lo
 		httpClient: httpClient,
 		config:     cfg,
 		logger:     logger,
+		traffic:    newTrafficLogger(cfg, logger),
 	}
 }
 
 // UpdateConfig 动态更新OpenAI配置。
 func (c *Client) UpdateConfig(cfg *config.OpenAIConfig) {
 	c.config = cfg
+	c.traffic = newTrafficLogger(cfg, c.logger)
 }
 
 // ChatCompletion 调用 /chat/completions 接口。
@@ -78,6 +104,9 @@ func (c *Client) ChatCompletion(ctx context.Context, payload interface{}, out in
 		return fmt.Errorf("marshal openai payload: %w", err)
 	}
 
+	conversationID := ConversationIDFromContext(ctx)
+	logTraffic := c.traffic.shouldLog(conversationID)
+
 	c.logger.Debug("sending OpenAI chat completion request",
 		zap.Int("payloadSizeKB", len(body)/1024))
 
@@ -128,10 +157,19 @@ func (c *Client) ChatCompletion(ctx context.Context, payload interface{}, out in
 			zap.Int("status", resp.StatusCode),
 			zap.String("body", string(respBody)),
 		)
-		return &APIError{
+		apiErr := &APIError{
 			StatusCode: resp.StatusCode,
 			Body:       string(respBody),
+			RetryAfter: parseRetryAfter(resp.Header),
 		}
+		if logTraffic {
+			c.traffic.log("ChatCompletion", conversationID, body, resp.StatusCode, respBody, apiErr)
+		}
+		return apiErr
+	}
+
+	if logTraffic {
+		c.traffic.log("ChatCompletion", conversationID, body, resp.StatusCode, respBody, nil)
 	}
 
 	if out != nil {
@@ -149,7 +187,7 @@ func (c *Client) ChatCompletion(ctx context.Context, payload interface{}, out in
 
 // ChatCompletionStream 调用 /chat/completions 的流式模式（stream=true），并在每个 delta 到达时回调 onDelta。
 // 返回最终拼接的 content（只拼 content delta；工具调用 delta 未做处理）。
-func (c *Client) ChatCompletionStream(ctx context.Context, payload interface{}, onDelta func(delta string) error) (string, error) {
+func (c *Client) ChatCompletionStream(ctx context.Context, payload interface{}, onDelta func(delta string) error) (result string, err error) {
 	if c == nil {
 		return "", fmt.Errorf("openai client is not initialized")
 	}
@@ -173,6 +211,16 @@ func (c *Client) ChatCompletionStream(ctx context.Context, payload interface{},
 		return "", fmt.Errorf("marshal openai payload: %w", err)
 	}
 
+	conversationID := ConversationIDFromContext(ctx)
+	logTraffic := c.traffic.shouldLog(conversationID)
+	var rawResponse strings.Builder
+	statusCode := 0
+	if logTraffic {
+		defer func() {
+			c.traffic.log("ChatCompletionStream", conversationID, body, statusCode, []byte(rawResponse.String()), err)
+		}()
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/chat/completions", bytes.NewReader(body))
 	if err != nil {
 		return "", fmt.Errorf("build openai request: %w", err)
@@ -186,6 +234,7 @@ func (c *Client) ChatCompletionStream(ctx context.Context, payload interface{},
 		return "", fmt.Errorf("call openai api: %w", err)
 	}
 	defer resp.Body.Close()
+	statusCode = resp.StatusCode
 
 	// 非200：读完 body 返回
 	if resp.StatusCode != http.StatusOK {
@@ -193,9 +242,11 @@ func (c *Client) ChatCompletionStream(ctx context.Context, payload interface{},
 		if readErr != nil {
 			c.logger.Warn("failed to read OpenAI error response body", zap.Error(readErr))
 		}
+		rawResponse.Write(respBody)
 		return "", &APIError{
 			StatusCode: resp.StatusCode,
 			Body:       string(respBody),
+			RetryAfter: parseRetryAfter(resp.Header),
 		}
 	}
 
@@ -225,6 +276,9 @@ func (c *Client) ChatCompletionStream(ctx context.Context, payload interface{},
 	// data: [DONE]\n\n
 	for {
 		line, readErr := reader.ReadString('\n')
+		if line != "" {
+			rawResponse.WriteString(line)
+		}
 		if readErr != nil {
 			if readErr == io.EOF {
 				break
@@ -293,7 +347,7 @@ func (c *Client) ChatCompletionStreamWithToolCalls(
 	ctx context.Context,
 	payload interface{},
 	onContentDelta func(delta string) error,
-) (string, []StreamToolCall, string, error) {
+) (content string, toolCalls []StreamToolCall, finishReason string, err error) {
 	if c == nil {
 		return "", nil, "", fmt.Errorf("openai client is not initialized")
 	}
@@ -317,6 +371,16 @@ func (c *Client) ChatCompletionStreamWithToolCalls(
 		return "", nil, "", fmt.Errorf("marshal openai payload: %w", err)
 	}
 
+	conversationID := ConversationIDFromContext(ctx)
+	logTraffic := c.traffic.shouldLog(conversationID)
+	var rawResponse strings.Builder
+	statusCode := 0
+	if logTraffic {
+		defer func() {
+			c.traffic.log("ChatCompletionStreamWithToolCalls", conversationID, body, statusCode, []byte(rawResponse.String()), err)
+		}()
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/chat/completions", bytes.NewReader(body))
 	if err != nil {
 		return "", nil, "", fmt.Errorf("build openai request: %w", err)
@@ -330,15 +394,18 @@ func (c *Client) ChatCompletionStreamWithToolCalls(
 		return "", nil, "", fmt.Errorf("call openai api: %w", err)
 	}
 	defer resp.Body.Close()
+	statusCode = resp.StatusCode
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, readErr := io.ReadAll(resp.Body)
 		if readErr != nil {
 			c.logger.Warn("failed to read OpenAI error response body", zap.Error(readErr))
 		}
+		rawResponse.Write(respBody)
 		return "", nil, "", &APIError{
 			StatusCode: resp.StatusCode,
 			Body:       string(respBody),
+			RetryAfter: parseRetryAfter(resp.Header),
 		}
 	}
 
@@ -357,6 +424,8 @@ func (c *Client) ChatCompletionStreamWithToolCalls(
 		Content   string          `json:"content,omitempty"`
 		Text      string          `json:"text,omitempty"`
 		ToolCalls []toolCallDelta `json:"tool_calls,omitempty"`
+		// FunctionCall 兼容部分较旧后端仍以单个顶层 function_call 增量代替 tool_calls 数组下发的情况。
+		FunctionCall *toolCallFunctionDelta `json:"function_call,omitempty"`
 	}
 	type streamChoice2 struct {
 		Delta        streamDelta2 `json:"delta"`
@@ -380,10 +449,13 @@ func (c *Client) ChatCompletionStreamWithToolCalls(
 
 	reader := bufio.NewReader(resp.Body)
 	var full strings.Builder
-	finishReason := ""
+	finishReason = ""
 
 	for {
 		line, readErr := reader.ReadString('\n')
+		if line != "" {
+			rawResponse.WriteString(line)
+		}
 		if readErr != nil {
 			if readErr == io.EOF {
 				break
@@ -454,6 +526,19 @@ func (c *Client) ChatCompletionStreamWithToolCalls(
 					acc.args.WriteString(tc.Function.Arguments)
 				}
 			}
+		} else if delta.FunctionCall != nil {
+			// 兼容旧版 function_call 流式增量：单调用场景下固定累积到 index 0。
+			acc, ok := toolCallAccums[0]
+			if !ok {
+				acc = &toolCallAccum{id: "legacy_call_0", typ: "function"}
+				toolCallAccums[0] = acc
+			}
+			if delta.FunctionCall.Name != "" {
+				acc.name = delta.FunctionCall.Name
+			}
+			if delta.FunctionCall.Arguments != "" {
+				acc.args.WriteString(delta.FunctionCall.Arguments)
+			}
 		}
 	}
 
@@ -471,7 +556,7 @@ func (c *Client) ChatCompletionStreamWithToolCalls(
 		}
 	}
 
-	toolCalls := make([]StreamToolCall, 0, len(indices))
+	toolCalls = make([]StreamToolCall, 0, len(indices))
 	for _, idx := range indices {
 		acc := toolCallAccums[idx]
 		tc := StreamToolCall{
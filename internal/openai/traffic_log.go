@@ -0,0 +1,204 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"cyberstrike-ai/internal/config"
+
+	"go.uber.org/zap"
+)
+
+type conversationIDKey struct{}
+
+// WithConversationID 在 context 中注入当前对话 ID，供 trafficLogger 按会话维度采样/落盘。
+func WithConversationID(ctx context.Context, id string) context.Context {
+	id = strings.TrimSpace(id)
+	if id == "" || ctx == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, conversationIDKey{}, id)
+}
+
+// ConversationIDFromContext 返回 context 中注入的对话 ID，未注入时返回空字符串。
+func ConversationIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	v, _ := ctx.Value(conversationIDKey{}).(string)
+	return v
+}
+
+const (
+	defaultTrafficLogDir             = "tmp/llm_traffic"
+	defaultTrafficLogMaxBytesPerFile = 1 << 20   // 1MB
+	defaultTrafficLogMaxTotalBytes   = 200 << 20 // 200MB
+)
+
+// trafficLogger 将原始 LLM 请求/响应报文采样落盘，用于排查特定服务商返回的异常报文（如错误的 tool-call JSON）。
+// 默认关闭；开启后按 SampleRate 采样，或按 ConversationIDs 白名单对指定会话全量记录，写盘前对常见密钥字段做掩码。
+type trafficLogger struct {
+	dir             string
+	sampleRate      float64
+	conversationIDs map[string]bool
+	maxBytesPerFile int
+	maxTotalBytes   int64
+	logger          *zap.Logger
+
+	mu         sync.Mutex
+	totalBytes int64
+	seq        int64
+}
+
+// newTrafficLogger 未启用（config.TrafficLog.Enabled 为 false）时返回 nil，调用方需先判空再记录。
+func newTrafficLogger(cfg *config.OpenAIConfig, logger *zap.Logger) *trafficLogger {
+	if cfg == nil || !cfg.TrafficLog.Enabled {
+		return nil
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	dir := strings.TrimSpace(cfg.TrafficLog.Dir)
+	if dir == "" {
+		dir = defaultTrafficLogDir
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		logger.Warn("创建 LLM 流量日志目录失败，调试落盘功能将不可用", zap.Error(err), zap.String("dir", dir))
+		return nil
+	}
+
+	sampleRate := cfg.TrafficLog.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = 1
+	}
+	if sampleRate > 1 {
+		sampleRate = 1
+	}
+
+	maxBytesPerFile := cfg.TrafficLog.MaxBytesPerFile
+	if maxBytesPerFile <= 0 {
+		maxBytesPerFile = defaultTrafficLogMaxBytesPerFile
+	}
+	maxTotalBytes := cfg.TrafficLog.MaxTotalBytes
+	if maxTotalBytes <= 0 {
+		maxTotalBytes = defaultTrafficLogMaxTotalBytes
+	}
+
+	var conversationIDs map[string]bool
+	if len(cfg.TrafficLog.ConversationIDs) > 0 {
+		conversationIDs = make(map[string]bool, len(cfg.TrafficLog.ConversationIDs))
+		for _, id := range cfg.TrafficLog.ConversationIDs {
+			if id = strings.TrimSpace(id); id != "" {
+				conversationIDs[id] = true
+			}
+		}
+	}
+
+	logger.Info("已启用 LLM 流量调试落盘",
+		zap.String("dir", dir),
+		zap.Float64("sampleRate", sampleRate),
+		zap.Int("conversationIDWhitelist", len(conversationIDs)),
+	)
+
+	return &trafficLogger{
+		dir:             dir,
+		sampleRate:      sampleRate,
+		conversationIDs: conversationIDs,
+		maxBytesPerFile: maxBytesPerFile,
+		maxTotalBytes:   maxTotalBytes,
+		logger:          logger,
+	}
+}
+
+// shouldLog 判断是否记录本次调用：命中会话白名单则始终记录；未配置白名单时按采样率随机决定。
+func (t *trafficLogger) shouldLog(conversationID string) bool {
+	if t == nil {
+		return false
+	}
+	if t.conversationIDs != nil {
+		return t.conversationIDs[conversationID]
+	}
+	if t.sampleRate >= 1 {
+		return true
+	}
+	return rand.Float64() < t.sampleRate
+}
+
+// trafficLogEntry 单次请求/响应记录的落盘结构。
+type trafficLogEntry struct {
+	Timestamp      time.Time `json:"timestamp"`
+	ConversationID string    `json:"conversationId,omitempty"`
+	Method         string    `json:"method"`
+	StatusCode     int       `json:"statusCode,omitempty"`
+	Request        string    `json:"request"`
+	Response       string    `json:"response,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// sensitiveKeyPattern 匹配 JSON 报文中常见的密钥类字段（api_key、Authorization 头等），写盘前替换为掩码。
+var sensitiveKeyPattern = regexp.MustCompile(`(?i)"(api[_-]?key|authorization|token|secret|password)"\s*:\s*"[^"]*"`)
+
+func redactTraffic(payload string) string {
+	return sensitiveKeyPattern.ReplaceAllString(payload, `"$1":"******"`)
+}
+
+func truncateTraffic(s string, maxBytes int) string {
+	if maxBytes <= 0 || len(s) <= maxBytes {
+		return s
+	}
+	return s[:maxBytes] + fmt.Sprintf("...(截断，原始长度 %d 字节)", len(s))
+}
+
+// log 在调用方 goroutine 中同步将一次请求/响应落盘为独立 JSON 文件；目录累计大小超过上限时跳过写入并告警。
+func (t *trafficLogger) log(method, conversationID string, requestBody []byte, statusCode int, responseBody []byte, callErr error) {
+	if t == nil {
+		return
+	}
+
+	entry := trafficLogEntry{
+		Timestamp:      time.Now(),
+		ConversationID: conversationID,
+		Method:         method,
+		StatusCode:     statusCode,
+		Request:        truncateTraffic(redactTraffic(string(requestBody)), t.maxBytesPerFile),
+		Response:       truncateTraffic(redactTraffic(string(responseBody)), t.maxBytesPerFile),
+	}
+	if callErr != nil {
+		entry.Error = callErr.Error()
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		t.logger.Warn("序列化 LLM 流量日志失败", zap.Error(err))
+		return
+	}
+
+	t.mu.Lock()
+	if t.totalBytes+int64(len(data)) > t.maxTotalBytes {
+		t.mu.Unlock()
+		t.logger.Warn("LLM 流量日志目录已达大小上限，跳过本次记录",
+			zap.String("dir", t.dir),
+			zap.Int64("maxTotalBytes", t.maxTotalBytes),
+		)
+		return
+	}
+	t.seq++
+	seq := t.seq
+	t.totalBytes += int64(len(data))
+	t.mu.Unlock()
+
+	fileName := fmt.Sprintf("%s_%s_%06d.json", entry.Timestamp.Format("20060102T150405.000"), method, seq)
+	path := filepath.Join(t.dir, fileName)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.logger.Warn("写入 LLM 流量日志文件失败", zap.Error(err), zap.String("path", path))
+	}
+}
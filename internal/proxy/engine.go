@@ -0,0 +1,64 @@
+// Package proxy 实现按对话配置的工具流量代理路由：把 Burp Suite 或 SOCKS 跳板的地址注入到工具
+// 执行环境（HTTP_PROXY/HTTPS_PROXY 环境变量）与支持代理参数的工具命令行（如 sqlmap --proxy、
+// nuclei -proxy），使该对话下发起的所有扫描流量都能被截获检视或经由跳板转发，而不必逐条改工具配置。
+package proxy
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// Config 是一次对话的代理路由配置。
+type Config struct {
+	URL string `json:"url"` // 代理地址，如 http://127.0.0.1:8080（Burp）或 socks5://127.0.0.1:1080
+}
+
+// IsEmpty 判断该配置是否等价于“未配置代理”
+func (c Config) IsEmpty() bool {
+	return c.URL == ""
+}
+
+// Engine 管理各对话的代理路由配置
+type Engine struct {
+	mu      sync.RWMutex
+	proxies map[string]Config // key: conversationID
+}
+
+// NewEngine 创建代理路由配置引擎
+func NewEngine() *Engine {
+	return &Engine{proxies: make(map[string]Config)}
+}
+
+// SetProxy 设置指定对话的代理配置（覆盖式，传入空 URL 等价于取消）
+func (e *Engine) SetProxy(conversationID string, cfg Config) error {
+	if conversationID == "" {
+		return fmt.Errorf("conversationID 不能为空")
+	}
+	if cfg.URL != "" {
+		parsed, err := url.Parse(cfg.URL)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("无效的代理地址 %q，需形如 http://host:port 或 socks5://host:port", cfg.URL)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.proxies[conversationID] = cfg
+	return nil
+}
+
+// GetProxy 返回指定对话的代理配置，未配置时返回 (Config{}, false)
+func (e *Engine) GetProxy(conversationID string) (Config, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	cfg, ok := e.proxies[conversationID]
+	return cfg, ok
+}
+
+// ClearProxy 移除指定对话的代理配置
+func (e *Engine) ClearProxy(conversationID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.proxies, conversationID)
+}
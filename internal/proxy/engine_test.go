@@ -0,0 +1,51 @@
+package proxy
+
+import "testing"
+
+func TestEngine_NoProxyConfigured_ReturnsNotOK(t *testing.T) {
+	e := NewEngine()
+	if _, ok := e.GetProxy("conv1"); ok {
+		t.Error("未配置代理时 GetProxy 应返回 ok=false")
+	}
+}
+
+func TestEngine_SetAndGetProxy(t *testing.T) {
+	e := NewEngine()
+	if err := e.SetProxy("conv1", Config{URL: "http://127.0.0.1:8080"}); err != nil {
+		t.Fatalf("设置代理失败: %v", err)
+	}
+
+	cfg, ok := e.GetProxy("conv1")
+	if !ok {
+		t.Fatal("设置后 GetProxy 应返回 ok=true")
+	}
+	if cfg.URL != "http://127.0.0.1:8080" {
+		t.Errorf("代理地址不符，实际: %s", cfg.URL)
+	}
+}
+
+func TestEngine_SetProxy_RejectsInvalidURL(t *testing.T) {
+	e := NewEngine()
+	if err := e.SetProxy("conv1", Config{URL: "not-a-url"}); err == nil {
+		t.Error("无效的代理地址应被拒绝")
+	}
+	if err := e.SetProxy("conv1", Config{URL: "://bad"}); err == nil {
+		t.Error("无效的代理地址应被拒绝")
+	}
+}
+
+func TestEngine_SetProxy_EmptyConversationID(t *testing.T) {
+	e := NewEngine()
+	if err := e.SetProxy("", Config{URL: "http://127.0.0.1:8080"}); err == nil {
+		t.Error("空 conversationID 应报错")
+	}
+}
+
+func TestEngine_ClearProxy(t *testing.T) {
+	e := NewEngine()
+	_ = e.SetProxy("conv1", Config{URL: "socks5://127.0.0.1:1080"})
+	e.ClearProxy("conv1")
+	if _, ok := e.GetProxy("conv1"); ok {
+		t.Error("清除后 GetProxy 应返回 ok=false")
+	}
+}
@@ -0,0 +1,80 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// RenderHTML 把 Data 渲染为一份自包含的 HTML 渗透测试报告（内联样式，无外部资源依赖）。
+func RenderHTML(d *Data) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"zh-CN\"><head><meta charset=\"utf-8\">\n")
+	b.WriteString(fmt.Sprintf("<title>渗透测试报告 - %s</title>\n", html.EscapeString(firstNonEmptyStr(d.Title, d.ConversationID))))
+	b.WriteString(`<style>
+body{font-family:-apple-system,"Microsoft YaHei",sans-serif;max-width:960px;margin:2em auto;color:#222;line-height:1.6}
+h1,h2,h3{border-bottom:1px solid #ddd;padding-bottom:.3em}
+table{border-collapse:collapse;width:100%}
+th,td{border:1px solid #ccc;padding:6px 10px;text-align:left}
+pre{background:#f5f5f5;padding:10px;overflow-x:auto;white-space:pre-wrap}
+.severity-critical{color:#b91c1c;font-weight:bold}
+.severity-high{color:#c2410c;font-weight:bold}
+.severity-medium{color:#a16207}
+.severity-low{color:#4d7c0f}
+</style></head><body>
+`)
+	b.WriteString(fmt.Sprintf("<h1>渗透测试报告 - %s</h1>\n", html.EscapeString(firstNonEmptyStr(d.Title, d.ConversationID))))
+	b.WriteString(fmt.Sprintf("<p>会话ID: %s<br>生成时间: %s</p>\n", html.EscapeString(d.ConversationID), d.GeneratedAt.Format("2006-01-02 15:04:05")))
+
+	b.WriteString("<h2>执行摘要</h2>\n")
+	b.WriteString(fmt.Sprintf("<p>%s</p>\n", html.EscapeString(d.ExecutiveSummary)))
+
+	b.WriteString("<h2>测试过程概览</h2>\n")
+	b.WriteString(fmt.Sprintf("<p>消息总数: %d ｜ 调用工具种类数: %d</p>\n", len(d.Messages), len(d.ToolExecutions)))
+	if len(d.ToolExecutions) > 0 {
+		b.WriteString("<table><tr><th>工具</th><th>调用次数</th></tr>\n")
+		for _, t := range d.ToolExecutions {
+			b.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%d</td></tr>\n", html.EscapeString(t.Name), t.Count))
+		}
+		b.WriteString("</table>\n")
+	}
+
+	b.WriteString("<h2>攻击链</h2>\n")
+	if d.AttackChain == nil || len(d.AttackChain.Nodes) == 0 {
+		b.WriteString("<p>未生成攻击链数据。</p>\n")
+	} else {
+		b.WriteString(fmt.Sprintf("<p>共 %d 个节点，%d 条边。</p>\n<ul>\n", len(d.AttackChain.Nodes), len(d.AttackChain.Edges)))
+		for _, n := range d.AttackChain.Nodes {
+			b.WriteString(fmt.Sprintf("<li><strong>%s</strong> (%s)</li>\n", html.EscapeString(n.Label), html.EscapeString(n.Type)))
+		}
+		b.WriteString("</ul>\n")
+	}
+
+	b.WriteString("<h2>漏洞详情</h2>\n")
+	if len(d.Vulnerabilities) == 0 {
+		b.WriteString("<p>未发现漏洞记录。</p>\n")
+	} else {
+		for i, v := range d.Vulnerabilities {
+			b.WriteString(fmt.Sprintf("<h3>%d. <span class=\"severity-%s\">[%s]</span> %s</h3>\n",
+				i+1, html.EscapeString(strings.ToLower(v.Severity)), html.EscapeString(v.Severity), html.EscapeString(v.Title)))
+			b.WriteString(fmt.Sprintf("<p>目标: %s ｜ 类型: %s ｜ 状态: %s</p>\n",
+				html.EscapeString(v.Target), html.EscapeString(v.Type), html.EscapeString(v.Status)))
+			if v.CVSSVector != "" {
+				b.WriteString(fmt.Sprintf("<p>CVSS向量: <code>%s</code>（评分: %.1f）</p>\n", html.EscapeString(v.CVSSVector), v.CVSSScore))
+			}
+			b.WriteString(fmt.Sprintf("<p><strong>描述</strong>: %s</p>\n", html.EscapeString(v.Description)))
+			if v.Proof != "" {
+				b.WriteString(fmt.Sprintf("<p><strong>证明</strong>:</p>\n<pre>%s</pre>\n", html.EscapeString(v.Proof)))
+			}
+			if v.Impact != "" {
+				b.WriteString(fmt.Sprintf("<p><strong>影响</strong>: %s</p>\n", html.EscapeString(v.Impact)))
+			}
+			if v.Recommendation != "" {
+				b.WriteString(fmt.Sprintf("<p><strong>修复建议</strong>: %s</p>\n", html.EscapeString(v.Recommendation)))
+			}
+		}
+	}
+
+	b.WriteString("</body></html>\n")
+	return b.String()
+}
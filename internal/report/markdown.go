@@ -0,0 +1,76 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RenderMarkdown 把 Data 渲染为完整的 Markdown 渗透测试报告。
+func RenderMarkdown(d *Data) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("# 渗透测试报告 - %s\n\n", firstNonEmptyStr(d.Title, d.ConversationID)))
+	b.WriteString(fmt.Sprintf("- 会话ID: %s\n", d.ConversationID))
+	b.WriteString(fmt.Sprintf("- 生成时间: %s\n\n", d.GeneratedAt.Format("2006-01-02 15:04:05")))
+
+	b.WriteString("## 执行摘要\n\n")
+	b.WriteString(d.ExecutiveSummary)
+	b.WriteString("\n\n")
+
+	b.WriteString("## 测试过程概览\n\n")
+	b.WriteString(fmt.Sprintf("- 消息总数: %d\n", len(d.Messages)))
+	b.WriteString(fmt.Sprintf("- 调用工具种类数: %d\n\n", len(d.ToolExecutions)))
+	if len(d.ToolExecutions) > 0 {
+		b.WriteString("| 工具 | 调用次数 |\n|---|---|\n")
+		for _, t := range d.ToolExecutions {
+			b.WriteString(fmt.Sprintf("| %s | %d |\n", t.Name, t.Count))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## 攻击链\n\n")
+	if d.AttackChain == nil || len(d.AttackChain.Nodes) == 0 {
+		b.WriteString("未生成攻击链数据。\n\n")
+	} else {
+		b.WriteString(fmt.Sprintf("共 %d 个节点，%d 条边。\n\n", len(d.AttackChain.Nodes), len(d.AttackChain.Edges)))
+		for _, n := range d.AttackChain.Nodes {
+			b.WriteString(fmt.Sprintf("- **%s** (%s)\n", n.Label, n.Type))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## 漏洞详情\n\n")
+	if len(d.Vulnerabilities) == 0 {
+		b.WriteString("未发现漏洞记录。\n\n")
+	} else {
+		for i, v := range d.Vulnerabilities {
+			b.WriteString(fmt.Sprintf("### %d. [%s] %s\n\n", i+1, v.Severity, v.Title))
+			b.WriteString(fmt.Sprintf("- 目标: %s\n", v.Target))
+			b.WriteString(fmt.Sprintf("- 类型: %s\n", v.Type))
+			b.WriteString(fmt.Sprintf("- 状态: %s\n", v.Status))
+			if v.CVSSVector != "" {
+				b.WriteString(fmt.Sprintf("- CVSS向量: `%s`（评分: %.1f）\n", v.CVSSVector, v.CVSSScore))
+			}
+			b.WriteString(fmt.Sprintf("\n**描述**: %s\n\n", v.Description))
+			if v.Proof != "" {
+				b.WriteString(fmt.Sprintf("**证明**:\n```\n%s\n```\n\n", v.Proof))
+			}
+			if v.Impact != "" {
+				b.WriteString(fmt.Sprintf("**影响**: %s\n\n", v.Impact))
+			}
+			if v.Recommendation != "" {
+				b.WriteString(fmt.Sprintf("**修复建议**: %s\n\n", v.Recommendation))
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func firstNonEmptyStr(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// RenderPDF 把 Data 渲染为一份最小可用的单栏文本 PDF（Courier 等宽字体，逐页排版）。
+// 不依赖任何第三方 PDF 库：直接按 PDF 1.4 规范手工拼装对象与交叉引用表。
+// 限制：标准 14 内置字体（Courier）不含中文字形，若报告内容含中文，PDF 阅读器会显示为缺字方框；
+// 如需正确显示中文，需改用 md/html 格式导出，或后续接入内嵌 CJK 字体的第三方 PDF 库。
+func RenderPDF(d *Data) ([]byte, error) {
+	const (
+		pageWidth    = 612.0 // US Letter
+		pageHeight   = 792.0
+		marginLeft   = 50.0
+		marginTop    = 742.0
+		fontSize     = 10.0
+		lineHeight   = 14.0
+		charsPerLine = 92
+	)
+
+	lines := wrapLines(plainTextLines(RenderMarkdown(d)), charsPerLine)
+	usableHeight := marginTop - 40.0
+	linesPerPage := int(usableHeight / lineHeight)
+	if linesPerPage < 1 {
+		linesPerPage = 1
+	}
+
+	var pages [][]string
+	for i := 0; i < len(lines); i += linesPerPage {
+		end := i + linesPerPage
+		if end > len(lines) {
+			end = len(lines)
+		}
+		pages = append(pages, lines[i:end])
+	}
+	if len(pages) == 0 {
+		pages = [][]string{{}}
+	}
+
+	buf := &bytes.Buffer{}
+	offsets := []int{}
+	writeObj := func(body string) int {
+		offsets = append(offsets, buf.Len())
+		buf.WriteString(body)
+		return len(offsets) // 1-based object number
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	// 对象编号预留: 1=Catalog, 2=Pages, 3=Font；页面与内容流从 4 开始交替排列。
+	numPages := len(pages)
+	pageObjNums := make([]int, numPages)
+	contentObjNums := make([]int, numPages)
+	nextObjNum := 4
+	for i := range pages {
+		pageObjNums[i] = nextObjNum
+		nextObjNum++
+		contentObjNums[i] = nextObjNum
+		nextObjNum++
+	}
+
+	writeObj("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	kids := make([]string, numPages)
+	for i, n := range pageObjNums {
+		kids[i] = fmt.Sprintf("%d 0 R", n)
+	}
+	writeObj(fmt.Sprintf("2 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), numPages))
+
+	writeObj("3 0 obj\n<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>\nendobj\n")
+
+	for i, pageLines := range pages {
+		content := buildPageContentStream(pageLines, marginLeft, marginTop, fontSize, lineHeight)
+		writeObj(fmt.Sprintf(
+			"%d 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+			pageObjNums[i], pageWidth, pageHeight, contentObjNums[i],
+		))
+		writeObj(fmt.Sprintf(
+			"%d 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n",
+			contentObjNums[i], len(content), content,
+		))
+	}
+
+	xrefStart := buf.Len()
+	totalObjs := len(offsets)
+	buf.WriteString(fmt.Sprintf("xref\n0 %d\n", totalObjs+1))
+	buf.WriteString("0000000000 65535 f \n")
+	for _, off := range offsets {
+		buf.WriteString(fmt.Sprintf("%010d 00000 n \n", off))
+	}
+	buf.WriteString(fmt.Sprintf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF", totalObjs+1, xrefStart))
+
+	return buf.Bytes(), nil
+}
+
+func buildPageContentStream(lines []string, marginLeft, marginTop, fontSize, lineHeight float64) string {
+	var b strings.Builder
+	b.WriteString("BT\n")
+	b.WriteString(fmt.Sprintf("/F1 %.0f Tf\n", fontSize))
+	b.WriteString(fmt.Sprintf("%.2f TL\n", lineHeight))
+	b.WriteString(fmt.Sprintf("%.2f %.2f Td\n", marginLeft, marginTop))
+	for _, line := range lines {
+		b.WriteString(fmt.Sprintf("(%s) Tj T*\n", escapePDFString(line)))
+	}
+	b.WriteString("ET\n")
+	return b.String()
+}
+
+func escapePDFString(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`)
+	return replacer.Replace(s)
+}
+
+// plainTextLines 去掉 Markdown 中的标题/加粗/代码围栏标记，按行返回适合等宽字体排版的纯文本。
+func plainTextLines(markdown string) []string {
+	raw := strings.Split(markdown, "\n")
+	lines := make([]string, 0, len(raw))
+	for _, line := range raw {
+		line = strings.TrimRight(line, "\r")
+		line = strings.TrimPrefix(line, "### ")
+		line = strings.TrimPrefix(line, "## ")
+		line = strings.TrimPrefix(line, "# ")
+		line = strings.TrimPrefix(line, "```")
+		line = strings.ReplaceAll(line, "**", "")
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// wrapLines 按最大字符数拆分过长的行，避免文本溢出页面右边距。
+func wrapLines(lines []string, maxChars int) []string {
+	wrapped := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if len(line) <= maxChars {
+			wrapped = append(wrapped, line)
+			continue
+		}
+		for len(line) > maxChars {
+			wrapped = append(wrapped, line[:maxChars])
+			line = line[maxChars:]
+		}
+		wrapped = append(wrapped, line)
+	}
+	return wrapped
+}
@@ -0,0 +1,200 @@
+// Package report 汇总一次渗透测试会话的消息、执行过的工具、攻击链与漏洞记录，
+// 生成模板化的渗透测试报告（Markdown/HTML/PDF），并调用 LLM 撰写执行摘要。
+package report
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cyberstrike-ai/internal/attackchain"
+	"cyberstrike-ai/internal/config"
+	"cyberstrike-ai/internal/database"
+	"cyberstrike-ai/internal/openai"
+
+	"go.uber.org/zap"
+)
+
+// ToolExecutionSummary 汇总某个工具在会话中被调用的次数，用于报告的"执行过的工具"章节。
+type ToolExecutionSummary struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Data 是渲染报告所需的全部数据，由 Compile 汇总产出。
+type Data struct {
+	ConversationID   string
+	Title            string
+	GeneratedAt      time.Time
+	Messages         []database.Message
+	ToolExecutions   []ToolExecutionSummary
+	AttackChain      *attackchain.Chain
+	Vulnerabilities  []*database.Vulnerability
+	ExecutiveSummary string
+}
+
+// Generator 编译报告数据；openAIClient 为 nil 时执行摘要退化为按漏洞统计拼接的简要文字，不调用 LLM。
+type Generator struct {
+	db           *database.DB
+	openAIClient *openai.Client
+	openAIConfig *config.OpenAIConfig
+	logger       *zap.Logger
+}
+
+// NewGenerator 创建报告生成器
+func NewGenerator(db *database.DB, openAIConfig *config.OpenAIConfig, logger *zap.Logger) *Generator {
+	var client *openai.Client
+	if openAIConfig != nil && strings.TrimSpace(openAIConfig.APIKey) != "" {
+		client = openai.NewClient(openAIConfig, nil, logger)
+	}
+	return &Generator{
+		db:           db,
+		openAIClient: client,
+		openAIConfig: openAIConfig,
+		logger:       logger,
+	}
+}
+
+// Compile 汇总会话消息、工具执行统计、攻击链与漏洞记录，并生成执行摘要。
+func (g *Generator) Compile(ctx context.Context, conversationID string) (*Data, error) {
+	conv, err := g.db.GetConversation(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("获取会话失败: %w", err)
+	}
+
+	messages, err := g.db.GetMessages(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("获取会话消息失败: %w", err)
+	}
+
+	total, err := g.db.CountVulnerabilities("", conversationID, "", "", "", "", "")
+	if err != nil {
+		return nil, fmt.Errorf("统计会话漏洞失败: %w", err)
+	}
+	var vulns []*database.Vulnerability
+	if total > 0 {
+		vulns, err = g.db.ListVulnerabilities(total, 0, "", conversationID, "", "", "", "", "")
+		if err != nil {
+			return nil, fmt.Errorf("获取会话漏洞失败: %w", err)
+		}
+	}
+
+	builder := attackchain.NewBuilder(g.db, g.openAIConfig, g.logger)
+	chain, err := builder.LoadChainFromDatabase(conversationID)
+	if err != nil || chain == nil || len(chain.Nodes) == 0 {
+		if built, buildErr := builder.BuildChainFromConversation(ctx, conversationID); buildErr == nil {
+			chain = built
+		} else {
+			g.logger.Warn("生成报告时构建攻击链失败，报告将不含攻击链章节", zap.String("conversationId", conversationID), zap.Error(buildErr))
+			chain = &attackchain.Chain{}
+		}
+	}
+
+	data := &Data{
+		ConversationID:  conversationID,
+		Title:           conv.Title,
+		GeneratedAt:     time.Now(),
+		Messages:        messages,
+		ToolExecutions:  summarizeToolExecutions(messages),
+		AttackChain:     chain,
+		Vulnerabilities: vulns,
+	}
+	data.ExecutiveSummary = g.generateExecutiveSummary(ctx, data)
+	return data, nil
+}
+
+// summarizeToolExecutions 统计 assistant 消息 processDetails 中出现的 tool_call 名称及调用次数。
+func summarizeToolExecutions(messages []database.Message) []ToolExecutionSummary {
+	counts := map[string]int{}
+	order := []string{}
+	for _, m := range messages {
+		for _, detail := range m.ProcessDetails {
+			detailType, _ := detail["type"].(string)
+			if detailType != "tool_call" {
+				continue
+			}
+			name, _ := detail["name"].(string)
+			if name == "" {
+				continue
+			}
+			if _, seen := counts[name]; !seen {
+				order = append(order, name)
+			}
+			counts[name]++
+		}
+	}
+	summaries := make([]ToolExecutionSummary, 0, len(order))
+	for _, name := range order {
+		summaries = append(summaries, ToolExecutionSummary{Name: name, Count: counts[name]})
+	}
+	return summaries
+}
+
+// generateExecutiveSummary 调用 LLM 撰写执行摘要；未配置 OpenAI 时退化为按漏洞严重程度统计拼接的简要文字。
+func (g *Generator) generateExecutiveSummary(ctx context.Context, data *Data) string {
+	if g.openAIClient == nil {
+		return fallbackExecutiveSummary(data)
+	}
+
+	prompt := buildExecutiveSummaryPrompt(data)
+	requestBody := map[string]interface{}{
+		"model": g.openAIConfig.Model,
+		"messages": []map[string]interface{}{
+			{
+				"role":    "system",
+				"content": "你是一名资深渗透测试报告撰写人，请用简洁、专业的中文撰写渗透测试报告的执行摘要（3-6句话），概述测试范围、关键发现与整体风险，不要使用Markdown标题。",
+			},
+			{
+				"role":    "user",
+				"content": prompt,
+			},
+		},
+		"temperature":           0.3,
+		"max_completion_tokens": 800,
+	}
+
+	var apiResponse struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	summaryCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	if err := g.openAIClient.ChatCompletion(summaryCtx, requestBody, &apiResponse); err != nil {
+		g.logger.Warn("生成执行摘要失败，回退为统计摘要", zap.Error(err))
+		return fallbackExecutiveSummary(data)
+	}
+	if len(apiResponse.Choices) == 0 || strings.TrimSpace(apiResponse.Choices[0].Message.Content) == "" {
+		return fallbackExecutiveSummary(data)
+	}
+	return strings.TrimSpace(apiResponse.Choices[0].Message.Content)
+}
+
+func buildExecutiveSummaryPrompt(data *Data) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("会话标题: %s\n", data.Title))
+	b.WriteString(fmt.Sprintf("消息总数: %d\n", len(data.Messages)))
+	b.WriteString(fmt.Sprintf("调用的工具数: %d\n", len(data.ToolExecutions)))
+	b.WriteString(fmt.Sprintf("发现漏洞总数: %d\n", len(data.Vulnerabilities)))
+	for _, v := range data.Vulnerabilities {
+		b.WriteString(fmt.Sprintf("- [%s] %s（目标: %s）\n", v.Severity, v.Title, v.Target))
+	}
+	return b.String()
+}
+
+func fallbackExecutiveSummary(data *Data) string {
+	severityCounts := map[string]int{}
+	for _, v := range data.Vulnerabilities {
+		severityCounts[v.Severity]++
+	}
+	if len(data.Vulnerabilities) == 0 {
+		return "本次测试未发现已记录的漏洞。"
+	}
+	return fmt.Sprintf(
+		"本次测试共发现 %d 个漏洞，其中严重 %d 个、高危 %d 个、中危 %d 个、低危 %d 个。详见下文各章节。",
+		len(data.Vulnerabilities), severityCounts["critical"], severityCounts["high"], severityCounts["medium"], severityCounts["low"],
+	)
+}
@@ -0,0 +1,22 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// RenderWithTemplate 用用户自定义的报告模板（Go模板语法，纯 Markdown 骨架不含 {{ }} 时按原文输出）渲染 Data。
+// 模板中可直接引用 Data 的导出字段，如 {{ .Title }}、{{ range .Vulnerabilities }}...{{ end }}。
+func RenderWithTemplate(templateContent string, d *Data) (string, error) {
+	tmpl, err := template.New("report").Parse(templateContent)
+	if err != nil {
+		return "", fmt.Errorf("解析报告模板失败: %w", err)
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, d); err != nil {
+		return "", fmt.Errorf("渲染报告模板失败: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,209 @@
+// Package scope 实现按对话/项目配置的目标范围校验：允许的 CIDR/域名/URL 模式，以及显式拒绝列表。
+// Executor 在真正执行工具前，对命令参数中带有目标语义的字段（如 target、url、domain）做范围校验，
+// 拒绝越界目标并返回可直接转述给用户的错误信息，而不是把越界扫描交给工具本身去失败。
+package scope
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Config 是一次对话/项目的目标范围配置。三个 Allowed 列表任一非空时即视为“已限定范围”，
+// 目标必须至少匹配其中一项才允许放行；三者均为空时不做范围限制（仅 DenyList 生效）。
+// DenyList 优先于 Allowed 列表：无论是否在允许范围内，命中 DenyList 一律拒绝。
+type Config struct {
+	AllowedCIDRs   []string `json:"allowed_cidrs,omitempty"`
+	AllowedDomains []string `json:"allowed_domains,omitempty"` // 支持 "*.example.com" 前缀通配
+	URLPatterns    []string `json:"url_patterns,omitempty"`    // 支持 "*" 通配（可跨 "/"，不含 "*" 时按子串匹配，见 matchGlob）
+	DenyList       []string `json:"deny_list,omitempty"`       // CIDR、域名（含通配）或 URL 模式均可
+}
+
+// IsEmpty 判断该配置是否等价于“未配置范围限制”
+func (c Config) IsEmpty() bool {
+	return len(c.AllowedCIDRs) == 0 && len(c.AllowedDomains) == 0 && len(c.URLPatterns) == 0 && len(c.DenyList) == 0
+}
+
+// Engine 管理各对话/项目的范围配置，并对目标做校验
+type Engine struct {
+	mu     sync.RWMutex
+	scopes map[string]Config // key: conversationID（或项目ID）
+}
+
+// NewEngine 创建范围校验引擎
+func NewEngine() *Engine {
+	return &Engine{scopes: make(map[string]Config)}
+}
+
+// SetScope 设置指定对话/项目的范围配置（覆盖式，传入空 Config 等价于取消限制）
+func (e *Engine) SetScope(conversationID string, cfg Config) error {
+	if conversationID == "" {
+		return fmt.Errorf("conversationID 不能为空")
+	}
+	for _, cidr := range cfg.AllowedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("无效的CIDR %q: %w", cidr, err)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scopes[conversationID] = cfg
+	return nil
+}
+
+// GetScope 返回指定对话/项目的范围配置，未配置时返回 (Config{}, false)
+func (e *Engine) GetScope(conversationID string) (Config, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	cfg, ok := e.scopes[conversationID]
+	return cfg, ok
+}
+
+// ClearScope 移除指定对话/项目的范围配置
+func (e *Engine) ClearScope(conversationID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.scopes, conversationID)
+}
+
+// CheckTarget 校验 target 是否在 conversationID 对应的范围内；未为该对话配置范围（或配置为空）时
+// 不做限制、总是放行。target 可以是 IP、CIDR、域名或 URL。
+func (e *Engine) CheckTarget(conversationID string, target string) error {
+	e.mu.RLock()
+	cfg, ok := e.scopes[conversationID]
+	e.mu.RUnlock()
+	if !ok || cfg.IsEmpty() {
+		return nil
+	}
+
+	host := extractHost(target)
+
+	for _, deny := range cfg.DenyList {
+		if matchRule(deny, target, host) {
+			return fmt.Errorf("目标 %q 命中拒绝列表规则 %q，禁止执行", target, deny)
+		}
+	}
+
+	hasAllowRules := len(cfg.AllowedCIDRs) > 0 || len(cfg.AllowedDomains) > 0 || len(cfg.URLPatterns) > 0
+	if !hasAllowRules {
+		return nil
+	}
+
+	for _, cidr := range cfg.AllowedCIDRs {
+		if matchCIDR(cidr, host) {
+			return nil
+		}
+	}
+	for _, domain := range cfg.AllowedDomains {
+		if matchDomain(domain, host) {
+			return nil
+		}
+	}
+	for _, pattern := range cfg.URLPatterns {
+		if matchGlob(pattern, target) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("目标 %q 不在已配置的范围内（允许的CIDR/域名/URL模式均未匹配），拒绝执行", target)
+}
+
+// matchRule 判断 rule 是否命中 target（原始值）或 host（提取出的主机部分），
+// 依次按 CIDR、域名通配、URL通配三种语义尝试匹配，命中任一即算匹配。
+func matchRule(rule, target, host string) bool {
+	if matchCIDR(rule, host) {
+		return true
+	}
+	if matchDomain(rule, host) {
+		return true
+	}
+	return matchGlob(rule, target)
+}
+
+func matchCIDR(cidr, host string) bool {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	return ipNet.Contains(ip)
+}
+
+// matchDomain 支持 "*.example.com" 通配前缀，匹配 example.com 自身及其所有子域名；
+// 不带通配前缀时要求完全相等。
+func matchDomain(pattern, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	pattern = strings.ToLower(pattern)
+	if strings.HasPrefix(pattern, "*.") {
+		base := pattern[2:]
+		return host == base || strings.HasSuffix(host, "."+base)
+	}
+	return host == pattern
+}
+
+// matchGlob 实现仅支持 "*"（匹配任意长度任意字符，含 "/"）的简单通配匹配，不含 "*" 时退化为子串包含匹配。
+// 不使用 path/filepath.Match，因为它把 "*" 视为不跨路径分隔符，不适合 URL 模式。
+func matchGlob(pattern, s string) bool {
+	if !strings.Contains(pattern, "*") {
+		return strings.Contains(s, pattern)
+	}
+
+	segments := strings.Split(pattern, "*")
+	if !strings.HasPrefix(pattern, "*") {
+		if !strings.HasPrefix(s, segments[0]) {
+			return false
+		}
+		s = s[len(segments[0]):]
+		segments = segments[1:]
+	}
+
+	last := len(segments) - 1
+	suffix := ""
+	if !strings.HasSuffix(pattern, "*") {
+		suffix = segments[last]
+		segments = segments[:last]
+	}
+
+	for _, seg := range segments {
+		if seg == "" {
+			continue
+		}
+		idx := strings.Index(s, seg)
+		if idx == -1 {
+			return false
+		}
+		s = s[idx+len(seg):]
+	}
+
+	return suffix == "" || strings.HasSuffix(s, suffix)
+}
+
+// extractHost 从目标字符串中提取主机部分：URL 提取 host，CIDR 提取网络地址，其余原样返回。
+func extractHost(target string) string {
+	t := strings.TrimSpace(target)
+	if idx := strings.Index(t, "://"); idx != -1 {
+		t = t[idx+3:]
+	}
+	// 去掉路径、查询串
+	if idx := strings.IndexAny(t, "/?#"); idx != -1 {
+		t = t[:idx]
+	}
+	// 去掉用户信息 user:pass@
+	if idx := strings.LastIndex(t, "@"); idx != -1 {
+		t = t[idx+1:]
+	}
+	// 去掉端口
+	if host, _, err := net.SplitHostPort(t); err == nil {
+		t = host
+	}
+	// CIDR 只取网络地址部分用于域名/IP 匹配，CIDR 本身的匹配走 matchCIDR
+	if idx := strings.Index(t, "/"); idx != -1 {
+		t = t[:idx]
+	}
+	return strings.TrimSpace(t)
+}
@@ -0,0 +1,85 @@
+package scope
+
+import "testing"
+
+func TestEngine_NoScopeConfigured_Allows(t *testing.T) {
+	e := NewEngine()
+	if err := e.CheckTarget("conv1", "10.0.0.1"); err != nil {
+		t.Errorf("未配置范围时应放行，实际报错: %v", err)
+	}
+}
+
+func TestEngine_AllowedCIDR(t *testing.T) {
+	e := NewEngine()
+	if err := e.SetScope("conv1", Config{AllowedCIDRs: []string{"10.0.0.0/24"}}); err != nil {
+		t.Fatalf("设置范围失败: %v", err)
+	}
+
+	if err := e.CheckTarget("conv1", "10.0.0.5"); err != nil {
+		t.Errorf("10.0.0.5 应在范围内，实际报错: %v", err)
+	}
+	if err := e.CheckTarget("conv1", "192.168.1.1"); err == nil {
+		t.Error("192.168.1.1 不在范围内，应拒绝")
+	}
+}
+
+func TestEngine_AllowedDomain_Wildcard(t *testing.T) {
+	e := NewEngine()
+	_ = e.SetScope("conv1", Config{AllowedDomains: []string{"*.example.com"}})
+
+	if err := e.CheckTarget("conv1", "https://api.example.com/v1"); err != nil {
+		t.Errorf("api.example.com 应在范围内: %v", err)
+	}
+	if err := e.CheckTarget("conv1", "example.com"); err != nil {
+		t.Errorf("example.com 本身应在范围内: %v", err)
+	}
+	if err := e.CheckTarget("conv1", "evil.com"); err == nil {
+		t.Error("evil.com 不在范围内，应拒绝")
+	}
+}
+
+func TestEngine_DenyListOverridesAllow(t *testing.T) {
+	e := NewEngine()
+	_ = e.SetScope("conv1", Config{
+		AllowedCIDRs: []string{"10.0.0.0/16"},
+		DenyList:     []string{"10.0.0.0/24"},
+	})
+
+	if err := e.CheckTarget("conv1", "10.0.1.5"); err != nil {
+		t.Errorf("10.0.1.5 在允许范围且未命中拒绝，应放行: %v", err)
+	}
+	if err := e.CheckTarget("conv1", "10.0.0.5"); err == nil {
+		t.Error("10.0.0.5 命中拒绝列表，应拒绝，即使在允许CIDR内")
+	}
+}
+
+func TestEngine_URLPattern(t *testing.T) {
+	e := NewEngine()
+	_ = e.SetScope("conv1", Config{URLPatterns: []string{"https://target.com/*"}})
+
+	if err := e.CheckTarget("conv1", "https://target.com/admin"); err != nil {
+		t.Errorf("应匹配URL模式: %v", err)
+	}
+	if err := e.CheckTarget("conv1", "https://other.com/admin"); err == nil {
+		t.Error("不匹配URL模式，应拒绝")
+	}
+}
+
+func TestEngine_InvalidCIDRRejected(t *testing.T) {
+	e := NewEngine()
+	if err := e.SetScope("conv1", Config{AllowedCIDRs: []string{"not-a-cidr"}}); err == nil {
+		t.Error("非法CIDR应在SetScope时报错")
+	}
+}
+
+func TestEngine_ClearScope(t *testing.T) {
+	e := NewEngine()
+	_ = e.SetScope("conv1", Config{AllowedCIDRs: []string{"10.0.0.0/24"}})
+	if err := e.CheckTarget("conv1", "192.168.1.1"); err == nil {
+		t.Fatal("清除前应拒绝越界目标")
+	}
+	e.ClearScope("conv1")
+	if err := e.CheckTarget("conv1", "192.168.1.1"); err != nil {
+		t.Errorf("清除范围后应放行: %v", err)
+	}
+}
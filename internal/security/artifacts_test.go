@@ -0,0 +1,104 @@
+package security
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cyberstrike-ai/internal/config"
+	"cyberstrike-ai/internal/mcp"
+	"cyberstrike-ai/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+func setupTestArtifactStorage(t *testing.T) *storage.FileArtifactStorage {
+	tmpDir := filepath.Join(os.TempDir(), "test_executor_artifacts_"+time.Now().Format("20060102_150405.000000000"))
+	logger := zap.NewNop()
+
+	artifactStorage, err := storage.NewFileArtifactStorage(tmpDir, logger)
+	if err != nil {
+		t.Fatalf("创建测试产出文件存储失败: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	return artifactStorage
+}
+
+// TestExecuteTool_OutputArtifactsRewritesParamToScratchDir 验证配置了 OutputArtifacts 的工具，
+// 执行前会自动创建按执行ID命名的暂存目录，并把声明的参数值改写为该目录下的文件名
+func TestExecuteTool_OutputArtifactsRewritesParamToScratchDir(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	artifactStorage := setupTestArtifactStorage(t)
+	executor.SetArtifactStorage(artifactStorage)
+
+	pos := 0
+	toolConfig := config.ToolConfig{
+		Name:    "echo_scan",
+		Command: "echo",
+		Enabled: true,
+		Parameters: []config.ParameterConfig{
+			{Name: "output_file", Type: "string", Position: &pos, Format: "positional"},
+		},
+		OutputArtifacts: []config.ArtifactConfig{
+			{Param: "output_file", Filename: "report.txt"},
+		},
+	}
+	executor.config.Tools = append(executor.config.Tools, toolConfig)
+	executor.toolIndex["echo_scan"] = &executor.config.Tools[len(executor.config.Tools)-1]
+
+	executionID := "artifact_test_exec"
+	ctx := mcp.WithExecutionID(context.Background(), executionID)
+
+	result, err := executor.ExecuteTool(ctx, "echo_scan", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("应该执行成功，但返回了错误: %s", result.Content[0].Text)
+	}
+
+	scratchDir, err := artifactStorage.ScratchDir(executionID)
+	if err != nil {
+		t.Fatalf("获取暂存目录失败: %v", err)
+	}
+	expectedPath := filepath.Join(scratchDir, "report.txt")
+	if !strings.Contains(result.Content[0].Text, expectedPath) {
+		t.Errorf("命令输出应包含被改写为暂存目录路径的参数值，期望包含%q，实际: %s", expectedPath, result.Content[0].Text)
+	}
+}
+
+// TestExecuteTool_NoArtifactStorage_LeavesArgsUntouched 验证未设置产出文件存储时，
+// 配置了 OutputArtifacts 的工具仍能正常执行，只是不会改写参数
+func TestExecuteTool_NoArtifactStorage_LeavesArgsUntouched(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+
+	pos := 0
+	toolConfig := config.ToolConfig{
+		Name:    "echo_scan_no_storage",
+		Command: "echo",
+		Enabled: true,
+		Parameters: []config.ParameterConfig{
+			{Name: "output_file", Type: "string", Position: &pos, Format: "positional", Default: "placeholder"},
+		},
+		OutputArtifacts: []config.ArtifactConfig{
+			{Param: "output_file", Filename: "report.txt"},
+		},
+	}
+	executor.config.Tools = append(executor.config.Tools, toolConfig)
+	executor.toolIndex["echo_scan_no_storage"] = &executor.config.Tools[len(executor.config.Tools)-1]
+
+	ctx := mcp.WithExecutionID(context.Background(), "artifact_test_exec_no_storage")
+	result, err := executor.ExecuteTool(ctx, "echo_scan_no_storage", map[string]interface{}{"output_file": "placeholder"})
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("应该执行成功，但返回了错误: %s", result.Content[0].Text)
+	}
+	if !strings.Contains(result.Content[0].Text, "placeholder") {
+		t.Errorf("未设置产出文件存储时不应改写参数值，实际输出: %s", result.Content[0].Text)
+	}
+}
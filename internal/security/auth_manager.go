@@ -2,6 +2,7 @@ package security
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -12,25 +13,136 @@ import (
 // Predefined errors for authentication operations.
 var (
 	ErrInvalidPassword = errors.New("invalid password")
+	// ErrAccountLocked 表示当前 IP 或账号因连续登录失败正处于暴力破解防护锁定期，见 loginThrottle。
+	ErrAccountLocked = errors.New("account temporarily locked due to repeated failed login attempts")
 )
 
+const (
+	defaultMaxLoginAttempts   = 5
+	defaultLockoutBaseSeconds = 30
+	defaultLockoutMaxSeconds  = 3600
+)
+
+// loginAttemptState 记录一个统计维度（某个 IP，或全局账号）的连续失败登录次数与当前锁定截止时间。
+type loginAttemptState struct {
+	failCount   int
+	lockedUntil time.Time
+}
+
+// loginThrottle 实现登录暴力破解防护：按来源 IP 和账号（本系统只有一个共享密码账号，因此账号维度是
+// 全局的）分别统计连续失败次数，达到阈值后按 2^n 指数退避锁定一段时间，任一维度处于锁定期都会拒绝
+// 登录尝试；登录成功后清零两个维度的计数器。
+type loginThrottle struct {
+	maxAttempts int
+	base        time.Duration
+	max         time.Duration
+
+	mu      sync.Mutex
+	byIP    map[string]*loginAttemptState
+	account loginAttemptState
+}
+
+func newLoginThrottle(maxAttempts, baseSeconds, maxSeconds int) *loginThrottle {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxLoginAttempts
+	}
+	if baseSeconds <= 0 {
+		baseSeconds = defaultLockoutBaseSeconds
+	}
+	if maxSeconds <= 0 {
+		maxSeconds = defaultLockoutMaxSeconds
+	}
+	return &loginThrottle{
+		maxAttempts: maxAttempts,
+		base:        time.Duration(baseSeconds) * time.Second,
+		max:         time.Duration(maxSeconds) * time.Second,
+		byIP:        make(map[string]*loginAttemptState),
+	}
+}
+
+// lockoutDuration 按超出阈值的次数指数增长（2^0, 2^1, ...），封顶 max。
+func (lt *loginThrottle) lockoutDuration(overBy int) time.Duration {
+	d := lt.base
+	for i := 0; i < overBy; i++ {
+		d *= 2
+		if d >= lt.max {
+			return lt.max
+		}
+	}
+	return d
+}
+
+// checkAllowed 返回距离解锁的剩余时长；ok 为 false 时应拒绝本次登录尝试。
+func (lt *loginThrottle) checkAllowed(ip string) (time.Duration, bool) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(lt.account.lockedUntil) {
+		return lt.account.lockedUntil.Sub(now), false
+	}
+	if s, ok := lt.byIP[ip]; ok && now.Before(s.lockedUntil) {
+		return s.lockedUntil.Sub(now), false
+	}
+	return 0, true
+}
+
+func (lt *loginThrottle) recordFailure(ip string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	lt.account.failCount++
+	if over := lt.account.failCount - lt.maxAttempts; over >= 0 {
+		lt.account.lockedUntil = time.Now().Add(lt.lockoutDuration(over))
+	}
+
+	s, ok := lt.byIP[ip]
+	if !ok {
+		s = &loginAttemptState{}
+		lt.byIP[ip] = s
+	}
+	s.failCount++
+	if over := s.failCount - lt.maxAttempts; over >= 0 {
+		s.lockedUntil = time.Now().Add(lt.lockoutDuration(over))
+	}
+}
+
+func (lt *loginThrottle) recordSuccess(ip string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	lt.account = loginAttemptState{}
+	delete(lt.byIP, ip)
+}
+
 // Session represents an authenticated user session.
 type Session struct {
 	Token     string
 	ExpiresAt time.Time
+	// Subject/Role 仅在会话由 OIDC 单点登录创建时（见 CreateSSOSession）非空；密码登录的会话不区分
+	// 身份，两者共用同一个 sessions 存储与校验逻辑。
+	Subject string
+	Role    string
+
+	// ID 是该会话对外展示/撤销时使用的标识符，与 Token 不同——列出会话时不应回显 Token 本身。
+	ID        string
+	CreatedAt time.Time
+	IP        string
+	UserAgent string
 }
 
 // AuthManager manages password-based authentication and session lifecycle.
 type AuthManager struct {
 	password        string
 	sessionDuration time.Duration
+	throttle        *loginThrottle
 
 	mu       sync.RWMutex
 	sessions map[string]Session
 }
 
 // NewAuthManager creates a new AuthManager instance.
-func NewAuthManager(password string, sessionDurationHours int) (*AuthManager, error) {
+func NewAuthManager(password string, sessionDurationHours, maxLoginAttempts, lockoutBaseSeconds, lockoutMaxSeconds int) (*AuthManager, error) {
 	if strings.TrimSpace(password) == "" {
 		return nil, errors.New("auth password must be configured")
 	}
@@ -42,15 +154,23 @@ func NewAuthManager(password string, sessionDurationHours int) (*AuthManager, er
 	return &AuthManager{
 		password:        password,
 		sessionDuration: time.Duration(sessionDurationHours) * time.Hour,
+		throttle:        newLoginThrottle(maxLoginAttempts, lockoutBaseSeconds, lockoutMaxSeconds),
 		sessions:        make(map[string]Session),
 	}, nil
 }
 
-// Authenticate validates the password and creates a new session.
-func (a *AuthManager) Authenticate(password string) (string, time.Time, error) {
+// Authenticate validates the password and creates a new session. 在密码校验之前先检查该 IP/账号是否
+// 处于暴力破解防护锁定期（见 loginThrottle）；密码错误会计入失败次数，达到阈值后触发指数退避锁定。
+func (a *AuthManager) Authenticate(password, ip, userAgent string) (string, time.Time, error) {
+	if remaining, ok := a.throttle.checkAllowed(ip); !ok {
+		return "", time.Time{}, fmt.Errorf("%w，请在 %d 秒后重试", ErrAccountLocked, int(remaining.Seconds())+1)
+	}
+
 	if password != a.password {
+		a.throttle.recordFailure(ip)
 		return "", time.Time{}, ErrInvalidPassword
 	}
+	a.throttle.recordSuccess(ip)
 
 	token := uuid.NewString()
 	expiresAt := time.Now().Add(a.sessionDuration)
@@ -59,12 +179,70 @@ func (a *AuthManager) Authenticate(password string) (string, time.Time, error) {
 	a.sessions[token] = Session{
 		Token:     token,
 		ExpiresAt: expiresAt,
+		ID:        uuid.NewString(),
+		CreatedAt: time.Now(),
+		IP:        ip,
+		UserAgent: userAgent,
 	}
 	a.mu.Unlock()
 
 	return token, expiresAt, nil
 }
 
+// CreateSSOSession 为通过 OIDC 单点登录（见 OIDCProvider.ExchangeCallback）验证过身份的用户创建一个
+// 会话，跳过密码校验；会话本身与密码登录产生的会话完全等价（同一 sessions 存储、同一过期时长、同一
+// AuthMiddleware 校验路径），只是额外记录了 subject/role 供 GET /api/auth/validate 展示。
+func (a *AuthManager) CreateSSOSession(subject, role, ip, userAgent string) (string, time.Time) {
+	token := uuid.NewString()
+	expiresAt := time.Now().Add(a.sessionDuration)
+
+	a.mu.Lock()
+	a.sessions[token] = Session{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		Subject:   subject,
+		Role:      role,
+		ID:        uuid.NewString(),
+		CreatedAt: time.Now(),
+		IP:        ip,
+		UserAgent: userAgent,
+	}
+	a.mu.Unlock()
+
+	return token, expiresAt
+}
+
+// ListSessions 返回全部未过期会话，用于 GET /api/auth/sessions 展示登录设备/来源，不回显 Token。
+func (a *AuthManager) ListSessions() []Session {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	now := time.Now()
+	sessions := make([]Session, 0, len(a.sessions))
+	for _, s := range a.sessions {
+		if now.After(s.ExpiresAt) {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// RevokeSessionByID 按 ListSessions 返回的 ID（而非 Token）撤销指定会话，用于
+// DELETE /api/auth/sessions/:id：调用方通常不掌握目标会话的 Token（例如在另一台设备上撤销登录）。
+func (a *AuthManager) RevokeSessionByID(id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for token, s := range a.sessions {
+		if s.ID == id {
+			delete(a.sessions, token)
+			return nil
+		}
+	}
+	return errors.New("会话不存在或已过期")
+}
+
 // ValidateToken checks whether the provided token is still valid.
 func (a *AuthManager) ValidateToken(token string) (Session, bool) {
 	if strings.TrimSpace(token) == "" {
@@ -0,0 +1,45 @@
+package security
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAuthManager_LockoutAfterRepeatedFailures(t *testing.T) {
+	manager, err := NewAuthManager("correct-horse", 12, 3, 1, 60)
+	if err != nil {
+		t.Fatalf("创建 AuthManager 失败: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := manager.Authenticate("wrong", "1.2.3.4", "ua"); !errors.Is(err, ErrInvalidPassword) {
+			t.Fatalf("第 %d 次错误密码应返回 ErrInvalidPassword，实际: %v", i+1, err)
+		}
+	}
+
+	if _, _, err := manager.Authenticate("correct-horse", "1.2.3.4", "ua"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("达到失败阈值后即使密码正确也应处于锁定期，实际: %v", err)
+	}
+
+	// 另一个 IP 未触发该 IP 维度的锁定，但全局账号维度已锁定，同样应被拒绝。
+	if _, _, err := manager.Authenticate("correct-horse", "5.6.7.8", "ua"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("账号维度锁定应对所有来源 IP 生效，实际: %v", err)
+	}
+}
+
+func TestAuthManager_SuccessResetsFailureCount(t *testing.T) {
+	manager, err := NewAuthManager("correct-horse", 12, 3, 1, 60)
+	if err != nil {
+		t.Fatalf("创建 AuthManager 失败: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, _, _ = manager.Authenticate("wrong", "9.9.9.9", "ua")
+	}
+	if _, _, err := manager.Authenticate("correct-horse", "9.9.9.9", "ua"); err != nil {
+		t.Fatalf("未达到锁定阈值前，正确密码应登录成功，实际: %v", err)
+	}
+	if _, _, err := manager.Authenticate("wrong", "9.9.9.9", "ua"); !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("登录成功后应重置失败计数，本次失败不应被锁定，实际: %v", err)
+	}
+}
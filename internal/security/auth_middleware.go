@@ -10,23 +10,68 @@ import (
 const (
 	ContextAuthTokenKey  = "authToken"
 	ContextSessionExpiry = "authSessionExpiry"
+	// ContextAPIKeyScopesKey 标记本次请求是由 API Key（而非交互式会话）通过认证，值为该 key 的
+	// scopes（[]string，为空表示不限范围）。
+	ContextAPIKeyScopesKey = "apiKeyScopes"
 )
 
-// AuthMiddleware enforces authentication on protected routes.
-func AuthMiddleware(manager *AuthManager) gin.HandlerFunc {
+// APIKeyValidator 由数据库层（database.DB）实现；security 包不直接依赖 database 包（database 已
+// 依赖 security，直接反向依赖会造成循环引用），改为在 app 装配层以接口注入。
+type APIKeyValidator interface {
+	// ValidateAPIKey 校验原始 key，返回其 scopes 与是否有效（未知/已撤销均视为无效）。
+	ValidateAPIKey(rawKey string) (scopes []string, ok bool)
+}
+
+// apiKeyScopeAllows 判断某个 scope 是否覆盖了请求路径：留空或 "*" 表示不限制；否则按前缀匹配，
+// 例如 scope "/api/agent-loop" 允许访问 "/api/agent-loop" 及其子路径。
+func apiKeyScopeAllows(scopes []string, path string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || scope == "*" {
+			return true
+		}
+		if path == scope || strings.HasPrefix(path, strings.TrimSuffix(scope, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthMiddleware enforces authentication on protected routes. 先尝试会话 token；未命中且
+// keyValidator 非空时，再尝试将其作为 API Key 校验（见 wivd/CyberStrikeAI#synth-3090），
+// 命中时按该 key 的 scopes 做路径前缀级别的访问范围限制。keyValidator 为 nil 时（如
+// NewKnowledgeDB 等不需要 API Key 能力的最小化装配场景）行为与之前完全一致，仅校验会话 token。
+func AuthMiddleware(manager *AuthManager, keyValidator APIKeyValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := extractTokenFromRequest(c)
-		session, ok := manager.ValidateToken(token)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "未授权访问，请先登录",
-			})
+
+		if session, ok := manager.ValidateToken(token); ok {
+			c.Set(ContextAuthTokenKey, session.Token)
+			c.Set(ContextSessionExpiry, session.ExpiresAt)
+			c.Next()
 			return
 		}
 
-		c.Set(ContextAuthTokenKey, session.Token)
-		c.Set(ContextSessionExpiry, session.ExpiresAt)
-		c.Next()
+		if keyValidator != nil {
+			if scopes, ok := keyValidator.ValidateAPIKey(token); ok {
+				if !apiKeyScopeAllows(scopes, c.Request.URL.Path) {
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+						"error": "该 API Key 无权访问此接口",
+					})
+					return
+				}
+				c.Set(ContextAPIKeyScopesKey, scopes)
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "未授权访问，请先登录",
+		})
 	}
 }
 
@@ -0,0 +1,144 @@
+package security
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// cveIDPattern 匹配 CVE 编号，格式为 CVE-yyyy-NNNN（年份4位，序号至少4位），大小写不敏感。
+var cveIDPattern = regexp.MustCompile(`(?i)CVE-\d{4}-\d{4,7}`)
+
+// ExtractCVEIDs 从任意文本（工具原始输出、漏洞描述/证明等）中提取去重后的 CVE 编号列表，统一转为大写，
+// 保留首次出现的顺序，供富化管线逐个查询详情。
+func ExtractCVEIDs(text string) []string {
+	matches := cveIDPattern.FindAllString(text, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(matches))
+	ids := make([]string, 0, len(matches))
+	for _, m := range matches {
+		id := strings.ToUpper(m)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// CVERecord 是从 NVD 查询到的单个 CVE 的标准化富化信息。
+type CVERecord struct {
+	ID             string   `json:"id"`
+	Description    string   `json:"description"`
+	CVSSVector     string   `json:"cvss_vector,omitempty"`
+	CVSSScore      float64  `json:"cvss_score,omitempty"`
+	CWEIDs         []string `json:"cwe_ids,omitempty"`
+	KnownExploited bool     `json:"known_exploited"` // 是否在 CISA KEV（已知被利用漏洞）目录中
+}
+
+// CVELookupClient 是查询单个 CVE 详情的最小接口，NVDClient 是其默认实现；测试中可用桩实现替换。
+type CVELookupClient interface {
+	LookupCVE(ctx context.Context, cveID string) (*CVERecord, error)
+}
+
+// CVEEnrichmentSink 接收富化结果并负责写回具体的漏洞记录（通常适配到 database.DB.ApplyCVEEnrichment）。
+type CVEEnrichmentSink interface {
+	ApplyCVEEnrichment(vulnerabilityID string, record CVERecord) error
+}
+
+// cveCacheEntry 是 CVECache 中的单条缓存记录及其过期时间。
+type cveCacheEntry struct {
+	record    CVERecord
+	expiresAt time.Time
+}
+
+// CVECache 是进程内的 CVE 详情本地缓存，避免同一 CVE 编号被反复扫描命中时重复请求 NVD API
+// （NVD 对未配置 API Key 的请求有较严格的限速）。结构上与 proxy.Engine/scope.Engine 一致：
+// 读写均加锁保护的 map。
+type CVECache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[string]cveCacheEntry
+}
+
+// NewCVECache 创建一个本地 CVE 缓存，ttl 为条目有效期，过期后会重新查询 NVD。
+func NewCVECache(ttl time.Duration) *CVECache {
+	return &CVECache{
+		ttl:     ttl,
+		entries: make(map[string]cveCacheEntry),
+	}
+}
+
+// Get 返回缓存中未过期的记录；未命中或已过期返回 (CVERecord{}, false)。
+func (c *CVECache) Get(cveID string) (CVERecord, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[cveID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return CVERecord{}, false
+	}
+	return entry.record, true
+}
+
+// Set 写入或刷新一条缓存记录。
+func (c *CVECache) Set(cveID string, record CVERecord) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[cveID] = cveCacheEntry{record: record, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// lookupCVEWithCache 优先读本地缓存，未命中时调用 client 查询并回填缓存。
+func lookupCVEWithCache(ctx context.Context, client CVELookupClient, cache *CVECache, cveID string) (*CVERecord, error) {
+	if cache != nil {
+		if record, ok := cache.Get(cveID); ok {
+			return &record, nil
+		}
+	}
+	record, err := client.LookupCVE(ctx, cveID)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil && record != nil {
+		cache.Set(cveID, *record)
+	}
+	return record, nil
+}
+
+// EnrichVulnerabilityCVEsAsync 在后台异步从给定文本中提取 CVE 编号并逐个查询富化信息，写回 sink；
+// client 为 nil 或文本中未发现 CVE 编号时直接跳过。与 extractAndRecordVulnerabilitiesAsync 一样是
+// 尽力而为的增强能力：单个 CVE 查询失败仅记录日志，不影响其余 CVE 的富化，也不回传给调用方。
+func EnrichVulnerabilityCVEsAsync(client CVELookupClient, cache *CVECache, sink CVEEnrichmentSink, logger *zap.Logger, vulnerabilityID, text string) {
+	if client == nil || sink == nil {
+		return
+	}
+	ids := ExtractCVEIDs(text)
+	if len(ids) == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		for _, cveID := range ids {
+			record, err := lookupCVEWithCache(ctx, client, cache, cveID)
+			if err != nil {
+				logger.Warn("查询CVE详情失败", zap.String("cve_id", cveID), zap.Error(err))
+				continue
+			}
+			if record == nil {
+				continue
+			}
+			if err := sink.ApplyCVEEnrichment(vulnerabilityID, *record); err != nil {
+				logger.Warn("写入CVE富化信息失败", zap.String("cve_id", cveID), zap.Error(err))
+			}
+		}
+	}()
+}
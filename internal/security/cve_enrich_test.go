@@ -0,0 +1,65 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExtractCVEIDs_DedupAndUppercase(t *testing.T) {
+	ids := ExtractCVEIDs("发现漏洞 cve-2021-44228，另请参考 CVE-2021-44228 和 CVE-2022-1234")
+	if len(ids) != 2 {
+		t.Fatalf("应去重为2个CVE编号，实际: %v", ids)
+	}
+	if ids[0] != "CVE-2021-44228" || ids[1] != "CVE-2022-1234" {
+		t.Errorf("CVE编号应统一转为大写且保留首次出现顺序，实际: %v", ids)
+	}
+}
+
+func TestExtractCVEIDs_NoMatch(t *testing.T) {
+	if ids := ExtractCVEIDs("普通输出，不含CVE编号"); ids != nil {
+		t.Errorf("无匹配时应返回nil，实际: %v", ids)
+	}
+}
+
+type fakeCVELookupClient struct {
+	calls  int
+	record *CVERecord
+	err    error
+}
+
+func (f *fakeCVELookupClient) LookupCVE(ctx context.Context, cveID string) (*CVERecord, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.record, nil
+}
+
+func TestLookupCVEWithCache_CachesResult(t *testing.T) {
+	client := &fakeCVELookupClient{record: &CVERecord{ID: "CVE-2021-44228", CVSSScore: 10.0}}
+	cache := NewCVECache(time.Minute)
+
+	first, err := lookupCVEWithCache(context.Background(), client, cache, "CVE-2021-44228")
+	if err != nil {
+		t.Fatalf("首次查询不应报错: %v", err)
+	}
+	second, err := lookupCVEWithCache(context.Background(), client, cache, "CVE-2021-44228")
+	if err != nil {
+		t.Fatalf("二次查询不应报错: %v", err)
+	}
+	if client.calls != 1 {
+		t.Errorf("命中缓存后不应再次调用底层客户端，实际调用次数: %d", client.calls)
+	}
+	if first.ID != second.ID || first.CVSSScore != second.CVSSScore {
+		t.Errorf("两次查询结果应一致，实际: %+v vs %+v", first, second)
+	}
+}
+
+func TestLookupCVEWithCache_ClientErrorPropagates(t *testing.T) {
+	client := &fakeCVELookupClient{err: errors.New("网络错误")}
+	if _, err := lookupCVEWithCache(context.Background(), client, NewCVECache(time.Minute), "CVE-2021-44228"); err == nil {
+		t.Error("客户端出错时应返回错误")
+	}
+}
@@ -0,0 +1,149 @@
+package security
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// cvssMetricWeights 是 CVSS v3.1 基础评分公式中各 Base Metric 取值对应的数值权重，
+// 取值来自 FIRST 官方规范（https://www.first.org/cvss/v3.1/specification-document）附录 A。
+var cvssMetricWeights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"PR": {"N": 0.85, "L": 0.62, "H": 0.27}, // Scope=Unchanged 时使用；Changed 时另有取值，见 cvssScoredPR
+	"UI": {"N": 0.85, "R": 0.62},
+	"S":  {"U": 0, "C": 0},
+	"C":  {"N": 0, "L": 0.22, "H": 0.56},
+	"I":  {"N": 0, "L": 0.22, "H": 0.56},
+	"A":  {"N": 0, "L": 0.22, "H": 0.56},
+}
+
+// cvssPRChangedWeights 是 Scope=Changed 时 PR（Privileges Required）的取值权重，与 Unchanged 不同。
+var cvssPRChangedWeights = map[string]float64{"N": 0.85, "L": 0.68, "H": 0.5}
+
+// cvssBaseMetricOrder 是 CVSS v3.1 Base Metric 向量字符串中各分量的固定顺序，用于校验向量完整性。
+var cvssBaseMetricOrder = []string{"AV", "AC", "PR", "UI", "S", "C", "I", "A"}
+
+// CVSSMetrics 保存解析后的 CVSS v3.1 Base Metric 取值，键为缩写（如 "AV"），值为单字母取值（如 "N"）。
+type CVSSMetrics map[string]string
+
+// ParseCVSSVector 解析形如 "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H" 的 CVSS v3.1 向量字符串，
+// 校验版本前缀与全部 8 个 Base Metric 是否齐全且取值合法。
+func ParseCVSSVector(vector string) (CVSSMetrics, error) {
+	vector = strings.TrimSpace(vector)
+	if vector == "" {
+		return nil, fmt.Errorf("CVSS向量为空")
+	}
+	segments := strings.Split(vector, "/")
+	if len(segments) == 0 || segments[0] != "CVSS:3.1" {
+		return nil, fmt.Errorf("不支持的CVSS版本前缀，仅支持 CVSS:3.1")
+	}
+
+	metrics := make(CVSSMetrics)
+	for _, seg := range segments[1:] {
+		parts := strings.SplitN(seg, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("CVSS向量分量格式错误: %q", seg)
+		}
+		metrics[parts[0]] = parts[1]
+	}
+
+	for _, key := range cvssBaseMetricOrder {
+		value, ok := metrics[key]
+		if !ok {
+			return nil, fmt.Errorf("CVSS向量缺少必需的Base Metric: %s", key)
+		}
+		if key == "PR" {
+			continue // PR 取值范围随 Scope 变化，单独校验
+		}
+		if _, ok := cvssMetricWeights[key][value]; !ok {
+			return nil, fmt.Errorf("CVSS向量分量 %s 取值非法: %s", key, value)
+		}
+	}
+	scope := metrics["S"]
+	if scope != "U" && scope != "C" {
+		return nil, fmt.Errorf("CVSS向量分量 S 取值非法: %s", scope)
+	}
+	if _, ok := cvssPRWeight(metrics["PR"], scope); !ok {
+		return nil, fmt.Errorf("CVSS向量分量 PR 取值非法: %s", metrics["PR"])
+	}
+
+	return metrics, nil
+}
+
+// cvssPRWeight 返回 PR（Privileges Required）在给定 Scope 下的权重，Scope=Changed 时使用更高的权重表。
+func cvssPRWeight(value, scope string) (float64, bool) {
+	if scope == "C" {
+		w, ok := cvssPRChangedWeights[value]
+		return w, ok
+	}
+	w, ok := cvssMetricWeights["PR"][value]
+	return w, ok
+}
+
+// ComputeCVSSBaseScore 按 CVSS v3.1 官方公式计算 Base Score（0.0-10.0，保留一位小数，向上取整到 0.1）。
+func ComputeCVSSBaseScore(vector string) (float64, error) {
+	metrics, err := ParseCVSSVector(vector)
+	if err != nil {
+		return 0, err
+	}
+
+	scope := metrics["S"]
+	prWeight, _ := cvssPRWeight(metrics["PR"], scope)
+
+	iss := 1 - (1-cvssMetricWeights["C"][metrics["C"]])*
+		(1-cvssMetricWeights["I"][metrics["I"]])*
+		(1-cvssMetricWeights["A"][metrics["A"]])
+
+	var impact float64
+	if scope == "U" {
+		impact = 6.42 * iss
+	} else {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+
+	exploitability := 8.22 * cvssMetricWeights["AV"][metrics["AV"]] *
+		cvssMetricWeights["AC"][metrics["AC"]] *
+		prWeight *
+		cvssMetricWeights["UI"][metrics["UI"]]
+
+	if impact <= 0 {
+		return 0, nil
+	}
+
+	var base float64
+	if scope == "U" {
+		base = math.Min(impact+exploitability, 10)
+	} else {
+		base = math.Min(1.08*(impact+exploitability), 10)
+	}
+
+	return roundUpToOneDecimal(base), nil
+}
+
+// roundUpToOneDecimal 实现 CVSS 规范要求的 Roundup 规则：保留一位小数，且总是向上取整而非四舍五入。
+func roundUpToOneDecimal(value float64) float64 {
+	intInput := int(math.Round(value * 100000))
+	if intInput%10000 == 0 {
+		return float64(intInput) / 100000
+	}
+	return float64((intInput/10000)+1) / 10
+}
+
+// SeverityFromCVSSScore 按 CVSS v3.1 官方定性分级（Qualitative Severity Rating Scale）将数值评分
+// 映射为本系统统一使用的严重程度字符串（critical/high/medium/low/info）。
+func SeverityFromCVSSScore(score float64) string {
+	switch {
+	case score >= 9.0:
+		return "critical"
+	case score >= 7.0:
+		return "high"
+	case score >= 4.0:
+		return "medium"
+	case score > 0.0:
+		return "low"
+	default:
+		return "info"
+	}
+}
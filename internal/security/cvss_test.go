@@ -0,0 +1,58 @@
+package security
+
+import "testing"
+
+func TestComputeCVSSBaseScore_KnownVectors(t *testing.T) {
+	cases := []struct {
+		vector string
+		want   float64
+	}{
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", 9.8}, // 典型未授权RCE
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:H/I:H/A:H", 9.6}, // Scope变更
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", 0.0}, // 无实际影响
+		{"CVSS:3.1/AV:L/AC:H/PR:H/UI:R/S:U/C:L/I:N/A:N", 1.8}, // 低危样例
+	}
+	for _, c := range cases {
+		got, err := ComputeCVSSBaseScore(c.vector)
+		if err != nil {
+			t.Fatalf("计算CVSS评分失败: %s: %v", c.vector, err)
+		}
+		if got != c.want {
+			t.Errorf("向量 %s 期望评分 %.1f，实际 %.1f", c.vector, c.want, got)
+		}
+	}
+}
+
+func TestComputeCVSSBaseScore_InvalidVector(t *testing.T) {
+	if _, err := ComputeCVSSBaseScore("AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"); err == nil {
+		t.Error("缺少版本前缀应报错")
+	}
+	if _, err := ComputeCVSSBaseScore("CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H"); err == nil {
+		t.Error("缺少Base Metric应报错")
+	}
+	if _, err := ComputeCVSSBaseScore("CVSS:3.1/AV:X/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"); err == nil {
+		t.Error("非法取值应报错")
+	}
+}
+
+func TestSeverityFromCVSSScore_Boundaries(t *testing.T) {
+	cases := []struct {
+		score float64
+		want  string
+	}{
+		{0.0, "info"},
+		{0.1, "low"},
+		{3.9, "low"},
+		{4.0, "medium"},
+		{6.9, "medium"},
+		{7.0, "high"},
+		{8.9, "high"},
+		{9.0, "critical"},
+		{10.0, "critical"},
+	}
+	for _, c := range cases {
+		if got := SeverityFromCVSSScore(c.score); got != c.want {
+			t.Errorf("评分 %.1f 期望严重程度 %s，实际 %s", c.score, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package security
+
+import (
+	"context"
+	"os/exec"
+
+	"cyberstrike-ai/internal/config"
+)
+
+// buildDockerCommand 将 toolConfig.Command 及其参数包装为一条 `docker run` 命令，使其在容器内隔离
+// 执行，而非直接暴露宿主机环境；用于隔离不可信的工具输出，并可在非 Kali 主机上运行仅 Kali 才提供的
+// 安全工具。仅调用 docker CLI（未引入 docker SDK 依赖），与仓库现有通过 exec.CommandContext 调用外部
+// 命令的方式保持一致。调用方须确保 toolConfig.Sandbox 非空。
+func buildDockerCommand(ctx context.Context, toolConfig *config.ToolConfig, cmdArgs []string) *exec.Cmd {
+	sandbox := toolConfig.Sandbox
+
+	dockerArgs := []string{"run", "--rm"}
+
+	networkMode := sandbox.NetworkMode
+	if networkMode == "" {
+		networkMode = "bridge"
+	}
+	dockerArgs = append(dockerArgs, "--network", networkMode)
+
+	if sandbox.CPULimit != "" {
+		dockerArgs = append(dockerArgs, "--cpus", sandbox.CPULimit)
+	}
+	if sandbox.MemoryLimit != "" {
+		dockerArgs = append(dockerArgs, "--memory", sandbox.MemoryLimit)
+	}
+	for _, mount := range sandbox.Mounts {
+		dockerArgs = append(dockerArgs, "-v", mount)
+	}
+
+	dockerArgs = append(dockerArgs, sandbox.Image, toolConfig.Command)
+	dockerArgs = append(dockerArgs, cmdArgs...)
+
+	return exec.CommandContext(ctx, "docker", dockerArgs...)
+}
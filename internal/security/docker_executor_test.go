@@ -0,0 +1,48 @@
+package security
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"cyberstrike-ai/internal/config"
+)
+
+func TestBuildDockerCommand(t *testing.T) {
+	toolConfig := &config.ToolConfig{
+		Command: "nmap",
+		Sandbox: &config.SandboxConfig{
+			Image:       "kalilinux/kali-rolling",
+			NetworkMode: "none",
+			Mounts:      []string{"/tmp/scans:/scans:ro"},
+			CPULimit:    "1.5",
+			MemoryLimit: "512m",
+		},
+	}
+
+	cmd := buildDockerCommand(context.Background(), toolConfig, []string{"-sV", "10.0.0.1"})
+
+	if cmd.Path == "" || !strings.HasSuffix(cmd.Path, "docker") {
+		t.Fatalf("期望调用 docker 二进制，实际 Path=%q", cmd.Path)
+	}
+
+	argsStr := strings.Join(cmd.Args, " ")
+	for _, want := range []string{"run", "--rm", "--network none", "--cpus 1.5", "--memory 512m", "-v /tmp/scans:/scans:ro", "kalilinux/kali-rolling nmap -sV 10.0.0.1"} {
+		if !strings.Contains(argsStr, want) {
+			t.Errorf("命令参数缺少 %q，实际: %s", want, argsStr)
+		}
+	}
+}
+
+func TestBuildDockerCommand_DefaultNetworkMode(t *testing.T) {
+	toolConfig := &config.ToolConfig{
+		Command: "sqlmap",
+		Sandbox: &config.SandboxConfig{Image: "kalilinux/kali-rolling"},
+	}
+
+	cmd := buildDockerCommand(context.Background(), toolConfig, []string{"-u", "http://target"})
+
+	if !strings.Contains(strings.Join(cmd.Args, " "), "--network bridge") {
+		t.Errorf("未配置 NetworkMode 时应默认使用 bridge，实际参数: %v", cmd.Args)
+	}
+}
@@ -6,17 +6,26 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"cyberstrike-ai/internal/config"
 	"cyberstrike-ai/internal/mcp"
+	"cyberstrike-ai/internal/proxy"
+	"cyberstrike-ai/internal/scope"
 	"cyberstrike-ai/internal/storage"
+	"cyberstrike-ai/internal/tracing"
+	"cyberstrike-ai/internal/worker"
 
 	"github.com/creack/pty"
 	"go.uber.org/zap"
@@ -31,35 +40,146 @@ type toolOutputCallbackCtxKey struct{}
 // ToolOutputCallbackCtxKey 是 context 中的 key，供 Agent 写入回调，Executor 读取并流式回调。
 var ToolOutputCallbackCtxKey = toolOutputCallbackCtxKey{}
 
+// progressAwareOutputCallback 将已有的 stdout/stderr 增量回调与 context 中的 MCP 进度回调（若有）组合：
+// 每收到一行输出，在转发给原回调的同时，把累计行数与该行内容作为一次 notifications/progress 上报，
+// 使外部 MCP 客户端在长时间运行的工具（如 nuclei、masscan）执行期间也能看到实时状态，而不必等待
+// 整个 tools/call 请求返回。
+func progressAwareOutputCallback(ctx context.Context) (ToolOutputCallback, bool) {
+	streamCb, hasStreamCb := ctx.Value(ToolOutputCallbackCtxKey).(ToolOutputCallback)
+	progressCb := mcp.ProgressCallbackFromContext(ctx)
+	if progressCb == nil {
+		return streamCb, hasStreamCb && streamCb != nil
+	}
+
+	var lineCount float64
+	combined := func(chunk string) {
+		if hasStreamCb && streamCb != nil {
+			streamCb(chunk)
+		}
+		lineCount++
+		progressCb(lineCount, strings.TrimSpace(chunk))
+	}
+	return combined, true
+}
+
+// executorOutputCallback 在 progressAwareOutputCallback 的基础上叠加结果存储的增量写入：只要注册了
+// ResultStorage 且 context 中带有本次 tools/call 的 executionID（见 mcp.WithExecutionID），就把每次读到
+// 的增量追加写入存储，使长时间运行、输出巨大的工具无需等待整个命令结束、把完整输出攒在内存里才能被查询到。
+// 返回值恒为非 nil 回调，调用方无需再判断 ok。
+func (e *Executor) executorOutputCallback(ctx context.Context, toolName string) ToolOutputCallback {
+	upstreamCb, hasUpstream := progressAwareOutputCallback(ctx)
+
+	executionID := mcp.ExecutionIDFromContext(ctx)
+	if e.resultStorage == nil || executionID == "" {
+		if hasUpstream && upstreamCb != nil {
+			return upstreamCb
+		}
+		return func(string) {}
+	}
+
+	return func(chunk string) {
+		if hasUpstream && upstreamCb != nil {
+			upstreamCb(chunk)
+		}
+		if err := e.resultStorage.AppendResult(executionID, toolName, chunk); err != nil {
+			e.logger.Warn("追加执行结果到存储失败",
+				zap.String("toolName", toolName),
+				zap.String("executionID", executionID),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// maxOutputBufferBytes 返回本次执行在内存中累计 stdout/stderr 增量的上限字节数，
+// 对应 config.SecurityConfig.MaxOutputBufferKB；完整输出始终经 executorOutputCallback 写入结果存储，
+// 不受此上限影响，此上限只影响直接返回给调用方/大模型的那份内容。
+func (e *Executor) maxOutputBufferBytes() int {
+	const defaultKB = 10 * 1024 // 默认 10MB
+	kb := e.config.MaxOutputBufferKB
+	if kb <= 0 {
+		kb = defaultKB
+	}
+	return kb * 1024
+}
+
 // Executor 安全工具执行器
 type Executor struct {
-	config        *config.SecurityConfig
-	toolIndex     map[string]*config.ToolConfig // 工具索引，用于 O(1) 查找
-	mcpServer     *mcp.Server
-	logger        *zap.Logger
-	resultStorage ResultStorage // 结果存储（用于查询工具）
+	config          *config.SecurityConfig
+	toolIndex       map[string]*config.ToolConfig // 工具索引，用于 O(1) 查找
+	mcpServer       *mcp.Server
+	logger          *zap.Logger
+	resultStorage   ResultStorage   // 结果存储（用于查询工具）
+	artifactStorage ArtifactStorage // 产出文件存储，见 SetArtifactStorage；为 nil 时不创建暂存目录，ToolConfig.OutputArtifacts 被忽略
+	workerManager   *worker.Manager // 远程Worker管理器，见 SetWorkerManager；为 nil 时忽略 ToolConfig.RemoteExec 配置
+	scopeEngine     *scope.Engine   // 目标范围校验引擎，见 SetScopeEngine；为 nil 时不做范围校验
+	proxyEngine     *proxy.Engine   // 按对话的代理路由配置引擎，见 SetProxyEngine；为 nil 时不注入代理
+
+	vulnExtractor       VulnExtractionClient // LLM 漏洞提取补全客户端，见 SetVulnerabilityExtractor；为 nil 时不做自动提取
+	vulnExtractionModel string               // 提取所用的模型名称，与 vulnExtractor 一并配置
+	vulnSink            VulnerabilitySink    // 提取结果的落库出口，见 SetVulnerabilitySink
+
+	assetSink AssetSink // 结构化解析结果（nmap/httpx）的资产台账落库出口，见 SetAssetSink
+
+	globalSem chan struct{}            // 全局并发执行信号量，见 config.SecurityConfig.MaxConcurrentExecutions；nil 表示不限制
+	toolSems  map[string]chan struct{} // 按工具名的并发信号量，见 config.ToolConfig.MaxConcurrency，由 buildToolIndex 构建
+
+	targetRateLimit  *config.TargetRateLimitConfig      // 按目标的并发/冷却限流配置，见 config.SecurityConfig.TargetRateLimit；nil 表示不限制
+	targetLimiters   map[string]*targetRateLimiterState // 按目标 key 懒加载的限流状态
+	targetLimitersMu sync.Mutex                         // 保护 targetLimiters 的创建
+
+	paused atomic.Bool // 全局紧急停止（见 Pause/Resume）：为 true 时 ExecuteTool 拒绝一切新的工具调用，直到显式 Resume
+
+	activeExecutions sync.WaitGroup // 正在执行的 ExecuteTool 调用计数，见 Drain，用于优雅关闭时等待其排空
+
+	simulationEnabled     bool   // 仿真模式：为 true 时所有工具调用不再真实执行，见 simulatedToolResult
+	simulationFixturesDir string // 仿真模式下预置输出文件所在目录
 }
 
+// SimulationResultBannerForModel 标出后续文本为仿真模式下的预置输出，避免被误认为真实工具执行结果。
+const SimulationResultBannerForModel = "---\n" +
+	"【仿真模式｜SIMULATION MODE】\n" +
+	"（以下为演示/培训用途的预置输出，工具并未真实执行，不代表任何真实目标的实际状态。）\n" +
+	"（This is a canned demo output; the tool was not actually executed and reflects no real target.）\n" +
+	"---\n\n"
+
 // ResultStorage 结果存储接口（直接使用 storage 包的类型）
 type ResultStorage interface {
 	SaveResult(executionID string, toolName string, result string) error
+	AppendResult(executionID string, toolName string, chunk string) error
 	GetResult(executionID string) (string, error)
 	GetResultPage(executionID string, page int, limit int) (*storage.ResultPage, error)
 	SearchResult(executionID string, keyword string, useRegex bool) ([]string, error)
 	FilterResult(executionID string, filter string, useRegex bool) ([]string, error)
+	ExtractMatches(executionID string, pattern string, maxMatches int) ([]string, error)
 	GetResultMetadata(executionID string) (*storage.ResultMetadata, error)
 	GetResultPath(executionID string) string
 	DeleteResult(executionID string) error
 }
 
+// ArtifactStorage 产出文件存储接口（直接使用 storage 包的类型），见 config.ToolConfig.OutputArtifacts
+type ArtifactStorage interface {
+	ScratchDir(executionID string) (string, error)
+	ListArtifacts(executionID string) ([]storage.ArtifactInfo, error)
+	ArtifactPath(executionID string, filename string) (string, error)
+	DeleteArtifacts(executionID string) error
+}
+
 // NewExecutor 创建新的执行器
 func NewExecutor(cfg *config.SecurityConfig, mcpServer *mcp.Server, logger *zap.Logger) *Executor {
 	executor := &Executor{
-		config:        cfg,
-		toolIndex:     make(map[string]*config.ToolConfig),
-		mcpServer:     mcpServer,
-		logger:        logger,
-		resultStorage: nil, // 稍后通过 SetResultStorage 设置
+		config:                cfg,
+		toolIndex:             make(map[string]*config.ToolConfig),
+		mcpServer:             mcpServer,
+		logger:                logger,
+		resultStorage:         nil, // 稍后通过 SetResultStorage 设置
+		simulationEnabled:     cfg.Simulation.Enabled,
+		simulationFixturesDir: cfg.Simulation.FixturesDir,
+		targetRateLimit:       cfg.TargetRateLimit,
+		targetLimiters:        make(map[string]*targetRateLimiterState),
+	}
+	if cfg.MaxConcurrentExecutions > 0 {
+		executor.globalSem = make(chan struct{}, cfg.MaxConcurrentExecutions)
 	}
 	// 构建工具索引
 	executor.buildToolIndex()
@@ -71,12 +191,242 @@ func (e *Executor) SetResultStorage(storage ResultStorage) {
 	e.resultStorage = storage
 }
 
+// SetWorkerManager 设置远程Worker管理器，之后配置了 ToolConfig.RemoteExec 的工具将分派到匹配的Worker执行
+func (e *Executor) SetWorkerManager(wm *worker.Manager) {
+	e.workerManager = wm
+}
+
+// SetScopeEngine 设置目标范围校验引擎，之后每次工具调用会校验目标参数是否在发起对话配置的范围内
+func (e *Executor) SetScopeEngine(engine *scope.Engine) {
+	e.scopeEngine = engine
+}
+
+// SetProxyEngine 设置代理路由配置引擎，之后配置了代理的对话发起的工具调用会自动注入
+// HTTP_PROXY/HTTPS_PROXY 环境变量，并对声明了 ToolConfig.ProxyFlag 的工具追加对应的代理命令行参数
+func (e *Executor) SetProxyEngine(engine *proxy.Engine) {
+	e.proxyEngine = engine
+}
+
+// SetVulnerabilityExtractor 配置 LLM 漏洞提取所使用的补全客户端与模型名称；model 为空时视为不启用。
+func (e *Executor) SetVulnerabilityExtractor(client VulnExtractionClient, model string) {
+	e.vulnExtractor = client
+	e.vulnExtractionModel = strings.TrimSpace(model)
+}
+
+// SetVulnerabilitySink 配置 LLM 提取出的漏洞记录的落库出口；为 nil 时提取结果仅记录日志，不会自动创建漏洞记录。
+func (e *Executor) SetVulnerabilitySink(sink VulnerabilitySink) {
+	e.vulnSink = sink
+}
+
+// SetAssetSink 配置结构化解析（nmap/httpx 等）产出的 Finding 落库到资产台账的出口；
+// 为 nil 时结构化解析结果仅附加到工具返回内容中供模型阅读，不会自动积累为资产。
+func (e *Executor) SetAssetSink(sink AssetSink) {
+	e.assetSink = sink
+}
+
+// conversationProxyURL 返回 ctx 对应对话配置的代理地址；未设置 proxyEngine、context 中无
+// conversationID，或该对话未配置代理时返回空字符串。
+func (e *Executor) conversationProxyURL(ctx context.Context) string {
+	if e.proxyEngine == nil {
+		return ""
+	}
+	conversationID := mcp.ConversationIDFromContext(ctx)
+	if conversationID == "" {
+		return ""
+	}
+	cfg, ok := e.proxyEngine.GetProxy(conversationID)
+	if !ok || cfg.IsEmpty() {
+		return ""
+	}
+	return cfg.URL
+}
+
+// applyProxyEnv 向命令进程环境注入 HTTP_PROXY/HTTPS_PROXY（大小写两种形式，兼容不同工具的读取习惯），
+// 使未声明 ToolConfig.ProxyFlag、但遵循标准代理环境变量约定的工具也能被路由到 Burp/SOCKS 跳板。
+func applyProxyEnv(cmd *exec.Cmd, proxyURL string) {
+	if cmd == nil || proxyURL == "" {
+		return
+	}
+	for _, key := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		cmd.Env = append(cmd.Env, key+"="+proxyURL)
+	}
+}
+
+// SetArtifactStorage 设置产出文件存储，之后配置了 ToolConfig.OutputArtifacts 的工具会在执行前
+// 获得一个按执行ID命名的暂存目录，并将声明的参数自动指向该目录下的产出文件
+func (e *Executor) SetArtifactStorage(storage ArtifactStorage) {
+	e.artifactStorage = storage
+}
+
+// Pause 触发全局紧急停止：之后所有 ExecuteTool 调用（包括 exec 与 internal 工具）直接拒绝执行，
+// 直到调用 Resume 显式恢复，见 handler.EmergencyStopHandler。仅拦截新调用，不影响已在执行中的
+// 工具进程，后者由 EmergencyStopHandler 通过取消运行中执行的 context 单独终止。
+func (e *Executor) Pause() {
+	e.paused.Store(true)
+}
+
+// Resume 解除 Pause 触发的全局紧急停止，恢复接受新的工具调用。
+func (e *Executor) Resume() {
+	e.paused.Store(false)
+}
+
+// IsPaused 返回当前是否处于 Pause 触发的全局紧急停止状态。
+func (e *Executor) IsPaused() bool {
+	return e.paused.Load()
+}
+
+// Drain 用于优雅关闭：先 Pause 拒绝一切新的工具调用，再有界等待所有已在执行中的 ExecuteTool
+// 调用完成（受 ctx 超时约束）。ctx 到期时直接返回，不影响调用方继续走后续关闭步骤（如关闭数据库）。
+func (e *Executor) Drain(ctx context.Context) {
+	e.Pause()
+
+	done := make(chan struct{})
+	go func() {
+		e.activeExecutions.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		e.logger.Warn("等待工具执行排空超时，继续关闭流程")
+	}
+}
+
+// targetParamNames 命令参数中约定俗成、带有目标语义的字段名（见 tools/*.yaml 中的 parameters 定义），
+// checkTargetScope 只对这些字段的值做范围校验，避免误把 wordlist、username 等无关参数当作目标处理。
+var targetParamNames = map[string]bool{
+	"target":      true,
+	"targets":     true,
+	"target_host": true,
+	"target_url":  true,
+	"host":        true,
+	"hosts":       true,
+	"url":         true,
+	"urls":        true,
+	"domain":      true,
+	"domains":     true,
+	"ip":          true,
+}
+
+// checkTargetScope 校验 args 中所有目标语义字段是否都在 ctx 对应对话配置的范围内；未设置 scopeEngine、
+// 或该对话未配置范围时直接放行。命中越界目标时返回可直接转述给用户的错误信息。
+func (e *Executor) checkTargetScope(ctx context.Context, args map[string]interface{}) error {
+	if e.scopeEngine == nil {
+		return nil
+	}
+	conversationID := mcp.ConversationIDFromContext(ctx)
+	if conversationID == "" {
+		return nil
+	}
+
+	for name, value := range args {
+		if !targetParamNames[strings.ToLower(name)] {
+			continue
+		}
+		for _, target := range targetValuesToStrings(value) {
+			if target == "" {
+				continue
+			}
+			if err := e.scopeEngine.CheckTarget(conversationID, target); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// targetValuesToStrings 把参数值统一展开为字符串列表：目标字段可能是单个字符串，也可能是逗号分隔的
+// 多目标字符串，或数组（如 targets: ["a.com", "b.com"]）。
+func targetValuesToStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		if strings.Contains(v, ",") {
+			parts := strings.Split(v, ",")
+			for i := range parts {
+				parts[i] = strings.TrimSpace(parts[i])
+			}
+			return parts
+		}
+		return []string{v}
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// dangerousShellMetaChars 非 exec 工具的参数值中禁止出现的字符：这些工具通过 exec.Command 以独立 argv
+// 元素传给目标程序，本身不经过 shell 解释，但部分工具（gobuster/sqlmap 的 --eval 类插件、自定义 wrapper 脚本等）
+// 可能把参数值再次交给 shell 或表达式解释器，禁止这些元字符可以在那一层之前先拦一道。
+const dangerousShellMetaChars = ";&|`$()<>\n\r"
+
+// maxParamValueLength 返回单个参数值允许的最大长度，见 config.SecurityConfig.MaxParamValueLength。
+func (e *Executor) maxParamValueLength() int {
+	if e.config.MaxParamValueLength > 0 {
+		return e.config.MaxParamValueLength
+	}
+	return 4096
+}
+
+// validateParamValues 校验 args 中所有字符串取值（含字符串数组元素）是否包含 shell 元字符或超长；
+// exec 工具的 command 参数本就是要执行的 shell 命令，不受此校验约束。
+func (e *Executor) validateParamValues(toolName string, args map[string]interface{}) error {
+	if toolName == "exec" {
+		return nil
+	}
+	maxLen := e.maxParamValueLength()
+	for name, value := range args {
+		if name == "_tool_name" {
+			continue
+		}
+		for _, s := range paramValueToStrings(value) {
+			if len(s) > maxLen {
+				return fmt.Errorf("参数 %s 的值长度 %d 超过上限 %d", name, len(s), maxLen)
+			}
+			if strings.ContainsAny(s, dangerousShellMetaChars) {
+				return fmt.Errorf("参数 %s 的值包含不允许的字符", name)
+			}
+		}
+	}
+	return nil
+}
+
+// paramValueToStrings 把参数值展开为字符串列表，供 validateParamValues 逐个校验；非字符串标量（数字、布尔）
+// 无需校验，直接跳过。
+func paramValueToStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
 // buildToolIndex 构建工具索引，将 O(n) 查找优化为 O(1)
 func (e *Executor) buildToolIndex() {
 	e.toolIndex = make(map[string]*config.ToolConfig)
+	e.toolSems = make(map[string]chan struct{})
 	for i := range e.config.Tools {
 		if e.config.Tools[i].Enabled {
 			e.toolIndex[e.config.Tools[i].Name] = &e.config.Tools[i]
+			if e.config.Tools[i].MaxConcurrency > 0 {
+				e.toolSems[e.config.Tools[i].Name] = make(chan struct{}, e.config.Tools[i].MaxConcurrency)
+			}
 		}
 	}
 	e.logger.Info("工具索引构建完成",
@@ -85,13 +435,304 @@ func (e *Executor) buildToolIndex() {
 	)
 }
 
+// acquireExecutionSlot 在全局与单工具并发上限内获取一个执行名额；两者都为 nil（未配置限制）时立即放行。
+// 需要排队等待时，把执行记录状态临时置为 "queued"（拿到名额后再置回 "running"），使监控页面能看到
+// 排队中的调用；ctx 被取消时放弃排队并返回错误。返回的 release 函数须在执行结束后调用一次以归还名额。
+func (e *Executor) acquireExecutionSlot(ctx context.Context, toolName string) (func(), error) {
+	toolSem := e.toolSems[toolName]
+	if e.globalSem == nil && toolSem == nil {
+		return func() {}, nil
+	}
+
+	release := func() {
+		if e.globalSem != nil {
+			<-e.globalSem
+		}
+		if toolSem != nil {
+			<-toolSem
+		}
+	}
+
+	// 先尝试非阻塞获取，绝大多数情况下名额充足，避免不必要地把状态置为 "queued"
+	if acquireSemNonBlocking(e.globalSem) {
+		if acquireSemNonBlocking(toolSem) {
+			return release, nil
+		}
+		if e.globalSem != nil {
+			<-e.globalSem
+		}
+	}
+
+	executionID := mcp.ExecutionIDFromContext(ctx)
+	if executionID != "" && e.mcpServer != nil {
+		e.mcpServer.SetExecutionStatus(executionID, "queued")
+	}
+
+	if err := acquireSemBlocking(ctx, e.globalSem); err != nil {
+		return nil, fmt.Errorf("等待执行名额时被取消: %w", err)
+	}
+	if err := acquireSemBlocking(ctx, toolSem); err != nil {
+		if e.globalSem != nil {
+			<-e.globalSem
+		}
+		return nil, fmt.Errorf("等待执行名额时被取消: %w", err)
+	}
+
+	if executionID != "" && e.mcpServer != nil {
+		e.mcpServer.SetExecutionStatus(executionID, "running")
+	}
+	return release, nil
+}
+
+// targetRateLimiterState 单个目标的限流状态：并发信号量与上一次调用结束时刻（供冷却计算）
+type targetRateLimiterState struct {
+	sem      chan struct{} // 并发信号量，见 config.TargetRateLimitConfig.MaxConcurrentPerTarget；nil 表示不限制并发
+	mu       sync.Mutex    // 保护 lastExec
+	lastExec time.Time     // 上一次调用释放名额（即执行结束）的时刻，零值表示尚无历史调用
+}
+
+// targetParamPriority 定义 firstTargetValue 在同一次调用中出现多个目标语义字段时的取值优先级，
+// 使限流 key 的选取在多次调用间保持稳定（map 遍历顺序不确定，不能直接依赖）。
+var targetParamPriority = []string{"target", "target_host", "target_url", "host", "url", "domain", "ip", "targets", "hosts", "urls", "domains"}
+
+// firstTargetValue 从 args 中按 targetParamPriority 顺序提取第一个非空目标值，用于按目标限流/冷却
+// 的 key；与 checkTargetScope 共用同一套目标字段名（见 targetParamNames）。目标值统一转小写以便
+// 大小写不同的同一主机被视为同一限流对象；多目标调用（逗号分隔或数组）只取第一个值，因为限流的
+// 目的是防止对同一主机的突发并发，而非精确按目标枚举限流。
+func firstTargetValue(args map[string]interface{}) string {
+	for _, name := range targetParamPriority {
+		value, ok := args[name]
+		if !ok {
+			continue
+		}
+		for _, target := range targetValuesToStrings(value) {
+			target = strings.ToLower(strings.TrimSpace(target))
+			if target != "" {
+				return target
+			}
+		}
+	}
+	return ""
+}
+
+// acquireTargetRateLimit 若配置了 config.SecurityConfig.TargetRateLimit，则按目标（见 firstTargetValue）
+// 依次执行：1) 并发限流，同一目标最多 MaxConcurrentPerTarget 个工具同时运行；2) 冷却，与该目标上一次
+// 调用结束时刻至少间隔 MinDelaySeconds，不足时阻塞等待补足。未配置目标限流、或本次调用未携带可识别
+// 的目标参数时直接放行。返回的 release 函数须在执行结束后调用一次以归还名额并刷新冷却计时起点。
+func (e *Executor) acquireTargetRateLimit(ctx context.Context, args map[string]interface{}) (func(), error) {
+	if e.targetRateLimit == nil {
+		return func() {}, nil
+	}
+	target := firstTargetValue(args)
+	if target == "" {
+		return func() {}, nil
+	}
+
+	e.targetLimitersMu.Lock()
+	state, exists := e.targetLimiters[target]
+	if !exists {
+		state = &targetRateLimiterState{}
+		if e.targetRateLimit.MaxConcurrentPerTarget > 0 {
+			state.sem = make(chan struct{}, e.targetRateLimit.MaxConcurrentPerTarget)
+		}
+		e.targetLimiters[target] = state
+	}
+	e.targetLimitersMu.Unlock()
+
+	if err := acquireSemBlocking(ctx, state.sem); err != nil {
+		return nil, fmt.Errorf("等待目标 %s 的并发名额时被取消: %w", target, err)
+	}
+
+	if e.targetRateLimit.MinDelaySeconds > 0 {
+		state.mu.Lock()
+		wait := time.Duration(0)
+		if !state.lastExec.IsZero() {
+			minDelay := time.Duration(e.targetRateLimit.MinDelaySeconds * float64(time.Second))
+			if remaining := minDelay - time.Since(state.lastExec); remaining > 0 {
+				wait = remaining
+			}
+		}
+		state.mu.Unlock()
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				if state.sem != nil {
+					<-state.sem
+				}
+				return nil, fmt.Errorf("等待目标 %s 的冷却时间时被取消: %w", target, ctx.Err())
+			}
+		}
+	}
+
+	release := func() {
+		state.mu.Lock()
+		state.lastExec = time.Now()
+		state.mu.Unlock()
+		if state.sem != nil {
+			<-state.sem
+		}
+	}
+	return release, nil
+}
+
+// acquireSemNonBlocking 非阻塞地尝试获取信号量；sem 为 nil（不限制）时视为总是成功。
+func acquireSemNonBlocking(sem chan struct{}) bool {
+	if sem == nil {
+		return true
+	}
+	select {
+	case sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// acquireSemBlocking 阻塞等待获取信号量，直到成功或 ctx 被取消；sem 为 nil 时立即返回成功。
+func acquireSemBlocking(ctx context.Context, sem chan struct{}) error {
+	if sem == nil {
+		return nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sensitiveParamNames 返回指定工具中标记为 sensitive 的参数名列表，用于日志/展示脱敏
+func (e *Executor) sensitiveParamNames(toolName string) []string {
+	toolConfig, exists := e.toolIndex[toolName]
+	if !exists {
+		return nil
+	}
+	var names []string
+	for _, param := range toolConfig.Parameters {
+		if param.Sensitive {
+			names = append(names, param.Name)
+		}
+	}
+	return names
+}
+
+// simulatedToolResult 构造仿真模式下的工具调用结果：优先从 simulationFixturesDir 读取
+// "<toolName>.txt" 作为预置输出，未命中时回退为一段通用提示，两种情况都会加上醒目的仿真水印。
+func (e *Executor) simulatedToolResult(toolName string, args map[string]interface{}) *mcp.ToolResult {
+	fixture := e.loadFixture(toolName)
+	if fixture == "" {
+		fixture = fmt.Sprintf("已模拟执行工具 %s（参数：%v），当前未配置该工具的预置示例输出文件。",
+			toolName, mcp.MaskArguments(args, e.sensitiveParamNames(toolName)))
+	}
+	return &mcp.ToolResult{
+		Content: []mcp.Content{{Type: "text", Text: SimulationResultBannerForModel + fixture}},
+		IsError: false,
+	}
+}
+
+// loadFixture 从 simulationFixturesDir 读取指定工具的预置输出文件，未配置目录或文件不存在时返回空字符串。
+func (e *Executor) loadFixture(toolName string) string {
+	if e.simulationFixturesDir == "" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(e.simulationFixturesDir, toolName+".txt"))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// buildToolCommand 构建工具的待执行命令：配置了 toolConfig.Sandbox 时通过 docker run 在容器内隔离
+// 执行，否则按原有方式直接在宿主机上执行 toolConfig.Command。
+func (e *Executor) buildToolCommand(ctx context.Context, toolConfig *config.ToolConfig, cmdArgs []string) *exec.Cmd {
+	if toolConfig.Sandbox != nil {
+		return buildDockerCommand(ctx, toolConfig, cmdArgs)
+	}
+	return exec.CommandContext(ctx, toolConfig.Command, cmdArgs...)
+}
+
+// executeOnRemoteWorker 将工具执行分派到匹配 toolConfig.RemoteExec.Label/Region 的远程 Worker 上，
+// 流式转发其输出；用于扫描主服务器网络不可达的网段。
+func (e *Executor) executeOnRemoteWorker(ctx context.Context, toolName string, toolConfig *config.ToolConfig, cmdArgs []string) (*mcp.ToolResult, error) {
+	if e.workerManager == nil {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{
+				{Type: "text", Text: fmt.Sprintf("错误: 工具 %s 配置了 remote_exec，但服务未启用Worker管理器", toolName)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	w, err := e.workerManager.SelectWorker(toolConfig.RemoteExec.Label, toolConfig.RemoteExec.Region)
+	if err != nil {
+		e.logger.Error("选择远程worker失败", zap.String("tool", toolName), zap.Error(err))
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("错误: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	cb := worker.OutputCallback(e.executorOutputCallback(ctx, toolName))
+
+	output, err := e.workerManager.DispatchTool(ctx, w, toolConfig.Command, cmdArgs, cb)
+	if err != nil {
+		e.logger.Error("远程worker执行失败",
+			zap.String("tool", toolName),
+			zap.String("workerId", w.ID),
+			zap.Error(err),
+		)
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("远程执行失败(worker=%s): %v\n%s", w.ID, err, output)}},
+			IsError: true,
+		}, nil
+	}
+
+	e.logger.Info("远程worker执行完成", zap.String("tool", toolName), zap.String("workerId", w.ID))
+	return &mcp.ToolResult{
+		Content: []mcp.Content{{Type: "text", Text: output}},
+		IsError: false,
+	}, nil
+}
+
 // ExecuteTool 执行安全工具
 func (e *Executor) ExecuteTool(ctx context.Context, toolName string, args map[string]interface{}) (*mcp.ToolResult, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "security.Executor.ExecuteTool")
+	defer span.End()
+	span.SetAttributes(tracing.StringAttr("tool.name", toolName))
+
 	e.logger.Info("ExecuteTool被调用",
 		zap.String("toolName", toolName),
-		zap.Any("args", args),
+		zap.Any("args", mcp.MaskArguments(args, e.sensitiveParamNames(toolName))),
 	)
 
+	// 计入正在执行的调用数，供 Drain 优雅关闭时有界等待排空；在 paused 检查之前计数，
+	// 确保 Pause() 与本次调用之间不存在竞态导致 Drain 提前判定"已排空"。
+	e.activeExecutions.Add(1)
+	defer e.activeExecutions.Done()
+
+	// 全局紧急停止（见 Pause/EmergencyStopHandler）：暂停期间拒绝一切新的工具调用，
+	// 包括 exec 与 internal: 工具，直到显式 Resume。
+	if e.paused.Load() {
+		e.logger.Warn("执行器已暂停（紧急停止中），拒绝新的工具调用", zap.String("toolName", toolName))
+		return &mcp.ToolResult{
+			Content: []mcp.Content{
+				{Type: "text", Text: "错误: 执行队列已被全局紧急停止暂停，需先调用恢复接口才能继续执行工具"},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// 仿真模式：不真实执行任何工具（包括 exec 与 internal: 工具），直接返回预置输出，
+	// 供培训、销售演示等无扫描器/无真实目标环境下完整走通 Agent 流程。
+	if e.simulationEnabled {
+		e.logger.Info("仿真模式已启用，返回预置输出", zap.String("toolName", toolName))
+		return e.simulatedToolResult(toolName, args), nil
+	}
+
 	// 特殊处理：exec工具直接执行系统命令
 	if toolName == "exec" {
 		e.logger.Info("执行exec工具")
@@ -115,6 +756,25 @@ func (e *Executor) ExecuteTool(ctx context.Context, toolName string, args map[st
 		zap.Strings("args", toolConfig.Args),
 	)
 
+	// 命名扫描档位（quick/standard/deep 等，见 ToolConfig.Profiles）：把档位预置的参数值合并进
+	// args，仅填充调用方未显式传入的参数，不覆盖显式传入的同名参数；消费后移除 profile 本身，
+	// 避免其被当作普通参数误传给后续的命令行拼装逻辑。
+	if profileName, ok := args["profile"].(string); ok && profileName != "" {
+		if preset, found := toolConfig.Profiles[profileName]; found {
+			for k, v := range preset {
+				if _, already := args[k]; !already {
+					args[k] = v
+				}
+			}
+		} else {
+			e.logger.Warn("未知的扫描档位名，忽略 profile 参数",
+				zap.String("toolName", toolName),
+				zap.String("profile", profileName),
+			)
+		}
+		delete(args, "profile")
+	}
+
 	// 特殊处理：内部工具（command 以 "internal:" 开头）
 	if strings.HasPrefix(toolConfig.Command, "internal:") {
 		e.logger.Info("执行内部工具",
@@ -124,6 +784,27 @@ func (e *Executor) ExecuteTool(ctx context.Context, toolName string, args map[st
 		return e.executeInternalTool(ctx, toolName, toolConfig.Command, args)
 	}
 
+	// 产出文件归档（见 config.ToolConfig.OutputArtifacts）：为本次执行创建按执行ID命名的暂存目录，
+	// 把每项声明的参数值自动设为该目录下的文件名，使 nuclei "-o"、nmap "-oX" 等工具的产出文件按
+	// 执行ID归档，可通过 GET /api/monitor/execution/:id/artifacts 列出/下载；调用方无需（也不应）
+	// 自己传入这些参数的值，此处会直接覆盖。
+	if len(toolConfig.OutputArtifacts) > 0 && e.artifactStorage != nil {
+		if executionID := mcp.ExecutionIDFromContext(ctx); executionID != "" {
+			scratchDir, err := e.artifactStorage.ScratchDir(executionID)
+			if err != nil {
+				e.logger.Warn("创建产出文件暂存目录失败，跳过归档",
+					zap.String("toolName", toolName),
+					zap.String("executionID", executionID),
+					zap.Error(err),
+				)
+			} else {
+				for _, artifact := range toolConfig.OutputArtifacts {
+					args[artifact.Param] = filepath.Join(scratchDir, artifact.Filename)
+				}
+			}
+		}
+	}
+
 	// 构建命令 - 根据工具类型使用不同的参数格式
 	cmdArgs := e.buildCommandArgs(toolName, toolConfig, args)
 
@@ -150,9 +831,98 @@ func (e *Executor) ExecuteTool(ctx context.Context, toolName string, args map[st
 		}, nil
 	}
 
-	// 执行命令
-	cmd := exec.CommandContext(ctx, toolConfig.Command, cmdArgs...)
+	// 代理路由（见 proxy.Engine、handler.ProxyHandler）：该对话若配置了代理，且工具声明了
+	// ProxyFlag，则在命令行末尾追加对应的代理参数（如 sqlmap --proxy、nuclei -proxy），使生成的
+	// 流量可被 Burp Suite 截获或经由 SOCKS 跳板转发；未声明 ProxyFlag 的工具仍会在下方通过
+	// HTTP_PROXY/HTTPS_PROXY 环境变量获得代理（见 applyProxyEnv）。
+	proxyURL := e.conversationProxyURL(ctx)
+	if proxyURL != "" && toolConfig.ProxyFlag != "" {
+		cmdArgs = append(cmdArgs, toolConfig.ProxyFlag, proxyURL)
+	}
+
+	// 参数值校验：拒绝 shell 元字符与超长值，防止 model 拼接出的参数值被下游工具或包装脚本二次解释为命令注入
+	if err := e.validateParamValues(toolName, args); err != nil {
+		e.logger.Warn("参数值未通过校验，拒绝执行",
+			zap.String("toolName", toolName),
+			zap.Error(err),
+		)
+		return &mcp.ToolResult{
+			Content: []mcp.Content{
+				{
+					Type: "text",
+					Text: fmt.Sprintf("错误: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// 目标范围校验：拒绝越界目标，返回可直接转述给用户的错误，而不是把越界扫描交给工具本身
+	if err := e.checkTargetScope(ctx, args); err != nil {
+		e.logger.Warn("目标超出范围，拒绝执行",
+			zap.String("toolName", toolName),
+			zap.Error(err),
+		)
+		return &mcp.ToolResult{
+			Content: []mcp.Content{
+				{
+					Type: "text",
+					Text: fmt.Sprintf("错误: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// 并发限流：全局与单工具并发都在上限内才继续；超出时在此排队等待，执行记录状态短暂置为 "queued"
+	release, slotErr := e.acquireExecutionSlot(ctx, toolName)
+	if slotErr != nil {
+		e.logger.Warn("等待执行名额失败",
+			zap.String("toolName", toolName),
+			zap.Error(slotErr),
+		)
+		return &mcp.ToolResult{
+			Content: []mcp.Content{
+				{
+					Type: "text",
+					Text: fmt.Sprintf("错误: %v", slotErr),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	defer release()
+
+	// 按目标限流：同一目标的并发上限与最小调用间隔，见 config.SecurityConfig.TargetRateLimit，
+	// 防止过于激进的 Agent 循环短时间内对同一目标发起大量并发扫描触发 WAF/IDS
+	targetRelease, targetErr := e.acquireTargetRateLimit(ctx, args)
+	if targetErr != nil {
+		e.logger.Warn("等待目标限流名额失败",
+			zap.String("toolName", toolName),
+			zap.Error(targetErr),
+		)
+		return &mcp.ToolResult{
+			Content: []mcp.Content{
+				{
+					Type: "text",
+					Text: fmt.Sprintf("错误: %v", targetErr),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	defer targetRelease()
+
+	// 若配置了 RemoteExec，则分派到匹配 Label/Region 的远程 Worker 执行，不在本地/沙箱执行
+	if toolConfig.RemoteExec != nil {
+		return e.executeOnRemoteWorker(ctx, toolName, toolConfig, cmdArgs)
+	}
+
+	// 执行命令（配置了 Sandbox 时在 Docker 容器内隔离执行，否则直接在宿主机上执行）
+	cmd := e.buildToolCommand(ctx, toolConfig, cmdArgs)
 	applyDefaultTerminalEnv(cmd)
+	applyProcessGroup(cmd)
+	applyProxyEnv(cmd, proxyURL)
 
 	e.logger.Info("执行安全工具",
 		zap.String("tool", toolName),
@@ -161,29 +931,20 @@ func (e *Executor) ExecuteTool(ctx context.Context, toolName string, args map[st
 
 	var output string
 	var err error
-	// 如果上层提供了 stdout/stderr 增量回调，则边执行边读取并回调。
-	if cb, ok := ctx.Value(ToolOutputCallbackCtxKey).(ToolOutputCallback); ok && cb != nil {
-		output, err = streamCommandOutput(cmd, cb)
-		if err != nil && shouldRetryWithPTY(output) {
-			e.logger.Info("检测到工具需要 TTY，使用 PTY 重试",
-				zap.String("tool", toolName),
-			)
-			cmd2 := exec.CommandContext(ctx, toolConfig.Command, cmdArgs...)
-			applyDefaultTerminalEnv(cmd2)
-			output, err = runCommandWithPTY(ctx, cmd2, cb)
-		}
-	} else {
-		outputBytes, err2 := cmd.CombinedOutput()
-		output = string(outputBytes)
-		err = err2
-		if err != nil && shouldRetryWithPTY(output) {
-			e.logger.Info("检测到工具需要 TTY，使用 PTY 重试",
-				zap.String("tool", toolName),
-			)
-			cmd2 := exec.CommandContext(ctx, toolConfig.Command, cmdArgs...)
-			applyDefaultTerminalEnv(cmd2)
-			output, err = runCommandWithPTY(ctx, cmd2, nil)
-		}
+	// 边执行边读取：增量流向上层回调/进度上报，并在配置了结果存储时同步落盘（见 executorOutputCallback），
+	// 返回给调用方的拼接内容按 MaxOutputBufferKB 截断，避免超大输出撑爆内存。
+	cb := e.executorOutputCallback(ctx, toolName)
+	maxBuf := e.maxOutputBufferBytes()
+	output, err = streamCommandOutput(cmd, cb, maxBuf)
+	if err != nil && shouldRetryWithPTY(output) {
+		e.logger.Info("检测到工具需要 TTY，使用 PTY 重试",
+			zap.String("tool", toolName),
+		)
+		cmd2 := e.buildToolCommand(ctx, toolConfig, cmdArgs)
+		applyDefaultTerminalEnv(cmd2)
+		applyProcessGroup(cmd2)
+		applyProxyEnv(cmd2, proxyURL)
+		output, err = runCommandWithPTY(ctx, cmd2, cb, maxBuf)
 	}
 	if err != nil {
 		// 检查退出码是否在允许列表中
@@ -196,13 +957,11 @@ func (e *Executor) ExecuteTool(ctx context.Context, toolName string, args map[st
 						zap.Int("exitCode", *exitCode),
 						zap.String("output", string(output)),
 					)
+					content := []mcp.Content{{Type: "text", Text: string(output)}}
+					content = e.appendStructuredFindings(ctx, content, toolName, output)
+					e.extractAndRecordVulnerabilitiesAsync(ctx, toolName, output)
 					return &mcp.ToolResult{
-						Content: []mcp.Content{
-							{
-								Type: "text",
-								Text: string(output),
-							},
-						},
+						Content: content,
 						IsError: false,
 					}, nil
 				}
@@ -231,13 +990,11 @@ func (e *Executor) ExecuteTool(ctx context.Context, toolName string, args map[st
 		zap.String("output", string(output)),
 	)
 
+	content := []mcp.Content{{Type: "text", Text: string(output)}}
+	content = e.appendStructuredFindings(ctx, content, toolName, output)
+	e.extractAndRecordVulnerabilitiesAsync(ctx, toolName, output)
 	return &mcp.ToolResult{
-		Content: []mcp.Content{
-			{
-				Type: "text",
-				Text: string(output),
-			},
-		},
+		Content: content,
 		IsError: false,
 	}, nil
 }
@@ -284,17 +1041,21 @@ func (e *Executor) RegisterTools(mcpServer *mcp.Server) {
 			shortDesc = "" // 使用 description 时清空 ShortDescription，下游会回退到 Description
 		}
 
+		sensitiveParams := e.sensitiveParamNames(toolName)
+
 		tool := mcp.Tool{
 			Name:             toolConfigCopy.Name,
 			Description:      toolConfigCopy.Description,
 			ShortDescription: shortDesc,
 			InputSchema:      e.buildInputSchema(&toolConfigCopy),
+			SensitiveParams:  sensitiveParams,
+			TimeoutSeconds:   toolConfigCopy.TimeoutSeconds,
 		}
 
 		handler := func(ctx context.Context, args map[string]interface{}) (*mcp.ToolResult, error) {
 			e.logger.Info("工具handler被调用",
 				zap.String("toolName", toolName),
-				zap.Any("args", args),
+				zap.Any("args", mcp.MaskArguments(args, sensitiveParams)),
 			)
 			return e.ExecuteTool(ctx, toolName, args)
 		}
@@ -783,6 +1544,20 @@ func IsBackgroundShellCommand(command string) bool {
 
 // executeSystemCommand 执行系统命令
 func (e *Executor) executeSystemCommand(ctx context.Context, args map[string]interface{}) (*mcp.ToolResult, error) {
+	// exec 工具默认关闭：任意 shell 命令执行风险远高于其它带参数白名单的工具，须在配置中显式开启；
+	// 即使开启，调用仍会照常经过 Agent 层的人机协同审批（见 handler.HITLManager.shouldInterrupt 对 "exec" 的强制审批处理）。
+	if !e.config.ExecToolEnabled {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{
+				{
+					Type: "text",
+					Text: "错误: exec 工具未启用，请在配置中设置 security.exec_tool_enabled: true 后重试",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
 	// 获取命令
 	command, ok := args["command"].(string)
 	if !ok {
@@ -837,6 +1612,8 @@ func (e *Executor) executeSystemCommand(ctx context.Context, args map[string]int
 	} else {
 		cmd = exec.CommandContext(ctx, shell, "-c", command)
 	}
+	// exec 是风险最高的工具，紧急停止时必须能连坐杀掉 shell 派生的子进程，而不只是 shell 本身
+	applyProcessGroup(cmd)
 
 	// 执行命令
 	e.logger.Info("执行系统命令",
@@ -865,6 +1642,7 @@ func (e *Executor) executeSystemCommand(ctx context.Context, args map[string]int
 		} else {
 			pidCmd = exec.CommandContext(ctx, shell, "-c", pidCommand)
 		}
+		applyProcessGroup(pidCmd)
 
 		// 获取stdout管道
 		stdout, err := pidCmd.StdoutPipe()
@@ -971,34 +1749,21 @@ func (e *Executor) executeSystemCommand(ctx context.Context, args map[string]int
 		}, nil
 	}
 
-	// 非后台命令：等待输出
+	// 非后台命令：等待输出。边执行边流式读取，增量回调/上报进度并在配置了结果存储时同步落盘。
 	var output string
 	var err error
-	// 若上层提供工具输出增量回调，则边执行边流式读取。
-	if cb, ok := ctx.Value(ToolOutputCallbackCtxKey).(ToolOutputCallback); ok && cb != nil {
-		output, err = streamCommandOutput(cmd, cb)
-		if err != nil && shouldRetryWithPTY(output) {
-			e.logger.Info("检测到系统命令需要 TTY，使用 PTY 重试")
-			cmd2 := exec.CommandContext(ctx, shell, "-c", command)
-			if workDir != "" {
-				cmd2.Dir = workDir
-			}
-			applyDefaultTerminalEnv(cmd2)
-			output, err = runCommandWithPTY(ctx, cmd2, cb)
-		}
-	} else {
-		outputBytes, err2 := cmd.CombinedOutput()
-		output = string(outputBytes)
-		err = err2
-		if err != nil && shouldRetryWithPTY(output) {
-			e.logger.Info("检测到系统命令需要 TTY，使用 PTY 重试")
-			cmd2 := exec.CommandContext(ctx, shell, "-c", command)
-			if workDir != "" {
-				cmd2.Dir = workDir
-			}
-			applyDefaultTerminalEnv(cmd2)
-			output, err = runCommandWithPTY(ctx, cmd2, nil)
+	cb := e.executorOutputCallback(ctx, "exec")
+	maxBuf := e.maxOutputBufferBytes()
+	output, err = streamCommandOutput(cmd, cb, maxBuf)
+	if err != nil && shouldRetryWithPTY(output) {
+		e.logger.Info("检测到系统命令需要 TTY，使用 PTY 重试")
+		cmd2 := exec.CommandContext(ctx, shell, "-c", command)
+		if workDir != "" {
+			cmd2.Dir = workDir
 		}
+		applyDefaultTerminalEnv(cmd2)
+		applyProcessGroup(cmd2)
+		output, err = runCommandWithPTY(ctx, cmd2, cb, maxBuf)
 	}
 	if err != nil {
 		e.logger.Error("系统命令执行失败",
@@ -1033,9 +1798,29 @@ func (e *Executor) executeSystemCommand(ctx context.Context, args map[string]int
 	}, nil
 }
 
+// appendCapped 将 chunk 写入 outBuilder，累计超过 maxBytes（<=0 表示不限制）时不再继续写入，
+// 仅返回是否发生了截断；cb 收到的增量不受此限制，完整内容始终经 cb 流向结果存储。
+func appendCapped(outBuilder *strings.Builder, chunk string, maxBytes int) (truncated bool) {
+	if maxBytes <= 0 || outBuilder.Len() >= maxBytes {
+		return maxBytes > 0 && outBuilder.Len() >= maxBytes
+	}
+	remaining := maxBytes - outBuilder.Len()
+	if len(chunk) <= remaining {
+		outBuilder.WriteString(chunk)
+		return false
+	}
+	outBuilder.WriteString(chunk[:remaining])
+	return true
+}
+
+func truncationNotice(maxBytes int) string {
+	return fmt.Sprintf("\n...[输出过大，已截断显示；完整内容已写入结果存储，可通过 query_execution_result 工具查询（超过 %d 字节的部分未在此直接返回）]", maxBytes)
+}
+
 // streamCommandOutput 以“边读边回调”的方式读取命令 stdout/stderr。
-// 保持输出内容完整拼接返回，并用 cb(chunk) 向上层持续推送。
-func streamCommandOutput(cmd *exec.Cmd, cb ToolOutputCallback) (string, error) {
+// 用 cb(chunk) 向上层持续推送完整增量；返回值中的拼接内容在 maxBufferBytes>0 时按该上限截断，
+// 避免长时间运行、输出巨大的工具把完整内容一直攒在本次调用的内存里（完整内容由 cb 一侧落盘）。
+func streamCommandOutput(cmd *exec.Cmd, cb ToolOutputCallback, maxBufferBytes int) (string, error) {
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
 		return "", err
@@ -1080,6 +1865,7 @@ func streamCommandOutput(cmd *exec.Cmd, cb ToolOutputCallback) (string, error) {
 	var outBuilder strings.Builder
 	var deltaBuilder strings.Builder
 	lastFlush := time.Now()
+	truncated := false
 
 	flush := func() {
 		if deltaBuilder.Len() == 0 {
@@ -1091,7 +1877,9 @@ func streamCommandOutput(cmd *exec.Cmd, cb ToolOutputCallback) (string, error) {
 	}
 
 	for chunk := range chunks {
-		outBuilder.WriteString(chunk)
+		if appendCapped(&outBuilder, chunk, maxBufferBytes) {
+			truncated = true
+		}
 		deltaBuilder.WriteString(chunk)
 		// 简单节流：buffer 大于 2KB 或 200ms 就刷新一次
 		if deltaBuilder.Len() >= 2048 || time.Since(lastFlush) >= 200*time.Millisecond {
@@ -1102,7 +1890,11 @@ func streamCommandOutput(cmd *exec.Cmd, cb ToolOutputCallback) (string, error) {
 
 	// 等待命令结束，返回最终退出状态
 	waitErr := cmd.Wait()
-	return outBuilder.String(), waitErr
+	result := outBuilder.String()
+	if truncated {
+		result += truncationNotice(maxBufferBytes)
+	}
+	return result, waitErr
 }
 
 // applyDefaultTerminalEnv 为外部工具补齐常见的终端环境变量。
@@ -1153,12 +1945,12 @@ func shouldRetryWithPTY(output string) bool {
 }
 
 // runCommandWithPTY 为子进程分配 PTY，适配需要交互式终端的工具（如 autorecon）。
-// 若 cb != nil，将持续回调增量输出（用于 SSE）。
-func runCommandWithPTY(ctx context.Context, cmd *exec.Cmd, cb ToolOutputCallback) (string, error) {
+// 若 cb != nil，将持续回调增量输出（用于 SSE）；maxBufferBytes 语义与 streamCommandOutput 一致。
+func runCommandWithPTY(ctx context.Context, cmd *exec.Cmd, cb ToolOutputCallback, maxBufferBytes int) (string, error) {
 	if runtime.GOOS == "windows" {
 		// PTY 方案为类 Unix；Windows 走原逻辑
 		if cb != nil {
-			return streamCommandOutput(cmd, cb)
+			return streamCommandOutput(cmd, cb, maxBufferBytes)
 		}
 		out, err := cmd.CombinedOutput()
 		return string(out), err
@@ -1187,6 +1979,7 @@ func runCommandWithPTY(ctx context.Context, cmd *exec.Cmd, cb ToolOutputCallback
 	var outBuilder strings.Builder
 	var deltaBuilder strings.Builder
 	lastFlush := time.Now()
+	truncated := false
 	flush := func() {
 		if cb == nil || deltaBuilder.Len() == 0 {
 			deltaBuilder.Reset()
@@ -1206,7 +1999,9 @@ func runCommandWithPTY(ctx context.Context, cmd *exec.Cmd, cb ToolOutputCallback
 			// 统一换行为 \n，避免前端错位
 			chunk = strings.ReplaceAll(chunk, "\r\n", "\n")
 			chunk = strings.ReplaceAll(chunk, "\r", "\n")
-			outBuilder.WriteString(chunk)
+			if appendCapped(&outBuilder, chunk, maxBufferBytes) {
+				truncated = true
+			}
 			deltaBuilder.WriteString(chunk)
 			if deltaBuilder.Len() >= 2048 || time.Since(lastFlush) >= 200*time.Millisecond {
 				flush()
@@ -1219,7 +2014,11 @@ func runCommandWithPTY(ctx context.Context, cmd *exec.Cmd, cb ToolOutputCallback
 	flush()
 
 	waitErr := cmd.Wait()
-	return outBuilder.String(), waitErr
+	result := outBuilder.String()
+	if truncated {
+		result += truncationNotice(maxBufferBytes)
+	}
+	return result, waitErr
 }
 
 // executeInternalTool 执行内部工具（不执行外部命令）
@@ -1230,13 +2029,24 @@ func (e *Executor) executeInternalTool(ctx context.Context, toolName string, com
 	e.logger.Info("执行内部工具",
 		zap.String("toolName", toolName),
 		zap.String("internalToolType", internalToolType),
-		zap.Any("args", args),
+		zap.Any("args", mcp.MaskArguments(args, e.sensitiveParamNames(toolName))),
 	)
 
+	// 流水线工具（tools/pipelines/*.yaml 编译而来）以 "pipeline:<name>" 为内部类型，单独分发
+	if strings.HasPrefix(internalToolType, "pipeline:") {
+		return e.executePipeline(ctx, toolName, args)
+	}
+
 	// 根据内部工具类型分发处理
 	switch internalToolType {
 	case "query_execution_result":
 		return e.executeQueryExecutionResult(ctx, args)
+	case "http_request":
+		return e.executeHTTPRequest(ctx, args)
+	case "dns_lookup":
+		return e.executeDNSLookup(ctx, args)
+	case "whois_lookup":
+		return e.executeWhoisLookup(ctx, args)
 	default:
 		return &mcp.ToolResult{
 			Content: []mcp.Content{
@@ -1301,6 +2111,16 @@ func (e *Executor) executeQueryExecutionResult(ctx context.Context, args map[str
 		useRegex = r
 	}
 
+	extract := ""
+	if ex, ok := args["extract"].(string); ok {
+		extract = ex
+	}
+
+	maxMatches := 0
+	if mm, ok := args["max_matches"].(float64); ok {
+		maxMatches = int(mm)
+	}
+
 	// 检查结果存储是否可用
 	if e.resultStorage == nil {
 		return &mcp.ToolResult{
@@ -1318,7 +2138,22 @@ func (e *Executor) executeQueryExecutionResult(ctx context.Context, args map[str
 	var resultPage *storage.ResultPage
 	var err error
 
-	if search != "" {
+	if extract != "" {
+		// 正则提取模式：提取捕获组（或整个匹配）而非返回命中行，适合从大输出中拉取所有 URL/IP 等字段
+		matches, err := e.resultStorage.ExtractMatches(executionID, extract, maxMatches)
+		if err != nil {
+			return &mcp.ToolResult{
+				Content: []mcp.Content{
+					{
+						Type: "text",
+						Text: fmt.Sprintf("提取失败: %v", err),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+		resultPage = paginateLines(matches, page, limit)
+	} else if search != "" {
 		// 搜索模式
 		matchedLines, err := e.resultStorage.SearchResult(executionID, search, useRegex)
 		if err != nil {
@@ -1409,6 +2244,9 @@ func (e *Executor) executeQueryExecutionResult(ctx context.Context, args map[str
 				sb.WriteString(" (正则模式)")
 			}
 		}
+		if extract != "" {
+			sb.WriteString(fmt.Sprintf("，或使用 extract=\"%s\" 继续提取", extract))
+		}
 		sb.WriteString("\n")
 	}
 
@@ -1423,6 +2261,171 @@ func (e *Executor) executeQueryExecutionResult(ctx context.Context, args map[str
 	}, nil
 }
 
+// httpRequestResult 内置 http_request 工具的标准化返回结构，序列化为 JSON 后作为工具结果文本返回
+type httpRequestResult struct {
+	StatusCode    int               `json:"status_code"`
+	Status        string            `json:"status"`
+	Headers       map[string]string `json:"headers"`
+	Body          string            `json:"body"`
+	BodyTruncated bool              `json:"body_truncated,omitempty"`
+}
+
+// executeHTTPRequest 内置 HTTP 请求工具：支持方法、请求头、请求体、Cookie、重定向与代理选项，
+// 用 net/http 直接发起真实请求并把状态码/响应头/响应体标准化返回，替代大部分 Web 测试场景中
+// 依赖 exec 工具转义拼接 curl 命令行的脆弱做法。
+func (e *Executor) executeHTTPRequest(ctx context.Context, args map[string]interface{}) (*mcp.ToolResult, error) {
+	rawURL, _ := args["url"].(string)
+	if strings.TrimSpace(rawURL) == "" {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: "错误: url 参数必需且不能为空"}},
+			IsError: true,
+		}, nil
+	}
+
+	// 目标范围校验：http_request 属于内部工具，走的是与外部命令工具不同的分支，不会经过
+	// ExecuteTool 主流程里通用的 checkTargetScope，因此在此显式复用同一套校验逻辑。
+	if err := e.checkTargetScope(ctx, map[string]interface{}{"url": rawURL}); err != nil {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("错误: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	method := "GET"
+	if m, ok := args["method"].(string); ok && strings.TrimSpace(m) != "" {
+		method = strings.ToUpper(strings.TrimSpace(m))
+	}
+
+	var bodyReader io.Reader
+	if body, ok := args["body"].(string); ok && body != "" {
+		bodyReader = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bodyReader)
+	if err != nil {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("构建请求失败: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	if headers, ok := args["headers"].(map[string]interface{}); ok {
+		for name, value := range headers {
+			if s, ok := value.(string); ok {
+				req.Header.Set(name, s)
+			}
+		}
+	}
+
+	if cookies, ok := args["cookies"].(map[string]interface{}); ok && len(cookies) > 0 {
+		pairs := make([]string, 0, len(cookies))
+		for name, value := range cookies {
+			if s, ok := value.(string); ok {
+				pairs = append(pairs, fmt.Sprintf("%s=%s", name, s))
+			}
+		}
+		if len(pairs) > 0 {
+			req.Header.Set("Cookie", strings.Join(pairs, "; "))
+		}
+	}
+
+	followRedirects := true
+	if v, ok := args["follow_redirects"].(bool); ok {
+		followRedirects = v
+	}
+
+	maxRedirects := 10
+	if v, ok := args["max_redirects"].(float64); ok && v >= 0 {
+		maxRedirects = int(v)
+	}
+
+	transport := &http.Transport{}
+	if proxyStr, ok := args["proxy"].(string); ok && proxyStr != "" {
+		proxyURL, perr := url.Parse(proxyStr)
+		if perr != nil {
+			return &mcp.ToolResult{
+				Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("proxy 参数无效: %v", perr)}},
+				IsError: true,
+			}, nil
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	timeout := 30 * time.Second
+	if v, ok := args["timeout_seconds"].(float64); ok && v > 0 {
+		timeout = time.Duration(v) * time.Second
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+	if followRedirects {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("超过最大重定向次数 %d", maxRedirects)
+			}
+			return nil
+		}
+	} else {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	e.logger.Info("执行内置 http_request 工具",
+		zap.String("method", method),
+		zap.String("url", rawURL),
+		zap.Bool("followRedirects", followRedirects),
+	)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("请求失败: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+	defer resp.Body.Close()
+
+	maxBodyBytes := e.maxOutputBufferBytes()
+	bodyBytes, readErr := io.ReadAll(io.LimitReader(resp.Body, int64(maxBodyBytes)+1))
+	if readErr != nil {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("读取响应体失败: %v", readErr)}},
+			IsError: true,
+		}, nil
+	}
+	truncated := len(bodyBytes) > maxBodyBytes
+	if truncated {
+		bodyBytes = bodyBytes[:maxBodyBytes]
+	}
+
+	headers := make(map[string]string, len(resp.Header))
+	for name := range resp.Header {
+		headers[name] = resp.Header.Get(name)
+	}
+
+	resultJSON, err := json.Marshal(httpRequestResult{
+		StatusCode:    resp.StatusCode,
+		Status:        resp.Status,
+		Headers:       headers,
+		Body:          string(bodyBytes),
+		BodyTruncated: truncated,
+	})
+	if err != nil {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("序列化响应失败: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.ToolResult{
+		Content: []mcp.Content{{Type: "text", Text: string(resultJSON)}},
+		IsError: false,
+	}, nil
+}
+
 // paginateLines 对行列表进行分页
 func paginateLines(lines []string, page int, limit int) *storage.ResultPage {
 	totalLines := len(lines)
@@ -1515,6 +2518,8 @@ func (e *Executor) buildInputSchema(toolConfig *config.ToolConfig) map[string]in
 			}
 		}
 
+		e.addProfileSchemaProperty(toolConfig, properties)
+
 		schema["properties"] = properties
 		schema["required"] = required
 		return schema
@@ -1526,9 +2531,35 @@ func (e *Executor) buildInputSchema(toolConfig *config.ToolConfig) map[string]in
 	e.logger.Warn("工具未定义参数配置，返回空schema",
 		zap.String("tool", toolConfig.Name),
 	)
+	if len(toolConfig.Profiles) > 0 {
+		properties := make(map[string]interface{})
+		e.addProfileSchemaProperty(toolConfig, properties)
+		schema["properties"] = properties
+	}
 	return schema
 }
 
+// addProfileSchemaProperty 当工具配置了 Profiles（命名扫描档位）时，在 properties 中追加一个
+// profile 枚举参数，枚举取值为所有档位名；工具自身已声明同名参数时不覆盖，尊重工具作者的自定义描述。
+func (e *Executor) addProfileSchemaProperty(toolConfig *config.ToolConfig, properties map[string]interface{}) {
+	if len(toolConfig.Profiles) == 0 {
+		return
+	}
+	if _, exists := properties["profile"]; exists {
+		return
+	}
+	profileNames := make([]string, 0, len(toolConfig.Profiles))
+	for name := range toolConfig.Profiles {
+		profileNames = append(profileNames, name)
+	}
+	sort.Strings(profileNames)
+	properties["profile"] = map[string]interface{}{
+		"type":        "string",
+		"description": "预设扫描档位，选择后自动套用该档位预置的参数值（显式传入的同名参数优先于档位预置值）",
+		"enum":        profileNames,
+	}
+}
+
 // convertToOpenAIType 将配置中的类型转换为OpenAI/JSON Schema标准类型
 func (e *Executor) convertToOpenAIType(configType string) string {
 	// 空或 null 类型统一视为 string，避免非法 schema 导致工具调用失败
@@ -207,6 +207,7 @@ func TestExecutor_ExecuteInternalTool_NoStorage(t *testing.T) {
 
 func TestExecuteSystemCommand_BackgroundDoesNotBlockOnChildStdout(t *testing.T) {
 	executor, _ := setupTestExecutor(t)
+	executor.config.ExecToolEnabled = true
 	// 子进程先向 stdout 写无换行字符再长时间 sleep；若与 echo $pid 共享管道且未重定向子进程 stdout，
 	// ReadString('\n') 会阻塞到子进程退出。后台包装须将子进程标准流与 PID 行分离。
 	ctx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
@@ -228,6 +229,235 @@ func TestExecuteSystemCommand_BackgroundDoesNotBlockOnChildStdout(t *testing.T)
 	}
 }
 
+func TestExecuteSystemCommand_DisabledByDefault(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	res, err := executor.executeSystemCommand(context.Background(), map[string]interface{}{"command": "echo hi"})
+	if err != nil {
+		t.Fatalf("executeSystemCommand: %v", err)
+	}
+	if res == nil || !res.IsError {
+		t.Fatalf("expected error result when exec tool disabled, got %+v", res)
+	}
+	if !strings.Contains(res.Content[0].Text, "未启用") {
+		t.Fatalf("unexpected body: %q", res.Content[0].Text)
+	}
+}
+
+func TestValidateParamValues(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+
+	if err := executor.validateParamValues("nmap", map[string]interface{}{"target": "example.com"}); err != nil {
+		t.Fatalf("expected clean value to pass, got %v", err)
+	}
+
+	if err := executor.validateParamValues("nmap", map[string]interface{}{"target": "example.com; rm -rf /"}); err == nil {
+		t.Fatal("expected shell metacharacter to be rejected")
+	}
+
+	if err := executor.validateParamValues("nmap", map[string]interface{}{"targets": []interface{}{"a.com", "b.com`whoami`"}}); err == nil {
+		t.Fatal("expected shell metacharacter in array element to be rejected")
+	}
+
+	executor.config.MaxParamValueLength = 4
+	if err := executor.validateParamValues("nmap", map[string]interface{}{"target": "toolong"}); err == nil {
+		t.Fatal("expected overlength value to be rejected")
+	}
+
+	// exec 工具的 command 本身就是要执行的 shell 命令，不受此校验约束
+	if err := executor.validateParamValues("exec", map[string]interface{}{"command": "echo a; echo b"}); err != nil {
+		t.Fatalf("exec tool should be exempt from validation, got %v", err)
+	}
+}
+
+func TestAcquireExecutionSlot_GlobalLimit(t *testing.T) {
+	logger := zap.NewNop()
+	mcpServer := mcp.NewServer(logger)
+	cfg := &config.SecurityConfig{MaxConcurrentExecutions: 1}
+	executor := NewExecutor(cfg, mcpServer, logger)
+
+	release1, err := executor.acquireExecutionSlot(context.Background(), "toolA")
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := executor.acquireExecutionSlot(ctx, "toolA"); err == nil {
+		t.Fatal("expected second acquire to be blocked by global limit until timeout")
+	}
+
+	release1()
+
+	release2, err := executor.acquireExecutionSlot(context.Background(), "toolA")
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	release2()
+}
+
+func TestAcquireExecutionSlot_PerToolLimit(t *testing.T) {
+	logger := zap.NewNop()
+	mcpServer := mcp.NewServer(logger)
+	cfg := &config.SecurityConfig{
+		Tools: []config.ToolConfig{
+			{Name: "nmap", Enabled: true, MaxConcurrency: 1},
+			{Name: "gobuster", Enabled: true},
+		},
+	}
+	executor := NewExecutor(cfg, mcpServer, logger)
+
+	releaseNmap, err := executor.acquireExecutionSlot(context.Background(), "nmap")
+	if err != nil {
+		t.Fatalf("acquire nmap: %v", err)
+	}
+	defer releaseNmap()
+
+	// 不同工具的信号量相互独立，不受 nmap 已占满的影响
+	releaseGobuster, err := executor.acquireExecutionSlot(context.Background(), "gobuster")
+	if err != nil {
+		t.Fatalf("acquire gobuster: %v", err)
+	}
+	releaseGobuster()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := executor.acquireExecutionSlot(ctx, "nmap"); err == nil {
+		t.Fatal("expected second nmap acquire to be blocked by per-tool limit")
+	}
+}
+
+func TestAcquireTargetRateLimit_ConcurrencyLimit(t *testing.T) {
+	logger := zap.NewNop()
+	mcpServer := mcp.NewServer(logger)
+	cfg := &config.SecurityConfig{
+		TargetRateLimit: &config.TargetRateLimitConfig{MaxConcurrentPerTarget: 1},
+	}
+	executor := NewExecutor(cfg, mcpServer, logger)
+
+	args := map[string]interface{}{"target": "example.com"}
+	release1, err := executor.acquireTargetRateLimit(context.Background(), args)
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := executor.acquireTargetRateLimit(ctx, args); err == nil {
+		t.Fatal("同一目标的第二次调用应该被并发上限阻塞直至超时")
+	}
+
+	// 不同目标互不影响
+	otherArgs := map[string]interface{}{"target": "other.com"}
+	releaseOther, err := executor.acquireTargetRateLimit(context.Background(), otherArgs)
+	if err != nil {
+		t.Fatalf("acquire other target: %v", err)
+	}
+	releaseOther()
+
+	release1()
+
+	release2, err := executor.acquireTargetRateLimit(context.Background(), args)
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	release2()
+}
+
+func TestAcquireTargetRateLimit_MinDelay(t *testing.T) {
+	logger := zap.NewNop()
+	mcpServer := mcp.NewServer(logger)
+	cfg := &config.SecurityConfig{
+		TargetRateLimit: &config.TargetRateLimitConfig{MinDelaySeconds: 0.2},
+	}
+	executor := NewExecutor(cfg, mcpServer, logger)
+
+	args := map[string]interface{}{"target": "example.com"}
+
+	release1, err := executor.acquireTargetRateLimit(context.Background(), args)
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+	release1()
+
+	start := time.Now()
+	release2, err := executor.acquireTargetRateLimit(context.Background(), args)
+	if err != nil {
+		t.Fatalf("second acquire: %v", err)
+	}
+	release2()
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("第二次调用应该等待冷却时间，实际只等待了 %v", elapsed)
+	}
+}
+
+func TestAcquireTargetRateLimit_NoConfig(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+
+	release, err := executor.acquireTargetRateLimit(context.Background(), map[string]interface{}{"target": "example.com"})
+	if err != nil {
+		t.Fatalf("未配置限流时不应报错: %v", err)
+	}
+	release()
+}
+
+func TestFirstTargetValue(t *testing.T) {
+	if got := firstTargetValue(map[string]interface{}{"target": "Example.COM"}); got != "example.com" {
+		t.Errorf("应返回小写化的目标值，实际: %s", got)
+	}
+	if got := firstTargetValue(map[string]interface{}{"other": "x"}); got != "" {
+		t.Errorf("不包含目标字段时应返回空字符串，实际: %s", got)
+	}
+	if got := firstTargetValue(map[string]interface{}{"target": "a.com", "host": "b.com"}); got != "a.com" {
+		t.Errorf("同时存在多个目标字段时应按优先级取值，期望a.com，实际: %s", got)
+	}
+}
+
+// TestExecuteTool_Paused_RejectsAllCalls 验证 Pause 后 ExecuteTool 拒绝一切新调用（包括 internal 工具），
+// Resume 后恢复正常
+func TestExecuteTool_Paused_RejectsAllCalls(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+
+	toolConfig := config.ToolConfig{
+		Name:    "query_execution_result",
+		Command: "internal:query_execution_result",
+		Enabled: true,
+	}
+	executor.config.Tools = append(executor.config.Tools, toolConfig)
+	executor.toolIndex["query_execution_result"] = &executor.config.Tools[len(executor.config.Tools)-1]
+
+	if executor.IsPaused() {
+		t.Fatal("初始状态不应处于暂停")
+	}
+
+	executor.Pause()
+	if !executor.IsPaused() {
+		t.Fatal("Pause 后 IsPaused 应返回 true")
+	}
+
+	result, err := executor.ExecuteTool(context.Background(), "query_execution_result", map[string]interface{}{"execution_id": "x"})
+	if err != nil {
+		t.Fatalf("暂停期间调用不应返回 error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("暂停期间应拒绝执行并返回错误结果")
+	}
+	if !strings.Contains(result.Content[0].Text, "紧急停止") {
+		t.Errorf("错误消息应说明处于紧急停止状态，实际: %s", result.Content[0].Text)
+	}
+
+	executor.Resume()
+	if executor.IsPaused() {
+		t.Fatal("Resume 后 IsPaused 应返回 false")
+	}
+	result2, err := executor.ExecuteTool(context.Background(), "query_execution_result", map[string]interface{}{"execution_id": "x"})
+	if err != nil {
+		t.Fatalf("恢复后调用不应返回 error: %v", err)
+	}
+	if strings.Contains(result2.Content[0].Text, "紧急停止") {
+		t.Errorf("恢复后不应再被紧急停止拦截，实际: %s", result2.Content[0].Text)
+	}
+}
+
 func TestPaginateLines(t *testing.T) {
 	lines := []string{"Line 1", "Line 2", "Line 3", "Line 4", "Line 5"}
 
@@ -0,0 +1,104 @@
+package security
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ToolHealth 描述单个工具的可用性检查结果，见 Executor.CheckToolsHealth。
+type ToolHealth struct {
+	Name      string `json:"name"`
+	Command   string `json:"command"`
+	Available bool   `json:"available"`
+	Path      string `json:"path,omitempty"`    // exec.LookPath 解析到的可执行文件绝对路径
+	Version   string `json:"version,omitempty"` // 尝试执行 --version 捕获到的第一行输出，取不到时留空
+	Error     string `json:"error,omitempty"`   // Available 为 false 时的原因
+}
+
+// toolVersionProbeTimeout 探测单个工具版本号的超时时间，避免个别工具的 --version 挂起拖慢整体检查。
+const toolVersionProbeTimeout = 5 * time.Second
+
+// CheckToolsHealth 遍历所有已配置的工具，对每个走宿主机 exec 的工具（跳过 "internal:" 内部工具与
+// PipelineSteps 编译出的流水线工具，两者均无独立可执行文件）用 exec.LookPath 检查其 Command 是否
+// 存在于 PATH 中，并尽力执行一次 "--version" 捕获版本号（失败不视为不可用，仅版本号留空）。
+// 不修改任何工具的 Enabled 状态，纯查询；自动禁用见 RunStartupHealthCheckAndDisable。
+func (e *Executor) CheckToolsHealth(ctx context.Context) []ToolHealth {
+	results := make([]ToolHealth, 0, len(e.config.Tools))
+	for i := range e.config.Tools {
+		toolConfig := &e.config.Tools[i]
+		if strings.HasPrefix(toolConfig.Command, "internal:") || len(toolConfig.PipelineSteps) > 0 {
+			continue
+		}
+		results = append(results, probeToolHealth(ctx, toolConfig.Name, toolConfig.Command))
+	}
+	return results
+}
+
+// probeToolHealth 对单个命令做一次可用性 + 版本号探测。
+func probeToolHealth(ctx context.Context, name, command string) ToolHealth {
+	health := ToolHealth{Name: name, Command: command}
+
+	path, err := exec.LookPath(command)
+	if err != nil {
+		health.Available = false
+		health.Error = "在 PATH 中未找到可执行文件: " + err.Error()
+		return health
+	}
+	health.Available = true
+	health.Path = path
+
+	probeCtx, cancel := context.WithTimeout(ctx, toolVersionProbeTimeout)
+	defer cancel()
+	out, _ := exec.CommandContext(probeCtx, command, "--version").CombinedOutput()
+	if firstLine := strings.TrimSpace(firstNonEmptyLine(string(out))); firstLine != "" {
+		health.Version = firstLine
+	}
+	return health
+}
+
+// firstNonEmptyLine 返回文本中第一个非空行，用于从 --version 输出中截取简短版本号，避免把整段
+// 帮助文本都塞进健康检查结果里。
+func firstNonEmptyLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
+
+// RunStartupHealthCheckAndDisable 在服务启动时调用一次：探测所有工具的可用性，把宿主机上确实
+// 找不到可执行文件的工具标记为 Enabled=false 并重建工具索引，避免 Agent 的工具列表里出现一个
+// 调用即失败的"僵尸工具"。已经被配置禁用的工具不受影响；结果同时返回供调用方打日志/落库。
+func (e *Executor) RunStartupHealthCheckAndDisable(ctx context.Context) []ToolHealth {
+	results := e.CheckToolsHealth(ctx)
+
+	unavailable := make(map[string]bool, len(results))
+	for _, health := range results {
+		if !health.Available {
+			unavailable[health.Name] = true
+		}
+	}
+	if len(unavailable) == 0 {
+		return results
+	}
+
+	disabled := make([]string, 0, len(unavailable))
+	for i := range e.config.Tools {
+		if e.config.Tools[i].Enabled && unavailable[e.config.Tools[i].Name] {
+			e.config.Tools[i].Enabled = false
+			disabled = append(disabled, e.config.Tools[i].Name)
+		}
+	}
+	if len(disabled) > 0 {
+		e.buildToolIndex()
+		e.logger.Warn("启动健康检查发现工具不可用，已自动禁用",
+			zap.Strings("tools", disabled),
+		)
+	}
+	return results
+}
@@ -0,0 +1,65 @@
+package security
+
+import (
+	"context"
+	"testing"
+
+	"cyberstrike-ai/internal/config"
+)
+
+// TestCheckToolsHealth_SkipsInternalAndFindsMissingCommand 验证健康检查跳过 internal: 工具，
+// 并能识别出 PATH 中不存在的命令
+func TestCheckToolsHealth_SkipsInternalAndFindsMissingCommand(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	executor.config.Tools = []config.ToolConfig{
+		{Name: "internal_tool", Command: "internal:query_execution_result", Enabled: true},
+		{Name: "echo_tool", Command: "echo", Enabled: true},
+		{Name: "missing_tool", Command: "definitely_not_a_real_command_xyz", Enabled: true},
+	}
+
+	results := executor.CheckToolsHealth(context.Background())
+	if len(results) != 2 {
+		t.Fatalf("应跳过 internal 工具，只探测2个工具，实际: %d", len(results))
+	}
+
+	byName := make(map[string]ToolHealth)
+	for _, h := range results {
+		byName[h.Name] = h
+	}
+
+	if !byName["echo_tool"].Available {
+		t.Errorf("echo 应在 PATH 中可用，实际: %+v", byName["echo_tool"])
+	}
+	if byName["missing_tool"].Available {
+		t.Errorf("不存在的命令应标记为不可用")
+	}
+	if byName["missing_tool"].Error == "" {
+		t.Errorf("不可用的工具应附带错误说明")
+	}
+}
+
+// TestRunStartupHealthCheckAndDisable_DisablesMissingTools 验证启动健康检查会自动禁用宿主机上
+// 找不到可执行文件的工具，并把它从工具索引中移除
+func TestRunStartupHealthCheckAndDisable_DisablesMissingTools(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	executor.config.Tools = []config.ToolConfig{
+		{Name: "echo_tool", Command: "echo", Enabled: true},
+		{Name: "missing_tool", Command: "definitely_not_a_real_command_xyz", Enabled: true},
+	}
+	executor.buildToolIndex()
+
+	executor.RunStartupHealthCheckAndDisable(context.Background())
+
+	if executor.config.Tools[0].Enabled != true {
+		t.Errorf("可用的工具不应被禁用")
+	}
+	if executor.config.Tools[1].Enabled {
+		t.Errorf("不可用的工具应被自动禁用")
+	}
+	if _, ok := executor.toolIndex["missing_tool"]; ok {
+		t.Errorf("被禁用的工具不应仍留在工具索引中")
+	}
+	if _, ok := executor.toolIndex["echo_tool"]; !ok {
+		t.Errorf("可用的工具应仍留在工具索引中")
+	}
+}
@@ -0,0 +1,92 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExecuteHTTPRequest_Success(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test") != "1" {
+			t.Errorf("expected header X-Test=1, got %q", r.Header.Get("X-Test"))
+		}
+		if got := r.Header.Get("Cookie"); !strings.Contains(got, "session=abc") {
+			t.Errorf("expected Cookie to contain session=abc, got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer ts.Close()
+
+	executor, _ := setupTestExecutor(t)
+	args := map[string]interface{}{
+		"url":     ts.URL,
+		"method":  "get",
+		"headers": map[string]interface{}{"X-Test": "1"},
+		"cookies": map[string]interface{}{"session": "abc"},
+	}
+
+	res, err := executor.executeHTTPRequest(context.Background(), args)
+	if err != nil {
+		t.Fatalf("executeHTTPRequest: %v", err)
+	}
+	if res.IsError {
+		t.Fatalf("expected success, got error: %s", res.Content[0].Text)
+	}
+
+	var parsed httpRequestResult
+	if err := json.Unmarshal([]byte(res.Content[0].Text), &parsed); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if parsed.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", parsed.StatusCode)
+	}
+	if !strings.Contains(parsed.Body, `"ok":true`) {
+		t.Errorf("unexpected body: %q", parsed.Body)
+	}
+}
+
+func TestExecuteHTTPRequest_MissingURL(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	res, err := executor.executeHTTPRequest(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("executeHTTPRequest: %v", err)
+	}
+	if !res.IsError {
+		t.Fatal("expected error result when url is missing")
+	}
+}
+
+func TestExecuteHTTPRequest_NoFollowRedirects(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/target", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	executor, _ := setupTestExecutor(t)
+	args := map[string]interface{}{
+		"url":              ts.URL,
+		"follow_redirects": false,
+	}
+
+	res, err := executor.executeHTTPRequest(context.Background(), args)
+	if err != nil {
+		t.Fatalf("executeHTTPRequest: %v", err)
+	}
+	if res.IsError {
+		t.Fatalf("expected success, got error: %s", res.Content[0].Text)
+	}
+
+	var parsed httpRequestResult
+	if err := json.Unmarshal([]byte(res.Content[0].Text), &parsed); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if parsed.StatusCode != http.StatusFound {
+		t.Errorf("expected status 302 when redirects not followed, got %d", parsed.StatusCode)
+	}
+}
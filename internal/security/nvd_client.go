@@ -0,0 +1,120 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// defaultNVDBaseURL 是 NVD REST API v2.0 的默认地址，未配置 nvd.base_url 时使用。
+const defaultNVDBaseURL = "https://services.nvd.nist.gov/rest/json/cves/2.0"
+
+// NVDClient 通过 NVD REST API v2.0 查询单个 CVE 的详情，实现 CVELookupClient。
+type NVDClient struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+}
+
+// NewNVDClient 创建 NVDClient；baseURL 为空时使用官方默认地址，apiKey 为空时以匿名方式请求
+// （匿名请求受 NVD 更严格的限速，因此强烈建议在生产环境配置 API Key）。
+func NewNVDClient(baseURL, apiKey string, httpClient *http.Client) *NVDClient {
+	if baseURL == "" {
+		baseURL = defaultNVDBaseURL
+	}
+	return &NVDClient{
+		httpClient: httpClient,
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+	}
+}
+
+// nvdAPIResponse 只解析富化所需的字段，忽略 NVD 返回中的其余元数据。
+type nvdAPIResponse struct {
+	Vulnerabilities []struct {
+		CVE struct {
+			ID           string `json:"id"`
+			Descriptions []struct {
+				Lang  string `json:"lang"`
+				Value string `json:"value"`
+			} `json:"descriptions"`
+			Metrics struct {
+				CvssMetricV31 []struct {
+					CvssData struct {
+						VectorString string  `json:"vectorString"`
+						BaseScore    float64 `json:"baseScore"`
+					} `json:"cvssData"`
+				} `json:"cvssMetricV31"`
+			} `json:"metrics"`
+			Weaknesses []struct {
+				Description []struct {
+					Lang  string `json:"lang"`
+					Value string `json:"value"`
+				} `json:"description"`
+			} `json:"weaknesses"`
+			CisaExploitAdd string `json:"cisaExploitAdd"` // 非空表示已被 CISA KEV 目录收录
+		} `json:"cve"`
+	} `json:"vulnerabilities"`
+}
+
+// LookupCVE 实现 CVELookupClient，查询单个 CVE 编号的详情。
+func (c *NVDClient) LookupCVE(ctx context.Context, cveID string) (*CVERecord, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"?cveId="+cveID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("构造NVD请求失败: %w", err)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("apiKey", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求NVD API失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("NVD API返回非200状态码: %d", resp.StatusCode)
+	}
+
+	var apiResp nvdAPIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("解析NVD响应失败: %w", err)
+	}
+	if len(apiResp.Vulnerabilities) == 0 {
+		return nil, fmt.Errorf("NVD未收录该CVE编号: %s", cveID)
+	}
+
+	cve := apiResp.Vulnerabilities[0].CVE
+	record := &CVERecord{
+		ID:             cve.ID,
+		KnownExploited: strings.TrimSpace(cve.CisaExploitAdd) != "",
+	}
+
+	for _, d := range cve.Descriptions {
+		if d.Lang == "en" {
+			record.Description = d.Value
+			break
+		}
+	}
+	if record.Description == "" && len(cve.Descriptions) > 0 {
+		record.Description = cve.Descriptions[0].Value
+	}
+
+	if len(cve.Metrics.CvssMetricV31) > 0 {
+		record.CVSSVector = cve.Metrics.CvssMetricV31[0].CvssData.VectorString
+		record.CVSSScore = cve.Metrics.CvssMetricV31[0].CvssData.BaseScore
+	}
+
+	for _, w := range cve.Weaknesses {
+		for _, d := range w.Description {
+			if strings.HasPrefix(d.Value, "CWE-") {
+				record.CWEIDs = append(record.CWEIDs, d.Value)
+			}
+		}
+	}
+
+	return record, nil
+}
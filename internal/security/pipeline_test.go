@@ -0,0 +1,129 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cyberstrike-ai/internal/config"
+	"cyberstrike-ai/internal/mcp"
+
+	"go.uber.org/zap"
+)
+
+func TestRenderPipelineParams(t *testing.T) {
+	params := map[string]interface{}{
+		"domain":  "{{input.domain}}",
+		"target":  "{{steps.discover.output}}",
+		"unknown": "{{steps.missing.output}}",
+		"count":   5,
+	}
+	input := map[string]interface{}{"domain": "example.com"}
+	stepOutputs := map[string]string{"discover": "sub1.example.com\nsub2.example.com"}
+
+	rendered := renderPipelineParams(params, input, stepOutputs)
+
+	if rendered["domain"] != "example.com" {
+		t.Errorf("expected domain=example.com, got %v", rendered["domain"])
+	}
+	if rendered["target"] != "sub1.example.com\nsub2.example.com" {
+		t.Errorf("unexpected target: %v", rendered["target"])
+	}
+	if rendered["unknown"] != "{{steps.missing.output}}" {
+		t.Errorf("expected unresolved placeholder to pass through unchanged, got %v", rendered["unknown"])
+	}
+	if rendered["count"] != 5 {
+		t.Errorf("expected non-string value to pass through unchanged, got %v", rendered["count"])
+	}
+}
+
+func newPipelineTestExecutor(t *testing.T, tools []config.ToolConfig) *Executor {
+	logger := zap.NewNop()
+	mcpServer := mcp.NewServer(logger)
+	cfg := &config.SecurityConfig{Tools: tools}
+	return NewExecutor(cfg, mcpServer, logger)
+}
+
+func TestExecutePipeline_TwoSteps(t *testing.T) {
+	ts1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello-from-step-one"))
+	}))
+	defer ts1.Close()
+
+	var receivedHeader string
+	ts2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedHeader = r.Header.Get("X-Prev")
+		_, _ = w.Write([]byte("done"))
+	}))
+	defer ts2.Close()
+
+	tools := []config.ToolConfig{
+		{Name: "step_tool", Command: "internal:http_request", Enabled: true},
+		(config.PipelineConfig{
+			Name:    "test_pipeline",
+			Enabled: true,
+			Steps: []config.PipelineStep{
+				{Name: "first", Tool: "step_tool", Params: map[string]interface{}{"url": ts1.URL}},
+				{Name: "second", Tool: "step_tool", Params: map[string]interface{}{
+					"url":     ts2.URL,
+					"headers": map[string]interface{}{"X-Prev": "{{steps.first.output}}"},
+				}},
+			},
+		}).ToToolConfig(),
+	}
+
+	executor := newPipelineTestExecutor(t, tools)
+	result, err := executor.ExecuteTool(context.Background(), "test_pipeline", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("ExecuteTool: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected success, got error: %s", result.Content[0].Text)
+	}
+	if !strings.Contains(receivedHeader, "hello-from-step-one") {
+		t.Errorf("expected second step to receive first step's output, got header %q", receivedHeader)
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &parsed); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	steps, ok := parsed["steps"].([]interface{})
+	if !ok || len(steps) != 2 {
+		t.Fatalf("expected 2 step summaries, got %v", parsed["steps"])
+	}
+}
+
+func TestExecutePipeline_AbortsOnStepError(t *testing.T) {
+	tools := []config.ToolConfig{
+		{Name: "step_tool", Command: "internal:http_request", Enabled: true},
+		(config.PipelineConfig{
+			Name:    "test_pipeline_abort",
+			Enabled: true,
+			Steps: []config.PipelineStep{
+				{Name: "first", Tool: "step_tool", Params: map[string]interface{}{}}, // 缺少 url，必然失败
+				{Name: "second", Tool: "step_tool", Params: map[string]interface{}{"url": "http://127.0.0.1:0"}},
+			},
+		}).ToToolConfig(),
+	}
+
+	executor := newPipelineTestExecutor(t, tools)
+	result, err := executor.ExecuteTool(context.Background(), "test_pipeline_abort", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("ExecuteTool: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected pipeline to report error when a step fails")
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &parsed); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if parsed["aborted_at_step"] != "first" {
+		t.Errorf("expected aborted_at_step=first, got %v", parsed["aborted_at_step"])
+	}
+}
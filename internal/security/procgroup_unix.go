@@ -0,0 +1,29 @@
+//go:build !windows
+
+package security
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// applyProcessGroup 让命令在自己独立的进程组中运行，并把 ctx 取消时的默认行为从
+// exec.CommandContext 只 kill 直接子进程，改为 kill 整个进程组（负 pid），使 nmap、
+// masscan 等工具内部 fork 出的子/孙进程也能在全局紧急停止（见 MonitorHandler.EmergencyStop）
+// 或单次执行取消时被一并终止，而不是残留成孤儿进程继续跑。
+func applyProcessGroup(cmd *exec.Cmd) {
+	if cmd == nil {
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+		// 对进程组（负 pid）发送 SIGKILL，覆盖 exec.CommandContext 默认只 kill 单个进程的行为
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package security
+
+import "os/exec"
+
+// applyProcessGroup 在 Windows 下无对应的 pgid/kill(-pid) 机制，退回 exec.CommandContext 的
+// 默认取消行为（只 kill 直接子进程）。
+func applyProcessGroup(cmd *exec.Cmd) {}
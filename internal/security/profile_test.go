@@ -0,0 +1,183 @@
+package security
+
+import (
+	"context"
+	"testing"
+
+	"cyberstrike-ai/internal/config"
+)
+
+// TestBuildInputSchema_ProfileEnum 验证配置了 Profiles 的工具会自动获得一个
+// profile 枚举参数，枚举取值为排序后的档位名
+func TestBuildInputSchema_ProfileEnum(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+
+	toolConfig := &config.ToolConfig{
+		Name:    "test_scan",
+		Command: "test_scan",
+		Parameters: []config.ParameterConfig{
+			{Name: "target", Type: "string", Description: "目标", Required: true},
+		},
+		Profiles: map[string]map[string]interface{}{
+			"quick":    {"ports": "80,443"},
+			"deep":     {"ports": "1-65535"},
+			"standard": {"ports": "1-1000"},
+		},
+	}
+
+	schema := executor.buildInputSchema(toolConfig)
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		t.Fatal("schema应该包含properties")
+	}
+
+	profileProp, ok := properties["profile"].(map[string]interface{})
+	if !ok {
+		t.Fatal("配置了Profiles的工具应该自动生成profile参数")
+	}
+
+	enumValues, ok := profileProp["enum"].([]string)
+	if !ok {
+		t.Fatal("profile参数应该包含enum")
+	}
+
+	expected := []string{"deep", "quick", "standard"}
+	if len(enumValues) != len(expected) {
+		t.Fatalf("enum数量不符，期望%v，实际%v", expected, enumValues)
+	}
+	for i, v := range expected {
+		if enumValues[i] != v {
+			t.Errorf("enum应该按字母序排列，期望%v，实际%v", expected, enumValues)
+			break
+		}
+	}
+}
+
+// TestBuildInputSchema_ProfileEnum_RespectsExplicitParam 验证工具已自行声明
+// 同名 profile 参数时，不会被自动生成的枚举覆盖
+func TestBuildInputSchema_ProfileEnum_RespectsExplicitParam(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+
+	toolConfig := &config.ToolConfig{
+		Name:    "test_scan",
+		Command: "test_scan",
+		Parameters: []config.ParameterConfig{
+			{Name: "profile", Type: "string", Description: "自定义档位说明"},
+		},
+		Profiles: map[string]map[string]interface{}{
+			"quick": {"ports": "80,443"},
+		},
+	}
+
+	schema := executor.buildInputSchema(toolConfig)
+	properties := schema["properties"].(map[string]interface{})
+	profileProp := properties["profile"].(map[string]interface{})
+
+	if profileProp["description"] != "自定义档位说明" {
+		t.Error("工具自行声明的profile参数不应被自动生成的枚举覆盖")
+	}
+	if _, hasEnum := profileProp["enum"]; hasEnum {
+		t.Error("工具自行声明的profile参数不应被追加enum")
+	}
+}
+
+// TestExecuteTool_ProfileMergesPresetArgs 验证ExecuteTool会将档位预置参数合并进args，
+// 且显式传入的同名参数优先于档位预置值；profile本身应在合并后被移除
+func TestExecuteTool_ProfileMergesPresetArgs(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	testStorage := setupTestStorage(t)
+	executor.SetResultStorage(testStorage)
+
+	executionID := "profile_test_exec"
+	if err := testStorage.SaveResult(executionID, "test_tool", "line1\nline2\nline3"); err != nil {
+		t.Fatalf("保存测试结果失败: %v", err)
+	}
+
+	toolConfig := config.ToolConfig{
+		Name:    "query_execution_result",
+		Command: "internal:query_execution_result",
+		Enabled: true,
+		Profiles: map[string]map[string]interface{}{
+			"quick": {"limit": float64(1), "page": float64(1)},
+		},
+	}
+	executor.config.Tools = append(executor.config.Tools, toolConfig)
+	executor.toolIndex["query_execution_result"] = &executor.config.Tools[len(executor.config.Tools)-1]
+
+	ctx := context.Background()
+
+	// 未显式传入limit/page，应套用quick档位的预置值
+	args := map[string]interface{}{
+		"execution_id": executionID,
+		"profile":      "quick",
+	}
+	result, err := executor.ExecuteTool(ctx, "query_execution_result", args)
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("应该执行成功，但返回了错误: %s", result.Content[0].Text)
+	}
+	if _, stillPresent := args["profile"]; stillPresent {
+		t.Error("profile参数应该在合并后被移除，避免误传给下游逻辑")
+	}
+
+	// 显式传入page，应优先于档位预置值（档位预置page=1，显式传入page=2）
+	args2 := map[string]interface{}{
+		"execution_id": executionID,
+		"profile":      "quick",
+		"page":         float64(2),
+	}
+	result2, err := executor.ExecuteTool(ctx, "query_execution_result", args2)
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if result2.IsError {
+		t.Fatalf("应该执行成功，但返回了错误: %s", result2.Content[0].Text)
+	}
+	if args2["page"] != float64(2) {
+		t.Errorf("显式传入的page应该优先于档位预置值，期望2，实际%v", args2["page"])
+	}
+}
+
+// TestExecuteTool_UnknownProfileWarnsAndContinues 验证传入不存在的档位名时不会中断执行，
+// 只是忽略该档位（工具应仍能正常执行）
+func TestExecuteTool_UnknownProfileWarnsAndContinues(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	testStorage := setupTestStorage(t)
+	executor.SetResultStorage(testStorage)
+
+	executionID := "profile_test_exec_unknown"
+	if err := testStorage.SaveResult(executionID, "test_tool", "line1\nline2"); err != nil {
+		t.Fatalf("保存测试结果失败: %v", err)
+	}
+
+	toolConfig := config.ToolConfig{
+		Name:    "query_execution_result",
+		Command: "internal:query_execution_result",
+		Enabled: true,
+		Profiles: map[string]map[string]interface{}{
+			"quick": {"limit": float64(1)},
+		},
+	}
+	executor.config.Tools = append(executor.config.Tools, toolConfig)
+	executor.toolIndex["query_execution_result"] = &executor.config.Tools[len(executor.config.Tools)-1]
+
+	ctx := context.Background()
+	args := map[string]interface{}{
+		"execution_id": executionID,
+		"profile":      "nonexistent_profile",
+		"page":         float64(1),
+	}
+
+	result, err := executor.ExecuteTool(ctx, "query_execution_result", args)
+	if err != nil {
+		t.Fatalf("未知档位不应导致error返回: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("未知档位应该被忽略而不是导致工具执行失败: %s", result.Content[0].Text)
+	}
+	if _, stillPresent := args["profile"]; stillPresent {
+		t.Error("即便档位未知，profile参数也应该被移除")
+	}
+}
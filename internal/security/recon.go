@@ -0,0 +1,509 @@
+package security
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"cyberstrike-ai/internal/mcp"
+
+	"go.uber.org/zap"
+)
+
+// executeDNSLookup 内置 DNS 查询工具：A/AAAA/MX/TXT/NS/CNAME 基于标准库 net.Resolver 实现；
+// AXFR（区域传送尝试）基于手写的最小 DNS-over-TCP 客户端（见 attemptAXFR），全部不依赖宿主机安装 dig。
+func (e *Executor) executeDNSLookup(ctx context.Context, args map[string]interface{}) (*mcp.ToolResult, error) {
+	domain, _ := args["domain"].(string)
+	domain = strings.TrimSpace(domain)
+	if domain == "" {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: "错误: domain 参数必需且不能为空"}},
+			IsError: true,
+		}, nil
+	}
+	if err := e.checkTargetScope(ctx, map[string]interface{}{"domain": domain}); err != nil {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("错误: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	recordType := "A"
+	if rt, ok := args["record_type"].(string); ok && strings.TrimSpace(rt) != "" {
+		recordType = strings.ToUpper(strings.TrimSpace(rt))
+	}
+
+	if recordType == "AXFR" {
+		return e.executeZoneTransferAttempt(ctx, domain)
+	}
+
+	var records []string
+	var lookupErr error
+	switch recordType {
+	case "A":
+		var ips []net.IP
+		ips, lookupErr = net.DefaultResolver.LookupIP(ctx, "ip4", domain)
+		for _, ip := range ips {
+			records = append(records, ip.String())
+		}
+	case "AAAA":
+		var ips []net.IP
+		ips, lookupErr = net.DefaultResolver.LookupIP(ctx, "ip6", domain)
+		for _, ip := range ips {
+			records = append(records, ip.String())
+		}
+	case "MX":
+		var mxs []*net.MX
+		mxs, lookupErr = net.DefaultResolver.LookupMX(ctx, domain)
+		for _, mx := range mxs {
+			records = append(records, fmt.Sprintf("%s (优先级 %d)", strings.TrimSuffix(mx.Host, "."), mx.Pref))
+		}
+	case "TXT":
+		records, lookupErr = net.DefaultResolver.LookupTXT(ctx, domain)
+	case "NS":
+		var nss []*net.NS
+		nss, lookupErr = net.DefaultResolver.LookupNS(ctx, domain)
+		for _, ns := range nss {
+			records = append(records, strings.TrimSuffix(ns.Host, "."))
+		}
+	case "CNAME":
+		var cname string
+		cname, lookupErr = net.DefaultResolver.LookupCNAME(ctx, domain)
+		if lookupErr == nil {
+			records = []string{strings.TrimSuffix(cname, ".")}
+		}
+	default:
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("错误: 不支持的 record_type: %s（支持 A/AAAA/MX/TXT/NS/CNAME/AXFR）", recordType)}},
+			IsError: true,
+		}, nil
+	}
+
+	if lookupErr != nil {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("查询 %s 记录失败: %v", recordType, lookupErr)}},
+			IsError: true,
+		}, nil
+	}
+
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"domain":      domain,
+		"record_type": recordType,
+		"records":     records,
+	})
+	if err != nil {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("序列化结果失败: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.ToolResult{
+		Content: []mcp.Content{{Type: "text", Text: string(resultJSON)}},
+		IsError: false,
+	}, nil
+}
+
+// executeZoneTransferAttempt 依次对 domain 的每个 NS 尝试 AXFR 区域传送，命中允许传送的服务器即返回；
+// 绝大多数正确配置的服务器会拒绝，返回结果本身（成功与否、每个 NS 的尝试结果）就是一条有价值的侦察发现。
+func (e *Executor) executeZoneTransferAttempt(ctx context.Context, domain string) (*mcp.ToolResult, error) {
+	nss, err := net.DefaultResolver.LookupNS(ctx, domain)
+	if err != nil || len(nss) == 0 {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("解析 %s 的 NS 记录失败: %v", domain, err)}},
+			IsError: true,
+		}, nil
+	}
+
+	var attempts []string
+	var transferSucceeded bool
+	var records []string
+	for _, ns := range nss {
+		server := strings.TrimSuffix(ns.Host, ".")
+		recs, aerr := attemptAXFR(ctx, server, domain)
+		if aerr != nil {
+			attempts = append(attempts, fmt.Sprintf("%s: 失败 (%v)", server, aerr))
+			continue
+		}
+		attempts = append(attempts, fmt.Sprintf("%s: 成功，返回 %d 条记录", server, len(recs)))
+		transferSucceeded = true
+		records = recs
+		break
+	}
+
+	e.logger.Info("区域传送尝试完成",
+		zap.String("domain", domain),
+		zap.Bool("succeeded", transferSucceeded),
+	)
+
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"domain":             domain,
+		"transfer_succeeded": transferSucceeded,
+		"attempts":           attempts,
+		"records":            records,
+	})
+	if err != nil {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("序列化结果失败: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.ToolResult{
+		Content: []mcp.Content{{Type: "text", Text: string(resultJSON)}},
+		IsError: false,
+	}, nil
+}
+
+// attemptAXFR 对指定 NS 发起一次 AXFR（区域传送，DNS 报文类型 252）尝试；服务器允许传送时返回
+// 格式化后的资源记录列表，拒绝或异常时返回 error。基于手写的最小 DNS-over-TCP 报文编解码，
+// 只覆盖 AXFR 场景常见的记录类型（A/AAAA/NS/CNAME/SOA/MX/TXT），其余类型退化为字节数展示。
+func attemptAXFR(ctx context.Context, server, domain string) ([]string, error) {
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(server, "53"))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(20 * time.Second))
+
+	query := buildDNSQuery(1, domain, 252)
+	lenPrefix := make([]byte, 2)
+	binary.BigEndian.PutUint16(lenPrefix, uint16(len(query)))
+	if _, err := conn.Write(append(lenPrefix, query...)); err != nil {
+		return nil, err
+	}
+
+	var formatted []string
+	soaCount := 0
+	for {
+		var msgLen uint16
+		if err := binary.Read(conn, binary.BigEndian, &msgLen); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		msg := make([]byte, msgLen)
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return nil, err
+		}
+		ancount, rcode, records, perr := parseDNSMessage(msg)
+		if perr != nil {
+			return nil, perr
+		}
+		if ancount == 0 {
+			if rcode != 0 {
+				return nil, fmt.Errorf("服务器拒绝区域传送 (rcode=%d)", rcode)
+			}
+			break
+		}
+		for _, rr := range records {
+			formatted = append(formatted, formatDNSRR(msg, rr))
+			if rr.Type == 6 {
+				soaCount++
+			}
+		}
+		if soaCount >= 2 {
+			break
+		}
+	}
+
+	if len(formatted) == 0 {
+		return nil, fmt.Errorf("服务器未返回任何记录")
+	}
+	return formatted, nil
+}
+
+// buildDNSQuery 构造一个最小的单 question DNS 查询报文，用于标准库 net 包不支持的原始查询类型（如 AXFR）。
+func buildDNSQuery(id uint16, name string, qtype uint16) []byte {
+	buf := make([]byte, 0, 32+len(name))
+	header := make([]byte, 12)
+	binary.BigEndian.PutUint16(header[0:2], id)
+	binary.BigEndian.PutUint16(header[4:6], 1) // qdcount
+	buf = append(buf, header...)
+	buf = append(buf, encodeDNSName(name)...)
+	qtypeBytes := make([]byte, 4)
+	binary.BigEndian.PutUint16(qtypeBytes[0:2], qtype)
+	binary.BigEndian.PutUint16(qtypeBytes[2:4], 1) // qclass = IN
+	buf = append(buf, qtypeBytes...)
+	return buf
+}
+
+// encodeDNSName 把点分域名编码为 DNS 报文中的标签序列（长度前缀 + 内容），以 0 字节结尾。
+func encodeDNSName(name string) []byte {
+	var buf []byte
+	name = strings.TrimSuffix(name, ".")
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			buf = append(buf, byte(len(label)))
+			buf = append(buf, []byte(label)...)
+		}
+	}
+	buf = append(buf, 0)
+	return buf
+}
+
+// dnsRR 一条已解析的 DNS 资源记录；RData 用报文内的绝对偏移量+长度表示（而非拷贝字节），
+// 因为部分记录类型（NS/CNAME/SOA/MX）的数据本身可能含指向报文其它位置的压缩指针，需要原始报文才能解出。
+type dnsRR struct {
+	Name       string
+	Type       uint16
+	TTL        uint32
+	RDataStart int
+	RDataLen   int
+}
+
+// parseDNSMessage 解析一个完整 DNS 报文（不含 TCP 长度前缀），返回 header 中的 ancount/rcode 与
+// 已解析的 answer 记录列表；仅解析到 answer 区（AXFR 响应不含有意义的 authority/additional 内容）。
+func parseDNSMessage(msg []byte) (ancount int, rcode int, records []dnsRR, err error) {
+	if len(msg) < 12 {
+		return 0, 0, nil, fmt.Errorf("DNS 报文过短")
+	}
+	flags := binary.BigEndian.Uint16(msg[2:4])
+	rcode = int(flags & 0x000F)
+	qdcount := int(binary.BigEndian.Uint16(msg[4:6]))
+	ancount = int(binary.BigEndian.Uint16(msg[6:8]))
+
+	offset := 12
+	for i := 0; i < qdcount; i++ {
+		_, next, perr := parseDNSName(msg, offset)
+		if perr != nil {
+			return ancount, rcode, nil, perr
+		}
+		offset = next + 4 // qtype(2) + qclass(2)
+	}
+
+	for i := 0; i < ancount; i++ {
+		name, next, perr := parseDNSName(msg, offset)
+		if perr != nil {
+			return ancount, rcode, records, perr
+		}
+		offset = next
+		if offset+10 > len(msg) {
+			return ancount, rcode, records, fmt.Errorf("DNS 资源记录头越界")
+		}
+		rtype := binary.BigEndian.Uint16(msg[offset : offset+2])
+		ttl := binary.BigEndian.Uint32(msg[offset+4 : offset+8])
+		rdlength := int(binary.BigEndian.Uint16(msg[offset+8 : offset+10]))
+		offset += 10
+		if offset+rdlength > len(msg) {
+			return ancount, rcode, records, fmt.Errorf("DNS 资源记录数据越界")
+		}
+		records = append(records, dnsRR{Name: name, Type: rtype, TTL: ttl, RDataStart: offset, RDataLen: rdlength})
+		offset += rdlength
+	}
+	return ancount, rcode, records, nil
+}
+
+// parseDNSName 从 msg 的 offset 处解析一个域名（可能含压缩指针），返回域名与紧跟在该字段之后的偏移量。
+func parseDNSName(msg []byte, offset int) (string, int, error) {
+	var labels []string
+	nextOffset := -1
+	jumps := 0
+	for {
+		if offset >= len(msg) {
+			return "", 0, fmt.Errorf("DNS 报文越界")
+		}
+		lengthByte := msg[offset]
+		if lengthByte&0xC0 == 0xC0 {
+			if offset+1 >= len(msg) {
+				return "", 0, fmt.Errorf("DNS 压缩指针越界")
+			}
+			if nextOffset == -1 {
+				nextOffset = offset + 2
+			}
+			jumps++
+			if jumps > 128 {
+				return "", 0, fmt.Errorf("DNS 压缩指针跳转次数过多，可能存在环")
+			}
+			offset = int(lengthByte&0x3F)<<8 | int(msg[offset+1])
+			continue
+		}
+		if lengthByte == 0 {
+			offset++
+			break
+		}
+		start := offset + 1
+		end := start + int(lengthByte)
+		if end > len(msg) {
+			return "", 0, fmt.Errorf("DNS 标签越界")
+		}
+		labels = append(labels, string(msg[start:end]))
+		offset = end
+	}
+	if nextOffset == -1 {
+		nextOffset = offset
+	}
+	return strings.Join(labels, "."), nextOffset, nil
+}
+
+// formatDNSRR 把一条已解析的资源记录格式化为可读字符串；只精确解析 AXFR 场景常见的记录类型，
+// 其余类型退化为字节数展示，不影响记录条数统计与传送成功判定。
+func formatDNSRR(msg []byte, rr dnsRR) string {
+	start, length := rr.RDataStart, rr.RDataLen
+	value := ""
+	switch rr.Type {
+	case 1: // A
+		if length == net.IPv4len {
+			value = net.IP(msg[start : start+length]).String()
+		}
+	case 28: // AAAA
+		if length == net.IPv6len {
+			value = net.IP(msg[start : start+length]).String()
+		}
+	case 2, 5: // NS, CNAME
+		if name, _, err := parseDNSName(msg, start); err == nil {
+			value = name
+		}
+	case 15: // MX
+		if length >= 2 {
+			pref := binary.BigEndian.Uint16(msg[start : start+2])
+			if name, _, err := parseDNSName(msg, start+2); err == nil {
+				value = fmt.Sprintf("%d %s", pref, name)
+			}
+		}
+	case 6: // SOA
+		if mname, next, err := parseDNSName(msg, start); err == nil {
+			if rname, next2, err2 := parseDNSName(msg, next); err2 == nil {
+				value = fmt.Sprintf("%s %s", mname, rname)
+				if next2+4 <= len(msg) {
+					serial := binary.BigEndian.Uint32(msg[next2 : next2+4])
+					value += fmt.Sprintf(" serial=%d", serial)
+				}
+			}
+		}
+	case 16: // TXT
+		var parts []string
+		p := start
+		for p < start+length {
+			l := int(msg[p])
+			p++
+			if p+l > start+length {
+				break
+			}
+			parts = append(parts, string(msg[p:p+l]))
+			p += l
+		}
+		value = strings.Join(parts, "")
+	}
+	if value == "" {
+		value = fmt.Sprintf("(%d 字节)", length)
+	}
+	return fmt.Sprintf("%s %s TTL=%d %s", rr.Name, dnsTypeName(rr.Type), rr.TTL, value)
+}
+
+// dnsTypeName 把 DNS 报文里的数字记录类型转换为常见的可读名称
+func dnsTypeName(t uint16) string {
+	switch t {
+	case 1:
+		return "A"
+	case 2:
+		return "NS"
+	case 5:
+		return "CNAME"
+	case 6:
+		return "SOA"
+	case 15:
+		return "MX"
+	case 16:
+		return "TXT"
+	case 28:
+		return "AAAA"
+	case 252:
+		return "AXFR"
+	default:
+		return fmt.Sprintf("TYPE%d", t)
+	}
+}
+
+// executeWhoisLookup 内置 WHOIS 查询工具：按 RFC 3912 直接与 WHOIS 服务器（TCP 43 端口）通信，
+// 默认从 whois.iana.org 起步，跟随其 "refer:" 字段重定向到具体注册局查询一次，不依赖宿主机安装 whois 客户端。
+func (e *Executor) executeWhoisLookup(ctx context.Context, args map[string]interface{}) (*mcp.ToolResult, error) {
+	query, _ := args["query"].(string)
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: "错误: query 参数必需且不能为空"}},
+			IsError: true,
+		}, nil
+	}
+	if err := e.checkTargetScope(ctx, map[string]interface{}{"domain": query, "ip": query}); err != nil {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("错误: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	server := "whois.iana.org"
+	if s, ok := args["server"].(string); ok && strings.TrimSpace(s) != "" {
+		server = strings.TrimSpace(s)
+	}
+
+	raw, err := whoisQuery(ctx, server, query)
+	if err != nil {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("查询 %s 失败: %v", server, err)}},
+			IsError: true,
+		}, nil
+	}
+
+	finalServer, finalRaw := server, raw
+	if referred := extractWhoisReferral(raw); referred != "" && !strings.EqualFold(referred, server) {
+		if raw2, err2 := whoisQuery(ctx, referred, query); err2 == nil {
+			finalServer, finalRaw = referred, raw2
+		}
+	}
+
+	resultJSON, err := json.Marshal(map[string]interface{}{
+		"query":  query,
+		"server": finalServer,
+		"raw":    finalRaw,
+	})
+	if err != nil {
+		return &mcp.ToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("序列化结果失败: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.ToolResult{
+		Content: []mcp.Content{{Type: "text", Text: string(resultJSON)}},
+		IsError: false,
+	}, nil
+}
+
+// whoisQuery 建立一次到 server:43 的 TCP 连接，发送查询后读取全部响应直到对端关闭连接（RFC 3912）。
+func whoisQuery(ctx context.Context, server, query string) (string, error) {
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(server, "43"))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(15 * time.Second))
+	if _, err := fmt.Fprintf(conn, "%s\r\n", query); err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(conn)
+	if err != nil && len(data) == 0 {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// whoisReferRe 匹配 WHOIS 响应中常见的到具体注册局的重定向字段（refer / whois server / registrar whois server）
+var whoisReferRe = regexp.MustCompile(`(?im)^\s*(?:refer|whois server|registrar whois server)\s*:\s*(\S+)`)
+
+func extractWhoisReferral(raw string) string {
+	if m := whoisReferRe.FindStringSubmatch(raw); len(m) == 2 {
+		return m[1]
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package security
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+)
+
+func TestExecuteDNSLookup_MissingDomain(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	res, err := executor.executeDNSLookup(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("executeDNSLookup: %v", err)
+	}
+	if !res.IsError {
+		t.Fatal("expected error result when domain is missing")
+	}
+}
+
+func TestExecuteDNSLookup_UnsupportedRecordType(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	res, err := executor.executeDNSLookup(context.Background(), map[string]interface{}{
+		"domain":      "example.com",
+		"record_type": "PTR",
+	})
+	if err != nil {
+		t.Fatalf("executeDNSLookup: %v", err)
+	}
+	if !res.IsError {
+		t.Fatal("expected error result for unsupported record_type")
+	}
+}
+
+func TestExecuteWhoisLookup_MissingQuery(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	res, err := executor.executeWhoisLookup(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("executeWhoisLookup: %v", err)
+	}
+	if !res.IsError {
+		t.Fatal("expected error result when query is missing")
+	}
+}
+
+func TestExtractWhoisReferral(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want string
+	}{
+		{"refer:        whois.verisign-grs.com\n", "whois.verisign-grs.com"},
+		{"Whois Server: whois.example-registry.net\n", "whois.example-registry.net"},
+		{"domain: example.com\nstatus: active\n", ""},
+	}
+	for _, c := range cases {
+		if got := extractWhoisReferral(c.raw); got != c.want {
+			t.Errorf("extractWhoisReferral(%q) = %q, want %q", c.raw, got, c.want)
+		}
+	}
+}
+
+func TestDNSNameEncodeDecodeRoundTrip(t *testing.T) {
+	name := "sub.example.com"
+	query := buildDNSQuery(1234, name, 252)
+
+	id := binary.BigEndian.Uint16(query[0:2])
+	if id != 1234 {
+		t.Fatalf("expected query id 1234, got %d", id)
+	}
+	qdcount := binary.BigEndian.Uint16(query[4:6])
+	if qdcount != 1 {
+		t.Fatalf("expected qdcount 1, got %d", qdcount)
+	}
+
+	decoded, next, err := parseDNSName(query, 12)
+	if err != nil {
+		t.Fatalf("parseDNSName: %v", err)
+	}
+	if decoded != name {
+		t.Errorf("expected decoded name %q, got %q", name, decoded)
+	}
+	qtype := binary.BigEndian.Uint16(query[next : next+2])
+	if qtype != 252 {
+		t.Errorf("expected qtype 252 (AXFR), got %d", qtype)
+	}
+}
+
+func TestParseDNSNameWithCompressionPointer(t *testing.T) {
+	// 手工构造一个报文：offset 12 处放完整域名 "example.com"，
+	// offset 30 处放一个指向 12 的压缩指针，验证指针解析与 nextOffset 计算是否正确。
+	msg := make([]byte, 32)
+	copy(msg[12:], encodeDNSName("example.com"))
+	msg[30] = 0xC0
+	msg[31] = 0x0C // 指向 offset 12
+
+	name, next, err := parseDNSName(msg, 30)
+	if err != nil {
+		t.Fatalf("parseDNSName: %v", err)
+	}
+	if name != "example.com" {
+		t.Errorf("expected example.com, got %q", name)
+	}
+	if next != 32 {
+		t.Errorf("expected next offset 32 (right after the 2-byte pointer), got %d", next)
+	}
+}
+
+func TestFormatDNSRR_ARecord(t *testing.T) {
+	msg := make([]byte, 16)
+	copy(msg[12:], []byte{93, 184, 216, 34}) // 93.184.216.34
+	rr := dnsRR{Name: "example.com", Type: 1, TTL: 300, RDataStart: 12, RDataLen: 4}
+
+	got := formatDNSRR(msg, rr)
+	want := "example.com A TTL=300 93.184.216.34"
+	if got != want {
+		t.Errorf("formatDNSRR() = %q, want %q", got, want)
+	}
+}
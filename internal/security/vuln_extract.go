@@ -0,0 +1,166 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"cyberstrike-ai/internal/mcp"
+
+	"go.uber.org/zap"
+)
+
+// maxVulnExtractionInputChars 送入提取模型的原始输出最大字符数，避免超大输出撑爆次级模型的上下文，
+// 与 agent.maxSummarizerInputChars 用途一致但独立配置，二者服务于不同的下游消费方。
+const maxVulnExtractionInputChars = 60000
+
+// ExtractedVulnerability 是 LLM 提取管线产出的单条标准化漏洞记录，字段对齐 database.Vulnerability
+// 的核心列（Title/Severity/Target/Proof/Recommendation），security 包不直接依赖 database 包，
+// 由调用方（如 handler 层）通过 VulnerabilitySink 转换落库。
+type ExtractedVulnerability struct {
+	Title       string `json:"title"`
+	Severity    string `json:"severity"`    // critical, high, medium, low, info
+	Component   string `json:"component"`   // 受影响组件：主机、URL、参数等
+	Evidence    string `json:"evidence"`    // 原始输出中支撑该结论的证据片段
+	Remediation string `json:"remediation"` // 修复建议
+	// CVSSVector 是可选的 CVSS v3.1 向量字符串；模型证据不足以给出全部 8 个 Base Metric 时应留空，
+	// 落库时会按 database.applyCVSSScoring 计算评分并据此覆盖 Severity，留空则沿用本结构体的 Severity。
+	CVSSVector string `json:"cvss_vector"`
+}
+
+// VulnExtractionClient 是漏洞提取所需的最小补全接口，与 agent.CompletionClient 形状一致，
+// 便于复用同一套 OpenAI 兼容适配器而不引入 security -> agent 的反向依赖（agent 已依赖 security）。
+type VulnExtractionClient interface {
+	Complete(ctx context.Context, model string, prompt string, timeout time.Duration) (string, error)
+}
+
+// VulnerabilitySink 接收提取出的标准化漏洞记录并负责持久化（通常适配到 database.DB.CreateVulnerability）。
+type VulnerabilitySink interface {
+	RecordVulnerability(conversationID, toolName string, vuln ExtractedVulnerability) error
+}
+
+// vulnExtractionSystemPrompt 约束模型只做信息抽取、不做漏洞的主观夸大或臆造。
+const vulnExtractionSystemPrompt = `你是安全工具输出分析助手。仅依据给定的工具原始输出提取真实存在的漏洞证据，
+不要臆造输出中不存在的信息。如果输出中没有可确认的漏洞，返回空数组。severity 必须是
+critical、high、medium、low、info 之一。如果证据足以判断全部 8 个 CVSS v3.1 Base Metric
+（AV/AC/PR/UI/S/C/I/A），可额外给出 cvss_vector（格式如 "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"），
+否则将 cvss_vector 留空，不要臆造无法从证据推断的评分。必须只输出 JSON，不要包含任何解释性文字。`
+
+// vulnExtractionResponseSchema 是要求模型严格遵循的 JSON Schema，替代此前基于子串匹配（如仅 grep
+// "sql injection"/"xss"）的启发式判断，使 severity、受影响组件、证据片段、修复建议等字段都能被结构化提取。
+var vulnExtractionResponseSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"vulnerabilities": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"title":       map[string]interface{}{"type": "string"},
+					"severity":    map[string]interface{}{"type": "string", "enum": []string{"critical", "high", "medium", "low", "info"}},
+					"component":   map[string]interface{}{"type": "string"},
+					"evidence":    map[string]interface{}{"type": "string"},
+					"remediation": map[string]interface{}{"type": "string"},
+					"cvss_vector": map[string]interface{}{"type": "string"},
+				},
+				"required": []string{"title", "severity", "component", "evidence", "remediation"},
+			},
+		},
+	},
+	"required": []string{"vulnerabilities"},
+}
+
+type vulnExtractionResult struct {
+	Vulnerabilities []ExtractedVulnerability `json:"vulnerabilities"`
+}
+
+// ExtractVulnerabilitiesLLM 用配置的提取模型分析工具原始输出，返回标准化的漏洞记录列表；
+// 未配置 vulnExtractor/vulnExtractionModel 时返回 (nil, nil)，调用方应视为“未启用该功能”而非错误。
+func (e *Executor) ExtractVulnerabilitiesLLM(ctx context.Context, toolName, output string) ([]ExtractedVulnerability, error) {
+	if e.vulnExtractor == nil || e.vulnExtractionModel == "" {
+		return nil, nil
+	}
+	if strings.TrimSpace(output) == "" {
+		return nil, nil
+	}
+
+	truncated := output
+	if len(truncated) > maxVulnExtractionInputChars {
+		truncated = truncated[:maxVulnExtractionInputChars] + "\n...(已截断)"
+	}
+
+	schemaJSON, err := json.Marshal(vulnExtractionResponseSchema)
+	if err != nil {
+		return nil, fmt.Errorf("序列化提取schema失败: %w", err)
+	}
+
+	prompt := fmt.Sprintf(
+		"%s\n\n目标 JSON Schema:\n%s\n\n工具 %s 的原始输出（可能已截断）:\n\n%s",
+		vulnExtractionSystemPrompt, string(schemaJSON), toolName, truncated,
+	)
+
+	raw, err := e.vulnExtractor.Complete(ctx, e.vulnExtractionModel, prompt, 60*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("调用漏洞提取模型失败: %w", err)
+	}
+
+	var result vulnExtractionResult
+	if err := json.Unmarshal([]byte(extractJSONObject(raw)), &result); err != nil {
+		return nil, fmt.Errorf("解析漏洞提取模型输出失败: %w", err)
+	}
+
+	return result.Vulnerabilities, nil
+}
+
+// extractJSONObject 从模型返回文本中截取首个 JSON 对象，兼容部分模型即便被要求“只输出JSON”
+// 仍会在前后附带 ```json 代码块围栏或说明文字的情况。
+func extractJSONObject(text string) string {
+	trimmed := strings.TrimSpace(text)
+	start := strings.Index(trimmed, "{")
+	end := strings.LastIndex(trimmed, "}")
+	if start == -1 || end == -1 || end < start {
+		return trimmed
+	}
+	return trimmed[start : end+1]
+}
+
+// extractAndRecordVulnerabilitiesAsync 在后台异步执行 LLM 提取并写入 VulnerabilitySink，不阻塞
+// ExecuteTool 的返回；失败仅记录日志，与 summarizeLargeResult/appendStructuredFindings 一样是
+// 尽力而为的增强能力，不影响工具原始输出的正常返回。
+func (e *Executor) extractAndRecordVulnerabilitiesAsync(ctx context.Context, toolName, output string) {
+	if e.vulnExtractor == nil || e.vulnExtractionModel == "" || e.vulnSink == nil {
+		return
+	}
+	conversationID := mcp.ConversationIDFromContext(ctx)
+	if conversationID == "" {
+		return
+	}
+
+	go func() {
+		bgCtx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+		defer cancel()
+
+		vulns, err := e.ExtractVulnerabilitiesLLM(bgCtx, toolName, output)
+		if err != nil {
+			e.logger.Warn("LLM漏洞提取失败", zap.String("tool", toolName), zap.Error(err))
+			return
+		}
+		for _, v := range vulns {
+			if err := e.vulnSink.RecordVulnerability(conversationID, toolName, v); err != nil {
+				e.logger.Warn("写入LLM提取的漏洞记录失败",
+					zap.String("tool", toolName),
+					zap.String("title", v.Title),
+					zap.Error(err),
+				)
+			}
+		}
+		if len(vulns) > 0 {
+			e.logger.Info("LLM漏洞提取完成",
+				zap.String("tool", toolName),
+				zap.Int("count", len(vulns)),
+			)
+		}
+	}()
+}
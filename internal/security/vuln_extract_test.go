@@ -0,0 +1,84 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeVulnExtractionClient 是测试用的 VulnExtractionClient 桩实现
+type fakeVulnExtractionClient struct {
+	response string
+	err      error
+}
+
+func (f *fakeVulnExtractionClient) Complete(ctx context.Context, model string, prompt string, timeout time.Duration) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	return f.response, nil
+}
+
+func TestExtractVulnerabilitiesLLM_NotConfigured_ReturnsNil(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	vulns, err := executor.ExtractVulnerabilitiesLLM(context.Background(), "nuclei", "some output")
+	if err != nil {
+		t.Fatalf("未配置提取器时不应返回错误: %v", err)
+	}
+	if vulns != nil {
+		t.Errorf("未配置提取器时应返回nil，实际: %v", vulns)
+	}
+}
+
+func TestExtractVulnerabilitiesLLM_EmptyOutput_SkipsCall(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	executor.SetVulnerabilityExtractor(&fakeVulnExtractionClient{response: `{"vulnerabilities":[]}`}, "gpt-4o-mini")
+
+	vulns, err := executor.ExtractVulnerabilitiesLLM(context.Background(), "nuclei", "   ")
+	if err != nil {
+		t.Fatalf("空输出不应报错: %v", err)
+	}
+	if vulns != nil {
+		t.Errorf("空输出应返回nil，实际: %v", vulns)
+	}
+}
+
+func TestExtractVulnerabilitiesLLM_ParsesStrictJSON(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	executor.SetVulnerabilityExtractor(&fakeVulnExtractionClient{response: `{"vulnerabilities":[{"title":"SQL注入","severity":"high","component":"/login?id=1","evidence":"Parameter: id (GET)","remediation":"使用参数化查询"}]}`}, "gpt-4o-mini")
+
+	vulns, err := executor.ExtractVulnerabilitiesLLM(context.Background(), "sqlmap", "Parameter: id (GET)\nType: boolean-based blind")
+	if err != nil {
+		t.Fatalf("解析提取结果失败: %v", err)
+	}
+	if len(vulns) != 1 {
+		t.Fatalf("应提取到1条漏洞，实际: %d", len(vulns))
+	}
+	if vulns[0].Title != "SQL注入" || vulns[0].Severity != "high" {
+		t.Errorf("提取结果字段不符，实际: %+v", vulns[0])
+	}
+}
+
+func TestExtractVulnerabilitiesLLM_TolerantOfCodeFence(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	fenced := "```json\n{\"vulnerabilities\":[{\"title\":\"XSS\",\"severity\":\"medium\",\"component\":\"q\",\"evidence\":\"<script>\",\"remediation\":\"转义输出\"}]}\n```"
+	executor.SetVulnerabilityExtractor(&fakeVulnExtractionClient{response: fenced}, "gpt-4o-mini")
+
+	vulns, err := executor.ExtractVulnerabilitiesLLM(context.Background(), "nuclei", "reflected <script>alert(1)</script>")
+	if err != nil {
+		t.Fatalf("应容忍代码块围栏: %v", err)
+	}
+	if len(vulns) != 1 || vulns[0].Title != "XSS" {
+		t.Errorf("提取结果不符，实际: %+v", vulns)
+	}
+}
+
+func TestExtractVulnerabilitiesLLM_ClientError_Propagates(t *testing.T) {
+	executor, _ := setupTestExecutor(t)
+	executor.SetVulnerabilityExtractor(&fakeVulnExtractionClient{err: errors.New("网络错误")}, "gpt-4o-mini")
+
+	if _, err := executor.ExtractVulnerabilitiesLLM(context.Background(), "nuclei", "output"); err == nil {
+		t.Error("补全客户端出错时应返回错误")
+	}
+}
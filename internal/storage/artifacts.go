@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ArtifactInfo 描述一个已归档的工具产出文件
+type ArtifactInfo struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// ArtifactStorage 按执行ID归档工具产出文件（如 nuclei "-o"、nmap "-oX" 写出的报告），
+// 与 ResultStorage 存放的 stdout/stderr 增量互不影响，各自独立管理生命周期。
+type ArtifactStorage interface {
+	// ScratchDir 返回（并在不存在时创建）本次执行的暂存目录，供 security.Executor 把
+	// ToolConfig.OutputArtifacts 中声明的产出文件路径参数指向此处。
+	ScratchDir(executionID string) (string, error)
+
+	// ListArtifacts 列出某次执行暂存目录下的所有产出文件
+	ListArtifacts(executionID string) ([]ArtifactInfo, error)
+
+	// ArtifactPath 返回某次执行下指定文件名的绝对路径，文件不存在时返回错误；
+	// 会拒绝包含路径分隔符的文件名，防止目录穿越读取暂存目录之外的文件。
+	ArtifactPath(executionID string, filename string) (string, error)
+
+	// DeleteArtifacts 删除某次执行的整个暂存目录
+	DeleteArtifacts(executionID string) error
+}
+
+// FileArtifactStorage 基于文件系统的 ArtifactStorage 实现，每次执行对应 baseDir 下的一个子目录
+type FileArtifactStorage struct {
+	baseDir string
+	logger  *zap.Logger
+	mu      sync.Mutex
+}
+
+// NewFileArtifactStorage 创建新的文件产出物存储
+func NewFileArtifactStorage(baseDir string, logger *zap.Logger) (*FileArtifactStorage, error) {
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建产出物存储目录失败: %w", err)
+	}
+
+	return &FileArtifactStorage{
+		baseDir: baseDir,
+		logger:  logger,
+	}, nil
+}
+
+func (s *FileArtifactStorage) executionDir(executionID string) string {
+	return filepath.Join(s.baseDir, filepath.Base(executionID))
+}
+
+// ScratchDir 返回（并在不存在时创建）本次执行的暂存目录
+func (s *FileArtifactStorage) ScratchDir(executionID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := s.executionDir(executionID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建执行暂存目录失败: %w", err)
+	}
+	return dir, nil
+}
+
+// ListArtifacts 列出某次执行暂存目录下的所有产出文件
+func (s *FileArtifactStorage) ListArtifacts(executionID string) ([]ArtifactInfo, error) {
+	dir := s.executionDir(executionID)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []ArtifactInfo{}, nil
+		}
+		return nil, fmt.Errorf("读取产出物目录失败: %w", err)
+	}
+
+	artifacts := make([]ArtifactInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			s.logger.Warn("读取产出文件信息失败", zap.String("executionID", executionID), zap.String("file", entry.Name()), zap.Error(err))
+			continue
+		}
+		artifacts = append(artifacts, ArtifactInfo{
+			Name:    entry.Name(),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	return artifacts, nil
+}
+
+// ArtifactPath 返回某次执行下指定文件名的绝对路径
+func (s *FileArtifactStorage) ArtifactPath(executionID string, filename string) (string, error) {
+	safeName := filepath.Base(filename)
+	if safeName == "." || safeName == string(filepath.Separator) || safeName != filename {
+		return "", fmt.Errorf("非法的产出文件名: %s", filename)
+	}
+
+	path := filepath.Join(s.executionDir(executionID), safeName)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("产出文件不存在: %s", filename)
+		}
+		return "", fmt.Errorf("访问产出文件失败: %w", err)
+	}
+	return path, nil
+}
+
+// DeleteArtifacts 删除某次执行的整个暂存目录
+func (s *FileArtifactStorage) DeleteArtifacts(executionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.RemoveAll(s.executionDir(executionID)); err != nil {
+		return fmt.Errorf("删除产出物目录失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// setupTestArtifactStorage 创建测试用的产出文件存储实例
+func setupTestArtifactStorage(t *testing.T) (*FileArtifactStorage, string) {
+	tmpDir := filepath.Join(os.TempDir(), "test_artifact_storage_"+time.Now().Format("20060102_150405.000000000"))
+	logger := zap.NewNop()
+
+	storage, err := NewFileArtifactStorage(tmpDir, logger)
+	if err != nil {
+		t.Fatalf("创建测试存储失败: %v", err)
+	}
+
+	return storage, tmpDir
+}
+
+func TestFileArtifactStorage_ScratchDirCreatesAndReuses(t *testing.T) {
+	storage, tmpDir := setupTestArtifactStorage(t)
+	defer os.RemoveAll(tmpDir)
+
+	dir1, err := storage.ScratchDir("exec_001")
+	if err != nil {
+		t.Fatalf("创建暂存目录失败: %v", err)
+	}
+	if info, statErr := os.Stat(dir1); statErr != nil || !info.IsDir() {
+		t.Fatalf("暂存目录未被创建: %v", statErr)
+	}
+
+	dir2, err := storage.ScratchDir("exec_001")
+	if err != nil {
+		t.Fatalf("重复获取暂存目录失败: %v", err)
+	}
+	if dir1 != dir2 {
+		t.Errorf("同一执行ID应返回相同的暂存目录，期望%s，实际%s", dir1, dir2)
+	}
+}
+
+func TestFileArtifactStorage_ListAndGetArtifact(t *testing.T) {
+	storage, tmpDir := setupTestArtifactStorage(t)
+	defer os.RemoveAll(tmpDir)
+
+	dir, err := storage.ScratchDir("exec_002")
+	if err != nil {
+		t.Fatalf("创建暂存目录失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "report.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("写入产出文件失败: %v", err)
+	}
+
+	artifacts, err := storage.ListArtifacts("exec_002")
+	if err != nil {
+		t.Fatalf("列出产出文件失败: %v", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].Name != "report.txt" {
+		t.Fatalf("产出文件列表不符，实际: %+v", artifacts)
+	}
+
+	path, err := storage.ArtifactPath("exec_002", "report.txt")
+	if err != nil {
+		t.Fatalf("获取产出文件路径失败: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("产出文件内容不符: %v, %q", err, data)
+	}
+}
+
+func TestFileArtifactStorage_ListArtifacts_NoExecution(t *testing.T) {
+	storage, tmpDir := setupTestArtifactStorage(t)
+	defer os.RemoveAll(tmpDir)
+
+	artifacts, err := storage.ListArtifacts("nonexistent")
+	if err != nil {
+		t.Fatalf("不存在的执行ID不应返回错误: %v", err)
+	}
+	if len(artifacts) != 0 {
+		t.Errorf("不存在的执行ID应返回空列表，实际: %+v", artifacts)
+	}
+}
+
+func TestFileArtifactStorage_ArtifactPath_RejectsPathTraversal(t *testing.T) {
+	storage, tmpDir := setupTestArtifactStorage(t)
+	defer os.RemoveAll(tmpDir)
+
+	if _, err := storage.ScratchDir("exec_003"); err != nil {
+		t.Fatalf("创建暂存目录失败: %v", err)
+	}
+
+	if _, err := storage.ArtifactPath("exec_003", "../secret.txt"); err == nil {
+		t.Error("路径穿越的文件名应被拒绝")
+	}
+	if _, err := storage.ArtifactPath("exec_003", "../../etc/passwd"); err == nil {
+		t.Error("路径穿越的文件名应被拒绝")
+	}
+}
+
+func TestFileArtifactStorage_DeleteArtifacts(t *testing.T) {
+	storage, tmpDir := setupTestArtifactStorage(t)
+	defer os.RemoveAll(tmpDir)
+
+	dir, err := storage.ScratchDir("exec_004")
+	if err != nil {
+		t.Fatalf("创建暂存目录失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("写入产出文件失败: %v", err)
+	}
+
+	if err := storage.DeleteArtifacts("exec_004"); err != nil {
+		t.Fatalf("删除产出物目录失败: %v", err)
+	}
+	if _, statErr := os.Stat(dir); !os.IsNotExist(statErr) {
+		t.Error("删除后暂存目录应不再存在")
+	}
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lineIndex 记录一个结果文件中每一行的起始字节偏移，用于 GetResultPage 直接 seek 到目标行，
+// 避免对多百MB级结果反复整读整切分。存储格式为定长 int64（小端）序列，第 i 个元素是第 i 行
+// （从0开始）在结果文件中的起始偏移；末尾行（无论是否以换行符结尾）读到文件末尾为止——这与
+// strings.Split(result, "\n") 的行为完全一致，含"以换行符结尾产生一个空尾行"的边界情况。
+type lineIndex []int64
+
+// getLineIndexPath 获取行索引文件路径
+func (s *FileResultStorage) getLineIndexPath(executionID string) string {
+	return filepath.Join(s.baseDir, executionID+".lineidx")
+}
+
+// newLineOffsets 扫描 data 中的换行符，返回相对 baseOffset 的新行起始偏移（不含 baseOffset 本身，
+// 调用方按场景决定是否需要额外补上"第0行起始于0"这一条）。
+func newLineOffsets(data []byte, baseOffset int64) []int64 {
+	var offsets []int64
+	for i, b := range data {
+		if b == '\n' {
+			offsets = append(offsets, baseOffset+int64(i)+1)
+		}
+	}
+	return offsets
+}
+
+// writeLineIndex 整体写入（覆盖）行索引文件，用于 SaveResult 一次性写入完整结果时建立索引。
+func writeLineIndex(path string, offsets lineIndex) error {
+	buf := make([]byte, len(offsets)*8)
+	for i, off := range offsets {
+		binary.LittleEndian.PutUint64(buf[i*8:], uint64(off))
+	}
+	return os.WriteFile(path, buf, 0644)
+}
+
+// appendLineIndex 追加新的行起始偏移，用于 AppendResult 增量更新索引，不重建已有部分。
+func appendLineIndex(path string, offsets lineIndex) error {
+	if len(offsets) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	buf := make([]byte, len(offsets)*8)
+	for i, off := range offsets {
+		binary.LittleEndian.PutUint64(buf[i*8:], uint64(off))
+	}
+	_, err = f.Write(buf)
+	return err
+}
+
+// readLineIndex 读取行索引文件；文件不存在或损坏（长度非8的倍数）时返回 error，调用方应
+// 回退到整读整切分，把行索引当作可选的加速手段而非唯一数据来源。
+func readLineIndex(path string) (lineIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%8 != 0 {
+		return nil, fmt.Errorf("行索引文件损坏: 长度 %d 不是8的倍数", len(data))
+	}
+	offsets := make(lineIndex, len(data)/8)
+	for i := range offsets {
+		offsets[i] = int64(binary.LittleEndian.Uint64(data[i*8:]))
+	}
+	return offsets, nil
+}
@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -10,14 +13,33 @@ import (
 	"sync"
 	"time"
 
+	"cyberstrike-ai/internal/config"
+
 	"go.uber.org/zap"
 )
 
+// NewResultStorage 按配置构造结果存储后端；cfg 为空或 Type 为空/"file" 时使用本地文件系统
+// （baseDir 下的 FileResultStorage）；Type 为 "s3" 时使用 S3 兼容对象存储，baseDir 此时被忽略。
+func NewResultStorage(baseDir string, logger *zap.Logger, cfg config.ResultStorageConfig) (ResultStorage, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Type)) {
+	case "", "file":
+		return NewFileResultStorage(baseDir, logger)
+	case "s3":
+		return newS3ResultStorage(cfg.S3, logger)
+	default:
+		return nil, fmt.Errorf("未知的结果存储后端类型: %s", cfg.Type)
+	}
+}
+
 // ResultStorage 结果存储接口
 type ResultStorage interface {
 	// SaveResult 保存工具执行结果
 	SaveResult(executionID string, toolName string, result string) error
 
+	// AppendResult 在工具执行过程中追加一段输出增量，用于边执行边落盘，避免长时间运行的工具
+	// 把完整输出一直攒在调用方内存里；可多次调用，元数据（大小/行数）随每次追加增量更新。
+	AppendResult(executionID string, toolName string, chunk string) error
+
 	// GetResult 获取完整结果
 	GetResult(executionID string) (string, error)
 
@@ -32,6 +54,11 @@ type ResultStorage interface {
 	// useRegex: 如果为 true，将 filter 作为正则表达式使用；如果为 false，使用简单的字符串包含匹配
 	FilterResult(executionID string, filter string, useRegex bool) ([]string, error)
 
+	// ExtractMatches 用正则表达式提取匹配内容：pattern 含捕获组时每条结果为第一个捕获组的文本
+	// （如 `(\d+\.\d+\.\d+\.\d+)` 只提取 IP 本身），不含捕获组时返回整个匹配文本；
+	// maxMatches <= 0 时使用默认上限，避免超大结果集下产生海量匹配耗尽内存。
+	ExtractMatches(executionID string, pattern string, maxMatches int) ([]string, error)
+
 	// GetResultMetadata 获取结果元信息
 	GetResultMetadata(executionID string) (*ResultMetadata, error)
 
@@ -40,6 +67,14 @@ type ResultStorage interface {
 
 	// DeleteResult 删除结果
 	DeleteResult(executionID string) error
+
+	// ListResults 列出当前存储的全部结果元信息，供保留策略/清理任务按创建时间或大小筛选；
+	// 返回顺序不保证，调用方按需自行排序。
+	ListResults() ([]*ResultMetadata, error)
+
+	// CompressResult 将指定结果的原始文件 gzip 压缩后替换原文件，返回回收的字节数
+	// （原始大小 - 压缩后大小）；结果已压缩或不存在时返回 (0, nil)，视为无需处理。
+	CompressResult(executionID string) (int64, error)
 }
 
 // ResultPage 分页结果
@@ -58,6 +93,9 @@ type ResultMetadata struct {
 	TotalSize   int       `json:"total_size"`
 	TotalLines  int       `json:"total_lines"`
 	CreatedAt   time.Time `json:"created_at"`
+	// Compressed 为 true 时结果文件已被 RetentionJob 以 gzip 压缩（见 CompressResult），
+	// TotalSize 此时反映压缩后的实际占用字节数。
+	Compressed bool `json:"compressed,omitempty"`
 }
 
 // FileResultStorage 基于文件的结果存储实现
@@ -90,16 +128,31 @@ func (s *FileResultStorage) getMetadataPath(executionID string) string {
 	return filepath.Join(s.baseDir, executionID+".meta.json")
 }
 
+// getCompressedResultPath 获取 gzip 压缩后的结果文件路径（见 CompressResult）
+func (s *FileResultStorage) getCompressedResultPath(executionID string) string {
+	return filepath.Join(s.baseDir, executionID+".txt.gz")
+}
+
 // SaveResult 保存工具执行结果
 func (s *FileResultStorage) SaveResult(executionID string, toolName string, result string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// 保存结果文件
+	// 保存结果文件；若此前已被压缩，新写入的原始内容作数，需清理掉过期的压缩副本
 	resultPath := s.getResultPath(executionID)
 	if err := os.WriteFile(resultPath, []byte(result), 0644); err != nil {
 		return fmt.Errorf("保存结果文件失败: %w", err)
 	}
+	if err := os.Remove(s.getCompressedResultPath(executionID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("清理旧压缩结果失败: %w", err)
+	}
+
+	// 建立行索引（见 line_index.go），使 GetResultPage 之后可以直接 seek 到目标行，
+	// 不必每次分页都整读整切分
+	offsets := append(lineIndex{0}, newLineOffsets([]byte(result), 0)...)
+	if err := writeLineIndex(s.getLineIndexPath(executionID), offsets); err != nil {
+		return fmt.Errorf("保存行索引失败: %w", err)
+	}
 
 	// 计算统计信息
 	lines := strings.Split(result, "\n")
@@ -132,21 +185,93 @@ func (s *FileResultStorage) SaveResult(executionID string, toolName string, resu
 	return nil
 }
 
-// GetResult 获取完整结果
+// AppendResult 在工具执行过程中追加一段输出增量。首次追加时创建结果文件与元数据；
+// 后续追加复用已有文件，仅按增量更新元数据中的大小/行数，不重新读取整个结果文件。
+func (s *FileResultStorage) AppendResult(executionID string, toolName string, chunk string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resultPath := s.getResultPath(executionID)
+	f, err := os.OpenFile(resultPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("打开结果文件失败: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(chunk); err != nil {
+		return fmt.Errorf("追加结果内容失败: %w", err)
+	}
+
+	metadataPath := s.getMetadataPath(executionID)
+	metadata := &ResultMetadata{}
+	if data, readErr := os.ReadFile(metadataPath); readErr == nil {
+		_ = json.Unmarshal(data, metadata)
+	} else {
+		metadata.ExecutionID = executionID
+		metadata.ToolName = toolName
+		metadata.CreatedAt = time.Now()
+	}
+
+	// 增量更新行索引：chunk 之前的文件大小即 chunk 内新行相对文件起始的基准偏移；
+	// 索引尚不存在（本次追加是这个执行ID的第一次写入）时连带补上第0行的起始偏移0。
+	lineIndexPath := s.getLineIndexPath(executionID)
+	if _, statErr := os.Stat(lineIndexPath); os.IsNotExist(statErr) {
+		offsets := append(lineIndex{0}, newLineOffsets([]byte(chunk), 0)...)
+		if err := writeLineIndex(lineIndexPath, offsets); err != nil {
+			return fmt.Errorf("保存行索引失败: %w", err)
+		}
+	} else {
+		offsets := newLineOffsets([]byte(chunk), int64(metadata.TotalSize))
+		if err := appendLineIndex(lineIndexPath, offsets); err != nil {
+			return fmt.Errorf("追加行索引失败: %w", err)
+		}
+	}
+
+	metadata.TotalSize += len(chunk)
+	metadata.TotalLines += strings.Count(chunk, "\n")
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("序列化元数据失败: %w", err)
+	}
+	if err := os.WriteFile(metadataPath, metadataJSON, 0644); err != nil {
+		return fmt.Errorf("保存元数据文件失败: %w", err)
+	}
+
+	return nil
+}
+
+// GetResult 获取完整结果。结果若已被 CompressResult 压缩，透明解压后返回，调用方无需感知。
 func (s *FileResultStorage) GetResult(executionID string) (string, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	resultPath := s.getResultPath(executionID)
 	data, err := os.ReadFile(resultPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return "", fmt.Errorf("结果不存在: %s", executionID)
-		}
+	if err == nil {
+		return string(data), nil
+	}
+	if !os.IsNotExist(err) {
 		return "", fmt.Errorf("读取结果文件失败: %w", err)
 	}
 
-	return string(data), nil
+	gzData, gzErr := os.ReadFile(s.getCompressedResultPath(executionID))
+	if gzErr != nil {
+		if os.IsNotExist(gzErr) {
+			return "", fmt.Errorf("结果不存在: %s", executionID)
+		}
+		return "", fmt.Errorf("读取压缩结果文件失败: %w", gzErr)
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(gzData))
+	if err != nil {
+		return "", fmt.Errorf("解压结果失败: %w", err)
+	}
+	defer gr.Close()
+	raw, err := io.ReadAll(gr)
+	if err != nil {
+		return "", fmt.Errorf("解压结果失败: %w", err)
+	}
+	return string(raw), nil
 }
 
 // GetResultMetadata 获取结果元信息
@@ -154,6 +279,11 @@ func (s *FileResultStorage) GetResultMetadata(executionID string) (*ResultMetada
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	return s.loadMetadata(executionID)
+}
+
+// loadMetadata 读取元数据文件，不加锁，调用方须自行持有 s.mu（读锁或写锁均可）。
+func (s *FileResultStorage) loadMetadata(executionID string) (*ResultMetadata, error) {
 	metadataPath := s.getMetadataPath(executionID)
 	data, err := os.ReadFile(metadataPath)
 	if err != nil {
@@ -171,22 +301,107 @@ func (s *FileResultStorage) GetResultMetadata(executionID string) (*ResultMetada
 	return &metadata, nil
 }
 
-// GetResultPage 分页获取结果
+// ListResults 列出当前存储的全部结果元信息
+func (s *FileResultStorage) ListResults() ([]*ResultMetadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := os.ReadDir(s.baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("读取存储目录失败: %w", err)
+	}
+
+	var results []*ResultMetadata
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".meta.json") {
+			continue
+		}
+		executionID := strings.TrimSuffix(entry.Name(), ".meta.json")
+		metadata, err := s.loadMetadata(executionID)
+		if err != nil {
+			s.logger.Warn("读取结果元数据失败，跳过", zap.String("executionID", executionID), zap.Error(err))
+			continue
+		}
+		results = append(results, metadata)
+	}
+	return results, nil
+}
+
+// CompressResult 将结果的原始 .txt 文件 gzip 压缩为 .txt.gz 并删除原文件，返回回收的字节数。
+// 结果已压缩或原始文件不存在（如已被删除）时返回 (0, nil)，不视为错误。
+func (s *FileResultStorage) CompressResult(executionID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resultPath := s.getResultPath(executionID)
+	data, err := os.ReadFile(resultPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("读取结果文件失败: %w", err)
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(data); err != nil {
+		gw.Close()
+		return 0, fmt.Errorf("压缩结果失败: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return 0, fmt.Errorf("压缩结果失败: %w", err)
+	}
+
+	if err := os.WriteFile(s.getCompressedResultPath(executionID), buf.Bytes(), 0644); err != nil {
+		return 0, fmt.Errorf("写入压缩结果失败: %w", err)
+	}
+	if err := os.Remove(resultPath); err != nil {
+		return 0, fmt.Errorf("删除原始结果文件失败: %w", err)
+	}
+	// 行索引指向的是原始文件的字节偏移，压缩后已失效；删除后 GetResultPage 自动回退到整读整解压
+	if err := os.Remove(s.getLineIndexPath(executionID)); err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("删除行索引失败: %w", err)
+	}
+
+	originalSize := int64(len(data))
+	compressedSize := int64(buf.Len())
+
+	if metadata, err := s.loadMetadata(executionID); err == nil {
+		metadata.Compressed = true
+		metadata.TotalSize = int(compressedSize)
+		if metaJSON, err := json.Marshal(metadata); err == nil {
+			_ = os.WriteFile(s.getMetadataPath(executionID), metaJSON, 0644)
+		}
+	}
+
+	return originalSize - compressedSize, nil
+}
+
+// GetResultPage 分页获取结果。存在行索引（见 line_index.go）时直接 seek 到目标行范围读取，
+// 避免多百MB级结果每次分页都整读整切分；索引缺失或损坏（如结果已被压缩、或写自本功能上线前
+// 的旧数据）时回退到整读整切分。
 func (s *FileResultStorage) GetResultPage(executionID string, page int, limit int) (*ResultPage, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// 获取完整结果
+	if offsets, err := readLineIndex(s.getLineIndexPath(executionID)); err == nil && len(offsets) > 0 {
+		if page, err := s.getResultPageFromIndex(executionID, offsets, page, limit); err == nil {
+			return page, nil
+		}
+		// seek 读取失败（如结果文件已不存在）时同样回退，不直接对外报错
+	}
+
 	result, err := s.GetResult(executionID)
 	if err != nil {
 		return nil, err
 	}
+	return paginateResultLines(strings.Split(result, "\n"), page, limit), nil
+}
 
-	// 分割为行
-	lines := strings.Split(result, "\n")
-	totalLines := len(lines)
-
-	// 计算分页
+// getResultPageFromIndex 借助行索引直接 seek 到 [start, end) 行对应的字节区间读取，
+// 不涉及区间外内容，是 GetResultPage 在索引可用时的加速路径。
+func (s *FileResultStorage) getResultPageFromIndex(executionID string, offsets lineIndex, page int, limit int) (*ResultPage, error) {
+	totalLines := len(offsets)
 	totalPages := (totalLines + limit - 1) / limit
 	if page < 1 {
 		page = 1
@@ -195,19 +410,43 @@ func (s *FileResultStorage) GetResultPage(executionID string, page int, limit in
 		page = totalPages
 	}
 
-	// 计算起始和结束索引
 	start := (page - 1) * limit
 	end := start + limit
 	if end > totalLines {
 		end = totalLines
 	}
 
-	// 提取指定页的行
-	var pageLines []string
-	if start < totalLines {
-		pageLines = lines[start:end]
+	if start >= totalLines {
+		return &ResultPage{Lines: []string{}, Page: page, Limit: limit, TotalLines: totalLines, TotalPages: totalPages}, nil
+	}
+
+	f, err := os.Open(s.getResultPath(executionID))
+	if err != nil {
+		return nil, fmt.Errorf("打开结果文件失败: %w", err)
+	}
+	defer f.Close()
+
+	byteStart := offsets[start]
+	var byteEnd int64
+	if end < totalLines {
+		byteEnd = offsets[end]
 	} else {
-		pageLines = []string{}
+		info, err := f.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("获取结果文件信息失败: %w", err)
+		}
+		byteEnd = info.Size()
+	}
+
+	buf := make([]byte, byteEnd-byteStart)
+	if _, err := f.ReadAt(buf, byteStart); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("按行索引读取结果失败: %w", err)
+	}
+
+	pageLines := strings.Split(string(buf), "\n")
+	// 区间恰好以本页最后一行的换行符结尾时会多出一个空字符串，裁掉不属于本页的部分
+	if len(pageLines) > end-start {
+		pageLines = pageLines[:end-start]
 	}
 
 	return &ResultPage{
@@ -224,13 +463,70 @@ func (s *FileResultStorage) SearchResult(executionID string, keyword string, use
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// 获取完整结果
 	result, err := s.GetResult(executionID)
 	if err != nil {
 		return nil, err
 	}
+	return matchLines(result, keyword, useRegex)
+}
+
+// FilterResult 过滤结果
+func (s *FileResultStorage) FilterResult(executionID string, filter string, useRegex bool) ([]string, error) {
+	// 过滤和搜索逻辑相同，都是查找包含关键词的行
+	return s.SearchResult(executionID, filter, useRegex)
+}
+
+// defaultMaxExtractMatches 是 ExtractMatches 在 maxMatches 未指定（<= 0）时使用的默认上限。
+const defaultMaxExtractMatches = 1000
+
+// ExtractMatches 用正则表达式在完整结果文本上提取匹配（允许匹配跨行，如多行拼接的 URL），
+// 含捕获组时只保留第一个捕获组的文本，否则保留整个匹配文本。
+func (s *FileResultStorage) ExtractMatches(executionID string, pattern string, maxMatches int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result, err := s.GetResult(executionID)
+	if err != nil {
+		return nil, err
+	}
+	return extractMatches(result, pattern, maxMatches)
+}
 
-	// 如果使用正则表达式，先编译正则
+// paginateResultLines 是 GetResultPage 的分页数学，供各 ResultStorage 实现共用。
+func paginateResultLines(lines []string, page int, limit int) *ResultPage {
+	totalLines := len(lines)
+	totalPages := (totalLines + limit - 1) / limit
+	if page < 1 {
+		page = 1
+	}
+	if page > totalPages && totalPages > 0 {
+		page = totalPages
+	}
+
+	start := (page - 1) * limit
+	end := start + limit
+	if end > totalLines {
+		end = totalLines
+	}
+
+	var pageLines []string
+	if start < totalLines {
+		pageLines = lines[start:end]
+	} else {
+		pageLines = []string{}
+	}
+
+	return &ResultPage{
+		Lines:      pageLines,
+		Page:       page,
+		Limit:      limit,
+		TotalLines: totalLines,
+		TotalPages: totalPages,
+	}
+}
+
+// matchLines 是 SearchResult/FilterResult 的共同实现，供各 ResultStorage 实现共用。
+func matchLines(result string, keyword string, useRegex bool) ([]string, error) {
 	var regex *regexp.Regexp
 	if useRegex {
 		compiledRegex, err := regexp.Compile(keyword)
@@ -240,10 +536,8 @@ func (s *FileResultStorage) SearchResult(executionID string, keyword string, use
 		regex = compiledRegex
 	}
 
-	// 分割为行并搜索
 	lines := strings.Split(result, "\n")
 	var matchedLines []string
-
 	for _, line := range lines {
 		var matched bool
 		if useRegex {
@@ -251,24 +545,41 @@ func (s *FileResultStorage) SearchResult(executionID string, keyword string, use
 		} else {
 			matched = strings.Contains(line, keyword)
 		}
-
 		if matched {
 			matchedLines = append(matchedLines, line)
 		}
 	}
-
 	return matchedLines, nil
 }
 
-// FilterResult 过滤结果
-func (s *FileResultStorage) FilterResult(executionID string, filter string, useRegex bool) ([]string, error) {
-	// 过滤和搜索逻辑相同，都是查找包含关键词的行
-	return s.SearchResult(executionID, filter, useRegex)
+// extractMatches 是 ExtractMatches 的共同实现，供各 ResultStorage 实现共用。
+func extractMatches(result string, pattern string, maxMatches int) ([]string, error) {
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("无效的正则表达式: %w", err)
+	}
+	if maxMatches <= 0 {
+		maxMatches = defaultMaxExtractMatches
+	}
+
+	rawMatches := regex.FindAllStringSubmatch(result, maxMatches)
+	extracted := make([]string, 0, len(rawMatches))
+	for _, m := range rawMatches {
+		if len(m) > 1 {
+			extracted = append(extracted, m[1])
+		} else {
+			extracted = append(extracted, m[0])
+		}
+	}
+	return extracted, nil
 }
 
-// GetResultPath 获取结果文件路径
+// GetResultPath 获取结果文件路径；若原始文件已被压缩，返回 gzip 压缩副本的路径。
 func (s *FileResultStorage) GetResultPath(executionID string) string {
-	return s.getResultPath(executionID)
+	if _, err := os.Stat(s.getResultPath(executionID)); err == nil {
+		return s.getResultPath(executionID)
+	}
+	return s.getCompressedResultPath(executionID)
 }
 
 // DeleteResult 删除结果
@@ -279,10 +590,16 @@ func (s *FileResultStorage) DeleteResult(executionID string) error {
 	resultPath := s.getResultPath(executionID)
 	metadataPath := s.getMetadataPath(executionID)
 
-	// 删除结果文件
+	// 删除结果文件（含可能存在的 gzip 压缩副本）
 	if err := os.Remove(resultPath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("删除结果文件失败: %w", err)
 	}
+	if err := os.Remove(s.getCompressedResultPath(executionID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除压缩结果文件失败: %w", err)
+	}
+	if err := os.Remove(s.getLineIndexPath(executionID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除行索引失败: %w", err)
+	}
 
 	// 删除元数据文件
 	if err := os.Remove(metadataPath); err != nil && !os.IsNotExist(err) {
@@ -77,6 +77,44 @@ func TestFileResultStorage_SaveResult(t *testing.T) {
 	}
 }
 
+func TestFileResultStorage_AppendResult(t *testing.T) {
+	storage, tmpDir := setupTestStorage(t)
+	defer cleanupTestStorage(t, tmpDir)
+
+	executionID := "test_exec_append_001"
+	toolName := "nuclei_scan"
+
+	if err := storage.AppendResult(executionID, toolName, "line 1\n"); err != nil {
+		t.Fatalf("追加结果失败: %v", err)
+	}
+	if err := storage.AppendResult(executionID, toolName, "line 2\n"); err != nil {
+		t.Fatalf("追加结果失败: %v", err)
+	}
+
+	result, err := storage.GetResult(executionID)
+	if err != nil {
+		t.Fatalf("读取结果失败: %v", err)
+	}
+	want := "line 1\nline 2\n"
+	if result != want {
+		t.Errorf("结果 = %q, want %q", result, want)
+	}
+
+	metadata, err := storage.GetResultMetadata(executionID)
+	if err != nil {
+		t.Fatalf("读取元数据失败: %v", err)
+	}
+	if metadata.ToolName != toolName {
+		t.Errorf("ToolName = %q, want %q", metadata.ToolName, toolName)
+	}
+	if metadata.TotalSize != len(want) {
+		t.Errorf("TotalSize = %d, want %d", metadata.TotalSize, len(want))
+	}
+	if metadata.TotalLines != 2 {
+		t.Errorf("TotalLines = %d, want 2", metadata.TotalLines)
+	}
+}
+
 func TestFileResultStorage_GetResult(t *testing.T) {
 	storage, tmpDir := setupTestStorage(t)
 	defer cleanupTestStorage(t, tmpDir)
@@ -326,6 +364,187 @@ func TestFileResultStorage_FilterResult(t *testing.T) {
 	}
 }
 
+func TestFileResultStorage_GetResultPage_LineIndexAfterAppend(t *testing.T) {
+	storage, tmpDir := setupTestStorage(t)
+	defer cleanupTestStorage(t, tmpDir)
+
+	executionID := "test_exec_009"
+	toolName := "test_tool"
+
+	// 分多次追加写入，行索引应随每次追加增量更新，而不是等到最后一次性重建
+	if err := storage.AppendResult(executionID, toolName, "Line 1\nLine 2\n"); err != nil {
+		t.Fatalf("追加结果失败: %v", err)
+	}
+	if err := storage.AppendResult(executionID, toolName, "Line 3\nLine 4\nLine 5"); err != nil {
+		t.Fatalf("追加结果失败: %v", err)
+	}
+
+	if _, err := os.Stat(storage.getLineIndexPath(executionID)); err != nil {
+		t.Fatalf("行索引文件应已创建: %v", err)
+	}
+
+	// 第一页
+	page1, err := storage.GetResultPage(executionID, 1, 2)
+	if err != nil {
+		t.Fatalf("获取第一页失败: %v", err)
+	}
+	if page1.TotalLines != 5 {
+		t.Fatalf("总行数不匹配。期望: 5, 实际: %d", page1.TotalLines)
+	}
+	if len(page1.Lines) != 2 || page1.Lines[0] != "Line 1" || page1.Lines[1] != "Line 2" {
+		t.Errorf("第一页内容不匹配: %v", page1.Lines)
+	}
+
+	// 最后一页，覆盖无尾随换行符的最后一行
+	lastPage, err := storage.GetResultPage(executionID, 3, 2)
+	if err != nil {
+		t.Fatalf("获取最后一页失败: %v", err)
+	}
+	if len(lastPage.Lines) != 1 || lastPage.Lines[0] != "Line 5" {
+		t.Errorf("最后一页内容不匹配: %v", lastPage.Lines)
+	}
+
+	// 超出范围的页应被钳制到最后一页，而不是报错或越界
+	clampedPage, err := storage.GetResultPage(executionID, 10, 2)
+	if err != nil {
+		t.Fatalf("获取超出范围页失败: %v", err)
+	}
+	if clampedPage.Page != lastPage.Page || len(clampedPage.Lines) != 1 || clampedPage.Lines[0] != "Line 5" {
+		t.Errorf("超出范围页应钳制为最后一页，实际: page=%d lines=%v", clampedPage.Page, clampedPage.Lines)
+	}
+
+	// 结果应与直接整读整分页的方式一致
+	full, err := storage.GetResult(executionID)
+	if err != nil {
+		t.Fatalf("获取完整结果失败: %v", err)
+	}
+	if full != "Line 1\nLine 2\nLine 3\nLine 4\nLine 5" {
+		t.Errorf("完整结果与预期不符: %q", full)
+	}
+}
+
+func TestFileResultStorage_CompressResult(t *testing.T) {
+	storage, tmpDir := setupTestStorage(t)
+	defer cleanupTestStorage(t, tmpDir)
+
+	executionID := "test_exec_007"
+	toolName := "test_tool"
+	result := strings.Repeat("line with some repeated content for compression\n", 200)
+
+	if err := storage.SaveResult(executionID, toolName, result); err != nil {
+		t.Fatalf("保存结果失败: %v", err)
+	}
+
+	saved, err := storage.CompressResult(executionID)
+	if err != nil {
+		t.Fatalf("压缩结果失败: %v", err)
+	}
+	if saved <= 0 {
+		t.Errorf("压缩应回收字节数，实际: %d", saved)
+	}
+
+	// 原始文件应已被替换为压缩文件
+	if _, err := os.Stat(storage.getResultPath(executionID)); !os.IsNotExist(err) {
+		t.Error("压缩后原始文件应已被删除")
+	}
+	if _, err := os.Stat(storage.getCompressedResultPath(executionID)); err != nil {
+		t.Errorf("压缩后应存在 .txt.gz 文件: %v", err)
+	}
+
+	// 压缩后读取应透明解压，内容不变
+	got, err := storage.GetResult(executionID)
+	if err != nil {
+		t.Fatalf("读取压缩结果失败: %v", err)
+	}
+	if got != result {
+		t.Error("压缩后读取的结果与原始内容不一致")
+	}
+
+	// 元数据应标记为已压缩
+	metadata, err := storage.GetResultMetadata(executionID)
+	if err != nil {
+		t.Fatalf("获取元数据失败: %v", err)
+	}
+	if !metadata.Compressed {
+		t.Error("元数据应标记 Compressed 为 true")
+	}
+
+	// 再次压缩应为空操作
+	saved2, err := storage.CompressResult(executionID)
+	if err != nil {
+		t.Fatalf("重复压缩失败: %v", err)
+	}
+	if saved2 != 0 {
+		t.Errorf("已压缩结果重复压缩应回收0字节，实际: %d", saved2)
+	}
+
+	// ListResults 应能列出该结果
+	all, err := storage.ListResults()
+	if err != nil {
+		t.Fatalf("列出结果失败: %v", err)
+	}
+	found := false
+	for _, m := range all {
+		if m.ExecutionID == executionID {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("ListResults 未包含已保存的结果")
+	}
+}
+
+func TestFileResultStorage_ExtractMatches(t *testing.T) {
+	storage, tmpDir := setupTestStorage(t)
+	defer cleanupTestStorage(t, tmpDir)
+
+	executionID := "test_exec_008"
+	toolName := "test_tool"
+	result := "host 10.0.0.1 is up\nhost 10.0.0.2 is up\nhost 10.0.0.3 is down\nno ip here"
+
+	err := storage.SaveResult(executionID, toolName, result)
+	if err != nil {
+		t.Fatalf("保存结果失败: %v", err)
+	}
+
+	// 带捕获组：只提取 IP 本身
+	ips, err := storage.ExtractMatches(executionID, `(\d+\.\d+\.\d+\.\d+)`, 0)
+	if err != nil {
+		t.Fatalf("提取失败: %v", err)
+	}
+	if len(ips) != 3 {
+		t.Fatalf("提取结果数量不匹配。期望: 3, 实际: %d", len(ips))
+	}
+	for _, ip := range ips {
+		if !strings.HasPrefix(ip, "10.0.0.") {
+			t.Errorf("提取结果不是预期的 IP: %s", ip)
+		}
+	}
+
+	// 不带捕获组：提取整个匹配
+	whole, err := storage.ExtractMatches(executionID, `\d+\.\d+\.\d+\.\d+ is up`, 0)
+	if err != nil {
+		t.Fatalf("提取失败: %v", err)
+	}
+	if len(whole) != 2 {
+		t.Errorf("提取结果数量不匹配。期望: 2, 实际: %d", len(whole))
+	}
+
+	// max_matches 限制
+	limited, err := storage.ExtractMatches(executionID, `(\d+\.\d+\.\d+\.\d+)`, 1)
+	if err != nil {
+		t.Fatalf("提取失败: %v", err)
+	}
+	if len(limited) != 1 {
+		t.Errorf("max_matches 未生效。期望: 1, 实际: %d", len(limited))
+	}
+
+	// 无效正则
+	if _, err := storage.ExtractMatches(executionID, "(", 0); err == nil {
+		t.Error("无效正则表达式应该返回错误")
+	}
+}
+
 func TestFileResultStorage_DeleteResult(t *testing.T) {
 	storage, tmpDir := setupTestStorage(t)
 	defer cleanupTestStorage(t, tmpDir)
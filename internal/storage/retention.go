@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultRetentionInterval 是保留策略/压缩清理任务默认的执行间隔。
+const defaultRetentionInterval = 1 * time.Hour
+
+// RetentionJob 定期扫描 ResultStorage：把超过压缩阈值的结果 gzip 压缩、把超过保留期限的结果
+// 删除，并在总占用超过上限时按创建时间从早到晚淘汰，避免长期运行下结果存储目录无限增长。
+type RetentionJob struct {
+	storage                ResultStorage
+	logger                 *zap.Logger
+	retentionDays          int
+	maxTotalSizeBytes      int64
+	compressThresholdBytes int64
+	interval               time.Duration
+}
+
+// NewRetentionJob 创建保留策略任务；retentionDays/maxTotalSizeBytes/compressThresholdBytes 中
+// 任意一项 <= 0 表示不启用对应的限制。
+func NewRetentionJob(storage ResultStorage, logger *zap.Logger, retentionDays int, maxTotalSizeBytes int64, compressThresholdBytes int64) *RetentionJob {
+	return &RetentionJob{
+		storage:                storage,
+		logger:                 logger,
+		retentionDays:          retentionDays,
+		maxTotalSizeBytes:      maxTotalSizeBytes,
+		compressThresholdBytes: compressThresholdBytes,
+		interval:               defaultRetentionInterval,
+	}
+}
+
+// Run 启动时立即执行一轮清理，之后按固定间隔重复，直到 ctx 被取消。调用方通常应以
+// `go job.Run(ctx)` 的方式在后台常驻运行。
+func (j *RetentionJob) Run(ctx context.Context) {
+	if j.retentionDays <= 0 && j.maxTotalSizeBytes <= 0 && j.compressThresholdBytes <= 0 {
+		return
+	}
+	j.runOnce()
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.runOnce()
+		}
+	}
+}
+
+// runOnce 执行一轮清理：先按保留期限删除过期结果，再压缩超过阈值的结果，最后在总占用仍超过
+// 上限时按创建时间从早到晚继续淘汰，三步共用同一份存活集合，避免重复扫描已删除的结果。
+func (j *RetentionJob) runOnce() {
+	results, err := j.storage.ListResults()
+	if err != nil {
+		j.logger.Warn("列出结果存储失败，跳过本轮清理", zap.Error(err))
+		return
+	}
+
+	var (
+		deleted        int
+		compressed     int
+		reclaimedBytes int64
+		now            = time.Now()
+	)
+
+	alive := results[:0]
+	for _, meta := range results {
+		if j.retentionDays > 0 && now.Sub(meta.CreatedAt) > time.Duration(j.retentionDays)*24*time.Hour {
+			if err := j.storage.DeleteResult(meta.ExecutionID); err != nil {
+				j.logger.Warn("删除过期结果失败", zap.String("executionID", meta.ExecutionID), zap.Error(err))
+				alive = append(alive, meta)
+				continue
+			}
+			deleted++
+			reclaimedBytes += int64(meta.TotalSize)
+			continue
+		}
+		alive = append(alive, meta)
+	}
+	results = alive
+
+	if j.compressThresholdBytes > 0 {
+		for _, meta := range results {
+			if meta.Compressed || int64(meta.TotalSize) < j.compressThresholdBytes {
+				continue
+			}
+			saved, err := j.storage.CompressResult(meta.ExecutionID)
+			if err != nil {
+				j.logger.Warn("压缩结果失败", zap.String("executionID", meta.ExecutionID), zap.Error(err))
+				continue
+			}
+			meta.Compressed = true
+			meta.TotalSize -= int(saved)
+			compressed++
+			reclaimedBytes += saved
+		}
+	}
+
+	if j.maxTotalSizeBytes > 0 {
+		var total int64
+		for _, meta := range results {
+			total += int64(meta.TotalSize)
+		}
+		if total > j.maxTotalSizeBytes {
+			sort.Slice(results, func(i, k int) bool { return results[i].CreatedAt.Before(results[k].CreatedAt) })
+			for _, meta := range results {
+				if total <= j.maxTotalSizeBytes {
+					break
+				}
+				if err := j.storage.DeleteResult(meta.ExecutionID); err != nil {
+					j.logger.Warn("按容量上限淘汰结果失败", zap.String("executionID", meta.ExecutionID), zap.Error(err))
+					continue
+				}
+				total -= int64(meta.TotalSize)
+				deleted++
+				reclaimedBytes += int64(meta.TotalSize)
+			}
+		}
+	}
+
+	if deleted > 0 || compressed > 0 {
+		j.logger.Info("结果存储清理完成",
+			zap.Int("deleted", deleted),
+			zap.Int("compressed", compressed),
+			zap.Int64("reclaimedBytes", reclaimedBytes),
+		)
+	}
+}
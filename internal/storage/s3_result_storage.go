@@ -0,0 +1,527 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"cyberstrike-ai/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// s3ResultStorage 用 S3 兼容对象存储（AWS S3、MinIO 等）实现 ResultStorage，使多节点部署下各
+// 实例产生的工具执行结果共享同一份存储，不再绑定到单机本地磁盘。签名走标准 AWS SigV4，不引入
+// 额外的 AWS SDK 依赖，风格与 knowledge.qdrantVectorStore（纯 REST + net/http）保持一致。
+//
+// 分页/搜索/正则提取的语义与 FileResultStorage 完全一致：取回完整对象内容后在内存中处理；
+// 元数据单独存成一个体积很小的 JSON 对象（<execID>.meta.json），GetResultMetadata/ListResults
+// 无需下载完整结果对象即可完成，代价与本地文件系统下 stat 元数据文件相当。
+type s3ResultStorage struct {
+	httpClient      *http.Client
+	logger          *zap.Logger
+	endpoint        *url.URL
+	region          string
+	bucket          string
+	prefix          string
+	accessKeyID     string
+	secretAccessKey string
+	usePathStyle    bool
+	appendMu        sync.Mutex // 保护 AppendResult 的读-改-写序列，避免并发追加互相覆盖
+}
+
+// errS3NotFound 标记对象不存在（HTTP 404），供上层统一转换为"结果不存在"错误。
+var errS3NotFound = fmt.Errorf("s3: 对象不存在")
+
+// s3ListBucketResult 是 ListObjectsV2 响应体中我们关心的字段（S3/MinIO 均遵循此 XML 结构）。
+type s3ListBucketResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	IsTruncated           bool     `xml:"IsTruncated"`
+	NextContinuationToken string   `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+}
+
+func newS3ResultStorage(cfg config.S3Config, logger *zap.Logger) (*s3ResultStorage, error) {
+	endpointStr := strings.TrimRight(strings.TrimSpace(cfg.Endpoint), "/")
+	if endpointStr == "" {
+		return nil, fmt.Errorf("s3 endpoint 未配置")
+	}
+	endpoint, err := url.Parse(endpointStr)
+	if err != nil {
+		return nil, fmt.Errorf("s3 endpoint 无效: %w", err)
+	}
+	if strings.TrimSpace(cfg.Bucket) == "" {
+		return nil, fmt.Errorf("s3 bucket 未配置")
+	}
+	region := strings.TrimSpace(cfg.Region)
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &s3ResultStorage{
+		httpClient:      &http.Client{Timeout: 60 * time.Second},
+		logger:          logger,
+		endpoint:        endpoint,
+		region:          region,
+		bucket:          strings.TrimSpace(cfg.Bucket),
+		prefix:          strings.Trim(strings.TrimSpace(cfg.Prefix), "/"),
+		accessKeyID:     cfg.AccessKeyID,
+		secretAccessKey: cfg.SecretAccessKey,
+		usePathStyle:    cfg.UsePathStyle,
+	}, nil
+}
+
+func (s *s3ResultStorage) objectKey(executionID, suffix string) string {
+	key := executionID + suffix
+	if s.prefix != "" {
+		key = s.prefix + "/" + key
+	}
+	return key
+}
+
+func (s *s3ResultStorage) resultKey(executionID string) string {
+	return s.objectKey(executionID, ".txt")
+}
+func (s *s3ResultStorage) compressedKey(executionID string) string {
+	return s.objectKey(executionID, ".txt.gz")
+}
+func (s *s3ResultStorage) metadataKey(executionID string) string {
+	return s.objectKey(executionID, ".meta.json")
+}
+
+// objectURL 按 path-style 或 virtual-hosted-style 拼出对象的请求地址，同时返回用于签名的 Host 头。
+func (s *s3ResultStorage) objectURL(key string) (*url.URL, string) {
+	u := *s.endpoint
+	if s.usePathStyle {
+		u.Path = "/" + s.bucket + "/" + key
+		return &u, u.Host
+	}
+	u.Host = s.bucket + "." + s.endpoint.Host
+	u.Path = "/" + key
+	return &u, u.Host
+}
+
+// bucketURL 拼出 bucket 根路径的请求地址（用于 ListObjectsV2），返回值同 objectURL。
+func (s *s3ResultStorage) bucketURL() (*url.URL, string) {
+	u := *s.endpoint
+	if s.usePathStyle {
+		u.Path = "/" + s.bucket + "/"
+		return &u, u.Host
+	}
+	u.Host = s.bucket + "." + s.endpoint.Host
+	u.Path = "/"
+	return &u, u.Host
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// signV4 为请求签发 AWS Signature Version 4，仅对 host/x-amz-content-sha256/x-amz-date 三个头
+// 签名——S3 兼容网关普遍只强制要求这三者，避免引入代理/负载均衡改写其他头导致签名失效。
+func (s *s3ResultStorage) signV4(req *http.Request, body []byte, host string) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Host = host
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", host, payloadHash, amzDate)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", dateStamp, s.region)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	kDate := hmacSHA256([]byte("AWS4"+s.secretAccessKey), dateStamp)
+	kRegion := hmacSHA256(kDate, s.region)
+	kService := hmacSHA256(kRegion, "s3")
+	kSigning := hmacSHA256(kService, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(kSigning, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+// do 发出一次已签名的 S3 请求；4xx/5xx 视为错误，404 转换为 errS3NotFound 供调用方识别。
+func (s *s3ResultStorage) do(ctx context.Context, method string, u *url.URL, host string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("s3: 构造请求失败: %w", err)
+	}
+	if body != nil {
+		req.ContentLength = int64(len(body))
+	}
+	s.signV4(req, body, host)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3: 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errS3NotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet := string(respBody)
+		if len(snippet) > 512 {
+			snippet = snippet[:512]
+		}
+		return nil, fmt.Errorf("s3: %s %s 返回 %d: %s", method, u.Path, resp.StatusCode, snippet)
+	}
+	return respBody, nil
+}
+
+func (s *s3ResultStorage) putObject(ctx context.Context, key string, body []byte) error {
+	u, host := s.objectURL(key)
+	_, err := s.do(ctx, http.MethodPut, u, host, body)
+	return err
+}
+
+func (s *s3ResultStorage) getObject(ctx context.Context, key string) ([]byte, error) {
+	u, host := s.objectURL(key)
+	return s.do(ctx, http.MethodGet, u, host, nil)
+}
+
+func (s *s3ResultStorage) deleteObject(ctx context.Context, key string) error {
+	u, host := s.objectURL(key)
+	_, err := s.do(ctx, http.MethodDelete, u, host, nil)
+	if err == errS3NotFound {
+		return nil
+	}
+	return err
+}
+
+// listObjectKeysWithSuffix 列出 bucket 下（可选前缀内）以 suffix 结尾的对象 key，用于 ListResults
+// 枚举全部结果的元数据对象。
+func (s *s3ResultStorage) listObjectKeysWithSuffix(ctx context.Context, suffix string) ([]string, error) {
+	u, host := s.bucketURL()
+	query := url.Values{}
+	query.Set("list-type", "2")
+	if s.prefix != "" {
+		query.Set("prefix", s.prefix+"/")
+	}
+	var keys []string
+	continuationToken := ""
+	for {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		if continuationToken != "" {
+			q.Set("continuation-token", continuationToken)
+		}
+		reqURL := *u
+		reqURL.RawQuery = q.Encode()
+
+		body, err := s.do(ctx, http.MethodGet, &reqURL, host, nil)
+		if err != nil {
+			if err == errS3NotFound {
+				return keys, nil
+			}
+			return nil, err
+		}
+
+		var parsed s3ListBucketResult
+		if err := xml.Unmarshal(body, &parsed); err != nil {
+			return nil, fmt.Errorf("s3: 解析 ListObjectsV2 响应失败: %w", err)
+		}
+		for _, c := range parsed.Contents {
+			if strings.HasSuffix(c.Key, suffix) {
+				keys = append(keys, c.Key)
+			}
+		}
+		if !parsed.IsTruncated || parsed.NextContinuationToken == "" {
+			break
+		}
+		continuationToken = parsed.NextContinuationToken
+	}
+	return keys, nil
+}
+
+func (s *s3ResultStorage) executionIDFromMetadataKey(key string) string {
+	base := key
+	if s.prefix != "" {
+		base = strings.TrimPrefix(base, s.prefix+"/")
+	}
+	return strings.TrimSuffix(base, ".meta.json")
+}
+
+func (s *s3ResultStorage) loadMetadata(ctx context.Context, executionID string) (*ResultMetadata, error) {
+	body, err := s.getObject(ctx, s.metadataKey(executionID))
+	if err != nil {
+		if err == errS3NotFound {
+			return nil, fmt.Errorf("结果不存在: %s", executionID)
+		}
+		return nil, fmt.Errorf("读取元数据失败: %w", err)
+	}
+	var metadata ResultMetadata
+	if err := json.Unmarshal(body, &metadata); err != nil {
+		return nil, fmt.Errorf("解析元数据失败: %w", err)
+	}
+	return &metadata, nil
+}
+
+func (s *s3ResultStorage) saveMetadata(ctx context.Context, metadata *ResultMetadata) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("序列化元数据失败: %w", err)
+	}
+	return s.putObject(ctx, s.metadataKey(metadata.ExecutionID), data)
+}
+
+// SaveResult 保存工具执行结果
+func (s *s3ResultStorage) SaveResult(executionID string, toolName string, result string) error {
+	ctx := context.Background()
+	if err := s.putObject(ctx, s.resultKey(executionID), []byte(result)); err != nil {
+		return fmt.Errorf("保存结果对象失败: %w", err)
+	}
+	// 新写入的原始内容作数，清理掉可能存在的过期压缩副本
+	if err := s.deleteObject(ctx, s.compressedKey(executionID)); err != nil {
+		return fmt.Errorf("清理旧压缩结果失败: %w", err)
+	}
+
+	lines := strings.Split(result, "\n")
+	metadata := &ResultMetadata{
+		ExecutionID: executionID,
+		ToolName:    toolName,
+		TotalSize:   len(result),
+		TotalLines:  len(lines),
+		CreatedAt:   time.Now(),
+	}
+	if err := s.saveMetadata(ctx, metadata); err != nil {
+		return fmt.Errorf("保存元数据失败: %w", err)
+	}
+
+	s.logger.Info("保存工具执行结果(S3)",
+		zap.String("executionID", executionID),
+		zap.String("toolName", toolName),
+		zap.Int("size", len(result)),
+		zap.Int("lines", len(lines)),
+	)
+	return nil
+}
+
+// AppendResult 追加一段输出增量。S3 对象不支持原地追加，这里退化为读-改-写：取回已有对象内容，
+// 拼接增量后整体重新上传；appendMu 序列化并发追加，避免"读到旧内容-写回覆盖"的竞态丢数据。
+func (s *s3ResultStorage) AppendResult(executionID string, toolName string, chunk string) error {
+	s.appendMu.Lock()
+	defer s.appendMu.Unlock()
+
+	ctx := context.Background()
+	existing, err := s.getObject(ctx, s.resultKey(executionID))
+	if err != nil && err != errS3NotFound {
+		return fmt.Errorf("读取已有结果失败: %w", err)
+	}
+
+	combined := append(existing, []byte(chunk)...)
+	if err := s.putObject(ctx, s.resultKey(executionID), combined); err != nil {
+		return fmt.Errorf("追加结果内容失败: %w", err)
+	}
+
+	metadata, err := s.loadMetadata(ctx, executionID)
+	if err != nil {
+		metadata = &ResultMetadata{ExecutionID: executionID, ToolName: toolName, CreatedAt: time.Now()}
+	}
+	metadata.TotalSize += len(chunk)
+	metadata.TotalLines += strings.Count(chunk, "\n")
+	if err := s.saveMetadata(ctx, metadata); err != nil {
+		return fmt.Errorf("保存元数据失败: %w", err)
+	}
+	return nil
+}
+
+// GetResult 获取完整结果。结果若已被 CompressResult 压缩，透明解压后返回。
+func (s *s3ResultStorage) GetResult(executionID string) (string, error) {
+	ctx := context.Background()
+	data, err := s.getObject(ctx, s.resultKey(executionID))
+	if err == nil {
+		return string(data), nil
+	}
+	if err != errS3NotFound {
+		return "", fmt.Errorf("读取结果对象失败: %w", err)
+	}
+
+	gzData, gzErr := s.getObject(ctx, s.compressedKey(executionID))
+	if gzErr != nil {
+		if gzErr == errS3NotFound {
+			return "", fmt.Errorf("结果不存在: %s", executionID)
+		}
+		return "", fmt.Errorf("读取压缩结果对象失败: %w", gzErr)
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(gzData))
+	if err != nil {
+		return "", fmt.Errorf("解压结果失败: %w", err)
+	}
+	defer gr.Close()
+	raw, err := io.ReadAll(gr)
+	if err != nil {
+		return "", fmt.Errorf("解压结果失败: %w", err)
+	}
+	return string(raw), nil
+}
+
+// GetResultPage 分页获取结果，分页数学与 FileResultStorage.GetResultPage 完全一致。
+func (s *s3ResultStorage) GetResultPage(executionID string, page int, limit int) (*ResultPage, error) {
+	result, err := s.GetResult(executionID)
+	if err != nil {
+		return nil, err
+	}
+	return paginateResultLines(strings.Split(result, "\n"), page, limit), nil
+}
+
+// SearchResult 搜索结果
+func (s *s3ResultStorage) SearchResult(executionID string, keyword string, useRegex bool) ([]string, error) {
+	result, err := s.GetResult(executionID)
+	if err != nil {
+		return nil, err
+	}
+	return matchLines(result, keyword, useRegex)
+}
+
+// FilterResult 过滤结果，语义同 SearchResult。
+func (s *s3ResultStorage) FilterResult(executionID string, filter string, useRegex bool) ([]string, error) {
+	return s.SearchResult(executionID, filter, useRegex)
+}
+
+// ExtractMatches 用正则表达式提取匹配内容，语义同 FileResultStorage.ExtractMatches。
+func (s *s3ResultStorage) ExtractMatches(executionID string, pattern string, maxMatches int) ([]string, error) {
+	result, err := s.GetResult(executionID)
+	if err != nil {
+		return nil, err
+	}
+	return extractMatches(result, pattern, maxMatches)
+}
+
+// GetResultMetadata 获取结果元信息，直接读取独立存放的小体积元数据对象，无需下载完整结果。
+func (s *s3ResultStorage) GetResultMetadata(executionID string) (*ResultMetadata, error) {
+	return s.loadMetadata(context.Background(), executionID)
+}
+
+// GetResultPath 对象存储没有本地文件路径，返回可读的伪路径（s3://bucket/key）用于展示/日志。
+func (s *s3ResultStorage) GetResultPath(executionID string) string {
+	return fmt.Sprintf("s3://%s/%s", s.bucket, s.resultKey(executionID))
+}
+
+// DeleteResult 删除结果对象、压缩副本与元数据对象。
+func (s *s3ResultStorage) DeleteResult(executionID string) error {
+	ctx := context.Background()
+	if err := s.deleteObject(ctx, s.resultKey(executionID)); err != nil {
+		return fmt.Errorf("删除结果对象失败: %w", err)
+	}
+	if err := s.deleteObject(ctx, s.compressedKey(executionID)); err != nil {
+		return fmt.Errorf("删除压缩结果对象失败: %w", err)
+	}
+	if err := s.deleteObject(ctx, s.metadataKey(executionID)); err != nil {
+		return fmt.Errorf("删除元数据对象失败: %w", err)
+	}
+	s.logger.Info("删除工具执行结果(S3)", zap.String("executionID", executionID))
+	return nil
+}
+
+// ListResults 列出当前存储的全部结果元信息，供保留策略/清理任务使用。
+func (s *s3ResultStorage) ListResults() ([]*ResultMetadata, error) {
+	ctx := context.Background()
+	keys, err := s.listObjectKeysWithSuffix(ctx, ".meta.json")
+	if err != nil {
+		return nil, fmt.Errorf("列出结果对象失败: %w", err)
+	}
+	var results []*ResultMetadata
+	for _, key := range keys {
+		executionID := s.executionIDFromMetadataKey(key)
+		metadata, err := s.loadMetadata(ctx, executionID)
+		if err != nil {
+			s.logger.Warn("读取结果元数据失败，跳过", zap.String("executionID", executionID), zap.Error(err))
+			continue
+		}
+		results = append(results, metadata)
+	}
+	return results, nil
+}
+
+// CompressResult 将结果的原始对象 gzip 压缩为 .txt.gz 并删除原对象，返回回收的字节数。
+func (s *s3ResultStorage) CompressResult(executionID string) (int64, error) {
+	ctx := context.Background()
+	data, err := s.getObject(ctx, s.resultKey(executionID))
+	if err != nil {
+		if err == errS3NotFound {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("读取结果对象失败: %w", err)
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(data); err != nil {
+		gw.Close()
+		return 0, fmt.Errorf("压缩结果失败: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return 0, fmt.Errorf("压缩结果失败: %w", err)
+	}
+
+	if err := s.putObject(ctx, s.compressedKey(executionID), buf.Bytes()); err != nil {
+		return 0, fmt.Errorf("写入压缩结果对象失败: %w", err)
+	}
+	if err := s.deleteObject(ctx, s.resultKey(executionID)); err != nil {
+		return 0, fmt.Errorf("删除原始结果对象失败: %w", err)
+	}
+
+	originalSize := int64(len(data))
+	compressedSize := int64(buf.Len())
+
+	if metadata, err := s.loadMetadata(ctx, executionID); err == nil {
+		metadata.Compressed = true
+		metadata.TotalSize = int(compressedSize)
+		_ = s.saveMetadata(ctx, metadata)
+	}
+
+	return originalSize - compressedSize, nil
+}
+
+var _ ResultStorage = (*s3ResultStorage)(nil)
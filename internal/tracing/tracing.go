@@ -0,0 +1,97 @@
+// Package tracing 提供 OpenTelemetry 分布式追踪的初始化与全局 Tracer 获取，
+// 用于 AgentLoop/callOpenAI/mcp.Server.CallTool/security.Executor.ExecuteTool 的端到端拆解。
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"cyberstrike-ai/internal/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// TracerName 是本项目所有 span 使用的统一 Tracer 名称
+const TracerName = "cyberstrike-ai"
+
+// noopShutdown 是未启用追踪时返回的空操作关闭函数
+func noopShutdown(context.Context) error { return nil }
+
+// Init 按配置初始化 OpenTelemetry 全局 TracerProvider，导出 span 到 OTLP/gRPC Collector；
+// cfg.Enabled 为 false 时不做任何事，全局 Tracer 保持 OTel 默认的空操作实现（各处 span 调用零开销）。
+// 返回的 shutdown 应在进程退出前调用，用于刷新未导出完的 span。
+func Init(ctx context.Context, cfg config.TracingConfig, logger *zap.Logger) (shutdown func(context.Context) error, err error) {
+	if !cfg.Enabled {
+		return noopShutdown, nil
+	}
+	if cfg.OTLPEndpoint == "" {
+		return noopShutdown, fmt.Errorf("tracing.enabled=true 但未配置 otlp_endpoint")
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "cyberstrike-ai"
+	}
+	sampleRatio := cfg.SampleRatio
+	if sampleRatio <= 0 {
+		sampleRatio = 1
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return noopShutdown, fmt.Errorf("创建 OTLP 导出器失败: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+	)
+	if err != nil {
+		return noopShutdown, fmt.Errorf("创建 OTel resource 失败: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+
+	logger.Info("OpenTelemetry 追踪已启用",
+		zap.String("otlp_endpoint", cfg.OTLPEndpoint),
+		zap.String("service_name", serviceName),
+		zap.Float64("sample_ratio", sampleRatio),
+	)
+
+	return tp.Shutdown, nil
+}
+
+// Tracer 返回全局统一的 Tracer；未调用 Init 或 Init 未启用时返回 OTel 默认的空操作实现
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// TraceIDFromContext 提取 ctx 中当前 span 的 trace ID（十六进制字符串）；
+// 未启用追踪或 ctx 中没有有效 span 时返回空字符串。
+func TraceIDFromContext(ctx context.Context) string {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return ""
+	}
+	return spanCtx.TraceID().String()
+}
+
+// StringAttr 是 attribute.String 的简写，避免各调用点重复 import attribute 包
+func StringAttr(key, value string) attribute.KeyValue {
+	return attribute.String(key, value)
+}
@@ -0,0 +1,73 @@
+// Package webhook 提供事件驱动的 outbound 通知能力（见 wivd/CyberStrikeAI#synth-3096）：
+// 任务完成/失败、发现漏洞时向配置的 URL POST 签名 JSON，用于对接 SOAR 平台或聊天机器人，
+// 使这些系统无需轮询本服务的 API 即可感知事件。
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Event 是投递给订阅端点的统一事件信封。
+type Event struct {
+	Type      string      `json:"type"` // task_completed/task_failed/vulnerability_found
+	Timestamp string      `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Client 是最小的 outbound webhook 客户端：POST JSON，可选 HMAC-SHA256 签名。
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient 创建 webhook 客户端；httpClient 为 nil 时使用默认超时的 http.Client。
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{httpClient: httpClient}
+}
+
+// Sign 计算 body 的 HMAC-SHA256 十六进制签名；secret 为空时返回空字符串（表示不签名）。
+func Sign(secret string, body []byte) string {
+	if secret == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Post 向 url 投递事件；secret 非空时附带 X-CyberStrike-Signature 请求头（格式 sha256=<hex>），
+// 供接收方校验请求确实来自本服务而非伪造。
+func (c *Client) Post(url, secret string, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("序列化 webhook 事件失败: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("构造 webhook 请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if sig := Sign(secret, body); sig != "" {
+		req.Header.Set("X-CyberStrike-Signature", "sha256="+sig)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送 webhook 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook 端点返回非成功状态码: %d", resp.StatusCode)
+	}
+	return nil
+}
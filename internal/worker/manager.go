@@ -0,0 +1,185 @@
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// OutputCallback 用于在远程执行过程中把 Worker 流式返回的输出增量推给上层（SSE），
+// 与 security.ToolOutputCallback 语义一致，独立定义以避免 worker 包反向依赖 security 包。
+type OutputCallback func(chunk string)
+
+// Manager 管理已注册的远程 Worker，并负责将工具执行分派到匹配的 Worker 上
+type Manager struct {
+	mu         sync.RWMutex
+	workers    map[string]*Worker
+	logger     *zap.Logger
+	httpClient *http.Client
+}
+
+// NewManager 创建 Worker 管理器
+func NewManager(logger *zap.Logger) *Manager {
+	return &Manager{
+		workers:    make(map[string]*Worker),
+		logger:     logger,
+		httpClient: &http.Client{
+			// 不设置整体 Timeout：工具可能长时间运行，超时由调用方传入的 ctx 控制
+		},
+	}
+}
+
+// RegisterWorker 注册或更新一个 Worker（重复注册视为刷新信息与心跳）
+func (m *Manager) RegisterWorker(req RegisterRequest) (*Worker, error) {
+	if req.ID == "" {
+		return nil, fmt.Errorf("worker id 不能为空")
+	}
+	if req.CallbackURL == "" {
+		return nil, fmt.Errorf("worker callbackUrl 不能为空")
+	}
+
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, exists := m.workers[req.ID]
+	if !exists {
+		w = &Worker{ID: req.ID, RegisteredAt: now}
+		m.workers[req.ID] = w
+	}
+	w.Label = req.Label
+	w.Region = req.Region
+	w.CallbackURL = req.CallbackURL
+	w.LastHeartbeat = now
+
+	m.logger.Info("Worker已注册",
+		zap.String("id", w.ID),
+		zap.String("label", w.Label),
+		zap.String("region", w.Region),
+	)
+	return w, nil
+}
+
+// Heartbeat 刷新指定 Worker 的最后心跳时间
+func (m *Manager) Heartbeat(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, exists := m.workers[id]
+	if !exists {
+		return fmt.Errorf("worker不存在: %s", id)
+	}
+	w.LastHeartbeat = time.Now()
+	return nil
+}
+
+// RemoveWorker 移除一个 Worker（用于 Worker 主动下线）
+func (m *Manager) RemoveWorker(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.workers, id)
+}
+
+// ListWorkers 返回所有已注册的 Worker（含离线的），按 ID 排序，用于前端展示
+func (m *Manager) ListWorkers() []*Worker {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*Worker, 0, len(m.workers))
+	for _, w := range m.workers {
+		clone := *w
+		result = append(result, &clone)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// SelectWorker 按 label/region 选择一个在线的 Worker；两者均为空时返回错误（避免无差别调度到任意机器）。
+// 均指定时要求同时匹配；只指定其一时按该字段匹配。多个候选时选择 ID 最小者，保证结果确定。
+func (m *Manager) SelectWorker(label, region string) (*Worker, error) {
+	if label == "" && region == "" {
+		return nil, fmt.Errorf("必须指定 label 或 region 之一来选择 worker")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var candidates []*Worker
+	for _, w := range m.workers {
+		if w.IsStale() {
+			continue
+		}
+		if label != "" && w.Label != label {
+			continue
+		}
+		if region != "" && w.Region != region {
+			continue
+		}
+		candidates = append(candidates, w)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("没有匹配 label=%q region=%q 的在线worker", label, region)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].ID < candidates[j].ID })
+	clone := *candidates[0]
+	return &clone, nil
+}
+
+// DispatchTool 将一次工具执行分派到指定 Worker：POST 到其 CallbackURL + "/execute"，
+// 按行读取 Worker 分块返回的输出并通过 cb 实时回调（cb 可为 nil），返回拼接后的完整输出。
+func (m *Manager) DispatchTool(ctx context.Context, w *Worker, command string, args []string, cb OutputCallback) (string, error) {
+	body, err := json.Marshal(ExecuteRequest{Command: command, Args: args})
+	if err != nil {
+		return "", fmt.Errorf("构建远程执行请求失败: %w", err)
+	}
+
+	url := strings.TrimRight(w.CallbackURL, "/") + "/execute"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("构建远程执行请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	m.logger.Info("分派工具到远程worker",
+		zap.String("workerId", w.ID),
+		zap.String("command", command),
+	)
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("连接worker失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("worker返回错误状态 %d: %s", resp.StatusCode, string(errBody))
+	}
+
+	var output strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		output.WriteString(line)
+		output.WriteString("\n")
+		if cb != nil {
+			cb(line + "\n")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return output.String(), fmt.Errorf("读取worker输出失败: %w", err)
+	}
+
+	return output.String(), nil
+}
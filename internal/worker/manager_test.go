@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestManager_RegisterAndSelectWorker(t *testing.T) {
+	m := NewManager(zap.NewNop())
+
+	if _, err := m.RegisterWorker(RegisterRequest{ID: "w1", Label: "vpn-segment-a", Region: "branch-1", CallbackURL: "http://10.0.0.1:9500"}); err != nil {
+		t.Fatalf("注册worker失败: %v", err)
+	}
+	if _, err := m.RegisterWorker(RegisterRequest{ID: "w2", Label: "vpn-segment-b", Region: "branch-2", CallbackURL: "http://10.0.0.2:9500"}); err != nil {
+		t.Fatalf("注册worker失败: %v", err)
+	}
+
+	w, err := m.SelectWorker("vpn-segment-a", "")
+	if err != nil {
+		t.Fatalf("按label选择worker失败: %v", err)
+	}
+	if w.ID != "w1" {
+		t.Errorf("ID = %q, want %q", w.ID, "w1")
+	}
+
+	w, err = m.SelectWorker("", "branch-2")
+	if err != nil {
+		t.Fatalf("按region选择worker失败: %v", err)
+	}
+	if w.ID != "w2" {
+		t.Errorf("ID = %q, want %q", w.ID, "w2")
+	}
+
+	if _, err := m.SelectWorker("vpn-segment-a", "branch-2"); err == nil {
+		t.Error("label与region不匹配同一worker时应返回错误")
+	}
+
+	if _, err := m.SelectWorker("", ""); err == nil {
+		t.Error("label/region均为空时应返回错误")
+	}
+}
+
+func TestManager_SelectWorker_SkipsStale(t *testing.T) {
+	m := NewManager(zap.NewNop())
+	if _, err := m.RegisterWorker(RegisterRequest{ID: "w1", Label: "kali", CallbackURL: "http://10.0.0.1:9500"}); err != nil {
+		t.Fatalf("注册worker失败: %v", err)
+	}
+
+	m.mu.Lock()
+	m.workers["w1"].LastHeartbeat = time.Now().Add(-2 * time.Minute)
+	m.mu.Unlock()
+
+	if _, err := m.SelectWorker("kali", ""); err == nil {
+		t.Error("心跳超时的worker不应被选中")
+	}
+}
+
+func TestManager_HeartbeatAndRemove(t *testing.T) {
+	m := NewManager(zap.NewNop())
+	if _, err := m.RegisterWorker(RegisterRequest{ID: "w1", Label: "kali", CallbackURL: "http://10.0.0.1:9500"}); err != nil {
+		t.Fatalf("注册worker失败: %v", err)
+	}
+
+	if err := m.Heartbeat("w1"); err != nil {
+		t.Errorf("心跳失败: %v", err)
+	}
+	if err := m.Heartbeat("unknown"); err == nil {
+		t.Error("不存在的worker心跳应返回错误")
+	}
+
+	m.RemoveWorker("w1")
+	if _, err := m.SelectWorker("kali", ""); err == nil {
+		t.Error("移除后不应再被选中")
+	}
+	if len(m.ListWorkers()) != 0 {
+		t.Error("移除后列表应为空")
+	}
+}
@@ -0,0 +1,38 @@
+// Package worker 实现分布式执行子系统：轻量级 Worker 代理通过 HTTP 向主服务注册，
+// Executor 在工具配置了 RemoteExec 时可将该工具的执行分派到匹配 Label/Region 的 Worker 上，
+// 用于扫描主服务器网络不可达的网段。
+package worker
+
+import "time"
+
+// Worker 表示一个已注册的远程执行代理
+type Worker struct {
+	ID            string    `json:"id"`
+	Label         string    `json:"label"`       // 自定义标签，如 "vpn-segment-a"
+	Region        string    `json:"region"`      // 所在区域/网段，如 "branch-office-1"
+	CallbackURL   string    `json:"callbackUrl"` // Worker 自身暴露的 HTTP 地址，如 "http://10.0.5.2:9500"
+	RegisteredAt  time.Time `json:"registeredAt"`
+	LastHeartbeat time.Time `json:"lastHeartbeat"`
+}
+
+// staleAfter 超过该时长未收到心跳的 Worker 视为离线，不再参与调度
+const staleAfter = 60 * time.Second
+
+// IsStale 判断 Worker 是否已超过心跳超时时间，视为离线
+func (w *Worker) IsStale() bool {
+	return time.Since(w.LastHeartbeat) > staleAfter
+}
+
+// RegisterRequest 是 Worker 向主服务发起注册时的请求体
+type RegisterRequest struct {
+	ID          string `json:"id"`
+	Label       string `json:"label"`
+	Region      string `json:"region"`
+	CallbackURL string `json:"callbackUrl"`
+}
+
+// ExecuteRequest 是主服务分派工具执行时发给 Worker 的请求体
+type ExecuteRequest struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}